@@ -0,0 +1,165 @@
+package chaos
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/0xProject/0x-mesh/ethereum/blockwatch"
+	"github.com/0xProject/0x-mesh/ethereum/miniheader"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// BlockClient is a blockwatch.Client that injects artificial chain reorgs. A
+// reorg of depth N replaces the latest N blocks with blocks that have different
+// hashes and no logs. Once the underlying chain advances past the replaced
+// blocks, the block watcher reorgs back to the underlying chain. Each artificial
+// reorg therefore causes two reorgs of depth N in the block watcher.
+type BlockClient struct {
+	client       blockwatch.Client
+	chaos        *Chaos
+	mu           sync.Mutex
+	pendingReorg bool
+	lastReorg    time.Time
+	// forkTip is the number of the latest block in the current fork.
+	forkTip      uint64
+	forkByNumber map[uint64]*miniheader.MiniHeader
+	forkByHash   map[common.Hash]*miniheader.MiniHeader
+}
+
+// WrapBlockClient returns a BlockClient that forwards requests to client and
+// injects a reorg of depth Config.ReorgDepth every Config.ReorgInterval.
+func (c *Chaos) WrapBlockClient(client blockwatch.Client) *BlockClient {
+	return &BlockClient{
+		client:       client,
+		chaos:        c,
+		lastReorg:    time.Now(),
+		forkByNumber: map[uint64]*miniheader.MiniHeader{},
+		forkByHash:   map[common.Hash]*miniheader.MiniHeader{},
+	}
+}
+
+// TriggerReorg causes a reorg to be injected the next time the latest block
+// header is requested.
+func (bc *BlockClient) TriggerReorg() {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.pendingReorg = true
+}
+
+// HeaderByNumber fetches a block header by its number. If no `number` is
+// supplied, it will return the latest block header.
+func (bc *BlockClient) HeaderByNumber(number *big.Int) (*miniheader.MiniHeader, error) {
+	if number != nil {
+		bc.mu.Lock()
+		header, ok := bc.forkByNumber[number.Uint64()]
+		bc.mu.Unlock()
+		if ok {
+			return copyHeader(header), nil
+		}
+		return bc.client.HeaderByNumber(number)
+	}
+
+	latest, err := bc.client.HeaderByNumber(nil)
+	if err != nil {
+		return nil, err
+	}
+	bc.mu.Lock()
+	if len(bc.forkByNumber) > 0 && latest.Number.Uint64() > bc.forkTip {
+		// The underlying chain has overtaken the fork, so the block watcher
+		// will reorg back to it.
+		bc.forkByNumber = map[uint64]*miniheader.MiniHeader{}
+	}
+	if header, ok := bc.forkByNumber[latest.Number.Uint64()]; ok {
+		bc.mu.Unlock()
+		return copyHeader(header), nil
+	}
+	interval := bc.chaos.config.ReorgInterval
+	shouldReorg := bc.pendingReorg || (interval > 0 && time.Since(bc.lastReorg) >= interval)
+	bc.mu.Unlock()
+	if !shouldReorg {
+		return latest, nil
+	}
+	return bc.fork(latest)
+}
+
+// fork replaces the latest Config.ReorgDepth blocks up to and including latest
+// with new blocks and returns the new latest block.
+func (bc *BlockClient) fork(latest *miniheader.MiniHeader) (*miniheader.MiniHeader, error) {
+	tipNumber := latest.Number.Uint64()
+	depth := uint64(bc.chaos.config.ReorgDepth)
+	if depth > tipNumber {
+		depth = tipNumber
+	}
+	if depth == 0 {
+		return latest, nil
+	}
+	ancestor, err := bc.client.HeaderByNumber(new(big.Int).SetUint64(tipNumber - depth))
+	if err != nil {
+		return nil, err
+	}
+
+	forkByNumber := map[uint64]*miniheader.MiniHeader{}
+	forkByHash := map[common.Hash]*miniheader.MiniHeader{}
+	parent := ancestor.Hash
+	for number := tipNumber - depth + 1; number <= tipNumber; number++ {
+		header := &miniheader.MiniHeader{
+			Parent:    parent,
+			Number:    new(big.Int).SetUint64(number),
+			Timestamp: latest.Timestamp,
+		}
+		bc.chaos.randomBytes(header.Hash[:])
+		forkByNumber[number] = header
+		forkByHash[header.Hash] = header
+		parent = header.Hash
+	}
+
+	bc.mu.Lock()
+	bc.forkTip = tipNumber
+	bc.forkByNumber = forkByNumber
+	bc.forkByHash = forkByHash
+	bc.pendingReorg = false
+	bc.lastReorg = time.Now()
+	bc.mu.Unlock()
+
+	log.WithFields(map[string]interface{}{
+		"depth":       depth,
+		"blockNumber": tipNumber,
+	}).Info("chaos: injecting artificial chain reorg")
+	return copyHeader(forkByNumber[tipNumber]), nil
+}
+
+// HeaderByHash fetches a block header by its block hash.
+func (bc *BlockClient) HeaderByHash(hash common.Hash) (*miniheader.MiniHeader, error) {
+	bc.mu.Lock()
+	header, ok := bc.forkByHash[hash]
+	bc.mu.Unlock()
+	if ok {
+		return copyHeader(header), nil
+	}
+	return bc.client.HeaderByHash(hash)
+}
+
+// FilterLogs returns the logs that satisfy the supplied filter query. Blocks
+// injected by a reorg never contain any logs.
+func (bc *BlockClient) FilterLogs(q ethereum.FilterQuery) ([]types.Log, error) {
+	if q.BlockHash != nil {
+		bc.mu.Lock()
+		_, ok := bc.forkByHash[*q.BlockHash]
+		bc.mu.Unlock()
+		if ok {
+			return []types.Log{}, nil
+		}
+	}
+	return bc.client.FilterLogs(q)
+}
+
+// copyHeader returns a shallow copy of header so that callers cannot modify the
+// headers stored by BlockClient.
+func copyHeader(header *miniheader.MiniHeader) *miniheader.MiniHeader {
+	headerCopy := *header
+	return &headerCopy
+}
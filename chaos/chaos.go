@@ -0,0 +1,148 @@
+// Package chaos injects failures into a Mesh node so that maintainers and
+// operators can verify how a deployment behaves under realistic failure modes.
+// It can wrap the Ethereum JSON-RPC client to simulate request timeouts, wrap
+// the block watcher client to simulate chain reorgs of a given depth, and
+// periodically disconnect random peers. It should never be enabled in
+// production.
+package chaos
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config determines which failures are injected and how often.
+type Config struct {
+	// RPCTimeoutRate is the probability (between 0 and 1) that any given
+	// Ethereum JSON-RPC request fails as if it had timed out.
+	RPCTimeoutRate float64
+	// PeerDisconnectInterval is how often a random peer is disconnected. If 0,
+	// peers are never disconnected.
+	PeerDisconnectInterval time.Duration
+	// ReorgInterval is how often an artificial chain reorg is injected. If 0,
+	// reorgs are only injected when BlockClient.TriggerReorg is called.
+	ReorgInterval time.Duration
+	// ReorgDepth is the number of blocks that are replaced by each artificial
+	// reorg. It should not exceed the number of blocks retained by the block
+	// watcher. Defaults to 1.
+	ReorgDepth int
+	// Seed is used to seed the random number generator. If 0, a seed based on
+	// the current time is used.
+	Seed int64
+}
+
+// Enabled returns true if the config injects any failures.
+func (c Config) Enabled() bool {
+	return c.RPCTimeoutRate > 0 || c.PeerDisconnectInterval > 0 || c.ReorgInterval > 0
+}
+
+// ParseConfig parses a comma-separated list of key=value pairs into a Config.
+// The supported keys are rpcTimeoutRate, peerDisconnectInterval, reorgInterval,
+// reorgDepth and seed (e.g.
+// "rpcTimeoutRate=0.05,peerDisconnectInterval=30s,reorgInterval=2m,reorgDepth=3").
+// An empty string results in a Config that injects no failures.
+func ParseConfig(s string) (Config, error) {
+	config := Config{}
+	if strings.TrimSpace(s) == "" {
+		return config, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 {
+			return Config{}, fmt.Errorf("invalid chaos option %q: expected key=value", pair)
+		}
+		key, value := kv[0], kv[1]
+		var err error
+		switch key {
+		case "rpcTimeoutRate":
+			config.RPCTimeoutRate, err = strconv.ParseFloat(value, 64)
+			if err == nil && (config.RPCTimeoutRate < 0 || config.RPCTimeoutRate > 1) {
+				err = fmt.Errorf("must be between 0 and 1")
+			}
+		case "peerDisconnectInterval":
+			config.PeerDisconnectInterval, err = parsePositiveDuration(value)
+		case "reorgInterval":
+			config.ReorgInterval, err = parsePositiveDuration(value)
+		case "reorgDepth":
+			config.ReorgDepth, err = strconv.Atoi(value)
+			if err == nil && config.ReorgDepth < 1 {
+				err = fmt.Errorf("must be at least 1")
+			}
+		case "seed":
+			config.Seed, err = strconv.ParseInt(value, 10, 64)
+		default:
+			return Config{}, fmt.Errorf("unknown chaos option %q", key)
+		}
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid value for chaos option %q: %s", key, err.Error())
+		}
+	}
+	return config, nil
+}
+
+func parsePositiveDuration(value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("must be positive")
+	}
+	return duration, nil
+}
+
+// Chaos injects failures according to its Config. It is safe for concurrent
+// use.
+type Chaos struct {
+	config Config
+	mu     sync.Mutex
+	rng    *rand.Rand
+}
+
+// New returns a new Chaos with the given config.
+func New(config Config) *Chaos {
+	if config.ReorgDepth == 0 {
+		config.ReorgDepth = 1
+	}
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Chaos{
+		config: config,
+		rng:    rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Config returns the config used by c.
+func (c *Chaos) Config() Config {
+	return c.config
+}
+
+// shouldInject returns true with the given probability.
+func (c *Chaos) shouldInject(probability float64) bool {
+	if probability <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rng.Float64() < probability
+}
+
+// intn returns a random int in [0, n).
+func (c *Chaos) intn(n int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rng.Intn(n)
+}
+
+// randomBytes fills b with random bytes.
+func (c *Chaos) randomBytes(b []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, _ = c.rng.Read(b)
+}
@@ -0,0 +1,177 @@
+// +build !js
+
+package chaos
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0xProject/0x-mesh/ethereum/miniheader"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfig(t *testing.T) {
+	config, err := ParseConfig("")
+	require.NoError(t, err)
+	assert.False(t, config.Enabled())
+
+	config, err = ParseConfig("rpcTimeoutRate=0.05, peerDisconnectInterval=30s,reorgInterval=2m,reorgDepth=3,seed=42")
+	require.NoError(t, err)
+	expected := Config{
+		RPCTimeoutRate:         0.05,
+		PeerDisconnectInterval: 30 * time.Second,
+		ReorgInterval:          2 * time.Minute,
+		ReorgDepth:             3,
+		Seed:                   42,
+	}
+	assert.Equal(t, expected, config)
+	assert.True(t, config.Enabled())
+
+	invalidConfigs := []string{
+		"rpcTimeoutRate",
+		"rpcTimeoutRate=1.5",
+		"peerDisconnectInterval=-1s",
+		"reorgInterval=often",
+		"reorgDepth=0",
+		"unknown=1",
+	}
+	for _, invalidConfig := range invalidConfigs {
+		_, err := ParseConfig(invalidConfig)
+		assert.Error(t, err, invalidConfig)
+	}
+}
+
+func TestWrapEthRPCClientInjectsTimeouts(t *testing.T) {
+	alwaysTimeout := New(Config{RPCTimeoutRate: 1, Seed: 1}).WrapEthRPCClient(nil)
+	err := alwaysTimeout.CallContext(context.Background(), nil, "eth_blockNumber")
+	assert.Equal(t, context.DeadlineExceeded, err)
+	_, err = alwaysTimeout.FilterLogs(context.Background(), ethereum.FilterQuery{})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestBlockClientReorg(t *testing.T) {
+	chain := newFakeChain(10)
+	blockClient := New(Config{ReorgDepth: 3, Seed: 1}).WrapBlockClient(chain)
+
+	latest, err := blockClient.HeaderByNumber(nil)
+	require.NoError(t, err)
+	assert.Equal(t, chain.headers[9], latest)
+
+	// After triggering a reorg, the latest 3 blocks are replaced.
+	blockClient.TriggerReorg()
+	forkedLatest, err := blockClient.HeaderByNumber(nil)
+	require.NoError(t, err)
+	assert.Equal(t, latest.Number, forkedLatest.Number)
+	assert.NotEqual(t, latest.Hash, forkedLatest.Hash)
+	header := forkedLatest
+	for i := 0; i < 3; i++ {
+		byHash, err := blockClient.HeaderByHash(header.Hash)
+		require.NoError(t, err)
+		assert.Equal(t, header, byHash)
+		byNumber, err := blockClient.HeaderByNumber(header.Number)
+		require.NoError(t, err)
+		assert.Equal(t, header, byNumber)
+		logs, err := blockClient.FilterLogs(ethereum.FilterQuery{BlockHash: &header.Hash})
+		require.NoError(t, err)
+		assert.Empty(t, logs)
+		header, err = blockClient.HeaderByHash(header.Parent)
+		require.NoError(t, err)
+	}
+	// The fork is built on top of the canonical chain.
+	assert.Equal(t, chain.headers[6], header)
+
+	// The fork is abandoned once the canonical chain overtakes it.
+	chain.mine()
+	latest, err = blockClient.HeaderByNumber(nil)
+	require.NoError(t, err)
+	assert.Equal(t, chain.headers[10], latest)
+	byNumber, err := blockClient.HeaderByNumber(big.NewInt(9))
+	require.NoError(t, err)
+	assert.Equal(t, chain.headers[9], byNumber)
+}
+
+func TestDisconnectRandomPeer(t *testing.T) {
+	network := &fakeNetwork{peers: []peer.ID{"a", "b", "c"}}
+	New(Config{Seed: 1}).disconnectRandomPeer(network)
+	require.Len(t, network.disconnected, 1)
+	assert.Contains(t, network.peers, network.disconnected[0])
+}
+
+// fakeChain is a blockwatch.Client backed by an in-memory chain without any
+// logs.
+type fakeChain struct {
+	mu      sync.Mutex
+	headers []*miniheader.MiniHeader
+}
+
+func newFakeChain(numBlocks int) *fakeChain {
+	chain := &fakeChain{}
+	for i := 0; i < numBlocks; i++ {
+		chain.mine()
+	}
+	return chain
+}
+
+func (fc *fakeChain) mine() {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	number := len(fc.headers)
+	header := &miniheader.MiniHeader{
+		Hash:      common.BigToHash(big.NewInt(int64(number + 1))),
+		Number:    big.NewInt(int64(number)),
+		Timestamp: time.Unix(int64(number), 0),
+	}
+	if number > 0 {
+		header.Parent = fc.headers[number-1].Hash
+	}
+	fc.headers = append(fc.headers, header)
+}
+
+func (fc *fakeChain) HeaderByNumber(number *big.Int) (*miniheader.MiniHeader, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if number == nil {
+		return fc.headers[len(fc.headers)-1], nil
+	}
+	if number.Int64() >= int64(len(fc.headers)) {
+		return nil, ethereum.NotFound
+	}
+	return fc.headers[number.Int64()], nil
+}
+
+func (fc *fakeChain) HeaderByHash(hash common.Hash) (*miniheader.MiniHeader, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	for _, header := range fc.headers {
+		if header.Hash == hash {
+			return header, nil
+		}
+	}
+	return nil, ethereum.NotFound
+}
+
+func (fc *fakeChain) FilterLogs(q ethereum.FilterQuery) ([]types.Log, error) {
+	return []types.Log{}, nil
+}
+
+type fakeNetwork struct {
+	peers        []peer.ID
+	disconnected []peer.ID
+}
+
+func (fn *fakeNetwork) Neighbors() []peer.ID {
+	return fn.peers
+}
+
+func (fn *fakeNetwork) Disconnect(peerID peer.ID) error {
+	fn.disconnected = append(fn.disconnected, peerID)
+	return nil
+}
@@ -0,0 +1,80 @@
+package chaos
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/0xProject/0x-mesh/ethereum/ethrpcclient"
+	"github.com/0xProject/0x-mesh/ethereum/miniheader"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// ethRPCClient is an ethrpcclient.Client that fails a random subset of requests
+// with context.DeadlineExceeded, the same error that is returned when a request
+// to the Ethereum JSON-RPC provider times out.
+type ethRPCClient struct {
+	ethrpcclient.Client
+	chaos *Chaos
+}
+
+// WrapEthRPCClient returns an ethrpcclient.Client that forwards requests to
+// client but fails them with probability Config.RPCTimeoutRate.
+func (c *Chaos) WrapEthRPCClient(client ethrpcclient.Client) ethrpcclient.Client {
+	return &ethRPCClient{
+		Client: client,
+		chaos:  c,
+	}
+}
+
+func (ec *ethRPCClient) injectTimeout(method string) error {
+	if !ec.chaos.shouldInject(ec.chaos.config.RPCTimeoutRate) {
+		return nil
+	}
+	log.WithField("method", method).Debug("chaos: injecting Ethereum RPC timeout")
+	return context.DeadlineExceeded
+}
+
+func (ec *ethRPCClient) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	if err := ec.injectTimeout("HeaderByHash"); err != nil {
+		return nil, err
+	}
+	return ec.Client.HeaderByHash(ctx, hash)
+}
+
+func (ec *ethRPCClient) HeaderByNumber(ctx context.Context, number *big.Int) (*miniheader.MiniHeader, error) {
+	if err := ec.injectTimeout("HeaderByNumber"); err != nil {
+		return nil, err
+	}
+	return ec.Client.HeaderByNumber(ctx, number)
+}
+
+func (ec *ethRPCClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	if err := ec.injectTimeout("FilterLogs"); err != nil {
+		return nil, err
+	}
+	return ec.Client.FilterLogs(ctx, q)
+}
+
+func (ec *ethRPCClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	if err := ec.injectTimeout(method); err != nil {
+		return err
+	}
+	return ec.Client.CallContext(ctx, result, method, args...)
+}
+
+func (ec *ethRPCClient) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	if err := ec.injectTimeout("CodeAt"); err != nil {
+		return []byte{}, err
+	}
+	return ec.Client.CodeAt(ctx, contract, blockNumber)
+}
+
+func (ec *ethRPCClient) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if err := ec.injectTimeout("CallContract"); err != nil {
+		return []byte{}, err
+	}
+	return ec.Client.CallContract(ctx, call, blockNumber)
+}
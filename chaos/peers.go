@@ -0,0 +1,49 @@
+package chaos
+
+import (
+	"context"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	log "github.com/sirupsen/logrus"
+)
+
+// Network is the subset of p2p.Node methods needed to disconnect peers.
+type Network interface {
+	Neighbors() []peer.ID
+	Disconnect(peerID peer.ID) error
+}
+
+// DisconnectPeersPeriodically disconnects a random peer every
+// Config.PeerDisconnectInterval until ctx is canceled. Peers are free to
+// reconnect through the usual peer discovery mechanisms. It returns immediately
+// if Config.PeerDisconnectInterval is 0.
+func (c *Chaos) DisconnectPeersPeriodically(ctx context.Context, network Network) {
+	if c.config.PeerDisconnectInterval == 0 {
+		return
+	}
+	ticker := time.NewTicker(c.config.PeerDisconnectInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.disconnectRandomPeer(network)
+		}
+	}
+}
+
+func (c *Chaos) disconnectRandomPeer(network Network) {
+	peers := network.Neighbors()
+	if len(peers) == 0 {
+		return
+	}
+	peerID := peers[c.intn(len(peers))]
+	logger := log.WithField("peerID", peerID.String())
+	if err := network.Disconnect(peerID); err != nil {
+		logger.WithError(err).Warn("chaos: could not disconnect peer")
+		return
+	}
+	logger.Info("chaos: disconnected peer")
+}
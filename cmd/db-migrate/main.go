@@ -0,0 +1,110 @@
+// +build !js
+
+// package db-migrate is an executable that can be used to migrate the
+// database used internally by 0x Mesh to a new location or to a different
+// storage backend (see TARGET_BACKEND).
+// Migrations can safely be interrupted and will resume where they left off
+// when run again.
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/0xProject/0x-mesh/db"
+	"github.com/plaid/go-envvar/envvar"
+)
+
+type envVars struct {
+	// DatabaseDir is the directory where the existing database files are
+	// persisted.
+	DatabaseDir string `envvar:"DATABASE_DIR" default:"0x_mesh/db"`
+	// TargetBackend is the storage backend of the migrated database. Either
+	// "leveldb" or "sqlite". The sqlite backend requires a binary built with
+	// CGO_ENABLED=1.
+	TargetBackend string `envvar:"TARGET_BACKEND" default:"leveldb"`
+	// TargetDatabaseDir is the directory where the migrated database files will
+	// be written. The sqlite backend writes a single mesh.sqlite file.
+	TargetDatabaseDir string `envvar:"TARGET_DATABASE_DIR"`
+	// BatchSize is the number of keys written to the target in a single batch.
+	BatchSize int `envvar:"BATCH_SIZE" default:"1000"`
+	// SkipVerification can be set to true to skip the verification pass after
+	// all data has been copied.
+	SkipVerification bool `envvar:"SKIP_VERIFICATION" default:"false"`
+}
+
+func main() {
+	env := envVars{}
+	if err := envvar.Parse(&env); err != nil {
+		log.Fatal(err)
+	}
+	// run returns errors instead of calling log.Fatal so that the deferred
+	// calls which close the databases are run first.
+	if err := run(env); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(env envVars) error {
+	source, err := db.Open(env.DatabaseDir)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	target, closeTarget, err := openTarget(env.TargetBackend, env.TargetDatabaseDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := closeTarget(); err != nil {
+			log.Printf("Could not close target database: %s", err)
+		}
+	}()
+
+	checkpoint, err := target.Checkpoint()
+	if err != nil {
+		return err
+	}
+	if checkpoint != nil {
+		log.Printf("Resuming migration after key %s", checkpoint)
+	}
+	progress, err := source.MigrateTo(target, db.MigrationOptions{
+		BatchSize: env.BatchSize,
+		OnProgress: func(progress db.MigrationProgress) {
+			log.Printf("Migrated %d keys (%d bytes)", progress.KeysMigrated, progress.BytesMigrated)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Finished copying %d keys (%d bytes)", progress.KeysMigrated, progress.BytesMigrated)
+
+	if !env.SkipVerification {
+		if err := source.VerifyMigration(target); err != nil {
+			return err
+		}
+		log.Print("Verification passed ✓")
+	}
+	if err := target.ClearCheckpoint(); err != nil {
+		return err
+	}
+	log.Print("Migration complete ✓")
+	return nil
+}
+
+// openTarget opens the migration target for the given backend. The returned
+// function closes the target.
+func openTarget(backend string, dir string) (db.MigrationTarget, func() error, error) {
+	switch backend {
+	case "leveldb":
+		dest, err := db.Open(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		return db.NewLevelDBMigrationTarget(dest), dest.Close, nil
+	case "sqlite":
+		return openSQLiteTarget(dir)
+	default:
+		return nil, nil, fmt.Errorf("unsupported TARGET_BACKEND: %q (must be \"leveldb\" or \"sqlite\")", backend)
+	}
+}
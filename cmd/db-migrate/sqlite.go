@@ -0,0 +1,22 @@
+// +build !js,cgo
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/0xProject/0x-mesh/db"
+)
+
+// openSQLiteTarget opens the SQLite migration target in the given directory.
+func openSQLiteTarget(dir string) (db.MigrationTarget, func() error, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, nil, err
+	}
+	target, err := db.NewSQLiteMigrationTarget(filepath.Join(dir, "mesh.sqlite"))
+	if err != nil {
+		return nil, nil, err
+	}
+	return target, target.Close, nil
+}
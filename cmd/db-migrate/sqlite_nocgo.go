@@ -0,0 +1,14 @@
+// +build !js,!cgo
+
+package main
+
+import (
+	"errors"
+
+	"github.com/0xProject/0x-mesh/db"
+)
+
+// openSQLiteTarget returns an error because the sqlite3 driver requires cgo.
+func openSQLiteTarget(dir string) (db.MigrationTarget, func() error, error) {
+	return nil, nil, errors.New("TARGET_BACKEND \"sqlite\" is not supported by binaries built with CGO_ENABLED=0")
+}
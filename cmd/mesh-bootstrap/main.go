@@ -12,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/0xProject/0x-mesh/keys"
 	"github.com/0xProject/0x-mesh/loghooks"
 	"github.com/0xProject/0x-mesh/p2p"
 	"github.com/0xProject/0x-mesh/p2p/banner"
@@ -31,6 +32,7 @@ import (
 	dhtopts "github.com/libp2p/go-libp2p-kad-dht/opts"
 	peerstore "github.com/libp2p/go-libp2p-peerstore"
 	"github.com/libp2p/go-libp2p-peerstore/pstoreds"
+	pnet "github.com/libp2p/go-libp2p-pnet"
 	"github.com/libp2p/go-libp2p/p2p/host/relay"
 	filter "github.com/libp2p/go-maddr-filter"
 	ma "github.com/multiformats/go-multiaddr"
@@ -122,6 +124,10 @@ type Config struct {
 	// allowed to send before failing the bandwidth check. Defaults to 1 MiB, which
 	// is roughly 100x expected usage based on real world measurements.
 	MaxBytesPerSecond float64 `envvar:"MAX_BYTES_PER_SECOND" default:"1048576"`
+	// P2PPrivateNetworkKeyPath is the path of the pre-shared key file of a
+	// private network. If set, the bootstrap node only connects to peers which
+	// hold the same key.
+	P2PPrivateNetworkKeyPath string `envvar:"P2P_PRIVATE_NETWORK_KEY_PATH" default:""`
 }
 
 func init() {
@@ -253,6 +259,17 @@ func main() {
 		p2p.Filters(filters),
 	}
 
+	if config.P2PPrivateNetworkKeyPath != "" {
+		psk, err := keys.GetPSKFromPath(config.P2PPrivateNetworkKeyPath)
+		if err != nil {
+			log.WithField("error", err).Fatal("could not load private network key")
+		}
+		protector, err := pnet.NewV1ProtectorFromBytes(psk)
+		if err != nil {
+			log.WithField("error", err).Fatal("could not create private network protector")
+		}
+		opts = append(opts, libp2p.PrivateNetwork(protector))
+	}
 	if config.EnableRelayHost {
 		opts = append(opts, libp2p.EnableRelay(circuit.OptHop))
 	} else {
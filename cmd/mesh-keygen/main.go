@@ -1,6 +1,7 @@
 // +build !js
 
-// mesh-keygen is a short program that can be used to generate private keys.
+// mesh-keygen is a short program that can be used to generate private keys
+// and the pre-shared keys of private networks.
 package main
 
 import (
@@ -14,6 +15,10 @@ import (
 type envVars struct {
 	// PrivateKeyPath is the path where the private key will be written.
 	PrivateKeyPath string `envvar:"PRIVATE_KEY_PATH" default:"0x_mesh/keys/privkey"`
+	// PrivateNetworkKeyPath is the path where the pre-shared key of a private
+	// network will be written. If set, a pre-shared key is generated instead
+	// of a private key.
+	PrivateNetworkKeyPath string `envvar:"PRIVATE_NETWORK_KEY_PATH" default:""`
 }
 
 func main() {
@@ -21,6 +26,15 @@ func main() {
 	if err := envvar.Parse(&env); err != nil {
 		log.Fatal(err)
 	}
+	if env.PrivateNetworkKeyPath != "" {
+		if _, err := os.Stat(env.PrivateNetworkKeyPath); !os.IsNotExist(err) {
+			log.Fatalf("Key file: %s already exists. If you really want to overwrite it, delete the file and try again.", env.PrivateNetworkKeyPath)
+		}
+		if _, err := keys.GenerateAndSavePSK(env.PrivateNetworkKeyPath); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	if _, err := os.Stat(env.PrivateKeyPath); !os.IsNotExist(err) {
 		log.Fatalf("Key file: %s already exists. If you really want to overwrite it, delete the file and try again.", env.PrivateKeyPath)
 	}
@@ -0,0 +1,285 @@
+// +build !js
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	log "github.com/sirupsen/logrus"
+)
+
+// Names of the invariants which are checked.
+const (
+	invariantExpiredNotFillable = "no order is both EXPIRED and FILLABLE"
+	invariantSequenceMonotonic  = "order event sequence numbers are strictly increasing"
+	invariantBooksConverge      = "the order books of all nodes converge"
+	invariantNodeRunning        = "nodes keep running"
+)
+
+// orderEventsBufferSize is the size of the channel order events of a node are
+// received on.
+const orderEventsBufferSize = 1024
+
+// fillableEndStates are the end states of order events after which an order
+// is fillable.
+var fillableEndStates = map[zeroex.OrderEventEndState]struct{}{
+	zeroex.ESOrderAdded:                {},
+	zeroex.ESOrderFilled:               {},
+	zeroex.ESOrderFillabilityIncreased: {},
+}
+
+// nodeState is what the invariant checker knows about a node from its order
+// events.
+type nodeState struct {
+	lastSequenceNumber uint64
+	// lastEndStates are the end states of the last event of every order.
+	lastEndStates map[common.Hash]zeroex.OrderEventEndState
+}
+
+// invariantChecker checks the invariants which must hold for every node and
+// counts the violations.
+type invariantChecker struct {
+	mu         sync.Mutex
+	failFast   bool
+	violations map[string]int
+	nodeStates map[int]*nodeState
+}
+
+func newInvariantChecker(failFast bool) *invariantChecker {
+	return &invariantChecker{
+		failFast:   failFast,
+		violations: map[string]int{},
+		nodeStates: map[int]*nodeState{},
+	}
+}
+
+// violation reports a violation of the given invariant. c.mu must not be
+// locked.
+func (c *invariantChecker) violation(invariant string, fields log.Fields) {
+	c.mu.Lock()
+	c.violations[invariant]++
+	c.mu.Unlock()
+	fields["invariant"] = invariant
+	if c.failFast {
+		log.WithFields(fields).Fatal("invariant violated")
+	}
+	log.WithFields(fields).Error("invariant violated")
+}
+
+// numViolations returns the number of violations of every invariant.
+func (c *invariantChecker) numViolations() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	numViolations := make(map[string]int, len(c.violations))
+	for invariant, count := range c.violations {
+		numViolations[invariant] = count
+	}
+	return numViolations
+}
+
+// watchNode checks the order events of the given node until ctx is
+// cancelled. It also reports a violation if the node exits.
+func (c *invariantChecker) watchNode(ctx context.Context, n *node) error {
+	c.mu.Lock()
+	c.nodeStates[n.id] = &nodeState{
+		lastEndStates: map[common.Hash]zeroex.OrderEventEndState{},
+	}
+	c.mu.Unlock()
+	orderEventsChan := make(chan []*zeroex.OrderEvent, orderEventsBufferSize)
+	subscription, err := n.client.SubscribeToOrders(ctx, orderEventsChan)
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer subscription.Unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-n.exited:
+				if ctx.Err() == nil {
+					c.violation(invariantNodeRunning, log.Fields{"node": n.id, "error": fmt.Sprint(n.exitErr)})
+				}
+				return
+			case err := <-subscription.Err():
+				if ctx.Err() == nil {
+					log.WithError(err).WithField("node", n.id).Error("order event subscription failed")
+				}
+				return
+			case orderEvents := <-orderEventsChan:
+				for _, orderEvent := range orderEvents {
+					c.checkOrderEvent(n.id, orderEvent)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// checkOrderEvent checks the invariants for an order event of the given
+// node.
+func (c *invariantChecker) checkOrderEvent(nodeID int, orderEvent *zeroex.OrderEvent) {
+	c.mu.Lock()
+	state := c.nodeStates[nodeID]
+	lastSequenceNumber := state.lastSequenceNumber
+	state.lastSequenceNumber = orderEvent.SequenceNumber
+	lastEndState, found := state.lastEndStates[orderEvent.OrderHash]
+	state.lastEndStates[orderEvent.OrderHash] = orderEvent.EndState
+	c.mu.Unlock()
+
+	fields := log.Fields{
+		"node":           nodeID,
+		"orderHash":      orderEvent.OrderHash.Hex(),
+		"endState":       orderEvent.EndState,
+		"sequenceNumber": orderEvent.SequenceNumber,
+	}
+	if orderEvent.SequenceNumber <= lastSequenceNumber {
+		fields["lastSequenceNumber"] = lastSequenceNumber
+		c.violation(invariantSequenceMonotonic, fields)
+	} else if lastSequenceNumber != 0 && orderEvent.SequenceNumber != lastSequenceNumber+1 {
+		// The subscription may drop events if it falls behind, so gaps are
+		// not a violation.
+		log.WithFields(fields).WithField("lastSequenceNumber", lastSequenceNumber).Warn("missed order events")
+	}
+	if !found || lastEndState != zeroex.ESOrderExpired {
+		return
+	}
+	if _, fillable := fillableEndStates[orderEvent.EndState]; fillable {
+		// Only an UNEXPIRED event (caused by a block reorg) can make an
+		// expired order fillable again.
+		fields["lastEndState"] = lastEndState
+		c.violation(invariantExpiredNotFillable, fields)
+	}
+}
+
+// bookSnapshot is the order book of a node at a point in time.
+type bookSnapshot struct {
+	latestBlockNumber int
+	orders            map[common.Hash]*big.Int
+}
+
+// checkConvergence waits until all nodes have processed the latest block and
+// store the same orders, and reports a violation if that doesn't happen
+// within the given timeout. Traffic must be paused. Once the books converged,
+// it also checks that none of the stored orders are expired.
+func (c *invariantChecker) checkConvergence(parentCtx context.Context, nodes []*node, ethClient *ethclient.Client, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(parentCtx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		header, err := ethClient.HeaderByNumber(context.Background(), nil)
+		if err != nil {
+			return err
+		}
+		snapshots, err := takeBookSnapshots(nodes)
+		if err != nil {
+			return err
+		}
+		if booksConverged(snapshots, int(header.Number.Int64())) {
+			c.checkNoExpiredOrders(nodes, snapshots, int64(header.Time))
+			log.WithFields(log.Fields{
+				"blockNumber": header.Number,
+				"numOrders":   len(snapshots[0].orders),
+			}).Info("order books converged")
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			if parentCtx.Err() != nil {
+				return parentCtx.Err()
+			}
+			fields := log.Fields{"timeout": timeout.String()}
+			for i, snapshot := range snapshots {
+				fields[fmt.Sprintf("node%dLatestBlock", nodes[i].id)] = snapshot.latestBlockNumber
+				fields[fmt.Sprintf("node%dNumOrders", nodes[i].id)] = len(snapshot.orders)
+				fields[fmt.Sprintf("node%dNumDifferentOrders", nodes[i].id)] = numDifferentOrders(snapshots[0], snapshot)
+			}
+			c.violation(invariantBooksConverge, fields)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// takeBookSnapshots returns the order books of the given nodes.
+func takeBookSnapshots(nodes []*node) ([]*bookSnapshot, error) {
+	snapshots := make([]*bookSnapshot, len(nodes))
+	for i, n := range nodes {
+		stats, err := n.client.GetStats()
+		if err != nil {
+			return nil, err
+		}
+		orderInfos, err := n.getOrders()
+		if err != nil {
+			return nil, err
+		}
+		snapshot := &bookSnapshot{
+			latestBlockNumber: stats.LatestBlock.Number,
+			orders:            make(map[common.Hash]*big.Int, len(orderInfos)),
+		}
+		for _, orderInfo := range orderInfos {
+			snapshot.orders[orderInfo.OrderHash] = orderInfo.SignedOrder.ExpirationTimeSeconds
+		}
+		snapshots[i] = snapshot
+	}
+	return snapshots, nil
+}
+
+// booksConverged returns true if all nodes processed the given block and
+// store the same orders.
+func booksConverged(snapshots []*bookSnapshot, blockNumber int) bool {
+	for _, snapshot := range snapshots {
+		if snapshot.latestBlockNumber != blockNumber || numDifferentOrders(snapshots[0], snapshot) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// numDifferentOrders returns the number of orders which are only stored by
+// one of the given nodes.
+func numDifferentOrders(a, b *bookSnapshot) int {
+	numDifferent := 0
+	for orderHash := range a.orders {
+		if _, found := b.orders[orderHash]; !found {
+			numDifferent++
+		}
+	}
+	for orderHash := range b.orders {
+		if _, found := a.orders[orderHash]; !found {
+			numDifferent++
+		}
+	}
+	return numDifferent
+}
+
+// checkNoExpiredOrders reports a violation for every stored order which
+// expired as of the given block timestamp or whose last order event was
+// EXPIRED. Orders stored by a node are considered fillable.
+func (c *invariantChecker) checkNoExpiredOrders(nodes []*node, snapshots []*bookSnapshot, blockTimestamp int64) {
+	for i, snapshot := range snapshots {
+		for orderHash, expirationTimeSeconds := range snapshot.orders {
+			c.mu.Lock()
+			lastEndState := c.nodeStates[nodes[i].id].lastEndStates[orderHash]
+			c.mu.Unlock()
+			if expirationTimeSeconds.Int64() > blockTimestamp && lastEndState != zeroex.ESOrderExpired {
+				continue
+			}
+			c.violation(invariantExpiredNotFillable, log.Fields{
+				"node":                  nodes[i].id,
+				"orderHash":             orderHash.Hex(),
+				"expirationTimeSeconds": expirationTimeSeconds,
+				"blockTimestamp":        blockTimestamp,
+				"lastEndState":          lastEndState,
+			})
+		}
+	}
+}
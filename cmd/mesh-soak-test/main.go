@@ -0,0 +1,217 @@
+// +build !js
+
+// mesh-soak-test is a long-running soak test for 0x Mesh. It runs a local
+// network of Mesh nodes against Ganache for hours, generates randomized order
+// traffic and chain events (fills, cancellations and time jumps), and
+// continuously checks invariants which must hold for every node:
+//
+//   - no order is both EXPIRED and FILLABLE
+//   - order event sequence numbers are strictly increasing
+//   - the order books of all nodes converge once traffic is paused
+//
+// It is meant to catch state-machine regressions before a release. It exits
+// with a non-zero status if any invariant was violated.
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/0xProject/0x-mesh/ethereum"
+	"github.com/ethereum/go-ethereum/ethclient"
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/plaid/go-envvar/envvar"
+	log "github.com/sirupsen/logrus"
+)
+
+type envVars struct {
+	// MeshBinary is the mesh executable to run the nodes with. Use
+	// `make mesh` to install the current version.
+	MeshBinary string `envvar:"MESH_BINARY" default:"mesh"`
+	// NumNodes is the number of nodes in the network.
+	NumNodes int `envvar:"NUM_NODES" default:"3"`
+	// Duration is how long to run the soak test for.
+	Duration time.Duration `envvar:"DURATION" default:"4h"`
+	// EthereumRPCURL is the URL of the Ganache node to use.
+	EthereumRPCURL string `envvar:"ETHEREUM_RPC_URL" default:"http://localhost:8545"`
+	// EthereumChainID is the chain ID of the Ganache node.
+	EthereumChainID int `envvar:"ETHEREUM_CHAIN_ID" default:"1337"`
+	// DataDirPrefix is the prefix of the data directories of the nodes. The
+	// id of the node is appended to it, and the output of the node is written
+	// to the data directory with ".log" appended. The data directories are
+	// removed when the soak test starts.
+	DataDirPrefix string `envvar:"DATA_DIR_PREFIX" default:"/tmp/mesh-soak-test/node_"`
+	// BasePort is the first of the ports used by the nodes. Every node uses
+	// four consecutive ports for its RPC APIs and P2P transports.
+	BasePort int `envvar:"BASE_PORT" default:"61000"`
+	// NodeVerbosity is the logging verbosity of the nodes.
+	NodeVerbosity int `envvar:"NODE_VERBOSITY" default:"4"`
+	// NodeBlockPollingInterval is the block polling interval of the nodes.
+	NodeBlockPollingInterval time.Duration `envvar:"NODE_BLOCK_POLLING_INTERVAL" default:"1s"`
+	// Seed is the seed of the random traffic. If it is 0, a seed based on the
+	// current time is used. The seed is logged, so that a run can be repeated
+	// with the same traffic (although timing still differs).
+	Seed int64 `envvar:"SEED" default:"0"`
+	// OrderInterval is the interval at which orders are added to a random
+	// node.
+	OrderInterval time.Duration `envvar:"ORDER_INTERVAL" default:"500ms"`
+	// ChainEventInterval is the interval at which a random order is filled or
+	// cancelled or the chain is moved forward in time.
+	ChainEventInterval time.Duration `envvar:"CHAIN_EVENT_INTERVAL" default:"5s"`
+	// MaxOrderLifetime is the maximum time until a generated order expires.
+	// Time jumps are up to a quarter of it. It must be at least one minute.
+	MaxOrderLifetime time.Duration `envvar:"MAX_ORDER_LIFETIME" default:"30m"`
+	// ConvergenceCheckInterval is the interval at which traffic is paused to
+	// check that the order books of all nodes converge.
+	ConvergenceCheckInterval time.Duration `envvar:"CONVERGENCE_CHECK_INTERVAL" default:"5m"`
+	// ConvergenceTimeout is how long the order books of all nodes may take to
+	// converge once traffic is paused.
+	ConvergenceTimeout time.Duration `envvar:"CONVERGENCE_TIMEOUT" default:"2m"`
+	// FailFast causes the soak test to exit on the first invariant violation
+	// instead of running for the full duration.
+	FailFast bool `envvar:"FAIL_FAST" default:"false"`
+	// Verbosity is the logging verbosity of the soak test itself.
+	Verbosity int `envvar:"VERBOSITY" default:"4"`
+}
+
+func main() {
+	env := envVars{}
+	if err := envvar.Parse(&env); err != nil {
+		log.Fatal(err)
+	}
+	if err := validateEnvVars(env); err != nil {
+		log.Fatal(err)
+	}
+	log.SetFormatter(&log.JSONFormatter{})
+	log.SetLevel(log.Level(env.Verbosity))
+	seed := env.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	log.WithFields(log.Fields{
+		"seed":     seed,
+		"numNodes": env.NumNodes,
+		"duration": env.Duration.String(),
+	}).Info("starting soak test")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		log.Info("received signal; stopping soak test early")
+		cancel()
+	}()
+
+	checker := newInvariantChecker(env.FailFast)
+	if err := run(ctx, env, seed, checker); err != nil {
+		log.WithError(err).Fatal("soak test failed")
+	}
+	numViolations := checker.numViolations()
+	if len(numViolations) > 0 {
+		log.WithField("violations", numViolations).Fatal("soak test finished with invariant violations")
+	}
+	log.Info("soak test finished without invariant violations")
+}
+
+func validateEnvVars(env envVars) error {
+	if env.NumNodes < 2 {
+		return errors.New("NUM_NODES must be at least 2")
+	}
+	if env.MaxOrderLifetime < minOrderLifetime {
+		return errors.New("MAX_ORDER_LIFETIME must be at least 1m")
+	}
+	if env.OrderInterval <= 0 || env.ChainEventInterval <= 0 || env.ConvergenceCheckInterval <= 0 {
+		return errors.New("ORDER_INTERVAL, CHAIN_EVENT_INTERVAL and CONVERGENCE_CHECK_INTERVAL must be positive")
+	}
+	return nil
+}
+
+// run starts the network and generates traffic until env.Duration has
+// elapsed or ctx is cancelled. It only returns an error if the soak test
+// could not be run; invariant violations are reported to checker.
+func run(ctx context.Context, env envVars, seed int64, checker *invariantChecker) error {
+	ethRPCClient, err := ethrpc.Dial(env.EthereumRPCURL)
+	if err != nil {
+		return err
+	}
+	ethClient := ethclient.NewClient(ethRPCClient)
+	contractAddresses, err := ethereum.NewContractAddressesForChainID(env.EthereumChainID)
+	if err != nil {
+		return err
+	}
+
+	// The nodes are killed when nodesCtx is cancelled, which happens after
+	// the final convergence check.
+	nodesCtx, cancelNodes := context.WithCancel(context.Background())
+	defer cancelNodes()
+	nodes := make([]*node, env.NumNodes)
+	for i := range nodes {
+		nodes[i], err = startNode(nodesCtx, env, i)
+		if err != nil {
+			return err
+		}
+		if err := checker.watchNode(nodesCtx, nodes[i]); err != nil {
+			return err
+		}
+	}
+	if err := connectNodes(nodes); err != nil {
+		return err
+	}
+
+	traffic, err := newTrafficGenerator(env, seed, nodes, ethRPCClient, contractAddresses)
+	if err != nil {
+		return err
+	}
+	if err := traffic.setupBalances(ctx); err != nil {
+		return err
+	}
+
+	runCtx, cancelRun := context.WithTimeout(ctx, env.Duration)
+	defer cancelRun()
+	go runPeriodically(runCtx, env.OrderInterval, "could not add order", traffic.addRandomOrder)
+	go runPeriodically(runCtx, env.ChainEventInterval, "could not create chain event", traffic.randomChainEvent)
+	convergenceTicker := time.NewTicker(env.ConvergenceCheckInterval)
+	defer convergenceTicker.Stop()
+	for {
+		select {
+		case <-runCtx.Done():
+			// Wait for the traffic to stop and check convergence one last
+			// time, unless the soak test was stopped early.
+			traffic.pause()
+			if ctx.Err() != nil {
+				return nil
+			}
+			return checker.checkConvergence(context.Background(), nodes, ethClient, env.ConvergenceTimeout)
+		case <-convergenceTicker.C:
+			traffic.pause()
+			err := checker.checkConvergence(runCtx, nodes, ethClient, env.ConvergenceTimeout)
+			traffic.resume()
+			if err != nil && runCtx.Err() == nil {
+				return err
+			}
+		}
+	}
+}
+
+// runPeriodically calls fn at the given interval until ctx is cancelled.
+// Errors are logged with the given message.
+func runPeriodically(ctx context.Context, interval time.Duration, errMessage string, fn func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := fn(ctx); err != nil && ctx.Err() == nil {
+				log.WithError(err).Warn(errMessage)
+			}
+		}
+	}
+}
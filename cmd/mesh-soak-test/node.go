@@ -0,0 +1,172 @@
+// +build !js
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/0xProject/0x-mesh/common/types"
+	"github.com/0xProject/0x-mesh/rpc"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	peerstore "github.com/libp2p/go-libp2p-peerstore"
+	ma "github.com/multiformats/go-multiaddr"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// portsPerNode is the number of ports reserved for every node, starting at
+	// env.BasePort + id*portsPerNode.
+	portsPerNode = 4
+	// nodeStartupTimeout is how long to wait for the RPC API of a node to
+	// become available.
+	nodeStartupTimeout = 30 * time.Second
+	// getOrdersPageSize is the page size used to fetch the orders of a node.
+	getOrdersPageSize = 500
+)
+
+// node is a Mesh node run as a child process of the soak test.
+type node struct {
+	id      int
+	client  *rpc.Client
+	peerID  peer.ID
+	p2pAddr ma.Multiaddr
+	// exited is closed when the process exits. exitErr is set before.
+	exited  chan struct{}
+	exitErr error
+}
+
+// startNode starts the Mesh node with the given id and waits until its RPC
+// API is available. The node is killed when ctx is cancelled. Its data
+// directory is removed first, so every run starts with empty databases.
+func startNode(ctx context.Context, env envVars, id int) (*node, error) {
+	basePort := env.BasePort + id*portsPerNode
+	wsRPCAddr := fmt.Sprintf("localhost:%d", basePort)
+	p2pTCPPort := basePort + 2
+	dataDir := env.DataDirPrefix + strconv.Itoa(id)
+	if err := os.RemoveAll(dataDir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dataDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	logFile, err := os.Create(dataDir + ".log")
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, env.MeshBinary)
+	cmd.Env = append(
+		os.Environ(),
+		"VERBOSITY="+strconv.Itoa(env.NodeVerbosity),
+		"DATA_DIR="+dataDir,
+		"ETHEREUM_RPC_URL="+env.EthereumRPCURL,
+		"ETHEREUM_CHAIN_ID="+strconv.Itoa(env.EthereumChainID),
+		"WS_RPC_ADDR="+wsRPCAddr,
+		"HTTP_RPC_ADDR="+fmt.Sprintf("localhost:%d", basePort+1),
+		"P2P_TCP_PORT="+strconv.Itoa(p2pTCPPort),
+		"P2P_WEBSOCKETS_PORT="+strconv.Itoa(basePort+3),
+		"USE_BOOTSTRAP_LIST=false",
+		"BLOCK_POLLING_INTERVAL="+env.NodeBlockPollingInterval.String(),
+		"ENABLE_ETHEREUM_RPC_RATE_LIMITING=false",
+	)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if err := cmd.Start(); err != nil {
+		_ = logFile.Close()
+		return nil, err
+	}
+	n := &node{
+		id:     id,
+		exited: make(chan struct{}),
+	}
+	go func() {
+		n.exitErr = cmd.Wait()
+		_ = logFile.Close()
+		close(n.exited)
+	}()
+
+	stats, err := n.waitForRPC(ctx, "ws://"+wsRPCAddr)
+	if err != nil {
+		return nil, fmt.Errorf("node %d did not start (see %s.log): %s", id, dataDir, err.Error())
+	}
+	n.peerID, err = peer.IDB58Decode(stats.PeerID)
+	if err != nil {
+		return nil, err
+	}
+	n.p2pAddr, err = ma.NewMultiaddr(fmt.Sprintf("/ip4/127.0.0.1/tcp/%d", p2pTCPPort))
+	if err != nil {
+		return nil, err
+	}
+	log.WithFields(log.Fields{
+		"node":      id,
+		"peerID":    stats.PeerID,
+		"wsRPCAddr": wsRPCAddr,
+	}).Info("started node")
+	return n, nil
+}
+
+// waitForRPC connects to the RPC API of the node, retrying until it is
+// available or nodeStartupTimeout has elapsed.
+func (n *node) waitForRPC(ctx context.Context, addr string) (*types.Stats, error) {
+	ctx, cancel := context.WithTimeout(ctx, nodeStartupTimeout)
+	defer cancel()
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-n.exited:
+			return nil, fmt.Errorf("process exited: %v", n.exitErr)
+		case <-ticker.C:
+		}
+		client, err := rpc.NewClient(addr)
+		if err != nil {
+			continue
+		}
+		stats, err := client.GetStats()
+		if err != nil {
+			continue
+		}
+		n.client = client
+		return stats, nil
+	}
+}
+
+// connectNodes connects every node to every other node.
+func connectNodes(nodes []*node) error {
+	for i, n := range nodes {
+		for _, other := range nodes[:i] {
+			peerInfo := peerstore.PeerInfo{
+				ID:    other.peerID,
+				Addrs: []ma.Multiaddr{other.p2pAddr},
+			}
+			if err := n.client.AddPeer(peerInfo); err != nil {
+				return fmt.Errorf("could not connect node %d to node %d: %s", n.id, other.id, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// getOrders returns all orders stored by the node.
+func (n *node) getOrders() ([]*types.OrderInfo, error) {
+	orders := []*types.OrderInfo{}
+	snapshotID := ""
+	for page := 0; ; page++ {
+		response, err := n.client.GetOrders(page, getOrdersPageSize, snapshotID)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, response.OrdersInfos...)
+		if len(response.OrdersInfos) < getOrdersPageSize {
+			return orders, nil
+		}
+		snapshotID = response.SnapshotID
+	}
+}
@@ -0,0 +1,367 @@
+// +build !js
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/ethereum"
+	"github.com/0xProject/0x-mesh/ethereum/signer"
+	"github.com/0xProject/0x-mesh/ethereum/wrappers"
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// txMiningTimeout is how long to wait for a transaction to be mined.
+	txMiningTimeout = 10 * time.Second
+	// maxAssetAmount is the maximum maker and taker asset amount of the
+	// generated orders (in base units).
+	maxAssetAmount = 1000000
+	// minOrderLifetime is the minimum time until a generated order expires,
+	// relative to the timestamp of the latest block.
+	minOrderLifetime = time.Minute
+	// minTimeJump is the minimum amount of time the chain is moved forward by
+	// a time jump.
+	minTimeJump = 10 * time.Second
+)
+
+var (
+	// makerAddress creates all orders and takerAddress fills them.
+	makerAddress = constants.GanacheAccount1
+	takerAddress = constants.GanacheAccount2
+	// zrxCoinbase holds the ZRX supply of Ganache.
+	zrxCoinbase = constants.GanacheAccount0
+	// makerZRXAmount and takerWETHAmount are the balances and allowances set up
+	// before generating traffic. They are large enough for many hours of
+	// orders with amounts of up to maxAssetAmount.
+	makerZRXAmount  = new(big.Int).Exp(big.NewInt(10), big.NewInt(24), nil)
+	takerWETHAmount = new(big.Int).Exp(big.NewInt(10), big.NewInt(19), nil)
+	// protocolFee is sent along with every fill to pay the protocol fee.
+	protocolFee = big.NewInt(100000000000000000)
+)
+
+// trafficGenerator adds randomized orders to the nodes and creates chain
+// events (fills, cancellations and time jumps) which affect them.
+type trafficGenerator struct {
+	// pauseMu is held for reading while generating traffic, so that
+	// pause can wait for the traffic to stop.
+	pauseMu sync.RWMutex
+	// mu guards rand and orders.
+	mu   sync.Mutex
+	rand *rand.Rand
+	// orders are the orders which were accepted by some node and might still
+	// be fillable. They are candidates for fills and cancellations.
+	orders            map[common.Hash]*zeroex.SignedOrder
+	nodes             []*node
+	ethRPCClient      *ethrpc.Client
+	ethClient         *ethclient.Client
+	exchange          *wrappers.Exchange
+	contractAddresses ethereum.ContractAddresses
+	chainID           int
+	maxOrderLifetime  time.Duration
+	makerAssetData    []byte
+	takerAssetData    []byte
+}
+
+func newTrafficGenerator(env envVars, seed int64, nodes []*node, ethRPCClient *ethrpc.Client, contractAddresses ethereum.ContractAddresses) (*trafficGenerator, error) {
+	ethClient := ethclient.NewClient(ethRPCClient)
+	exchange, err := wrappers.NewExchange(contractAddresses.Exchange, ethClient)
+	if err != nil {
+		return nil, err
+	}
+	return &trafficGenerator{
+		rand:              rand.New(rand.NewSource(seed)),
+		orders:            map[common.Hash]*zeroex.SignedOrder{},
+		nodes:             nodes,
+		ethRPCClient:      ethRPCClient,
+		ethClient:         ethClient,
+		exchange:          exchange,
+		contractAddresses: contractAddresses,
+		chainID:           env.EthereumChainID,
+		maxOrderLifetime:  env.MaxOrderLifetime,
+		makerAssetData:    erc20AssetData(contractAddresses.ZRXToken),
+		takerAssetData:    erc20AssetData(contractAddresses.WETH9),
+	}, nil
+}
+
+// erc20AssetData returns the assetData for the given ERC20 token.
+func erc20AssetData(tokenAddress common.Address) []byte {
+	return common.Hex2Bytes(zeroex.ERC20AssetDataID + "000000000000000000000000" + tokenAddress.Hex()[2:])
+}
+
+// setupBalances gives the maker ZRX and the taker WETH and sets the
+// allowances of the ERC20Proxy, so that the generated orders are fillable.
+func (g *trafficGenerator) setupBalances(ctx context.Context) error {
+	zrx, err := wrappers.NewZRXToken(g.contractAddresses.ZRXToken, g.ethClient)
+	if err != nil {
+		return err
+	}
+	txn, err := zrx.Transfer(transactOpts(zrxCoinbase), makerAddress, makerZRXAmount)
+	if err != nil {
+		return err
+	}
+	if err := g.waitMined(ctx, txn); err != nil {
+		return err
+	}
+	txn, err = zrx.Approve(transactOpts(makerAddress), g.contractAddresses.ERC20Proxy, makerZRXAmount)
+	if err != nil {
+		return err
+	}
+	if err := g.waitMined(ctx, txn); err != nil {
+		return err
+	}
+
+	weth9, err := wrappers.NewWETH9(g.contractAddresses.WETH9, g.ethClient)
+	if err != nil {
+		return err
+	}
+	opts := transactOpts(takerAddress)
+	opts.Value = takerWETHAmount
+	txn, err = weth9.Deposit(opts)
+	if err != nil {
+		return err
+	}
+	if err := g.waitMined(ctx, txn); err != nil {
+		return err
+	}
+	txn, err = weth9.Approve(transactOpts(takerAddress), g.contractAddresses.ERC20Proxy, takerWETHAmount)
+	if err != nil {
+		return err
+	}
+	return g.waitMined(ctx, txn)
+}
+
+// transactOpts returns the options for sending a transaction from the given
+// Ganache account.
+func transactOpts(from common.Address) *bind.TransactOpts {
+	return &bind.TransactOpts{
+		From: from,
+		Signer: func(s ethtypes.Signer, address common.Address, tx *ethtypes.Transaction) (*ethtypes.Transaction, error) {
+			testSigner := signer.NewTestSigner()
+			signature, err := testSigner.(*signer.TestSigner).SignTx(s.Hash(tx).Bytes(), from)
+			if err != nil {
+				return nil, err
+			}
+			return tx.WithSignature(s, signature)
+		},
+	}
+}
+
+// waitMined waits for the given transaction to be mined. Reverted
+// transactions are not considered an error, since fills and cancellations
+// of orders which are no longer fillable are expected to revert.
+func (g *trafficGenerator) waitMined(ctx context.Context, txn *ethtypes.Transaction) error {
+	ctx, cancel := context.WithTimeout(ctx, txMiningTimeout)
+	defer cancel()
+	receipt, err := bind.WaitMined(ctx, g.ethClient, txn)
+	if err != nil {
+		return err
+	}
+	if receipt.Status != ethtypes.ReceiptStatusSuccessful {
+		log.WithField("txHash", txn.Hash().Hex()).Debug("transaction reverted")
+	}
+	return nil
+}
+
+// pause stops the traffic once the current action is done. It is resumed
+// with resume.
+func (g *trafficGenerator) pause() {
+	g.pauseMu.Lock()
+}
+
+func (g *trafficGenerator) resume() {
+	g.pauseMu.Unlock()
+}
+
+// randomInt64 returns a random number in [min, max].
+func (g *trafficGenerator) randomInt64(min, max int64) int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return min + g.rand.Int63n(max-min+1)
+}
+
+// latestBlockTime returns the timestamp of the latest block. Since the chain
+// is moved forward by time jumps, it is used instead of the wall clock for
+// the expiration of orders.
+func (g *trafficGenerator) latestBlockTime(ctx context.Context) (time.Time, error) {
+	header, err := g.ethClient.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(header.Time), 0), nil
+}
+
+// addRandomOrder adds an order with random amounts and expiration time to a
+// random node.
+func (g *trafficGenerator) addRandomOrder(ctx context.Context) error {
+	g.pauseMu.RLock()
+	defer g.pauseMu.RUnlock()
+
+	blockTime, err := g.latestBlockTime(ctx)
+	if err != nil {
+		return err
+	}
+	lifetime := time.Duration(g.randomInt64(int64(minOrderLifetime), int64(g.maxOrderLifetime)))
+	order := &zeroex.Order{
+		ChainID:               big.NewInt(int64(g.chainID)),
+		MakerAddress:          makerAddress,
+		TakerAddress:          constants.NullAddress,
+		SenderAddress:         constants.NullAddress,
+		FeeRecipientAddress:   constants.NullAddress,
+		MakerAssetData:        g.makerAssetData,
+		MakerFeeAssetData:     constants.NullBytes,
+		TakerAssetData:        g.takerAssetData,
+		TakerFeeAssetData:     constants.NullBytes,
+		Salt:                  big.NewInt(g.randomInt64(0, 1<<62)),
+		MakerFee:              big.NewInt(0),
+		TakerFee:              big.NewInt(0),
+		MakerAssetAmount:      big.NewInt(g.randomInt64(1, maxAssetAmount)),
+		TakerAssetAmount:      big.NewInt(g.randomInt64(1, maxAssetAmount)),
+		ExpirationTimeSeconds: big.NewInt(blockTime.Add(lifetime).Unix()),
+		ExchangeAddress:       g.contractAddresses.Exchange,
+	}
+	signedOrder, err := zeroex.SignTestOrder(order)
+	if err != nil {
+		return err
+	}
+	n := g.nodes[g.randomInt64(0, int64(len(g.nodes)-1))]
+	results, err := n.client.AddOrders([]*zeroex.SignedOrder{signedOrder})
+	if err != nil {
+		return fmt.Errorf("could not add order to node %d: %s", n.id, err.Error())
+	}
+	for _, accepted := range results.Accepted {
+		g.mu.Lock()
+		g.orders[accepted.OrderHash] = signedOrder
+		g.mu.Unlock()
+		log.WithFields(log.Fields{
+			"node":      n.id,
+			"orderHash": accepted.OrderHash.Hex(),
+			"lifetime":  lifetime.String(),
+		}).Debug("added order")
+	}
+	for _, rejected := range results.Rejected {
+		log.WithFields(log.Fields{
+			"node":      n.id,
+			"orderHash": rejected.OrderHash.Hex(),
+			"status":    rejected.Status.Code,
+		}).Debug("order was rejected")
+	}
+	return nil
+}
+
+// randomChainEvent fills or cancels a random order or moves the chain
+// forward in time, which expires orders.
+func (g *trafficGenerator) randomChainEvent(ctx context.Context) error {
+	g.pauseMu.RLock()
+	defer g.pauseMu.RUnlock()
+
+	switch action := g.randomInt64(0, 9); {
+	case action < 4:
+		return g.fillRandomOrder(ctx)
+	case action < 6:
+		return g.cancelRandomOrder(ctx)
+	default:
+		return g.jumpForward(ctx)
+	}
+}
+
+// randomOrder returns a random order which might still be fillable, or nil if
+// there is none. Orders which expired are forgotten.
+func (g *trafficGenerator) randomOrder(blockTime time.Time) *zeroex.SignedOrder {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	candidates := []*zeroex.SignedOrder{}
+	for orderHash, order := range g.orders {
+		if order.ExpirationTimeSeconds.Int64() <= blockTime.Unix() {
+			delete(g.orders, orderHash)
+			continue
+		}
+		candidates = append(candidates, order)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	// Map iteration order is random, so sort to keep runs reproducible with
+	// the same seed.
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Salt.Cmp(candidates[j].Salt) == -1
+	})
+	return candidates[g.rand.Intn(len(candidates))]
+}
+
+func (g *trafficGenerator) fillRandomOrder(ctx context.Context) error {
+	blockTime, err := g.latestBlockTime(ctx)
+	if err != nil {
+		return err
+	}
+	order := g.randomOrder(blockTime)
+	if order == nil {
+		return nil
+	}
+	fillAmount := big.NewInt(g.randomInt64(1, order.TakerAssetAmount.Int64()))
+	opts := transactOpts(takerAddress)
+	opts.Value = protocolFee
+	txn, err := g.exchange.FillOrder(opts, order.Trim(), fillAmount, order.Signature)
+	if err != nil {
+		// Fills of orders which are no longer fillable fail during gas
+		// estimation.
+		log.WithError(err).Debug("could not fill order")
+		return nil
+	}
+	log.WithField("fillAmount", fillAmount).Debug("filling order")
+	return g.waitMined(ctx, txn)
+}
+
+func (g *trafficGenerator) cancelRandomOrder(ctx context.Context) error {
+	blockTime, err := g.latestBlockTime(ctx)
+	if err != nil {
+		return err
+	}
+	order := g.randomOrder(blockTime)
+	if order == nil {
+		return nil
+	}
+	orderHash, err := order.ComputeOrderHash()
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	delete(g.orders, orderHash)
+	g.mu.Unlock()
+	txn, err := g.exchange.CancelOrder(transactOpts(makerAddress), order.Trim())
+	if err != nil {
+		log.WithError(err).Debug("could not cancel order")
+		return nil
+	}
+	log.WithField("orderHash", orderHash.Hex()).Debug("cancelling order")
+	return g.waitMined(ctx, txn)
+}
+
+// jumpForward increases the time of the chain by a random duration and mines
+// a block, which expires the orders whose expiration time was skipped.
+func (g *trafficGenerator) jumpForward(ctx context.Context) error {
+	jump := time.Duration(g.randomInt64(int64(minTimeJump), int64(g.maxOrderLifetime/4)))
+	var newOffset interface{}
+	if err := g.ethRPCClient.CallContext(ctx, &newOffset, "evm_increaseTime", int64(jump.Seconds())); err != nil {
+		return err
+	}
+	var mined interface{}
+	if err := g.ethRPCClient.CallContext(ctx, &mined, "evm_mine"); err != nil {
+		return err
+	}
+	log.WithField("jump", jump.String()).Debug("moved chain forward in time")
+	return nil
+}
@@ -0,0 +1,47 @@
+// +build !js
+
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/0xProject/0x-mesh/core"
+	"github.com/0xProject/0x-mesh/ethereum/ratelimit"
+	"github.com/0xProject/0x-mesh/p2p/banner"
+	"github.com/0xProject/0x-mesh/rpc"
+)
+
+// apiError converts the errors returned by core.App which clients can do
+// something about into rpc.APIErrors. It returns nil for any other error,
+// which should be logged and reported as constants.ErrInternal.
+func apiError(err error) error {
+	switch err := err.(type) {
+	case core.ErrSubsystemDisabled:
+		return rpc.NewAPIError(rpc.ErrorCodeUnavailable, err).WithDetails(map[string]interface{}{
+			"subsystem": err.Subsystem,
+		})
+	case core.ErrSnapshotNotFound, core.ErrOrderNotFound, core.ErrOrderStateNotFound, core.ErrPeerNotBanned, core.ErrIPRangeNotBanned, core.ErrNotOnAllowlist:
+		return rpc.NewAPIError(rpc.ErrorCodeNotFound, err)
+	case core.ErrOrderEventsUnavailable:
+		// The client needs to re-sync all orders with GetOrders.
+		return rpc.NewAPIError(rpc.ErrorCodeNotFound, err).WithDetails(map[string]interface{}{
+			"resyncRequired": true,
+		})
+	case core.ErrPerPageZero, core.ErrInvalidOrderAnnotations, core.ErrInvalidEthereumRPCURL, core.ErrInvalidIPRange, core.ErrInvalidAllowlistEntry:
+		return rpc.NewAPIError(rpc.ErrorCodeInvalidParams, err)
+	}
+	switch {
+	case err == core.ErrMalformedOrderJSON, err == core.ErrInvalidOrderEventsLimit, err == core.ErrInvalidOrdersStreamChunkSize, err == banner.ErrProtectedIP:
+		return rpc.NewAPIError(rpc.ErrorCodeInvalidParams, err)
+	case err == core.ErrOrderStateHistoryDisabled:
+		return rpc.NewAPIError(rpc.ErrorCodeUnavailable, err)
+	case errors.Is(err, ratelimit.ErrTooManyRequestsIn24Hours), errors.Is(err, ratelimit.ErrSubsystemBudgetExceeded):
+		// The Ethereum RPC request budget is reset at the start of the next
+		// UTC day.
+		now := time.Now().UTC()
+		startOfNextUTCDay := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		return rpc.NewAPIError(rpc.ErrorCodeRateLimited, err).WithRetryAfter(startOfNextUTCDay.Sub(now))
+	}
+	return nil
+}
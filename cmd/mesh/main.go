@@ -1,3 +1,4 @@
+//go:build !js
 // +build !js
 
 // package mesh is a standalone 0x Mesh node that can be run from the command
@@ -8,7 +9,9 @@ package main
 import (
 	"context"
 	"os"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/0xProject/0x-mesh/core"
 	"github.com/0xProject/0x-mesh/rpc"
@@ -25,6 +28,47 @@ type standaloneConfig struct {
 	// HTTPRPCAddr is the interface and port to use for the JSON-RPC API over
 	// HTTP. By default, 0x Mesh will listen on localhost and port 60556.
 	HTTPRPCAddr string `envvar:"HTTP_RPC_ADDR" default:"localhost:60556"`
+	// EnableWSRPC determines whether to start the JSON-RPC API over WebSockets.
+	// It can be disabled for nodes which are only used to relay orders or when
+	// the node is embedded in a service with its own API.
+	EnableWSRPC bool `envvar:"ENABLE_WS_RPC" default:"true"`
+	// EnableHTTPRPC determines whether to start the JSON-RPC API over HTTP.
+	// Note that the HTTP health check is not available if it is disabled.
+	EnableHTTPRPC bool `envvar:"ENABLE_HTTP_RPC" default:"true"`
+	// RPCAllowedOrigins is a comma-separated list of origins (e.g.
+	// "https://app.example.com") from which browsers are allowed to use the
+	// JSON-RPC API over WebSockets and HTTP. "*" allows all origins. Clients
+	// which are not browsers don't send an origin and are not affected.
+	RPCAllowedOrigins string `envvar:"RPC_ALLOWED_ORIGINS" default:"*"`
+	// RPCAuthToken is a secret token that clients must include in every
+	// JSON-RPC request, either as a bearer token in the Authorization header or
+	// in the "token" query parameter (e.g. ws://localhost:60557?token=<token>).
+	// HTTP health checks don't require it. If empty, no token is required.
+	RPCAuthToken string `envvar:"RPC_AUTH_TOKEN" default:"" json:"-"`
+	// RPCAdminToken is a secret token that clients must include, in the same
+	// way as RPCAuthToken, to use the admin methods which change how the node
+	// operates (banning peers, changing the allowlist, removing and
+	// revalidating orders, terminating subscriptions and changing the Ethereum
+	// RPC endpoint). It must be different from RPCAuthToken. If empty, the
+	// admin methods are not available.
+	RPCAdminToken string `envvar:"RPC_ADMIN_TOKEN" default:"" json:"-"`
+	// RPCTimeout is how long the JSON-RPC API waits for mesh_addOrders,
+	// mesh_getOrders and mesh_revalidateOrders to return before it cancels
+	// them and returns a timeout error. 0 means they never time out.
+	RPCTimeout time.Duration `envvar:"RPC_TIMEOUT" default:"60s"`
+	// RPCMethodTimeouts is a comma-separated list of timeouts for specific
+	// methods which override RPCTimeout, e.g.
+	// "mesh_getOrders=30s,mesh_addOrders=2m".
+	RPCMethodTimeouts string `envvar:"RPC_METHOD_TIMEOUTS" default:""`
+	// RPCMaxSubscriptionsPerConnection is the maximum number of concurrent
+	// subscriptions (e.g. to order events) a single WebSocket connection can
+	// have. 0 means there is no limit.
+	RPCMaxSubscriptionsPerConnection int `envvar:"RPC_MAX_SUBSCRIPTIONS_PER_CONNECTION" default:"100"`
+	// RPCResponseCacheTTL is how long the responses of mesh_getOrders and
+	// mesh_getStats are cached, keyed by the method and its parameters. The
+	// cache is cleared whenever orders change. This protects public nodes from
+	// clients which repeatedly send the same requests. 0 disables the cache.
+	RPCResponseCacheTTL time.Duration `envvar:"RPC_RESPONSE_CACHE_TTL" default:"0s"`
 }
 
 func main() {
@@ -38,6 +82,25 @@ func main() {
 		log.WithField("error", err.Error()).Fatal("could not parse environment variables")
 	}
 
+	accessPolicy := rpc.AccessPolicy{
+		AllowedOrigins: parseAllowedOrigins(config.RPCAllowedOrigins),
+		AuthToken:      config.RPCAuthToken,
+		AdminToken:     config.RPCAdminToken,
+	}
+	methodTimeouts, err := rpc.ParseMethodTimeouts(config.RPCMethodTimeouts)
+	if err != nil {
+		log.WithField("error", err.Error()).Fatal("could not parse RPC_METHOD_TIMEOUTS")
+	}
+	timeouts := rpc.Timeouts{
+		Default: config.RPCTimeout,
+		Methods: methodTimeouts,
+	}
+	// The WS and HTTP servers share a registry so that subscriptions can be
+	// managed via either of them, and a response cache so that clients can't
+	// bypass it by switching between them.
+	subscriptions := rpc.NewSubscriptionRegistry(config.RPCMaxSubscriptionsPerConnection)
+	responseCache := rpc.NewResponseCache(config.RPCResponseCacheTTL)
+
 	// Start core.App.
 	app, err := core.New(coreConfig)
 	if err != nil {
@@ -60,43 +123,61 @@ func main() {
 		}
 	}()
 
-	// Start WS RPC server.
-	wsRPCErrChan := make(chan error, 1)
+	// Purge cached RPC responses whenever orders change so that clients are
+	// never served orders which are out of date.
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		log.WithField("ws_rpc_addr", config.WSRPCAddr).Info("starting WS RPC server")
-		rpcServer := instantiateServer(ctx, app, config.WSRPCAddr)
+		if err := responseCache.PurgeOnOrderEvents(ctx, app.SubscribeToOrderEvents); err != nil {
+			log.WithError(err).Error("RPC response cache stopped purging on order events")
+		}
+	}()
+
+	// Start WS RPC server.
+	wsRPCErrChan := make(chan error, 1)
+	if !config.EnableWSRPC {
+		log.Info("WS RPC server is disabled")
+	} else {
+		wg.Add(1)
 		go func() {
-			selectedRPCAddr, err := waitForSelectedAddress(ctx, rpcServer)
-			if err != nil {
-				log.WithError(err).Warn("WS RPC server did not start")
+			defer wg.Done()
+			log.WithField("ws_rpc_addr", config.WSRPCAddr).Info("starting WS RPC server")
+			rpcServer := instantiateServer(ctx, app, config.WSRPCAddr, accessPolicy, timeouts, subscriptions, responseCache)
+			go func() {
+				selectedRPCAddr, err := waitForSelectedAddress(ctx, rpcServer)
+				if err != nil {
+					log.WithError(err).Warn("WS RPC server did not start")
+				}
+				log.WithField("address", selectedRPCAddr).Info("started WS RPC server")
+			}()
+			if err := rpcServer.Listen(ctx, rpc.WSHandler); err != nil {
+				wsRPCErrChan <- err
 			}
-			log.WithField("address", selectedRPCAddr).Info("started WS RPC server")
 		}()
-		if err := rpcServer.Listen(ctx, rpc.WSHandler); err != nil {
-			wsRPCErrChan <- err
-		}
-	}()
+	}
 
 	// Start HTTP RPC server.
 	httpRPCErrChan := make(chan error, 1)
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		log.WithField("http_rpc_addr", config.HTTPRPCAddr).Info("starting HTTP RPC server")
-		rpcServer := instantiateServer(ctx, app, config.HTTPRPCAddr)
+	if !config.EnableHTTPRPC {
+		log.Info("HTTP RPC server is disabled")
+	} else {
+		wg.Add(1)
 		go func() {
-			selectedRPCAddr, err := waitForSelectedAddress(ctx, rpcServer)
-			if err != nil {
-				log.WithError(err).Warn("HTTP RPC server did not start")
+			defer wg.Done()
+			log.WithField("http_rpc_addr", config.HTTPRPCAddr).Info("starting HTTP RPC server")
+			rpcServer := instantiateServer(ctx, app, config.HTTPRPCAddr, accessPolicy, timeouts, subscriptions, responseCache)
+			go func() {
+				selectedRPCAddr, err := waitForSelectedAddress(ctx, rpcServer)
+				if err != nil {
+					log.WithError(err).Warn("HTTP RPC server did not start")
+				}
+				log.WithField("address", selectedRPCAddr).Info("started HTTP RPC server")
+			}()
+			if err := rpcServer.Listen(ctx, rpc.HTTPHandler); err != nil {
+				httpRPCErrChan <- err
 			}
-			log.WithField("address", selectedRPCAddr).Info("started HTTP RPC server")
 		}()
-		if err := rpcServer.Listen(ctx, rpc.HTTPHandler); err != nil {
-			httpRPCErrChan <- err
-		}
-	}()
+	}
 
 	// Block until there is an error or the app is closed.
 	select {
@@ -121,3 +202,15 @@ func main() {
 	wg.Wait()
 	os.Exit(1)
 }
+
+// parseAllowedOrigins parses the comma-separated list of allowed origins.
+func parseAllowedOrigins(allowedOrigins string) []string {
+	origins := []string{}
+	for _, origin := range strings.Split(allowedOrigins, ",") {
+		origin = strings.TrimSpace(origin)
+		if origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
@@ -7,18 +7,24 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
 	"net"
 	"runtime/debug"
 	"strings"
 	"time"
 
+	"github.com/0xProject/0x-mesh/common/requestid"
+	"github.com/0xProject/0x-mesh/common/supervisor"
 	"github.com/0xProject/0x-mesh/common/types"
 	"github.com/0xProject/0x-mesh/constants"
 	"github.com/0xProject/0x-mesh/core"
 	"github.com/0xProject/0x-mesh/rpc"
 	"github.com/0xProject/0x-mesh/zeroex"
 	"github.com/0xProject/0x-mesh/zeroex/ordervalidator"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/event"
 	ethrpc "github.com/ethereum/go-ethereum/rpc"
+	peer "github.com/libp2p/go-libp2p-core/peer"
 	peerstore "github.com/libp2p/go-libp2p-peerstore"
 	log "github.com/sirupsen/logrus"
 )
@@ -46,21 +52,21 @@ func waitForSelectedAddress(ctx context.Context, rpcServer *rpc.Server) (string,
 }
 
 // instantiateServer instantiates a new RPC server with the rpcHandler.
-func instantiateServer(ctx context.Context, app *core.App, rpcAddr string) *rpc.Server {
+func instantiateServer(ctx context.Context, app *core.App, rpcAddr string, accessPolicy rpc.AccessPolicy, timeouts rpc.Timeouts, subscriptions *rpc.SubscriptionRegistry, responseCache *rpc.ResponseCache) *rpc.Server {
 	// Initialize the JSON RPC WebSocket server (but don't start it yet).
 	rpcHandler := &rpcHandler{
 		app: app,
 		ctx: ctx,
 	}
-	rpcServer, err := rpc.NewServer(rpcAddr, rpcHandler)
+	rpcServer, err := rpc.NewServer(rpcAddr, rpcHandler, accessPolicy, timeouts, subscriptions, responseCache)
 	if err != nil {
-		return nil
+		log.WithField("error", err.Error()).Fatal("could not initialize RPC server")
 	}
 	return rpcServer
 }
 
 // GetOrders is called when an RPC client calls GetOrders.
-func (handler *rpcHandler) GetOrders(page, perPage int, snapshotID string) (result *types.GetOrdersResponse, err error) {
+func (handler *rpcHandler) GetOrders(ctx context.Context, page, perPage int, snapshotID string) (result *types.GetOrdersResponse, err error) {
 	log.WithFields(map[string]interface{}{
 		"page":       page,
 		"perPage":    perPage,
@@ -82,13 +88,14 @@ func (handler *rpcHandler) GetOrders(page, perPage int, snapshotID string) (resu
 			err = errors.New("method handler crashed in GetOrders RPC call (check logs for stack trace)")
 		}
 	}()
-	getOrdersResponse, err := handler.app.GetOrders(page, perPage, snapshotID)
+	getOrdersResponse, err := handler.app.GetOrders(ctx, page, perPage, snapshotID)
 	if err != nil {
-		if _, ok := err.(core.ErrSnapshotNotFound); ok {
-			return nil, err
+		if apiErr := apiError(err); apiErr != nil {
+			return nil, apiErr
 		}
-		if _, ok := err.(core.ErrPerPageZero); ok {
-			return nil, err
+		if ctx.Err() != nil {
+			// The request timed out or the client disconnected.
+			return nil, ctx.Err()
 		}
 		// We don't want to leak internal error details to the RPC client.
 		log.WithField("error", err.Error()).Error("internal error in GetOrders RPC call")
@@ -97,11 +104,42 @@ func (handler *rpcHandler) GetOrders(page, perPage int, snapshotID string) (resu
 	return getOrdersResponse, nil
 }
 
+// GetOrder is called when an RPC client calls GetOrder.
+func (handler *rpcHandler) GetOrder(orderHash common.Hash) (result *types.OrderInfo, err error) {
+	log.WithField("orderHash", orderHash.Hex()).Debug("received GetOrder request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "GetOrder",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in GetOrder RPC call (check logs for stack trace)")
+		}
+	}()
+	result, err = handler.app.GetOrder(orderHash)
+	if err != nil {
+		if apiErr := apiError(err); apiErr != nil {
+			return nil, apiErr
+		}
+		log.WithField("error", err.Error()).Error("internal error in GetOrder RPC call")
+		return nil, constants.ErrInternal
+	}
+	return result, nil
+}
+
 // AddOrders is called when an RPC client calls AddOrders.
-func (handler *rpcHandler) AddOrders(signedOrdersRaw []*json.RawMessage, opts types.AddOrdersOpts) (results *ordervalidator.ValidationResults, err error) {
+func (handler *rpcHandler) AddOrders(ctx context.Context, signedOrdersRaw []*json.RawMessage, opts types.AddOrdersOpts) (results *ordervalidator.ValidationResults, err error) {
 	log.WithFields(log.Fields{
-		"count":  len(signedOrdersRaw),
-		"pinned": opts.Pinned,
+		"count":     len(signedOrdersRaw),
+		"pinned":    opts.Pinned,
+		"requestID": opts.RequestID,
 	}).Info("received AddOrders request via RPC")
 	// Catch panics, log stack trace and return RPC error message
 	defer func() {
@@ -119,10 +157,20 @@ func (handler *rpcHandler) AddOrders(signedOrdersRaw []*json.RawMessage, opts ty
 			err = errors.New("method handler crashed in AddOrders RPC call (check logs for stack trace)")
 		}
 	}()
-	validationResults, err := handler.app.AddOrders(handler.ctx, signedOrdersRaw, opts.Pinned)
+	validationResults, err := handler.app.AddOrders(requestid.NewContext(ctx, opts.RequestID), signedOrdersRaw, opts.Pinned)
 	if err != nil {
+		if apiErr := apiError(err); apiErr != nil {
+			return nil, apiErr
+		}
+		if ctx.Err() != nil {
+			// The request timed out or the client disconnected.
+			return nil, ctx.Err()
+		}
 		// We don't want to leak internal error details to the RPC client.
-		log.WithField("error", err.Error()).Error("internal error in AddOrders RPC call")
+		log.WithFields(log.Fields{
+			"error":     err.Error(),
+			"requestID": opts.RequestID,
+		}).Error("internal error in AddOrders RPC call")
 		return nil, constants.ErrInternal
 	}
 	return validationResults, nil
@@ -148,6 +196,9 @@ func (handler *rpcHandler) AddPeer(peerInfo peerstore.PeerInfo) (err error) {
 		}
 	}()
 	if err := handler.app.AddPeer(peerInfo); err != nil {
+		if apiErr := apiError(err); apiErr != nil {
+			return apiErr
+		}
 		log.WithField("error", err.Error()).Error("internal error in AddPeer RPC call")
 		return constants.ErrInternal
 	}
@@ -181,9 +232,649 @@ func (handler *rpcHandler) GetStats() (result *types.Stats, err error) {
 	return getStatsResponse, nil
 }
 
+// GetStatsAndSnapshot is called when an RPC client calls GetStatsAndSnapshot.
+func (handler *rpcHandler) GetStatsAndSnapshot(ctx context.Context) (result *types.StatsAndSnapshot, err error) {
+	log.Debug("received GetStatsAndSnapshot request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "GetStatsAndSnapshot",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in GetStatsAndSnapshot RPC call (check logs for stack trace)")
+		}
+	}()
+	result, err = handler.app.GetStatsAndSnapshot(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			// The request timed out or the client disconnected.
+			return nil, ctx.Err()
+		}
+		log.WithField("error", err.Error()).Error("internal error in GetStatsAndSnapshot RPC call")
+		return nil, constants.ErrInternal
+	}
+	return result, nil
+}
+
+// BanPeer is called when an RPC client calls BanPeer,
+func (handler *rpcHandler) BanPeer(peerID peer.ID, reason string, duration time.Duration) (err error) {
+	log.Debug("received BanPeer request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "BanPeer",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in BanPeer RPC call (check logs for stack trace)")
+		}
+	}()
+	if err := handler.app.BanPeer(peerID, reason, duration); err != nil {
+		if apiErr := apiError(err); apiErr != nil {
+			return apiErr
+		}
+		log.WithField("error", err.Error()).Error("internal error in BanPeer RPC call")
+		return constants.ErrInternal
+	}
+	return nil
+}
+
+// UnbanPeer is called when an RPC client calls UnbanPeer,
+func (handler *rpcHandler) UnbanPeer(peerID peer.ID) (err error) {
+	log.Debug("received UnbanPeer request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "UnbanPeer",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in UnbanPeer RPC call (check logs for stack trace)")
+		}
+	}()
+	if err := handler.app.UnbanPeer(peerID); err != nil {
+		if apiErr := apiError(err); apiErr != nil {
+			return apiErr
+		}
+		log.WithField("error", err.Error()).Error("internal error in UnbanPeer RPC call")
+		return constants.ErrInternal
+	}
+	return nil
+}
+
+// GetPeerBans is called when an RPC client calls GetPeerBans,
+func (handler *rpcHandler) GetPeerBans() (result []*types.PeerBan, err error) {
+	log.Debug("received GetPeerBans request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "GetPeerBans",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in GetPeerBans RPC call (check logs for stack trace)")
+		}
+	}()
+	peerBans, err := handler.app.GetPeerBans()
+	if err != nil {
+		if apiErr := apiError(err); apiErr != nil {
+			return nil, apiErr
+		}
+		log.WithField("error", err.Error()).Error("internal error in GetPeerBans RPC call")
+		return nil, constants.ErrInternal
+	}
+	return peerBans, nil
+}
+
+// BanIPRange is called when an RPC client calls BanIPRange,
+func (handler *rpcHandler) BanIPRange(ipRange string, reason string) (err error) {
+	log.Debug("received BanIPRange request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "BanIPRange",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in BanIPRange RPC call (check logs for stack trace)")
+		}
+	}()
+	if err := handler.app.BanIPRange(ipRange, reason); err != nil {
+		if apiErr := apiError(err); apiErr != nil {
+			return apiErr
+		}
+		log.WithField("error", err.Error()).Error("internal error in BanIPRange RPC call")
+		return constants.ErrInternal
+	}
+	return nil
+}
+
+// UnbanIPRange is called when an RPC client calls UnbanIPRange,
+func (handler *rpcHandler) UnbanIPRange(ipRange string) (err error) {
+	log.Debug("received UnbanIPRange request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "UnbanIPRange",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in UnbanIPRange RPC call (check logs for stack trace)")
+		}
+	}()
+	if err := handler.app.UnbanIPRange(ipRange); err != nil {
+		if apiErr := apiError(err); apiErr != nil {
+			return apiErr
+		}
+		log.WithField("error", err.Error()).Error("internal error in UnbanIPRange RPC call")
+		return constants.ErrInternal
+	}
+	return nil
+}
+
+// GetIPRangeBans is called when an RPC client calls GetIPRangeBans,
+func (handler *rpcHandler) GetIPRangeBans() (result []*types.IPRangeBan, err error) {
+	log.Debug("received GetIPRangeBans request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "GetIPRangeBans",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in GetIPRangeBans RPC call (check logs for stack trace)")
+		}
+	}()
+	ipRangeBans, err := handler.app.GetIPRangeBans()
+	if err != nil {
+		if apiErr := apiError(err); apiErr != nil {
+			return nil, apiErr
+		}
+		log.WithField("error", err.Error()).Error("internal error in GetIPRangeBans RPC call")
+		return nil, constants.ErrInternal
+	}
+	return ipRangeBans, nil
+}
+
+// AddToAllowlist is called when an RPC client calls AddToAllowlist,
+func (handler *rpcHandler) AddToAllowlist(entry string) (err error) {
+	log.Debug("received AddToAllowlist request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "AddToAllowlist",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in AddToAllowlist RPC call (check logs for stack trace)")
+		}
+	}()
+	if err := handler.app.AddToAllowlist(entry); err != nil {
+		if apiErr := apiError(err); apiErr != nil {
+			return apiErr
+		}
+		log.WithField("error", err.Error()).Error("internal error in AddToAllowlist RPC call")
+		return constants.ErrInternal
+	}
+	return nil
+}
+
+// RemoveFromAllowlist is called when an RPC client calls RemoveFromAllowlist,
+func (handler *rpcHandler) RemoveFromAllowlist(entry string) (err error) {
+	log.Debug("received RemoveFromAllowlist request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "RemoveFromAllowlist",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in RemoveFromAllowlist RPC call (check logs for stack trace)")
+		}
+	}()
+	if err := handler.app.RemoveFromAllowlist(entry); err != nil {
+		if apiErr := apiError(err); apiErr != nil {
+			return apiErr
+		}
+		log.WithField("error", err.Error()).Error("internal error in RemoveFromAllowlist RPC call")
+		return constants.ErrInternal
+	}
+	return nil
+}
+
+// GetAllowlist is called when an RPC client calls GetAllowlist,
+func (handler *rpcHandler) GetAllowlist() (result []string, err error) {
+	log.Debug("received GetAllowlist request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "GetAllowlist",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in GetAllowlist RPC call (check logs for stack trace)")
+		}
+	}()
+	allowlist, err := handler.app.GetAllowlist()
+	if err != nil {
+		if apiErr := apiError(err); apiErr != nil {
+			return nil, apiErr
+		}
+		log.WithField("error", err.Error()).Error("internal error in GetAllowlist RPC call")
+		return nil, constants.ErrInternal
+	}
+	return allowlist, nil
+}
+
+// GetPeers is called when an RPC client calls GetPeers,
+func (handler *rpcHandler) GetPeers() (result []*types.PeerInfo, err error) {
+	log.Debug("received GetPeers request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "GetPeers",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in GetPeers RPC call (check logs for stack trace)")
+		}
+	}()
+	peers, err := handler.app.GetPeers()
+	if err != nil {
+		if apiErr := apiError(err); apiErr != nil {
+			return nil, apiErr
+		}
+		log.WithField("error", err.Error()).Error("internal error in GetPeers RPC call")
+		return nil, constants.ErrInternal
+	}
+	return peers, nil
+}
+
+// GetGossipSubTopology is called when an RPC client calls GetGossipSubTopology,
+func (handler *rpcHandler) GetGossipSubTopology() (result *types.GossipSubTopology, err error) {
+	log.Debug("received GetGossipSubTopology request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "GetGossipSubTopology",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in GetGossipSubTopology RPC call (check logs for stack trace)")
+		}
+	}()
+	topology, err := handler.app.GetGossipSubTopology()
+	if err != nil {
+		if apiErr := apiError(err); apiErr != nil {
+			return nil, apiErr
+		}
+		log.WithField("error", err.Error()).Error("internal error in GetGossipSubTopology RPC call")
+		return nil, constants.ErrInternal
+	}
+	return topology, nil
+}
+
+// GetValidationQueue is called when an RPC client calls GetValidationQueue,
+func (handler *rpcHandler) GetValidationQueue() (result *types.ValidationQueue, err error) {
+	log.Debug("received GetValidationQueue request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "GetValidationQueue",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in GetValidationQueue RPC call (check logs for stack trace)")
+		}
+	}()
+	validationQueue, err := handler.app.GetValidationQueue()
+	if err != nil {
+		log.WithField("error", err.Error()).Error("internal error in GetValidationQueue RPC call")
+		return nil, constants.ErrInternal
+	}
+	return validationQueue, nil
+}
+
+// TestOrderAgainstFilter is called when an RPC client calls
+// TestOrderAgainstFilter,
+func (handler *rpcHandler) TestOrderAgainstFilter(signedOrderRaw json.RawMessage) (result *types.FilterTestResult, err error) {
+	log.Debug("received TestOrderAgainstFilter request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "TestOrderAgainstFilter",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in TestOrderAgainstFilter RPC call (check logs for stack trace)")
+		}
+	}()
+	result, err = handler.app.TestOrderAgainstFilter(signedOrderRaw)
+	if err != nil {
+		if apiErr := apiError(err); apiErr != nil {
+			return nil, apiErr
+		}
+		log.WithField("error", err.Error()).Error("internal error in TestOrderAgainstFilter RPC call")
+		return nil, constants.ErrInternal
+	}
+	return result, nil
+}
+
+// GetOrderStateAtBlock is called when an RPC client calls GetOrderStateAtBlock,
+func (handler *rpcHandler) GetOrderStateAtBlock(orderHash common.Hash, blockNumber *big.Int) (result *types.OrderState, err error) {
+	log.Debug("received GetOrderStateAtBlock request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "GetOrderStateAtBlock",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in GetOrderStateAtBlock RPC call (check logs for stack trace)")
+		}
+	}()
+	result, err = handler.app.GetOrderStateAtBlock(orderHash, blockNumber)
+	if err != nil {
+		if apiErr := apiError(err); apiErr != nil {
+			return nil, apiErr
+		}
+		log.WithField("error", err.Error()).Error("internal error in GetOrderStateAtBlock RPC call")
+		return nil, constants.ErrInternal
+	}
+	return result, nil
+}
+
+// GetOrdersFillableAtBlock is called when an RPC client calls GetOrdersFillableAtBlock,
+func (handler *rpcHandler) GetOrdersFillableAtBlock(blockNumber *big.Int) (result []*types.OrderState, err error) {
+	log.Debug("received GetOrdersFillableAtBlock request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "GetOrdersFillableAtBlock",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in GetOrdersFillableAtBlock RPC call (check logs for stack trace)")
+		}
+	}()
+	result, err = handler.app.GetOrdersFillableAtBlock(blockNumber)
+	if err != nil {
+		if apiErr := apiError(err); apiErr != nil {
+			return nil, apiErr
+		}
+		log.WithField("error", err.Error()).Error("internal error in GetOrdersFillableAtBlock RPC call")
+		return nil, constants.ErrInternal
+	}
+	return result, nil
+}
+
+// GetOrderEventsSince is called when an RPC client calls GetOrderEventsSince,
+func (handler *rpcHandler) GetOrderEventsSince(sequenceNumber uint64, limit int) (result *types.GetOrderEventsSinceResponse, err error) {
+	log.Debug("received GetOrderEventsSince request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "GetOrderEventsSince",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in GetOrderEventsSince RPC call (check logs for stack trace)")
+		}
+	}()
+	result, err = handler.app.GetOrderEventsSince(sequenceNumber, limit)
+	if err != nil {
+		if apiErr := apiError(err); apiErr != nil {
+			return nil, apiErr
+		}
+		log.WithField("error", err.Error()).Error("internal error in GetOrderEventsSince RPC call")
+		return nil, constants.ErrInternal
+	}
+	return result, nil
+}
+
+// RemoveOrders is called when an RPC client calls RemoveOrders,
+func (handler *rpcHandler) RemoveOrders(orderHashes []common.Hash, reason string) (result *types.RemoveOrdersResponse, err error) {
+	log.Debug("received RemoveOrders request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "RemoveOrders",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in RemoveOrders RPC call (check logs for stack trace)")
+		}
+	}()
+	result, err = handler.app.RemoveOrders(orderHashes, reason)
+	if err != nil {
+		log.WithField("error", err.Error()).Error("internal error in RemoveOrders RPC call")
+		return nil, constants.ErrInternal
+	}
+	return result, nil
+}
+
+// RevalidateOrders is called when an RPC client calls RevalidateOrders,
+func (handler *rpcHandler) RevalidateOrders(ctx context.Context, orderHashes []common.Hash) (result *types.RevalidateOrdersResponse, err error) {
+	log.Debug("received RevalidateOrders request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "RevalidateOrders",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in RevalidateOrders RPC call (check logs for stack trace)")
+		}
+	}()
+	result, err = handler.app.RevalidateOrders(ctx, orderHashes)
+	if err != nil {
+		if apiErr := apiError(err); apiErr != nil {
+			return nil, apiErr
+		}
+		if ctx.Err() != nil {
+			// The request timed out or the client disconnected.
+			return nil, ctx.Err()
+		}
+		log.WithField("error", err.Error()).Error("internal error in RevalidateOrders RPC call")
+		return nil, constants.ErrInternal
+	}
+	return result, nil
+}
+
+// SetEthereumRPCURL is called when an RPC client calls SetEthereumRPCURL,
+func (handler *rpcHandler) SetEthereumRPCURL(ctx context.Context, rawurl string) (err error) {
+	log.Debug("received SetEthereumRPCURL request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "SetEthereumRPCURL",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in SetEthereumRPCURL RPC call (check logs for stack trace)")
+		}
+	}()
+	if err := handler.app.SetEthereumRPCURL(ctx, rawurl); err != nil {
+		if apiErr := apiError(err); apiErr != nil {
+			return apiErr
+		}
+		log.WithField("error", err.Error()).Error("internal error in SetEthereumRPCURL RPC call")
+		return constants.ErrInternal
+	}
+	return nil
+}
+
+// SetOrderAnnotations is called when an RPC client calls SetOrderAnnotations,
+func (handler *rpcHandler) SetOrderAnnotations(orderHash common.Hash, annotations map[string]string) (result map[string]string, err error) {
+	log.Debug("received SetOrderAnnotations request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "SetOrderAnnotations",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in SetOrderAnnotations RPC call (check logs for stack trace)")
+		}
+	}()
+	result, err = handler.app.SetOrderAnnotations(orderHash, annotations)
+	if err != nil {
+		if apiErr := apiError(err); apiErr != nil {
+			return nil, apiErr
+		}
+		log.WithField("error", err.Error()).Error("internal error in SetOrderAnnotations RPC call")
+		return nil, constants.ErrInternal
+	}
+	return result, nil
+}
+
+// IsReady is called when a health check is received over HTTP.
+func (handler *rpcHandler) IsReady() bool {
+	return handler.app.IsReady()
+}
+
+// PipelineHealth is called when a health check is received over HTTP.
+func (handler *rpcHandler) PipelineHealth() *types.PipelineHealth {
+	return handler.app.PipelineHealth()
+}
+
+// SubscribeToOrderEvents is called when a client connects to the order events
+// SSE endpoint.
+func (handler *rpcHandler) SubscribeToOrderEvents(sink chan<- []*zeroex.OrderEvent) event.Subscription {
+	log.Debug("received order events SSE connection")
+	return handler.app.SubscribeToOrderEvents(sink)
+}
+
 // SubscribeToOrders is called when an RPC client sends a `mesh_subscribe` request with the `orders` topic parameter
-func (handler *rpcHandler) SubscribeToOrders(ctx context.Context) (result *ethrpc.Subscription, err error) {
-	log.Debug("received order event subscription request via RPC")
+func (handler *rpcHandler) SubscribeToOrders(ctx context.Context, opts types.OrdersSubscriptionOpts) (result *ethrpc.Subscription, err error) {
+	log.WithField("compact", opts.Compact).Debug("received order event subscription request via RPC")
 	// Catch panics, log stack trace and return RPC error message
 	defer func() {
 		if r := recover(); r != nil {
@@ -200,7 +891,7 @@ func (handler *rpcHandler) SubscribeToOrders(ctx context.Context) (result *ethrp
 			err = errors.New("method handler crashed in SubscribeToOrders RPC call (check logs for stack trace)")
 		}
 	}()
-	subscription, err := SetupOrderStream(ctx, handler.app)
+	subscription, err := SetupOrderStream(ctx, handler.app, opts)
 	if err != nil {
 		log.WithField("error", err.Error()).Error("internal error in `mesh_subscribe` to `orders` RPC call")
 		return nil, constants.ErrInternal
@@ -208,16 +899,110 @@ func (handler *rpcHandler) SubscribeToOrders(ctx context.Context) (result *ethrp
 	return subscription, nil
 }
 
-// SetupOrderStream sets up the order stream for a subscription
-func SetupOrderStream(ctx context.Context, app *core.App) (*ethrpc.Subscription, error) {
+// StreamOrders is called when an RPC client sends a `mesh_subscribe` request with the `ordersSnapshot` topic parameter
+func (handler *rpcHandler) StreamOrders(ctx context.Context, chunkSize int) (result *ethrpc.Subscription, err error) {
+	log.Debug("received orders snapshot subscription request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "StreamOrders",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in StreamOrders RPC call (check logs for stack trace)")
+		}
+	}()
+	// Validate the chunk size up front since errors returned by the stream
+	// itself can only be sent as notifications.
+	if chunkSize < 0 || chunkSize > core.MaxOrdersStreamChunkSize {
+		return nil, apiError(core.ErrInvalidOrdersStreamChunkSize)
+	}
+	subscription, err := SetupOrdersSnapshotStream(ctx, handler.app, chunkSize)
+	if err != nil {
+		log.WithField("error", err.Error()).Error("internal error in `mesh_subscribe` to `ordersSnapshot` RPC call")
+		return nil, constants.ErrInternal
+	}
+	return subscription, nil
+}
+
+// SetupOrdersSnapshotStream sets up a subscription which sends all orders
+// currently stored in chunks. Each chunk is only sent once the previous one has
+// been written to the connection, so slow clients slow down the stream instead
+// of causing chunks to pile up in memory.
+func SetupOrdersSnapshotStream(ctx context.Context, app *core.App, chunkSize int) (*ethrpc.Subscription, error) {
+	notifier, supported := ethrpc.NotifierFromContext(ctx)
+	if !supported {
+		return &ethrpc.Subscription{}, ethrpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+	tracked := rpc.TrackedSubscriptionFromContext(ctx)
+
+	go func() {
+		defer supervisor.Recover("rpc.ordersSnapshotSubscription")
+		streamCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-rpcSub.Err():
+			case <-notifier.Closed():
+			case <-tracked.Terminated():
+			case <-streamCtx.Done():
+			}
+			cancel()
+		}()
+
+		err := app.StreamOrders(streamCtx, chunkSize, func(chunk *types.OrdersStreamChunk) error {
+			if err := notifier.Notify(rpcSub.ID, chunk); err != nil {
+				tracked.RecordDropped()
+				return err
+			}
+			return nil
+		})
+		if err == nil || err == context.Canceled {
+			return
+		}
+		logEntry := log.WithFields(map[string]interface{}{
+			"error":            err.Error(),
+			"subscriptionType": "ordersSnapshot",
+		})
+		// See SetupOrderStream for why these errors are not logged with an
+		// `Error` severity. In both cases the client is gone so there is no
+		// point in notifying it.
+		if _, ok := err.(*net.OpError); ok || strings.Contains(err.Error(), "write: broken pipe") {
+			logEntry.Trace("error while streaming orders snapshot")
+			return
+		}
+		logEntry.Error("error while streaming orders snapshot")
+		_ = notifier.Notify(rpcSub.ID, &types.OrdersStreamChunk{
+			OrdersInfos: []*types.OrderInfo{},
+			Done:        true,
+			Error:       constants.ErrInternal.Error(),
+		})
+	}()
+
+	return rpcSub, nil
+}
+
+// SetupOrderStream sets up the order stream for a subscription. If opts.Compact
+// is true, the signed orders are left out of the order events.
+func SetupOrderStream(ctx context.Context, app *core.App, opts types.OrdersSubscriptionOpts) (*ethrpc.Subscription, error) {
 	notifier, supported := ethrpc.NotifierFromContext(ctx)
 	if !supported {
 		return &ethrpc.Subscription{}, ethrpc.ErrNotificationsUnsupported
 	}
 
 	rpcSub := notifier.CreateSubscription()
+	tracked := rpc.TrackedSubscriptionFromContext(ctx)
 
 	go func() {
+		defer supervisor.Recover("rpc.ordersSubscription")
 		orderEventsChan := make(chan []*zeroex.OrderEvent, orderEventsBufferSize)
 		orderWatcherSub := app.SubscribeToOrderEvents(orderEventsChan)
 		defer orderWatcherSub.Unsubscribe()
@@ -225,8 +1010,19 @@ func SetupOrderStream(ctx context.Context, app *core.App) (*ethrpc.Subscription,
 		for {
 			select {
 			case orderEvents := <-orderEventsChan:
+				tracked.SetBacklog(len(orderEventsChan))
+				if opts.Compact {
+					// The order events are shared with the other subscribers, so
+					// they are copied instead of modified.
+					compactOrderEvents := make([]*zeroex.OrderEvent, len(orderEvents))
+					for i, orderEvent := range orderEvents {
+						compactOrderEvents[i] = orderEvent.Compact()
+					}
+					orderEvents = compactOrderEvents
+				}
 				err := notifier.Notify(rpcSub.ID, orderEvents)
 				if err != nil {
+					tracked.RecordDropped()
 					// TODO(fabio): The current implementation of `notifier.Notify` returns a
 					// `write: broken pipe` error when it is called _after_ the client has
 					// disconnected but before the corresponding error is received on the
@@ -264,6 +1060,8 @@ func SetupOrderStream(ctx context.Context, app *core.App) (*ethrpc.Subscription,
 				return
 			case <-notifier.Closed():
 				return
+			case <-tracked.Terminated():
+				return
 			}
 		}
 	}()
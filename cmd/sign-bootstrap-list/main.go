@@ -0,0 +1,65 @@
+// +build !js
+
+// sign-bootstrap-list is a short program that can be used to sign bootstrap
+// lists for BOOTSTRAP_LIST_URLS. It prints the signed list, which can be served
+// from any URL, and logs the public key to use for BOOTSTRAP_LIST_PUBLIC_KEY.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/0xProject/0x-mesh/keys"
+	"github.com/0xProject/0x-mesh/p2p"
+	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/plaid/go-envvar/envvar"
+)
+
+type envVars struct {
+	// PrivateKeyPath is the path of the private key to sign the list with.
+	PrivateKeyPath string `envvar:"PRIVATE_KEY_PATH" default:"0x_mesh/keys/privkey"`
+	// EthereumChainID is the chain ID of the nodes which use the list.
+	EthereumChainID int `envvar:"ETHEREUM_CHAIN_ID"`
+	// BootstrapEnvironment is the bootstrap environment of the nodes which use
+	// the list.
+	BootstrapEnvironment string `envvar:"BOOTSTRAP_ENVIRONMENT" default:"production"`
+	// BootstrapList is a comma-separated list of multiaddresses.
+	BootstrapList string `envvar:"BOOTSTRAP_LIST"`
+}
+
+func main() {
+	env := envVars{}
+	if err := envvar.Parse(&env); err != nil {
+		log.Fatal(err)
+	}
+	privKey, err := keys.GetPrivateKeyFromPath(env.PrivateKeyPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	signedList, err := p2p.SignBootstrapList(privKey, &p2p.BootstrapListPayload{
+		ChainID:       env.EthereumChainID,
+		Environment:   env.BootstrapEnvironment,
+		UpdatedAt:     time.Now().UTC(),
+		BootstrapList: strings.Split(env.BootstrapList, ","),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Make sure the list is valid before printing it.
+	if _, err := signedList.Verify(privKey.GetPublic(), env.EthereumChainID, env.BootstrapEnvironment); err != nil {
+		log.Fatal(err)
+	}
+	encodedPubKey, err := p2pcrypto.MarshalPublicKey(privKey.GetPublic())
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("BOOTSTRAP_LIST_PUBLIC_KEY=%s", p2pcrypto.ConfigEncodeKey(encodedPubKey))
+	encoded, err := json.Marshal(signedList)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(encoded))
+}
@@ -0,0 +1,39 @@
+// Package requestid contains helpers for attaching a request ID to a context.
+// Request IDs are either supplied by API clients or generated by Mesh and are
+// used to correlate a request with the logs and order events it produces.
+package requestid
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type contextKey struct{}
+
+// MaxLength is the maximum length of a client-supplied request ID.
+const MaxLength = 128
+
+// New generates a new random request ID.
+func New() string {
+	return uuid.New().String()
+}
+
+// NewContext returns a copy of ctx which carries the given request ID. If id is
+// empty, ctx is returned unchanged.
+func NewContext(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx or an empty string if there
+// is none.
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
@@ -0,0 +1,19 @@
+package requestid
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "", FromContext(ctx))
+	assert.Equal(t, ctx, NewContext(ctx, ""))
+
+	id := New()
+	assert.NotEmpty(t, id)
+	assert.NotEqual(t, id, New())
+	assert.Equal(t, id, FromContext(NewContext(ctx, id)))
+}
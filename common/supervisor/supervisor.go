@@ -0,0 +1,199 @@
+// Package supervisor isolates panics in long-running goroutines. Run restarts
+// a subsystem with exponential backoff if it panics, and Recover logs a crash
+// report for goroutines which don't need to be restarted. In both cases the
+// panic no longer takes down the whole node.
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultMinBackoff is the default value for Config.MinBackoff.
+	defaultMinBackoff = 1 * time.Second
+	// defaultMaxBackoff is the default value for Config.MaxBackoff.
+	defaultMaxBackoff = 1 * time.Minute
+	// defaultMaxRestarts is the default value for Config.MaxRestarts.
+	defaultMaxRestarts = 5
+	// defaultRestartWindow is the default value for Config.RestartWindow.
+	defaultRestartWindow = 10 * time.Minute
+)
+
+// Config configures how a subsystem is restarted by Run.
+type Config struct {
+	// Name identifies the subsystem in crash reports (e.g. "orderwatch.mainLoop").
+	Name string
+	// MinBackoff is how long to wait before restarting the subsystem after the
+	// first panic. It doubles after each consecutive panic within
+	// RestartWindow. Defaults to 1 second.
+	MinBackoff time.Duration
+	// MaxBackoff is the maximum amount of time to wait before restarting the
+	// subsystem. Defaults to 1 minute.
+	MaxBackoff time.Duration
+	// MaxRestarts is the maximum number of times the subsystem is restarted
+	// within RestartWindow. If it panics more often, Run gives up and returns a
+	// TooManyRestartsError so that the node shuts down instead of running
+	// with a subsystem that keeps crashing. Defaults to 5.
+	MaxRestarts int
+	// RestartWindow is the window in which restarts are counted. Defaults to
+	// 10 minutes.
+	RestartWindow time.Duration
+}
+
+// CrashReport describes a panic in a subsystem.
+type CrashReport struct {
+	Subsystem  string
+	Panic      string
+	StackTrace string
+	Time       time.Time
+}
+
+// TooManyRestartsError is returned by Run if the subsystem panicked more than
+// MaxRestarts times within RestartWindow.
+type TooManyRestartsError struct {
+	Subsystem   string
+	NumRestarts int
+	LastCrash   *CrashReport
+}
+
+func (e TooManyRestartsError) Error() string {
+	return fmt.Sprintf("%s crashed after being restarted %d times: %s", e.Subsystem, e.NumRestarts, e.LastCrash.Panic)
+}
+
+var (
+	numCrashesMu sync.Mutex
+	numCrashes   = map[string]int64{}
+)
+
+// NumCrashes returns the number of times each subsystem panicked since the
+// process was started.
+func NumCrashes() map[string]int64 {
+	numCrashesMu.Lock()
+	defer numCrashesMu.Unlock()
+	result := make(map[string]int64, len(numCrashes))
+	for subsystem, count := range numCrashes {
+		result[subsystem] = count
+	}
+	return result
+}
+
+// Run calls fn and restarts it if it panics. It returns when fn returns
+// (without panicking), when ctx is canceled or when fn panicked too often (see
+// Config.MaxRestarts). fn must release any resources it holds (e.g. locks)
+// with deferred calls so that it can be restarted safely.
+func Run(ctx context.Context, config Config, fn func(ctx context.Context) error) error {
+	if config.MinBackoff == 0 {
+		config.MinBackoff = defaultMinBackoff
+	}
+	if config.MaxBackoff == 0 {
+		config.MaxBackoff = defaultMaxBackoff
+	}
+	if config.MaxRestarts == 0 {
+		config.MaxRestarts = defaultMaxRestarts
+	}
+	if config.RestartWindow == 0 {
+		config.RestartWindow = defaultRestartWindow
+	}
+	recentCrashes := []time.Time{}
+	for {
+		crash, err := runOnce(ctx, config.Name, fn)
+		if crash == nil {
+			return err
+		}
+
+		// Only count the crashes within the restart window.
+		cutoff := crash.Time.Add(-config.RestartWindow)
+		for len(recentCrashes) > 0 && recentCrashes[0].Before(cutoff) {
+			recentCrashes = recentCrashes[1:]
+		}
+		recentCrashes = append(recentCrashes, crash.Time)
+		if len(recentCrashes) > config.MaxRestarts {
+			log.WithFields(log.Fields{
+				"subsystem":   config.Name,
+				"numRestarts": len(recentCrashes) - 1,
+			}).Error("subsystem keeps crashing; giving up")
+			return TooManyRestartsError{
+				Subsystem:   config.Name,
+				NumRestarts: len(recentCrashes) - 1,
+				LastCrash:   crash,
+			}
+		}
+
+		delay := backoffForCrashes(config, len(recentCrashes))
+		log.WithFields(log.Fields{
+			"subsystem":         config.Name,
+			"numRecentCrashes":  len(recentCrashes),
+			"restartInSeconds":  delay.Seconds(),
+			"maxRecentRestarts": config.MaxRestarts,
+		}).Warn("restarting subsystem after crash")
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+	}
+}
+
+// backoffForCrashes returns how long to wait before restarting a subsystem
+// after the given number of recent crashes.
+func backoffForCrashes(config Config, numCrashes int) time.Duration {
+	delay := config.MinBackoff
+	for i := 1; i < numCrashes; i++ {
+		delay *= 2
+		if delay >= config.MaxBackoff {
+			return config.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// runOnce calls fn and returns a crash report if it panics.
+func runOnce(ctx context.Context, name string, fn func(ctx context.Context) error) (crash *CrashReport, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			crash = newCrashReport(name, r)
+		}
+	}()
+	return nil, fn(ctx)
+}
+
+// Recover recovers from a panic in the calling goroutine and logs a crash
+// report. It must be deferred directly (i.e. `defer supervisor.Recover(name)`).
+// It returns the crash report, or nil if there was no panic. Goroutines that
+// need to clean up after a panic should call recover themselves and use
+// ReportCrash instead.
+func Recover(name string) *CrashReport {
+	if r := recover(); r != nil {
+		return newCrashReport(name, r)
+	}
+	return nil
+}
+
+// ReportCrash logs a crash report for the given value returned by recover.
+func ReportCrash(name string, recovered interface{}) *CrashReport {
+	return newCrashReport(name, recovered)
+}
+
+func newCrashReport(name string, recovered interface{}) *CrashReport {
+	crash := &CrashReport{
+		Subsystem:  name,
+		Panic:      fmt.Sprint(recovered),
+		StackTrace: string(debug.Stack()),
+		Time:       time.Now(),
+	}
+	numCrashesMu.Lock()
+	numCrashes[name]++
+	numCrashesMu.Unlock()
+	log.WithFields(log.Fields{
+		"subsystem":  crash.Subsystem,
+		"panic":      crash.Panic,
+		"stackTrace": crash.StackTrace,
+	}).Error("subsystem crashed")
+	return crash
+}
@@ -0,0 +1,72 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunRestartsAfterPanic(t *testing.T) {
+	config := Config{Name: "TestRunRestartsAfterPanic", MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	expectedErr := errors.New("done")
+	numCalls := 0
+	err := Run(context.Background(), config, func(ctx context.Context) error {
+		numCalls++
+		if numCalls < 3 {
+			panic("something went wrong")
+		}
+		return expectedErr
+	})
+	assert.Equal(t, expectedErr, err)
+	assert.Equal(t, 3, numCalls)
+	assert.Equal(t, int64(2), NumCrashes()["TestRunRestartsAfterPanic"])
+}
+
+func TestRunGivesUpAfterTooManyRestarts(t *testing.T) {
+	config := Config{Name: "TestRunGivesUpAfterTooManyRestarts", MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond, MaxRestarts: 2}
+	numCalls := 0
+	err := Run(context.Background(), config, func(ctx context.Context) error {
+		numCalls++
+		panic("something went wrong")
+	})
+	require.IsType(t, TooManyRestartsError{}, err)
+	assert.Equal(t, 2, err.(TooManyRestartsError).NumRestarts)
+	assert.Equal(t, "something went wrong", err.(TooManyRestartsError).LastCrash.Panic)
+	assert.Equal(t, 3, numCalls)
+}
+
+func TestRunStopsWhenContextIsCanceled(t *testing.T) {
+	config := Config{Name: "TestRunStopsWhenContextIsCanceled", MinBackoff: time.Hour, MaxBackoff: time.Hour}
+	ctx, cancel := context.WithCancel(context.Background())
+	err := Run(ctx, config, func(ctx context.Context) error {
+		cancel()
+		panic("something went wrong")
+	})
+	assert.NoError(t, err)
+}
+
+func TestBackoffForCrashes(t *testing.T) {
+	config := Config{MinBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, backoffForCrashes(config, 1))
+	assert.Equal(t, 2*time.Second, backoffForCrashes(config, 2))
+	assert.Equal(t, 4*time.Second, backoffForCrashes(config, 3))
+	assert.Equal(t, 5*time.Second, backoffForCrashes(config, 4))
+}
+
+func TestRecover(t *testing.T) {
+	var crash *CrashReport
+	func() {
+		defer func() {
+			crash = ReportCrash("TestRecover", recover())
+		}()
+		panic("something went wrong")
+	}()
+	require.NotNil(t, crash)
+	assert.Equal(t, "TestRecover", crash.Subsystem)
+	assert.Equal(t, "something went wrong", crash.Panic)
+	assert.NotEmpty(t, crash.StackTrace)
+}
@@ -8,6 +8,7 @@ import (
 	"math/big"
 	"time"
 
+	"github.com/0xProject/0x-mesh/orderfilter"
 	"github.com/0xProject/0x-mesh/zeroex"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
@@ -16,21 +17,313 @@ import (
 // Stats is the return value for core.GetStats. Also used in the browser and RPC
 // interface.
 type Stats struct {
-	Version                           string      `json:"version"`
-	PubSubTopic                       string      `json:"pubSubTopic"`
-	Rendezvous                        string      `json:"rendezvous"`
-	SecondaryRendezvous               []string    `json:"secondaryRendezvous"`
-	PeerID                            string      `json:"peerID"`
-	EthereumChainID                   int         `json:"ethereumChainID"`
-	LatestBlock                       LatestBlock `json:"latestBlock"`
-	NumPeers                          int         `json:"numPeers"`
-	NumOrders                         int         `json:"numOrders"`
-	NumOrdersIncludingRemoved         int         `json:"numOrdersIncludingRemoved"`
-	NumPinnedOrders                   int         `json:"numPinnedOrders"`
-	MaxExpirationTime                 string      `json:"maxExpirationTime"`
-	StartOfCurrentUTCDay              time.Time   `json:"startOfCurrentUTCDay"`
-	EthRPCRequestsSentInCurrentUTCDay int         `json:"ethRPCRequestsSentInCurrentUTCDay"`
-	EthRPCRateLimitExpiredRequests    int64       `json:"ethRPCRateLimitExpiredRequests"`
+	Version                           string                    `json:"version"`
+	PubSubTopic                       string                    `json:"pubSubTopic"`
+	OrderFilterHash                   string                    `json:"orderFilterHash"`
+	Rendezvous                        string                    `json:"rendezvous"`
+	SecondaryRendezvous               []string                  `json:"secondaryRendezvous"`
+	PeerID                            string                    `json:"peerID"`
+	EthereumChainID                   int                       `json:"ethereumChainID"`
+	LatestBlock                       LatestBlock               `json:"latestBlock"`
+	NumPeers                          int                       `json:"numPeers"`
+	NumOrders                         int                       `json:"numOrders"`
+	NumOrdersIncludingRemoved         int                       `json:"numOrdersIncludingRemoved"`
+	NumPinnedOrders                   int                       `json:"numPinnedOrders"`
+	NumProvisionalOrders              int                       `json:"numProvisionalOrders"`
+	MaxExpirationTime                 string                    `json:"maxExpirationTime"`
+	StartOfCurrentUTCDay              time.Time                 `json:"startOfCurrentUTCDay"`
+	EthRPCRequestsSentInCurrentUTCDay int                       `json:"ethRPCRequestsSentInCurrentUTCDay"`
+	EthRPCRateLimitExpiredRequests    int64                     `json:"ethRPCRateLimitExpiredRequests"`
+	OrderSyncProviders                []*OrderSyncProviderStats `json:"orderSyncProviders"`
+	EthRPCHealth                      EthRPCHealth              `json:"ethRPCHealth"`
+	DiskUsage                         DiskUsage                 `json:"diskUsage"`
+	MaxOrderSizeInBytes               int                       `json:"maxOrderSizeInBytes"`
+	NumOversizedOrdersRejected        int64                     `json:"numOversizedOrdersRejected"`
+	NumOversizedMessagesDropped       int64                     `json:"numOversizedMessagesDropped"`
+	BandwidthByProtocol               []*ProtocolBandwidthStats `json:"bandwidthByProtocol"`
+	TopPeersByBandwidth               []*PeerBandwidthStats     `json:"topPeersByBandwidth"`
+	DialStats                         DialStats                 `json:"dialStats"`
+	ResourceLimitStats                ResourceLimitStats        `json:"resourceLimitStats"`
+	SubsystemCrashes                  map[string]int64          `json:"subsystemCrashes"`
+	NumDuplicateOrders                map[string]int64          `json:"numDuplicateOrders"`
+	OrderSyncCompression              OrderSyncCompressionStats `json:"orderSyncCompression"`
+	PipelineHealth                    PipelineHealth            `json:"pipelineHealth"`
+	OrderFilter                       OrderFilterStats          `json:"orderFilter"`
+	ResourceUsage                     ResourceUsage             `json:"resourceUsage"`
+	OrderFeedback                     OrderFeedbackStats        `json:"orderFeedback"`
+}
+
+// ProtocolBandwidthStats describes the bandwidth used by the node for a single
+// libp2p protocol (e.g. GossipSub, ordersync or the DHT). Totals are in bytes
+// and rates are in bytes per second.
+type ProtocolBandwidthStats struct {
+	Protocol string  `json:"protocol"`
+	TotalIn  int64   `json:"totalIn"`
+	TotalOut int64   `json:"totalOut"`
+	RateIn   float64 `json:"rateIn"`
+	RateOut  float64 `json:"rateOut"`
+}
+
+// PeerBandwidthStats describes the bandwidth used by the node for a single
+// peer. Totals are in bytes and rates are in bytes per second.
+type PeerBandwidthStats struct {
+	PeerID   string  `json:"peerID"`
+	TotalIn  int64   `json:"totalIn"`
+	TotalOut int64   `json:"totalOut"`
+	RateIn   float64 `json:"rateIn"`
+	RateOut  float64 `json:"rateOut"`
+}
+
+// EthRPCHealth describes the health of the Ethereum RPC provider as observed
+// by the block watcher. CircuitState is "open" if the provider has returned
+// too many consecutive errors and "closed" otherwise. InconsistentHeaders is
+// the number of block headers the provider served which cannot belong to the
+// chain (e.g. because their parent hash, number, timestamp or difficulty don't
+// match the stored blocks) since it was last changed.
+type EthRPCHealth struct {
+	CircuitState        string    `json:"circuitState"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	LastError           string    `json:"lastError"`
+	LastErrorTime       time.Time `json:"lastErrorTime"`
+	InconsistentHeaders int       `json:"inconsistentHeaders"`
+}
+
+// PipelineHealth values
+const (
+	PipelineHealthOK       = "ok"
+	PipelineHealthDegraded = "degraded"
+)
+
+// PipelineHealth describes whether the order processing pipeline is making
+// progress. Status is "degraded" while any stage has stalled (i.e. has been
+// working on the same unit of work for longer than its threshold) and "ok"
+// otherwise.
+type PipelineHealth struct {
+	Status        string          `json:"status"`
+	StalledStages []*StalledStage `json:"stalledStages"`
+}
+
+// StalledStage describes a stalled stage of the order processing pipeline.
+// Stage is one of "BLOCK_PROCESSING", "VALIDATION" or "EVENT_DELIVERY".
+type StalledStage struct {
+	Stage        string `json:"stage"`
+	StalledForMs int64  `json:"stalledForMs"`
+	ThresholdMs  int64  `json:"thresholdMs"`
+}
+
+// DiskUsage describes the disk usage of the database. MaxBytes is 0 if there is
+// no disk usage budget. While BudgetExceeded is true, new orders that are not
+// pinned are rejected.
+type DiskUsage struct {
+	UsageBytes     int64 `json:"usageBytes"`
+	MaxBytes       int64 `json:"maxBytes"`
+	BudgetExceeded bool  `json:"budgetExceeded"`
+}
+
+// DialStats counts the dials to other peers attempted by the node since it was
+// started. NumFailures is keyed by the reason for the failure (e.g. "timeout"
+// or "connectionRefused"). NumSkipped counts the dials that peer discovery
+// skipped because a recent dial to the same peer failed, and NumPeersInBackoff
+// is the number of peers that are currently being skipped.
+type DialStats struct {
+	NumSuccesses      int64            `json:"numSuccesses"`
+	NumFailures       map[string]int64 `json:"numFailures"`
+	NumSkipped        int64            `json:"numSkipped"`
+	NumPeersInBackoff int              `json:"numPeersInBackoff"`
+}
+
+// ResourceLimitStats counts the streams and connections that the node rejected
+// because a resource limit was hit since it was started. NumLimitHits is keyed
+// by the limit (e.g. "inboundStreamsPerPeer" or "connections").
+// NumInboundStreams is the number of inbound ordersync streams that are
+// currently open.
+type ResourceLimitStats struct {
+	NumLimitHits      map[string]int64 `json:"numLimitHits"`
+	NumInboundStreams int              `json:"numInboundStreams"`
+}
+
+// OrderSyncCompressionStats describes the compressed ordersync response pages
+// that the node sent and received since it was started. CompressionRatio is
+// the total size of these pages before compression divided by their total size
+// after compression, or 0 if no pages were compressed.
+type OrderSyncCompressionStats struct {
+	CompressedPagesSent     int64   `json:"compressedPagesSent"`
+	CompressedPagesReceived int64   `json:"compressedPagesReceived"`
+	UncompressedBytes       int64   `json:"uncompressedBytes"`
+	CompressedBytes         int64   `json:"compressedBytes"`
+	CompressionRatio        float64 `json:"compressionRatio"`
+}
+
+// ResourceUsage describes the most recent sample of the resources used by the
+// node. CPUPercent is a percentage of a single core (or -1 if it can't be
+// measured on this platform), MemoryBytes is the memory obtained from the
+// operating system by the Go runtime and the bandwidth rates are in bytes per
+// second. SampledAt is the zero time if resource usage has not been sampled
+// yet. PeerCountLow, PeerCountHigh and ValidationConcurrency are the current
+// limits, which are lowered while Throttled is true (see
+// Config.AdaptiveResourceLimits).
+type ResourceUsage struct {
+	SampledAt             time.Time `json:"sampledAt"`
+	CPUPercent            float64   `json:"cpuPercent"`
+	MemoryBytes           int64     `json:"memoryBytes"`
+	BandwidthInPerSecond  float64   `json:"bandwidthInPerSecond"`
+	BandwidthOutPerSecond float64   `json:"bandwidthOutPerSecond"`
+	PeerCountLow          int       `json:"peerCountLow"`
+	PeerCountHigh         int       `json:"peerCountHigh"`
+	ValidationConcurrency int       `json:"validationConcurrency"`
+	Throttled             bool      `json:"throttled"`
+}
+
+// OrderFilterStats counts the messages received from peers and the orders
+// added via the API or synced with peers that the order filter accepted and
+// rejected since it was set.
+type OrderFilterStats struct {
+	Messages OrderFilterCheckStats `json:"messages"`
+	Orders   OrderFilterCheckStats `json:"orders"`
+}
+
+// OrderFilterCheckStats counts the outcomes of one kind of check of the order
+// filter. NumRejected is keyed by the reason for the rejection (e.g. "schema",
+// "maxExpiration" or "schema:makerAssetData" for orders which violate the
+// schema of their makerAssetData). NumErrors counts the checks which failed,
+// e.g. because the input was not valid JSON.
+type OrderFilterCheckStats struct {
+	NumAccepted int64            `json:"numAccepted"`
+	NumRejected map[string]int64 `json:"numRejected"`
+	NumErrors   int64            `json:"numErrors"`
+}
+
+// OrderFeedbackStats counts the rejections of orders which the node reported
+// to the authors of the orders (NumRejectionsSent) and which peers reported
+// for the orders the node shared (NumRejectionsReceived) since it was started.
+// Both are keyed by the rejection code (e.g. "OrderExpired").
+type OrderFeedbackStats struct {
+	NumRejectionsSent     map[string]int64 `json:"numRejectionsSent"`
+	NumRejectionsReceived map[string]int64 `json:"numRejectionsReceived"`
+}
+
+// OrderSyncProviderStats summarizes the recent history of ordersync attempts
+// with a single provider.
+type OrderSyncProviderStats struct {
+	PeerID            string         `json:"peerID"`
+	NumSyncs          int            `json:"numSyncs"`
+	NumFailures       int            `json:"numFailures"`
+	OrdersReceived    int            `json:"ordersReceived"`
+	OrdersAccepted    int            `json:"ordersAccepted"`
+	OrdersRejected    map[string]int `json:"ordersRejected"`
+	AverageDurationMs int64          `json:"averageDurationMs"`
+	AverageLatencyMs  int64          `json:"averageLatencyMs"`
+	LastSyncTime      time.Time      `json:"lastSyncTime"`
+	LastSubprotocol   string         `json:"lastSubprotocol"`
+}
+
+// PeerBan is a ban on a specific peer. Peers can be banned and unbanned via
+// the RPC API. A zero Expiry means the ban never expires.
+type PeerBan struct {
+	PeerID string    `json:"peerID"`
+	Reason string    `json:"reason"`
+	Expiry time.Time `json:"expiry"`
+}
+
+// SubscriptionInfo describes an active subscription of an RPC client. Used in
+// the RPC interface.
+type SubscriptionInfo struct {
+	ID string `json:"id"`
+	// ConnectionID identifies the connection the subscription was created on.
+	// Subscriptions with the same ConnectionID share a connection.
+	ConnectionID int `json:"connectionID"`
+	// Type is the topic of the subscription ("orders", "ordersSnapshot" or
+	// "heartbeat").
+	Type string `json:"type"`
+	// Filter holds the options of "orders" subscriptions and is nil for other
+	// types.
+	Filter *OrdersSubscriptionOpts `json:"filter"`
+	// Backlog is the number of notifications (e.g. batches of order events)
+	// which are waiting to be sent.
+	Backlog int `json:"backlog"`
+	// Dropped is the number of notifications which could not be sent.
+	Dropped   int       `json:"dropped"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// IPRangeBan is a ban on a range of IP addresses in CIDR notation. IP ranges
+// can be banned and unbanned via the RPC API.
+type IPRangeBan struct {
+	IPRange string `json:"ipRange"`
+	Reason  string `json:"reason"`
+}
+
+// PeerInfo describes a peer the node is connected to. Also used in the RPC
+// interface.
+type PeerInfo struct {
+	PeerID string   `json:"peerID"`
+	Addrs  []string `json:"addrs"`
+	// Metadata is null if the peer didn't send any metadata.
+	Metadata *PeerMetadata `json:"metadata"`
+}
+
+// PeerMetadata is optional, self-reported information about a peer (see
+// p2p.PeerMetadata). It is not verified in any way.
+type PeerMetadata struct {
+	Name       string   `json:"name,omitempty"`
+	ContactURL string   `json:"contactURL,omitempty"`
+	ChainIDs   []int    `json:"chainIDs,omitempty"`
+	Topics     []string `json:"topics,omitempty"`
+}
+
+// GossipSubTopology is a snapshot of the GossipSub overlay of the node (see
+// p2p.GossipSubTopology). Also used in the RPC interface.
+type GossipSubTopology struct {
+	Topics []*GossipSubTopic `json:"topics"`
+	// StatsWindowSeconds is the duration over which the control messages in
+	// Control and the IHAVE counts of the topics were counted.
+	StatsWindowSeconds int                    `json:"statsWindowSeconds"`
+	Control            *GossipSubControlStats `json:"control"`
+}
+
+// GossipSubTopic describes the peers of the node for a single topic.
+type GossipSubTopic struct {
+	Topic         string   `json:"topic"`
+	Joined        bool     `json:"joined"`
+	Peers         []string `json:"peers"`
+	MeshPeers     []string `json:"meshPeers"`
+	FanoutPeers   []string `json:"fanoutPeers"`
+	IHaveSent     int      `json:"ihaveSent"`
+	IHaveReceived int      `json:"ihaveReceived"`
+}
+
+// GossipSubControlStats counts the GossipSub control messages sent and
+// received by the node. IHAVE and IWANT messages are counted by the number of
+// message IDs they contain.
+type GossipSubControlStats struct {
+	IHaveSent     int `json:"ihaveSent"`
+	IHaveReceived int `json:"ihaveReceived"`
+	IWantSent     int `json:"iwantSent"`
+	IWantReceived int `json:"iwantReceived"`
+	GraftSent     int `json:"graftSent"`
+	GraftReceived int `json:"graftReceived"`
+	PruneSent     int `json:"pruneSent"`
+	PruneReceived int `json:"pruneReceived"`
+}
+
+// ValidationQueue summarizes the batches of orders which are currently being
+// validated (see orderwatch.ValidationQueue). Also used in the RPC interface.
+type ValidationQueue struct {
+	NumBatches            int                `json:"numBatches"`
+	NumOrders             int                `json:"numOrders"`
+	NumOrdersByWaitReason map[string]int     `json:"numOrdersByWaitReason"`
+	OldestBatchAgeMs      int64              `json:"oldestBatchAgeMs"`
+	Batches               []*ValidationBatch `json:"batches"`
+}
+
+// ValidationBatch is a batch of orders which is currently being validated. An
+// EtaMs of -1 means that the ETA is not known yet.
+type ValidationBatch struct {
+	Source     string `json:"source"`
+	NumOrders  int    `json:"numOrders"`
+	WaitReason string `json:"waitReason"`
+	AgeMs      int64  `json:"ageMs"`
+	EtaMs      int64  `json:"etaMs"`
 }
 
 // LatestBlock is the latest block processed by the Mesh node.
@@ -47,6 +340,22 @@ type GetOrdersResponse struct {
 	OrdersInfos       []*OrderInfo `json:"ordersInfos"`
 }
 
+// OrdersStreamChunk is a chunk of orders sent by core.StreamOrders. All chunks
+// of a stream belong to the same snapshot. The last chunk has Done set to true
+// and includes the total number of orders in the snapshot along with a
+// checksum, which is the hex-encoded Keccak-256 hash of the concatenation of
+// all order hashes in the order in which they were sent. If the stream fails,
+// the last chunk has Done set to true and Error set instead. Also used in the
+// RPC interface.
+type OrdersStreamChunk struct {
+	SnapshotTimestamp time.Time    `json:"snapshotTimestamp"`
+	OrdersInfos       []*OrderInfo `json:"ordersInfos"`
+	Done              bool         `json:"done"`
+	NumOrders         int          `json:"numOrders,omitempty"`
+	Checksum          string       `json:"checksum,omitempty"`
+	Error             string       `json:"error,omitempty"`
+}
+
 // AddOrdersOpts is a set of options for core.AddOrders. Also used in the
 // browser and RPC interface.
 type AddOrdersOpts struct {
@@ -55,6 +364,20 @@ type AddOrdersOpts struct {
 	// and will always stay in storage until they are no longer fillable. Defaults
 	// to true.
 	Pinned bool `json:"pinned"`
+	// RequestID is an optional identifier for the request. It is included in
+	// the logs, the validation results and the order events that result from
+	// the request so that they can be correlated. If empty, a random request ID
+	// is generated.
+	RequestID string `json:"requestID"`
+}
+
+// OrdersSubscriptionOpts is a set of options for order event subscriptions.
+// Also used in the RPC interface.
+type OrdersSubscriptionOpts struct {
+	// Compact determines whether the subscription receives compact order events
+	// which don't include the signed order (see zeroex.OrderEvent.Compact).
+	// Subscribers can fetch the full order with GetOrder. Defaults to false.
+	Compact bool `json:"compact"`
 }
 
 // OrderInfo represents an fillable order and how much it could be filled for.
@@ -62,21 +385,37 @@ type OrderInfo struct {
 	OrderHash                common.Hash         `json:"orderHash"`
 	SignedOrder              *zeroex.SignedOrder `json:"signedOrder"`
 	FillableTakerAssetAmount *big.Int            `json:"fillableTakerAssetAmount"`
+	// Annotations are the key/value annotations attached to the order with
+	// SetOrderAnnotations. They are never shared with peers.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// OrderFilters are the names of the order filters which the order passes.
+	// They are only set by GetOrders if the node uses additional order filters
+	// (see core.Config.AdditionalOrderFilters).
+	OrderFilters []string `json:"orderFilters,omitempty"`
 }
 
 type orderInfoJSON struct {
 	OrderHash                string              `json:"orderHash"`
 	SignedOrder              *zeroex.SignedOrder `json:"signedOrder"`
 	FillableTakerAssetAmount string              `json:"fillableTakerAssetAmount"`
+	Annotations              map[string]string   `json:"annotations,omitempty"`
+	OrderFilters             []string            `json:"orderFilters,omitempty"`
 }
 
 // MarshalJSON is a custom Marshaler for OrderInfo
 func (o OrderInfo) MarshalJSON() ([]byte, error) {
-	return json.Marshal(map[string]interface{}{
+	orderInfoJSON := map[string]interface{}{
 		"orderHash":                o.OrderHash.Hex(),
-		"signedOrder":              o.SignedOrder,
+		"signedOrder":              zeroex.ChecksummedSignedOrder{SignedOrder: o.SignedOrder},
 		"fillableTakerAssetAmount": o.FillableTakerAssetAmount.String(),
-	})
+	}
+	if len(o.Annotations) > 0 {
+		orderInfoJSON["annotations"] = o.Annotations
+	}
+	if len(o.OrderFilters) > 0 {
+		orderInfoJSON["orderFilters"] = o.OrderFilters
+	}
+	return json.Marshal(orderInfoJSON)
 }
 
 // UnmarshalJSON implements a custom JSON unmarshaller for the OrderEvent type
@@ -89,6 +428,8 @@ func (o *OrderInfo) UnmarshalJSON(data []byte) error {
 
 	o.OrderHash = common.HexToHash(orderInfoJSON.OrderHash)
 	o.SignedOrder = orderInfoJSON.SignedOrder
+	o.Annotations = orderInfoJSON.Annotations
+	o.OrderFilters = orderInfoJSON.OrderFilters
 	var ok bool
 	o.FillableTakerAssetAmount, ok = math.ParseBig256(orderInfoJSON.FillableTakerAssetAmount)
 	if !ok {
@@ -96,3 +437,134 @@ func (o *OrderInfo) UnmarshalJSON(data []byte) error {
 	}
 	return nil
 }
+
+// OrderState is the state of an order at a specific block as recorded in the
+// order state history. It is the return value for core.GetOrderStateAtBlock
+// and core.GetOrdersFillableAtBlock. Also used in the RPC interface.
+type OrderState struct {
+	OrderHash common.Hash `json:"orderHash"`
+	// BlockNumber is the number of the block at which the order last changed
+	// state, which may be lower than the block that was queried.
+	BlockNumber              int                       `json:"blockNumber"`
+	EndState                 zeroex.OrderEventEndState `json:"endState"`
+	FillableTakerAssetAmount *big.Int                  `json:"fillableTakerAssetAmount"`
+	Timestamp                time.Time                 `json:"timestamp"`
+}
+
+type orderStateJSON struct {
+	OrderHash                string    `json:"orderHash"`
+	BlockNumber              int       `json:"blockNumber"`
+	EndState                 string    `json:"endState"`
+	FillableTakerAssetAmount string    `json:"fillableTakerAssetAmount"`
+	Timestamp                time.Time `json:"timestamp"`
+}
+
+// MarshalJSON is a custom Marshaler for OrderState
+func (o OrderState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"orderHash":                o.OrderHash.Hex(),
+		"blockNumber":              o.BlockNumber,
+		"endState":                 o.EndState,
+		"fillableTakerAssetAmount": o.FillableTakerAssetAmount.String(),
+		"timestamp":                o.Timestamp,
+	})
+}
+
+// UnmarshalJSON implements a custom JSON unmarshaller for the OrderState type
+func (o *OrderState) UnmarshalJSON(data []byte) error {
+	var orderStateJSON orderStateJSON
+	err := json.Unmarshal(data, &orderStateJSON)
+	if err != nil {
+		return err
+	}
+
+	o.OrderHash = common.HexToHash(orderStateJSON.OrderHash)
+	o.BlockNumber = orderStateJSON.BlockNumber
+	o.EndState = zeroex.OrderEventEndState(orderStateJSON.EndState)
+	o.Timestamp = orderStateJSON.Timestamp
+	var ok bool
+	o.FillableTakerAssetAmount, ok = math.ParseBig256(orderStateJSON.FillableTakerAssetAmount)
+	if !ok {
+		return errors.New("Invalid uint256 number encountered for FillableTakerAssetAmount")
+	}
+	return nil
+}
+
+// RemoveOrdersResponse is the return value for core.RemoveOrders. Also used
+// in the RPC interface.
+type RemoveOrdersResponse struct {
+	// Removed are the hashes of the orders that were removed.
+	Removed []common.Hash `json:"removed"`
+	// NotRemoved are the orders that could not be removed.
+	NotRemoved []*NotRemovedOrderInfo `json:"notRemoved"`
+}
+
+// NotRemovedOrderInfo describes an order that could not be removed by
+// core.RemoveOrders. Reason is either "NOT_FOUND" or "NOT_ADDED_LOCALLY".
+type NotRemovedOrderInfo struct {
+	OrderHash common.Hash `json:"orderHash"`
+	Reason    string      `json:"reason"`
+}
+
+// RevalidateOrdersResponse is the return value for core.RevalidateOrders.
+// Also used in the RPC interface.
+type RevalidateOrdersResponse struct {
+	// OrdersInfos are the revalidated orders with their updated fillable taker
+	// asset amount. It is 0 for orders which are no longer fillable.
+	OrdersInfos []*OrderInfo `json:"ordersInfos"`
+	// NotRevalidated are the orders that could not be revalidated.
+	NotRevalidated []*NotRevalidatedOrderInfo `json:"notRevalidated"`
+}
+
+// NotRevalidatedOrderInfo describes an order that could not be revalidated by
+// core.RevalidateOrders. Reason is either "NOT_FOUND" or the code of the
+// RejectedOrderStatus that prevented validation (e.g. "EthRPCRequestFailed").
+type NotRevalidatedOrderInfo struct {
+	OrderHash common.Hash `json:"orderHash"`
+	Reason    string      `json:"reason"`
+}
+
+// GetOrderEventsSinceResponse is the return value for
+// core.GetOrderEventsSince. Also used in the RPC interface.
+type GetOrderEventsSinceResponse struct {
+	// OrderEvents are the order events with a sequence number greater than the
+	// requested sequence number, sorted by sequence number.
+	OrderEvents []*zeroex.OrderEvent `json:"orderEvents"`
+	// LatestSequenceNumber is the sequence number of the most recent order
+	// event emitted by Mesh. If it is greater than the sequence number of the
+	// last event in OrderEvents, there are more events to fetch.
+	LatestSequenceNumber uint64 `json:"latestSequenceNumber"`
+}
+
+// StatsAndSnapshot is the return value for core.GetStatsAndSnapshot. Also used
+// in the RPC interface.
+type StatsAndSnapshot struct {
+	// Stats are the stats of the node. NumOrders is the number of orders in
+	// the snapshot.
+	Stats *Stats `json:"stats"`
+	// SnapshotID can be passed to GetOrders to page through the orders in the
+	// snapshot.
+	SnapshotID        string    `json:"snapshotID"`
+	SnapshotTimestamp time.Time `json:"snapshotTimestamp"`
+	// SequenceNumber is the sequence number of the last order event which is
+	// reflected by the snapshot. Clients which bootstrap their state from the
+	// snapshot should apply the order events with a greater sequence number
+	// (see GetOrderEventsSince).
+	SequenceNumber uint64 `json:"sequenceNumber"`
+}
+
+// FilterTestResult is the return value for core.TestOrderAgainstFilter. Also
+// used in the RPC interface.
+type FilterTestResult struct {
+	// Valid is true if the order passes the order filter of the node.
+	Valid bool `json:"valid"`
+	// OrderHash is the hash of the order, after omitted optional fields were
+	// defaulted. It is omitted if the order is too malformed to be hashed.
+	OrderHash *common.Hash `json:"orderHash,omitempty"`
+	// FieldErrors are all of the violations of the order schema. It is empty
+	// if the order is valid.
+	FieldErrors []*orderfilter.FieldError `json:"fieldErrors"`
+	// OrderFilterHash is the hash of the order filter the order was tested
+	// against (see Stats.OrderFilterHash).
+	OrderFilterHash string `json:"orderFilterHash"`
+}
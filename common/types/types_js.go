@@ -30,9 +30,30 @@ func (s Stats) JSValue() js.Value {
 	for i, rendezvousPoint := range s.SecondaryRendezvous {
 		secondaryRendezvous[i] = rendezvousPoint
 	}
+	orderSyncProviders := make([]interface{}, len(s.OrderSyncProviders))
+	for i, providerStats := range s.OrderSyncProviders {
+		orderSyncProviders[i] = providerStats.JSValue()
+	}
+	bandwidthByProtocol := make([]interface{}, len(s.BandwidthByProtocol))
+	for i, protocolStats := range s.BandwidthByProtocol {
+		bandwidthByProtocol[i] = protocolStats.JSValue()
+	}
+	topPeersByBandwidth := make([]interface{}, len(s.TopPeersByBandwidth))
+	for i, peerStats := range s.TopPeersByBandwidth {
+		topPeersByBandwidth[i] = peerStats.JSValue()
+	}
+	subsystemCrashes := make(map[string]interface{}, len(s.SubsystemCrashes))
+	for subsystem, count := range s.SubsystemCrashes {
+		subsystemCrashes[subsystem] = count
+	}
+	numDuplicateOrders := make(map[string]interface{}, len(s.NumDuplicateOrders))
+	for source, count := range s.NumDuplicateOrders {
+		numDuplicateOrders[source] = count
+	}
 	return js.ValueOf(map[string]interface{}{
 		"version":                           s.Version,
 		"pubSubTopic":                       s.PubSubTopic,
+		"orderFilterHash":                   s.OrderFilterHash,
 		"rendezvous":                        s.Rendezvous,
 		"secondaryRendezvous":               secondaryRendezvous,
 		"peerID":                            s.PeerID,
@@ -42,9 +63,181 @@ func (s Stats) JSValue() js.Value {
 		"numOrders":                         s.NumOrders,
 		"numOrdersIncludingRemoved":         s.NumOrdersIncludingRemoved,
 		"numPinnedOrders":                   s.NumPinnedOrders,
+		"numProvisionalOrders":              s.NumProvisionalOrders,
 		"maxExpirationTime":                 s.MaxExpirationTime,
 		"startOfCurrentUTCDay":              s.StartOfCurrentUTCDay.String(),
 		"ethRPCRequestsSentInCurrentUTCDay": s.EthRPCRequestsSentInCurrentUTCDay,
 		"ethRPCRateLimitExpiredRequests":    s.EthRPCRateLimitExpiredRequests,
+		"orderSyncProviders":                orderSyncProviders,
+		"ethRPCHealth":                      s.EthRPCHealth.JSValue(),
+		"diskUsage":                         s.DiskUsage.JSValue(),
+		"maxOrderSizeInBytes":               s.MaxOrderSizeInBytes,
+		"numOversizedOrdersRejected":        s.NumOversizedOrdersRejected,
+		"numOversizedMessagesDropped":       s.NumOversizedMessagesDropped,
+		"bandwidthByProtocol":               bandwidthByProtocol,
+		"topPeersByBandwidth":               topPeersByBandwidth,
+		"dialStats":                         s.DialStats.JSValue(),
+		"resourceLimitStats":                s.ResourceLimitStats.JSValue(),
+		"subsystemCrashes":                  subsystemCrashes,
+		"numDuplicateOrders":                numDuplicateOrders,
+		"orderSyncCompression":              s.OrderSyncCompression.JSValue(),
+		"pipelineHealth":                    s.PipelineHealth.JSValue(),
+		"orderFilter":                       s.OrderFilter.JSValue(),
+		"resourceUsage":                     s.ResourceUsage.JSValue(),
+		"orderFeedback":                     s.OrderFeedback.JSValue(),
+	})
+}
+
+func (h PipelineHealth) JSValue() js.Value {
+	stalledStages := make([]interface{}, len(h.StalledStages))
+	for i, stalledStage := range h.StalledStages {
+		stalledStages[i] = map[string]interface{}{
+			"stage":        stalledStage.Stage,
+			"stalledForMs": stalledStage.StalledForMs,
+			"thresholdMs":  stalledStage.ThresholdMs,
+		}
+	}
+	return js.ValueOf(map[string]interface{}{
+		"status":        h.Status,
+		"stalledStages": stalledStages,
+	})
+}
+
+func (p ProtocolBandwidthStats) JSValue() js.Value {
+	return js.ValueOf(map[string]interface{}{
+		"protocol": p.Protocol,
+		"totalIn":  p.TotalIn,
+		"totalOut": p.TotalOut,
+		"rateIn":   p.RateIn,
+		"rateOut":  p.RateOut,
+	})
+}
+
+func (p PeerBandwidthStats) JSValue() js.Value {
+	return js.ValueOf(map[string]interface{}{
+		"peerID":   p.PeerID,
+		"totalIn":  p.TotalIn,
+		"totalOut": p.TotalOut,
+		"rateIn":   p.RateIn,
+		"rateOut":  p.RateOut,
+	})
+}
+
+func (h EthRPCHealth) JSValue() js.Value {
+	return js.ValueOf(map[string]interface{}{
+		"circuitState":        h.CircuitState,
+		"consecutiveFailures": h.ConsecutiveFailures,
+		"lastError":           h.LastError,
+		"lastErrorTime":       h.LastErrorTime.String(),
+		"inconsistentHeaders": h.InconsistentHeaders,
+	})
+}
+
+func (d DiskUsage) JSValue() js.Value {
+	return js.ValueOf(map[string]interface{}{
+		"usageBytes":     d.UsageBytes,
+		"maxBytes":       d.MaxBytes,
+		"budgetExceeded": d.BudgetExceeded,
+	})
+}
+
+func (d DialStats) JSValue() js.Value {
+	numFailures := make(map[string]interface{}, len(d.NumFailures))
+	for reason, count := range d.NumFailures {
+		numFailures[reason] = count
+	}
+	return js.ValueOf(map[string]interface{}{
+		"numSuccesses":      d.NumSuccesses,
+		"numFailures":       numFailures,
+		"numSkipped":        d.NumSkipped,
+		"numPeersInBackoff": d.NumPeersInBackoff,
+	})
+}
+
+func (r ResourceLimitStats) JSValue() js.Value {
+	numLimitHits := make(map[string]interface{}, len(r.NumLimitHits))
+	for limit, count := range r.NumLimitHits {
+		numLimitHits[limit] = count
+	}
+	return js.ValueOf(map[string]interface{}{
+		"numLimitHits":      numLimitHits,
+		"numInboundStreams": r.NumInboundStreams,
+	})
+}
+
+func (r ResourceUsage) JSValue() js.Value {
+	return js.ValueOf(map[string]interface{}{
+		"sampledAt":             r.SampledAt.String(),
+		"cpuPercent":            r.CPUPercent,
+		"memoryBytes":           r.MemoryBytes,
+		"bandwidthInPerSecond":  r.BandwidthInPerSecond,
+		"bandwidthOutPerSecond": r.BandwidthOutPerSecond,
+		"peerCountLow":          r.PeerCountLow,
+		"peerCountHigh":         r.PeerCountHigh,
+		"validationConcurrency": r.ValidationConcurrency,
+		"throttled":             r.Throttled,
+	})
+}
+
+func (o OrderFilterStats) JSValue() js.Value {
+	return js.ValueOf(map[string]interface{}{
+		"messages": o.Messages.JSValue(),
+		"orders":   o.Orders.JSValue(),
+	})
+}
+
+func (o OrderFilterCheckStats) JSValue() js.Value {
+	numRejected := make(map[string]interface{}, len(o.NumRejected))
+	for reason, count := range o.NumRejected {
+		numRejected[reason] = count
+	}
+	return js.ValueOf(map[string]interface{}{
+		"numAccepted": o.NumAccepted,
+		"numRejected": numRejected,
+		"numErrors":   o.NumErrors,
+	})
+}
+
+func (o OrderFeedbackStats) JSValue() js.Value {
+	numRejectionsSent := make(map[string]interface{}, len(o.NumRejectionsSent))
+	for code, count := range o.NumRejectionsSent {
+		numRejectionsSent[code] = count
+	}
+	numRejectionsReceived := make(map[string]interface{}, len(o.NumRejectionsReceived))
+	for code, count := range o.NumRejectionsReceived {
+		numRejectionsReceived[code] = count
+	}
+	return js.ValueOf(map[string]interface{}{
+		"numRejectionsSent":     numRejectionsSent,
+		"numRejectionsReceived": numRejectionsReceived,
+	})
+}
+
+func (o OrderSyncCompressionStats) JSValue() js.Value {
+	return js.ValueOf(map[string]interface{}{
+		"compressedPagesSent":     o.CompressedPagesSent,
+		"compressedPagesReceived": o.CompressedPagesReceived,
+		"uncompressedBytes":       o.UncompressedBytes,
+		"compressedBytes":         o.CompressedBytes,
+		"compressionRatio":        o.CompressionRatio,
+	})
+}
+
+func (o OrderSyncProviderStats) JSValue() js.Value {
+	ordersRejected := make(map[string]interface{}, len(o.OrdersRejected))
+	for code, count := range o.OrdersRejected {
+		ordersRejected[code] = count
+	}
+	return js.ValueOf(map[string]interface{}{
+		"peerID":            o.PeerID,
+		"numSyncs":          o.NumSyncs,
+		"numFailures":       o.NumFailures,
+		"ordersReceived":    o.OrdersReceived,
+		"ordersAccepted":    o.OrdersAccepted,
+		"ordersRejected":    ordersRejected,
+		"averageDurationMs": o.AverageDurationMs,
+		"averageLatencyMs":  o.AverageLatencyMs,
+		"lastSyncTime":      o.LastSyncTime.String(),
+		"lastSubprotocol":   o.LastSubprotocol,
 	})
 }
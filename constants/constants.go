@@ -2,7 +2,6 @@ package constants
 
 import (
 	"errors"
-	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -76,18 +75,32 @@ func init() {
 }
 
 const (
-	// MaxOrderSizeInBytes is the maximum number of bytes allowed for encoded
-	// orders. It allows for MultiAssetProxy orders with roughly 45 total ERC20
-	// assets or roughly 36 total ERC721 assets (combined between both maker and
-	// taker; depends on the other fields of the order).
+	// MaxOrderSizeInBytes is the default maximum number of bytes allowed for
+	// encoded orders. It allows for MultiAssetProxy orders with roughly 45 total
+	// ERC20 assets or roughly 36 total ERC721 assets (combined between both maker
+	// and taker; depends on the other fields of the order). It can be overridden
+	// via core.Config.MaxOrderSizeInBytes.
 	MaxOrderSizeInBytes = 16000
-	messageOverhead     = len(`{"messageType":"order","Order":}`)
-	// MaxMessageSizeInBytes is the maximum size for messages sent through
-	// GossipSub. It is the max order size plus some overhead for the message
-	// format.
-	MaxMessageSizeInBytes = MaxOrderSizeInBytes + messageOverhead
+	// MessageOverheadInBytes is the number of bytes added to an encoded order
+	// when it is wrapped in a GossipSub message.
+	MessageOverheadInBytes = len(`{"messageType":"order","Order":}`)
+	// MaxMessageSizeInBytes is the default maximum size for messages sent
+	// through GossipSub. It is the max order size plus some overhead for the
+	// message format.
+	MaxMessageSizeInBytes = MaxOrderSizeInBytes + MessageOverheadInBytes
+	// MaxPubSubTransportMessageSizeInBytes is the largest message that the
+	// GossipSub transport will read from a stream. Larger messages are dropped by
+	// libp2p before they reach any of our validators, so the maximum order size
+	// must never result in messages larger than this.
+	MaxPubSubTransportMessageSizeInBytes = 1 << 20
 )
 
+// MaxMessageSizeForOrderSize returns the maximum size for GossipSub messages
+// given the maximum size for encoded orders.
+func MaxMessageSizeForOrderSize(maxOrderSizeInBytes int) int {
+	return maxOrderSizeInBytes + MessageOverheadInBytes
+}
+
 // MaxBlocksStoredInNonArchiveNode is the max number of historical blocks for which a regular Ethereum
 // node stores archive-level state. One cannot make `eth_call` requests specifying blocks earlier than
 // 128 blocks ago on non-archive nodes.
@@ -96,10 +109,10 @@ const MaxBlocksStoredInNonArchiveNode = 128
 var (
 	// ErrMaxMessageSize is returned or emitted when a GossipSub message exceeds
 	// the max size.
-	ErrMaxMessageSize = fmt.Errorf("message exceeds maximum size of %d bytes", MaxMessageSizeInBytes)
+	ErrMaxMessageSize = errors.New("message exceeds maximum size")
 	// ErrMaxOrderSize is returned or emitted when a signed order encoded as JSON
 	// exceeds the max size.
-	ErrMaxOrderSize = fmt.Errorf("order exceeds maximum size of %d bytes", MaxOrderSizeInBytes)
+	ErrMaxOrderSize = errors.New("order exceeds maximum size")
 )
 
 const ParityFilterUnknownBlock = "One of the blocks specified in filter (fromBlock, toBlock or blockHash) cannot be found"
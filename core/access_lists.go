@@ -0,0 +1,238 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/0xProject/0x-mesh/common/types"
+	"github.com/0xProject/0x-mesh/db"
+	"github.com/0xProject/0x-mesh/meshdb"
+	"github.com/0xProject/0x-mesh/p2p/banner"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrInvalidIPRange is returned by BanIPRange and UnbanIPRange if the given IP
+// range is neither in CIDR notation nor a single IP address.
+type ErrInvalidIPRange struct {
+	ipRange string
+}
+
+func (e ErrInvalidIPRange) Error() string {
+	return fmt.Sprintf("invalid IP range (expected CIDR notation or an IP address): %q", e.ipRange)
+}
+
+// ErrIPRangeNotBanned is returned by UnbanIPRange if the given IP range is not
+// banned.
+type ErrIPRangeNotBanned struct {
+	ipRange string
+}
+
+func (e ErrIPRangeNotBanned) Error() string {
+	return fmt.Sprintf("IP range is not banned: %s", e.ipRange)
+}
+
+// ErrInvalidAllowlistEntry is returned by AddToAllowlist and
+// RemoveFromAllowlist if the given entry is neither a peer ID nor an IP range.
+type ErrInvalidAllowlistEntry struct {
+	entry string
+}
+
+func (e ErrInvalidAllowlistEntry) Error() string {
+	return fmt.Sprintf("invalid allowlist entry (expected a peer ID or an IP range): %q", e.entry)
+}
+
+// ErrNotOnAllowlist is returned by RemoveFromAllowlist if the given entry is
+// not on the allowlist.
+type ErrNotOnAllowlist struct {
+	entry string
+}
+
+func (e ErrNotOnAllowlist) Error() string {
+	return fmt.Sprintf("entry is not on the allowlist: %s", e.entry)
+}
+
+// BanIPRange bans all IP addresses in the given range (in CIDR notation or a
+// single IP address) and stores the ban in the database so that it persists
+// across restarts. If the range is already banned, the existing ban is
+// replaced. It returns banner.ErrProtectedIP if the range contains the IP
+// address of a bootstrap node.
+func (app *App) BanIPRange(ipRange string, reason string) error {
+	<-app.started
+
+	if !app.isEnabled(SubsystemP2P) {
+		return ErrSubsystemDisabled{Subsystem: SubsystemP2P}
+	}
+
+	ipNet, err := banner.ParseIPRange(ipRange)
+	if err != nil {
+		return ErrInvalidIPRange{ipRange: ipRange}
+	}
+	if err := app.node.BanIPRange(banner.IPRangeBan{
+		IPRange: ipNet,
+		Reason:  reason,
+	}); err != nil {
+		return err
+	}
+	return app.db.SaveIPRangeBan(&meshdb.IPRangeBan{
+		IPRange:   ipNet.String(),
+		Reason:    reason,
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+// UnbanIPRange lifts the ban on the given IP range and removes it from the
+// database. The range must match the banned range exactly.
+func (app *App) UnbanIPRange(ipRange string) error {
+	<-app.started
+
+	if !app.isEnabled(SubsystemP2P) {
+		return ErrSubsystemDisabled{Subsystem: SubsystemP2P}
+	}
+
+	ipNet, err := banner.ParseIPRange(ipRange)
+	if err != nil {
+		return ErrInvalidIPRange{ipRange: ipRange}
+	}
+	wasBanned := app.node.UnbanIPRange(ipNet)
+	if err := app.db.DeleteIPRangeBan(ipNet.String()); err != nil {
+		if _, ok := err.(db.NotFoundError); !ok {
+			return err
+		}
+		if !wasBanned {
+			return ErrIPRangeNotBanned{ipRange: ipNet.String()}
+		}
+	}
+	return nil
+}
+
+// GetIPRangeBans returns all IP range bans.
+func (app *App) GetIPRangeBans() ([]*types.IPRangeBan, error) {
+	<-app.started
+
+	if !app.isEnabled(SubsystemP2P) {
+		return nil, ErrSubsystemDisabled{Subsystem: SubsystemP2P}
+	}
+
+	nodeBans := app.node.IPRangeBans()
+	ipRangeBans := make([]*types.IPRangeBan, len(nodeBans))
+	for i, ban := range nodeBans {
+		ipRangeBans[i] = &types.IPRangeBan{
+			IPRange: ban.IPRange.String(),
+			Reason:  ban.Reason,
+		}
+	}
+	return ipRangeBans, nil
+}
+
+// AddToAllowlist adds a peer ID or an IP range to the allowlist and stores it
+// in the database so that it persists across restarts. While the allowlist is
+// not empty, the node only connects to peers which are on it. This includes
+// bootstrap nodes, so they usually need to be added as well.
+func (app *App) AddToAllowlist(entry string) error {
+	<-app.started
+
+	if !app.isEnabled(SubsystemP2P) {
+		return ErrSubsystemDisabled{Subsystem: SubsystemP2P}
+	}
+
+	parsedEntry, err := banner.ParseAllowlistEntry(entry)
+	if err != nil {
+		return ErrInvalidAllowlistEntry{entry: entry}
+	}
+	if err := app.db.SaveAllowlistEntry(&meshdb.AllowlistEntry{
+		Value:     parsedEntry.String(),
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		return err
+	}
+	app.node.AddToAllowlist(parsedEntry)
+	return nil
+}
+
+// RemoveFromAllowlist removes a peer ID or an IP range from the allowlist and
+// the database.
+func (app *App) RemoveFromAllowlist(entry string) error {
+	<-app.started
+
+	if !app.isEnabled(SubsystemP2P) {
+		return ErrSubsystemDisabled{Subsystem: SubsystemP2P}
+	}
+
+	parsedEntry, err := banner.ParseAllowlistEntry(entry)
+	if err != nil {
+		return ErrInvalidAllowlistEntry{entry: entry}
+	}
+	wasAllowed := app.node.RemoveFromAllowlist(parsedEntry)
+	if err := app.db.DeleteAllowlistEntry(parsedEntry.String()); err != nil {
+		if _, ok := err.(db.NotFoundError); !ok {
+			return err
+		}
+		if !wasAllowed {
+			return ErrNotOnAllowlist{entry: parsedEntry.String()}
+		}
+	}
+	return nil
+}
+
+// GetAllowlist returns all peer IDs and IP ranges on the allowlist. An empty
+// allowlist means that the node connects to any peer which is not banned.
+func (app *App) GetAllowlist() ([]string, error) {
+	<-app.started
+
+	if !app.isEnabled(SubsystemP2P) {
+		return nil, ErrSubsystemDisabled{Subsystem: SubsystemP2P}
+	}
+
+	nodeEntries := app.node.Allowlist()
+	allowlist := make([]string, len(nodeEntries))
+	for i, entry := range nodeEntries {
+		allowlist[i] = entry.String()
+	}
+	return allowlist, nil
+}
+
+// applyStoredAccessLists applies the IP range bans and the allowlist stored in
+// the database. It should be called after app.node is initialized but before
+// it is started.
+func (app *App) applyStoredAccessLists() error {
+	storedBans, err := app.db.FindAllIPRangeBans()
+	if err != nil {
+		return err
+	}
+	for _, storedBan := range storedBans {
+		ipNet, err := banner.ParseIPRange(storedBan.IPRange)
+		if err != nil {
+			log.WithField("ipRange", storedBan.IPRange).Warn("ignoring stored ban with invalid IP range")
+			continue
+		}
+		if err := app.node.BanIPRange(banner.IPRangeBan{
+			IPRange: ipNet,
+			Reason:  storedBan.Reason,
+		}); err != nil {
+			log.WithFields(log.Fields{
+				"error":   err.Error(),
+				"ipRange": storedBan.IPRange,
+			}).Warn("could not apply stored IP range ban")
+		}
+	}
+
+	storedEntries, err := app.db.FindAllAllowlistEntries()
+	if err != nil {
+		return err
+	}
+	for _, storedEntry := range storedEntries {
+		entry, err := banner.ParseAllowlistEntry(storedEntry.Value)
+		if err != nil {
+			log.WithField("entry", storedEntry.Value).Warn("ignoring invalid stored allowlist entry")
+			continue
+		}
+		app.node.AddToAllowlist(entry)
+	}
+	if len(storedBans) > 0 || len(storedEntries) > 0 {
+		log.WithFields(log.Fields{
+			"numIPRangeBans":      len(storedBans),
+			"numAllowlistEntries": len(storedEntries),
+		}).Info("applied stored IP range bans and allowlist")
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/0xProject/0x-mesh/zeroex"
+)
+
+func validateUnknownAssetProxyPolicy(config Config) error {
+	switch config.UnknownAssetProxyPolicy {
+	case unknownAssetProxyPolicyReject, unknownAssetProxyPolicyAccept, unknownAssetProxyPolicyQuarantine:
+		return nil
+	default:
+		return fmt.Errorf("config.UnknownAssetProxyPolicy is invalid: %q (must be %q, %q or %q)", config.UnknownAssetProxyPolicy, unknownAssetProxyPolicyReject, unknownAssetProxyPolicyAccept, unknownAssetProxyPolicyQuarantine)
+	}
+}
+
+// isQuarantined returns true if the given order must not be shared with peers
+// because it involves an unrecognized asset proxy and
+// Config.UnknownAssetProxyPolicy is "quarantine".
+func (app *App) isQuarantined(order *zeroex.SignedOrder) bool {
+	if app.config.UnknownAssetProxyPolicy != unknownAssetProxyPolicyQuarantine {
+		return false
+	}
+	for _, assetData := range [][]byte{order.MakerAssetData, order.TakerAssetData, order.MakerFeeAssetData, order.TakerFeeAssetData} {
+		if app.assetDataDecoder.HasUnrecognizedAssetProxy(assetData) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"sort"
+
+	"github.com/0xProject/0x-mesh/common/types"
+	"github.com/libp2p/go-libp2p-core/metrics"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+)
+
+// maxPeersInBandwidthStats is the maximum number of peers included in the
+// bandwidth section of the stats.
+const maxPeersInBandwidthStats = 10
+
+// bandwidthByProtocol converts the bandwidth reported by libp2p for each
+// protocol to stats, sorted by protocol ID.
+func bandwidthByProtocol(statsByProtocol map[protocol.ID]metrics.Stats) []*types.ProtocolBandwidthStats {
+	result := make([]*types.ProtocolBandwidthStats, 0, len(statsByProtocol))
+	for protocolID, stats := range statsByProtocol {
+		result = append(result, &types.ProtocolBandwidthStats{
+			Protocol: string(protocolID),
+			TotalIn:  stats.TotalIn,
+			TotalOut: stats.TotalOut,
+			RateIn:   stats.RateIn,
+			RateOut:  stats.RateOut,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Protocol < result[j].Protocol
+	})
+	return result
+}
+
+// topPeersByBandwidth converts the bandwidth reported by libp2p for each peer
+// to stats and returns at most max of them, ordered by total bandwidth used
+// (incoming plus outgoing) from highest to lowest.
+func topPeersByBandwidth(statsByPeer map[peer.ID]metrics.Stats, max int) []*types.PeerBandwidthStats {
+	result := make([]*types.PeerBandwidthStats, 0, len(statsByPeer))
+	for peerID, stats := range statsByPeer {
+		result = append(result, &types.PeerBandwidthStats{
+			PeerID:   peerID.Pretty(),
+			TotalIn:  stats.TotalIn,
+			TotalOut: stats.TotalOut,
+			RateIn:   stats.RateIn,
+			RateOut:  stats.RateOut,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		totalI := result[i].TotalIn + result[i].TotalOut
+		totalJ := result[j].TotalIn + result[j].TotalOut
+		if totalI != totalJ {
+			return totalI > totalJ
+		}
+		return result[i].PeerID < result[j].PeerID
+	})
+	if len(result) > max {
+		result = result[:max]
+	}
+	return result
+}
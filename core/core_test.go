@@ -4,20 +4,34 @@ package core
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
 	"flag"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/0xProject/0x-mesh/common/types"
 	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/core/ordersync"
 	"github.com/0xProject/0x-mesh/ethereum"
+	"github.com/0xProject/0x-mesh/ethereum/ethrpcclient"
 	"github.com/0xProject/0x-mesh/meshdb"
+	"github.com/0xProject/0x-mesh/orderfilter"
+	"github.com/0xProject/0x-mesh/p2p"
 	"github.com/0xProject/0x-mesh/scenario"
 	"github.com/0xProject/0x-mesh/scenario/orderopts"
 	"github.com/0xProject/0x-mesh/zeroex"
 	"github.com/davecgh/go-spew/spew"
+	"github.com/ethereum/go-ethereum/common"
 	ethrpc "github.com/ethereum/go-ethereum/rpc"
 	"github.com/google/uuid"
+	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/metrics"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -207,7 +221,7 @@ func TestOrderSync(t *testing.T) {
 	// We have to wait for latest block to be processed by the Mesh node.
 	time.Sleep(blockProcessingWaitTime)
 
-	results, err := originalNode.orderWatcher.ValidateAndStoreValidOrders(ctx, originalOrders, true, constants.TestChainID)
+	results, err := originalNode.orderWatcher.ValidateAndStoreValidOrders(ctx, originalOrders, true, meshdb.OrderSourceGossip, constants.TestChainID)
 	require.NoError(t, err)
 	require.Empty(t, results.Rejected, "tried to add orders but some were invalid: \n%s\n", spew.Sdump(results))
 
@@ -255,7 +269,7 @@ OrderEventLoop:
 
 	// Test that the orders are actually in the database and are returned by
 	// GetOrders.
-	newNodeOrdersResp, err := newNode.GetOrders(0, len(originalOrders), "")
+	newNodeOrdersResp, err := newNode.GetOrders(ctx, 0, len(originalOrders), "")
 	require.NoError(t, err)
 	assert.Len(t, newNodeOrdersResp.OrdersInfos, len(originalOrders), "new node should have %d orders", len(originalOrders))
 	for _, expectedOrder := range originalOrders {
@@ -273,9 +287,565 @@ OrderEventLoop:
 	wg.Wait()
 }
 
+func TestValidateMaxOrderSizeConfig(t *testing.T) {
+	t.Parallel()
+
+	validConfig := Config{
+		EthereumRPCMaxContentLength: 524288,
+		MaxOrderSizeInBytes:         constants.MaxOrderSizeInBytes,
+		OversizedOrderPolicy:        oversizedOrderPolicyPenalize,
+	}
+	require.NoError(t, validateMaxOrderSizeConfig(validConfig))
+
+	ignoreConfig := validConfig
+	ignoreConfig.OversizedOrderPolicy = oversizedOrderPolicyIgnore
+	assert.NoError(t, validateMaxOrderSizeConfig(ignoreConfig))
+
+	invalidPolicyConfig := validConfig
+	invalidPolicyConfig.OversizedOrderPolicy = "foo"
+	assert.Error(t, validateMaxOrderSizeConfig(invalidPolicyConfig))
+
+	tooLargeForRPCConfig := validConfig
+	tooLargeForRPCConfig.MaxOrderSizeInBytes = validConfig.EthereumRPCMaxContentLength + 1
+	assert.Error(t, validateMaxOrderSizeConfig(tooLargeForRPCConfig))
+
+	// Orders that could never fit in a GossipSub message are not allowed.
+	tooLargeForPubSubConfig := validConfig
+	tooLargeForPubSubConfig.EthereumRPCMaxContentLength = 2 * constants.MaxPubSubTransportMessageSizeInBytes
+	tooLargeForPubSubConfig.MaxOrderSizeInBytes = constants.MaxPubSubTransportMessageSizeInBytes
+	assert.Error(t, validateMaxOrderSizeConfig(tooLargeForPubSubConfig))
+}
+
+func TestParseTrustedMakerAddresses(t *testing.T) {
+	t.Parallel()
+
+	makerAddresses, err := parseTrustedMakerAddresses(Config{})
+	require.NoError(t, err)
+	assert.Empty(t, makerAddresses)
+
+	makerAddresses, err = parseTrustedMakerAddresses(Config{
+		TrustedMakerAddresses: "0x6ecbe1db9ef729cbe972c83fb886247691fb6beb, 0xe36ea790bc9d7ab70c55260c66d52b1eca985f84",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []common.Address{
+		common.HexToAddress("0x6ecbe1db9ef729cbe972c83fb886247691fb6beb"),
+		common.HexToAddress("0xe36ea790bc9d7ab70c55260c66d52b1eca985f84"),
+	}, makerAddresses)
+
+	_, err = parseTrustedMakerAddresses(Config{TrustedMakerAddresses: "0x6ecbe1db9ef729cbe972c83fb886247691fb6beb,foo"})
+	assert.Error(t, err)
+}
+
+func TestParseSubscribeTopicShards(t *testing.T) {
+	t.Parallel()
+
+	shards, err := parseSubscribeTopicShards(Config{})
+	require.NoError(t, err)
+	assert.Empty(t, shards)
+
+	shards, err = parseSubscribeTopicShards(Config{TopicShards: 16, SubscribeTopicShards: "3, 0,15"})
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 0, 15}, shards)
+
+	_, err = parseSubscribeTopicShards(Config{TopicShards: 16, SubscribeTopicShards: "16"})
+	assert.Error(t, err)
+	_, err = parseSubscribeTopicShards(Config{TopicShards: 16, SubscribeTopicShards: "foo"})
+	assert.Error(t, err)
+	_, err = parseSubscribeTopicShards(Config{SubscribeTopicShards: "0"})
+	assert.Error(t, err)
+	_, err = parseSubscribeTopicShards(Config{TopicShards: 257})
+	assert.Error(t, err)
+}
+
+func TestNewAdditionalOrderFilters(t *testing.T) {
+	t.Parallel()
+
+	primary, err := orderfilter.New(constants.TestChainID, orderfilter.DefaultCustomOrderSchema, contractAddresses, orderfilter.ProtocolVersionV3)
+	require.NoError(t, err)
+	newConfig := func(additionalOrderFilters string) Config {
+		return Config{EthereumChainID: constants.TestChainID, AdditionalOrderFilters: additionalOrderFilters}
+	}
+
+	filters, err := newAdditionalOrderFilters(newConfig(""), contractAddresses, primary)
+	require.NoError(t, err)
+	assert.Empty(t, filters)
+
+	filters, err = newAdditionalOrderFilters(newConfig(`{"zrx":"takerFee == 0; expiresWithin 1h","sender":{"properties":{"senderAddress":{"const":"0x00000000000000000000000000000000ba5eba11"}}}}`), contractAddresses, primary)
+	require.NoError(t, err)
+	require.Len(t, filters, 2)
+	assert.Equal(t, "sender", filters[0].name)
+	assert.Equal(t, "zrx", filters[1].name)
+	assert.Equal(t, time.Hour, filters[1].filter.MaxExpirationDuration())
+	assert.Equal(t, time.Hour, orderFiltersMaxExpirationDuration(filters[1:]))
+	assert.Equal(t, time.Duration(0), orderFiltersMaxExpirationDuration(filters))
+
+	// Messages are validated against the filter of the topic they were
+	// received on.
+	app := &App{
+		config:                 Config{TopicShards: 4},
+		orderFilter:            primary,
+		additionalOrderFilters: filters,
+	}
+	assert.Equal(t, filters[1].filter, app.orderFilterForTopic(p2p.ShardTopic(filters[1].filter.Topic(), 4, 2)))
+	assert.Equal(t, primary, app.orderFilterForTopic(primary.Topic()))
+	assert.Equal(t, primary, app.orderFilterForTopic("/0x-mesh-testing/unknown-topic"))
+
+	for _, invalid := range []string{
+		`[]`,
+		`{"primary":{}}`,
+		`{"not a valid name":{}}`,
+		`{"same-as-primary":{}}`,
+		`{"a":"takerFee == 0","b":"takerFee == 0"}`,
+		`{"a":"takerFee ~ 0"}`,
+		`{"a":{"type":5}}`,
+	} {
+		_, err := newAdditionalOrderFilters(newConfig(invalid), contractAddresses, primary)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestParseBootstrapConfig(t *testing.T) {
+	t.Parallel()
+
+	bootstrapList, signedBootstrapLists, err := parseBootstrapConfig(Config{EthereumChainID: 1})
+	require.NoError(t, err)
+	assert.Equal(t, p2p.DefaultBootstrapList, bootstrapList)
+	assert.Nil(t, signedBootstrapLists)
+
+	bootstrapList, _, err = parseBootstrapConfig(Config{EthereumChainID: 1337, BootstrapEnvironment: p2p.BootstrapEnvironmentDevelopment})
+	require.NoError(t, err)
+	assert.Empty(t, bootstrapList)
+
+	_, pubKey, err := p2pcrypto.GenerateSecp256k1Key(rand.Reader)
+	require.NoError(t, err)
+	encodedPubKey, err := p2pcrypto.MarshalPublicKey(pubKey)
+	require.NoError(t, err)
+	_, signedBootstrapLists, err = parseBootstrapConfig(Config{
+		EthereumChainID:              1,
+		BootstrapListURLs:            "https://example.com/a.json, https://example.com/b.json",
+		BootstrapListPublicKey:       p2pcrypto.ConfigEncodeKey(encodedPubKey),
+		BootstrapListRefreshInterval: 10 * time.Minute,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/a.json", "https://example.com/b.json"}, signedBootstrapLists.URLs)
+	assert.True(t, pubKey.Equals(signedBootstrapLists.PublicKey))
+	assert.Equal(t, 1, signedBootstrapLists.ChainID)
+	assert.Equal(t, p2p.BootstrapEnvironmentProduction, signedBootstrapLists.Environment)
+	assert.Equal(t, 10*time.Minute, signedBootstrapLists.RefreshInterval)
+
+	_, _, err = parseBootstrapConfig(Config{EthereumChainID: 1, BootstrapEnvironment: "staging"})
+	assert.Error(t, err)
+	_, _, err = parseBootstrapConfig(Config{EthereumChainID: 1, BootstrapList: "foo"})
+	assert.Error(t, err)
+	_, _, err = parseBootstrapConfig(Config{EthereumChainID: 1, BootstrapListURLs: "https://example.com/a.json"})
+	assert.Error(t, err)
+	_, _, err = parseBootstrapConfig(Config{EthereumChainID: 1, BootstrapListURLs: "https://example.com/a.json", BootstrapListPublicKey: "foo"})
+	assert.Error(t, err)
+}
+
+func TestParseDisabledSubsystems(t *testing.T) {
+	t.Parallel()
+
+	disabled, err := parseDisabledSubsystems(Config{})
+	require.NoError(t, err)
+	assert.Empty(t, disabled)
+
+	// Disabling p2p or orderwatch also disables ordersync.
+	disabled, err = parseDisabledSubsystems(Config{DisabledSubsystems: "p2p"})
+	require.NoError(t, err)
+	assert.Equal(t, map[Subsystem]bool{SubsystemP2P: true, SubsystemOrderSync: true}, disabled)
+	disabled, err = parseDisabledSubsystems(Config{DisabledSubsystems: " orderwatch"})
+	require.NoError(t, err)
+	assert.Equal(t, map[Subsystem]bool{SubsystemOrderWatch: true, SubsystemOrderSync: true}, disabled)
+	disabled, err = parseDisabledSubsystems(Config{DisabledSubsystems: "ordersync"})
+	require.NoError(t, err)
+	assert.Equal(t, map[Subsystem]bool{SubsystemOrderSync: true}, disabled)
+
+	_, err = parseDisabledSubsystems(Config{DisabledSubsystems: "graphql"})
+	assert.Error(t, err)
+	_, err = parseDisabledSubsystems(Config{DisabledSubsystems: "ordersync", WarmStartMinPeers: 1})
+	assert.Error(t, err)
+}
+
+func TestValidateOrderStateHistoryBackfillConfig(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, validateOrderStateHistoryBackfillConfig(Config{}, nil))
+	assert.NoError(t, validateOrderStateHistoryBackfillConfig(Config{OrderStateHistoryBackfillFromBlock: 100, OrderStateHistoryRetentionBlocks: 1000}, nil))
+	assert.Error(t, validateOrderStateHistoryBackfillConfig(Config{OrderStateHistoryBackfillFromBlock: -1, OrderStateHistoryRetentionBlocks: 1000}, nil))
+	assert.Error(t, validateOrderStateHistoryBackfillConfig(Config{OrderStateHistoryBackfillFromBlock: 100}, nil))
+	disabled := map[Subsystem]bool{SubsystemOrderWatch: true}
+	assert.Error(t, validateOrderStateHistoryBackfillConfig(Config{OrderStateHistoryBackfillFromBlock: 100, OrderStateHistoryRetentionBlocks: 1000}, disabled))
+}
+
+func TestValidateBlockPollingIntervalConfig(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, validateBlockPollingIntervalConfig(Config{BlockPollingInterval: 5 * time.Second}))
+	assert.NoError(t, validateBlockPollingIntervalConfig(Config{BlockPollingInterval: 5 * time.Second, MinBlockPollingInterval: 500 * time.Millisecond, MaxBlockPollingInterval: 30 * time.Second}))
+	assert.Error(t, validateBlockPollingIntervalConfig(Config{}))
+	assert.Error(t, validateBlockPollingIntervalConfig(Config{BlockPollingInterval: 5 * time.Second, MinBlockPollingInterval: 500 * time.Millisecond}))
+	assert.Error(t, validateBlockPollingIntervalConfig(Config{BlockPollingInterval: 5 * time.Second, MaxBlockPollingInterval: 30 * time.Second}))
+	assert.Error(t, validateBlockPollingIntervalConfig(Config{BlockPollingInterval: 5 * time.Second, MinBlockPollingInterval: time.Minute, MaxBlockPollingInterval: 30 * time.Second}))
+}
+
+func TestNextResourceLimits(t *testing.T) {
+	t.Parallel()
+
+	config := Config{MaxMemoryBytes: 1000, AdaptiveResourceLimits: true}
+	maxLimits := resourceLimits{peerCountLow: 100, peerCountHigh: 110, validationConcurrency: 5}
+	over := types.ResourceUsage{CPUPercent: -1, MemoryBytes: 1200}
+	between := types.ResourceUsage{CPUPercent: -1, MemoryBytes: 900}
+	under := types.ResourceUsage{CPUPercent: -1, MemoryBytes: 500}
+
+	limits := nextResourceLimits(config, over, maxLimits, maxLimits)
+	assert.Equal(t, resourceLimits{peerCountLow: 75, peerCountHigh: 85, validationConcurrency: 2}, limits)
+	for i := 0; i < 20; i++ {
+		limits = nextResourceLimits(config, over, limits, maxLimits)
+	}
+	assert.Equal(t, resourceLimits{peerCountLow: minAdaptivePeerCountLow, peerCountHigh: minAdaptivePeerCountLow + 10, validationConcurrency: 1}, limits)
+
+	// The limits are only raised once there is enough headroom.
+	assert.Equal(t, limits, nextResourceLimits(config, between, limits, maxLimits))
+	limits = nextResourceLimits(config, under, limits, maxLimits)
+	assert.Equal(t, resourceLimits{peerCountLow: minAdaptivePeerCountLow + adaptivePeerCountStep, peerCountHigh: minAdaptivePeerCountLow + adaptivePeerCountStep + 10, validationConcurrency: 2}, limits)
+	for i := 0; i < 20; i++ {
+		limits = nextResourceLimits(config, under, limits, maxLimits)
+	}
+	assert.Equal(t, maxLimits, limits)
+
+	// The peer count watermarks are left alone if the p2p subsystem is
+	// disabled.
+	noP2P := resourceLimits{validationConcurrency: 5}
+	assert.Equal(t, resourceLimits{validationConcurrency: 2}, nextResourceLimits(config, over, noP2P, noP2P))
+
+	assert.NoError(t, validateResourceUsageConfig(Config{ResourceSampleInterval: time.Second, MaxCPUPercent: 50, AdaptiveResourceLimits: true}))
+	assert.Error(t, validateResourceUsageConfig(Config{ResourceSampleInterval: time.Second, AdaptiveResourceLimits: true}))
+	assert.Error(t, validateResourceUsageConfig(Config{MaxCPUPercent: 50, AdaptiveResourceLimits: true}))
+	assert.Error(t, validateResourceUsageConfig(Config{MaxMemoryBytes: -1}))
+}
+
+func TestOrderUpdateHintLimiter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	limiter := newOrderUpdateHintLimiter()
+	limiter.nowFunc = func() time.Time { return now }
+
+	// Each peer can send a burst of hints and is then rate limited.
+	peerA := peer.ID("peerA")
+	peerB := peer.ID("peerB")
+	for i := 0; i < orderUpdateHintsBurstPerPeer; i++ {
+		assert.True(t, limiter.allowPeer(peerA))
+	}
+	assert.False(t, limiter.allowPeer(peerA))
+	assert.True(t, limiter.allowPeer(peerB))
+	now = now.Add(time.Second)
+	assert.True(t, limiter.allowPeer(peerA))
+
+	// Orders are only re-validated once per cooldown period.
+	orderHashA := common.HexToHash("0x1")
+	orderHashB := common.HexToHash("0x2")
+	assert.Equal(t, []common.Hash{orderHashA}, limiter.takeOrderHashes([]common.Hash{orderHashA, orderHashA}))
+	assert.Equal(t, []common.Hash{orderHashB}, limiter.takeOrderHashes([]common.Hash{orderHashA, orderHashB}))
+	now = now.Add(orderUpdateHintCooldown)
+	assert.Equal(t, []common.Hash{orderHashA, orderHashB}, limiter.takeOrderHashes([]common.Hash{orderHashA, orderHashB}))
+}
+
+func TestParseEthereumRPCDialConfig(t *testing.T) {
+	t.Parallel()
+
+	dialConfig, err := parseEthereumRPCDialConfig(Config{})
+	require.NoError(t, err)
+	assert.Equal(t, ethrpcclient.DialConfig{}, dialConfig)
+
+	dialConfig, err = parseEthereumRPCDialConfig(Config{
+		EthereumRPCHeaders:     `{"authorization": "Bearer foo", "X-Api-Key": "bar"}`,
+		EthereumRPCJWTSecret:   "0x0102",
+		EthereumRPCTLSCertFile: "client.crt",
+		EthereumRPCTLSKeyFile:  "client.key",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ethrpcclient.DialConfig{
+		Headers: http.Header{
+			"Authorization": []string{"Bearer foo"},
+			"X-Api-Key":     []string{"bar"},
+		},
+		JWTSecret:   []byte{1, 2},
+		TLSCertFile: "client.crt",
+		TLSKeyFile:  "client.key",
+	}, dialConfig)
+
+	_, err = parseEthereumRPCDialConfig(Config{EthereumRPCHeaders: "Authorization: Bearer foo"})
+	assert.Error(t, err)
+
+	_, err = parseEthereumRPCDialConfig(Config{EthereumRPCJWTSecret: "not hex"})
+	assert.Error(t, err)
+}
+
+func TestTopPeersByBandwidth(t *testing.T) {
+	t.Parallel()
+
+	peerIDs := make([]peer.ID, 3)
+	for i := range peerIDs {
+		peerIDs[i] = peer.ID(fmt.Sprintf("peer-%d", i))
+	}
+	statsByPeer := map[peer.ID]metrics.Stats{
+		peerIDs[0]: {TotalIn: 10, TotalOut: 10},
+		peerIDs[1]: {TotalIn: 5, TotalOut: 100},
+		peerIDs[2]: {TotalIn: 1, TotalOut: 1},
+	}
+
+	actual := topPeersByBandwidth(statsByPeer, 2)
+	require.Len(t, actual, 2)
+	assert.Equal(t, peerIDs[1].Pretty(), actual[0].PeerID)
+	assert.Equal(t, int64(100), actual[0].TotalOut)
+	assert.Equal(t, peerIDs[0].Pretty(), actual[1].PeerID)
+
+	assert.Len(t, topPeersByBandwidth(statsByPeer, 10), 3)
+}
+
 func setupSubTest(t *testing.T) func(t *testing.T) {
 	blockchainLifecycle.Start(t)
 	return func(t *testing.T) {
 		blockchainLifecycle.Revert(t)
 	}
 }
+
+func TestScoreOrderSyncProviders(t *testing.T) {
+	fast := "16Uiu2HAmGd949LwaV4KNvK2WDSiMVy7xEmW983VH75CMmefmMpP7"
+	slow := "16Uiu2HAmVqV4kepwSiNRmvKiBxwpt4EQJi3pAe9auSMyGjzA1eBZ"
+	failing := "16Uiu2HAmAmmoyR4M492Aq8vWFh4gyVr9Gz2uEGAWjdpGPfKpcw5F"
+	records := []*meshdb.OrderSyncRecord{
+		{ProviderID: fast, Succeeded: true, Duration: time.Second, Latency: 50 * time.Millisecond, OrdersReceived: 1000, OrdersAccepted: 1000},
+		{ProviderID: slow, Succeeded: true, Duration: 4 * time.Second, Latency: 400 * time.Millisecond, OrdersReceived: 1000, OrdersAccepted: 1000},
+		{ProviderID: slow, Succeeded: true, Duration: 4 * time.Second, Latency: 400 * time.Millisecond, OrdersReceived: 1000, OrdersAccepted: 1000},
+		{ProviderID: failing, Succeeded: false, Latency: 20 * time.Millisecond},
+		{ProviderID: failing, Succeeded: false},
+	}
+	scores := scoreOrderSyncProviders(records)
+
+	fastID, err := peer.IDB58Decode(fast)
+	require.NoError(t, err)
+	slowID, err := peer.IDB58Decode(slow)
+	require.NoError(t, err)
+	failingID, err := peer.IDB58Decode(failing)
+	require.NoError(t, err)
+	// The slow provider has more successful attempts but the fast one should
+	// still be preferred.
+	assert.InDelta(t, 1, scores[fastID], 0.0001)
+	assert.InDelta(t, (0.125+0.25)/2, scores[slowID], 0.0001)
+	assert.Equal(t, -2*orderSyncFailurePenalty, scores[failingID])
+}
+
+func TestOrderSyncHistoryProviderStats(t *testing.T) {
+	meshDB, err := meshdb.New("/tmp/meshdb_testing/"+uuid.New().String(), contractAddresses)
+	require.NoError(t, err)
+	defer meshDB.Close()
+
+	providerA := "16Uiu2HAmGd949LwaV4KNvK2WDSiMVy7xEmW983VH75CMmefmMpP7"
+	providerB := "16Uiu2HAmVqV4kepwSiNRmvKiBxwpt4EQJi3pAe9auSMyGjzA1eBZ"
+	providerAID, err := peer.IDB58Decode(providerA)
+	require.NoError(t, err)
+	providerBID, err := peer.IDB58Decode(providerB)
+	require.NoError(t, err)
+	now := time.Now().UTC()
+	// Records from a previous run are included in the stats until they are
+	// cleared by the next call to RecordOutcome.
+	require.NoError(t, meshDB.OrderSyncRecords.Insert(&meshdb.OrderSyncRecord{
+		ProviderID:     providerA,
+		Subprotocol:    "/pagination-with-filter/version/0",
+		StartTime:      now.Add(-orderSyncHistoryRetention - time.Minute),
+		Duration:       4 * time.Second,
+		OrdersReceived: 10,
+		OrdersAccepted: 8,
+		OrdersRejected: map[string]int{"OrderExpired": 2},
+		Succeeded:      true,
+	}))
+	require.NoError(t, meshDB.OrderSyncRecords.Insert(&meshdb.OrderSyncRecord{
+		ProviderID:     providerB,
+		StartTime:      now.Add(-orderSyncHistoryRetention - time.Minute),
+		OrdersRejected: map[string]int{},
+		Error:          "context deadline exceeded",
+	}))
+	history, err := newOrderSyncHistory(meshDB)
+	require.NoError(t, err)
+	stats := history.ProviderStats()
+	require.Len(t, stats, 2)
+	assert.Equal(t, providerA, stats[0].PeerID)
+	assert.Equal(t, 8, stats[0].OrdersAccepted)
+	assert.Equal(t, int64(4000), stats[0].AverageDurationMs)
+	assert.Equal(t, providerB, stats[1].PeerID)
+	assert.Equal(t, 1, stats[1].NumFailures)
+
+	require.NoError(t, history.RecordOutcome(&ordersync.Outcome{
+		ProviderID:     providerAID,
+		Subprotocol:    "/pagination-with-filter/version/1",
+		StartTime:      now,
+		Duration:       2 * time.Second,
+		Latency:        100 * time.Millisecond,
+		OrdersReceived: 5,
+		OrdersAccepted: 4,
+		OrdersRejected: map[string]int{"OrderCancelled": 1},
+	}))
+	require.NoError(t, history.RecordOutcome(&ordersync.Outcome{
+		ProviderID:     providerBID,
+		StartTime:      now,
+		OrdersRejected: map[string]int{},
+		Err:            errors.New("stream reset"),
+	}))
+	// The records from the previous run were cleared and subtracted from the
+	// stats.
+	expected := []*types.OrderSyncProviderStats{
+		{
+			PeerID:            providerA,
+			NumSyncs:          1,
+			OrdersReceived:    5,
+			OrdersAccepted:    4,
+			OrdersRejected:    map[string]int{"OrderCancelled": 1},
+			AverageDurationMs: 2000,
+			AverageLatencyMs:  100,
+			LastSyncTime:      now,
+			LastSubprotocol:   "/pagination-with-filter/version/1",
+		},
+		{
+			PeerID:          providerB,
+			NumSyncs:        1,
+			NumFailures:     1,
+			OrdersRejected:  map[string]int{},
+			LastSyncTime:    now,
+			LastSubprotocol: "",
+		},
+	}
+	assert.Equal(t, expected, history.ProviderStats())
+
+	// The totals match the stats computed from the records in the database.
+	reloaded, err := newOrderSyncHistory(meshDB)
+	require.NoError(t, err)
+	actual := reloaded.ProviderStats()
+	require.Len(t, actual, len(expected))
+	for i := range actual {
+		assert.True(t, expected[i].LastSyncTime.Equal(actual[i].LastSyncTime))
+		actual[i].LastSyncTime = expected[i].LastSyncTime
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestDecodeAddressArray(t *testing.T) {
+	tokenA := common.HexToAddress("0xe41d2489571d322189246dafa5ebde1f4699f498")
+	tokenB := common.HexToAddress("0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2")
+	data := append(common.LeftPadBytes([]byte{0x20}, 32), common.LeftPadBytes([]byte{2}, 32)...)
+	data = append(data, common.LeftPadBytes(tokenA.Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(tokenB.Bytes(), 32)...)
+	addresses, err := decodeAddressArray(data)
+	require.NoError(t, err)
+	assert.Equal(t, []common.Address{tokenA, tokenB}, addresses)
+
+	// The length is larger than the data.
+	_, err = decodeAddressArray(data[:len(data)-32])
+	assert.Equal(t, errInvalidRegistryResponse, err)
+	_, err = decodeAddressArray(data[:32])
+	assert.Equal(t, errInvalidRegistryResponse, err)
+}
+
+func TestNewDynamicTokenList(t *testing.T) {
+	tokenList := `{"name":"test","tokens":[{"chainId":1337,"address":"0xe41d2489571d322189246dafa5ebde1f4699f498"},{"chainId":1,"address":"0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2"}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(tokenList))
+	}))
+	defer server.Close()
+
+	config := Config{
+		EthereumChainID:                           constants.TestChainID,
+		CustomOrderFilter:                         orderfilter.DefaultCustomOrderSchema,
+		CustomOrderFilterPresets:                  orderfilter.PresetWhitelistedPairs,
+		CustomOrderFilterTokenList:                server.URL,
+		CustomOrderFilterTokenListRefreshInterval: time.Hour,
+	}
+	dynamicTokenList, err := newDynamicTokenList(context.Background(), config, nil)
+	require.NoError(t, err)
+	require.NotNil(t, dynamicTokenList)
+	assert.Equal(t, []common.Address{common.HexToAddress("0xe41d2489571d322189246dafa5ebde1f4699f498")}, dynamicTokenList.Tokens())
+
+	// The filter refers to the list by its format rather than its tokens.
+	customOrderFilter, err := parseCustomOrderFilter(config)
+	require.NoError(t, err)
+	assert.Contains(t, customOrderFilter, dynamicTokenList.Format())
+
+	// Token list files are compiled into the schema.
+	config.CustomOrderFilterTokenList = "/data/tokens.json"
+	dynamicTokenList, err = newDynamicTokenList(context.Background(), config, nil)
+	require.NoError(t, err)
+	assert.Nil(t, dynamicTokenList)
+}
+
+func TestOrderFeedbackTracker(t *testing.T) {
+	t.Parallel()
+
+	tracker := newOrderFeedbackTracker()
+	author := peer.ID("author")
+	for i := 0; i < p2p.MaxOrderFeedbackHashesPerCode+1; i++ {
+		tracker.recordRejection(author, common.BigToHash(big.NewInt(int64(i))), "OrderExpired")
+	}
+	tracker.recordRejection(author, common.HexToHash("0x1"), "OrderCancelled")
+
+	pending := tracker.takePending()
+	require.Len(t, pending, 1)
+	feedback := pending[author]
+	require.Len(t, feedback.Rejections, 2)
+	assert.Equal(t, "OrderCancelled", feedback.Rejections[0].Code)
+	assert.Equal(t, 1, feedback.Rejections[0].Count)
+	assert.Equal(t, "OrderExpired", feedback.Rejections[1].Code)
+	assert.Equal(t, p2p.MaxOrderFeedbackHashesPerCode+1, feedback.Rejections[1].Count)
+	assert.Len(t, feedback.Rejections[1].OrderHashes, p2p.MaxOrderFeedbackHashesPerCode)
+	assert.Empty(t, tracker.takePending())
+
+	tracker.countRejections(tracker.numRejectionsSent, feedback)
+	stats := tracker.stats()
+	assert.Equal(t, map[string]int64{
+		"OrderCancelled": 1,
+		"OrderExpired":   int64(p2p.MaxOrderFeedbackHashesPerCode + 1),
+	}, stats.NumRejectionsSent)
+	assert.Empty(t, stats.NumRejectionsReceived)
+}
+
+func TestNewTopicMigration(t *testing.T) {
+	t.Parallel()
+
+	filter, err := orderfilter.New(constants.TestChainID, orderfilter.DefaultCustomOrderSchema, contractAddresses, orderfilter.ProtocolVersionV3)
+	require.NoError(t, err)
+	meshDB, err := meshdb.New("/tmp/meshdb_testing/"+uuid.New().String(), contractAddresses)
+	require.NoError(t, err)
+	defer meshDB.Close()
+	require.NoError(t, meshDB.SaveMetadata(&meshdb.Metadata{EthereumChainID: constants.TestChainID}))
+
+	// Without a window, the node doesn't use the legacy topics.
+	migration, err := newTopicMigration(Config{}, meshDB, []*orderfilter.Filter{filter})
+	require.NoError(t, err)
+	assert.False(t, migration.isActive())
+	assert.Empty(t, migration.legacyTopics())
+
+	migration, err = newTopicMigration(Config{TopicMigrationWindow: time.Hour}, meshDB, []*orderfilter.Filter{filter})
+	require.NoError(t, err)
+	require.True(t, migration.isActive())
+	assert.ElementsMatch(t, filter.LegacyTopics(), migration.legacyTopics())
+	for _, legacyTopic := range filter.LegacyTopics() {
+		currentTopic, ok := migration.currentTopicFor(legacyTopic)
+		assert.True(t, ok)
+		assert.Equal(t, filter.Topic(), currentTopic)
+	}
+	_, ok := migration.currentTopicFor(filter.Topic())
+	assert.False(t, ok)
+
+	// The start of the window is persisted, so restarting the node with the
+	// same window doesn't extend it.
+	restarted, err := newTopicMigration(Config{TopicMigrationWindow: time.Hour}, meshDB, []*orderfilter.Filter{filter})
+	require.NoError(t, err)
+	assert.True(t, migration.endTime.Equal(restarted.endTime))
+	restarted, err = newTopicMigration(Config{TopicMigrationWindow: time.Nanosecond}, meshDB, []*orderfilter.Filter{filter})
+	require.NoError(t, err)
+	assert.False(t, restarted.isActive())
+
+	migration.end()
+	assert.False(t, migration.isActive())
+}
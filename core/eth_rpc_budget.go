@@ -0,0 +1,28 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/0xProject/0x-mesh/ethereum/ratelimit"
+)
+
+// ethereumRPCBudget returns the ratelimit.Budget described by the
+// EthereumRPC*Share and EthereumRPCSubsystemPriority config options.
+func ethereumRPCBudget(config Config) (ratelimit.Budget, error) {
+	budget := ratelimit.Budget{
+		Shares: map[ratelimit.Subsystem]float64{
+			ratelimit.SubsystemBlockWatch:      config.EthereumRPCBlockWatchShare,
+			ratelimit.SubsystemOrderValidation: config.EthereumRPCOrderValidationShare,
+			ratelimit.SubsystemAPI:             config.EthereumRPCAPIShare,
+		},
+		Priority: []ratelimit.Subsystem{},
+	}
+	for _, subsystem := range strings.Split(config.EthereumRPCSubsystemPriority, ",") {
+		budget.Priority = append(budget.Priority, ratelimit.Subsystem(strings.TrimSpace(subsystem)))
+	}
+	if err := budget.Validate(); err != nil {
+		return ratelimit.Budget{}, fmt.Errorf("invalid Ethereum RPC request budget: %s", err.Error())
+	}
+	return budget, nil
+}
@@ -8,12 +8,24 @@ import (
 	"github.com/ethereum/go-ethereum/common/math"
 )
 
+// ethRPCCaller is implemented by both ethrpcclient.Client and the RPC clients
+// it wraps.
+type ethRPCCaller interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
 func (app *App) getEthRPCChainID(ctx context.Context) (*big.Int, error) {
+	return getChainID(ctx, app.ethRPCClient)
+}
+
+// getChainID returns the chain ID of the Ethereum RPC endpoint behind the given
+// client.
+func getChainID(ctx context.Context, client ethRPCCaller) (*big.Int, error) {
 	ctx, cancel := context.WithTimeout(ctx, ethereumRPCRequestTimeout)
 	defer cancel()
 
 	var chainIDRaw string
-	err := app.ethRPCClient.CallContext(ctx, &chainIDRaw, "eth_chainId")
+	err := client.CallContext(ctx, &chainIDRaw, "eth_chainId")
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/0xProject/0x-mesh/ethereum/ethrpcclient"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrInvalidEthereumRPCURL is returned by SetEthereumRPCURL if the new
+// Ethereum RPC endpoint cannot be used. The current endpoint is kept in that
+// case.
+type ErrInvalidEthereumRPCURL struct {
+	Reason string
+}
+
+func (e ErrInvalidEthereumRPCURL) Error() string {
+	return fmt.Sprintf("invalid Ethereum RPC URL: %s", e.Reason)
+}
+
+// SetEthereumRPCURL replaces the Ethereum RPC endpoint of the node without
+// restarting it, e.g. to rotate the API key of a provider or to fail over to
+// another provider manually. The new endpoint is dialed with the same headers,
+// JWT secret and TLS configuration as config.EthereumRPCURL, and it must be
+// for the same chain as the node. Once it has been checked, new requests wait
+// until all in-flight requests to the old endpoint have finished and are then
+// sent to the new one. The block watcher resumes from the latest block it
+// stored, so blocks which were missed while the old endpoint was failing are
+// backfilled. The new endpoint is not persisted, so the node uses
+// config.EthereumRPCURL again once it is restarted.
+func (app *App) SetEthereumRPCURL(ctx context.Context, rawurl string) error {
+	<-app.started
+
+	app.setEthereumRPCURLMu.Lock()
+	defer app.setEthereumRPCURLMu.Unlock()
+
+	if rawurl == "" {
+		return ErrInvalidEthereumRPCURL{Reason: "URL cannot be empty"}
+	}
+	dialConfig, err := parseEthereumRPCDialConfig(app.config)
+	if err != nil {
+		return err
+	}
+	rpcClient, err := ethrpcclient.DialEndpoint(ctx, rawurl, dialConfig)
+	if err != nil {
+		return ErrInvalidEthereumRPCURL{Reason: fmt.Sprintf("could not dial endpoint: %s", err.Error())}
+	}
+	chainID, err := getChainID(ctx, rpcClient)
+	if err != nil {
+		rpcClient.Close()
+		return ErrInvalidEthereumRPCURL{Reason: fmt.Sprintf("could not get chain ID: %s", err.Error())}
+	}
+	if chainID.Int64() != int64(app.chainID) {
+		rpcClient.Close()
+		return ErrInvalidEthereumRPCURL{Reason: fmt.Sprintf("endpoint is for chain ID %d but the node is for chain ID %d", chainID, app.chainID)}
+	}
+
+	app.swappableEthRPCClient.Swap(rpcClient)
+	// The URL is not logged because it may contain an API key.
+	log.Info("changed Ethereum RPC endpoint")
+
+	if app.isEnabled(SubsystemOrderWatch) {
+		app.blockWatcher.ResetInconsistentHeaders()
+		if err := app.blockWatcher.SyncNow(); err != nil {
+			// The block watcher keeps polling, so this is not fatal.
+			log.WithError(err).Warn("could not sync to latest block after changing Ethereum RPC endpoint")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/0xProject/0x-mesh/common/types"
+	"github.com/0xProject/0x-mesh/orderfilter"
+	"github.com/0xProject/0x-mesh/zeroex"
+)
+
+// ErrMalformedOrderJSON is returned by TestOrderAgainstFilter if the order is
+// not a JSON object.
+var ErrMalformedOrderJSON = errors.New("signed order is malformed JSON or an empty payload")
+
+// TestOrderAgainstFilter validates the given signed order against the order
+// filter of the node exactly like AddOrders does, but neither adds the order
+// nor shares it with peers. It returns all of the violations of the order
+// schema, which helps integrators debug their orders and custom order
+// filters. It doesn't check whether the order is fillable.
+func (app *App) TestOrderAgainstFilter(signedOrderRaw json.RawMessage) (*types.FilterTestResult, error) {
+	<-app.started
+
+	signedOrderBytes := []byte(signedOrderRaw)
+	// Prepare the order like AddOrders does, so that the result is the same.
+	defaultedSignedOrderBytes, err := app.orderFieldDefaults.Apply(signedOrderBytes)
+	if err != nil {
+		return nil, ErrMalformedOrderJSON
+	}
+	signedOrderBytes = defaultedSignedOrderBytes
+	if normalizedSignedOrderBytes, err := zeroex.NormalizeSignedOrderJSON(signedOrderBytes); err == nil {
+		signedOrderBytes = normalizedSignedOrderBytes
+	}
+	orderFilter := app.getOrderFilter()
+	result, err := orderFilter.ValidateOrderJSON(signedOrderBytes)
+	if err != nil {
+		return nil, ErrMalformedOrderJSON
+	}
+	filterTestResult := &types.FilterTestResult{
+		Valid:           result.Valid(),
+		FieldErrors:     []*orderfilter.FieldError{},
+		OrderFilterHash: orderFilter.Hash(),
+	}
+	if !result.Valid() {
+		filterTestResult.FieldErrors = orderfilter.FieldErrors(signedOrderBytes, result)
+	}
+	signedOrder := &zeroex.SignedOrder{}
+	if err := signedOrder.UnmarshalJSON(signedOrderBytes); err == nil {
+		if orderHash, err := signedOrder.ComputeOrderHash(); err == nil {
+			filterTestResult.OrderHash = &orderHash
+		}
+	}
+	return filterTestResult, nil
+}
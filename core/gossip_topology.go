@@ -0,0 +1,48 @@
+package core
+
+import (
+	"github.com/0xProject/0x-mesh/common/types"
+	"github.com/0xProject/0x-mesh/p2p"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// GetGossipSubTopology returns the current GossipSub mesh and fanout peers of
+// the node for every topic it has joined or recently published to, along with
+// statistics about the IHAVE, IWANT, GRAFT and PRUNE control messages it sent
+// and received within the last p2p.GossipStatsWindow.
+func (app *App) GetGossipSubTopology() (*types.GossipSubTopology, error) {
+	<-app.started
+
+	if !app.isEnabled(SubsystemP2P) {
+		return nil, ErrSubsystemDisabled{Subsystem: SubsystemP2P}
+	}
+
+	topology := app.node.GossipSubTopology()
+	topics := make([]*types.GossipSubTopic, len(topology.Topics))
+	for i, topic := range topology.Topics {
+		topics[i] = &types.GossipSubTopic{
+			Topic:         topic.Topic,
+			Joined:        topic.Joined,
+			Peers:         prettyPeerIDs(topic.Peers),
+			MeshPeers:     prettyPeerIDs(topic.MeshPeers),
+			FanoutPeers:   prettyPeerIDs(topic.FanoutPeers),
+			IHaveSent:     topic.IHaveSent,
+			IHaveReceived: topic.IHaveReceived,
+		}
+	}
+	control := types.GossipSubControlStats(topology.Control)
+	return &types.GossipSubTopology{
+		Topics:             topics,
+		StatsWindowSeconds: int(p2p.GossipStatsWindow.Seconds()),
+		Control:            &control,
+	}, nil
+}
+
+// prettyPeerIDs returns the string representations of the given peer IDs.
+func prettyPeerIDs(peerIDs []peer.ID) []string {
+	pretty := make([]string, len(peerIDs))
+	for i, peerID := range peerIDs {
+		pretty[i] = peerID.Pretty()
+	}
+	return pretty
+}
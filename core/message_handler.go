@@ -5,6 +5,7 @@ import (
 
 	"github.com/0xProject/0x-mesh/constants"
 	"github.com/0xProject/0x-mesh/encoding"
+	"github.com/0xProject/0x-mesh/meshdb"
 	"github.com/0xProject/0x-mesh/p2p"
 	"github.com/0xProject/0x-mesh/zeroex"
 	"github.com/0xProject/0x-mesh/zeroex/ordervalidator"
@@ -15,6 +16,16 @@ import (
 // Ensure that App implements p2p.MessageHandler.
 var _ p2p.MessageHandler = &App{}
 
+// relayMessageHandler is the p2p.MessageHandler used when the orderwatch
+// subsystem is disabled. GossipSub forwards each message which passes the
+// custom message validator to our peers regardless of how it is handled, so
+// the node keeps relaying orders even though it drops them.
+type relayMessageHandler struct{}
+
+func (relayMessageHandler) HandleMessages(ctx context.Context, messages []*p2p.Message) error {
+	return nil
+}
+
 func min(a int, b int) int {
 	if a < b {
 		return a
@@ -26,16 +37,43 @@ func (app *App) HandleMessages(ctx context.Context, messages []*p2p.Message) err
 	// First we validate the messages and decode them into orders.
 	orders := []*zeroex.SignedOrder{}
 	orderHashToMessage := map[common.Hash]*p2p.Message{}
+	// legacyOrderHashes are the hashes of the orders which were received on a
+	// legacy topic during the topic migration window.
+	legacyOrderHashes := map[common.Hash]struct{}{}
 
 	for _, msg := range messages {
-		if err := validateMessageSize(msg); err != nil {
+		isLegacy := false
+		if currentTopic, ok := app.currentTopicForMessage(msg.Topic); ok {
+			translated, err := translateLegacyMessage(currentTopic, msg.Data)
+			if err != nil {
+				log.WithFields(map[string]interface{}{
+					"error": err,
+					"from":  msg.From,
+					"topic": msg.Topic,
+				}).Trace("could not translate message received on legacy topic")
+				continue
+			}
+			msg = &p2p.Message{From: msg.From, Topic: msg.Topic, Data: translated}
+			isLegacy = true
+		}
+		if err := app.validateMessageSize(msg); err != nil {
 			log.WithFields(map[string]interface{}{
 				"error":                 err,
 				"from":                  msg.From,
-				"maxMessageSizeInBytes": constants.MaxMessageSizeInBytes,
+				"maxMessageSizeInBytes": constants.MaxMessageSizeForOrderSize(app.config.MaxOrderSizeInBytes),
 				"actualSizeInBytes":     len(msg.Data),
 			}).Trace("received message that exceeds maximum size")
-			app.handlePeerScoreEvent(msg.From, psInvalidMessage)
+			if app.config.OversizedOrderPolicy != oversizedOrderPolicyIgnore {
+				app.handlePeerScoreEvent(msg.From, psInvalidMessage)
+			}
+			continue
+		}
+
+		// Order update hints are handled separately (see handleOrderUpdateHint).
+		if messageType, err := encoding.RawMessageType(msg.Data); err == nil && messageType == encoding.MessageTypeOrderUpdateHint {
+			if err := app.handleOrderUpdateHint(ctx, msg); err != nil {
+				return err
+			}
 			continue
 		}
 
@@ -59,11 +97,14 @@ func (app *App) HandleMessages(ctx context.Context, messages []*p2p.Message) err
 		}
 		orders = append(orders, order)
 		orderHashToMessage[orderHash] = msg
+		if isLegacy {
+			legacyOrderHashes[orderHash] = struct{}{}
+		}
 		app.handlePeerScoreEvent(msg.From, psValidMessage)
 	}
 
 	// Next, we validate the orders.
-	validationResults, err := app.orderWatcher.ValidateAndStoreValidOrders(ctx, orders, false, app.chainID)
+	validationResults, err := app.orderWatcher.ValidateAndStoreValidOrders(ctx, orders, false, meshdb.OrderSourceGossip, app.chainID)
 	if err != nil {
 		return err
 	}
@@ -90,6 +131,10 @@ func (app *App) HandleMessages(ctx context.Context, messages []*p2p.Message) err
 			"protocol":  "GossipSub",
 		}).Trace("all fields for new valid order received from peer")
 		app.handlePeerScoreEvent(msg.From, psOrderStored)
+		_, fromLegacyTopic := legacyOrderHashes[acceptedOrderInfo.OrderHash]
+		if err := app.bridgeOrder(acceptedOrderInfo.SignedOrder, fromLegacyTopic); err != nil {
+			log.WithError(err).WithField("orderHash", acceptedOrderInfo.OrderHash.Hex()).Warn("could not bridge order between topic versions")
+		}
 	}
 
 	// We don't store invalid orders, but in some cases still need to update peer
@@ -100,10 +145,19 @@ func (app *App) HandleMessages(ctx context.Context, messages []*p2p.Message) err
 			"rejectedOrderInfo": rejectedOrderInfo,
 			"from":              msg.From.String(),
 		}).Trace("not storing rejected order received from peer")
+		if app.config.EnableOrderFeedback {
+			app.orderFeedback.recordRejection(msg.From, rejectedOrderInfo.OrderHash, rejectedOrderInfo.Status.Code)
+		}
 		switch rejectedOrderInfo.Status {
-		case ordervalidator.ROInternalError, ordervalidator.ROEthRPCRequestFailed, ordervalidator.ROCoordinatorRequestFailed, ordervalidator.RODatabaseFullOfOrders:
+		case ordervalidator.ROInternalError, ordervalidator.ROEthRPCRequestFailed, ordervalidator.ROCoordinatorRequestFailed, ordervalidator.RODatabaseFullOfOrders, ordervalidator.ROMaxDiskUsageExceeded:
 			// Don't incur a negative score for these status types (it might not be
 			// their fault).
+		case ordervalidator.ROMaxOrderSizeExceeded:
+			// Peers may be using a larger max order size than we are, so whether
+			// or not this incurs a negative score is configurable.
+			if app.config.OversizedOrderPolicy != oversizedOrderPolicyIgnore {
+				app.handlePeerScoreEvent(msg.From, psInvalidMessage)
+			}
 		default:
 			// For other status types, we need to update the peer's score
 			app.handlePeerScoreEvent(msg.From, psInvalidMessage)
@@ -112,8 +166,8 @@ func (app *App) HandleMessages(ctx context.Context, messages []*p2p.Message) err
 	return nil
 }
 
-func validateMessageSize(message *p2p.Message) error {
-	if len(message.Data) > constants.MaxMessageSizeInBytes {
+func (app *App) validateMessageSize(message *p2p.Message) error {
+	if len(message.Data) > constants.MaxMessageSizeForOrderSize(app.config.MaxOrderSizeInBytes) {
 		return constants.ErrMaxMessageSize
 	}
 	return nil
@@ -0,0 +1,132 @@
+package core
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/0xProject/0x-mesh/common/types"
+	"github.com/0xProject/0x-mesh/metrics"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+)
+
+const (
+	// metricsPrefix is prepended to the names of all pushed metrics.
+	metricsPrefix = "mesh"
+	// metricsPushGatewayJob is the job that metrics are grouped by in the
+	// Prometheus pushgateway. They are also grouped by the peer ID of the node.
+	metricsPushGatewayJob = "mesh"
+)
+
+// newMetricsEmitters returns the metrics emitters enabled in config.
+func newMetricsEmitters(config Config, peerID peer.ID) ([]metrics.Emitter, error) {
+	emitters := []metrics.Emitter{}
+	if config.MetricsPushGatewayURL != "" {
+		emitter, err := metrics.NewPushGatewayEmitter(config.MetricsPushGatewayURL, metricsPushGatewayJob, peerID.String(), metricsPrefix)
+		if err != nil {
+			return nil, err
+		}
+		emitters = append(emitters, emitter)
+	}
+	if config.MetricsStatsDAddr != "" {
+		emitter, err := metrics.NewStatsDEmitter(config.MetricsStatsDAddr, metricsPrefix)
+		if err != nil {
+			return nil, err
+		}
+		emitters = append(emitters, emitter)
+	}
+	if len(emitters) > 0 && config.MetricsFlushInterval <= 0 {
+		return nil, errors.New("config.MetricsFlushInterval must be positive")
+	}
+	return emitters, nil
+}
+
+// collectMetrics returns the metrics pushed by the metrics emitters.
+func (app *App) collectMetrics() ([]*metrics.Metric, error) {
+	stats, err := app.GetStats()
+	if err != nil {
+		return nil, err
+	}
+	return statsMetrics(stats), nil
+}
+
+// statsMetrics converts the numeric stats returned by GetStats to metrics.
+func statsMetrics(stats *types.Stats) []*metrics.Metric {
+	gauge := func(name string, value float64) *metrics.Metric {
+		return &metrics.Metric{Name: name, Value: value}
+	}
+	diskBudgetExceeded := 0.0
+	if stats.DiskUsage.BudgetExceeded {
+		diskBudgetExceeded = 1
+	}
+	resourceLimitsThrottled := 0.0
+	if stats.ResourceUsage.Throttled {
+		resourceLimitsThrottled = 1
+	}
+	pipelineDegraded := 0.0
+	if stats.PipelineHealth.Status == types.PipelineHealthDegraded {
+		pipelineDegraded = 1
+	}
+	result := []*metrics.Metric{
+		gauge("latest_block_number", float64(stats.LatestBlock.Number)),
+		gauge("num_peers", float64(stats.NumPeers)),
+		gauge("num_orders", float64(stats.NumOrders)),
+		gauge("num_orders_including_removed", float64(stats.NumOrdersIncludingRemoved)),
+		gauge("num_pinned_orders", float64(stats.NumPinnedOrders)),
+		gauge("num_provisional_orders", float64(stats.NumProvisionalOrders)),
+		gauge("eth_rpc_requests_sent_in_current_utc_day", float64(stats.EthRPCRequestsSentInCurrentUTCDay)),
+		gauge("eth_rpc_rate_limit_expired_requests", float64(stats.EthRPCRateLimitExpiredRequests)),
+		gauge("eth_rpc_consecutive_failures", float64(stats.EthRPCHealth.ConsecutiveFailures)),
+		gauge("eth_rpc_inconsistent_headers", float64(stats.EthRPCHealth.InconsistentHeaders)),
+		gauge("disk_usage_bytes", float64(stats.DiskUsage.UsageBytes)),
+		gauge("disk_budget_exceeded", diskBudgetExceeded),
+		gauge("order_pipeline_degraded", pipelineDegraded),
+		gauge("num_oversized_orders_rejected", float64(stats.NumOversizedOrdersRejected)),
+		gauge("num_oversized_messages_dropped", float64(stats.NumOversizedMessagesDropped)),
+		gauge("num_dial_successes", float64(stats.DialStats.NumSuccesses)),
+		gauge("num_dials_skipped", float64(stats.DialStats.NumSkipped)),
+		gauge("num_peers_in_dial_backoff", float64(stats.DialStats.NumPeersInBackoff)),
+		gauge("num_inbound_streams", float64(stats.ResourceLimitStats.NumInboundStreams)),
+		gauge("ordersync_compression_ratio", stats.OrderSyncCompression.CompressionRatio),
+		gauge("memory_bytes", float64(stats.ResourceUsage.MemoryBytes)),
+		gauge("bandwidth_in_bytes_per_second", stats.ResourceUsage.BandwidthInPerSecond),
+		gauge("bandwidth_out_bytes_per_second", stats.ResourceUsage.BandwidthOutPerSecond),
+		gauge("peer_count_low_watermark", float64(stats.ResourceUsage.PeerCountLow)),
+		gauge("validation_concurrency", float64(stats.ResourceUsage.ValidationConcurrency)),
+		gauge("resource_limits_throttled", resourceLimitsThrottled),
+		gauge("order_filter_messages_accepted", float64(stats.OrderFilter.Messages.NumAccepted)),
+		gauge("order_filter_message_errors", float64(stats.OrderFilter.Messages.NumErrors)),
+		gauge("order_filter_orders_accepted", float64(stats.OrderFilter.Orders.NumAccepted)),
+		gauge("order_filter_order_errors", float64(stats.OrderFilter.Orders.NumErrors)),
+	}
+	labeled := func(name string, labelName string, values map[string]int64) {
+		for label, value := range values {
+			result = append(result, &metrics.Metric{
+				Name:   name,
+				Labels: map[string]string{labelName: label},
+				Value:  float64(value),
+			})
+		}
+	}
+	if stats.ResourceUsage.CPUPercent >= 0 {
+		result = append(result, gauge("cpu_percent", stats.ResourceUsage.CPUPercent))
+	}
+	labeled("num_dial_failures", "reason", stats.DialStats.NumFailures)
+	labeled("num_resource_limit_hits", "limit", stats.ResourceLimitStats.NumLimitHits)
+	labeled("subsystem_crashes", "subsystem", stats.SubsystemCrashes)
+	labeled("num_duplicate_orders", "source", stats.NumDuplicateOrders)
+	labeled("order_filter_messages_rejected", "reason", stats.OrderFilter.Messages.NumRejected)
+	labeled("order_filter_orders_rejected", "reason", stats.OrderFilter.Orders.NumRejected)
+	for _, protocolStats := range stats.BandwidthByProtocol {
+		labels := map[string]string{"protocol": protocolStats.Protocol}
+		result = append(result,
+			&metrics.Metric{Name: "bandwidth_total_in_bytes", Labels: labels, Value: float64(protocolStats.TotalIn)},
+			&metrics.Metric{Name: "bandwidth_total_out_bytes", Labels: labels, Value: float64(protocolStats.TotalOut)},
+		)
+	}
+	result = append(result, &metrics.Metric{
+		Name:   "info",
+		Labels: map[string]string{"version": stats.Version, "chain_id": strconv.Itoa(stats.EthereumChainID)},
+		Value:  1,
+	})
+	return result
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/0xProject/0x-mesh/db"
+	"github.com/0xProject/0x-mesh/meshdb"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// maxOrderAnnotations is the maximum number of annotations per order.
+	maxOrderAnnotations = 32
+	// maxOrderAnnotationKeyLength is the maximum length of an annotation key.
+	maxOrderAnnotationKeyLength = 64
+	// maxOrderAnnotationValueLength is the maximum length of an annotation
+	// value.
+	maxOrderAnnotationValueLength = 1024
+)
+
+// ErrOrderNotFound is returned by GetOrder and SetOrderAnnotations if the
+// order is not stored by the node (or was removed).
+type ErrOrderNotFound struct {
+	orderHash common.Hash
+}
+
+func (e ErrOrderNotFound) Error() string {
+	return fmt.Sprintf("order %s not found", e.orderHash.Hex())
+}
+
+// ErrInvalidOrderAnnotations is returned by SetOrderAnnotations if the
+// annotations exceed one of the limits.
+type ErrInvalidOrderAnnotations struct {
+	reason string
+}
+
+func (e ErrInvalidOrderAnnotations) Error() string {
+	return fmt.Sprintf("invalid order annotations: %s", e.reason)
+}
+
+// SetOrderAnnotations attaches the given key/value annotations to a stored
+// order, e.g. to record which internal strategy created it, and returns all of
+// the order's annotations. Existing annotations with other keys are kept and
+// annotations with an empty value are removed. Annotations are returned by
+// GetOrders and are deleted along with the order, but they are never shared
+// with peers.
+func (app *App) SetOrderAnnotations(orderHash common.Hash, annotations map[string]string) (map[string]string, error) {
+	<-app.started
+
+	for key, value := range annotations {
+		if key == "" {
+			return nil, ErrInvalidOrderAnnotations{reason: "keys cannot be empty"}
+		}
+		if len(key) > maxOrderAnnotationKeyLength {
+			return nil, ErrInvalidOrderAnnotations{reason: fmt.Sprintf("key %q is longer than %d bytes", key, maxOrderAnnotationKeyLength)}
+		}
+		if len(value) > maxOrderAnnotationValueLength {
+			return nil, ErrInvalidOrderAnnotations{reason: fmt.Sprintf("value of key %q is longer than %d bytes", key, maxOrderAnnotationValueLength)}
+		}
+	}
+	var order meshdb.Order
+	if err := app.db.Orders.FindByID(orderHash.Bytes(), &order); err != nil {
+		if _, ok := err.(db.NotFoundError); ok {
+			return nil, ErrOrderNotFound{orderHash: orderHash}
+		}
+		return nil, err
+	}
+	if order.IsRemoved {
+		return nil, ErrOrderNotFound{orderHash: orderHash}
+	}
+
+	existing, err := app.db.FindOrderAnnotations([]common.Hash{orderHash})
+	if err != nil {
+		return nil, err
+	}
+	numAnnotations := len(existing[orderHash])
+	for key, value := range annotations {
+		_, found := existing[orderHash][key]
+		if value != "" && !found {
+			numAnnotations++
+		} else if value == "" && found {
+			numAnnotations--
+		}
+	}
+	if numAnnotations > maxOrderAnnotations {
+		return nil, ErrInvalidOrderAnnotations{reason: fmt.Sprintf("orders cannot have more than %d annotations", maxOrderAnnotations)}
+	}
+	return app.db.UpdateOrderAnnotations(orderHash, annotations)
+}
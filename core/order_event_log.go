@@ -0,0 +1,68 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/0xProject/0x-mesh/common/types"
+)
+
+const (
+	// defaultOrderEventsPerRequest is the number of order events returned by
+	// GetOrderEventsSince if no limit is given.
+	defaultOrderEventsPerRequest = 1000
+	// maxOrderEventsPerRequest is the maximum number of order events returned by
+	// GetOrderEventsSince.
+	maxOrderEventsPerRequest = 1000
+)
+
+// ErrInvalidOrderEventsLimit is returned by GetOrderEventsSince if the limit is
+// out of range.
+var ErrInvalidOrderEventsLimit = errors.New("limit must be between 0 and 1000")
+
+// ErrOrderEventsUnavailable is returned by GetOrderEventsSince if some of the
+// order events after the given sequence number are no longer in the order event
+// log, or if the sequence number is greater than the sequence number of any
+// order event emitted by this node (e.g. because its database was reset). In
+// both cases the caller needs to re-sync all orders with GetOrders.
+type ErrOrderEventsUnavailable struct {
+	sequenceNumber       uint64
+	oldestSequenceNumber uint64
+	latestSequenceNumber uint64
+}
+
+func (e ErrOrderEventsUnavailable) Error() string {
+	if e.sequenceNumber > e.latestSequenceNumber {
+		return fmt.Sprintf("sequence number %d is greater than the latest sequence number %d; re-sync all orders with GetOrders", e.sequenceNumber, e.latestSequenceNumber)
+	}
+	return fmt.Sprintf("order events after sequence number %d are no longer available (the oldest available sequence number is %d); re-sync all orders with GetOrders", e.sequenceNumber, e.oldestSequenceNumber)
+}
+
+// GetOrderEventsSince returns up to limit order events with a sequence number
+// greater than the given sequence number, sorted by sequence number. If limit is
+// 0, up to 1000 order events are returned. It returns ErrOrderEventsUnavailable
+// if it cannot return every event after the given sequence number.
+func (app *App) GetOrderEventsSince(sequenceNumber uint64, limit int) (*types.GetOrderEventsSinceResponse, error) {
+	<-app.started
+
+	if limit < 0 || limit > maxOrderEventsPerRequest {
+		return nil, ErrInvalidOrderEventsLimit
+	} else if limit == 0 {
+		limit = defaultOrderEventsPerRequest
+	}
+	orderEvents, oldest, latest, err := app.orderWatcher.GetOrderEventsSince(sequenceNumber, limit)
+	if err != nil {
+		return nil, err
+	}
+	if sequenceNumber > latest || (oldest > 0 && sequenceNumber+1 < oldest) {
+		return nil, ErrOrderEventsUnavailable{
+			sequenceNumber:       sequenceNumber,
+			oldestSequenceNumber: oldest,
+			latestSequenceNumber: latest,
+		}
+	}
+	return &types.GetOrderEventsSinceResponse{
+		OrderEvents:          orderEvents,
+		LatestSequenceNumber: latest,
+	}, nil
+}
@@ -0,0 +1,185 @@
+package core
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/0xProject/0x-mesh/common/types"
+	"github.com/0xProject/0x-mesh/p2p"
+	"github.com/ethereum/go-ethereum/common"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// orderFeedbackInterval is how often the rejections of orders received
+	// through GossipSub are sent to the authors of the orders.
+	orderFeedbackInterval = 1 * time.Minute
+	// maxOrderFeedbackPeers is the maximum number of peers for which
+	// rejections are collected between two rounds of feedback. Rejections of
+	// orders from additional peers are not reported.
+	maxOrderFeedbackPeers = 256
+	// maxOrderFeedbackStatsCodes is the maximum number of different rejection
+	// codes counted in the order feedback stats. Any new codes are counted as
+	// orderFeedbackStatsOtherCode.
+	maxOrderFeedbackStatsCodes  = 64
+	orderFeedbackStatsOtherCode = "other"
+)
+
+// orderFeedbackTracker collects the rejections of orders received through
+// GossipSub until they are sent to the authors of the orders and counts the
+// rejections which were sent to and received from peers.
+type orderFeedbackTracker struct {
+	mu sync.Mutex
+	// pending are the rejections which have not been sent yet, by author and
+	// rejection code.
+	pending               map[peer.ID]map[string]*p2p.OrderRejections
+	numRejectionsSent     map[string]int64
+	numRejectionsReceived map[string]int64
+}
+
+func newOrderFeedbackTracker() *orderFeedbackTracker {
+	return &orderFeedbackTracker{
+		pending:               map[peer.ID]map[string]*p2p.OrderRejections{},
+		numRejectionsSent:     map[string]int64{},
+		numRejectionsReceived: map[string]int64{},
+	}
+}
+
+// recordRejection records that an order which was authored by the given peer
+// was rejected with the given code.
+func (t *orderFeedbackTracker) recordRejection(author peer.ID, orderHash common.Hash, code string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rejectionsByCode, found := t.pending[author]
+	if !found {
+		if len(t.pending) >= maxOrderFeedbackPeers {
+			return
+		}
+		rejectionsByCode = map[string]*p2p.OrderRejections{}
+		t.pending[author] = rejectionsByCode
+	}
+	rejections, found := rejectionsByCode[code]
+	if !found {
+		if len(rejectionsByCode) >= p2p.MaxOrderFeedbackCodes {
+			return
+		}
+		rejections = &p2p.OrderRejections{
+			Code:        code,
+			OrderHashes: []string{},
+		}
+		rejectionsByCode[code] = rejections
+	}
+	rejections.Count++
+	if len(rejections.OrderHashes) < p2p.MaxOrderFeedbackHashesPerCode {
+		rejections.OrderHashes = append(rejections.OrderHashes, orderHash.Hex())
+	}
+}
+
+// takePending returns the feedback for each peer with pending rejections and
+// starts over with no pending rejections.
+func (t *orderFeedbackTracker) takePending() map[peer.ID]*p2p.OrderFeedback {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = map[peer.ID]map[string]*p2p.OrderRejections{}
+	t.mu.Unlock()
+
+	feedbackByPeer := make(map[peer.ID]*p2p.OrderFeedback, len(pending))
+	for peerID, rejectionsByCode := range pending {
+		feedback := &p2p.OrderFeedback{
+			Rejections: make([]*p2p.OrderRejections, 0, len(rejectionsByCode)),
+		}
+		for _, rejections := range rejectionsByCode {
+			feedback.Rejections = append(feedback.Rejections, rejections)
+		}
+		sort.Slice(feedback.Rejections, func(i, j int) bool {
+			return feedback.Rejections[i].Code < feedback.Rejections[j].Code
+		})
+		feedbackByPeer[peerID] = feedback
+	}
+	return feedbackByPeer
+}
+
+// countRejections adds the rejections in the given feedback to counts.
+func (t *orderFeedbackTracker) countRejections(counts map[string]int64, feedback *p2p.OrderFeedback) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, rejections := range feedback.Rejections {
+		code := rejections.Code
+		if _, found := counts[code]; !found && len(counts) >= maxOrderFeedbackStatsCodes {
+			code = orderFeedbackStatsOtherCode
+		}
+		counts[code] += int64(rejections.Count)
+	}
+}
+
+// stats returns a copy of the counts.
+func (t *orderFeedbackTracker) stats() types.OrderFeedbackStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := types.OrderFeedbackStats{
+		NumRejectionsSent:     make(map[string]int64, len(t.numRejectionsSent)),
+		NumRejectionsReceived: make(map[string]int64, len(t.numRejectionsReceived)),
+	}
+	for code, count := range t.numRejectionsSent {
+		stats.NumRejectionsSent[code] = count
+	}
+	for code, count := range t.numRejectionsReceived {
+		stats.NumRejectionsReceived[code] = count
+	}
+	return stats
+}
+
+// periodicallySendOrderFeedback sends the rejections of orders received
+// through GossipSub to the authors of the orders every orderFeedbackInterval
+// until ctx is canceled.
+func (app *App) periodicallySendOrderFeedback(ctx context.Context) error {
+	ticker := time.NewTicker(orderFeedbackInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			app.sendOrderFeedback(ctx)
+		}
+	}
+}
+
+// sendOrderFeedback sends the pending rejections to the authors of the
+// orders. Authors which can't be reached or don't accept order feedback are
+// skipped and their rejections are dropped.
+func (app *App) sendOrderFeedback(ctx context.Context) {
+	wg := &sync.WaitGroup{}
+	for peerID, feedback := range app.orderFeedback.takePending() {
+		wg.Add(1)
+		go func(peerID peer.ID, feedback *p2p.OrderFeedback) {
+			defer wg.Done()
+			if err := app.node.SendOrderFeedback(ctx, peerID, feedback); err != nil {
+				log.WithFields(log.Fields{
+					"error":        err.Error(),
+					"remotePeerID": peerID.Pretty(),
+				}).Trace("could not send order feedback")
+				return
+			}
+			app.orderFeedback.countRejections(app.orderFeedback.numRejectionsSent, feedback)
+		}(peerID, feedback)
+	}
+	wg.Wait()
+}
+
+// handleOrderFeedback is the p2p.OrderFeedbackHandler of the node. It logs the
+// rejections of the orders we shared and counts them in the stats.
+func (app *App) handleOrderFeedback(from peer.ID, feedback *p2p.OrderFeedback) {
+	app.orderFeedback.countRejections(app.orderFeedback.numRejectionsReceived, feedback)
+	for _, rejections := range feedback.Rejections {
+		log.WithFields(log.Fields{
+			"from":        from.Pretty(),
+			"code":        rejections.Code,
+			"count":       rejections.Count,
+			"orderHashes": rejections.OrderHashes,
+		}).Info("peer rejected orders we shared")
+	}
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"context"
+	"errors"
+
+	"github.com/0xProject/0x-mesh/orderfilter"
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	log "github.com/sirupsen/logrus"
+)
+
+// RemovalReasonOrderFilterChanged is the removal reason of the REMOVED order
+// events emitted for orders which no longer match the order filter after it
+// was changed with SetOrderFilter.
+const RemovalReasonOrderFilterChanged = "ORDER_FILTER_CHANGED"
+
+// ErrOrderFilterChainIDMismatch is returned by SetOrderFilter if the new order
+// filter is for a different chain than the node.
+var ErrOrderFilterChainIDMismatch = errors.New("order filter is for a different chain ID")
+
+// getOrderFilter returns the order filter which is currently in use.
+func (app *App) getOrderFilter() *orderfilter.Filter {
+	app.orderFilterMu.RLock()
+	defer app.orderFilterMu.RUnlock()
+	return app.orderFilter
+}
+
+// validatePubSubMessage validates GossipSub messages against the order filter
+// for the topic they were received on (see orderFilterForTopic).
+func (app *App) validatePubSubMessage(ctx context.Context, sender peer.ID, msg *pubsub.Message) bool {
+	orderFilter := app.getOrderFilter()
+	if topics := msg.GetTopicIDs(); len(topics) > 0 {
+		if currentTopic, isLegacy := app.currentTopicForMessage(topics[0]); isLegacy {
+			return app.validateLegacyPubSubMessage(ctx, sender, msg, currentTopic)
+		}
+		orderFilter = app.orderFilterForTopic(topics[0])
+	}
+	return orderFilter.ValidatePubSubMessage(ctx, sender, msg)
+}
+
+// SetOrderFilter replaces the order filter of the node without restarting it.
+// The node unsubscribes from the topic of the old filter and subscribes to the
+// topic of the new one, and new orders are validated against the new filter.
+// All stored orders which match neither the new filter nor one of the
+// additional order filters are removed and a REMOVED order event with
+// RemovalReasonOrderFilterChanged is emitted for each of them. It returns the
+// hashes of the removed orders. The new filter is not persisted, so the node
+// uses the filter from its config again once it is restarted. The additional
+// order filters cannot be changed.
+func (app *App) SetOrderFilter(orderFilter *orderfilter.Filter) ([]common.Hash, error) {
+	<-app.started
+
+	app.setOrderFilterMu.Lock()
+	defer app.setOrderFilterMu.Unlock()
+
+	if orderFilter.Describe().ChainID != app.chainID {
+		return nil, ErrOrderFilterChainIDMismatch
+	}
+	oldOrderFilter := app.getOrderFilter()
+	var publishTopics, rendezvousPoints []string
+	if app.isEnabled(SubsystemP2P) {
+		var err error
+		publishTopics, err = getPublishTopics(app.config.EthereumChainID, *app.contractAddresses, orderFilter)
+		if err != nil {
+			return nil, err
+		}
+		rendezvousPoints, err = app.getRendezvousPoints(orderFilter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The filter is swapped before the topics so that messages on the new
+	// topic are never validated against the old filter.
+	app.orderFilterMu.Lock()
+	app.orderFilter = orderFilter
+	app.orderFilterMu.Unlock()
+	if app.isEnabled(SubsystemP2P) {
+		if err := app.node.SetTopics(orderFilter.Topic(), publishTopics, rendezvousPoints); err != nil {
+			app.orderFilterMu.Lock()
+			app.orderFilter = oldOrderFilter
+			app.orderFilterMu.Unlock()
+			return nil, err
+		}
+	}
+	app.orderWatcher.SetOrderFilterHash(orderFilter.Hash())
+	app.orderWatcher.SetMaxExpirationDuration(orderFiltersMaxExpirationDuration(app.getOrderFilters()))
+
+	matchOrder := func(order *zeroex.SignedOrder) (bool, error) {
+		matches, _, err := orderFilter.MatchOrder(order)
+		if err != nil || matches {
+			return matches, err
+		}
+		// Orders which pass one of the additional order filters are kept.
+		for _, namedFilter := range app.additionalOrderFilters {
+			if namedFilter.filter.MatchesOrder(order) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	removedOrderHashes, err := app.orderWatcher.RemoveOrdersNotMatching(matchOrder, RemovalReasonOrderFilterChanged)
+	if err != nil {
+		return nil, err
+	}
+	log.WithFields(map[string]interface{}{
+		"oldTopic":         oldOrderFilter.Topic(),
+		"topic":            orderFilter.Topic(),
+		"orderFilter":      orderFilter.Describe(),
+		"numOrdersRemoved": len(removedOrderHashes),
+	}).Info("changed order filter")
+	return removedOrderHashes, nil
+}
@@ -0,0 +1,194 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/0xProject/0x-mesh/ethereum"
+	"github.com/0xProject/0x-mesh/orderfilter"
+	"github.com/0xProject/0x-mesh/p2p"
+	"github.com/0xProject/0x-mesh/zeroex"
+)
+
+// PrimaryOrderFilterName is the name of the custom order filter of the node
+// when it is used together with additional order filters (see
+// Config.AdditionalOrderFilters).
+const PrimaryOrderFilterName = "primary"
+
+// orderFilterNameRegex matches valid names of additional order filters.
+var orderFilterNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,64}$`)
+
+// namedOrderFilter is an order filter together with the name it was configured
+// with.
+type namedOrderFilter struct {
+	name   string
+	filter *orderfilter.Filter
+}
+
+// newAdditionalOrderFilters returns the order filters configured in
+// config.AdditionalOrderFilters, sorted by name. Each of them must have a
+// different topic than the others and than the given primary filter.
+func newAdditionalOrderFilters(config Config, contractAddresses ethereum.ContractAddresses, primary *orderfilter.Filter) ([]namedOrderFilter, error) {
+	if config.AdditionalOrderFilters == "" {
+		return nil, nil
+	}
+	var rawFilters map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(config.AdditionalOrderFilters), &rawFilters); err != nil {
+		return nil, fmt.Errorf("config.AdditionalOrderFilters is invalid: must be a JSON object: %s", err.Error())
+	}
+	names := make([]string, 0, len(rawFilters))
+	for name := range rawFilters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	topics := map[string]string{primary.Topic(): PrimaryOrderFilterName}
+	filters := make([]namedOrderFilter, 0, len(names))
+	for _, name := range names {
+		if name == PrimaryOrderFilterName || !orderFilterNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("config.AdditionalOrderFilters is invalid: %q is not a valid name (must match %s and not be %q)", name, orderFilterNameRegex, PrimaryOrderFilterName)
+		}
+		filter, err := newAdditionalOrderFilter(config.EthereumChainID, rawFilters[name], contractAddresses)
+		if err != nil {
+			return nil, fmt.Errorf("config.AdditionalOrderFilters is invalid: filter %q: %s", name, err.Error())
+		}
+		if otherName, found := topics[filter.Topic()]; found {
+			return nil, fmt.Errorf("config.AdditionalOrderFilters is invalid: filters %q and %q have the same topic", otherName, name)
+		}
+		topics[filter.Topic()] = name
+		filters = append(filters, namedOrderFilter{name: name, filter: filter})
+	}
+	return filters, nil
+}
+
+// newAdditionalOrderFilter creates an order filter from either a custom order
+// schema (a JSON object) or constraints (a JSON string).
+func newAdditionalOrderFilter(chainID int, rawFilter json.RawMessage, contractAddresses ethereum.ContractAddresses) (*orderfilter.Filter, error) {
+	var constraintsSource string
+	if err := json.Unmarshal(rawFilter, &constraintsSource); err != nil {
+		return orderfilter.New(chainID, string(rawFilter), contractAddresses, orderfilter.ProtocolVersionV3)
+	}
+	constraints, err := orderfilter.ParseConstraints(constraintsSource)
+	if err != nil {
+		return nil, err
+	}
+	return orderfilter.NewFromConstraints(chainID, constraints, contractAddresses)
+}
+
+// getOrderFilters returns all order filters which are currently in use,
+// starting with the primary one.
+func (app *App) getOrderFilters() []namedOrderFilter {
+	return append([]namedOrderFilter{{name: PrimaryOrderFilterName, filter: app.getOrderFilter()}}, app.additionalOrderFilters...)
+}
+
+// additionalOrderFilterTopics returns the topics of the additional order
+// filters.
+func (app *App) additionalOrderFilterTopics() []string {
+	topics := make([]string, len(app.additionalOrderFilters))
+	for i, namedFilter := range app.additionalOrderFilters {
+		topics[i] = namedFilter.filter.Topic()
+	}
+	return topics
+}
+
+// orderFilterForTopic returns the order filter which messages received on the
+// given (possibly sharded) topic are validated against. Messages on any topic
+// which doesn't belong to an additional order filter (e.g. the default topic
+// the node also publishes to) are validated against the primary one.
+func (app *App) orderFilterForTopic(topic string) *orderfilter.Filter {
+	topic = p2p.UnshardTopic(topic, app.config.TopicShards)
+	for _, namedFilter := range app.additionalOrderFilters {
+		if namedFilter.filter.Topic() == topic {
+			return namedFilter.filter
+		}
+	}
+	return app.getOrderFilter()
+}
+
+// orderFilterForOrderJSON returns the order filter which a JSON encoded order
+// added via AddOrders is validated against: the primary order filter, unless
+// the order only passes one of the additional ones. The primary filter is also
+// returned for orders which cannot be decoded, so that the client gets a
+// description of the problem.
+func (app *App) orderFilterForOrderJSON(signedOrderJSON []byte) *orderfilter.Filter {
+	primary := app.getOrderFilter()
+	if len(app.additionalOrderFilters) == 0 {
+		return primary
+	}
+	order := &zeroex.SignedOrder{}
+	if err := order.UnmarshalJSON(signedOrderJSON); err != nil || primary.MatchesOrder(order) {
+		return primary
+	}
+	for _, namedFilter := range app.additionalOrderFilters {
+		if namedFilter.filter.MatchesOrder(order) {
+			return namedFilter.filter
+		}
+	}
+	return primary
+}
+
+// matchOrderFilters checks whether many orders pass any of the order filters
+// at once. Orders are checked against the primary order filter first (see
+// orderfilter.Filter.ValidateOrders) and only orders which don't pass it are
+// checked against the additional ones.
+func (app *App) matchOrderFilters(orders []*zeroex.SignedOrder) []*orderfilter.BatchValidationResult {
+	results := app.getOrderFilter().ValidateOrders(orders)
+	for i, result := range results {
+		if result.Valid || result.Err != nil {
+			continue
+		}
+		for _, namedFilter := range app.additionalOrderFilters {
+			if namedFilter.filter.MatchesOrder(orders[i]) {
+				results[i] = &orderfilter.BatchValidationResult{Valid: true}
+				break
+			}
+		}
+	}
+	return results
+}
+
+// matchingOrderFilters returns the order filters which the given order passes.
+func (app *App) matchingOrderFilters(order *zeroex.SignedOrder) []namedOrderFilter {
+	matching := []namedOrderFilter{}
+	for _, namedFilter := range app.getOrderFilters() {
+		if namedFilter.filter.MatchesOrder(order) {
+			matching = append(matching, namedFilter)
+		}
+	}
+	return matching
+}
+
+// orderFilterNames returns the names of the order filters which the given
+// order passes. It returns nil if the node only uses one order filter, since
+// every stored order passes it.
+func (app *App) orderFilterNames(order *zeroex.SignedOrder) []string {
+	if len(app.additionalOrderFilters) == 0 {
+		return nil
+	}
+	matching := app.matchingOrderFilters(order)
+	names := make([]string, len(matching))
+	for i, namedFilter := range matching {
+		names[i] = namedFilter.name
+	}
+	return names
+}
+
+// orderFiltersMaxExpirationDuration returns the longest max expiration
+// duration of the given order filters, or 0 if any of them doesn't limit the
+// expiration time of orders.
+func orderFiltersMaxExpirationDuration(namedFilters []namedOrderFilter) time.Duration {
+	var maxDuration time.Duration
+	for _, namedFilter := range namedFilters {
+		duration := namedFilter.filter.MaxExpirationDuration()
+		if duration == 0 {
+			return 0
+		}
+		if duration > maxDuration {
+			maxDuration = duration
+		}
+	}
+	return maxDuration
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/0xProject/0x-mesh/common/types"
+	"github.com/0xProject/0x-mesh/db"
+	"github.com/0xProject/0x-mesh/meshdb"
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrOrderStateHistoryDisabled is returned when querying the order state
+// history while Config.OrderStateHistoryRetentionBlocks is 0.
+var ErrOrderStateHistoryDisabled = errors.New("order state history is disabled (see ORDER_STATE_HISTORY_RETENTION_BLOCKS)")
+
+// ErrOrderStateNotFound is returned by GetOrderStateAtBlock if there is no
+// recorded state for the given order at the given block.
+type ErrOrderStateNotFound struct {
+	orderHash   common.Hash
+	blockNumber *big.Int
+}
+
+func (e ErrOrderStateNotFound) Error() string {
+	return fmt.Sprintf("no state recorded for order %s at block %s", e.orderHash.Hex(), e.blockNumber)
+}
+
+// GetOrderStateAtBlock returns the state of the given order at the given
+// block. History which is older than Config.OrderStateHistoryRetentionBlocks
+// only includes orders which were still fillable.
+func (app *App) GetOrderStateAtBlock(orderHash common.Hash, blockNumber *big.Int) (*types.OrderState, error) {
+	<-app.started
+
+	if app.config.OrderStateHistoryRetentionBlocks == 0 {
+		return nil, ErrOrderStateHistoryDisabled
+	}
+	record, err := app.db.FindOrderStateAtBlock(orderHash, blockNumber)
+	if err != nil {
+		if _, ok := err.(db.NotFoundError); ok {
+			return nil, ErrOrderStateNotFound{orderHash: orderHash, blockNumber: blockNumber}
+		}
+		return nil, err
+	}
+	return orderStateRecordToOrderState(record), nil
+}
+
+// GetOrdersFillableAtBlock returns the state of every order which was fillable
+// at the given block, sorted by order hash.
+func (app *App) GetOrdersFillableAtBlock(blockNumber *big.Int) ([]*types.OrderState, error) {
+	<-app.started
+
+	if app.config.OrderStateHistoryRetentionBlocks == 0 {
+		return nil, ErrOrderStateHistoryDisabled
+	}
+	records, err := app.db.FindOrdersFillableAtBlock(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	orderStates := make([]*types.OrderState, len(records))
+	for i, record := range records {
+		orderStates[i] = orderStateRecordToOrderState(record)
+	}
+	return orderStates, nil
+}
+
+// validateOrderStateHistoryBackfillConfig checks that the order state history
+// backfill is only enabled if it can run.
+func validateOrderStateHistoryBackfillConfig(config Config, disabledSubsystems map[Subsystem]bool) error {
+	if config.OrderStateHistoryBackfillFromBlock == 0 {
+		return nil
+	}
+	if config.OrderStateHistoryBackfillFromBlock < 0 {
+		return fmt.Errorf("config.OrderStateHistoryBackfillFromBlock cannot be negative: %d", config.OrderStateHistoryBackfillFromBlock)
+	}
+	if config.OrderStateHistoryRetentionBlocks == 0 {
+		return errors.New("config.OrderStateHistoryBackfillFromBlock requires config.OrderStateHistoryRetentionBlocks")
+	}
+	if disabledSubsystems[SubsystemOrderWatch] {
+		return fmt.Errorf("config.OrderStateHistoryBackfillFromBlock cannot be used when the %s subsystem is disabled", SubsystemOrderWatch)
+	}
+	return nil
+}
+
+// backfillOrderStateHistory reconstructs the order state history starting at
+// Config.OrderStateHistoryBackfillFromBlock (see
+// orderwatch.Watcher.BackfillOrderStateHistory). Errors are logged since
+// the backfill is not required for Mesh to work.
+func (app *App) backfillOrderStateHistory(ctx context.Context) {
+	if _, err := app.orderWatcher.BackfillOrderStateHistory(ctx, app.config.OrderStateHistoryBackfillFromBlock); err != nil {
+		if err == context.Canceled {
+			return
+		}
+		log.WithError(err).Error("order state history backfill failed")
+	}
+}
+
+func orderStateRecordToOrderState(record *meshdb.OrderStateRecord) *types.OrderState {
+	return &types.OrderState{
+		OrderHash:                record.OrderHash,
+		BlockNumber:              int(record.BlockNumber.Int64()),
+		EndState:                 zeroex.OrderEventEndState(record.EndState),
+		FillableTakerAssetAmount: record.FillableTakerAssetAmount,
+		Timestamp:                record.Timestamp,
+	}
+}
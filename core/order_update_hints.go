@@ -0,0 +1,266 @@
+package core
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/0xProject/0x-mesh/encoding"
+	"github.com/0xProject/0x-mesh/meshdb"
+	"github.com/0xProject/0x-mesh/p2p"
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// maxOrderHashesPerHint is the maximum number of order hashes in an order
+	// update hint. It must match the maxItems of orderHashes in the message
+	// schema (see orderfilter.rootOrderMessageSchema).
+	maxOrderHashesPerHint = 100
+	// orderUpdateHintCooldown is the minimum amount of time between two
+	// re-validations of the same order that are triggered by hints. It keeps
+	// peers from using hints to make us spend our Ethereum RPC requests.
+	orderUpdateHintCooldown = 1 * time.Minute
+	// maxOrderUpdateHintBlocksAhead is how far ahead of the latest block we
+	// processed the block of a hint can be. Hints for blocks that are further
+	// ahead are ignored.
+	maxOrderUpdateHintBlocksAhead = 64
+	// orderUpdateHintsPerPeerPerSecond and orderUpdateHintsBurstPerPeer limit
+	// the rate at which each peer can send hints. Hints beyond this rate are
+	// dropped and count as invalid messages.
+	orderUpdateHintsPerPeerPerSecond = 1
+	orderUpdateHintsBurstPerPeer     = 10
+	// maxOrderUpdateHintPeers is the maximum number of peers whose hint rate
+	// is tracked at once. If it is exceeded, all peers start over with a full
+	// burst.
+	maxOrderUpdateHintPeers = 1000
+	// orderUpdateHintEventBufferSize is the size of the buffer for the order
+	// events which are turned into hints.
+	orderUpdateHintEventBufferSize = 100
+)
+
+// orderUpdateHintLimiter keeps track of the rate at which peers send order
+// update hints and of the orders which were recently re-validated because of a
+// hint.
+type orderUpdateHintLimiter struct {
+	mu                 sync.Mutex
+	peerLimiters       map[peer.ID]*rate.Limiter
+	lastRevalidatedAt  map[common.Hash]time.Time
+	lastPrunedAt       time.Time
+	nowFunc            func() time.Time
+	perPeerPerSecond   rate.Limit
+	burstPerPeer       int
+	revalidateCooldown time.Duration
+}
+
+func newOrderUpdateHintLimiter() *orderUpdateHintLimiter {
+	return &orderUpdateHintLimiter{
+		peerLimiters:       map[peer.ID]*rate.Limiter{},
+		lastRevalidatedAt:  map[common.Hash]time.Time{},
+		nowFunc:            time.Now,
+		perPeerPerSecond:   orderUpdateHintsPerPeerPerSecond,
+		burstPerPeer:       orderUpdateHintsBurstPerPeer,
+		revalidateCooldown: orderUpdateHintCooldown,
+	}
+}
+
+// allowPeer returns false if the given peer has exceeded its hint rate.
+func (l *orderUpdateHintLimiter) allowPeer(peerID peer.ID) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limiter, found := l.peerLimiters[peerID]
+	if !found {
+		if len(l.peerLimiters) >= maxOrderUpdateHintPeers {
+			l.peerLimiters = map[peer.ID]*rate.Limiter{}
+		}
+		limiter = rate.NewLimiter(l.perPeerPerSecond, l.burstPerPeer)
+		l.peerLimiters[peerID] = limiter
+	}
+	return limiter.AllowN(l.nowFunc(), 1)
+}
+
+// takeOrderHashes returns the given order hashes without duplicates and
+// without the orders which were re-validated because of a hint within the
+// cooldown period. The returned orders are considered to be re-validated now.
+func (l *orderUpdateHintLimiter) takeOrderHashes(orderHashes []common.Hash) []common.Hash {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.nowFunc()
+	if now.Sub(l.lastPrunedAt) >= l.revalidateCooldown {
+		for orderHash, revalidatedAt := range l.lastRevalidatedAt {
+			if now.Sub(revalidatedAt) >= l.revalidateCooldown {
+				delete(l.lastRevalidatedAt, orderHash)
+			}
+		}
+		l.lastPrunedAt = now
+	}
+	taken := []common.Hash{}
+	for _, orderHash := range orderHashes {
+		if revalidatedAt, found := l.lastRevalidatedAt[orderHash]; found && now.Sub(revalidatedAt) < l.revalidateCooldown {
+			continue
+		}
+		l.lastRevalidatedAt[orderHash] = now
+		taken = append(taken, orderHash)
+	}
+	return taken
+}
+
+// handleOrderUpdateHint re-validates the orders in the order update hint
+// contained in the given message. Hints never change the state of an order
+// by themselves: the orders are re-validated on-chain at the block of the hint
+// so that they are updated before we process that block. Peers which send
+// hints too often or hints which turn out to be wrong are penalized.
+func (app *App) handleOrderUpdateHint(ctx context.Context, msg *p2p.Message) error {
+	if !app.config.EnableOrderUpdateHints {
+		return nil
+	}
+	hint, err := encoding.RawMessageToOrderUpdateHint(msg.Data)
+	if err != nil || len(hint.OrderHashes) > maxOrderHashesPerHint {
+		log.WithFields(map[string]interface{}{
+			"error": err,
+			"from":  msg.From,
+		}).Trace("could not decode received order update hint")
+		app.handlePeerScoreEvent(msg.From, psInvalidMessage)
+		return nil
+	}
+	if !app.orderUpdateHintLimiter.allowPeer(msg.From) {
+		log.WithField("from", msg.From).Trace("dropping order update hint from peer which exceeded its hint rate")
+		app.handlePeerScoreEvent(msg.From, psInvalidMessage)
+		return nil
+	}
+
+	latestBlock, err := app.db.FindLatestMiniHeader()
+	if err != nil {
+		return err
+	}
+	if hint.BlockNumber.Cmp(latestBlock.Number) <= 0 {
+		// We already processed the block of the hint.
+		return nil
+	}
+	maxBlockNumber := new(big.Int).Add(latestBlock.Number, big.NewInt(maxOrderUpdateHintBlocksAhead))
+	if hint.BlockNumber.Cmp(maxBlockNumber) > 0 {
+		log.WithFields(map[string]interface{}{
+			"from":              msg.From,
+			"blockNumber":       hint.BlockNumber,
+			"latestBlockNumber": latestBlock.Number,
+		}).Trace("ignoring order update hint for a block which is too far ahead")
+		return nil
+	}
+
+	// Only orders that we store and haven't removed yet are re-validated. Hints
+	// for other orders are ignored without a penalty because our peers don't
+	// necessarily store the same orders as we do.
+	storedOrderHashes := []common.Hash{}
+	for _, orderHash := range hint.OrderHashes {
+		var order meshdb.Order
+		if err := app.db.Orders.FindByID(orderHash.Bytes(), &order); err != nil {
+			continue
+		}
+		if order.IsRemoved {
+			continue
+		}
+		storedOrderHashes = append(storedOrderHashes, orderHash)
+	}
+	orderHashes := app.orderUpdateHintLimiter.takeOrderHashes(storedOrderHashes)
+	if len(orderHashes) == 0 {
+		return nil
+	}
+
+	results, err := app.orderWatcher.RevalidateOrdersAtBlock(ctx, orderHashes, hint.BlockNumber)
+	if err != nil {
+		// Hints are only an optimization and the orders are re-validated
+		// anyway once we process the block, so a failure (e.g. a failed
+		// Ethereum RPC request) must not stop us from handling other messages.
+		log.WithFields(map[string]interface{}{
+			"error":       err.Error(),
+			"from":        msg.From,
+			"blockNumber": hint.BlockNumber,
+			"numOrders":   len(orderHashes),
+		}).Warn("could not re-validate orders because of an order update hint")
+		return nil
+	}
+	log.WithFields(map[string]interface{}{
+		"from":           msg.From,
+		"blockNumber":    hint.BlockNumber,
+		"numOrders":      len(orderHashes),
+		"numOrderEvents": results.NumOrderEvents,
+	}).Debug("re-validated orders because of an order update hint")
+	// If the orders could not be re-validated (e.g. because our Ethereum RPC
+	// provider doesn't know about the block yet), it might not be the peer's
+	// fault.
+	if len(results.Orders) > 0 && results.NumOrderEvents == 0 && len(results.NotRevalidated) == 0 {
+		app.handlePeerScoreEvent(msg.From, psInaccurateOrderUpdateHint)
+	}
+	return nil
+}
+
+// shareOrderUpdateHints sends order update hints to our peers whenever orders
+// are filled or cancelled. It blocks until the context is canceled.
+func (app *App) shareOrderUpdateHints(ctx context.Context) error {
+	sink := make(chan []*zeroex.OrderEvent, orderUpdateHintEventBufferSize)
+	subscription := app.orderWatcher.Subscribe(sink)
+	defer subscription.Unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-subscription.Err():
+			return err
+		case orderEvents := <-sink:
+			if err := app.sendOrderUpdateHints(orderEvents); err != nil {
+				log.WithError(err).Warn("could not send order update hints")
+			}
+		}
+	}
+}
+
+// sendOrderUpdateHints sends order update hints for the orders which were
+// filled or cancelled according to the given order events.
+func (app *App) sendOrderUpdateHints(orderEvents []*zeroex.OrderEvent) error {
+	orderHashesByShard := map[int][]common.Hash{}
+	for _, orderEvent := range orderEvents {
+		// Only changes that were caused by contract events are shared. Orders
+		// which were re-validated because of a hint have none, so hints are not
+		// passed on by every node which receives them.
+		if len(orderEvent.ContractEvents) == 0 {
+			continue
+		}
+		switch orderEvent.EndState {
+		case zeroex.ESOrderFilled, zeroex.ESOrderFullyFilled, zeroex.ESOrderCancelled:
+		default:
+			continue
+		}
+		shard := p2p.ShardForKey(orderEvent.OrderHash.Bytes(), app.config.TopicShards)
+		orderHashesByShard[shard] = append(orderHashesByShard[shard], orderEvent.OrderHash)
+	}
+	if len(orderHashesByShard) == 0 {
+		return nil
+	}
+
+	// The events are emitted after the block which caused them was stored, so
+	// the latest block is at least as new as that block.
+	latestBlock, err := app.db.FindLatestMiniHeader()
+	if err != nil {
+		return err
+	}
+	for shard, orderHashes := range orderHashesByShard {
+		for start := 0; start < len(orderHashes); start += maxOrderHashesPerHint {
+			end := min(start+maxOrderHashesPerHint, len(orderHashes))
+			encoded, err := encoding.OrderUpdateHintToRawMessage(app.getOrderFilter().Topic(), &encoding.OrderUpdateHint{
+				OrderHashes: orderHashes[start:end],
+				BlockNumber: latestBlock.Number,
+			})
+			if err != nil {
+				return err
+			}
+			if err := app.node.SendToShard(encoded, shard); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,400 @@
+// Package orderprovider exposes the orders stored by an in-process Mesh node in
+// the shape expected by the order providers of 0x API and asset-swapper, so
+// that those services can consume Mesh directly instead of polling a Standard
+// Relayer API (SRA) endpoint.
+package orderprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/0xProject/0x-mesh/common/types"
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/0xProject/0x-mesh/zeroex/ordervalidator"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// loadOrdersChunkSize is the number of orders loaded at a time when the
+// OrderProvider is started.
+const loadOrdersChunkSize = 1000
+
+// Mesh is the subset of the methods of *core.App used by the OrderProvider.
+type Mesh interface {
+	StreamOrders(ctx context.Context, chunkSize int, handleChunk func(*types.OrdersStreamChunk) error) error
+	SubscribeToOrderEvents(sink chan<- []*zeroex.OrderEvent) event.Subscription
+	AddOrders(ctx context.Context, signedOrdersRaw []*json.RawMessage, pinned bool) (*ordervalidator.ValidationResults, error)
+}
+
+// APIOrder is an order together with its metadata, as returned by the order
+// endpoints of the SRA and 0x API.
+type APIOrder struct {
+	Order    *zeroex.SignedOrder `json:"order"`
+	MetaData *APIOrderMetaData   `json:"metaData"`
+}
+
+// APIOrderMetaData is the metadata of an APIOrder.
+type APIOrderMetaData struct {
+	OrderHash                         common.Hash
+	RemainingFillableTakerAssetAmount *big.Int
+}
+
+type apiOrderMetaDataJSON struct {
+	OrderHash                         string `json:"orderHash"`
+	RemainingFillableTakerAssetAmount string `json:"remainingFillableTakerAssetAmount"`
+}
+
+// MarshalJSON implements a custom JSON marshaller for the APIOrderMetaData
+// type, which encodes the remaining fillable amount as a decimal string like
+// the SRA does.
+func (m *APIOrderMetaData) MarshalJSON() ([]byte, error) {
+	return json.Marshal(apiOrderMetaDataJSON{
+		OrderHash:                         m.OrderHash.Hex(),
+		RemainingFillableTakerAssetAmount: m.RemainingFillableTakerAssetAmount.String(),
+	})
+}
+
+// UnmarshalJSON implements a custom JSON unmarshaller for the APIOrderMetaData
+// type.
+func (m *APIOrderMetaData) UnmarshalJSON(data []byte) error {
+	var metaDataJSON apiOrderMetaDataJSON
+	if err := json.Unmarshal(data, &metaDataJSON); err != nil {
+		return err
+	}
+	m.OrderHash = common.HexToHash(metaDataJSON.OrderHash)
+	remainingFillableTakerAssetAmount, ok := new(big.Int).SetString(metaDataJSON.RemainingFillableTakerAssetAmount, 10)
+	if !ok {
+		return fmt.Errorf("invalid remainingFillableTakerAssetAmount: %q", metaDataJSON.RemainingFillableTakerAssetAmount)
+	}
+	m.RemainingFillableTakerAssetAmount = remainingFillableTakerAssetAmount
+	return nil
+}
+
+// AssetPair is a pair of assets for which orders are available in either
+// direction. AssetDataA is always less than AssetDataB, so that every pair is
+// only returned once.
+type AssetPair struct {
+	AssetDataA hexutil.Bytes `json:"assetDataA"`
+	AssetDataB hexutil.Bytes `json:"assetDataB"`
+}
+
+// Update is sent to the subscribers of an OrderProvider whenever orders were
+// added, updated or removed.
+type Update struct {
+	// Added are the orders which were added or whose remaining fillable amount
+	// changed. They replace any previous version of the same order.
+	Added []*APIOrder
+	// Removed are the orders which are no longer fillable.
+	Removed []*APIOrder
+}
+
+// Config is a set of configuration options for an OrderProvider.
+type Config struct {
+	// Mesh is the Mesh node whose orders are provided (usually a *core.App).
+	Mesh Mesh
+}
+
+// assetDataKey is the key of the orders of one side of the order book. The
+// asset data is converted to a string so that it can be used as a map key.
+type assetDataKey struct {
+	makerAssetData string
+	takerAssetData string
+}
+
+// OrderProvider keeps an in-memory order book of the orders stored by a Mesh
+// node, indexed by asset data, and keeps it up to date using order events.
+type OrderProvider struct {
+	mesh           Mesh
+	mu             sync.RWMutex
+	wasStartedOnce bool
+	ordersByHash   map[common.Hash]*APIOrder
+	ordersByAssets map[assetDataKey]map[common.Hash]*APIOrder
+	ready          chan struct{}
+	updatesFeed    event.Feed
+	updatesScope   event.SubscriptionScope
+}
+
+// New creates a new OrderProvider. Watch must be called to load the orders and
+// keep them up to date.
+func New(config Config) (*OrderProvider, error) {
+	if config.Mesh == nil {
+		return nil, errors.New("config.Mesh is required")
+	}
+	return &OrderProvider{
+		mesh:           config.Mesh,
+		ordersByHash:   map[common.Hash]*APIOrder{},
+		ordersByAssets: map[assetDataKey]map[common.Hash]*APIOrder{},
+		ready:          make(chan struct{}),
+	}, nil
+}
+
+// Watch loads all orders currently stored by Mesh and then keeps the order
+// book up to date until the context is canceled. Order events which are
+// emitted while the orders are loaded are applied afterwards, so no update is
+// missed. It blocks until the context is canceled or an error occurs and can
+// only be called once.
+func (p *OrderProvider) Watch(ctx context.Context) error {
+	p.mu.Lock()
+	if p.wasStartedOnce {
+		p.mu.Unlock()
+		return errors.New("Can only start OrderProvider once per instance")
+	}
+	p.wasStartedOnce = true
+	p.mu.Unlock()
+
+	innerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer p.updatesScope.Close()
+
+	// Subscribe before loading the orders, so that no event is missed. Events
+	// are buffered until the orders are loaded, since Mesh blocks until every
+	// subscriber received them.
+	orderEvents := make(chan []*zeroex.OrderEvent)
+	subscription := p.mesh.SubscribeToOrderEvents(orderEvents)
+	defer subscription.Unsubscribe()
+	loadErrChan := make(chan error, 1)
+	go func() {
+		loadErrChan <- p.loadOrders(innerCtx)
+	}()
+	pendingOrderEvents := [][]*zeroex.OrderEvent{}
+	for loading := true; loading; {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-subscription.Err():
+			return err
+		case err := <-loadErrChan:
+			if err != nil {
+				return err
+			}
+			loading = false
+		case events := <-orderEvents:
+			pendingOrderEvents = append(pendingOrderEvents, events)
+		}
+	}
+	for _, events := range pendingOrderEvents {
+		p.handleOrderEvents(events)
+	}
+	close(p.ready)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-subscription.Err():
+			return err
+		case events := <-orderEvents:
+			p.handleOrderEvents(events)
+		}
+	}
+}
+
+// Ready returns a channel which is closed once the orders stored by Mesh have
+// been loaded.
+func (p *OrderProvider) Ready() <-chan struct{} {
+	return p.ready
+}
+
+// loadOrders adds every order currently stored by Mesh to the order book.
+func (p *OrderProvider) loadOrders(ctx context.Context) error {
+	return p.mesh.StreamOrders(ctx, loadOrdersChunkSize, func(chunk *types.OrdersStreamChunk) error {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for _, orderInfo := range chunk.OrdersInfos {
+			p.addOrder(&APIOrder{
+				Order: orderInfo.SignedOrder,
+				MetaData: &APIOrderMetaData{
+					OrderHash:                         orderInfo.OrderHash,
+					RemainingFillableTakerAssetAmount: orderInfo.FillableTakerAssetAmount,
+				},
+			})
+		}
+		return nil
+	})
+}
+
+// handleOrderEvents applies the given order events to the order book and sends
+// an Update to the subscribers if anything changed.
+func (p *OrderProvider) handleOrderEvents(events []*zeroex.OrderEvent) {
+	update := &Update{
+		Added:   []*APIOrder{},
+		Removed: []*APIOrder{},
+	}
+	p.mu.Lock()
+	for _, orderEvent := range events {
+		switch orderEvent.EndState {
+		case zeroex.ESOrderAdded, zeroex.ESOrderFilled, zeroex.ESOrderFillabilityIncreased, zeroex.ESOrderUnexpired:
+			signedOrder := orderEvent.SignedOrder
+			if signedOrder == nil {
+				// Compact events don't include the order, so it can only be
+				// updated if it is already known.
+				existing, found := p.ordersByHash[orderEvent.OrderHash]
+				if !found {
+					continue
+				}
+				signedOrder = existing.Order
+			}
+			order := &APIOrder{
+				Order: signedOrder,
+				MetaData: &APIOrderMetaData{
+					OrderHash:                         orderEvent.OrderHash,
+					RemainingFillableTakerAssetAmount: orderEvent.FillableTakerAssetAmount,
+				},
+			}
+			p.addOrder(order)
+			update.Added = append(update.Added, order)
+		case zeroex.ESOrderFullyFilled, zeroex.ESOrderCancelled, zeroex.ESOrderExpired, zeroex.ESOrderBecameUnfunded,
+			zeroex.ESStoppedWatching, zeroex.ESOrderStaticCallFailed, zeroex.ESOrderRemoved:
+			if order := p.removeOrder(orderEvent.OrderHash); order != nil {
+				update.Removed = append(update.Removed, order)
+			}
+		}
+	}
+	p.mu.Unlock()
+	if len(update.Added) > 0 || len(update.Removed) > 0 {
+		p.updatesFeed.Send(update)
+	}
+}
+
+// addOrder adds the order to the order book or replaces the previous version
+// of it. p.mu must be locked.
+func (p *OrderProvider) addOrder(order *APIOrder) {
+	p.removeOrder(order.MetaData.OrderHash)
+	p.ordersByHash[order.MetaData.OrderHash] = order
+	key := assetDataKey{
+		makerAssetData: string(order.Order.MakerAssetData),
+		takerAssetData: string(order.Order.TakerAssetData),
+	}
+	orders, found := p.ordersByAssets[key]
+	if !found {
+		orders = map[common.Hash]*APIOrder{}
+		p.ordersByAssets[key] = orders
+	}
+	orders[order.MetaData.OrderHash] = order
+}
+
+// removeOrder removes the order with the given hash from the order book and
+// returns it, or nil if it wasn't found. p.mu must be locked.
+func (p *OrderProvider) removeOrder(orderHash common.Hash) *APIOrder {
+	order, found := p.ordersByHash[orderHash]
+	if !found {
+		return nil
+	}
+	delete(p.ordersByHash, orderHash)
+	key := assetDataKey{
+		makerAssetData: string(order.Order.MakerAssetData),
+		takerAssetData: string(order.Order.TakerAssetData),
+	}
+	delete(p.ordersByAssets[key], orderHash)
+	if len(p.ordersByAssets[key]) == 0 {
+		delete(p.ordersByAssets, key)
+	}
+	return order
+}
+
+// GetOrders returns the orders with the given maker and taker asset data,
+// sorted by order hash. This is the equivalent of getOrdersAsync of the
+// asset-swapper order providers.
+func (p *OrderProvider) GetOrders(makerAssetData []byte, takerAssetData []byte) []*APIOrder {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.getOrders(makerAssetData, takerAssetData)
+}
+
+// GetBatchOrders is like GetOrders for several pairs of maker and taker asset
+// data at once, which are given at the same index of makerAssetDatas and
+// takerAssetDatas. This is the equivalent of getBatchOrdersAsync of the
+// asset-swapper order providers.
+func (p *OrderProvider) GetBatchOrders(makerAssetDatas [][]byte, takerAssetDatas [][]byte) ([][]*APIOrder, error) {
+	if len(makerAssetDatas) != len(takerAssetDatas) {
+		return nil, errors.New("makerAssetDatas and takerAssetDatas must have the same length")
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	batches := make([][]*APIOrder, len(makerAssetDatas))
+	for i := range makerAssetDatas {
+		batches[i] = p.getOrders(makerAssetDatas[i], takerAssetDatas[i])
+	}
+	return batches, nil
+}
+
+// getOrders returns the orders with the given maker and taker asset data.
+// p.mu must be locked.
+func (p *OrderProvider) getOrders(makerAssetData []byte, takerAssetData []byte) []*APIOrder {
+	key := assetDataKey{
+		makerAssetData: string(makerAssetData),
+		takerAssetData: string(takerAssetData),
+	}
+	orders := make([]*APIOrder, 0, len(p.ordersByAssets[key]))
+	for _, order := range p.ordersByAssets[key] {
+		orders = append(orders, order)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return bytes.Compare(orders[i].MetaData.OrderHash.Bytes(), orders[j].MetaData.OrderHash.Bytes()) == -1
+	})
+	return orders
+}
+
+// GetAssetPairs returns the pairs of assets for which there is at least one
+// order, in either direction, sorted by their asset data. This is the
+// equivalent of getAvailableAssetDatasAsync of the asset-swapper order
+// providers.
+func (p *OrderProvider) GetAssetPairs() []*AssetPair {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	seen := map[assetDataKey]struct{}{}
+	assetPairs := []*AssetPair{}
+	for key := range p.ordersByAssets {
+		assetDataA, assetDataB := key.makerAssetData, key.takerAssetData
+		if assetDataA > assetDataB {
+			assetDataA, assetDataB = assetDataB, assetDataA
+		}
+		pairKey := assetDataKey{makerAssetData: assetDataA, takerAssetData: assetDataB}
+		if _, found := seen[pairKey]; found {
+			continue
+		}
+		seen[pairKey] = struct{}{}
+		assetPairs = append(assetPairs, &AssetPair{
+			AssetDataA: []byte(assetDataA),
+			AssetDataB: []byte(assetDataB),
+		})
+	}
+	sort.Slice(assetPairs, func(i, j int) bool {
+		if c := bytes.Compare(assetPairs[i].AssetDataA, assetPairs[j].AssetDataA); c != 0 {
+			return c == -1
+		}
+		return bytes.Compare(assetPairs[i].AssetDataB, assetPairs[j].AssetDataB) == -1
+	})
+	return assetPairs
+}
+
+// AddOrders adds the given orders to Mesh. Accepted orders are added to the
+// order book once Mesh emits their ADDED events. This is the equivalent of
+// addOrdersAsync of the asset-swapper order providers.
+func (p *OrderProvider) AddOrders(ctx context.Context, signedOrders []*zeroex.SignedOrder) (*ordervalidator.ValidationResults, error) {
+	signedOrdersRaw := make([]*json.RawMessage, len(signedOrders))
+	for i, signedOrder := range signedOrders {
+		encoded, err := json.Marshal(signedOrder)
+		if err != nil {
+			return nil, err
+		}
+		signedOrderRaw := json.RawMessage(encoded)
+		signedOrdersRaw[i] = &signedOrderRaw
+	}
+	return p.mesh.AddOrders(ctx, signedOrdersRaw, false)
+}
+
+// SubscribeToUpdates subscribes to the changes of the order book. Loading the
+// orders stored by Mesh doesn't send any updates, so subscribers should
+// subscribe first and then call GetOrders once the OrderProvider is ready (see
+// Ready) to get the initial state. The subscription ends once Watch returns.
+func (p *OrderProvider) SubscribeToUpdates(sink chan<- *Update) event.Subscription {
+	return p.updatesScope.Track(p.updatesFeed.Subscribe(sink))
+}
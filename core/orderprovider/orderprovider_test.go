@@ -0,0 +1,155 @@
+package orderprovider
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/0xProject/0x-mesh/common/types"
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/0xProject/0x-mesh/zeroex/ordervalidator"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	zrxAssetData  = common.Hex2Bytes("f47261b0000000000000000000000000e41d2489571d322189246dafa5ebde1f4699f498")
+	wethAssetData = common.Hex2Bytes("f47261b0000000000000000000000000c02aaa39b223fe8d0a0e5c4f27ead9083c756cc2")
+)
+
+// fakeMesh is a Mesh which stores the given orders and emits the order events
+// sent to its feed.
+type fakeMesh struct {
+	orders          []*types.OrderInfo
+	orderEventsFeed event.Feed
+	addedOrders     []*json.RawMessage
+}
+
+func (m *fakeMesh) StreamOrders(ctx context.Context, chunkSize int, handleChunk func(*types.OrdersStreamChunk) error) error {
+	if err := handleChunk(&types.OrdersStreamChunk{OrdersInfos: m.orders}); err != nil {
+		return err
+	}
+	return handleChunk(&types.OrdersStreamChunk{OrdersInfos: []*types.OrderInfo{}, Done: true, NumOrders: len(m.orders)})
+}
+
+func (m *fakeMesh) SubscribeToOrderEvents(sink chan<- []*zeroex.OrderEvent) event.Subscription {
+	return m.orderEventsFeed.Subscribe(sink)
+}
+
+func (m *fakeMesh) AddOrders(ctx context.Context, signedOrdersRaw []*json.RawMessage, pinned bool) (*ordervalidator.ValidationResults, error) {
+	m.addedOrders = append(m.addedOrders, signedOrdersRaw...)
+	return &ordervalidator.ValidationResults{}, nil
+}
+
+func newOrderInfo(salt int64, makerAssetData []byte, takerAssetData []byte) *types.OrderInfo {
+	signedOrder := &zeroex.SignedOrder{
+		Order: zeroex.Order{
+			Salt:           big.NewInt(salt),
+			MakerAssetData: makerAssetData,
+			TakerAssetData: takerAssetData,
+		},
+	}
+	return &types.OrderInfo{
+		OrderHash:                common.BigToHash(big.NewInt(salt)),
+		SignedOrder:              signedOrder,
+		FillableTakerAssetAmount: big.NewInt(100),
+	}
+}
+
+func TestOrderProvider(t *testing.T) {
+	t.Parallel()
+
+	zrxForWETH := newOrderInfo(1, zrxAssetData, wethAssetData)
+	wethForZRX := newOrderInfo(2, wethAssetData, zrxAssetData)
+	mesh := &fakeMesh{orders: []*types.OrderInfo{zrxForWETH, wethForZRX}}
+	provider, err := New(Config{Mesh: mesh})
+	require.NoError(t, err)
+	updates := make(chan *Update, 1)
+	subscription := provider.SubscribeToUpdates(updates)
+	defer subscription.Unsubscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watchErrChan := make(chan error, 1)
+	go func() {
+		watchErrChan <- provider.Watch(ctx)
+	}()
+	select {
+	case <-provider.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the OrderProvider to be ready")
+	}
+
+	orders := provider.GetOrders(zrxAssetData, wethAssetData)
+	require.Len(t, orders, 1)
+	assert.Equal(t, zrxForWETH.OrderHash, orders[0].MetaData.OrderHash)
+	assert.Equal(t, []*AssetPair{{AssetDataA: wethAssetData, AssetDataB: zrxAssetData}}, provider.GetAssetPairs())
+
+	// Order events are applied incrementally.
+	zrxForWETH2 := newOrderInfo(3, zrxAssetData, wethAssetData)
+	mesh.orderEventsFeed.Send([]*zeroex.OrderEvent{
+		{
+			OrderHash:                zrxForWETH2.OrderHash,
+			SignedOrder:              zrxForWETH2.SignedOrder,
+			EndState:                 zeroex.ESOrderAdded,
+			FillableTakerAssetAmount: big.NewInt(100),
+		},
+		{
+			OrderHash:                zrxForWETH.OrderHash,
+			EndState:                 zeroex.ESOrderFilled,
+			FillableTakerAssetAmount: big.NewInt(40),
+		},
+		{
+			OrderHash:                wethForZRX.OrderHash,
+			SignedOrder:              wethForZRX.SignedOrder,
+			EndState:                 zeroex.ESOrderCancelled,
+			FillableTakerAssetAmount: big.NewInt(0),
+		},
+	})
+	select {
+	case update := <-updates:
+		require.Len(t, update.Added, 2)
+		assert.Equal(t, zrxForWETH2.OrderHash, update.Added[0].MetaData.OrderHash)
+		assert.Equal(t, zrxForWETH.OrderHash, update.Added[1].MetaData.OrderHash)
+		require.Len(t, update.Removed, 1)
+		assert.Equal(t, wethForZRX.OrderHash, update.Removed[0].MetaData.OrderHash)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an update")
+	}
+
+	orders = provider.GetOrders(zrxAssetData, wethAssetData)
+	require.Len(t, orders, 2)
+	assert.Equal(t, zrxForWETH.OrderHash, orders[0].MetaData.OrderHash)
+	assert.Equal(t, big.NewInt(40), orders[0].MetaData.RemainingFillableTakerAssetAmount)
+	assert.Equal(t, zrxForWETH2.OrderHash, orders[1].MetaData.OrderHash)
+	batches, err := provider.GetBatchOrders([][]byte{zrxAssetData, wethAssetData}, [][]byte{wethAssetData, zrxAssetData})
+	require.NoError(t, err)
+	assert.Equal(t, [][]*APIOrder{orders, {}}, batches)
+
+	cancel()
+	select {
+	case err := <-watchErrChan:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for Watch to return")
+	}
+}
+
+func TestAPIOrderMetaDataJSON(t *testing.T) {
+	t.Parallel()
+
+	metaData := &APIOrderMetaData{
+		OrderHash:                         common.HexToHash("0x01"),
+		RemainingFillableTakerAssetAmount: big.NewInt(1000),
+	}
+	encoded, err := json.Marshal(metaData)
+	require.NoError(t, err)
+	assert.Equal(t, `{"orderHash":"0x0000000000000000000000000000000000000000000000000000000000000001","remainingFillableTakerAssetAmount":"1000"}`, string(encoded))
+	var decoded APIOrderMetaData
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, metaData, &decoded)
+}
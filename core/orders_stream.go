@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/0xProject/0x-mesh/common/types"
+	"github.com/0xProject/0x-mesh/meshdb"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	// defaultOrdersStreamChunkSize is the number of orders in each chunk sent by
+	// StreamOrders if no chunk size is given.
+	defaultOrdersStreamChunkSize = 1000
+	// MaxOrdersStreamChunkSize is the maximum number of orders in each chunk
+	// sent by StreamOrders.
+	MaxOrdersStreamChunkSize = 10000
+)
+
+// ErrInvalidOrdersStreamChunkSize is returned by StreamOrders if the chunk size
+// is out of range.
+var ErrInvalidOrdersStreamChunkSize = errors.New("chunkSize must be between 0 and 10000")
+
+// StreamOrders takes a snapshot of all orders currently stored and calls
+// handleChunk with up to chunkSize orders at a time until every order in the
+// snapshot has been handled. If chunkSize is 0, chunks contain up to 1000
+// orders. After the last order, handleChunk is called one more time with an
+// empty chunk that has Done set to true and includes a checksum which can be
+// used to verify that every order was received. Unlike GetOrders, only one
+// chunk of orders is held in memory at a time and handleChunk is called
+// synchronously, so a slow consumer naturally slows down the stream.
+// StreamOrders stops and returns an error if ctx is canceled or if handleChunk
+// returns an error.
+func (app *App) StreamOrders(ctx context.Context, chunkSize int, handleChunk func(*types.OrdersStreamChunk) error) error {
+	<-app.started
+
+	if chunkSize < 0 || chunkSize > MaxOrdersStreamChunkSize {
+		return ErrInvalidOrdersStreamChunkSize
+	} else if chunkSize == 0 {
+		chunkSize = defaultOrdersStreamChunkSize
+	}
+
+	snapshot, err := app.db.Orders.GetSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snapshot.Release()
+	createdAt := time.Now().UTC()
+
+	checksum := sha3.NewLegacyKeccak256()
+	numOrders := 0
+	notRemovedFilter := app.db.Orders.IsRemovedIndex.ValueFilter([]byte{0})
+	var orders []*meshdb.Order
+	err = snapshot.NewQuery(notRemovedFilter).RunInChunks(&orders, chunkSize, func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ordersInfos := make([]*types.OrderInfo, len(orders))
+		for i, order := range orders {
+			ordersInfos[i] = &types.OrderInfo{
+				OrderHash:                order.Hash,
+				SignedOrder:              order.SignedOrder,
+				FillableTakerAssetAmount: order.FillableTakerAssetAmount,
+			}
+			_, _ = checksum.Write(order.Hash.Bytes())
+		}
+		numOrders += len(orders)
+		return handleChunk(&types.OrdersStreamChunk{
+			SnapshotTimestamp: createdAt,
+			OrdersInfos:       ordersInfos,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return handleChunk(&types.OrdersStreamChunk{
+		SnapshotTimestamp: createdAt,
+		OrdersInfos:       []*types.OrderInfo{},
+		Done:              true,
+		NumOrders:         numOrders,
+		Checksum:          hexutil.Encode(checksum.Sum(nil)),
+	})
+}
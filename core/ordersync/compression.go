@@ -0,0 +1,156 @@
+package ordersync
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync/atomic"
+
+	"github.com/0xProject/0x-mesh/zeroex"
+)
+
+const (
+	// CompressionGzip is used to identify response pages compressed with gzip.
+	CompressionGzip = "gzip"
+	// maxDecompressedPageSize is the maximum size of a response page after
+	// decompression. It protects requesters from decompression bombs.
+	maxDecompressedPageSize = 64 * 1024 * 1024
+)
+
+// supportedCompressions are the compression algorithms which are supported for
+// response pages, most preferred first. Requesters send them in each request
+// and providers compress the response with the first one they support.
+// Requesters and providers which don't support compression ignore the field
+// and fall back to uncompressed pages.
+var supportedCompressions = []string{CompressionGzip}
+
+// compressedPage is the part of a rawResponse which is compressed.
+type compressedPage struct {
+	Orders   zeroex.WireSignedOrders `json:"orders"`
+	Metadata json.RawMessage         `json:"metadata"`
+}
+
+// CompressionStats describes the response pages which were compressed by the
+// Service (as a provider) or decompressed by it (as a requester).
+type CompressionStats struct {
+	// CompressedPagesSent is the number of compressed pages sent to requesters.
+	CompressedPagesSent int64
+	// CompressedPagesReceived is the number of compressed pages received from
+	// providers.
+	CompressedPagesReceived int64
+	// UncompressedBytes is the total size of all of these pages before
+	// compression.
+	UncompressedBytes int64
+	// CompressedBytes is the total size of all of these pages after
+	// compression.
+	CompressedBytes int64
+}
+
+// compressionCounters is used to compute CompressionStats. All fields must be
+// accessed atomically.
+type compressionCounters struct {
+	compressedPagesSent     int64
+	compressedPagesReceived int64
+	uncompressedBytes       int64
+	compressedBytes         int64
+}
+
+func (c *compressionCounters) recordSent(uncompressedSize, compressedSize int) {
+	atomic.AddInt64(&c.compressedPagesSent, 1)
+	atomic.AddInt64(&c.uncompressedBytes, int64(uncompressedSize))
+	atomic.AddInt64(&c.compressedBytes, int64(compressedSize))
+}
+
+func (c *compressionCounters) recordReceived(uncompressedSize, compressedSize int) {
+	atomic.AddInt64(&c.compressedPagesReceived, 1)
+	atomic.AddInt64(&c.uncompressedBytes, int64(uncompressedSize))
+	atomic.AddInt64(&c.compressedBytes, int64(compressedSize))
+}
+
+func (c *compressionCounters) stats() CompressionStats {
+	return CompressionStats{
+		CompressedPagesSent:     atomic.LoadInt64(&c.compressedPagesSent),
+		CompressedPagesReceived: atomic.LoadInt64(&c.compressedPagesReceived),
+		UncompressedBytes:       atomic.LoadInt64(&c.uncompressedBytes),
+		CompressedBytes:         atomic.LoadInt64(&c.compressedBytes),
+	}
+}
+
+// selectCompression returns the first of the requested compression algorithms
+// which is supported or "" if none of them are.
+func selectCompression(requested []string) string {
+	for _, compression := range requested {
+		for _, supported := range supportedCompressions {
+			if compression == supported {
+				return compression
+			}
+		}
+	}
+	return ""
+}
+
+// compressResponse moves the orders and metadata of the given response into
+// its compressed page. It returns the size of the page before and after
+// compression.
+func compressResponse(rawRes *rawResponse, compression string) (uncompressedSize int, compressedSize int, err error) {
+	if compression != CompressionGzip {
+		return 0, 0, fmt.Errorf("unsupported ordersync compression: %q", compression)
+	}
+	page, err := json.Marshal(compressedPage{
+		Orders:   rawRes.Orders,
+		Metadata: rawRes.Metadata,
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(page); err != nil {
+		return 0, 0, err
+	}
+	if err := writer.Close(); err != nil {
+		return 0, 0, err
+	}
+	rawRes.Compression = compression
+	rawRes.CompressedPage = buf.Bytes()
+	rawRes.Orders = nil
+	rawRes.Metadata = nil
+	return len(page), buf.Len(), nil
+}
+
+// decompressResponse restores the orders and metadata of the given response
+// from its compressed page. It returns the size of the page before and after
+// compression. Responses which are not compressed are left as is.
+func decompressResponse(rawRes *rawResponse) (uncompressedSize int, compressedSize int, err error) {
+	if rawRes.Compression == "" {
+		return 0, 0, nil
+	}
+	if rawRes.Compression != CompressionGzip {
+		return 0, 0, fmt.Errorf("unsupported ordersync compression: %q", rawRes.Compression)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(rawRes.CompressedPage))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer reader.Close()
+	page, err := ioutil.ReadAll(io.LimitReader(reader, maxDecompressedPageSize+1))
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(page) > maxDecompressedPageSize {
+		return 0, 0, fmt.Errorf("decompressed ordersync page exceeds %d bytes", maxDecompressedPageSize)
+	}
+	var decoded compressedPage
+	if err := json.Unmarshal(page, &decoded); err != nil {
+		return 0, 0, err
+	}
+	compressedSize = len(rawRes.CompressedPage)
+	rawRes.Orders = decoded.Orders
+	rawRes.Metadata = decoded.Metadata
+	rawRes.Compression = ""
+	rawRes.CompressedPage = nil
+	return len(page), compressedSize, nil
+}
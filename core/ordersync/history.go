@@ -0,0 +1,94 @@
+package ordersync
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// Outcome is a summary of a single attempt to get orders from a provider via
+// the ordersync protocol.
+type Outcome struct {
+	ProviderID  peer.ID
+	Subprotocol string
+	StartTime   time.Time
+	Duration    time.Duration
+	// Latency is the round trip time of the first request, i.e. the time
+	// between sending it and receiving the first response. It is 0 if no
+	// response was received.
+	Latency        time.Duration
+	OrdersReceived int
+	OrdersAccepted int
+	// OrdersRejected is the number of rejected orders keyed by the code of the
+	// rejection reason.
+	OrdersRejected map[string]int
+	// Err is the error (if any) that caused ordersync with this provider to
+	// fail.
+	Err error
+}
+
+// History is used to persist the Outcome of each ordersync attempt and to
+// rank providers for future runs of the protocol. Providers with a higher
+// score are tried first (apart from the occasional provider that is tried
+// early for exploration). Scores should take into account how fast providers
+// responded in the past so that ordersync prefers nearby providers.
+type History interface {
+	RecordOutcome(outcome *Outcome) error
+	ProviderScores() (map[peer.ID]float64, error)
+}
+
+type outcomeContextKey struct{}
+
+// outcomeRecorder wraps an Outcome so that it can be safely updated by
+// subprotocols while ordersync is in progress.
+type outcomeRecorder struct {
+	mut     sync.Mutex
+	outcome *Outcome
+}
+
+// RecordValidationResults is used by subprotocols to record the number of
+// orders that were accepted and rejected while handling a response. ctx must
+// be the context passed to HandleOrderSyncResponse. It is a no-op if ctx was
+// not created by the ordersync service.
+func RecordValidationResults(ctx context.Context, accepted int, rejectedByCode map[string]int) {
+	recorder, ok := ctx.Value(outcomeContextKey{}).(*outcomeRecorder)
+	if !ok {
+		return
+	}
+	recorder.mut.Lock()
+	defer recorder.mut.Unlock()
+	recorder.outcome.OrdersAccepted += accepted
+	for code, count := range rejectedByCode {
+		recorder.outcome.OrdersRejected[code] += count
+	}
+}
+
+// rankPeers randomizes the order of the given peers and then sorts them by
+// descending score. Peers without a score are treated as having a score of 0,
+// so new peers will be tried before peers with a history of failures.
+func rankPeers(peers []peer.ID, scores map[peer.ID]float64) {
+	shufflePeers(peers)
+	sort.SliceStable(peers, func(i, j int) bool {
+		return scores[peers[i]] > scores[peers[j]]
+	})
+}
+
+// explorePeers moves randomly chosen peers forward in the given ranking. For
+// each position, with probability explorationRate, the peer at that position
+// is swapped with a random peer further down the ranking. Without
+// exploration, providers that were slow or unreliable in the past would never
+// be tried again while better ones are available, even if they have improved
+// (or if their scores are based on few attempts).
+func explorePeers(peers []peer.ID, explorationRate float64) {
+	for i := 0; i < len(peers)-1; i++ {
+		if rand.Float64() >= explorationRate {
+			continue
+		}
+		j := i + 1 + rand.Intn(len(peers)-i-1)
+		peers[i], peers[j] = peers[j], peers[i]
+	}
+}
@@ -44,6 +44,10 @@ const (
 	//    approxDelay * (1 - jitter) <= actualDelay < approxDelay * (1 + jitter)
 	//
 	ordersyncJitterAmount = 0.1
+	// providerExplorationRate is the probability with which each provider in
+	// the ranking is swapped with a random lower ranked provider in GetOrders
+	// (see explorePeers).
+	providerExplorationRate = 0.1
 )
 
 var (
@@ -84,6 +88,14 @@ type rawRequest struct {
 	Type         string          `json:"type"`
 	Subprotocols []string        `json:"subprotocols"`
 	Metadata     json.RawMessage `json:"metadata"`
+	// NumShards and Shards are set if the requester only subscribes to some of
+	// the shards of the order topic (see p2p.Config.NumTopicShards). In that
+	// case, the provider only responds with orders in those shards.
+	NumShards int   `json:"numShards,omitempty"`
+	Shards    []int `json:"shards,omitempty"`
+	// Compression lists the compression algorithms the requester supports for
+	// the response, most preferred first (see supportedCompressions).
+	Compression []string `json:"compression,omitempty"`
 }
 
 // Response represents a high-level ordersync response. It abstracts away some
@@ -96,13 +108,20 @@ type Response struct {
 }
 
 // rawResponse contains all the details we need at the lowest level to encode/decode
-// the response, perform subprotocol negoatiation, and more.
+// the response, perform subprotocol negoatiation, and more. Orders are encoded
+// in the wire format, so that a response with orders which don't parse
+// strictly is rejected as a whole.
 type rawResponse struct {
-	Type        string                `json:"type"`
-	Subprotocol string                `json:"subprotocol"`
-	Orders      []*zeroex.SignedOrder `json:"orders"`
-	Complete    bool                  `json:"complete"`
-	Metadata    json.RawMessage       `json:"metadata"`
+	Type        string                  `json:"type"`
+	Subprotocol string                  `json:"subprotocol"`
+	Orders      zeroex.WireSignedOrders `json:"orders"`
+	Complete    bool                    `json:"complete"`
+	Metadata    json.RawMessage         `json:"metadata"`
+	// Compression is the compression algorithm used for CompressedPage. If it
+	// is set, Orders and Metadata are empty and contained in CompressedPage
+	// instead (see compressedPage).
+	Compression    string `json:"compression,omitempty"`
+	CompressedPage []byte `json:"compressedPage,omitempty"`
 }
 
 // Service is the main entrypoint for running the ordersync protocol. It handles
@@ -114,6 +133,12 @@ type Service struct {
 	// requestRateLimiter is a rate limiter for incoming ordersync requests. It's
 	// shared between all peers.
 	requestRateLimiter *rate.Limiter
+	// history is used to record the outcome of each ordersync attempt and rank
+	// providers. It may be nil.
+	history History
+	// compression counts the compressed response pages that were sent and
+	// received.
+	compression compressionCounters
 }
 
 // SupportedSubprotocols returns the subprotocols that are supported by the service.
@@ -154,8 +179,10 @@ type Subprotocol interface {
 // requesting orders from other peers and providing orders to peers who request
 // them. New expects an array of subprotocols which the service will support, in the
 // order of preference. The service will automatically pick the most preferred protocol
-// that is supported by both peers for each request/response.
-func New(ctx context.Context, node *p2p.Node, subprotocols []Subprotocol) *Service {
+// that is supported by both peers for each request/response. If history is
+// not nil, it will be used to record the outcome of each attempt to get orders
+// from a provider and to prefer providers with a good track record.
+func New(ctx context.Context, node *p2p.Node, subprotocols []Subprotocol, history History) *Service {
 	supportedSubprotocols := map[string]Subprotocol{}
 	for _, subp := range subprotocols {
 		supportedSubprotocols[subp.Name()] = subp
@@ -165,11 +192,18 @@ func New(ctx context.Context, node *p2p.Node, subprotocols []Subprotocol) *Servi
 		node:               node,
 		subprotocols:       supportedSubprotocols,
 		requestRateLimiter: rate.NewLimiter(maxRequestsPerSecond, requestsBurst),
+		history:            history,
 	}
 	s.node.SetStreamHandler(ID, s.HandleStream)
 	return s
 }
 
+// CompressionStats returns statistics about the compressed response pages
+// that were sent and received.
+func (s *Service) CompressionStats() CompressionStats {
+	return s.compression.stats()
+}
+
 // GetMatchingSubprotocol returns the most preferred subprotocol to use
 // based on the given request.
 func (s *Service) GetMatchingSubprotocol(rawReq *rawRequest) (Subprotocol, error) {
@@ -236,6 +270,7 @@ func (s *Service) HandleStream(stream network.Stream) {
 			log.WithError(err).Warn("subprotocol returned error")
 			return
 		}
+		res.Orders = filterOrdersByShard(res.Orders, rawReq.NumShards, rawReq.Shards)
 		encodedMetadata, err := json.Marshal(res.Metadata)
 		if err != nil {
 			log.WithError(err).Error("could not encode raw metadata")
@@ -249,6 +284,14 @@ func (s *Service) HandleStream(stream network.Stream) {
 			Complete:    res.Complete,
 			Metadata:    encodedMetadata,
 		}
+		if compression := selectCompression(rawReq.Compression); compression != "" {
+			uncompressedSize, compressedSize, err := compressResponse(&rawRes, compression)
+			if err != nil {
+				log.WithError(err).Error("could not compress ordersync response")
+				return
+			}
+			s.compression.recordSent(uncompressedSize, compressedSize)
+		}
 		if err := json.NewEncoder(stream).Encode(rawRes); err != nil {
 			log.WithFields(log.Fields{
 				"error":     err.Error(),
@@ -288,7 +331,8 @@ func (s *Service) GetOrders(ctx context.Context, minPeers int) error {
 		// TODO(albrow): As a performance optimization, do this for loop
 		// partly in parallel.
 		currentNeighbors := s.node.Neighbors()
-		shufflePeers(currentNeighbors)
+		rankPeers(currentNeighbors, s.providerScores())
+		explorePeers(currentNeighbors, providerExplorationRate)
 		for _, peerID := range currentNeighbors {
 			if len(successfullySyncedPeers) >= minPeers {
 				return nil
@@ -406,7 +450,46 @@ func parseResponseWithSubprotocol(subprotocol Subprotocol, providerID peer.ID, r
 	}, nil
 }
 
+// providerScores returns the scores for each provider according to s.history.
+// It returns nil if there is no history or the scores could not be computed.
+func (s *Service) providerScores() map[peer.ID]float64 {
+	if s.history == nil {
+		return nil
+	}
+	scores, err := s.history.ProviderScores()
+	if err != nil {
+		log.WithError(err).Warn("could not get ordersync provider scores")
+		return nil
+	}
+	return scores
+}
+
 func (s *Service) getOrdersFromPeer(ctx context.Context, providerID peer.ID) error {
+	recorder := &outcomeRecorder{
+		outcome: &Outcome{
+			ProviderID:     providerID,
+			StartTime:      time.Now(),
+			OrdersRejected: map[string]int{},
+		},
+	}
+	err := s.getOrdersFromPeerWithRecorder(context.WithValue(ctx, outcomeContextKey{}, recorder), providerID, recorder)
+	if s.history != nil {
+		recorder.mut.Lock()
+		outcome := recorder.outcome
+		outcome.Duration = time.Since(outcome.StartTime)
+		outcome.Err = err
+		recorder.mut.Unlock()
+		if err := s.history.RecordOutcome(outcome); err != nil {
+			log.WithFields(log.Fields{
+				"error":    err.Error(),
+				"provider": providerID.Pretty(),
+			}).Warn("could not record ordersync outcome")
+		}
+	}
+	return err
+}
+
+func (s *Service) getOrdersFromPeerWithRecorder(ctx context.Context, providerID peer.ID, recorder *outcomeRecorder) error {
 	stream, err := s.node.NewStream(ctx, providerID, ID)
 	if err != nil {
 		s.handlePeerScoreEvent(providerID, psUnexpectedDisconnect)
@@ -418,6 +501,7 @@ func (s *Service) getOrdersFromPeer(ctx context.Context, providerID peer.ID) err
 
 	var nextReq *Request
 	var selectedSubprotocol Subprotocol
+	numShards, shards := s.node.TopicShards()
 	for {
 		select {
 		case <-ctx.Done():
@@ -432,6 +516,9 @@ func (s *Service) getOrdersFromPeer(ctx context.Context, providerID peer.ID) err
 				Type:         TypeRequest,
 				Subprotocols: s.SupportedSubprotocols(),
 				Metadata:     nil,
+				NumShards:    numShards,
+				Shards:       shards,
+				Compression:  supportedCompressions,
 			}
 		} else {
 			encodedMetadata, err := json.Marshal(nextReq.Metadata)
@@ -442,9 +529,13 @@ func (s *Service) getOrdersFromPeer(ctx context.Context, providerID peer.ID) err
 				Type:         TypeRequest,
 				Subprotocols: []string{selectedSubprotocol.Name()},
 				Metadata:     encodedMetadata,
+				NumShards:    numShards,
+				Shards:       shards,
+				Compression:  supportedCompressions,
 			}
 		}
 
+		requestSentAt := time.Now()
 		if err := json.NewEncoder(stream).Encode(rawReq); err != nil {
 			s.handlePeerScoreEvent(providerID, psUnexpectedDisconnect)
 			return err
@@ -454,7 +545,20 @@ func (s *Service) getOrdersFromPeer(ctx context.Context, providerID peer.ID) err
 		if err != nil {
 			return err
 		}
+		uncompressedSize, compressedSize, err := decompressResponse(rawRes)
+		if err != nil {
+			s.handlePeerScoreEvent(providerID, psInvalidMessage)
+			return err
+		}
+		if compressedSize > 0 {
+			s.compression.recordReceived(uncompressedSize, compressedSize)
+		}
 		s.handlePeerScoreEvent(providerID, psValidMessage)
+		if nextReq == nil {
+			recorder.mut.Lock()
+			recorder.outcome.Latency = time.Since(requestSentAt)
+			recorder.mut.Unlock()
+		}
 
 		subprotocol, found := s.subprotocols[rawRes.Subprotocol]
 		if !found {
@@ -462,6 +566,13 @@ func (s *Service) getOrdersFromPeer(ctx context.Context, providerID peer.ID) err
 			return fmt.Errorf("unsupported subprotocol: %s", subprotocol)
 		}
 		selectedSubprotocol = subprotocol
+		recorder.mut.Lock()
+		recorder.outcome.Subprotocol = subprotocol.Name()
+		recorder.outcome.OrdersReceived += len(rawRes.Orders)
+		recorder.mut.Unlock()
+		// Providers which don't support sharding respond with orders from all
+		// shards. Drop any orders outside of the shards we subscribe to.
+		rawRes.Orders = filterOrdersByShard(rawRes.Orders, numShards, shards)
 		res, err := parseResponseWithSubprotocol(subprotocol, providerID, rawRes)
 		if err != nil {
 			s.handlePeerScoreEvent(providerID, psInvalidMessage)
@@ -480,6 +591,30 @@ func (s *Service) getOrdersFromPeer(ctx context.Context, providerID peer.ID) err
 	}
 }
 
+// filterOrdersByShard returns the orders whose order hash falls into one of the
+// given shards of an order topic split into numShards shards. If the topic is
+// not sharded, it returns all orders.
+func filterOrdersByShard(orders []*zeroex.SignedOrder, numShards int, shards []int) []*zeroex.SignedOrder {
+	if numShards <= 1 || len(shards) == 0 {
+		return orders
+	}
+	shardSet := map[int]struct{}{}
+	for _, shard := range shards {
+		shardSet[shard] = struct{}{}
+	}
+	filtered := []*zeroex.SignedOrder{}
+	for _, order := range orders {
+		orderHash, err := order.ComputeOrderHash()
+		if err != nil {
+			continue
+		}
+		if _, found := shardSet[p2p.ShardForKey(orderHash.Bytes(), numShards)]; found {
+			filtered = append(filtered, order)
+		}
+	}
+	return filtered
+}
+
 // shufflePeers randomizes the order of the given list of peers.
 func shufflePeers(peers []peer.ID) {
 	rand.Seed(time.Now().UnixNano())
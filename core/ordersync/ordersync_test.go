@@ -1,10 +1,17 @@
 package ordersync
 
 import (
+	"context"
+	"encoding/json"
+	"math/big"
 	"testing"
 	"time"
 
+	"github.com/0xProject/0x-mesh/p2p"
+	"github.com/0xProject/0x-mesh/zeroex"
+	peer "github.com/libp2p/go-libp2p-peer"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCalculateDelayWithJitters(t *testing.T) {
@@ -18,3 +25,164 @@ func TestCalculateDelayWithJitters(t *testing.T) {
 		assert.InDelta(t, approxDelay, actualDelay, float64(1*time.Second), "actualDelay: %s", actualDelay)
 	}
 }
+
+func TestRankPeers(t *testing.T) {
+	good := peer.ID("good")
+	bad := peer.ID("bad")
+	unknown := peer.ID("unknown")
+	scores := map[peer.ID]float64{
+		good: 10,
+		bad:  -5,
+	}
+	for i := 0; i < 10; i++ {
+		peers := []peer.ID{bad, unknown, good}
+		rankPeers(peers, scores)
+		assert.Equal(t, []peer.ID{good, unknown, bad}, peers)
+	}
+}
+
+func TestExplorePeers(t *testing.T) {
+	peers := []peer.ID{"a", "b", "c", "d"}
+	explorePeers(peers, 0)
+	assert.Equal(t, []peer.ID{"a", "b", "c", "d"}, peers)
+
+	// With exploration, every peer should eventually be tried first.
+	first := map[peer.ID]bool{}
+	for i := 0; i < 1000; i++ {
+		peers := []peer.ID{"a", "b", "c", "d"}
+		explorePeers(peers, 0.5)
+		assert.ElementsMatch(t, []peer.ID{"a", "b", "c", "d"}, peers)
+		first[peers[0]] = true
+	}
+	assert.Len(t, first, 4)
+}
+
+func TestRecordValidationResults(t *testing.T) {
+	recorder := &outcomeRecorder{
+		outcome: &Outcome{
+			OrdersRejected: map[string]int{},
+		},
+	}
+	ctx := context.WithValue(context.Background(), outcomeContextKey{}, recorder)
+	RecordValidationResults(ctx, 3, map[string]int{"OrderExpired": 2})
+	RecordValidationResults(ctx, 1, map[string]int{"OrderExpired": 1, "OrderCancelled": 1})
+	assert.Equal(t, 4, recorder.outcome.OrdersAccepted)
+	assert.Equal(t, map[string]int{"OrderExpired": 3, "OrderCancelled": 1}, recorder.outcome.OrdersRejected)
+
+	// Should be a no-op for contexts not created by the ordersync service.
+	RecordValidationResults(context.Background(), 1, nil)
+}
+
+func TestFilterOrdersByShard(t *testing.T) {
+	numShards := 4
+	orders := make([]*zeroex.SignedOrder, 20)
+	for i := range orders {
+		orders[i] = &zeroex.SignedOrder{
+			Order: zeroex.Order{
+				ChainID:               big.NewInt(1337),
+				Salt:                  big.NewInt(int64(i)),
+				MakerAssetData:        []byte{},
+				MakerFeeAssetData:     []byte{},
+				TakerAssetData:        []byte{},
+				TakerFeeAssetData:     []byte{},
+				MakerAssetAmount:      big.NewInt(1),
+				TakerAssetAmount:      big.NewInt(1),
+				MakerFee:              big.NewInt(0),
+				TakerFee:              big.NewInt(0),
+				ExpirationTimeSeconds: big.NewInt(0),
+			},
+		}
+	}
+
+	assert.Equal(t, orders, filterOrdersByShard(orders, 0, nil))
+	assert.Equal(t, orders, filterOrdersByShard(orders, numShards, nil))
+
+	shards := []int{1, 2}
+	filtered := filterOrdersByShard(orders, numShards, shards)
+	numInShards := 0
+	for _, order := range orders {
+		orderHash, err := order.ComputeOrderHash()
+		require.NoError(t, err)
+		shard := p2p.ShardForKey(orderHash.Bytes(), numShards)
+		if shard == 1 || shard == 2 {
+			assert.Contains(t, filtered, order)
+			numInShards++
+		} else {
+			assert.NotContains(t, filtered, order)
+		}
+	}
+	assert.Len(t, filtered, numInShards)
+}
+
+func TestSelectCompression(t *testing.T) {
+	assert.Equal(t, CompressionGzip, selectCompression([]string{"zstd", CompressionGzip}))
+	assert.Equal(t, "", selectCompression([]string{"zstd"}))
+	// Requesters which don't support compression don't send the field.
+	assert.Equal(t, "", selectCompression(nil))
+}
+
+func TestCompressResponse(t *testing.T) {
+	orders := make([]*zeroex.SignedOrder, 50)
+	for i := range orders {
+		orders[i] = &zeroex.SignedOrder{
+			Order: zeroex.Order{
+				ChainID:               big.NewInt(1337),
+				Salt:                  big.NewInt(int64(i)),
+				MakerAssetData:        []byte{},
+				MakerFeeAssetData:     []byte{},
+				TakerAssetData:        []byte{},
+				TakerFeeAssetData:     []byte{},
+				MakerAssetAmount:      big.NewInt(1),
+				TakerAssetAmount:      big.NewInt(1),
+				MakerFee:              big.NewInt(0),
+				TakerFee:              big.NewInt(0),
+				ExpirationTimeSeconds: big.NewInt(0),
+			},
+			Signature: []byte{},
+		}
+	}
+	rawRes := &rawResponse{
+		Type:        TypeResponse,
+		Subprotocol: "/test",
+		Orders:      orders,
+		Complete:    true,
+		Metadata:    json.RawMessage(`{"page":1}`),
+	}
+
+	uncompressedSize, compressedSize, err := compressResponse(rawRes, CompressionGzip)
+	require.NoError(t, err)
+	assert.True(t, compressedSize < uncompressedSize, "expected compressed page (%d bytes) to be smaller than uncompressed page (%d bytes)", compressedSize, uncompressedSize)
+	assert.Nil(t, rawRes.Orders)
+	assert.Nil(t, rawRes.Metadata)
+
+	// Send the response over the wire.
+	encoded, err := json.Marshal(rawRes)
+	require.NoError(t, err)
+	var receivedRes rawResponse
+	require.NoError(t, json.Unmarshal(encoded, &receivedRes))
+
+	decompressedSize, receivedCompressedSize, err := decompressResponse(&receivedRes)
+	require.NoError(t, err)
+	assert.Equal(t, uncompressedSize, decompressedSize)
+	assert.Equal(t, compressedSize, receivedCompressedSize)
+	assert.Equal(t, "", receivedRes.Compression)
+	assert.JSONEq(t, `{"page":1}`, string(receivedRes.Metadata))
+	require.Len(t, receivedRes.Orders, len(orders))
+	for i, order := range receivedRes.Orders {
+		expectedHash, err := orders[i].ComputeOrderHash()
+		require.NoError(t, err)
+		actualHash, err := order.ComputeOrderHash()
+		require.NoError(t, err)
+		assert.Equal(t, expectedHash, actualHash)
+	}
+
+	// Uncompressed responses (e.g. from providers which don't support
+	// compression) are left as is.
+	_, compressedSize, err = decompressResponse(&receivedRes)
+	require.NoError(t, err)
+	assert.Equal(t, 0, compressedSize)
+	assert.Len(t, receivedRes.Orders, len(orders))
+
+	_, _, err = decompressResponse(&rawResponse{Compression: "zstd"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,323 @@
+package core
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/0xProject/0x-mesh/common/types"
+	"github.com/0xProject/0x-mesh/core/ordersync"
+	"github.com/0xProject/0x-mesh/meshdb"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+)
+
+const (
+	// orderSyncHistoryRetention is how long to keep records of past ordersync
+	// attempts in the database.
+	orderSyncHistoryRetention = 7 * 24 * time.Hour
+	// orderSyncFailurePenalty is subtracted from a provider's score for each
+	// failed ordersync attempt.
+	orderSyncFailurePenalty = 1.0
+)
+
+// Ensure that orderSyncHistory implements the ordersync.History interface.
+var _ ordersync.History = (*orderSyncHistory)(nil)
+
+// orderSyncHistory stores the outcome of each ordersync attempt in the
+// database and scores providers based on how many of the orders they sent us
+// were valid.
+type orderSyncHistory struct {
+	db *meshdb.MeshDB
+	// mu protects syncedProviders and providerTotals.
+	mu sync.Mutex
+	// syncedProviders are the providers with which at least one ordersync
+	// attempt succeeded since the history was created. Unlike the records in
+	// the database, it does not include attempts from previous runs.
+	syncedProviders map[peer.ID]struct{}
+	// providerTotals are the running totals of the records in the database,
+	// keyed by provider ID. They are updated whenever a record is added or
+	// removed so that ProviderStats doesn't need to read every record.
+	providerTotals map[string]*orderSyncProviderTotals
+}
+
+// newOrderSyncHistory returns a history backed by the given database. It reads
+// the existing records once to compute the totals for ProviderStats.
+func newOrderSyncHistory(db *meshdb.MeshDB) (*orderSyncHistory, error) {
+	h := &orderSyncHistory{
+		db:              db,
+		syncedProviders: map[peer.ID]struct{}{},
+		providerTotals:  map[string]*orderSyncProviderTotals{},
+	}
+	if err := db.ForEachOrderSyncRecord(func(record *meshdb.OrderSyncRecord) error {
+		h.addToTotals(record)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// RecordOutcome stores the given outcome and removes any records which are
+// older than orderSyncHistoryRetention.
+func (h *orderSyncHistory) RecordOutcome(outcome *ordersync.Outcome) error {
+	record := &meshdb.OrderSyncRecord{
+		ProviderID:     outcome.ProviderID.Pretty(),
+		Subprotocol:    outcome.Subprotocol,
+		StartTime:      outcome.StartTime,
+		Duration:       outcome.Duration,
+		Latency:        outcome.Latency,
+		OrdersReceived: outcome.OrdersReceived,
+		OrdersAccepted: outcome.OrdersAccepted,
+		OrdersRejected: outcome.OrdersRejected,
+		Succeeded:      outcome.Err == nil,
+	}
+	// Hold the lock while writing to the database so that providerTotals always
+	// matches the records in it.
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if outcome.Err != nil {
+		record.Error = outcome.Err.Error()
+	} else {
+		h.syncedProviders[outcome.ProviderID] = struct{}{}
+	}
+	if err := h.db.OrderSyncRecords.Insert(record); err != nil {
+		return err
+	}
+	h.addToTotals(record)
+	removed, err := h.db.ClearOrderSyncRecordsBefore(time.Now().Add(-orderSyncHistoryRetention))
+	if err != nil {
+		return err
+	}
+	for _, record := range removed {
+		h.removeFromTotals(record)
+	}
+	return nil
+}
+
+// orderSyncProviderTotals are the running totals of the ordersync records of a
+// single provider.
+type orderSyncProviderTotals struct {
+	stats         types.OrderSyncProviderStats
+	totalDuration time.Duration
+	// totalLatency is the sum of the latencies of the numLatencies records in
+	// which the provider responded.
+	totalLatency time.Duration
+	numLatencies int
+}
+
+// addToTotals adds the given record to the totals of its provider. The caller
+// must hold mu unless the history is still being created.
+func (h *orderSyncHistory) addToTotals(record *meshdb.OrderSyncRecord) {
+	totals, found := h.providerTotals[record.ProviderID]
+	if !found {
+		totals = &orderSyncProviderTotals{
+			stats: types.OrderSyncProviderStats{
+				PeerID:         record.ProviderID,
+				OrdersRejected: map[string]int{},
+			},
+		}
+		h.providerTotals[record.ProviderID] = totals
+	}
+	totals.stats.NumSyncs++
+	if !record.Succeeded {
+		totals.stats.NumFailures++
+	}
+	totals.stats.OrdersReceived += record.OrdersReceived
+	totals.stats.OrdersAccepted += record.OrdersAccepted
+	for code, count := range record.OrdersRejected {
+		totals.stats.OrdersRejected[code] += count
+	}
+	if !record.StartTime.Before(totals.stats.LastSyncTime) {
+		totals.stats.LastSyncTime = record.StartTime
+		totals.stats.LastSubprotocol = record.Subprotocol
+	}
+	totals.totalDuration += record.Duration
+	if record.Latency > 0 {
+		totals.totalLatency += record.Latency
+		totals.numLatencies++
+	}
+}
+
+// removeFromTotals subtracts the given record from the totals of its
+// provider. Records are removed oldest first, so the most recent sync of the
+// provider only changes if all of its records are removed, in which case the
+// provider is removed from the totals. The caller must hold mu.
+func (h *orderSyncHistory) removeFromTotals(record *meshdb.OrderSyncRecord) {
+	totals, found := h.providerTotals[record.ProviderID]
+	if !found {
+		return
+	}
+	totals.stats.NumSyncs--
+	if totals.stats.NumSyncs <= 0 {
+		delete(h.providerTotals, record.ProviderID)
+		return
+	}
+	if !record.Succeeded {
+		totals.stats.NumFailures--
+	}
+	totals.stats.OrdersReceived -= record.OrdersReceived
+	totals.stats.OrdersAccepted -= record.OrdersAccepted
+	for code, count := range record.OrdersRejected {
+		totals.stats.OrdersRejected[code] -= count
+		if totals.stats.OrdersRejected[code] <= 0 {
+			delete(totals.stats.OrdersRejected, code)
+		}
+	}
+	totals.totalDuration -= record.Duration
+	if record.Latency > 0 {
+		totals.totalLatency -= record.Latency
+		totals.numLatencies--
+	}
+}
+
+// NumSyncedProviders returns the number of providers with which at least one
+// ordersync attempt succeeded since the history was created.
+func (h *orderSyncHistory) NumSyncedProviders() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.syncedProviders)
+}
+
+// ProviderScores returns a score for each provider in the history (see
+// scoreOrderSyncProviders).
+func (h *orderSyncHistory) ProviderScores() (map[peer.ID]float64, error) {
+	records, err := h.db.FindAllOrderSyncRecords()
+	if err != nil {
+		return nil, err
+	}
+	return scoreOrderSyncProviders(records), nil
+}
+
+// orderSyncProviderPerformance aggregates the ordersync records of a single
+// provider.
+type orderSyncProviderPerformance struct {
+	numSuccesses   int
+	numFailures    int
+	ordersReceived int
+	ordersAccepted int
+	// syncDuration is the total duration of the successful attempts.
+	syncDuration time.Duration
+	// totalLatency is the sum of the latencies of the numLatencies attempts in
+	// which the provider responded.
+	totalLatency time.Duration
+	numLatencies int
+}
+
+func (p *orderSyncProviderPerformance) averageLatency() time.Duration {
+	if p.numLatencies == 0 {
+		return 0
+	}
+	return p.totalLatency / time.Duration(p.numLatencies)
+}
+
+// ordersPerSecond returns the rate at which the provider sent us orders
+// during successful attempts.
+func (p *orderSyncProviderPerformance) ordersPerSecond() float64 {
+	if p.syncDuration <= 0 {
+		return 0
+	}
+	return float64(p.ordersReceived) / p.syncDuration.Seconds()
+}
+
+// scoreOrderSyncProviders returns a score for each provider in the given
+// records. Providers without a successful attempt get a negative score of
+// orderSyncFailurePenalty per failed attempt, which ranks them after providers
+// we haven't tried yet. The score of the other providers is the product of
+// the fraction of attempts that succeeded, the fraction of received orders
+// that were accepted and the speed of the provider relative to the fastest
+// provider. Speed is the average of the latency and throughput relative to the
+// provider with the lowest average latency and the one with the highest
+// throughput, so that providers which are close to us are preferred. A
+// provider without latency measurements (e.g. from before they were recorded)
+// is assumed to be as fast as the fastest one.
+func scoreOrderSyncProviders(records []*meshdb.OrderSyncRecord) map[peer.ID]float64 {
+	performances := map[peer.ID]*orderSyncProviderPerformance{}
+	for _, record := range records {
+		providerID, err := peer.IDB58Decode(record.ProviderID)
+		if err != nil {
+			continue
+		}
+		performance, found := performances[providerID]
+		if !found {
+			performance = &orderSyncProviderPerformance{}
+			performances[providerID] = performance
+		}
+		if record.Latency > 0 {
+			performance.totalLatency += record.Latency
+			performance.numLatencies++
+		}
+		if !record.Succeeded {
+			performance.numFailures++
+			continue
+		}
+		performance.numSuccesses++
+		performance.ordersReceived += record.OrdersReceived
+		performance.ordersAccepted += record.OrdersAccepted
+		performance.syncDuration += record.Duration
+	}
+
+	var minLatency time.Duration
+	maxOrdersPerSecond := 0.0
+	for _, performance := range performances {
+		if performance.numSuccesses == 0 {
+			continue
+		}
+		if latency := performance.averageLatency(); latency > 0 && (minLatency == 0 || latency < minLatency) {
+			minLatency = latency
+		}
+		if ordersPerSecond := performance.ordersPerSecond(); ordersPerSecond > maxOrdersPerSecond {
+			maxOrdersPerSecond = ordersPerSecond
+		}
+	}
+
+	scores := map[peer.ID]float64{}
+	for providerID, performance := range performances {
+		if performance.numSuccesses == 0 {
+			scores[providerID] = -orderSyncFailurePenalty * float64(performance.numFailures)
+			continue
+		}
+		if performance.ordersReceived == 0 {
+			scores[providerID] = 0
+			continue
+		}
+		latencyFactor := 1.0
+		if latency := performance.averageLatency(); latency > 0 {
+			latencyFactor = float64(minLatency) / float64(latency)
+		}
+		throughputFactor := 1.0
+		if maxOrdersPerSecond > 0 {
+			throughputFactor = performance.ordersPerSecond() / maxOrdersPerSecond
+		}
+		successRate := float64(performance.numSuccesses) / float64(performance.numSuccesses+performance.numFailures)
+		acceptanceRate := float64(performance.ordersAccepted) / float64(performance.ordersReceived)
+		scores[providerID] = successRate * acceptanceRate * (latencyFactor + throughputFactor) / 2
+	}
+	return scores
+}
+
+// ProviderStats returns per-provider stats for the history, sorted by
+// descending number of accepted orders. It doesn't read from the database.
+func (h *orderSyncHistory) ProviderStats() []*types.OrderSyncProviderStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	allStats := make([]*types.OrderSyncProviderStats, 0, len(h.providerTotals))
+	for _, totals := range h.providerTotals {
+		stats := totals.stats
+		stats.OrdersRejected = make(map[string]int, len(totals.stats.OrdersRejected))
+		for code, count := range totals.stats.OrdersRejected {
+			stats.OrdersRejected[code] = count
+		}
+		stats.AverageDurationMs = (totals.totalDuration / time.Duration(stats.NumSyncs)).Milliseconds()
+		if totals.numLatencies > 0 {
+			stats.AverageLatencyMs = (totals.totalLatency / time.Duration(totals.numLatencies)).Milliseconds()
+		}
+		allStats = append(allStats, &stats)
+	}
+	sort.Slice(allStats, func(i, j int) bool {
+		if allStats[i].OrdersAccepted != allStats[j].OrdersAccepted {
+			return allStats[i].OrdersAccepted > allStats[j].OrdersAccepted
+		}
+		return allStats[i].PeerID < allStats[j].PeerID
+	})
+	return allStats
+}
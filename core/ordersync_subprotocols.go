@@ -7,7 +7,7 @@ import (
 	"fmt"
 
 	"github.com/0xProject/0x-mesh/core/ordersync"
-	"github.com/0xProject/0x-mesh/orderfilter"
+	"github.com/0xProject/0x-mesh/meshdb"
 	"github.com/0xProject/0x-mesh/zeroex"
 	log "github.com/sirupsen/logrus"
 )
@@ -19,18 +19,16 @@ var _ ordersync.Subprotocol = (*FilteredPaginationSubProtocol)(nil)
 // paginating through them. It involves sending multiple requests until pagination is
 // finished and all orders have been returned.
 type FilteredPaginationSubProtocol struct {
-	app         *App
-	orderFilter *orderfilter.Filter
-	perPage     int
+	app     *App
+	perPage int
 }
 
 // NewFilteredPaginationSubprotocol creates and returns a new FilteredPaginationSubprotocol
 // which will respond with perPage orders for each individual request/response.
 func NewFilteredPaginationSubprotocol(app *App, perPage int) *FilteredPaginationSubProtocol {
 	return &FilteredPaginationSubProtocol{
-		app:         app,
-		orderFilter: app.orderFilter,
-		perPage:     perPage,
+		app:     app,
+		perPage: perPage,
 	}
 }
 
@@ -86,7 +84,7 @@ func (p *FilteredPaginationSubProtocol) HandleOrderSyncRequest(ctx context.Conte
 		default:
 		}
 		// Get the orders for this page.
-		ordersResp, err := p.app.GetOrders(currentPage, p.perPage, metadata.SnapshotID)
+		ordersResp, err := p.app.GetOrders(ctx, currentPage, p.perPage, metadata.SnapshotID)
 		if err != nil {
 			return nil, err
 		}
@@ -96,11 +94,18 @@ func (p *FilteredPaginationSubProtocol) HandleOrderSyncRequest(ctx context.Conte
 			break
 		}
 		// Filter the orders for this page.
+		pageOrders := make([]*zeroex.SignedOrder, 0, len(ordersResp.OrdersInfos))
 		for _, orderInfo := range ordersResp.OrdersInfos {
-			if matches, err := p.orderFilter.MatchOrder(orderInfo.SignedOrder); err != nil {
-				return nil, err
-			} else if matches {
-				filteredOrders = append(filteredOrders, orderInfo.SignedOrder)
+			if p.app.isQuarantined(orderInfo.SignedOrder) {
+				continue
+			}
+			pageOrders = append(pageOrders, orderInfo.SignedOrder)
+		}
+		for i, result := range p.app.matchOrderFilters(pageOrders) {
+			if result.Err != nil {
+				return nil, result.Err
+			} else if result.Valid {
+				filteredOrders = append(filteredOrders, pageOrders[i])
 			}
 		}
 		if len(filteredOrders) == 0 {
@@ -135,19 +140,24 @@ func (p *FilteredPaginationSubProtocol) HandleOrderSyncResponse(ctx context.Cont
 		return nil, fmt.Errorf("FilteredPaginationSubProtocol received response with wrong metadata type (got %T)", res.Metadata)
 	}
 	filteredOrders := []*zeroex.SignedOrder{}
-	for _, order := range res.Orders {
-		if matches, err := p.orderFilter.MatchOrder(order); err != nil {
-			return nil, err
-		} else if matches {
-			filteredOrders = append(filteredOrders, order)
-		} else if !matches {
+	for i, result := range p.app.matchOrderFilters(res.Orders) {
+		if result.Err != nil {
+			return nil, result.Err
+		} else if result.Valid {
+			filteredOrders = append(filteredOrders, res.Orders[i])
+		} else {
 			p.app.handlePeerScoreEvent(res.ProviderID, psReceivedOrderDoesNotMatchFilter)
 		}
 	}
-	validationResults, err := p.app.orderWatcher.ValidateAndStoreValidOrders(ctx, filteredOrders, false, p.app.chainID)
+	validationResults, err := p.app.orderWatcher.ValidateAndStoreValidOrders(ctx, filteredOrders, false, meshdb.OrderSourceOrderSync, p.app.chainID)
 	if err != nil {
 		return nil, err
 	}
+	rejectedByCode := map[string]int{}
+	for _, rejectedOrderInfo := range validationResults.Rejected {
+		rejectedByCode[rejectedOrderInfo.Status.Code]++
+	}
+	ordersync.RecordValidationResults(ctx, len(validationResults.Accepted), rejectedByCode)
 	for _, acceptedOrderInfo := range validationResults.Accepted {
 		if acceptedOrderInfo.IsNew {
 			log.WithFields(map[string]interface{}{
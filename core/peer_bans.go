@@ -0,0 +1,121 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/0xProject/0x-mesh/common/types"
+	"github.com/0xProject/0x-mesh/db"
+	"github.com/0xProject/0x-mesh/meshdb"
+	"github.com/0xProject/0x-mesh/p2p/banner"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrPeerNotBanned is returned by UnbanPeer if the given peer is not banned.
+type ErrPeerNotBanned struct {
+	peerID peer.ID
+}
+
+func (e ErrPeerNotBanned) Error() string {
+	return fmt.Sprintf("peer is not banned: %s", e.peerID.Pretty())
+}
+
+// BanPeer bans the given peer and stores the ban in the database so that it
+// persists across restarts. If duration is 0, the ban never expires. If the
+// peer is already banned, the existing ban is replaced.
+func (app *App) BanPeer(peerID peer.ID, reason string, duration time.Duration) error {
+	<-app.started
+
+	if !app.isEnabled(SubsystemP2P) {
+		return ErrSubsystemDisabled{Subsystem: SubsystemP2P}
+	}
+
+	now := time.Now().UTC()
+	var expiry time.Time
+	if duration > 0 {
+		expiry = now.Add(duration)
+	}
+	if err := app.db.SavePeerBan(&meshdb.PeerBan{
+		PeerID:    peerID.Pretty(),
+		Reason:    reason,
+		Expiry:    expiry,
+		CreatedAt: now,
+	}); err != nil {
+		return err
+	}
+	app.node.BanPeer(banner.PeerBan{
+		PeerID: peerID,
+		Reason: reason,
+		Expiry: expiry,
+	})
+	return nil
+}
+
+// UnbanPeer lifts the ban on the given peer and removes it from the database.
+func (app *App) UnbanPeer(peerID peer.ID) error {
+	<-app.started
+
+	if !app.isEnabled(SubsystemP2P) {
+		return ErrSubsystemDisabled{Subsystem: SubsystemP2P}
+	}
+
+	wasBanned := app.node.UnbanPeer(peerID)
+	if err := app.db.DeletePeerBan(peerID.Pretty()); err != nil {
+		if _, ok := err.(db.NotFoundError); !ok {
+			return err
+		}
+		if !wasBanned {
+			return ErrPeerNotBanned{peerID: peerID}
+		}
+	}
+	return nil
+}
+
+// GetPeerBans returns all active peer bans.
+func (app *App) GetPeerBans() ([]*types.PeerBan, error) {
+	<-app.started
+
+	if !app.isEnabled(SubsystemP2P) {
+		return nil, ErrSubsystemDisabled{Subsystem: SubsystemP2P}
+	}
+
+	nodeBans := app.node.PeerBans()
+	peerBans := make([]*types.PeerBan, len(nodeBans))
+	for i, ban := range nodeBans {
+		peerBans[i] = &types.PeerBan{
+			PeerID: ban.PeerID.Pretty(),
+			Reason: ban.Reason,
+			Expiry: ban.Expiry,
+		}
+	}
+	return peerBans, nil
+}
+
+// applyStoredPeerBans bans all peers with an active ban in the database. It
+// should be called after app.node is initialized but before it is started.
+func (app *App) applyStoredPeerBans() error {
+	storedBans, err := app.db.FindAllPeerBans()
+	if err != nil {
+		return err
+	}
+	for _, storedBan := range storedBans {
+		peerID, err := peer.IDB58Decode(storedBan.PeerID)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err.Error(),
+				"peerID": storedBan.PeerID,
+			}).Warn("ignoring stored ban with invalid peer ID")
+			continue
+		}
+		app.node.BanPeer(banner.PeerBan{
+			PeerID: peerID,
+			Reason: storedBan.Reason,
+			Expiry: storedBan.Expiry,
+		})
+	}
+	if len(storedBans) > 0 {
+		log.WithField("numPeerBans", len(storedBans)).Info("applied stored peer bans")
+	}
+	return nil
+}
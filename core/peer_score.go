@@ -12,6 +12,7 @@ const (
 	psValidMessage
 	psOrderStored
 	psReceivedOrderDoesNotMatchFilter
+	psInaccurateOrderUpdateHint
 )
 
 func (app *App) handlePeerScoreEvent(id peer.ID, event peerScoreEvent) {
@@ -29,6 +30,8 @@ func (app *App) handlePeerScoreEvent(id peer.ID, event peerScoreEvent) {
 		app.node.SetPeerScore(id, "order-stored", 10)
 	case psReceivedOrderDoesNotMatchFilter:
 		app.node.SetPeerScore(id, "received-order-does-not-match-filter", -10)
+	case psInaccurateOrderUpdateHint:
+		app.node.AddPeerScore(id, "inaccurate-order-update-hint", -2)
 	default:
 		log.WithField("event", event).Error("unknown peerScoreEvent")
 	}
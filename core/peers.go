@@ -0,0 +1,38 @@
+package core
+
+import (
+	"sort"
+
+	"github.com/0xProject/0x-mesh/common/types"
+)
+
+// GetPeers returns the peers the node is connected to along with the metadata
+// they sent (see Config.P2PNodeName), sorted by peer ID.
+func (app *App) GetPeers() ([]*types.PeerInfo, error) {
+	<-app.started
+
+	if !app.isEnabled(SubsystemP2P) {
+		return nil, ErrSubsystemDisabled{Subsystem: SubsystemP2P}
+	}
+
+	connectedPeers := app.node.ConnectedPeers()
+	peers := make([]*types.PeerInfo, len(connectedPeers))
+	for i, connectedPeer := range connectedPeers {
+		peers[i] = &types.PeerInfo{
+			PeerID: connectedPeer.ID.Pretty(),
+			Addrs:  connectedPeer.Addrs,
+		}
+		if metadata := connectedPeer.Metadata; metadata != nil {
+			peers[i].Metadata = &types.PeerMetadata{
+				Name:       metadata.Name,
+				ContactURL: metadata.ContactURL,
+				ChainIDs:   metadata.ChainIDs,
+				Topics:     metadata.Topics,
+			}
+		}
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].PeerID < peers[j].PeerID
+	})
+	return peers, nil
+}
@@ -0,0 +1,28 @@
+package core
+
+import (
+	"github.com/0xProject/0x-mesh/common/types"
+)
+
+// PipelineHealth returns whether the order processing pipeline is making
+// progress (see Config.BlockProcessingStallThreshold). Unlike most other
+// methods, it doesn't wait for the App to be started, so it can be used for
+// health checks.
+func (app *App) PipelineHealth() *types.PipelineHealth {
+	stalledStages := app.orderWatcher.StalledStages()
+	health := &types.PipelineHealth{
+		Status:        types.PipelineHealthOK,
+		StalledStages: make([]*types.StalledStage, len(stalledStages)),
+	}
+	if len(stalledStages) > 0 {
+		health.Status = types.PipelineHealthDegraded
+	}
+	for i, stalledStage := range stalledStages {
+		health.StalledStages[i] = &types.StalledStage{
+			Stage:        string(stalledStage.Stage),
+			StalledForMs: stalledStage.StalledFor.Milliseconds(),
+			ThresholdMs:  stalledStage.Threshold.Milliseconds(),
+		}
+	}
+	return health
+}
@@ -0,0 +1,11 @@
+// +build js windows
+
+package core
+
+import "time"
+
+// processCPUTime returns false because the CPU time used by the process can't
+// be measured on this platform.
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
@@ -0,0 +1,37 @@
+package core
+
+import (
+	"github.com/0xProject/0x-mesh/common/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// RemoveOrders permanently removes the orders with the given hashes from the
+// node and emits a REMOVED order event with the given reason for each of them.
+// Only orders that were added through AddOrders on this node can be removed;
+// orders received from peers are left untouched and reported in NotRemoved.
+// Peers that already received the orders may continue to share them.
+func (app *App) RemoveOrders(orderHashes []common.Hash, reason string) (*types.RemoveOrdersResponse, error) {
+	<-app.started
+
+	results, err := app.orderWatcher.RemoveOrders(orderHashes, reason)
+	if err != nil {
+		return nil, err
+	}
+	response := &types.RemoveOrdersResponse{
+		Removed:    results.Removed,
+		NotRemoved: []*types.NotRemovedOrderInfo{},
+	}
+	// Report the orders that were not removed in the order they were requested.
+	for _, orderHash := range orderHashes {
+		notRemovedReason, found := results.NotRemoved[orderHash]
+		if !found {
+			continue
+		}
+		response.NotRemoved = append(response.NotRemoved, &types.NotRemovedOrderInfo{
+			OrderHash: orderHash,
+			Reason:    notRemovedReason,
+		})
+		delete(results.NotRemoved, orderHash)
+	}
+	return response, nil
+}
@@ -0,0 +1,235 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/0xProject/0x-mesh/common/types"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// minAdaptivePeerCountLow is the lowest low peer count watermark that
+	// adaptive resource limits go down to.
+	minAdaptivePeerCountLow = 8
+	// adaptivePeerCountStep is how much the low peer count watermark is raised
+	// after each sample once there is enough headroom.
+	adaptivePeerCountStep = 10
+	// resourceHeadroom is the fraction of each ceiling that the resource usage
+	// must stay below before adaptive resource limits are raised again. It
+	// keeps the limits from oscillating around a ceiling.
+	resourceHeadroom = 0.8
+)
+
+// Resources, as logged when a ceiling is exceeded.
+const (
+	resourceCPU       = "cpu"
+	resourceMemory    = "memory"
+	resourceBandwidth = "bandwidth"
+)
+
+func validateResourceUsageConfig(config Config) error {
+	if config.ResourceSampleInterval < 0 {
+		return errors.New("config.ResourceSampleInterval cannot be negative")
+	}
+	if config.MaxCPUPercent < 0 || config.MaxMemoryBytes < 0 || config.MaxBandwidthBytesPerSecond < 0 {
+		return errors.New("config.MaxCPUPercent, config.MaxMemoryBytes and config.MaxBandwidthBytesPerSecond cannot be negative")
+	}
+	if config.AdaptiveResourceLimits {
+		if config.ResourceSampleInterval == 0 {
+			return errors.New("config.AdaptiveResourceLimits requires config.ResourceSampleInterval")
+		}
+		if config.MaxCPUPercent == 0 && config.MaxMemoryBytes == 0 && config.MaxBandwidthBytesPerSecond == 0 {
+			return errors.New("config.AdaptiveResourceLimits requires at least one of config.MaxCPUPercent, config.MaxMemoryBytes and config.MaxBandwidthBytesPerSecond")
+		}
+	}
+	return nil
+}
+
+// resourceLimits are the limits which are adjusted by adaptive resource
+// limits (see Config.AdaptiveResourceLimits).
+type resourceLimits struct {
+	peerCountLow          int
+	peerCountHigh         int
+	validationConcurrency int
+}
+
+// resourceUsageTracker holds the most recent sample of the resources used by
+// the node.
+type resourceUsageTracker struct {
+	mu          sync.Mutex
+	lastSample  time.Time
+	lastCPUTime time.Duration
+	usage       types.ResourceUsage
+}
+
+func newResourceUsageTracker() *resourceUsageTracker {
+	cpuTime, _ := processCPUTime()
+	return &resourceUsageTracker{
+		lastSample:  time.Now(),
+		lastCPUTime: cpuTime,
+	}
+}
+
+// ResourceUsage returns the most recent sample of the resources used by the
+// node (see Config.ResourceSampleInterval), together with the current limits.
+func (app *App) ResourceUsage() types.ResourceUsage {
+	app.resourceUsage.mu.Lock()
+	usage := app.resourceUsage.usage
+	app.resourceUsage.mu.Unlock()
+	limits := app.currentResourceLimits()
+	usage.PeerCountLow = limits.peerCountLow
+	usage.PeerCountHigh = limits.peerCountHigh
+	usage.ValidationConcurrency = limits.validationConcurrency
+	return usage
+}
+
+// currentResourceLimits returns the limits which are currently in use. The
+// peer count watermarks are 0 if the p2p subsystem is disabled.
+func (app *App) currentResourceLimits() resourceLimits {
+	limits := resourceLimits{
+		validationConcurrency: app.orderValidator.ConcurrencyLimit(),
+	}
+	if app.isEnabled(SubsystemP2P) && app.node != nil {
+		limits.peerCountLow, limits.peerCountHigh = app.node.PeerCountWatermarks()
+	}
+	return limits
+}
+
+// monitorResourceUsage samples the resources used by the node every
+// config.ResourceSampleInterval until ctx is canceled. If adaptive resource
+// limits are enabled, it adjusts the limits after each sample, never raising
+// them above the ones in use when it was started.
+func (app *App) monitorResourceUsage(ctx context.Context) {
+	maxLimits := app.currentResourceLimits()
+	ticker := time.NewTicker(app.config.ResourceSampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			usage := app.sampleResourceUsage(now)
+			exceeded := exceededResourceCeilings(app.config, usage, 1)
+			if len(exceeded) > 0 {
+				log.WithFields(log.Fields{
+					"exceeded":              exceeded,
+					"cpuPercent":            usage.CPUPercent,
+					"memoryBytes":           usage.MemoryBytes,
+					"bandwidthInPerSecond":  usage.BandwidthInPerSecond,
+					"bandwidthOutPerSecond": usage.BandwidthOutPerSecond,
+				}).Warn("resource usage exceeds configured ceiling")
+			}
+			if app.config.AdaptiveResourceLimits {
+				app.adjustResourceLimits(usage, maxLimits)
+			}
+		}
+	}
+}
+
+// sampleResourceUsage measures the resources used by the node since the last
+// sample and stores the result.
+func (app *App) sampleResourceUsage(now time.Time) types.ResourceUsage {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	usage := types.ResourceUsage{
+		SampledAt:   now,
+		CPUPercent:  -1,
+		MemoryBytes: int64(memStats.Sys - memStats.HeapReleased),
+	}
+	if app.isEnabled(SubsystemP2P) && app.node != nil {
+		bandwidth := app.node.BandwidthTotals()
+		usage.BandwidthInPerSecond = bandwidth.RateIn
+		usage.BandwidthOutPerSecond = bandwidth.RateOut
+	}
+
+	tracker := app.resourceUsage
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	if cpuTime, ok := processCPUTime(); ok {
+		if elapsed := now.Sub(tracker.lastSample); elapsed > 0 {
+			usage.CPUPercent = 100 * float64(cpuTime-tracker.lastCPUTime) / float64(elapsed)
+		}
+		tracker.lastCPUTime = cpuTime
+	}
+	tracker.lastSample = now
+	usage.Throttled = tracker.usage.Throttled
+	tracker.usage = usage
+	return usage
+}
+
+// adjustResourceLimits applies the limits returned by nextResourceLimits for
+// the given usage.
+func (app *App) adjustResourceLimits(usage types.ResourceUsage, maxLimits resourceLimits) {
+	current := app.currentResourceLimits()
+	next := nextResourceLimits(app.config, usage, current, maxLimits)
+	if next != current {
+		if next.peerCountLow != current.peerCountLow || next.peerCountHigh != current.peerCountHigh {
+			if err := app.node.SetPeerCountWatermarks(next.peerCountLow, next.peerCountHigh); err != nil {
+				log.WithError(err).Error("could not set peer count watermarks")
+				return
+			}
+		}
+		app.orderValidator.SetConcurrencyLimit(next.validationConcurrency)
+		log.WithFields(log.Fields{
+			"peerCountLow":          next.peerCountLow,
+			"peerCountHigh":         next.peerCountHigh,
+			"validationConcurrency": next.validationConcurrency,
+		}).Info("adjusted resource limits")
+	}
+	app.resourceUsage.mu.Lock()
+	app.resourceUsage.usage.Throttled = next != maxLimits
+	app.resourceUsage.mu.Unlock()
+}
+
+// nextResourceLimits returns the limits to use after a sample with the given
+// usage. While any ceiling is exceeded, the low peer count watermark is
+// lowered by a quarter (but not below minAdaptivePeerCountLow) and the
+// validation concurrency is halved. Once the usage is below resourceHeadroom
+// of every ceiling, they are raised back towards maxLimits step by step. The
+// high peer count watermark keeps its distance to the low one. Limits which
+// are 0 (e.g. the peer count watermarks if the p2p subsystem is disabled) are
+// left unchanged.
+func nextResourceLimits(config Config, usage types.ResourceUsage, current resourceLimits, maxLimits resourceLimits) resourceLimits {
+	next := current
+	switch {
+	case len(exceededResourceCeilings(config, usage, 1)) > 0:
+		next.peerCountLow = current.peerCountLow * 3 / 4
+		if next.peerCountLow < minAdaptivePeerCountLow {
+			next.peerCountLow = min(current.peerCountLow, minAdaptivePeerCountLow)
+		}
+		next.validationConcurrency = current.validationConcurrency / 2
+	case len(exceededResourceCeilings(config, usage, resourceHeadroom)) == 0:
+		next.peerCountLow = min(current.peerCountLow+adaptivePeerCountStep, maxLimits.peerCountLow)
+		next.validationConcurrency = current.validationConcurrency + 1
+	}
+	if next.peerCountLow != 0 {
+		next.peerCountHigh = next.peerCountLow + maxLimits.peerCountHigh - maxLimits.peerCountLow
+	}
+	if next.validationConcurrency < 1 {
+		next.validationConcurrency = 1
+	}
+	if next.validationConcurrency > maxLimits.validationConcurrency {
+		next.validationConcurrency = maxLimits.validationConcurrency
+	}
+	return next
+}
+
+// exceededResourceCeilings returns the resources whose usage exceeds the given
+// fraction of their ceiling.
+func exceededResourceCeilings(config Config, usage types.ResourceUsage, fraction float64) []string {
+	exceeded := []string{}
+	if config.MaxCPUPercent != 0 && usage.CPUPercent > fraction*config.MaxCPUPercent {
+		exceeded = append(exceeded, resourceCPU)
+	}
+	if config.MaxMemoryBytes != 0 && float64(usage.MemoryBytes) > fraction*float64(config.MaxMemoryBytes) {
+		exceeded = append(exceeded, resourceMemory)
+	}
+	if config.MaxBandwidthBytesPerSecond != 0 && usage.BandwidthInPerSecond+usage.BandwidthOutPerSecond > fraction*float64(config.MaxBandwidthBytesPerSecond) {
+		exceeded = append(exceeded, resourceBandwidth)
+	}
+	return exceeded
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/0xProject/0x-mesh/common/types"
+	"github.com/0xProject/0x-mesh/ethereum/ratelimit"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// RevalidateOrders immediately re-validates the orders with the given hashes
+// against the latest block and returns their updated state. It is useful when
+// the caller knows that the state of an order changed (e.g. because they filled
+// it) and doesn't want to wait for Mesh to process the corresponding block.
+// Order events are emitted for any orders whose state changed.
+func (app *App) RevalidateOrders(ctx context.Context, orderHashes []common.Hash) (*types.RevalidateOrdersResponse, error) {
+	<-app.started
+
+	if !app.isEnabled(SubsystemOrderWatch) {
+		return nil, ErrSubsystemDisabled{Subsystem: SubsystemOrderWatch}
+	}
+	ctx = ratelimit.WithSubsystem(ctx, ratelimit.SubsystemAPI)
+	results, err := app.orderWatcher.RevalidateOrders(ctx, orderHashes)
+	if err != nil {
+		return nil, err
+	}
+	response := &types.RevalidateOrdersResponse{
+		OrdersInfos:    []*types.OrderInfo{},
+		NotRevalidated: []*types.NotRevalidatedOrderInfo{},
+	}
+	for _, order := range results.Orders {
+		fillableTakerAssetAmount := order.FillableTakerAssetAmount
+		if order.IsRemoved {
+			fillableTakerAssetAmount = big.NewInt(0)
+		}
+		response.OrdersInfos = append(response.OrdersInfos, &types.OrderInfo{
+			OrderHash:                order.Hash,
+			SignedOrder:              order.SignedOrder,
+			FillableTakerAssetAmount: fillableTakerAssetAmount,
+		})
+	}
+	// Report the orders that were not revalidated in the order they were
+	// requested.
+	for _, orderHash := range orderHashes {
+		reason, found := results.NotRevalidated[orderHash]
+		if !found {
+			continue
+		}
+		response.NotRevalidated = append(response.NotRevalidated, &types.NotRevalidatedOrderInfo{
+			OrderHash: orderHash,
+			Reason:    reason,
+		})
+		delete(results.NotRevalidated, orderHash)
+	}
+	return response, nil
+}
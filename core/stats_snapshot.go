@@ -0,0 +1,56 @@
+package core
+
+import (
+	"context"
+
+	"github.com/0xProject/0x-mesh/common/types"
+	"github.com/0xProject/0x-mesh/db"
+)
+
+// GetStatsAndSnapshot returns the stats of the node together with a new
+// snapshot of the stored orders (see GetOrders) and the sequence number of the
+// last order event reflected by the snapshot, which are taken atomically. This
+// lets clients bootstrap their state without racing against new order events:
+// they page through the snapshot with GetOrders and then apply the order
+// events with a greater sequence number, e.g. with GetOrderEventsSince or by
+// resuming the order events SSE stream from it. Events after the sequence
+// number may already be reflected by the snapshot, but since order events
+// describe the resulting state of an order, applying them again is harmless.
+func (app *App) GetStatsAndSnapshot(ctx context.Context) (*types.StatsAndSnapshot, error) {
+	select {
+	case <-app.started:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	var snapshot *db.Snapshot
+	var sequenceNumber uint64
+	err := app.orderWatcher.WithOrderEventsPaused(func(lastSequenceNumber uint64) error {
+		var err error
+		snapshot, err = app.db.Orders.GetSnapshot()
+		sequenceNumber = lastSequenceNumber
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	notRemovedFilter := app.db.Orders.IsRemovedIndex.ValueFilter([]byte{0})
+	numOrders, err := snapshot.NewQuery(notRemovedFilter).Context(ctx).Count()
+	if err != nil {
+		snapshot.Release()
+		return nil, err
+	}
+	stats, err := app.GetStats()
+	if err != nil {
+		snapshot.Release()
+		return nil, err
+	}
+	stats.NumOrders = numOrders
+	snapshotID, createdAt := app.addSnapshot(snapshot)
+	return &types.StatsAndSnapshot{
+		Stats:             stats,
+		SnapshotID:        snapshotID,
+		SnapshotTimestamp: createdAt,
+		SequenceNumber:    sequenceNumber,
+	}, nil
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Subsystem is an optional part of core.App which can be disabled via
+// Config.DisabledSubsystems.
+type Subsystem string
+
+// Subsystem values
+const (
+	// SubsystemOrderWatch watches the Ethereum chain (via the block watcher) and
+	// validates, stores and updates orders. Without it, no orders can be added
+	// and no Ethereum RPC requests are made, but the orders which are already
+	// stored can still be read.
+	SubsystemOrderWatch Subsystem = "orderwatch"
+	// SubsystemP2P connects to the p2p network and shares orders with peers via
+	// GossipSub. If SubsystemOrderWatch is disabled, the node only relays the
+	// messages of its peers.
+	SubsystemP2P Subsystem = "p2p"
+	// SubsystemOrderSync periodically syncs orders with peers and serves orders
+	// to peers.
+	SubsystemOrderSync Subsystem = "ordersync"
+)
+
+// subsystemDependencies maps each subsystem to the subsystems it depends on.
+// Subsystems are started after and stopped before their dependencies.
+var subsystemDependencies = map[Subsystem][]Subsystem{
+	SubsystemOrderWatch: {},
+	SubsystemP2P:        {},
+	SubsystemOrderSync:  {SubsystemP2P, SubsystemOrderWatch},
+}
+
+// ErrSubsystemDisabled is returned by methods of core.App which require a
+// subsystem that was disabled via Config.DisabledSubsystems.
+type ErrSubsystemDisabled struct {
+	Subsystem Subsystem
+}
+
+func (e ErrSubsystemDisabled) Error() string {
+	return fmt.Sprintf("the %s subsystem is disabled", e.Subsystem)
+}
+
+// parseDisabledSubsystems parses config.DisabledSubsystems. Subsystems which
+// depend on a disabled subsystem are disabled as well.
+func parseDisabledSubsystems(config Config) (map[Subsystem]bool, error) {
+	disabled := map[Subsystem]bool{}
+	if config.DisabledSubsystems == "" {
+		return disabled, nil
+	}
+	for _, name := range strings.Split(config.DisabledSubsystems, ",") {
+		subsystem := Subsystem(strings.TrimSpace(name))
+		if _, found := subsystemDependencies[subsystem]; !found {
+			return nil, fmt.Errorf("config.DisabledSubsystems contains unknown subsystem %q", subsystem)
+		}
+		disabled[subsystem] = true
+	}
+	for subsystem, dependencies := range subsystemDependencies {
+		if disabled[subsystem] {
+			continue
+		}
+		for _, dependency := range dependencies {
+			if disabled[dependency] {
+				log.WithFields(log.Fields{
+					"subsystem":  subsystem,
+					"dependency": dependency,
+				}).Info("disabling subsystem because one of its dependencies is disabled")
+				disabled[subsystem] = true
+				break
+			}
+		}
+	}
+	if config.WarmStartMinPeers > 0 && disabled[SubsystemOrderSync] {
+		return nil, fmt.Errorf("config.WarmStartMinPeers cannot be used when the %s subsystem is disabled", SubsystemOrderSync)
+	}
+	return disabled, nil
+}
+
+// isEnabled returns true if the given subsystem was not disabled via
+// Config.DisabledSubsystems.
+func (app *App) isEnabled(subsystem Subsystem) bool {
+	return !app.disabledSubsystems[subsystem]
+}
+
+// shutdownStage is a group of goroutines started by App.Start which are
+// stopped together. Stages are stopped one after the other so that no
+// subsystem is stopped before the subsystems which depend on it.
+type shutdownStage struct {
+	name      string
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        *sync.WaitGroup
+	setupOnce sync.Once
+}
+
+func newShutdownStage(name string) *shutdownStage {
+	ctx, cancel := context.WithCancel(context.Background())
+	stage := &shutdownStage{
+		name:   name,
+		ctx:    ctx,
+		cancel: cancel,
+		wg:     &sync.WaitGroup{},
+	}
+	// The stage is held open until setupDone is called so that goroutines
+	// cannot be added to it while it is being stopped.
+	stage.wg.Add(1)
+	return stage
+}
+
+// setupDone must be called once all goroutines of the stage were started. It
+// can safely be called more than once.
+func (s *shutdownStage) setupDone() {
+	s.setupOnce.Do(s.wg.Done)
+}
+
+// stop cancels the context of the stage and waits for all of its goroutines
+// to exit.
+func (s *shutdownStage) stop() {
+	s.cancel()
+	s.wg.Wait()
+	log.WithField("stage", s.name).Debug("shutdown stage stopped")
+}
@@ -0,0 +1,191 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/0xProject/0x-mesh/ethereum/ethrpcclient"
+	"github.com/0xProject/0x-mesh/orderfilter"
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// RemovalReasonTokenListChanged is the removal reason of the REMOVED order
+	// events emitted for orders which no longer match any order filter after
+	// tokens were removed from the dynamic token list (see
+	// Config.CustomOrderFilterTokenList).
+	RemovalReasonTokenListChanged = "TOKEN_LIST_CHANGED"
+	// registryTokenListPrefix is the prefix of token list sources which refer
+	// to an on-chain token registry.
+	registryTokenListPrefix = "registry:"
+	// maxTokenListSize is the maximum size of a token list fetched from a URL.
+	maxTokenListSize = 16 * 1024 * 1024
+	// tokenListFetchTimeout is the timeout for fetching a token list.
+	tokenListFetchTimeout = 30 * time.Second
+)
+
+// getTokenAddressesSelector is the function selector of getTokenAddresses(),
+// which on-chain token registries must implement.
+var getTokenAddressesSelector = crypto.Keccak256([]byte("getTokenAddresses()"))[:4]
+
+// errInvalidRegistryResponse is returned if the response of a token registry
+// is not an ABI encoded address[].
+var errInvalidRegistryResponse = errors.New("token registry returned an invalid response (expected address[])")
+
+// isDynamicTokenListSource returns true if the given token list source refers
+// to a list which can change and is refreshed periodically (i.e. a URL or an
+// on-chain registry) rather than a file.
+func isDynamicTokenListSource(source string) bool {
+	return strings.HasPrefix(source, "http://") ||
+		strings.HasPrefix(source, "https://") ||
+		strings.HasPrefix(source, registryTokenListPrefix)
+}
+
+// newDynamicTokenList loads the dynamic token list configured in
+// config.CustomOrderFilterTokenList. It returns nil if the token list is a file
+// (which is compiled into the custom order schema), or if it is not used
+// because no presets are configured.
+func newDynamicTokenList(ctx context.Context, config Config, ethClient ethrpcclient.Client) (*orderfilter.DynamicTokenList, error) {
+	source := config.CustomOrderFilterTokenList
+	if config.CustomOrderFilterPresets == "" || !isDynamicTokenListSource(source) {
+		return nil, nil
+	}
+	if config.CustomOrderFilterTokenListRefreshInterval < 0 {
+		return nil, fmt.Errorf("config.CustomOrderFilterTokenListRefreshInterval is invalid: must not be negative (got %s)", config.CustomOrderFilterTokenListRefreshInterval)
+	}
+	tokenList, err := orderfilter.GetDynamicTokenList(source)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := fetchTokenList(ctx, source, config.EthereumChainID, ethClient)
+	if err != nil {
+		return nil, fmt.Errorf("could not load config.CustomOrderFilterTokenList: %s", err.Error())
+	}
+	tokenList.SetTokens(tokens)
+	return tokenList, nil
+}
+
+// fetchTokenList fetches the tokens in the given dynamic token list.
+func fetchTokenList(ctx context.Context, source string, chainID int, ethClient ethrpcclient.Client) ([]common.Address, error) {
+	ctx, cancel := context.WithTimeout(ctx, tokenListFetchTimeout)
+	defer cancel()
+	if strings.HasPrefix(source, registryTokenListPrefix) {
+		registryAddress, err := zeroex.ParseAddress(strings.TrimPrefix(source, registryTokenListPrefix))
+		if err != nil {
+			return nil, err
+		}
+		return fetchRegistryTokenList(ctx, ethClient, registryAddress)
+	}
+
+	req, err := http.NewRequest("GET", source, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(res.Body, maxTokenListSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxTokenListSize {
+		return nil, fmt.Errorf("token list cannot be larger than %d bytes", maxTokenListSize)
+	}
+	return orderfilter.ParseTokenList(data, chainID)
+}
+
+// fetchRegistryTokenList calls getTokenAddresses() on the token registry at
+// the given address.
+func fetchRegistryTokenList(ctx context.Context, ethClient ethrpcclient.Client, registryAddress common.Address) ([]common.Address, error) {
+	data, err := ethClient.CallContract(ctx, ethereum.CallMsg{
+		To:   &registryAddress,
+		Data: getTokenAddressesSelector,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return decodeAddressArray(data)
+}
+
+// decodeAddressArray decodes an ABI encoded address[] return value.
+func decodeAddressArray(data []byte) ([]common.Address, error) {
+	if len(data) < 64 {
+		return nil, errInvalidRegistryResponse
+	}
+	offset := new(big.Int).SetBytes(data[:32])
+	if !offset.IsUint64() || offset.Uint64() > uint64(len(data)-32) {
+		return nil, errInvalidRegistryResponse
+	}
+	start := int(offset.Uint64()) + 32
+	length := new(big.Int).SetBytes(data[start-32 : start])
+	if !length.IsUint64() || length.Uint64() > uint64((len(data)-start)/32) {
+		return nil, errInvalidRegistryResponse
+	}
+	addresses := make([]common.Address, length.Uint64())
+	for i := range addresses {
+		word := data[start+32*i : start+32*(i+1)]
+		addresses[i] = common.BytesToAddress(word[12:])
+	}
+	return addresses, nil
+}
+
+// periodicallyRefreshTokenList refreshes the dynamic token list every
+// config.CustomOrderFilterTokenListRefreshInterval until ctx is canceled.
+func (app *App) periodicallyRefreshTokenList(ctx context.Context) {
+	ticker := time.NewTicker(app.config.CustomOrderFilterTokenListRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			app.refreshTokenList(ctx)
+		}
+	}
+}
+
+// refreshTokenList fetches the dynamic token list and updates the tokens in
+// it. If tokens were removed, all stored orders which no longer match any of
+// the order filters are removed and a REMOVED order event with
+// RemovalReasonTokenListChanged is emitted for each of them. The node keeps
+// using the last known list if it can't be fetched.
+func (app *App) refreshTokenList(ctx context.Context) {
+	tokens, err := fetchTokenList(ctx, app.config.CustomOrderFilterTokenList, app.chainID, app.ethRPCClient)
+	if err != nil {
+		log.WithError(err).Warn("could not refresh token list")
+		return
+	}
+	removedTokens := app.tokenList.SetTokens(tokens)
+	logFields := log.Fields{
+		"numTokens":        len(tokens),
+		"numTokensRemoved": len(removedTokens),
+	}
+	if len(removedTokens) > 0 && app.isEnabled(SubsystemOrderWatch) {
+		matchOrder := func(order *zeroex.SignedOrder) (bool, error) {
+			return len(app.matchingOrderFilters(order)) > 0, nil
+		}
+		removedOrderHashes, err := app.orderWatcher.RemoveOrdersNotMatching(matchOrder, RemovalReasonTokenListChanged)
+		if err != nil {
+			log.WithError(err).Error("could not remove orders after refreshing token list")
+			return
+		}
+		logFields["numOrdersRemoved"] = len(removedOrderHashes)
+	}
+	log.WithFields(logFields).Debug("refreshed token list")
+}
@@ -0,0 +1,207 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/0xProject/0x-mesh/encoding"
+	"github.com/0xProject/0x-mesh/meshdb"
+	"github.com/0xProject/0x-mesh/orderfilter"
+	"github.com/0xProject/0x-mesh/p2p"
+	"github.com/0xProject/0x-mesh/zeroex"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	log "github.com/sirupsen/logrus"
+)
+
+// topicMigration keeps track of the legacy topics of the order filters which
+// the node subscribes and publishes to during a topic migration window (see
+// Config.TopicMigrationWindow).
+type topicMigration struct {
+	mu sync.RWMutex
+	// currentTopics maps the legacy topics to the current topics of the same
+	// order filters. It is empty once the window is over.
+	currentTopics map[string]string
+	endTime       time.Time
+}
+
+// newTopicMigration returns the topic migration for the given order filters.
+// The migration window starts the first time the node is started with a
+// window, which is persisted so that restarting the node doesn't extend it.
+// The returned migration has no legacy topics if the window is over or
+// config.TopicMigrationWindow is 0.
+func newTopicMigration(config Config, meshDB *meshdb.MeshDB, filters []*orderfilter.Filter) (*topicMigration, error) {
+	migration := &topicMigration{
+		currentTopics: map[string]string{},
+	}
+	if config.TopicMigrationWindow <= 0 {
+		return migration, nil
+	}
+	var startTime time.Time
+	if err := meshDB.UpdateMetadata(func(metadata meshdb.Metadata) meshdb.Metadata {
+		if metadata.TopicMigrationStartTime.IsZero() {
+			metadata.TopicMigrationStartTime = time.Now()
+		}
+		startTime = metadata.TopicMigrationStartTime
+		return metadata
+	}); err != nil {
+		return nil, err
+	}
+	migration.endTime = startTime.Add(config.TopicMigrationWindow)
+	if !time.Now().Before(migration.endTime) {
+		return migration, nil
+	}
+	for _, filter := range filters {
+		for _, legacyTopic := range filter.LegacyTopics() {
+			migration.currentTopics[legacyTopic] = filter.Topic()
+		}
+	}
+	return migration, nil
+}
+
+// legacyTopics returns the legacy topics the node subscribes and publishes to.
+func (m *topicMigration) legacyTopics() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	topics := make([]string, 0, len(m.currentTopics))
+	for legacyTopic := range m.currentTopics {
+		topics = append(topics, legacyTopic)
+	}
+	return topics
+}
+
+// currentTopicFor returns the current topic which corresponds to the given
+// unsharded legacy topic. ok is false if the topic is not a legacy topic or
+// the window is over.
+func (m *topicMigration) currentTopicFor(legacyTopic string) (currentTopic string, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	currentTopic, ok = m.currentTopics[legacyTopic]
+	return currentTopic, ok
+}
+
+// end removes all legacy topics.
+func (m *topicMigration) end() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.currentTopics = map[string]string{}
+}
+
+// isActive returns true if the node still subscribes and publishes to legacy
+// topics.
+func (m *topicMigration) isActive() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.currentTopics) > 0
+}
+
+// currentTopicForMessage returns the current topic which corresponds to the
+// (possibly sharded) legacy topic a message was received on. ok is false if
+// the message was not received on a legacy topic.
+func (app *App) currentTopicForMessage(topic string) (currentTopic string, ok bool) {
+	return app.topicMigration.currentTopicFor(p2p.UnshardTopic(topic, app.config.TopicShards))
+}
+
+// translateLegacyMessage translates an order message which was received on a
+// legacy topic into an order message for the given current topic. Messages
+// which are not order messages (e.g. order update hints) are returned
+// unchanged.
+func translateLegacyMessage(currentTopic string, data []byte) ([]byte, error) {
+	if messageType, err := encoding.RawMessageType(data); err == nil && messageType != encoding.MessageTypeOrder {
+		return data, nil
+	}
+	return encoding.TranslateLegacyOrderMessage(data, currentTopic)
+}
+
+// validateLegacyPubSubMessage translates a message which was received on a
+// legacy topic and validates it against the order filter of the given current
+// topic. Messages which cannot be translated are rejected.
+func (app *App) validateLegacyPubSubMessage(ctx context.Context, sender peer.ID, msg *pubsub.Message, currentTopic string) bool {
+	translated, err := translateLegacyMessage(currentTopic, msg.Data)
+	if err != nil {
+		return false
+	}
+	// The translated message is passed on as a copy since msg is shared with
+	// the other validators.
+	translatedMsg := &pubsub.Message{
+		Message: &pb.Message{
+			Data:     translated,
+			TopicIDs: []string{currentTopic},
+		},
+		ReceivedFrom: msg.ReceivedFrom,
+	}
+	return app.orderFilterForTopic(currentTopic).ValidatePubSubMessage(ctx, sender, translatedMsg)
+}
+
+// shareOrderOnLegacyTopics shares the given order on the legacy topics of the
+// order filters it passes during the topic migration window. It does nothing
+// if the window is over or the legacy topic policy is "translate", in which
+// case the legacy topics are publish topics already.
+func (app *App) shareOrderOnLegacyTopics(order *zeroex.SignedOrder) error {
+	if !app.isEnabled(SubsystemP2P) || !app.topicMigration.isActive() || orderfilter.GetLegacyTopicPolicy() == orderfilter.LegacyTopicsTranslated {
+		return nil
+	}
+	orderHash, err := order.ComputeOrderHash()
+	if err != nil {
+		return err
+	}
+	shard := p2p.ShardForKey(orderHash.Bytes(), app.config.TopicShards)
+	matchingTopics := map[string]struct{}{}
+	for _, namedFilter := range app.matchingOrderFilters(order) {
+		matchingTopics[namedFilter.filter.Topic()] = struct{}{}
+	}
+	// Note: If there is an error, we still try to share the order on the
+	// remaining topics and return the first error.
+	var firstErr error
+	for _, legacyTopic := range app.topicMigration.legacyTopics() {
+		currentTopic, ok := app.topicMigration.currentTopicFor(legacyTopic)
+		if !ok {
+			continue
+		}
+		if _, found := matchingTopics[currentTopic]; !found {
+			continue
+		}
+		encoded, err := encoding.OrderToRawMessage(legacyTopic, order)
+		if err == nil {
+			err = app.node.SendToTopicShard(legacyTopic, encoded, shard)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// bridgeOrder shares an order received through GossipSub with the peers on the
+// other side of the topic migration: orders received on a legacy topic are
+// shared on the current topics and vice versa. It does nothing once the window
+// is over.
+func (app *App) bridgeOrder(order *zeroex.SignedOrder, fromLegacyTopic bool) error {
+	if !app.topicMigration.isActive() {
+		return nil
+	}
+	if fromLegacyTopic {
+		return app.shareOrder(order)
+	}
+	return app.shareOrderOnLegacyTopics(order)
+}
+
+// endTopicMigrationAfterWindow stops subscribing and publishing to the legacy
+// topics once the topic migration window is over.
+func (app *App) endTopicMigrationAfterWindow(ctx context.Context) error {
+	timer := time.NewTimer(time.Until(app.topicMigration.endTime))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-timer.C:
+	}
+	app.topicMigration.end()
+	if err := app.node.SetAdditionalSubscribeTopics(app.additionalOrderFilterTopics()); err != nil {
+		return err
+	}
+	log.Info("topic migration window is over; stopped subscribing to legacy topics")
+	return nil
+}
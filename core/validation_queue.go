@@ -0,0 +1,39 @@
+package core
+
+import (
+	"github.com/0xProject/0x-mesh/common/types"
+)
+
+// GetValidationQueue returns a summary of the batches of orders which are
+// currently being validated. It can be used to tell whether new orders are
+// slow to be added because of Ethereum RPC provider limits, a backlog of
+// blocks or Mesh-specific filtering.
+func (app *App) GetValidationQueue() (*types.ValidationQueue, error) {
+	<-app.started
+
+	queue := app.orderWatcher.ValidationQueue()
+	response := &types.ValidationQueue{
+		NumBatches:            queue.NumBatches,
+		NumOrders:             queue.NumOrders,
+		NumOrdersByWaitReason: make(map[string]int, len(queue.NumOrdersByWaitReason)),
+		OldestBatchAgeMs:      queue.OldestBatchAge.Milliseconds(),
+		Batches:               make([]*types.ValidationBatch, len(queue.Batches)),
+	}
+	for waitReason, numOrders := range queue.NumOrdersByWaitReason {
+		response.NumOrdersByWaitReason[string(waitReason)] = numOrders
+	}
+	for i, batch := range queue.Batches {
+		etaMs := int64(-1)
+		if batch.ETA >= 0 {
+			etaMs = batch.ETA.Milliseconds()
+		}
+		response.Batches[i] = &types.ValidationBatch{
+			Source:     string(batch.Source),
+			NumOrders:  batch.NumOrders,
+			WaitReason: string(batch.WaitReason),
+			AgeMs:      batch.Age.Milliseconds(),
+			EtaMs:      etaMs,
+		}
+	}
+	return response, nil
+}
@@ -0,0 +1,106 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// warmStartCheckInterval is how often to check whether the warm start
+// thresholds have been reached.
+const warmStartCheckInterval = 1 * time.Second
+
+func validateWarmStartConfig(config Config) error {
+	if config.WarmStartMinPeers < 0 || config.WarmStartMinPeers > ordersyncMinPeers {
+		return fmt.Errorf("config.WarmStartMinPeers must be between 0 and %d", ordersyncMinPeers)
+	}
+	if config.WarmStartMinOrders < 0 {
+		return errors.New("config.WarmStartMinOrders cannot be negative")
+	}
+	if config.WarmStartTimeout < 0 {
+		return errors.New("config.WarmStartTimeout cannot be negative")
+	}
+	return nil
+}
+
+// Ready returns a channel which is closed once the App is ready to serve
+// requests. If warm start mode is enabled (see Config.WarmStartMinPeers and
+// Config.WarmStartMinOrders), this happens once enough orders have been synced
+// or Config.WarmStartTimeout has elapsed. Otherwise it happens as soon as the
+// App is started.
+func (app *App) Ready() <-chan struct{} {
+	return app.ready
+}
+
+// IsReady returns true if the App is ready to serve requests (see Ready).
+func (app *App) IsReady() bool {
+	select {
+	case <-app.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// warmUp blocks until the warm start thresholds have been reached, the warm
+// start timeout has elapsed or ctx is canceled and then signals that the App is
+// ready.
+func (app *App) warmUp(ctx context.Context) {
+	defer close(app.ready)
+	if app.config.WarmStartMinPeers == 0 && app.config.WarmStartMinOrders == 0 {
+		return
+	}
+	log.WithFields(log.Fields{
+		"minPeers":  app.config.WarmStartMinPeers,
+		"minOrders": app.config.WarmStartMinOrders,
+		"timeout":   app.config.WarmStartTimeout,
+	}).Info("waiting for initial ordersync before reporting ready")
+
+	var timeout <-chan time.Time
+	if app.config.WarmStartTimeout != 0 {
+		timer := time.NewTimer(app.config.WarmStartTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	ticker := time.NewTicker(warmStartCheckInterval)
+	defer ticker.Stop()
+	for {
+		isWarm, err := app.isWarm()
+		if err != nil {
+			log.WithError(err).Error("could not check whether warm start is done")
+		} else if isWarm {
+			log.Info("warm start done; reporting ready")
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-timeout:
+			log.Warn("warm start timed out before reaching the configured thresholds; reporting ready anyway")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// isWarm returns true if either of the configured warm start thresholds has
+// been reached.
+func (app *App) isWarm() (bool, error) {
+	if app.config.WarmStartMinPeers > 0 && app.orderSyncHistory.NumSyncedProviders() >= app.config.WarmStartMinPeers {
+		return true, nil
+	}
+	if app.config.WarmStartMinOrders > 0 {
+		notRemovedFilter := app.db.Orders.IsRemovedIndex.ValueFilter([]byte{0})
+		numOrders, err := app.db.Orders.NewQuery(notRemovedFilter).Count()
+		if err != nil {
+			return false, err
+		}
+		if numOrders >= app.config.WarmStartMinOrders {
+			return true, nil
+		}
+	}
+	return false, nil
+}
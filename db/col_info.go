@@ -11,7 +11,9 @@ type colInfo struct {
 	db        *DB
 	name      string
 	modelType reflect.Type
-	indexes   []*Index
+	// hasHotFields is true if the model type implements HotFieldsModel.
+	hasHotFields bool
+	indexes      []*Index
 	// indexMut protects the indexes slice.
 	indexMut sync.RWMutex
 	// writeMut is used by transactions to prevent other goroutines from writing
@@ -29,11 +31,12 @@ func (info *colInfo) copy() *colInfo {
 	copy(indexes, info.indexes)
 	info.indexMut.RUnlock()
 	return &colInfo{
-		db:        info.db,
-		name:      info.name,
-		modelType: info.modelType,
-		indexes:   indexes,
-		writeMut:  info.writeMut,
+		db:           info.db,
+		name:         info.name,
+		modelType:    info.modelType,
+		hasHotFields: info.hasHotFields,
+		indexes:      indexes,
+		writeMut:     info.writeMut,
 	}
 }
 
@@ -18,14 +18,17 @@ type Collection struct {
 // model type. You should create exactly one collection for each model type. The
 // collection should typically be created once at the start of your application
 // and re-used. NewCollection returns an error if a collection has already been
-// created with the given name for this db.
+// created with the given name for this db. If typ implements HotFieldsModel,
+// the hot fields of each model are stored separately.
 func (db *DB) NewCollection(name string, typ Model) (*Collection, error) {
+	_, hasHotFields := typ.(HotFieldsModel)
 	col := &Collection{
 		info: &colInfo{
-			db:        db,
-			name:      name,
-			modelType: reflect.TypeOf(typ),
-			writeMut:  &sync.Mutex{},
+			db:           db,
+			name:         name,
+			modelType:    reflect.TypeOf(typ),
+			hasHotFields: hasHotFields,
+			writeMut:     &sync.Mutex{},
 		},
 		ldb: db.ldb,
 	}
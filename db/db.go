@@ -30,10 +30,17 @@ type Model interface {
 
 // DB is the top-level Database.
 type DB struct {
-	ldb             *leveldb.DB
+	ldb *leveldb.DB
+	// path is the directory used for permanent storage. It is empty for
+	// in-memory databases.
+	path            string
 	globalWriteLock sync.RWMutex
 	collections     []*Collection
 	colLock         sync.Mutex
+	// slowQueryThreshold is the minimum duration of queries which are logged
+	// as slow, in nanoseconds. If 0, no queries are logged. It must be
+	// accessed atomically.
+	slowQueryThreshold int64
 }
 
 // Close closes the database. It is not safe to call Close if there are any
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// DiskUsage returns the total size in bytes of all files used by the database.
+// It always returns 0 for in-memory databases.
+func (db *DB) DiskUsage() (int64, error) {
+	if db.path == "" {
+		return 0, nil
+	}
+	var total int64
+	err := filepath.Walk(db.path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Files can be removed by a concurrent compaction while we are
+			// walking the directory.
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			total += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// Compact compacts the entire database. Disk space used by deleted models is
+// not reclaimed until the files that contain them are compacted, so Compact can
+// be called after deleting a large number of models to free up disk space
+// right away.
+func (db *DB) Compact() error {
+	return db.ldb.CompactRange(util.Range{})
+}
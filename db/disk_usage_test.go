@@ -0,0 +1,30 @@
+// +build !js
+
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskUsage(t *testing.T) {
+	t.Parallel()
+	db := newTestDB(t)
+	defer db.Close()
+	col, err := db.NewCollection("people", &testModel{})
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, col.Insert(&testModel{
+			Name: fmt.Sprintf("person_%d", i),
+			Age:  i,
+		}))
+	}
+	require.NoError(t, db.Compact())
+	usage, err := db.DiskUsage()
+	require.NoError(t, err)
+	assert.True(t, usage > 0, "expected disk usage to be greater than 0")
+}
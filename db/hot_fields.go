@@ -0,0 +1,175 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// hotFieldsMigrationBatchSize is the number of models rewritten in a single
+// transaction by SplitHotFields.
+const hotFieldsMigrationBatchSize = 1000
+
+// HotFieldsModel is implemented by models which have a few small fields that
+// are updated much more often than the rest of the model (e.g. the fillable
+// amount of an order, which changes whenever the order is revalidated, as
+// opposed to the signed order itself, which never changes). The hot fields are
+// stored separately from the cold fields, so that updating them doesn't
+// rewrite the whole model.
+//
+// ColdFields and HotFields must return values whose JSON encodings together
+// contain every field of the model, using the same keys as the JSON encoding
+// of the model itself. When a model is read, both are decoded into the model.
+type HotFieldsModel interface {
+	Model
+	// ColdFields returns the fields which rarely change.
+	ColdFields() interface{}
+	// HotFields returns the fields which change frequently.
+	HotFields() interface{}
+}
+
+// hotFieldsMigratedValue is the value stored under hotFieldsMigratedKey. Only
+// the presence of the key is meaningful.
+var hotFieldsMigratedValue = []byte{1}
+
+func (info *colInfo) hotKeyForPrimaryKey(pk []byte) []byte {
+	return append([]byte("hot:"), bytes.TrimPrefix(pk, []byte("model:"))...)
+}
+
+// hotFieldsMigratedKey returns the key used to record that SplitHotFields
+// completed for the collection.
+func (info *colInfo) hotFieldsMigratedKey() []byte {
+	return []byte(fmt.Sprintf("hotFieldsMigrated:%s", escape([]byte(info.name))))
+}
+
+// encodeModel returns the data stored under the primary key of the model and,
+// for collections of HotFieldsModels, the data stored under its hot key.
+func encodeModel(info *colInfo, model Model) (data []byte, hotData []byte, err error) {
+	if !info.hasHotFields {
+		data, err := json.Marshal(model)
+		return data, nil, err
+	}
+	hotFieldsModel := model.(HotFieldsModel)
+	data, err = json.Marshal(hotFieldsModel.ColdFields())
+	if err != nil {
+		return nil, nil, err
+	}
+	hotData, err = json.Marshal(hotFieldsModel.HotFields())
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, hotData, nil
+}
+
+// decodeModel decodes the data stored under the given primary key into
+// modelRef. For collections of HotFieldsModels, the hot fields are read and
+// decoded as well. Models which were stored before their hot fields were split
+// (see SplitHotFields) don't have any hot fields stored separately and are
+// decoded as is.
+func decodeModel(info *colInfo, reader dbReader, pk []byte, data []byte, modelRef interface{}) error {
+	if err := json.Unmarshal(data, modelRef); err != nil {
+		return err
+	}
+	if !info.hasHotFields {
+		return nil
+	}
+	hotData, err := reader.Get(info.hotKeyForPrimaryKey(pk), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	return json.Unmarshal(hotData, modelRef)
+}
+
+// SplitHotFields rewrites every model in a collection of HotFieldsModels
+// which was stored as a whole (i.e. before the model implemented
+// HotFieldsModel) so that its hot fields are stored separately. Such models
+// can still be read without it and are rewritten the next time they are
+// updated, but SplitHotFields makes sure that the cold fields are not
+// rewritten on that first update. It only does any work the first time it is
+// called for a collection and returns the number of models it rewrote.
+func (c *Collection) SplitHotFields() (int, error) {
+	if !c.info.hasHotFields {
+		return 0, fmt.Errorf("models in the %q collection don't implement HotFieldsModel", c.info.name)
+	}
+	if migrated, err := c.ldb.Has(c.info.hotFieldsMigratedKey(), nil); err != nil {
+		return 0, err
+	} else if migrated {
+		return 0, nil
+	}
+
+	total := 0
+	var start []byte
+	for {
+		rewritten, next, err := c.splitHotFieldsBatch(start)
+		if err != nil {
+			return total, err
+		}
+		total += rewritten
+		if next == nil {
+			break
+		}
+		start = next
+	}
+	return total, c.ldb.Put(c.info.hotFieldsMigratedKey(), hotFieldsMigratedValue, nil)
+}
+
+// splitHotFieldsBatch rewrites up to hotFieldsMigrationBatchSize models,
+// starting at the given primary key (or the first one if start is nil). It
+// returns the primary key at which the next batch starts or nil if there are
+// no more models. All reads go through the transaction, which holds the write
+// lock of the collection, so models can't be updated between being read and
+// being rewritten.
+func (c *Collection) splitHotFieldsBatch(start []byte) (rewritten int, next []byte, err error) {
+	txn := c.OpenTransaction()
+	defer func() {
+		_ = txn.Discard()
+	}()
+	slice := util.BytesPrefix([]byte(fmt.Sprintf("%s:", c.info.prefix())))
+	if start != nil {
+		slice.Start = start
+	}
+	iter := txn.readWriter.NewIterator(slice, nil)
+	defer iter.Release()
+	for i := 0; iter.Next(); i++ {
+		if i == hotFieldsMigrationBatchSize {
+			next = append([]byte{}, iter.Key()...)
+			break
+		}
+		pk := iter.Key()
+		if hasHotFields, err := txn.readWriter.Has(c.info.hotKeyForPrimaryKey(pk), nil); err != nil {
+			return 0, nil, err
+		} else if hasHotFields {
+			continue
+		}
+		modelRef := reflect.New(c.info.modelType).Interface()
+		if err := json.Unmarshal(iter.Value(), modelRef); err != nil {
+			return 0, nil, err
+		}
+		model := reflect.ValueOf(modelRef).Elem().Interface().(Model)
+		data, hotData, err := encodeModel(c.info, model)
+		if err != nil {
+			return 0, nil, err
+		}
+		if err := txn.readWriter.Put(append([]byte{}, pk...), data, nil); err != nil {
+			return 0, nil, err
+		}
+		if err := txn.readWriter.Put(c.info.hotKeyForPrimaryKey(pk), hotData, nil); err != nil {
+			return 0, nil, err
+		}
+		rewritten++
+	}
+	if err := iter.Error(); err != nil {
+		return 0, nil, err
+	}
+	if err := txn.Commit(); err != nil {
+		return 0, nil, err
+	}
+	return rewritten, next, nil
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// largeNicknames makes the cold fields of the models used in the benchmarks
+// about as large as a signed order.
+var largeNicknames = func() []string {
+	nicknames := make([]string, 32)
+	for i := range nicknames {
+		nicknames[i] = fmt.Sprintf("a_fairly_long_nickname_number_%d", i)
+	}
+	return nicknames
+}()
+
+// BenchmarkUpdateAgeWhole and BenchmarkUpdateAgeSplit compare updates of a
+// model whose hot fields are stored separately to updates of the same model
+// stored as a whole. The bytes written per update are reported as
+// "written-B/op".
+func BenchmarkUpdateAgeWhole(b *testing.B) {
+	db := newTestDB(b)
+	defer db.Close()
+	col, err := db.NewCollection("people", &testModel{})
+	require.NoError(b, err)
+	col.AddIndex("age", func(m Model) []byte {
+		return []byte(fmt.Sprint(m.(*testModel).Age))
+	})
+	model := &testModel{Name: "person_0", Nicknames: largeNicknames}
+	require.NoError(b, col.Insert(model))
+	benchmarkUpdate(b, col, func(i int) Model {
+		return &testModel{Name: model.Name, Age: i + 1, Nicknames: model.Nicknames}
+	})
+}
+
+func BenchmarkUpdateAgeSplit(b *testing.B) {
+	db := newTestDB(b)
+	defer db.Close()
+	col, err := db.NewCollection("people", &testHotFieldsModel{})
+	require.NoError(b, err)
+	col.AddIndex("age", func(m Model) []byte {
+		return []byte(fmt.Sprint(m.(*testHotFieldsModel).Age))
+	})
+	model := &testHotFieldsModel{Name: "person_0", Nicknames: largeNicknames}
+	require.NoError(b, col.Insert(model))
+	benchmarkUpdate(b, col, func(i int) Model {
+		return &testHotFieldsModel{Name: model.Name, Age: i + 1, Nicknames: model.Nicknames}
+	})
+}
+
+func benchmarkUpdate(b *testing.B, col *Collection, updatedModel func(i int) Model) {
+	b.Helper()
+	bytesWritten := 0
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		updated := updatedModel(i)
+		b.StartTimer()
+		txn := col.OpenTransaction()
+		err := txn.Update(updated)
+		b.StopTimer()
+		require.NoError(b, err)
+		bytesWritten += len(txn.readWriter.batch.Dump())
+		b.StartTimer()
+		err = txn.Commit()
+		b.StopTimer()
+		require.NoError(b, err)
+		b.StartTimer()
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(bytesWritten)/float64(b.N), "written-B/op")
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testHotFieldsModel is like testModel but stores Age, which changes every
+// year, separately.
+type testHotFieldsModel struct {
+	Name      string
+	Age       int
+	Nicknames []string
+}
+
+func (m *testHotFieldsModel) ID() []byte {
+	return []byte(m.Name)
+}
+
+func (m *testHotFieldsModel) ColdFields() interface{} {
+	return struct {
+		Name      string
+		Nicknames []string
+	}{m.Name, m.Nicknames}
+}
+
+func (m *testHotFieldsModel) HotFields() interface{} {
+	return struct{ Age int }{m.Age}
+}
+
+func TestHotFields(t *testing.T) {
+	t.Parallel()
+	db := newTestDB(t)
+	defer db.Close()
+	col, err := db.NewCollection("people", &testHotFieldsModel{})
+	require.NoError(t, err)
+	ageIndex := col.AddIndex("age", func(m Model) []byte {
+		return []byte{byte(m.(*testHotFieldsModel).Age)}
+	})
+
+	model := &testHotFieldsModel{Name: "foo", Age: 42, Nicknames: []string{"bar"}}
+	require.NoError(t, col.Insert(model))
+	pk := col.info.primaryKeyForModel(model)
+	coldData, err := db.ldb.Get(pk, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `{"Name":"foo","Nicknames":["bar"]}`, string(coldData))
+	hotData, err := db.ldb.Get(col.info.hotKeyForPrimaryKey(pk), nil)
+	require.NoError(t, err)
+	assert.Equal(t, `{"Age":42}`, string(hotData))
+
+	var found testHotFieldsModel
+	require.NoError(t, col.FindByID(model.ID(), &found))
+	assert.Equal(t, model, &found)
+
+	// Updating only the hot fields doesn't rewrite the cold fields.
+	model.Age = 43
+	txn := col.OpenTransaction()
+	require.NoError(t, txn.Update(model))
+	// One put for the hot fields, plus one delete and one put for the age
+	// index.
+	assert.Equal(t, 3, txn.readWriter.batch.Len())
+	require.NoError(t, txn.Commit())
+
+	var models []*testHotFieldsModel
+	require.NoError(t, col.NewQuery(ageIndex.ValueFilter([]byte{43})).Run(&models))
+	assert.Equal(t, []*testHotFieldsModel{model}, models)
+	models = []*testHotFieldsModel{}
+	require.NoError(t, col.FindAll(&models))
+	assert.Equal(t, []*testHotFieldsModel{model}, models)
+	require.NoError(t, db.CheckIntegrity())
+
+	require.NoError(t, col.Delete(model.ID()))
+	exists, err := db.ldb.Has(col.info.hotKeyForPrimaryKey(pk), nil)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestSplitHotFields(t *testing.T) {
+	t.Parallel()
+	path := "/tmp/leveldb_testing/" + uuid.New().String()
+
+	// Store models as a whole, like a collection of a model which doesn't
+	// implement HotFieldsModel would.
+	db, err := Open(path)
+	require.NoError(t, err)
+	col, err := db.NewCollection("people", &testModel{})
+	require.NoError(t, err)
+	expected := []*testHotFieldsModel{}
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(t, col.Insert(&testModel{Name: name, Age: 42}))
+		expected = append(expected, &testHotFieldsModel{Name: name, Age: 42})
+	}
+	db.Close()
+
+	db, err = Open(path)
+	require.NoError(t, err)
+	defer db.Close()
+	col, err = db.NewCollection("people", &testHotFieldsModel{})
+	require.NoError(t, err)
+
+	// Models stored as a whole can be read before they are split.
+	var models []*testHotFieldsModel
+	require.NoError(t, col.FindAll(&models))
+	assert.Equal(t, expected, models)
+
+	migrated, err := col.SplitHotFields()
+	require.NoError(t, err)
+	assert.Equal(t, 3, migrated)
+	for _, model := range expected {
+		pk := col.info.primaryKeyForModel(model)
+		hotData, err := db.ldb.Get(col.info.hotKeyForPrimaryKey(pk), nil)
+		require.NoError(t, err)
+		assert.Equal(t, `{"Age":42}`, string(hotData))
+	}
+	models = []*testHotFieldsModel{}
+	require.NoError(t, col.FindAll(&models))
+	assert.Equal(t, expected, models)
+
+	// The migration only runs once.
+	migrated, err = col.SplitHotFields()
+	require.NoError(t, err)
+	assert.Equal(t, 0, migrated)
+}
@@ -1,7 +1,6 @@
 package db
 
 import (
-	"encoding/json"
 	"fmt"
 	"reflect"
 
@@ -37,7 +36,7 @@ func (db *DB) checkCollectionIntegrity(col *Collection) error {
 		// Check that the model data can be unmarshaled into the expected type.
 		data := iter.Value()
 		modelVal := reflect.New(col.info.modelType)
-		if err := json.Unmarshal(data, modelVal.Interface()); err != nil {
+		if err := decodeModel(col.info, snapshot.snapshot, iter.Key(), data, modelVal.Interface()); err != nil {
 			return fmt.Errorf("integritiy check failed for collection %s: could not unmarshal model data for primary key %s: %s", col.Name(), iter.Key(), err.Error())
 		}
 		model := modelVal.Elem().Interface().(Model)
@@ -88,7 +87,7 @@ func (db *DB) checkIndexIntegrity(snapshot *Snapshot, col *Collection, index *In
 			}
 		}
 		modelVal := reflect.New(col.info.modelType)
-		if err := json.Unmarshal(data, modelVal.Interface()); err != nil {
+		if err := decodeModel(col.info, snapshot.snapshot, pk, data, modelVal.Interface()); err != nil {
 			return fmt.Errorf("integritiy check failed for index %s.%s: could not unmarshal model data: %s", col.Name(), index.Name(), err.Error())
 		}
 	}
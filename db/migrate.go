@@ -0,0 +1,283 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	// defaultMigrationBatchSize is the number of key/value pairs written to the
+	// target in a single batch if MigrationOptions.BatchSize is not set.
+	defaultMigrationBatchSize = 1000
+)
+
+// ErrMigrationTargetNotFound is returned by MigrationTarget.Get if the target
+// does not contain the given key.
+var ErrMigrationTargetNotFound = errors.New("key not found in migration target")
+
+// KeyValue is a single raw key/value pair as stored in the database.
+type KeyValue struct {
+	Key   []byte
+	Value []byte
+}
+
+// MigrationTarget is the destination for a database migration. Each storage
+// backend that Mesh can migrate to must provide an implementation (see
+// LevelDBMigrationTarget and SQLiteMigrationTarget). Keys are always streamed
+// to the target in ascending order.
+type MigrationTarget interface {
+	// WriteBatch atomically writes the given key/value pairs to the target and
+	// records the last key in the batch as the new checkpoint.
+	WriteBatch(kvs []*KeyValue) error
+	// Get returns the value for the given key or ErrMigrationTargetNotFound.
+	Get(key []byte) ([]byte, error)
+	// ForEachKey calls fn for every migrated key in the target. It stops and
+	// returns the error if fn returns one. The checkpoint is not a migrated
+	// key.
+	ForEachKey(fn func(key []byte) error) error
+	// Checkpoint returns the last key that was successfully written or nil if
+	// the migration has not been started yet.
+	Checkpoint() ([]byte, error)
+	// ClearCheckpoint removes the checkpoint once a migration is complete.
+	ClearCheckpoint() error
+}
+
+// MigrationProgress is passed to MigrationOptions.OnProgress after each batch
+// is written to the target.
+type MigrationProgress struct {
+	// KeysMigrated is the number of keys written during this run. It does not
+	// include keys written before a resumed migration was interrupted.
+	KeysMigrated int
+	// BytesMigrated is the total size of the keys and values written during
+	// this run.
+	BytesMigrated int
+	// LastKey is the last key that was written to the target.
+	LastKey []byte
+}
+
+// MigrationOptions are options for DB.MigrateTo.
+type MigrationOptions struct {
+	// BatchSize is the maximum number of key/value pairs written in a single
+	// batch. Defaults to 1000.
+	BatchSize int
+	// OnProgress, if set, is called after each batch is written.
+	OnProgress func(progress MigrationProgress)
+}
+
+// MigrationVerificationError is returned by VerifyMigration if the target does
+// not contain the same data as the source.
+type MigrationVerificationError struct {
+	Key    []byte
+	Reason string
+}
+
+func (e MigrationVerificationError) Error() string {
+	return fmt.Sprintf("migration verification failed for key %s: %s", e.Key, e.Reason)
+}
+
+// MigrateTo streams every key/value pair in the database into the given
+// target. The data is read from a consistent snapshot. If the target has a
+// checkpoint from a previous interrupted run, MigrateTo resumes from the key
+// immediately after it. The checkpoint is not cleared on completion so that
+// the caller can run VerifyMigration first.
+func (db *DB) MigrateTo(target MigrationTarget, opts MigrationOptions) (MigrationProgress, error) {
+	progress := MigrationProgress{}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultMigrationBatchSize
+	}
+
+	checkpoint, err := target.Checkpoint()
+	if err != nil {
+		return progress, err
+	}
+	slice := &util.Range{}
+	if checkpoint != nil {
+		// Appending a zero byte yields the smallest key which is strictly greater
+		// than the checkpoint.
+		slice.Start = append(append([]byte{}, checkpoint...), 0)
+	}
+
+	snapshot, err := db.ldb.GetSnapshot()
+	if err != nil {
+		return progress, err
+	}
+	defer snapshot.Release()
+	iter := snapshot.NewIterator(slice, nil)
+	defer iter.Release()
+
+	batch := make([]*KeyValue, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := target.WriteBatch(batch); err != nil {
+			return err
+		}
+		progress.KeysMigrated += len(batch)
+		progress.LastKey = batch[len(batch)-1].Key
+		if opts.OnProgress != nil {
+			opts.OnProgress(progress)
+		}
+		batch = make([]*KeyValue, 0, batchSize)
+		return nil
+	}
+	for iter.Next() {
+		// The iterator re-uses the underlying byte slices so we need to copy them.
+		kv := &KeyValue{
+			Key:   append([]byte{}, iter.Key()...),
+			Value: append([]byte{}, iter.Value()...),
+		}
+		batch = append(batch, kv)
+		progress.BytesMigrated += len(kv.Key) + len(kv.Value)
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return progress, err
+			}
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return progress, err
+	}
+	if err := flush(); err != nil {
+		return progress, err
+	}
+	return progress, nil
+}
+
+// VerifyMigration checks that every key/value pair in the database is present
+// in the given target with an identical value and that the target doesn't
+// contain any keys which are not in the database. It returns a
+// MigrationVerificationError describing the first mismatch it finds.
+func (db *DB) VerifyMigration(target MigrationTarget) error {
+	snapshot, err := db.ldb.GetSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snapshot.Release()
+	if err := verifySourceKeys(snapshot, target); err != nil {
+		return err
+	}
+	return target.ForEachKey(func(key []byte) error {
+		exists, err := snapshot.Has(key, nil)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return MigrationVerificationError{
+				Key:    append([]byte{}, key...),
+				Reason: "key does not exist in source",
+			}
+		}
+		return nil
+	})
+}
+
+// verifySourceKeys checks that every key/value pair in the given snapshot is
+// present in the given target with an identical value.
+func verifySourceKeys(snapshot *leveldb.Snapshot, target MigrationTarget) error {
+	iter := snapshot.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		actual, err := target.Get(iter.Key())
+		if err != nil {
+			if err == ErrMigrationTargetNotFound {
+				return MigrationVerificationError{
+					Key:    append([]byte{}, iter.Key()...),
+					Reason: "key does not exist in target",
+				}
+			}
+			return err
+		}
+		if !bytes.Equal(actual, iter.Value()) {
+			return MigrationVerificationError{
+				Key:    append([]byte{}, iter.Key()...),
+				Reason: "value in target does not match source",
+			}
+		}
+	}
+	return iter.Error()
+}
+
+// migrationCheckpointKey is the key used by LevelDBMigrationTarget to store
+// the checkpoint. It does not share a prefix with any keys used by the db
+// package.
+var migrationCheckpointKey = []byte("migration:checkpoint")
+
+// LevelDBMigrationTarget is a MigrationTarget backed by another DB. It can be
+// used to copy or compact an existing database and serves as the reference
+// implementation for other backends.
+type LevelDBMigrationTarget struct {
+	db *DB
+}
+
+var _ MigrationTarget = &LevelDBMigrationTarget{}
+
+// NewLevelDBMigrationTarget returns a MigrationTarget that writes to the given
+// DB.
+func NewLevelDBMigrationTarget(db *DB) *LevelDBMigrationTarget {
+	return &LevelDBMigrationTarget{
+		db: db,
+	}
+}
+
+// WriteBatch implements MigrationTarget.
+func (t *LevelDBMigrationTarget) WriteBatch(kvs []*KeyValue) error {
+	if len(kvs) == 0 {
+		return nil
+	}
+	batch := &leveldb.Batch{}
+	for _, kv := range kvs {
+		batch.Put(kv.Key, kv.Value)
+	}
+	batch.Put(migrationCheckpointKey, kvs[len(kvs)-1].Key)
+	return t.db.ldb.Write(batch, nil)
+}
+
+// Get implements MigrationTarget.
+func (t *LevelDBMigrationTarget) Get(key []byte) ([]byte, error) {
+	value, err := t.db.ldb.Get(key, nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, ErrMigrationTargetNotFound
+		}
+		return nil, err
+	}
+	return value, nil
+}
+
+// ForEachKey implements MigrationTarget.
+func (t *LevelDBMigrationTarget) ForEachKey(fn func(key []byte) error) error {
+	iter := t.db.ldb.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		if bytes.Equal(iter.Key(), migrationCheckpointKey) {
+			continue
+		}
+		if err := fn(iter.Key()); err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
+
+// Checkpoint implements MigrationTarget.
+func (t *LevelDBMigrationTarget) Checkpoint() ([]byte, error) {
+	checkpoint, err := t.db.ldb.Get(migrationCheckpointKey, nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// ClearCheckpoint implements MigrationTarget.
+func (t *LevelDBMigrationTarget) ClearCheckpoint() error {
+	return t.db.ldb.Delete(migrationCheckpointKey, nil)
+}
@@ -0,0 +1,311 @@
+// +build !js,cgo
+
+package db
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+
+	// Registers the sqlite3 driver for database/sql.
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteMigrationSchema creates the tables used by SQLiteMigrationTarget. Each
+// kind of key used by the db package is stored in its own table, with the
+// collection name, index name, and model ID in separate columns. The
+// checkpoint is stored in a separate table so that it can never be mistaken
+// for a migrated key.
+const sqliteMigrationSchema = `
+CREATE TABLE IF NOT EXISTS models (
+	collection TEXT NOT NULL,
+	id BLOB NOT NULL,
+	data TEXT NOT NULL,
+	PRIMARY KEY (collection, id)
+) WITHOUT ROWID;
+CREATE TABLE IF NOT EXISTS hot_fields (
+	collection TEXT NOT NULL,
+	id BLOB NOT NULL,
+	data TEXT NOT NULL,
+	PRIMARY KEY (collection, id)
+) WITHOUT ROWID;
+CREATE TABLE IF NOT EXISTS index_entries (
+	collection TEXT NOT NULL,
+	index_name TEXT NOT NULL,
+	value BLOB NOT NULL,
+	id BLOB NOT NULL,
+	PRIMARY KEY (collection, index_name, value, id)
+) WITHOUT ROWID;
+CREATE TABLE IF NOT EXISTS collection_counts (
+	collection TEXT PRIMARY KEY,
+	count INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS hot_fields_migrated (
+	collection TEXT PRIMARY KEY
+);
+CREATE TABLE IF NOT EXISTS migration_checkpoint (
+	id INTEGER PRIMARY KEY CHECK (id = 0),
+	key BLOB NOT NULL
+);
+`
+
+// sqliteKeyKind is the kind of a key used by the db package. It determines
+// which table the key is stored in.
+type sqliteKeyKind int
+
+const (
+	sqliteModelKey sqliteKeyKind = iota
+	sqliteHotFieldsKey
+	sqliteIndexKey
+	sqliteCountKey
+	sqliteHotFieldsMigratedKey
+)
+
+// sqliteKey is a key used by the db package split into the columns it is
+// stored as by SQLiteMigrationTarget.
+type sqliteKey struct {
+	kind       sqliteKeyKind
+	collection string
+	indexName  string
+	indexValue []byte
+	id         []byte
+}
+
+// parseSQLiteKey splits the given key into its columns. It returns an error if
+// the key was not written by the db package.
+func parseSQLiteKey(key []byte) (*sqliteKey, error) {
+	parts := bytes.Split(key, []byte(":"))
+	unsupported := fmt.Errorf("cannot migrate unsupported key to SQLite: %q", key)
+	unescaped := make([][]byte, len(parts))
+	for i, part := range parts {
+		var err error
+		unescaped[i], err = unescape(part)
+		if err != nil {
+			return nil, unsupported
+		}
+	}
+	var parsed *sqliteKey
+	switch {
+	case string(parts[0]) == "model" && len(parts) == 3:
+		parsed = &sqliteKey{kind: sqliteModelKey, collection: string(unescaped[1]), id: unescaped[2]}
+	case string(parts[0]) == "hot" && len(parts) == 3:
+		parsed = &sqliteKey{kind: sqliteHotFieldsKey, collection: string(unescaped[1]), id: unescaped[2]}
+	case string(parts[0]) == "index" && len(parts) == 5:
+		// Collection and index names are not escaped in index keys.
+		parsed = &sqliteKey{
+			kind:       sqliteIndexKey,
+			collection: string(parts[1]),
+			indexName:  string(parts[2]),
+			indexValue: unescaped[3],
+			id:         unescaped[4],
+		}
+	case string(parts[0]) == "count" && len(parts) == 2:
+		parsed = &sqliteKey{kind: sqliteCountKey, collection: string(unescaped[1])}
+	case string(parts[0]) == "hotFieldsMigrated" && len(parts) == 2:
+		parsed = &sqliteKey{kind: sqliteHotFieldsMigratedKey, collection: string(unescaped[1])}
+	default:
+		return nil, unsupported
+	}
+	// Rebuilding the key guarantees that it can be migrated back without any
+	// loss (e.g. rejects collection names which contain a ':').
+	if !bytes.Equal(parsed.key(), key) {
+		return nil, unsupported
+	}
+	return parsed, nil
+}
+
+// key returns the key in the format used by the db package.
+func (k *sqliteKey) key() []byte {
+	info := &colInfo{name: k.collection}
+	switch k.kind {
+	case sqliteModelKey:
+		return info.primaryKeyForID(k.id)
+	case sqliteHotFieldsKey:
+		return info.hotKeyForPrimaryKey(info.primaryKeyForID(k.id))
+	case sqliteIndexKey:
+		index := &Index{colInfo: info, name: k.indexName}
+		return []byte(fmt.Sprintf("%s:%s:%s", index.prefix(), escape(k.indexValue), escape(k.id)))
+	case sqliteCountKey:
+		return info.countKey()
+	case sqliteHotFieldsMigratedKey:
+		return info.hotFieldsMigratedKey()
+	default:
+		panic(fmt.Sprintf("unexpected sqliteKeyKind: %d", k.kind))
+	}
+}
+
+// SQLiteMigrationTarget is a MigrationTarget backed by a SQLite database. Models,
+// hot fields, index entries, and collection counts are each written to their
+// own table (see sqliteMigrationSchema). Keys which were not written by the db
+// package cannot be migrated.
+type SQLiteMigrationTarget struct {
+	sqlDB *sql.DB
+}
+
+var _ MigrationTarget = &SQLiteMigrationTarget{}
+
+// NewSQLiteMigrationTarget opens (or creates) the SQLite database at the given
+// path and returns a MigrationTarget that writes to it. Close must be called
+// once the migration is complete. SQLiteMigrationTarget is only available in
+// binaries built with cgo.
+func NewSQLiteMigrationTarget(path string) (*SQLiteMigrationTarget, error) {
+	sqlDB, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := sqlDB.Exec(sqliteMigrationSchema); err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
+	return &SQLiteMigrationTarget{
+		sqlDB: sqlDB,
+	}, nil
+}
+
+// WriteBatch implements MigrationTarget.
+func (t *SQLiteMigrationTarget) WriteBatch(kvs []*KeyValue) error {
+	if len(kvs) == 0 {
+		return nil
+	}
+	txn, err := t.sqlDB.Begin()
+	if err != nil {
+		return err
+	}
+	if err := writeSQLiteBatch(txn, kvs); err != nil {
+		_ = txn.Rollback()
+		return err
+	}
+	return txn.Commit()
+}
+
+func writeSQLiteBatch(txn *sql.Tx, kvs []*KeyValue) error {
+	for _, kv := range kvs {
+		key, err := parseSQLiteKey(kv.Key)
+		if err != nil {
+			return err
+		}
+		if err := writeSQLiteKey(txn, key, kv.Value); err != nil {
+			return err
+		}
+	}
+	_, err := txn.Exec("INSERT OR REPLACE INTO migration_checkpoint (id, key) VALUES (0, ?)", kvs[len(kvs)-1].Key)
+	return err
+}
+
+func writeSQLiteKey(txn *sql.Tx, key *sqliteKey, value []byte) error {
+	var err error
+	switch key.kind {
+	case sqliteModelKey:
+		_, err = txn.Exec("INSERT OR REPLACE INTO models (collection, id, data) VALUES (?, ?, ?)", key.collection, key.id, string(value))
+	case sqliteHotFieldsKey:
+		_, err = txn.Exec("INSERT OR REPLACE INTO hot_fields (collection, id, data) VALUES (?, ?, ?)", key.collection, key.id, string(value))
+	case sqliteIndexKey:
+		if len(value) != 0 {
+			return fmt.Errorf("cannot migrate index key with a value to SQLite: %q", key.key())
+		}
+		_, err = txn.Exec("INSERT OR REPLACE INTO index_entries (collection, index_name, value, id) VALUES (?, ?, ?, ?)", key.collection, key.indexName, key.indexValue, key.id)
+	case sqliteCountKey:
+		count, decodeErr := decodeInt(value)
+		if decodeErr != nil || !bytes.Equal(encodeInt(count), value) {
+			return fmt.Errorf("cannot migrate invalid count to SQLite: %q", value)
+		}
+		_, err = txn.Exec("INSERT OR REPLACE INTO collection_counts (collection, count) VALUES (?, ?)", key.collection, count)
+	case sqliteHotFieldsMigratedKey:
+		if !bytes.Equal(value, hotFieldsMigratedValue) {
+			return fmt.Errorf("cannot migrate unexpected value to SQLite for key %q", key.key())
+		}
+		_, err = txn.Exec("INSERT OR REPLACE INTO hot_fields_migrated (collection) VALUES (?)", key.collection)
+	}
+	return err
+}
+
+// Get implements MigrationTarget.
+func (t *SQLiteMigrationTarget) Get(rawKey []byte) ([]byte, error) {
+	key, err := parseSQLiteKey(rawKey)
+	if err != nil {
+		// The key could never have been written.
+		return nil, ErrMigrationTargetNotFound
+	}
+	var row *sql.Row
+	switch key.kind {
+	case sqliteModelKey:
+		row = t.sqlDB.QueryRow("SELECT data FROM models WHERE collection = ? AND id = ?", key.collection, key.id)
+	case sqliteHotFieldsKey:
+		row = t.sqlDB.QueryRow("SELECT data FROM hot_fields WHERE collection = ? AND id = ?", key.collection, key.id)
+	case sqliteIndexKey:
+		row = t.sqlDB.QueryRow("SELECT x'' FROM index_entries WHERE collection = ? AND index_name = ? AND value = ? AND id = ?", key.collection, key.indexName, key.indexValue, key.id)
+	case sqliteCountKey:
+		row = t.sqlDB.QueryRow("SELECT CAST(count AS TEXT) FROM collection_counts WHERE collection = ?", key.collection)
+	case sqliteHotFieldsMigratedKey:
+		row = t.sqlDB.QueryRow("SELECT ? FROM hot_fields_migrated WHERE collection = ?", hotFieldsMigratedValue, key.collection)
+	}
+	var value []byte
+	if err := row.Scan(&value); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrMigrationTargetNotFound
+		}
+		return nil, err
+	}
+	return value, nil
+}
+
+// ForEachKey implements MigrationTarget.
+func (t *SQLiteMigrationTarget) ForEachKey(fn func(key []byte) error) error {
+	queries := []struct {
+		kind  sqliteKeyKind
+		query string
+	}{
+		{sqliteModelKey, "SELECT collection, '', x'', id FROM models"},
+		{sqliteHotFieldsKey, "SELECT collection, '', x'', id FROM hot_fields"},
+		{sqliteIndexKey, "SELECT collection, index_name, value, id FROM index_entries"},
+		{sqliteCountKey, "SELECT collection, '', x'', x'' FROM collection_counts"},
+		{sqliteHotFieldsMigratedKey, "SELECT collection, '', x'', x'' FROM hot_fields_migrated"},
+	}
+	for _, q := range queries {
+		if err := t.forEachRow(q.kind, q.query, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *SQLiteMigrationTarget) forEachRow(kind sqliteKeyKind, query string, fn func(key []byte) error) error {
+	rows, err := t.sqlDB.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		key := &sqliteKey{kind: kind}
+		if err := rows.Scan(&key.collection, &key.indexName, &key.indexValue, &key.id); err != nil {
+			return err
+		}
+		if err := fn(key.key()); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Checkpoint implements MigrationTarget.
+func (t *SQLiteMigrationTarget) Checkpoint() ([]byte, error) {
+	var checkpoint []byte
+	if err := t.sqlDB.QueryRow("SELECT key FROM migration_checkpoint WHERE id = 0").Scan(&checkpoint); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// ClearCheckpoint implements MigrationTarget.
+func (t *SQLiteMigrationTarget) ClearCheckpoint() error {
+	_, err := t.sqlDB.Exec("DELETE FROM migration_checkpoint")
+	return err
+}
+
+// Close closes the underlying SQLite database.
+func (t *SQLiteMigrationTarget) Close() error {
+	return t.sqlDB.Close()
+}
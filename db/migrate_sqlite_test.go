@@ -0,0 +1,125 @@
+// +build !js,cgo
+
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSQLiteMigrationTarget(t *testing.T) *SQLiteMigrationTarget {
+	target, err := NewSQLiteMigrationTarget("/tmp/sqlite_testing_" + uuid.New().String() + ".db")
+	require.NoError(t, err)
+	return target
+}
+
+func TestMigrateToSQLite(t *testing.T) {
+	t.Parallel()
+	source := setUpMigrationSource(t, 25)
+	defer source.Close()
+	target := newTestSQLiteMigrationTarget(t)
+	defer target.Close()
+
+	// Simulate an interrupted migration by failing after the first batch.
+	failing := &failingSQLiteMigrationTarget{SQLiteMigrationTarget: target, batchesBeforeFailure: 1}
+	firstRun, err := source.MigrateTo(failing, MigrationOptions{BatchSize: 10})
+	require.Error(t, err)
+	assert.Equal(t, 10, firstRun.KeysMigrated)
+	require.Error(t, source.VerifyMigration(target))
+
+	secondRun, err := source.MigrateTo(target, MigrationOptions{BatchSize: 10})
+	require.NoError(t, err)
+	assert.Equal(t, countKeys(t, source), firstRun.KeysMigrated+secondRun.KeysMigrated)
+	require.NoError(t, source.VerifyMigration(target))
+	require.NoError(t, target.ClearCheckpoint())
+	checkpoint, err := target.Checkpoint()
+	require.NoError(t, err)
+	assert.Nil(t, checkpoint)
+}
+
+func TestVerifySQLiteMigrationExtraKey(t *testing.T) {
+	t.Parallel()
+	source := setUpMigrationSource(t, 5)
+	defer source.Close()
+	target := newTestSQLiteMigrationTarget(t)
+	defer target.Close()
+
+	_, err := source.MigrateTo(target, MigrationOptions{})
+	require.NoError(t, err)
+	require.NoError(t, target.WriteBatch([]*KeyValue{{Key: []byte("model:people:Person_extra"), Value: []byte("extra")}}))
+	err = source.VerifyMigration(target)
+	require.Error(t, err)
+	verificationErr, ok := err.(MigrationVerificationError)
+	require.True(t, ok, "expected MigrationVerificationError but got %T", err)
+	assert.Equal(t, []byte("model:people:Person_extra"), verificationErr.Key)
+}
+
+func TestMigrateToSQLiteTables(t *testing.T) {
+	t.Parallel()
+	source := setUpMigrationSource(t, 5)
+	defer source.Close()
+	hotCol, err := source.NewCollection("hotPeople", &testHotFieldsModel{})
+	require.NoError(t, err)
+	hotCol.AddIndex("nickname", func(m Model) []byte {
+		return []byte(m.(*testHotFieldsModel).Nicknames[0])
+	})
+	require.NoError(t, hotCol.Insert(&testHotFieldsModel{Name: "foo:bar", Age: 42, Nicknames: []string{"b\\az"}}))
+	_, err = hotCol.SplitHotFields()
+	require.NoError(t, err)
+	target := newTestSQLiteMigrationTarget(t)
+	defer target.Close()
+
+	_, err = source.MigrateTo(target, MigrationOptions{BatchSize: 3})
+	require.NoError(t, err)
+	require.NoError(t, source.VerifyMigration(target))
+
+	var name, data string
+	require.NoError(t, target.sqlDB.QueryRow("SELECT collection, data FROM models WHERE id = ?", []byte("Person_3")).Scan(&name, &data))
+	assert.Equal(t, "people", name)
+	assert.Equal(t, `{"Name":"Person_3","Age":3,"Nicknames":null}`, data)
+	require.NoError(t, target.sqlDB.QueryRow("SELECT data FROM hot_fields WHERE collection = ? AND id = ?", "hotPeople", []byte("foo:bar")).Scan(&data))
+	assert.Equal(t, `{"Age":42}`, data)
+	var id []byte
+	require.NoError(t, target.sqlDB.QueryRow("SELECT id FROM index_entries WHERE collection = ? AND index_name = ? AND value = ?", "people", "age", []byte("4")).Scan(&id))
+	assert.Equal(t, []byte("Person_4"), id)
+	var count int
+	require.NoError(t, target.sqlDB.QueryRow("SELECT count FROM collection_counts WHERE collection = ?", "people").Scan(&count))
+	assert.Equal(t, 5, count)
+	require.NoError(t, target.sqlDB.QueryRow("SELECT COUNT(*) FROM hot_fields_migrated WHERE collection = ?", "hotPeople").Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+func TestSQLiteMigrationTargetUnsupportedKey(t *testing.T) {
+	t.Parallel()
+	target := newTestSQLiteMigrationTarget(t)
+	defer target.Close()
+
+	require.NoError(t, target.WriteBatch([]*KeyValue{{Key: []byte("model:people:Person_0"), Value: []byte("{}")}}))
+	for _, key := range []string{"unknown", "model:people", "index:people:age:1", "count:people"} {
+		err := target.WriteBatch([]*KeyValue{{Key: []byte(key), Value: []byte("foo")}})
+		assert.Error(t, err, key)
+	}
+	// Failed batches are not written and don't move the checkpoint.
+	checkpoint, err := target.Checkpoint()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("model:people:Person_0"), checkpoint)
+	_, err = target.Get([]byte("unknown"))
+	assert.Equal(t, ErrMigrationTargetNotFound, err)
+}
+
+type failingSQLiteMigrationTarget struct {
+	*SQLiteMigrationTarget
+	batchesBeforeFailure int
+}
+
+func (t *failingSQLiteMigrationTarget) WriteBatch(kvs []*KeyValue) error {
+	if t.batchesBeforeFailure == 0 {
+		return fmt.Errorf("simulated failure")
+	}
+	t.batchesBeforeFailure--
+	return t.SQLiteMigrationTarget.WriteBatch(kvs)
+}
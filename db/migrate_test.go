@@ -0,0 +1,134 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateTo(t *testing.T) {
+	t.Parallel()
+	source := setUpMigrationSource(t, 25)
+	defer source.Close()
+	dest := newTestDB(t)
+	defer dest.Close()
+	target := NewLevelDBMigrationTarget(dest)
+
+	numProgressCalls := 0
+	progress, err := source.MigrateTo(target, MigrationOptions{
+		BatchSize: 10,
+		OnProgress: func(MigrationProgress) {
+			numProgressCalls++
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, countKeys(t, source), progress.KeysMigrated)
+	assert.True(t, numProgressCalls > 1, "expected OnProgress to be called once per batch")
+	require.NoError(t, source.VerifyMigration(target))
+	require.NoError(t, target.ClearCheckpoint())
+
+	// The migrated data should be usable as a normal database.
+	destCol, err := dest.NewCollection("people", &testModel{})
+	require.NoError(t, err)
+	count, err := destCol.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 25, count)
+}
+
+func TestMigrateToResume(t *testing.T) {
+	t.Parallel()
+	source := setUpMigrationSource(t, 25)
+	defer source.Close()
+	dest := newTestDB(t)
+	defer dest.Close()
+	target := NewLevelDBMigrationTarget(dest)
+
+	// Simulate an interrupted migration by failing after the first batch.
+	failing := &failingMigrationTarget{LevelDBMigrationTarget: target, batchesBeforeFailure: 1}
+	firstRun, err := source.MigrateTo(failing, MigrationOptions{BatchSize: 10})
+	require.Error(t, err)
+	assert.Equal(t, 10, firstRun.KeysMigrated)
+	require.Error(t, source.VerifyMigration(target))
+
+	secondRun, err := source.MigrateTo(target, MigrationOptions{BatchSize: 10})
+	require.NoError(t, err)
+	assert.Equal(t, countKeys(t, source), firstRun.KeysMigrated+secondRun.KeysMigrated)
+	require.NoError(t, source.VerifyMigration(target))
+}
+
+func TestVerifyMigrationMismatch(t *testing.T) {
+	t.Parallel()
+	source := setUpMigrationSource(t, 5)
+	defer source.Close()
+	dest := newTestDB(t)
+	defer dest.Close()
+	target := NewLevelDBMigrationTarget(dest)
+
+	_, err := source.MigrateTo(target, MigrationOptions{})
+	require.NoError(t, err)
+	require.NoError(t, dest.ldb.Put([]byte("model:people:Person_0"), []byte("corrupted"), nil))
+	err = source.VerifyMigration(target)
+	require.Error(t, err)
+	_, ok := err.(MigrationVerificationError)
+	assert.True(t, ok, "expected MigrationVerificationError but got %T", err)
+}
+
+func TestVerifyMigrationExtraKey(t *testing.T) {
+	t.Parallel()
+	source := setUpMigrationSource(t, 5)
+	defer source.Close()
+	dest := newTestDB(t)
+	defer dest.Close()
+	target := NewLevelDBMigrationTarget(dest)
+
+	_, err := source.MigrateTo(target, MigrationOptions{})
+	require.NoError(t, err)
+	require.NoError(t, dest.ldb.Put([]byte("model:people:Person_extra"), []byte("extra"), nil))
+	err = source.VerifyMigration(target)
+	require.Error(t, err)
+	verificationErr, ok := err.(MigrationVerificationError)
+	require.True(t, ok, "expected MigrationVerificationError but got %T", err)
+	assert.Equal(t, []byte("model:people:Person_extra"), verificationErr.Key)
+}
+
+type failingMigrationTarget struct {
+	*LevelDBMigrationTarget
+	batchesBeforeFailure int
+}
+
+func (t *failingMigrationTarget) WriteBatch(kvs []*KeyValue) error {
+	if t.batchesBeforeFailure == 0 {
+		return fmt.Errorf("simulated failure")
+	}
+	t.batchesBeforeFailure--
+	return t.LevelDBMigrationTarget.WriteBatch(kvs)
+}
+
+func setUpMigrationSource(t *testing.T, numModels int) *DB {
+	db := newTestDB(t)
+	col, err := db.NewCollection("people", &testModel{})
+	require.NoError(t, err)
+	col.AddIndex("age", func(m Model) []byte {
+		return []byte(fmt.Sprint(m.(*testModel).Age))
+	})
+	for i := 0; i < numModels; i++ {
+		require.NoError(t, col.Insert(&testModel{
+			Name: fmt.Sprintf("Person_%d", i),
+			Age:  i,
+		}))
+	}
+	return db
+}
+
+func countKeys(t *testing.T, db *DB) int {
+	iter := db.ldb.NewIterator(nil, nil)
+	defer iter.Release()
+	count := 0
+	for iter.Next() {
+		count++
+	}
+	require.NoError(t, iter.Error())
+	return count
+}
@@ -12,6 +12,7 @@ func Open(path string) (*DB, error) {
 		return nil, err
 	}
 	return &DB{
-		ldb: ldb,
+		ldb:  ldb,
+		path: path,
 	}, nil
 }
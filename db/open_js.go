@@ -71,6 +71,7 @@ func openBrowserFSDB(path string) (*DB, error) {
 		return nil, err
 	}
 	return &DB{
-		ldb: ldb,
+		ldb:  ldb,
+		path: path,
 	}, nil
 }
@@ -1,7 +1,7 @@
 package db
 
 import (
-	"encoding/json"
+	"bytes"
 	"errors"
 	"fmt"
 	"reflect"
@@ -24,16 +24,23 @@ func findByID(info *colInfo, reader dbReader, id []byte, model Model) error {
 		}
 		return err
 	}
-	return json.Unmarshal(data, model)
+	return decodeModel(info, reader, pk, data, model)
 }
 
 func findAll(info *colInfo, reader dbReader, models interface{}) error {
+	// The hot fields of each model are looked up separately, so they have to
+	// be read from the same point in time as the models.
+	reader, release, err := pointInTimeReader(reader)
+	if err != nil {
+		return err
+	}
+	defer release()
 	prefixRange := util.BytesPrefix([]byte(fmt.Sprintf("%s:", info.prefix())))
 	iter := reader.NewIterator(prefixRange, nil)
-	return findWithIterator(info, iter, models)
+	return findWithIterator(info, reader, iter, models)
 }
 
-func findWithIterator(info *colInfo, iter iterator.Iterator, models interface{}) error {
+func findWithIterator(info *colInfo, reader dbReader, iter iterator.Iterator, models interface{}) error {
 	defer iter.Release()
 	if err := info.checkModelsType(models); err != nil {
 		return err
@@ -44,7 +51,7 @@ func findWithIterator(info *colInfo, iter iterator.Iterator, models interface{})
 		// model.
 		data := iter.Value()
 		model := reflect.New(info.modelType)
-		if err := json.Unmarshal(data, model.Interface()); err != nil {
+		if err := decodeModel(info, reader, iter.Key(), data, model.Interface()); err != nil {
 			return err
 		}
 		modelsVal.Set(reflect.Append(modelsVal, model.Elem()))
@@ -61,9 +68,13 @@ func findExistingModelByPrimaryKeyWithTransaction(info *colInfo, readWriter dbRe
 	if err != nil {
 		return nil, err
 	}
+	return decodeExistingModel(info, readWriter, primaryKey, data)
+}
+
+func decodeExistingModel(info *colInfo, readWriter dbReadWriter, primaryKey []byte, data []byte) (Model, error) {
 	// Use reflect to create a new reference for the model type.
 	modelRef := reflect.New(info.modelType).Interface()
-	if err := json.Unmarshal(data, modelRef); err != nil {
+	if err := decodeModel(info, readWriter, primaryKey, data, modelRef); err != nil {
 		return nil, err
 	}
 	model := reflect.ValueOf(modelRef).Elem().Interface().(Model)
@@ -77,7 +88,7 @@ func insertWithTransaction(info *colInfo, readWriter dbReadWriter, model Model)
 	if err := info.checkModelType(model); err != nil {
 		return err
 	}
-	data, err := json.Marshal(model)
+	data, hotData, err := encodeModel(info, model)
 	if err != nil {
 		return err
 	}
@@ -90,6 +101,11 @@ func insertWithTransaction(info *colInfo, readWriter dbReadWriter, model Model)
 	if err := readWriter.Put(pk, data, nil); err != nil {
 		return err
 	}
+	if hotData != nil {
+		if err := readWriter.Put(info.hotKeyForPrimaryKey(pk), hotData, nil); err != nil {
+			return err
+		}
+	}
 	if err := saveIndexesWithTransaction(info, readWriter, model); err != nil {
 		return err
 	}
@@ -104,35 +120,38 @@ func updateWithTransaction(info *colInfo, readWriter dbReadWriter, model Model)
 		return err
 	}
 
-	// Check if the model already exists and return an error if not.
+	// Get the existing data for the model and return an error if it doesn't
+	// exist.
 	pk := info.primaryKeyForModel(model)
-	if exists, err := readWriter.Has(pk, nil); err != nil {
-		return err
-	} else if !exists {
-		return NotFoundError{ID: model.ID()}
-	}
-
-	// Get the existing data for the model and delete any (now outdated) indexes.
-	existingModel, err := findExistingModelByPrimaryKeyWithTransaction(info, readWriter, pk)
+	existingData, err := readWriter.Get(pk, nil)
 	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return NotFoundError{ID: model.ID()}
+		}
 		return err
 	}
-	if err := deleteIndexesWithTransaction(info, readWriter, existingModel); err != nil {
+	existingModel, err := decodeExistingModel(info, readWriter, pk, existingData)
+	if err != nil {
 		return err
 	}
 
-	// Save the new data and add the new indexes.
-	newData, err := json.Marshal(model)
+	// Save the new data. The cold fields of a HotFieldsModel usually don't
+	// change, in which case they are not rewritten.
+	newData, newHotData, err := encodeModel(info, model)
 	if err != nil {
 		return err
 	}
-	if err := readWriter.Put(pk, newData, nil); err != nil {
-		return err
+	if !bytes.Equal(existingData, newData) {
+		if err := readWriter.Put(pk, newData, nil); err != nil {
+			return err
+		}
 	}
-	if err := saveIndexesWithTransaction(info, readWriter, model); err != nil {
-		return err
+	if newHotData != nil {
+		if err := readWriter.Put(info.hotKeyForPrimaryKey(pk), newHotData, nil); err != nil {
+			return err
+		}
 	}
-	return nil
+	return updateIndexesWithTransaction(info, readWriter, existingModel, model)
 }
 
 func deleteWithTransaction(info *colInfo, readWriter dbReadWriter, id []byte) error {
@@ -155,6 +174,11 @@ func deleteWithTransaction(info *colInfo, readWriter dbReadWriter, id []byte) er
 	if err := readWriter.Delete(pk, nil); err != nil {
 		return err
 	}
+	if info.hasHotFields {
+		if err := readWriter.Delete(info.hotKeyForPrimaryKey(pk), nil); err != nil {
+			return err
+		}
+	}
 
 	// Delete any index entries.
 	if err := deleteIndexesWithTransaction(info, readWriter, latest); err != nil {
@@ -178,6 +202,36 @@ func saveIndexesWithTransaction(info *colInfo, readWriter dbReadWriter, model Mo
 	return nil
 }
 
+// updateIndexesWithTransaction replaces the index entries computed from
+// existingModel with the ones computed from model. Index entries which are the
+// same for both (e.g. because the indexed fields didn't change) are not
+// rewritten. It *doesn't* discard the transaction if there is an error.
+func updateIndexesWithTransaction(info *colInfo, readWriter dbReadWriter, existingModel Model, model Model) error {
+	info.indexMut.RLock()
+	defer info.indexMut.RUnlock()
+	for _, index := range info.indexes {
+		existingKeys := map[string]struct{}{}
+		for _, key := range index.keysForModel(existingModel) {
+			existingKeys[string(key)] = struct{}{}
+		}
+		for _, key := range index.keysForModel(model) {
+			if _, found := existingKeys[string(key)]; found {
+				delete(existingKeys, string(key))
+				continue
+			}
+			if err := readWriter.Put(key, nil, nil); err != nil {
+				return err
+			}
+		}
+		for key := range existingKeys {
+			if err := readWriter.Delete([]byte(key), nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // deleteIndexesForModel deletes any indexes computed from the given model. It
 // *doesn't* discard the transaction if there is an error.
 func deleteIndexesWithTransaction(info *colInfo, readWriter dbReadWriter, model Model) error {
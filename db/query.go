@@ -1,9 +1,11 @@
 package db
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/syndtr/goleveldb/leveldb"
 
@@ -14,6 +16,7 @@ import (
 
 // Query is used to return certain results from the database.
 type Query struct {
+	ctx     context.Context
 	colInfo *colInfo
 	reader  dbReader
 	filter  *Filter
@@ -31,6 +34,7 @@ type Filter struct {
 
 func newQuery(colInfo *colInfo, reader dbReader, filter *Filter) *Query {
 	return &Query{
+		ctx:     context.Background(),
 		colInfo: colInfo,
 		reader:  reader,
 		filter:  filter,
@@ -65,6 +69,15 @@ func (q *Query) Offset(offset int) *Query {
 	return q
 }
 
+// Context causes the query to stop iterating and return the error of the given
+// context (e.g. context.DeadlineExceeded) as soon as it is done. It is useful
+// for queries which scan a lot of keys (e.g. because of a high offset) on
+// behalf of a client that might give up on them.
+func (q *Query) Context(ctx context.Context) *Query {
+	q.ctx = ctx
+	return q
+}
+
 // ValueFilter returns a Filter which will match all models with an index value
 // equal to the given value.
 func (index *Index) ValueFilter(val []byte) *Filter {
@@ -112,12 +125,95 @@ func (q *Query) Run(models interface{}) error {
 		return err
 	}
 
-	iter := q.reader.NewIterator(q.filter.slice, nil)
+	start := time.Now()
+	reader, release, err := pointInTimeReader(q.reader)
+	if err != nil {
+		return err
+	}
+	defer release()
+	iter := reader.NewIterator(q.filter.slice, nil)
 	defer iter.Release()
+	var keysScanned int
 	if q.reverse {
-		return q.getModelsWithIteratorReverse(iter, models)
+		keysScanned, err = q.getModelsWithIteratorReverse(reader, iter, models)
+	} else {
+		keysScanned, err = q.getModelsWithIteratorForward(reader, iter, models)
 	}
-	return q.getModelsWithIteratorForward(iter, models)
+	q.logIfSlow(start, keysScanned, reflect.ValueOf(models).Elem().Len())
+	return err
+}
+
+// RunInChunks runs the query and scans the results into models chunkSize models
+// at a time. models should be a pointer to an empty slice of a concrete model
+// type (e.g. *[]myModelType). handleChunk is called each time models holds
+// chunkSize models, and once more with any remaining models after the last
+// model was read. models is reset to an empty slice after each call to
+// handleChunk. Unlike Run, RunInChunks only holds one chunk of models in memory
+// at a time, which makes it suitable for iterating through a large number of
+// models. If handleChunk returns an error, RunInChunks stops iterating and
+// returns that error. All chunks are read from the same point in time, so
+// handleChunk may write to the collection without affecting the models which
+// are returned.
+func (q *Query) RunInChunks(models interface{}, chunkSize int, handleChunk func() error) error {
+	if chunkSize <= 0 {
+		return errors.New("chunkSize must be greater than 0")
+	}
+	if err := q.colInfo.checkModelsType(models); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	reader, release, err := pointInTimeReader(q.reader)
+	if err != nil {
+		return err
+	}
+	defer release()
+	iter := reader.NewIterator(q.filter.slice, nil)
+	defer iter.Release()
+	next := iter.Next
+	if q.reverse {
+		// Move the iterator to the last key and then iterate backwards.
+		iter.Last()
+		iter.Next()
+		next = iter.Prev
+	}
+	pkSet := stringset.New()
+	modelsVal := reflect.ValueOf(models).Elem()
+	numModels := 0
+	keysScanned := 0
+	defer func() {
+		q.logIfSlow(start, keysScanned, numModels)
+	}()
+	for i := 0; next() && iter.Error() == nil; i++ {
+		if err := q.ctx.Err(); err != nil {
+			return err
+		}
+		keysScanned++
+		if i < q.offset {
+			continue
+		}
+		lenBefore := modelsVal.Len()
+		if err := q.getAndAppendModelIfUnique(reader, q.filter.index, pkSet, iter.Key(), modelsVal); err != nil {
+			return err
+		}
+		numModels += modelsVal.Len() - lenBefore
+		if modelsVal.Len() >= chunkSize {
+			if err := handleChunk(); err != nil {
+				return err
+			}
+			modelsVal.Set(reflect.MakeSlice(modelsVal.Type(), 0, chunkSize))
+		}
+		if q.max != 0 && numModels >= q.max {
+			break
+		}
+	}
+	if iter.Error() != nil {
+		return iter.Error()
+	}
+	if modelsVal.Len() > 0 {
+		return handleChunk()
+	}
+	return nil
 }
 
 // Count returns the number of unique models that match the query. It does not
@@ -125,10 +221,19 @@ func (q *Query) Run(models interface{}) error {
 // respect q.Max. If the number of models that match the filter is greater than
 // q.Max, it will stop counting and return q.Max.
 func (q *Query) Count() (int, error) {
+	start := time.Now()
 	iter := q.reader.NewIterator(q.filter.slice, nil)
 	defer iter.Release()
 	pkSet := stringset.New()
+	keysScanned := 0
+	defer func() {
+		q.logIfSlow(start, keysScanned, len(pkSet))
+	}()
 	for i := 0; iter.Next() && iter.Error() == nil; i++ {
+		if err := q.ctx.Err(); err != nil {
+			return 0, err
+		}
+		keysScanned++
 		if i < q.offset {
 			continue
 		}
@@ -144,48 +249,63 @@ func (q *Query) Count() (int, error) {
 	return len(pkSet), nil
 }
 
-func (q *Query) getModelsWithIteratorForward(iter iterator.Iterator, models interface{}) error {
+// getModelsWithIteratorForward scans the models into models and returns the
+// number of index keys that were scanned.
+func (q *Query) getModelsWithIteratorForward(reader dbReader, iter iterator.Iterator, models interface{}) (int, error) {
 	// MultiIndexes can result in the same model being included more than once. To
 	// prevent this, we keep track of the primaryKeys we have already seen using
 	// pkSet.
 	pkSet := stringset.New()
 	modelsVal := reflect.ValueOf(models).Elem()
-	for i := 0; iter.Next() && iter.Error() == nil; i++ {
+	i := 0
+	for ; iter.Next() && iter.Error() == nil; i++ {
+		if err := q.ctx.Err(); err != nil {
+			return i, err
+		}
 		if i < q.offset {
 			continue
 		}
-		if err := q.getAndAppendModelIfUnique(q.filter.index, pkSet, iter.Key(), modelsVal); err != nil {
-			return err
+		if err := q.getAndAppendModelIfUnique(reader, q.filter.index, pkSet, iter.Key(), modelsVal); err != nil {
+			return i + 1, err
 		}
 		if q.max != 0 && modelsVal.Len() >= q.max {
-			return iter.Error()
+			return i + 1, iter.Error()
 		}
 	}
-	return iter.Error()
+	return i, iter.Error()
 }
 
-func (q *Query) getModelsWithIteratorReverse(iter iterator.Iterator, models interface{}) error {
+// getModelsWithIteratorReverse is like getModelsWithIteratorForward but
+// iterates through the keys in descending order.
+func (q *Query) getModelsWithIteratorReverse(reader dbReader, iter iterator.Iterator, models interface{}) (int, error) {
 	pkSet := stringset.New()
 	modelsVal := reflect.ValueOf(models).Elem()
 	// Move the iterator to the last key and then iterate backwards by calling
 	// Prev instead of Next for each iteration of the for loop.
 	iter.Last()
 	iter.Next()
-	for i := 0; iter.Prev() && iter.Error() == nil; i++ {
+	i := 0
+	for ; iter.Prev() && iter.Error() == nil; i++ {
+		if err := q.ctx.Err(); err != nil {
+			return i, err
+		}
 		if i < q.offset {
 			continue
 		}
-		if err := q.getAndAppendModelIfUnique(q.filter.index, pkSet, iter.Key(), modelsVal); err != nil {
-			return err
+		if err := q.getAndAppendModelIfUnique(reader, q.filter.index, pkSet, iter.Key(), modelsVal); err != nil {
+			return i + 1, err
 		}
 		if q.max != 0 && modelsVal.Len() >= q.max {
-			return iter.Error()
+			return i + 1, iter.Error()
 		}
 	}
-	return iter.Error()
+	return i, iter.Error()
 }
 
-func (q *Query) getAndAppendModelIfUnique(index *Index, pkSet stringset.Set, key []byte, modelsVal reflect.Value) error {
+// getAndAppendModelIfUnique looks up the model for the given index key with
+// reader, which must see the same point in time as the iterator the key was
+// read from.
+func (q *Query) getAndAppendModelIfUnique(reader dbReader, index *Index, pkSet stringset.Set, key []byte, modelsVal reflect.Value) error {
 	// We assume that each key in the iterator consists of an index prefix, the
 	// value for a particular model, and the model ID. We can extract a primary
 	// key from this key and use it to get the encoded data for the model
@@ -195,18 +315,17 @@ func (q *Query) getAndAppendModelIfUnique(index *Index, pkSet stringset.Set, key
 		return nil
 	}
 	pkSet.Add(string(pk))
-	data, err := q.reader.Get(pk, nil)
+	data, err := reader.Get(pk, nil)
 	if err == leveldb.ErrNotFound || data == nil {
-		// It is possible that a separate goroutine deleted the model while we were
-		// iterating through the keys in the index. This is not considered an error.
-		// We simply don't include this model in the final results.
+		// Writes are atomic, so every index key has a model at the same point
+		// in time. Skip the key anyway rather than failing the whole query.
 		return nil
 	}
 	if err != nil {
 		return err
 	}
 	model := reflect.New(q.colInfo.modelType)
-	if err := json.Unmarshal(data, model.Interface()); err != nil {
+	if err := decodeModel(q.colInfo, reader, pk, data, model.Interface()); err != nil {
 		return err
 	}
 	modelsVal.Set(reflect.Append(modelsVal, model.Elem()))
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/albrow/stringset"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// offsetHintMinKeys is the minimum number of keys a query must skip
+	// because of its offset before a hint is given.
+	offsetHintMinKeys = 1000
+	// selectivityHintMinModels is the minimum number of models a collection
+	// must have before a hint is given for filters which match most of them.
+	selectivityHintMinModels = 1000
+	// selectivityHintRatio is the fraction of all models in the collection
+	// above which a filter is considered not selective.
+	selectivityHintRatio = 0.5
+)
+
+// SetSlowQueryThreshold causes all queries which take at least threshold to
+// run to be logged along with their filter, the number of index keys they
+// scanned and the number of models they returned. A threshold of 0 disables
+// the slow-query log.
+func (db *DB) SetSlowQueryThreshold(threshold time.Duration) {
+	atomic.StoreInt64(&db.slowQueryThreshold, int64(threshold))
+}
+
+// QueryPlan describes how a query is executed. It is returned by
+// Query.Explain.
+type QueryPlan struct {
+	Collection string
+	Index      string
+	// Start and Limit are the bounds of the index values that are scanned.
+	// Limit is empty if the filter matches all values with a certain prefix.
+	Start   string
+	Limit   string
+	Reverse bool
+	Offset  int
+	Max     int
+	// KeysScanned is the number of index keys the query iterates through,
+	// including the keys skipped because of Offset.
+	KeysScanned int
+	// ModelsMatched is the number of unique models the query returns.
+	ModelsMatched int
+	// TotalModels is the number of models in the collection.
+	TotalModels int
+	// Hints suggest how the query could be made faster.
+	Hints []string
+}
+
+// Explain returns the plan of the query without retrieving any models. Like
+// the EXPLAIN ANALYZE statement in SQL, it iterates through the index keys
+// that running the query would scan. It can be used to tell whether a query
+// needs a new index.
+func (q *Query) Explain() (*QueryPlan, error) {
+	totalModels, err := count(q.colInfo, q.reader)
+	if err != nil {
+		return nil, err
+	}
+	iter := q.reader.NewIterator(q.filter.slice, nil)
+	defer iter.Release()
+	next := iter.Next
+	if q.reverse {
+		iter.Last()
+		iter.Next()
+		next = iter.Prev
+	}
+	pkSet := stringset.New()
+	keysScanned := 0
+	for i := 0; next() && iter.Error() == nil; i++ {
+		keysScanned++
+		if i < q.offset {
+			continue
+		}
+		pk := q.filter.index.primaryKeyFromIndexKey(iter.Key())
+		pkSet.Add(string(pk))
+		if q.max != 0 && len(pkSet) >= q.max {
+			break
+		}
+	}
+	if iter.Error() != nil {
+		return nil, iter.Error()
+	}
+	start, limit := q.filter.bounds()
+	plan := &QueryPlan{
+		Collection:    q.colInfo.name,
+		Index:         q.filter.index.name,
+		Start:         start,
+		Limit:         limit,
+		Reverse:       q.reverse,
+		Offset:        q.offset,
+		Max:           q.max,
+		KeysScanned:   keysScanned,
+		ModelsMatched: len(pkSet),
+		TotalModels:   totalModels,
+	}
+	plan.Hints = q.hints(keysScanned, len(pkSet), totalModels)
+	return plan, nil
+}
+
+// bounds returns a human-readable representation of the bounds of the index
+// values that the filter matches.
+func (f *Filter) bounds() (start string, limit string) {
+	prefix := string(f.index.prefix()) + ":"
+	start = fmt.Sprintf("%q", strings.TrimPrefix(string(f.slice.Start), prefix))
+	if f.slice.Limit != nil && strings.HasPrefix(string(f.slice.Limit), prefix) {
+		limit = fmt.Sprintf("%q", strings.TrimPrefix(string(f.slice.Limit), prefix))
+	}
+	return start, limit
+}
+
+// hints returns suggestions for making the query faster. totalModels may be
+// -1 if it is not known.
+func (q *Query) hints(keysScanned int, modelsReturned int, totalModels int) []string {
+	hints := []string{}
+	if q.offset >= offsetHintMinKeys && keysScanned >= q.offset {
+		hints = append(hints, fmt.Sprintf("the query skips %d keys because of its offset; paginate with a RangeFilter starting after the last index value of the previous page instead", q.offset))
+	}
+	if totalModels >= selectivityHintMinModels && q.max == 0 && float64(modelsReturned) > selectivityHintRatio*float64(totalModels) {
+		hints = append(hints, fmt.Sprintf("the filter on index %q matches %d of %d models; add a more selective index", q.filter.index.name, modelsReturned, totalModels))
+	}
+	if scannedWithoutOffset := keysScanned - q.offset; modelsReturned > 0 && scannedWithoutOffset > 2*modelsReturned {
+		hints = append(hints, fmt.Sprintf("the query scanned %d keys for %d models; index %q has many values per model", scannedWithoutOffset, modelsReturned, q.filter.index.name))
+	}
+	return hints
+}
+
+// logIfSlow logs the query if it took at least the slow-query threshold of
+// the database to run.
+func (q *Query) logIfSlow(start time.Time, keysScanned int, modelsReturned int) {
+	if q.colInfo.db == nil {
+		return
+	}
+	threshold := time.Duration(atomic.LoadInt64(&q.colInfo.db.slowQueryThreshold))
+	if threshold == 0 {
+		return
+	}
+	duration := time.Since(start)
+	if duration < threshold {
+		return
+	}
+	startBound, limitBound := q.filter.bounds()
+	log.WithFields(log.Fields{
+		"collection":     q.colInfo.name,
+		"index":          q.filter.index.name,
+		"start":          startBound,
+		"limit":          limitBound,
+		"reverse":        q.reverse,
+		"offset":         q.offset,
+		"max":            q.max,
+		"keysScanned":    keysScanned,
+		"modelsReturned": modelsReturned,
+		"durationMs":     duration.Milliseconds(),
+		"hints":          q.hints(keysScanned, modelsReturned, -1),
+	}).Warn("slow database query")
+}
@@ -1,10 +1,13 @@
 package db
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -135,6 +138,101 @@ func TestQueryWithPrefix(t *testing.T) {
 	}
 }
 
+func TestQueryRunInChunks(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	col, err := db.NewCollection("people", &testModel{})
+	require.NoError(t, err)
+
+	ageIndex := col.AddIndex("age", func(m Model) []byte {
+		return []byte(fmt.Sprint(m.(*testModel).Age))
+	})
+
+	all := []*testModel{}
+	for i := 0; i < 7; i++ {
+		model := &testModel{
+			Name: "Person_" + strconv.Itoa(i),
+			Age:  i,
+		}
+		require.NoError(t, col.Insert(model))
+		all = append(all, model)
+	}
+
+	var models []*testModel
+	chunks := [][]*testModel{}
+	err = col.NewQuery(ageIndex.All()).RunInChunks(&models, 3, func() error {
+		chunks = append(chunks, models)
+		return nil
+	})
+	require.NoError(t, err)
+	expectedChunks := [][]*testModel{all[0:3], all[3:6], all[6:7]}
+	assert.Equal(t, expectedChunks, chunks)
+
+	// Offset and max are respected.
+	chunks = [][]*testModel{}
+	models = []*testModel{}
+	err = col.NewQuery(ageIndex.All()).Offset(1).Max(4).RunInChunks(&models, 3, func() error {
+		chunks = append(chunks, models)
+		return nil
+	})
+	require.NoError(t, err)
+	expectedChunks = [][]*testModel{all[1:4], all[4:5]}
+	assert.Equal(t, expectedChunks, chunks)
+
+	// Errors returned by handleChunk are returned.
+	expectedErr := errors.New("stop")
+	numCalls := 0
+	models = []*testModel{}
+	err = col.NewQuery(ageIndex.All()).RunInChunks(&models, 2, func() error {
+		numCalls++
+		return expectedErr
+	})
+	assert.Equal(t, expectedErr, err)
+	assert.Equal(t, 1, numCalls)
+}
+
+func TestQueryWithContext(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	col, err := db.NewCollection("people", &testModel{})
+	require.NoError(t, err)
+
+	ageIndex := col.AddIndex("age", func(m Model) []byte {
+		return []byte(fmt.Sprint(m.(*testModel).Age))
+	})
+
+	all := []*testModel{}
+	for i := 0; i < 5; i++ {
+		model := &testModel{
+			Name: "Person_" + strconv.Itoa(i),
+			Age:  i,
+		}
+		require.NoError(t, col.Insert(model))
+		all = append(all, model)
+	}
+
+	// Queries with a context that is not done behave as usual.
+	var actual []*testModel
+	require.NoError(t, col.NewQuery(ageIndex.All()).Context(context.Background()).Offset(1).Run(&actual))
+	assert.Equal(t, all[1:], actual)
+
+	// Queries with a context that is done return its error.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	actual = []*testModel{}
+	err = col.NewQuery(ageIndex.All()).Context(ctx).Offset(1).Run(&actual)
+	assert.Equal(t, context.Canceled, err)
+	assert.Empty(t, actual)
+	err = col.NewQuery(ageIndex.All()).Context(ctx).Reverse().Run(&actual)
+	assert.Equal(t, context.Canceled, err)
+	_, err = col.NewQuery(ageIndex.All()).Context(ctx).Count()
+	assert.Equal(t, context.Canceled, err)
+	err = col.NewQuery(ageIndex.All()).Context(ctx).RunInChunks(&actual, 2, func() error {
+		return nil
+	})
+	assert.Equal(t, context.Canceled, err)
+}
+
 func TestFindWithValueWithMultiIndex(t *testing.T) {
 	db := newTestDB(t)
 	defer db.Close()
@@ -325,3 +423,94 @@ func reverseSlice(s []*testModel) []*testModel {
 	}
 	return reversed
 }
+
+func TestQueryExplain(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	col, err := db.NewCollection("people", &testModel{})
+	require.NoError(t, err)
+
+	ageIndex := col.AddIndex("age", func(m Model) []byte {
+		return []byte(fmt.Sprint(m.(*testModel).Age))
+	})
+	for i := 0; i < 10; i++ {
+		model := &testModel{
+			Name: "Person_" + strconv.Itoa(i),
+			Age:  i,
+		}
+		require.NoError(t, col.Insert(model))
+	}
+
+	plan, err := col.NewQuery(ageIndex.RangeFilter([]byte("2"), []byte("8"))).Offset(2).Max(3).Explain()
+	require.NoError(t, err)
+	assert.Equal(t, "people", plan.Collection)
+	assert.Equal(t, "age", plan.Index)
+	assert.Equal(t, `"2"`, plan.Start)
+	assert.Equal(t, `"8"`, plan.Limit)
+	assert.Equal(t, 2, plan.Offset)
+	assert.Equal(t, 3, plan.Max)
+	assert.Equal(t, 5, plan.KeysScanned)
+	assert.Equal(t, 3, plan.ModelsMatched)
+	assert.Equal(t, 10, plan.TotalModels)
+	assert.Empty(t, plan.Hints)
+
+	// Explain should not change the results of the query.
+	var actual []*testModel
+	require.NoError(t, col.NewQuery(ageIndex.RangeFilter([]byte("2"), []byte("8"))).Offset(2).Max(3).Run(&actual))
+	assert.Len(t, actual, 3)
+}
+
+func TestQueryExplainHints(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	col, err := db.NewCollection("people", &testModel{})
+	require.NoError(t, err)
+
+	nicknameIndex := col.AddMultiIndex("nicknames", func(m Model) [][]byte {
+		person := m.(*testModel)
+		indexValues := make([][]byte, len(person.Nicknames))
+		for i, nickname := range person.Nicknames {
+			indexValues[i] = []byte(nickname)
+		}
+		return indexValues
+	})
+	for i := 0; i < 5; i++ {
+		model := &testModel{
+			Name:      "Person_" + strconv.Itoa(i),
+			Nicknames: []string{"a" + strconv.Itoa(i), "b" + strconv.Itoa(i), "c" + strconv.Itoa(i)},
+		}
+		require.NoError(t, col.Insert(model))
+	}
+
+	plan, err := col.NewQuery(nicknameIndex.All()).Explain()
+	require.NoError(t, err)
+	assert.Equal(t, 15, plan.KeysScanned)
+	assert.Equal(t, 5, plan.ModelsMatched)
+	assert.Equal(t, `""`, plan.Start)
+	assert.Equal(t, "", plan.Limit)
+	require.Len(t, plan.Hints, 1)
+	assert.Contains(t, plan.Hints[0], "many values per model")
+}
+
+func TestQueryWithSlowQueryLog(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	// Log every query.
+	db.SetSlowQueryThreshold(time.Nanosecond)
+	col, err := db.NewCollection("people", &testModel{})
+	require.NoError(t, err)
+
+	ageIndex := col.AddIndex("age", func(m Model) []byte {
+		return []byte(fmt.Sprint(m.(*testModel).Age))
+	})
+	expected := []*testModel{}
+	for i := 0; i < 5; i++ {
+		model := &testModel{
+			Name: "Person_" + strconv.Itoa(i),
+			Age:  i,
+		}
+		require.NoError(t, col.Insert(model))
+		expected = append(expected, model)
+	}
+	testQueryWithFilter(t, col, ageIndex.All(), expected)
+}
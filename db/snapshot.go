@@ -5,7 +5,17 @@ import (
 )
 
 // Snapshot is a frozen, read-only snapshot of a DB state at a particular point
-// in time.
+// in time. Writes are applied atomically (see Collection.Insert and
+// Transaction), so a snapshot never contains half of a write.
+//
+// Every query and FindAll call sees the collection at a single point in time,
+// even if it is run on the Collection itself and the collection is written to
+// while it runs: no model is returned twice or skipped because it was inserted,
+// updated or deleted concurrently, and the returned models match the filter as
+// of that point in time. Separate calls can see different points in time,
+// though. Iterations which span several calls (e.g. paginating through a
+// collection with Offset) must run all of them on the same Snapshot to get the
+// same guarantees.
 type Snapshot struct {
 	colInfo  *colInfo
 	snapshot *leveldb.Snapshot
@@ -25,6 +35,30 @@ func (c *Collection) GetSnapshot() (*Snapshot, error) {
 	}, nil
 }
 
+// snapshotReader is implemented by readers which can take a snapshot of
+// themselves, i.e. the underlying *leveldb.DB. Snapshots and transactions
+// already read from a single point in time.
+type snapshotReader interface {
+	GetSnapshot() (*leveldb.Snapshot, error)
+}
+
+// pointInTimeReader returns a reader which sees the same state as the given
+// reader at the time it is called. Iterators over a *leveldb.DB see a single
+// point in time as well, but models are looked up with Get, which would see
+// any writes that happened since the iterator was created. The returned
+// function must be called once the reader is no longer used.
+func pointInTimeReader(reader dbReader) (dbReader, func(), error) {
+	snapshotReader, ok := reader.(snapshotReader)
+	if !ok {
+		return reader, func() {}, nil
+	}
+	snapshot, err := snapshotReader.GetSnapshot()
+	if err != nil {
+		return nil, nil, err
+	}
+	return snapshot, snapshot.Release, nil
+}
+
 // Release releases the snapshot. This will not release any ongoing queries,
 // which will still finish unless the database is closed. Other methods should
 // not be called after the snapshot has been released.
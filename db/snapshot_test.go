@@ -2,7 +2,9 @@ package db
 
 import (
 	"fmt"
+	"math/rand"
 	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -67,3 +69,143 @@ func TestSnapshot(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, len(expected), actualCount)
 }
+
+// pointInTimeTestModels is the number of models in the collection used by the
+// tests which iterate under write load.
+const pointInTimeTestModels = 50
+
+// newPointInTimeTestCollection creates a collection with pointInTimeTestModels
+// models and starts a goroutine which keeps replacing and updating them until
+// the returned function is called. The number of models never changes, and
+// every model has an age of 0 or 1.
+func newPointInTimeTestCollection(t *testing.T, db *DB) (*Collection, *Index, func()) {
+	col, err := db.NewCollection("people", &testModel{})
+	require.NoError(t, err)
+	ageIndex := col.AddIndex("age", func(m Model) []byte {
+		return []byte(fmt.Sprint(m.(*testModel).Age))
+	})
+	names := make([]string, pointInTimeTestModels)
+	for i := range names {
+		names[i] = fmt.Sprintf("person_%d_0", i)
+		require.NoError(t, col.Insert(&testModel{Name: names[i], Age: i % 2}))
+	}
+
+	stop := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for generation := 1; ; generation++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			// Atomically replace one model with a new one...
+			i := rand.Intn(pointInTimeTestModels)
+			txn := col.OpenTransaction()
+			if !assert.NoError(t, txn.Delete([]byte(names[i]))) {
+				_ = txn.Discard()
+				return
+			}
+			names[i] = fmt.Sprintf("person_%d_%d", i, generation)
+			if !assert.NoError(t, txn.Insert(&testModel{Name: names[i], Age: rand.Intn(2)})) {
+				_ = txn.Discard()
+				return
+			}
+			if !assert.NoError(t, txn.Commit()) {
+				return
+			}
+			// ...and move another one to the other end of the age index.
+			j := rand.Intn(pointInTimeTestModels)
+			if !assert.NoError(t, col.Update(&testModel{Name: names[j], Age: generation % 2})) {
+				return
+			}
+		}
+	}()
+	return col, ageIndex, func() {
+		close(stop)
+		wg.Wait()
+	}
+}
+
+// assertPointInTimeResults checks that models could have been read at a single
+// point in time by the tests which iterate under write load.
+func assertPointInTimeResults(t *testing.T, models []*testModel) {
+	require.Len(t, models, pointInTimeTestModels)
+	seen := map[string]bool{}
+	for i, model := range models {
+		assert.False(t, seen[model.Name], "duplicated model: %s", model.Name)
+		seen[model.Name] = true
+		if i > 0 {
+			assert.True(t, models[i-1].Age <= model.Age, "models are not sorted by age")
+		}
+	}
+}
+
+func TestQueryIsPointInTimeUnderWriteLoad(t *testing.T) {
+	t.Parallel()
+	db := newTestDB(t)
+	defer db.Close()
+	col, ageIndex, stopWriting := newPointInTimeTestCollection(t, db)
+	defer stopWriting()
+
+	for i := 0; i < 100; i++ {
+		var models []*testModel
+		require.NoError(t, col.NewQuery(ageIndex.All()).Run(&models))
+		assertPointInTimeResults(t, models)
+
+		var olderModels []*testModel
+		require.NoError(t, col.NewQuery(ageIndex.ValueFilter([]byte("1"))).Run(&olderModels))
+		for _, model := range olderModels {
+			assert.Equal(t, 1, model.Age, "model does not match the filter: %s", model.Name)
+		}
+
+		var allModels []*testModel
+		require.NoError(t, col.FindAll(&allModels))
+		assert.Len(t, allModels, pointInTimeTestModels)
+	}
+}
+
+func TestRunInChunksIsPointInTimeUnderWriteLoad(t *testing.T) {
+	t.Parallel()
+	db := newTestDB(t)
+	defer db.Close()
+	col, ageIndex, stopWriting := newPointInTimeTestCollection(t, db)
+	defer stopWriting()
+
+	for i := 0; i < 100; i++ {
+		var chunk []*testModel
+		var models []*testModel
+		require.NoError(t, col.NewQuery(ageIndex.All()).RunInChunks(&chunk, 7, func() error {
+			models = append(models, chunk...)
+			return nil
+		}))
+		assertPointInTimeResults(t, models)
+	}
+}
+
+func TestSnapshotPaginationUnderWriteLoad(t *testing.T) {
+	t.Parallel()
+	db := newTestDB(t)
+	defer db.Close()
+	col, ageIndex, stopWriting := newPointInTimeTestCollection(t, db)
+	defer stopWriting()
+
+	const perPage = 7
+	for i := 0; i < 20; i++ {
+		snapshot, err := col.GetSnapshot()
+		require.NoError(t, err)
+		var models []*testModel
+		for page := 0; ; page++ {
+			var pageModels []*testModel
+			require.NoError(t, snapshot.NewQuery(ageIndex.All()).Offset(page*perPage).Max(perPage).Run(&pageModels))
+			if len(pageModels) == 0 {
+				break
+			}
+			models = append(models, pageModels...)
+		}
+		snapshot.Release()
+		assertPointInTimeResults(t, models)
+	}
+}
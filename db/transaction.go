@@ -139,6 +139,22 @@ func (txn *Transaction) Discard() error {
 	return nil
 }
 
+// FindByID finds the model with the given ID and scans the results into the
+// given model. Like Collection.FindByID, it doesn't see the operations queued
+// in the transaction. However, since no other state changes can be made to the
+// collection while the transaction is open, the model can't change until the
+// transaction is closed, so it can safely be modified and updated within the
+// transaction. It returns a NotFoundError if there is no model with the given
+// ID.
+func (txn *Transaction) FindByID(id []byte, model Model) error {
+	txn.mut.Lock()
+	defer txn.mut.Unlock()
+	if err := txn.unsafeCheckState(); err != nil {
+		return err
+	}
+	return findByID(txn.colInfo, txn.readWriter, id, model)
+}
+
 // Insert queues an operation to insert the given model into the database. It
 // returns an error if a model with the same id already exists. The model will
 // not actually be inserted until the transaction is committed.
@@ -387,3 +387,36 @@ func TestTransactionInsertThenUpdateSameModel(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, ConflictingOperationsError{operation: "update"}, err, "wrong error")
 }
+
+func TestTransactionFindByID(t *testing.T) {
+	t.Parallel()
+	db := newTestDB(t)
+	defer db.Close()
+	col, err := db.NewCollection("people", &testModel{})
+	require.NoError(t, err)
+	model := &testModel{
+		Name: "ExpectedPerson",
+		Age:  42,
+	}
+
+	txn := col.OpenTransaction()
+	defer func() {
+		err := txn.Discard()
+		if err != nil && err != ErrCommitted {
+			t.Error(err)
+		}
+	}()
+	var found testModel
+	assert.Equal(t, NotFoundError{ID: model.ID()}, txn.FindByID(model.ID(), &found))
+
+	// Operations queued in the transaction are not visible until it is
+	// committed.
+	require.NoError(t, txn.Insert(model))
+	assert.Equal(t, NotFoundError{ID: model.ID()}, txn.FindByID(model.ID(), &found))
+	require.NoError(t, txn.Commit())
+	assert.Equal(t, ErrCommitted, txn.FindByID(model.ID(), &found))
+
+	txn = col.OpenTransaction()
+	require.NoError(t, txn.FindByID(model.ID(), &found))
+	assert.Equal(t, model, &found)
+}
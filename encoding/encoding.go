@@ -3,33 +3,133 @@ package encoding
 import (
 	"encoding/json"
 	"fmt"
+	"math/big"
 
 	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Message types
+const (
+	// MessageTypeOrder is the type of messages which contain an order.
+	MessageTypeOrder = "order"
+	// MessageTypeOrderUpdateHint is the type of messages which contain an
+	// OrderUpdateHint.
+	MessageTypeOrderUpdateHint = "orderUpdateHint"
 )
 
 type orderMessage struct {
-	MessageType string              `json:"messageType"`
-	Order       *zeroex.SignedOrder `json:"order"`
-	Topics      []string            `json:"topics"`
+	MessageType string `json:"messageType"`
+	// Order is encoded in the wire format (see zeroex.MarshalSignedOrderWire).
+	Order  json.RawMessage `json:"order"`
+	Topics []string        `json:"topics"`
+}
+
+// OrderUpdateHint is a hint that the state of some orders changed (e.g.
+// because they were filled or cancelled) at the given block. It is only a
+// hint: receivers must re-validate the orders on-chain before acting on it.
+type OrderUpdateHint struct {
+	OrderHashes []common.Hash
+	BlockNumber *big.Int
+}
+
+type orderUpdateHintMessage struct {
+	MessageType string        `json:"messageType"`
+	OrderHashes []common.Hash `json:"orderHashes"`
+	BlockNumber string        `json:"blockNumber"`
+	Topics      []string      `json:"topics"`
 }
 
 // OrderToRawMessage encodes an order into an order message to be sent over the wire
 func OrderToRawMessage(topic string, order *zeroex.SignedOrder) ([]byte, error) {
+	encodedOrder, err := zeroex.MarshalSignedOrderWire(order)
+	if err != nil {
+		return nil, err
+	}
 	return json.Marshal(orderMessage{
-		MessageType: "order",
-		Order:       order,
+		MessageType: MessageTypeOrder,
+		Order:       encodedOrder,
 		Topics:      []string{topic},
 	})
 }
 
-// RawMessageToOrder decodes an order message sent over the wire into an order
+// RawMessageToOrder decodes an order message sent over the wire into an order.
+// The order is parsed strictly (see zeroex.UnmarshalSignedOrderWire), so that
+// every node which accepts the message decodes it into the same order.
 func RawMessageToOrder(data []byte) (*zeroex.SignedOrder, error) {
 	var orderMessage orderMessage
 	if err := json.Unmarshal(data, &orderMessage); err != nil {
 		return nil, err
 	}
-	if orderMessage.MessageType != "order" {
+	if orderMessage.MessageType != MessageTypeOrder {
 		return nil, fmt.Errorf("unexpected message type: %q", orderMessage.MessageType)
 	}
-	return orderMessage.Order, nil
+	return zeroex.UnmarshalSignedOrderWire(orderMessage.Order)
+}
+
+// TranslateLegacyOrderMessage decodes an order message which was sent on the
+// topic of a previous topic version and encodes it as an order message for the
+// given topic. Unlike RawMessageToOrder, it accepts orders which are not
+// encoded strictly according to the wire format, since nodes running a
+// previous version of Mesh don't use it. It returns an error if the order
+// cannot be decoded or cannot be encoded in the wire format.
+func TranslateLegacyOrderMessage(data []byte, topic string) ([]byte, error) {
+	var orderMessage orderMessage
+	if err := json.Unmarshal(data, &orderMessage); err != nil {
+		return nil, err
+	}
+	if orderMessage.MessageType != MessageTypeOrder {
+		return nil, fmt.Errorf("unexpected message type: %q", orderMessage.MessageType)
+	}
+	var order zeroex.SignedOrder
+	if err := order.UnmarshalJSON(orderMessage.Order); err != nil {
+		return nil, err
+	}
+	return OrderToRawMessage(topic, &order)
+}
+
+// OrderUpdateHintToRawMessage encodes an order update hint into a message to
+// be sent over the wire
+func OrderUpdateHintToRawMessage(topic string, hint *OrderUpdateHint) ([]byte, error) {
+	if hint.BlockNumber == nil {
+		return nil, fmt.Errorf("order update hint is missing the block number")
+	}
+	return json.Marshal(orderUpdateHintMessage{
+		MessageType: MessageTypeOrderUpdateHint,
+		OrderHashes: hint.OrderHashes,
+		BlockNumber: hint.BlockNumber.String(),
+		Topics:      []string{topic},
+	})
+}
+
+// RawMessageToOrderUpdateHint decodes an order update hint message sent over
+// the wire into an order update hint
+func RawMessageToOrderUpdateHint(data []byte) (*OrderUpdateHint, error) {
+	var hintMessage orderUpdateHintMessage
+	if err := json.Unmarshal(data, &hintMessage); err != nil {
+		return nil, err
+	}
+	if hintMessage.MessageType != MessageTypeOrderUpdateHint {
+		return nil, fmt.Errorf("unexpected message type: %q", hintMessage.MessageType)
+	}
+	blockNumber, ok := new(big.Int).SetString(hintMessage.BlockNumber, 10)
+	if !ok || blockNumber.Sign() < 0 {
+		return nil, fmt.Errorf("invalid block number in order update hint: %q", hintMessage.BlockNumber)
+	}
+	return &OrderUpdateHint{
+		OrderHashes: hintMessage.OrderHashes,
+		BlockNumber: blockNumber,
+	}, nil
+}
+
+// RawMessageType returns the type of a message sent over the wire (e.g.
+// MessageTypeOrder)
+func RawMessageType(data []byte) (string, error) {
+	var message struct {
+		MessageType string `json:"messageType"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil {
+		return "", err
+	}
+	return message.MessageType, nil
 }
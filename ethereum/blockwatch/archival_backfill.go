@@ -0,0 +1,117 @@
+package blockwatch
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/0xProject/0x-mesh/ethereum/miniheader"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// archivalBackfillBlocksPerQuery is the number of blocks to fetch logs for in a
+// single query during an archival backfill. It is much larger than
+// maxBlocksInGetLogsQuery because backfills are usually restricted to a few
+// rare topics. Queries which return too many logs for Infura are split up (see
+// filterLogsWithTopics).
+var archivalBackfillBlocksPerQuery = 2000
+
+// ArchivalBackfillHandler is called by ArchivalBackfill with the block events
+// for a range of historical blocks, in ascending block order.
+type ArchivalBackfillHandler func(events []*Event) error
+
+// ArchivalBackfill replays the historical blocks from fromBlock to toBlock
+// (inclusive). It fetches the logs which match any of the given topics (or the
+// topics of the Watcher if none are given) and passes them to handler as Added
+// events, one range of blocks at a time. Only blocks which contain matching
+// logs are passed to handler.
+//
+// Unlike FastSyncToLatestBlock, ArchivalBackfill doesn't modify the retained
+// blocks and doesn't emit any events to subscribers, so it can safely be called
+// while the Watcher is watching. Depending on the range, the Ethereum RPC
+// provider might have to be an archive node.
+func (w *Watcher) ArchivalBackfill(ctx context.Context, fromBlock, toBlock int, topics []common.Hash, handler ArchivalBackfillHandler) error {
+	if fromBlock < 0 || toBlock < fromBlock {
+		return fmt.Errorf("invalid archival backfill block range: %d to %d", fromBlock, toBlock)
+	}
+	if len(topics) == 0 {
+		topics = w.topics
+	}
+	for from := fromBlock; from <= toBlock; from += archivalBackfillBlocksPerQuery {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		to := from + archivalBackfillBlocksPerQuery - 1
+		if to > toBlock {
+			to = toBlock
+		}
+		logs, err := w.filterLogsWithTopics(from, to, topics, []types.Log{})
+		if err != nil {
+			return err
+		}
+		events, err := w.logsToBackfillEvents(logs)
+		if err != nil {
+			return err
+		}
+		if len(events) > 0 {
+			if err := handler(events); err != nil {
+				return err
+			}
+		}
+		log.WithFields(map[string]interface{}{
+			"fromBlock": from,
+			"toBlock":   to,
+			"numLogs":   len(logs),
+		}).Debug("archival backfill processed block range")
+	}
+	return nil
+}
+
+// logsToBackfillEvents groups the given logs by block and returns an Added
+// event for each block, sorted by block number. The logs of each block are
+// sorted by log index.
+func (w *Watcher) logsToBackfillEvents(logs []types.Log) ([]*Event, error) {
+	hashToBlockHeader := map[common.Hash]*miniheader.MiniHeader{}
+	for _, log := range logs {
+		if log.Removed {
+			continue
+		}
+		blockHeader, ok := hashToBlockHeader[log.BlockHash]
+		if !ok {
+			blockNumber := big.NewInt(0).SetUint64(log.BlockNumber)
+			header, err := w.client.HeaderByNumber(blockNumber)
+			if err != nil {
+				return nil, err
+			}
+			blockHeader = &miniheader.MiniHeader{
+				Hash:      log.BlockHash,
+				Parent:    header.Parent,
+				Number:    blockNumber,
+				Logs:      []types.Log{},
+				Timestamp: header.Timestamp,
+			}
+			hashToBlockHeader[log.BlockHash] = blockHeader
+		}
+		blockHeader.Logs = append(blockHeader.Logs, log)
+	}
+	events := make([]*Event, 0, len(hashToBlockHeader))
+	for _, blockHeader := range hashToBlockHeader {
+		logs := blockHeader.Logs
+		sort.Slice(logs, func(i, j int) bool {
+			return logs[i].Index < logs[j].Index
+		})
+		events = append(events, &Event{
+			Type:        Added,
+			BlockHeader: blockHeader,
+		})
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].BlockHeader.Number.Cmp(events[j].BlockHeader.Number) < 0
+	})
+	return events, nil
+}
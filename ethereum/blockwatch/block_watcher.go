@@ -9,12 +9,14 @@ import (
 	"sync"
 	"time"
 
+	"github.com/0xProject/0x-mesh/common/supervisor"
 	"github.com/0xProject/0x-mesh/constants"
 	"github.com/0xProject/0x-mesh/ethereum/miniheader"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/jpillora/backoff"
 	log "github.com/sirupsen/logrus"
 	"github.com/syndtr/goleveldb/leveldb"
 )
@@ -80,9 +82,19 @@ func (e TooMayBlocksBehindError) Error() string {
 type Config struct {
 	Stack           Stack
 	PollingInterval time.Duration
-	WithLogs        bool
-	Topics          []common.Hash
-	Client          Client
+	// MinPollingInterval and MaxPollingInterval enable adaptive polling if
+	// MaxPollingInterval is not 0. The Watcher then starts polling at
+	// PollingInterval and adjusts the interval to half of the average interval
+	// between the blocks it observes, bounded by MinPollingInterval and
+	// MaxPollingInterval.
+	MinPollingInterval time.Duration
+	MaxPollingInterval time.Duration
+	WithLogs           bool
+	Topics             []common.Hash
+	Client             Client
+	// ChainProfile determines which block the Watcher syncs to. If it is the
+	// zero value, the Watcher syncs to the latest block.
+	ChainProfile ChainProfile
 }
 
 // Watcher maintains a consistent representation of the latest X blocks (where X is enforced by the
@@ -94,21 +106,33 @@ type Watcher struct {
 	blockFeed           event.Feed
 	blockScope          event.SubscriptionScope // Subscription scope tracking current live listeners
 	wasStartedOnce      bool                    // Whether the block watcher has previously been started
-	pollingInterval     time.Duration
+	pollingInterval     *pollingIntervalTracker
 	withLogs            bool
 	topics              []common.Hash
 	mu                  sync.RWMutex
 	syncToLatestBlockMu sync.Mutex
+	health              healthTracker
+	recoveryFeed        event.Feed
+	chainProfile        ChainProfile
+	// headTagUnsupported is set once the Ethereum RPC provider has rejected
+	// chainProfile.HeadTag, after which we only use the confirmation depth.
+	headTagUnsupported bool
+	// latestChainHeadNumber is the number of the most recent chain head
+	// returned by ChainHead, or -1 if it hasn't been fetched yet.
+	latestChainHeadNumber int64
 }
 
 // New creates a new Watcher instance.
 func New(config Config) *Watcher {
 	return &Watcher{
-		pollingInterval: config.PollingInterval,
+		pollingInterval: newPollingIntervalTracker(config),
 		stack:           config.Stack,
 		client:          config.Client,
 		withLogs:        config.WithLogs,
 		topics:          config.Topics,
+		chainProfile:    config.ChainProfile,
+		// The chain head is unknown until it is fetched for the first time.
+		latestChainHeadNumber: -1,
 	}
 }
 
@@ -135,7 +159,7 @@ func (w *Watcher) FastSyncToLatestBlock(ctx context.Context) (blocksElapsed int,
 		return 0, nil
 	}
 
-	latestBlock, err := w.client.HeaderByNumber(nil)
+	latestBlock, err := w.ChainHead()
 	if err != nil {
 		return 0, err
 	}
@@ -165,8 +189,12 @@ func (w *Watcher) FastSyncToLatestBlock(ctx context.Context) (blocksElapsed int,
 
 // Watch starts the Watcher. It will continuously look for new blocks and blocks
 // until there is a critical error or the given context is canceled. Typically,
-// you want to call Watch inside a goroutine. For non-critical errors, callers
-// must receive them from the Errors channel.
+// you want to call Watch inside a goroutine. Non-critical errors are logged and
+// cause the Watcher to back off exponentially (with jitter) before polling
+// again. After circuitBreakerThreshold consecutive errors the circuit breaker
+// opens (see Health) and a RecoveryEvent is emitted once the Watcher is able
+// to sync again. If polling panics, the Watcher is restarted with exponential
+// backoff (see supervisor.Run).
 func (w *Watcher) Watch(ctx context.Context) error {
 	w.mu.Lock()
 	if w.wasStartedOnce {
@@ -176,55 +204,115 @@ func (w *Watcher) Watch(ctx context.Context) error {
 	w.wasStartedOnce = true
 	w.mu.Unlock()
 
+	return supervisor.Run(ctx, supervisor.Config{Name: "blockwatch"}, w.pollLoop)
+}
+
+// pollLoop continuously syncs to the latest block until there is a critical
+// error or the given context is canceled.
+func (w *Watcher) pollLoop(ctx context.Context) error {
+	retryBackoff := &backoff.Backoff{
+		Min:    w.pollingInterval.get(),
+		Max:    maxPollingBackoff,
+		Factor: 2,
+		Jitter: true,
+	}
+
 	// Sync immediately when `Watch()` is called instead of waiting for the
-	// first Ticker tick
-	if err := w.SyncToLatestBlock(); err != nil {
-		if err == leveldb.ErrClosed {
-			// We can't continue if the database is closed. Stop the watcher and
-			// return an error.
-			return err
+	// first polling interval to elapse.
+	isFirstSync := true
+	for {
+		if err := w.syncToLatestBlockAndUpdateHealth(); err != nil {
+			if err == leveldb.ErrClosed {
+				// We can't continue if the database is closed. Stop the watcher and
+				// return an error.
+				return err
+			}
+			if _, ok := err.(TooMayBlocksBehindError); ok && !isFirstSync {
+				// We've fallen too many blocks behind to sync to the latest block.
+				// We'd need to start again from the latest block but also require
+				// the OrderWatcher to re-validate all orders at the latest block.
+				// By returning an error here, we cause Mesh to gracefully shut down.
+				// Upon re-booting, it will reset the blocks stored in the DB and
+				// re-validate all orders stored.
+				return err
+			}
 		}
+		isFirstSync = false
+
+		delay := w.pollingInterval.get()
+		if w.health.isFailing() {
+			delay = retryBackoff.Duration()
+		} else {
+			retryBackoff.Reset()
+			if latestHeader, err := w.stack.Peek(); err == nil {
+				w.pollingInterval.observe(latestHeader)
+				delay = w.pollingInterval.get()
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+	}
+}
+
+// syncToLatestBlockAndUpdateHealth calls syncToLatestBlock, logs any error
+// and updates the circuit breaker state accordingly.
+func (w *Watcher) syncToLatestBlockAndUpdateHealth() error {
+	blocksAdded, err := w.syncToLatestBlock()
+	if err != nil {
 		logMessage := "blockwatch.Watcher error encountered"
 		if isWarning(err) {
 			log.WithError(err).Warn(logMessage)
 		} else {
 			log.WithError(err).Error(logMessage)
 		}
-	}
-
-	ticker := time.NewTicker(w.pollingInterval)
-	for {
-		select {
-		case <-ctx.Done():
-			ticker.Stop()
-			return nil
-		case <-ticker.C:
-			if err := w.SyncToLatestBlock(); err != nil {
-				if err == leveldb.ErrClosed {
-					// We can't continue if the database is closed. Stop the watcher and
-					// return an error.
-					ticker.Stop()
-					return err
-				}
-				if _, ok := err.(TooMayBlocksBehindError); ok {
-					// We've fallen too many blocks behind to sync to the latest block.
-					// We'd need to start again from the latest block but also require
-					// the OrderWatcher to re-validate all orders at the latest block.
-					// By returning an error here, we cause Mesh to gracefully shut down.
-					// Upon re-booting, it will reset the blocks stored in the DB and
-					// re-validate all orders stored.
-					ticker.Stop()
-					return err
-				}
-				logMessage := "blockwatch.Watcher error encountered"
-				if isWarning(err) {
-					log.WithError(err).Warn(logMessage)
-				} else {
-					log.WithError(err).Error(logMessage)
-				}
+		if _, ok := err.(InconsistentHeaderError); ok {
+			if first := w.health.recordInconsistentHeader(); first {
+				log.WithError(err).Error("Ethereum RPC provider served a block header which is inconsistent with the chain; it may be malfunctioning or malicious and should be replaced")
 			}
 		}
+		if opened := w.health.recordFailure(err); opened {
+			log.WithFields(log.Fields{
+				"error":               err.Error(),
+				"consecutiveFailures": circuitBreakerThreshold,
+			}).Warn("Ethereum RPC provider is failing repeatedly; backing off")
+		}
+		return err
+	}
+	if recovered, downtime := w.health.recordSuccess(); recovered {
+		recoveryEvent := &RecoveryEvent{
+			Downtime:         downtime,
+			BlocksBackfilled: blocksAdded,
+		}
+		log.WithFields(log.Fields{
+			"downtime":         downtime.String(),
+			"blocksBackfilled": blocksAdded,
+		}).Info("Ethereum RPC provider recovered")
+		w.recoveryFeed.Send(recoveryEvent)
 	}
+	return nil
+}
+
+// Health returns the current health of the Watcher's Ethereum RPC provider as
+// observed by the polling loop.
+func (w *Watcher) Health() Health {
+	return w.health.get()
+}
+
+// PollingInterval returns the interval at which the Watcher currently polls
+// for new blocks. It only changes if adaptive polling is enabled (see
+// Config.MaxPollingInterval).
+func (w *Watcher) PollingInterval() time.Duration {
+	return w.pollingInterval.get()
+}
+
+// SubscribeToRecovery allows one to subscribe to the RecoveryEvents emitted by
+// the Watcher whenever it recovers from repeated provider failures. To
+// unsubscribe, simply call `Unsubscribe` on the returned subscription.
+func (w *Watcher) SubscribeToRecovery(sink chan<- *RecoveryEvent) event.Subscription {
+	return w.blockScope.Track(w.recoveryFeed.Subscribe(sink))
 }
 
 // Subscribe allows one to subscribe to the block events emitted by the Watcher.
@@ -238,22 +326,43 @@ func (w *Watcher) Subscribe(sink chan<- []*Event) event.Subscription {
 // SyncToLatestBlock syncs our local state of the chain to the latest block found via
 // Ethereum RPC
 func (w *Watcher) SyncToLatestBlock() error {
+	_, err := w.syncToLatestBlock()
+	return err
+}
+
+// ResetInconsistentHeaders resets Health.InconsistentHeaders. It should be
+// called once the client was pointed to a different Ethereum RPC endpoint.
+func (w *Watcher) ResetInconsistentHeaders() {
+	w.health.resetInconsistentHeaders()
+}
+
+// SyncNow immediately syncs our local state of the chain to the latest block
+// like a regular poll would, continuing from the latest retained block, and
+// updates Health accordingly. It is used to resume watching right away after
+// the client was pointed to a different Ethereum RPC endpoint.
+func (w *Watcher) SyncNow() error {
+	return w.syncToLatestBlockAndUpdateHealth()
+}
+
+// syncToLatestBlock is like SyncToLatestBlock but also returns the number of
+// blocks that were added.
+func (w *Watcher) syncToLatestBlock() (blocksAdded int, err error) {
 	w.syncToLatestBlockMu.Lock()
 	defer w.syncToLatestBlockMu.Unlock()
 
 	checkpointID, err := w.stack.Checkpoint()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	latestHeader, err := w.client.HeaderByNumber(nil)
+	latestHeader, err := w.ChainHead()
 	if err != nil {
-		return err
+		return 0, err
 	}
 	latestBlockNumber := latestHeader.Number.Int64()
 	lastStoredHeader, err := w.stack.Peek()
 	if err != nil {
-		return err
+		return 0, err
 	}
 	var lastStoredBlockNumber int64
 	if lastStoredHeader != nil {
@@ -267,13 +376,13 @@ func (w *Watcher) SyncToLatestBlock() error {
 	} else {
 		// Noop if already caught up or ahead of latest block returned from Ethereum node
 		if latestBlockNumber <= lastStoredBlockNumber {
-			return nil
+			return 0, nil
 		}
 		numBlocksToFetch = int(latestBlockNumber - lastStoredBlockNumber)
 	}
 
 	if numBlocksToFetch >= constants.MaxBlocksStoredInNonArchiveNode {
-		return TooMayBlocksBehindError{
+		return 0, TooMayBlocksBehindError{
 			blocksMissing: numBlocksToFetch,
 		}
 	}
@@ -300,6 +409,13 @@ func (w *Watcher) SyncToLatestBlock() error {
 				syncErr = err
 				break
 			}
+			if nextHeader.Number.Cmp(nextBlockNumber) != 0 {
+				syncErr = InconsistentHeaderError{
+					Header: nextHeader,
+					Reason: fmt.Sprintf("requested block number %s", nextBlockNumber),
+				}
+				break
+			}
 		}
 
 		var events []*Event
@@ -311,21 +427,114 @@ func (w *Watcher) SyncToLatestBlock() error {
 		}
 	}
 	if len(allEvents) == 0 {
-		return syncErr
+		return 0, syncErr
+	}
+	if _, ok := syncErr.(InconsistentHeaderError); ok {
+		// None of the blocks fetched from a provider which served an
+		// inconsistent header can be trusted.
+		if err := w.stack.Reset(checkpointID); err != nil {
+			return 0, err
+		}
+		return 0, syncErr
 	}
 	if w.shouldRevertChanges(lastStoredHeader, allEvents) {
 		if err := w.stack.Reset(checkpointID); err != nil {
-			return err
+			return 0, err
 		}
 	} else {
 		_, err = w.stack.Checkpoint()
 		if err != nil {
-			return err
+			return 0, err
 		}
 		w.blockFeed.Send(allEvents)
+		for _, event := range allEvents {
+			if event.Type == Added {
+				blocksAdded++
+			}
+		}
 	}
 
-	return syncErr
+	return blocksAdded, syncErr
+}
+
+// BlocksBehind returns the number of blocks between the latest block stored by
+// the Watcher and the most recent chain head it has seen (see ChainHead). It is
+// greater than 0 while the Watcher is catching up to the chain, e.g. after
+// Mesh was offline. If the chain head hasn't been fetched yet, it returns 0.
+func (w *Watcher) BlocksBehind() (int, error) {
+	w.mu.RLock()
+	latestChainHeadNumber := w.latestChainHeadNumber
+	w.mu.RUnlock()
+	if latestChainHeadNumber == -1 {
+		return 0, nil
+	}
+	latestStoredHeader, err := w.stack.Peek()
+	if err != nil {
+		return 0, err
+	}
+	if latestStoredHeader == nil {
+		return 0, nil
+	}
+	blocksBehind := latestChainHeadNumber - latestStoredHeader.Number.Int64()
+	if blocksBehind < 0 {
+		return 0, nil
+	}
+	return int(blocksBehind), nil
+}
+
+// ChainHead returns the header of the block the Watcher syncs to. This is the
+// latest block unless the ChainProfile specifies a different HeadTag or a
+// ConfirmationDepth, in which case it is the block with that tag (if the
+// Ethereum RPC provider supports it) or the block ConfirmationDepth blocks
+// behind the latest block (if it doesn't).
+func (w *Watcher) ChainHead() (*miniheader.MiniHeader, error) {
+	header, err := w.chainHead()
+	if err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	w.latestChainHeadNumber = header.Number.Int64()
+	w.mu.Unlock()
+	return header, nil
+}
+
+func (w *Watcher) chainHead() (*miniheader.MiniHeader, error) {
+	headTag := w.chainProfile.HeadTag
+	w.mu.RLock()
+	headTagUnsupported := w.headTagUnsupported
+	w.mu.RUnlock()
+	if headTag != "" && headTag != BlockTagLatest && !headTagUnsupported {
+		if taggedClient, ok := w.client.(TaggedHeaderClient); ok {
+			header, err := taggedClient.HeaderByTag(headTag)
+			if err == nil {
+				return header, nil
+			}
+			if !isUnsupportedBlockTagError(err) {
+				return nil, err
+			}
+			log.WithFields(log.Fields{
+				"error":             err.Error(),
+				"headTag":           headTag,
+				"confirmationDepth": w.chainProfile.ConfirmationDepth,
+			}).Warn("Ethereum RPC provider does not support block tag; falling back to confirmation depth")
+			w.mu.Lock()
+			w.headTagUnsupported = true
+			w.mu.Unlock()
+		}
+	}
+
+	latestHeader, err := w.client.HeaderByNumber(nil)
+	if err != nil {
+		return nil, err
+	}
+	if w.chainProfile.ConfirmationDepth <= 0 {
+		return latestHeader, nil
+	}
+	confirmedBlockNumber := big.NewInt(0).Sub(latestHeader.Number, big.NewInt(int64(w.chainProfile.ConfirmationDepth)))
+	if confirmedBlockNumber.Sign() < 0 {
+		confirmedBlockNumber.SetInt64(0)
+	}
+	return w.client.HeaderByNumber(confirmedBlockNumber)
 }
 
 func (w *Watcher) shouldRevertChanges(lastStoredHeader *miniheader.MiniHeader, events []*Event) bool {
@@ -346,6 +555,11 @@ func (w *Watcher) buildCanonicalChain(nextHeader *miniheader.MiniHeader, events
 	}
 	// Is the stack empty or is it the next block?
 	if latestHeader == nil || nextHeader.Parent == latestHeader.Hash {
+		if latestHeader != nil {
+			if err := verifyChildHeader(latestHeader, nextHeader); err != nil {
+				return events, err
+			}
+		}
 		nextHeader, err := w.addLogs(nextHeader)
 		if err != nil {
 			return events, err
@@ -374,6 +588,9 @@ func (w *Watcher) buildCanonicalChain(nextHeader *miniheader.MiniHeader, events
 	if err != nil {
 		return events, err
 	}
+	if err := verifyChildHeader(nextParentHeader, nextHeader); err != nil {
+		return events, err
+	}
 	events, err = w.buildCanonicalChain(nextParentHeader, events)
 	if err != nil {
 		return events, err
@@ -635,19 +852,25 @@ func (w *Watcher) getSubBlockRanges(from, to, rangeSize int) []*blockRange {
 const infuraTooManyResultsErrMsg = "query returned more than 10000 results"
 
 func (w *Watcher) filterLogsRecurisively(from, to int, allLogs []types.Log) ([]types.Log, error) {
+	return w.filterLogsWithTopics(from, to, w.topics, allLogs)
+}
+
+// filterLogsWithTopics is like filterLogsRecurisively but fetches the logs which
+// match any of the given topics instead of the topics of the Watcher.
+func (w *Watcher) filterLogsWithTopics(from, to int, topics []common.Hash, allLogs []types.Log) ([]types.Log, error) {
 	log.WithFields(map[string]interface{}{
 		"from": from,
 		"to":   to,
 	}).Trace("Fetching block logs")
 	numBlocks := to - from
-	topics := [][]common.Hash{}
-	if len(w.topics) > 0 {
-		topics = append(topics, w.topics)
+	topicsFilter := [][]common.Hash{}
+	if len(topics) > 0 {
+		topicsFilter = append(topicsFilter, topics)
 	}
 	logs, err := w.client.FilterLogs(ethereum.FilterQuery{
 		FromBlock: big.NewInt(int64(from)),
 		ToBlock:   big.NewInt(int64(to)),
-		Topics:    topics,
+		Topics:    topicsFilter,
 	})
 	if err != nil {
 		// Infura caps the logs returned to 10,000 per request, if our request exceeds this limit, split it
@@ -671,11 +894,11 @@ func (w *Watcher) filterLogsRecurisively(from, to int, allLogs []types.Log) ([]t
 
 			endFirstHalf := from + firstBatchSize
 			startSecondHalf := endFirstHalf + 1
-			allLogs, err := w.filterLogsRecurisively(from, endFirstHalf, allLogs)
+			allLogs, err := w.filterLogsWithTopics(from, endFirstHalf, topics, allLogs)
 			if err != nil {
 				return nil, err
 			}
-			allLogs, err = w.filterLogsRecurisively(startSecondHalf, to, allLogs)
+			allLogs, err = w.filterLogsWithTopics(startSecondHalf, to, topics, allLogs)
 			if err != nil {
 				return nil, err
 			}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/0xProject/0x-mesh/ethereum/miniheader"
 	"github.com/0xProject/0x-mesh/ethereum/simplestack"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/stretchr/testify/assert"
@@ -534,6 +535,65 @@ func TestGetLogsInBlockRange(t *testing.T) {
 	}
 }
 
+func TestArchivalBackfill(t *testing.T) {
+	originalBlocksPerQuery := archivalBackfillBlocksPerQuery
+	archivalBackfillBlocksPerQuery = 10
+	defer func() {
+		archivalBackfillBlocksPerQuery = originalBlocksPerQuery
+	}()
+
+	logInBlock := func(blockNumber uint64, index uint) types.Log {
+		return types.Log{
+			BlockNumber: blockNumber,
+			BlockHash:   common.BigToHash(big.NewInt(int64(blockNumber))),
+			Index:       index,
+		}
+	}
+	removedLog := logInBlock(4, 0)
+	removedLog.Removed = true
+	fakeLogClient, err := newFakeLogClient(map[string]filterLogsResponse{
+		"0-9":   {Logs: []types.Log{logInBlock(7, 2), logInBlock(3, 0), logInBlock(7, 1), removedLog}},
+		"10-19": {Logs: []types.Log{}},
+		"20-25": {Logs: []types.Log{logInBlock(25, 0)}},
+	})
+	require.NoError(t, err)
+	config.Stack = simplestack.New(blockRetentionLimit, startMiniHeaders)
+	config.Client = fakeLogClient
+	watcher := New(config)
+
+	// Subscribers must not receive any of the replayed blocks.
+	subscriberEvents := make(chan []*Event, 10)
+	sub := watcher.Subscribe(subscriberEvents)
+	defer sub.Unsubscribe()
+
+	var handledEvents [][]*Event
+	err = watcher.ArchivalBackfill(context.Background(), 0, 25, nil, func(events []*Event) error {
+		handledEvents = append(handledEvents, events)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, fakeLogClient.Count())
+	require.Len(t, handledEvents, 2, "ranges without logs should be skipped")
+
+	require.Len(t, handledEvents[0], 2)
+	assert.Equal(t, big.NewInt(3), handledEvents[0][0].BlockHeader.Number)
+	assert.Equal(t, time.Unix(3, 0), handledEvents[0][0].BlockHeader.Timestamp)
+	assert.Equal(t, big.NewInt(7), handledEvents[0][1].BlockHeader.Number)
+	require.Len(t, handledEvents[0][1].BlockHeader.Logs, 2)
+	assert.Equal(t, uint(1), handledEvents[0][1].BlockHeader.Logs[0].Index)
+	assert.Equal(t, uint(2), handledEvents[0][1].BlockHeader.Logs[1].Index)
+	require.Len(t, handledEvents[1], 1)
+	assert.Equal(t, big.NewInt(25), handledEvents[1][0].BlockHeader.Number)
+
+	assert.Len(t, subscriberEvents, 0)
+	retainedBlocks, err := watcher.getAllRetainedBlocks()
+	require.NoError(t, err)
+	assert.Len(t, retainedBlocks, 0)
+
+	err = watcher.ArchivalBackfill(context.Background(), 10, 5, nil, func(events []*Event) error { return nil })
+	assert.Error(t, err)
+}
+
 func TestIsWarning(t *testing.T) {
 	errs := map[error]bool{
 		errors.New("not found"):     true,
@@ -551,3 +611,177 @@ func aRange(from, to int) string {
 	r := fmt.Sprintf("%d-%d", from, to)
 	return r
 }
+
+func TestHealthTrackerCircuitBreaker(t *testing.T) {
+	tracker := &healthTracker{}
+	providerErr := errors.New("503 Service Unavailable")
+	for i := 1; i < circuitBreakerThreshold; i++ {
+		assert.False(t, tracker.recordFailure(providerErr))
+		assert.Equal(t, CircuitClosed, tracker.get().State)
+	}
+	assert.True(t, tracker.recordFailure(providerErr), "expected circuit breaker to open")
+	health := tracker.get()
+	assert.Equal(t, CircuitOpen, health.State)
+	assert.Equal(t, circuitBreakerThreshold, health.ConsecutiveFailures)
+	assert.Equal(t, providerErr, health.LastError)
+	assert.True(t, tracker.isFailing())
+
+	// Additional failures should not re-open the circuit.
+	assert.False(t, tracker.recordFailure(providerErr))
+
+	recovered, downtime := tracker.recordSuccess()
+	assert.True(t, recovered)
+	assert.True(t, downtime > 0)
+	assert.Equal(t, CircuitClosed, tracker.get().State)
+	assert.False(t, tracker.isFailing())
+
+	// A success while the circuit is closed is not a recovery.
+	recovered, _ = tracker.recordSuccess()
+	assert.False(t, recovered)
+}
+
+func TestPollingIntervalTracker(t *testing.T) {
+	start := time.Now()
+	header := func(number int64, elapsed time.Duration) *miniheader.MiniHeader {
+		return &miniheader.MiniHeader{Number: big.NewInt(number), Timestamp: start.Add(elapsed)}
+	}
+	// The moving average is computed with floats, so allow for rounding errors.
+	assertInterval := func(expected time.Duration, tracker *pollingIntervalTracker) {
+		assert.InDelta(t, float64(expected), float64(tracker.get()), float64(time.Millisecond))
+	}
+
+	// Without a max polling interval, the interval never changes.
+	fixedTracker := newPollingIntervalTracker(Config{PollingInterval: 5 * time.Second})
+	fixedTracker.observe(header(100, 0))
+	fixedTracker.observe(header(105, 10*time.Second))
+	assertInterval(5*time.Second, fixedTracker)
+
+	tracker := newPollingIntervalTracker(Config{
+		PollingInterval:    5 * time.Second,
+		MinPollingInterval: 500 * time.Millisecond,
+		MaxPollingInterval: 30 * time.Second,
+	})
+	tracker.observe(header(100, 0))
+	assertInterval(5*time.Second, tracker)
+
+	// Blocks every 2 seconds result in polling every second.
+	tracker.observe(header(105, 10*time.Second))
+	assertInterval(1*time.Second, tracker)
+
+	// Blocks with the same timestamp are measured against the last block with
+	// an earlier timestamp.
+	tracker.observe(header(105, 10*time.Second))
+	tracker.observe(header(106, 10*time.Second))
+	assertInterval(1*time.Second, tracker)
+	tracker.observe(header(110, 20*time.Second))
+	assertInterval(1*time.Second, tracker)
+
+	// The interval follows the moving average of the block interval.
+	tracker.observe(header(111, 120*time.Second))
+	assertInterval(10800*time.Millisecond, tracker)
+
+	// The interval is bounded.
+	tracker.observe(header(112, 1120*time.Second))
+	assertInterval(30*time.Second, tracker)
+
+	// A re-org doesn't change the interval.
+	tracker.observe(header(50, 1130*time.Second))
+	assertInterval(30*time.Second, tracker)
+}
+
+func TestVerifyChildHeader(t *testing.T) {
+	start := time.Now()
+	parent := &miniheader.MiniHeader{
+		Hash:       common.HexToHash("0x1"),
+		Number:     big.NewInt(100),
+		Timestamp:  start,
+		Difficulty: big.NewInt(2000000),
+	}
+	child := func(number int64, elapsed time.Duration, difficulty *big.Int) *miniheader.MiniHeader {
+		return &miniheader.MiniHeader{
+			Hash:       common.HexToHash("0x2"),
+			Parent:     parent.Hash,
+			Number:     big.NewInt(number),
+			Timestamp:  start.Add(elapsed),
+			Difficulty: difficulty,
+		}
+	}
+
+	assert.NoError(t, verifyChildHeader(parent, child(101, 12*time.Second, big.NewInt(2000000+2000000/2048))))
+	// Unknown and low difficulties (e.g. proof of authority or proof of stake)
+	// are not checked.
+	assert.NoError(t, verifyChildHeader(parent, child(101, 12*time.Second, nil)))
+	assert.NoError(t, verifyChildHeader(parent, child(101, 12*time.Second, big.NewInt(0))))
+
+	for _, invalidChild := range []*miniheader.MiniHeader{
+		child(100, 12*time.Second, parent.Difficulty),
+		child(102, 12*time.Second, parent.Difficulty),
+		child(101, -12*time.Second, parent.Difficulty),
+		child(101, 12*time.Second, big.NewInt(4000000)),
+		child(101, 12*time.Second, big.NewInt(1000000)),
+	} {
+		err := verifyChildHeader(parent, invalidChild)
+		assert.IsType(t, InconsistentHeaderError{}, err)
+	}
+}
+
+// inconsistentClient is a Client which serves a chain head that claims to be
+// the child of the given parent but cannot be.
+type inconsistentClient struct {
+	parent *miniheader.MiniHeader
+}
+
+func (c *inconsistentClient) HeaderByNumber(number *big.Int) (*miniheader.MiniHeader, error) {
+	return &miniheader.MiniHeader{
+		Hash:   common.HexToHash("0x3"),
+		Parent: c.parent.Hash,
+		Number: big.NewInt(0).Add(c.parent.Number, big.NewInt(1)),
+		// The head claims to be the child of c.parent but was mined before it.
+		Timestamp: c.parent.Timestamp.Add(-time.Minute),
+	}, nil
+}
+
+func (c *inconsistentClient) HeaderByHash(hash common.Hash) (*miniheader.MiniHeader, error) {
+	return nil, errors.New("not found")
+}
+
+func (c *inconsistentClient) FilterLogs(q ethereum.FilterQuery) ([]types.Log, error) {
+	return nil, nil
+}
+
+func TestWatcherRejectsInconsistentHeader(t *testing.T) {
+	storedHeader := &miniheader.MiniHeader{
+		Hash:      common.HexToHash("0x2"),
+		Parent:    common.HexToHash("0x1"),
+		Number:    big.NewInt(5),
+		Timestamp: time.Now(),
+	}
+	watcherConfig := config
+	watcherConfig.Stack = simplestack.New(blockRetentionLimit, []*miniheader.MiniHeader{})
+	require.NoError(t, watcherConfig.Stack.Push(storedHeader))
+	watcherConfig.Client = &inconsistentClient{parent: storedHeader}
+	watcher := New(watcherConfig)
+
+	events := make(chan []*Event, 1)
+	sub := watcher.Subscribe(events)
+	defer sub.Unsubscribe()
+
+	for i := 0; i < 2; i++ {
+		err := watcher.SyncNow()
+		assert.IsType(t, InconsistentHeaderError{}, err)
+	}
+	retainedBlocks, err := watcher.getAllRetainedBlocks()
+	require.NoError(t, err)
+	assert.Equal(t, []*miniheader.MiniHeader{storedHeader}, retainedBlocks)
+	select {
+	case gotEvents := <-events:
+		t.Fatalf("expected no events but got %d", len(gotEvents))
+	default:
+	}
+
+	health := watcher.Health()
+	assert.Equal(t, 2, health.InconsistentHeaders)
+	assert.Equal(t, 2, health.ConsecutiveFailures)
+	watcher.ResetInconsistentHeaders()
+	assert.Equal(t, 0, watcher.Health().InconsistentHeaders)
+}
@@ -0,0 +1,114 @@
+package blockwatch
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BlockTag is a named block which can be passed to eth_getBlockByNumber
+// instead of a block number.
+type BlockTag string
+
+const (
+	// BlockTagLatest is the most recent block. It may be re-orged.
+	BlockTagLatest BlockTag = "latest"
+	// BlockTagSafe is the most recent block which is considered safe from
+	// re-orgs under honest majority assumptions. On L2s, it is typically the
+	// most recent block which has been posted to L1.
+	BlockTagSafe BlockTag = "safe"
+	// BlockTagFinalized is the most recent block which can no longer be
+	// re-orged.
+	BlockTagFinalized BlockTag = "finalized"
+)
+
+// unsupportedBlockTagErrorMessages are the error messages Ethereum RPC
+// providers return when they don't support the "safe" or "finalized" tags (or
+// when the chain has no safe or finalized block yet).
+var unsupportedBlockTagErrorMessages = []string{
+	"hex string without 0x prefix",
+	"invalid block",
+	"unknown block",
+	"not supported",
+	"cannot unmarshal",
+	rpcClientNotFoundError,
+}
+
+// ParseBlockTag parses a block tag. It returns an error if tag is not one of
+// "latest", "safe" or "finalized".
+func ParseBlockTag(tag string) (BlockTag, error) {
+	switch BlockTag(tag) {
+	case BlockTagLatest, BlockTagSafe, BlockTagFinalized:
+		return BlockTag(tag), nil
+	default:
+		return "", fmt.Errorf("invalid block tag %q (must be one of %q, %q or %q)", tag, BlockTagLatest, BlockTagSafe, BlockTagFinalized)
+	}
+}
+
+// ChainProfile describes how blocks are produced and finalized on a chain. It
+// determines which block the Watcher considers to be the head of the chain.
+type ChainProfile struct {
+	// BlockTime is the expected amount of time between blocks.
+	BlockTime time.Duration
+	// HeadTag is the tag of the block the Watcher syncs to. If it is not
+	// BlockTagLatest and the Ethereum RPC provider does not support it, the
+	// Watcher falls back to syncing to the block ConfirmationDepth blocks behind
+	// the latest block.
+	HeadTag BlockTag
+	// ConfirmationDepth is the number of blocks behind the latest block which
+	// are considered safe from re-orgs when HeadTag is not supported.
+	ConfirmationDepth int
+	// MaxReorgDepth is the deepest re-org that is expected to happen on the
+	// chain (counting from the latest block). The Watcher can only handle
+	// re-orgs which are shallower than the number of blocks it retains.
+	MaxReorgDepth int
+}
+
+// DefaultChainProfile is used for chains without a known profile. It syncs
+// to the latest block, which is how the Watcher has always behaved.
+var DefaultChainProfile = ChainProfile{
+	BlockTime:         12 * time.Second,
+	HeadTag:           BlockTagLatest,
+	ConfirmationDepth: 0,
+	MaxReorgDepth:     10,
+}
+
+// chainProfiles are the profiles of chains with known characteristics, by
+// chain ID.
+var chainProfiles = map[int]ChainProfile{
+	// Mainnet
+	1: DefaultChainProfile,
+	// Ropsten
+	3: {BlockTime: 15 * time.Second, HeadTag: BlockTagLatest, MaxReorgDepth: 20},
+	// Rinkeby
+	4: {BlockTime: 15 * time.Second, HeadTag: BlockTagLatest, MaxReorgDepth: 5},
+	// Optimism
+	10: {BlockTime: 2 * time.Second, HeadTag: BlockTagSafe, ConfirmationDepth: 60, MaxReorgDepth: 60},
+	// Kovan
+	42: {BlockTime: 4 * time.Second, HeadTag: BlockTagLatest, MaxReorgDepth: 5},
+	// Polygon
+	137: {BlockTime: 2 * time.Second, HeadTag: BlockTagFinalized, ConfirmationDepth: 64, MaxReorgDepth: 128},
+	// Ganache
+	1337: {BlockTime: 1 * time.Second, HeadTag: BlockTagLatest, MaxReorgDepth: 0},
+	// Arbitrum One
+	42161: {BlockTime: 250 * time.Millisecond, HeadTag: BlockTagSafe, ConfirmationDepth: 240, MaxReorgDepth: 240},
+}
+
+// ChainProfileForChainID returns the profile of the chain with the given ID, or
+// DefaultChainProfile if the chain is not known.
+func ChainProfileForChainID(chainID int) ChainProfile {
+	if profile, found := chainProfiles[chainID]; found {
+		return profile
+	}
+	return DefaultChainProfile
+}
+
+func isUnsupportedBlockTagError(err error) bool {
+	message := err.Error()
+	for _, unsupportedMessage := range unsupportedBlockTagErrorMessages {
+		if strings.Contains(message, unsupportedMessage) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+// +build !browser
+
+package blockwatch
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/0xProject/0x-mesh/ethereum/miniheader"
+	"github.com/0xProject/0x-mesh/ethereum/simplestack"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// taggedChainClient is a Client with a chain of blocks numbered 0 to latest
+// which optionally supports block tags.
+type taggedChainClient struct {
+	latest      int64
+	tags        map[BlockTag]int64
+	tagErr      error
+	numTagged   int
+	numByNumber int
+}
+
+func (c *taggedChainClient) header(number int64) *miniheader.MiniHeader {
+	return &miniheader.MiniHeader{
+		Hash:   common.BigToHash(big.NewInt(number + 1)),
+		Parent: common.BigToHash(big.NewInt(number)),
+		Number: big.NewInt(number),
+	}
+}
+
+func (c *taggedChainClient) HeaderByNumber(number *big.Int) (*miniheader.MiniHeader, error) {
+	c.numByNumber++
+	if number == nil {
+		return c.header(c.latest), nil
+	}
+	return c.header(number.Int64()), nil
+}
+
+func (c *taggedChainClient) HeaderByHash(hash common.Hash) (*miniheader.MiniHeader, error) {
+	return c.header(hash.Big().Int64() - 1), nil
+}
+
+func (c *taggedChainClient) FilterLogs(q ethereum.FilterQuery) ([]types.Log, error) {
+	return []types.Log{}, nil
+}
+
+func (c *taggedChainClient) HeaderByTag(tag BlockTag) (*miniheader.MiniHeader, error) {
+	c.numTagged++
+	if c.tagErr != nil {
+		return nil, c.tagErr
+	}
+	return c.header(c.tags[tag]), nil
+}
+
+func TestChainHeadDefaultsToLatestBlock(t *testing.T) {
+	client := &taggedChainClient{latest: 100, tags: map[BlockTag]int64{BlockTagFinalized: 90}}
+	watcher := New(Config{Client: client})
+
+	head, err := watcher.ChainHead()
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), head.Number.Int64())
+	assert.Equal(t, 0, client.numTagged)
+}
+
+func TestChainHeadUsesHeadTag(t *testing.T) {
+	client := &taggedChainClient{latest: 100, tags: map[BlockTag]int64{BlockTagFinalized: 90}}
+	watcher := New(Config{
+		Client:       client,
+		ChainProfile: ChainProfile{HeadTag: BlockTagFinalized, ConfirmationDepth: 5},
+	})
+
+	head, err := watcher.ChainHead()
+	require.NoError(t, err)
+	assert.Equal(t, int64(90), head.Number.Int64())
+}
+
+func TestChainHeadFallsBackToConfirmationDepth(t *testing.T) {
+	client := &taggedChainClient{latest: 100, tagErr: errors.New("invalid argument 0: hex string without 0x prefix")}
+	watcher := New(Config{
+		Client:       client,
+		ChainProfile: ChainProfile{HeadTag: BlockTagSafe, ConfirmationDepth: 5},
+	})
+
+	head, err := watcher.ChainHead()
+	require.NoError(t, err)
+	assert.Equal(t, int64(95), head.Number.Int64())
+
+	// The tag should not be requested again once it is known to be unsupported.
+	_, err = watcher.ChainHead()
+	require.NoError(t, err)
+	assert.Equal(t, 1, client.numTagged)
+}
+
+func TestChainHeadReturnsOtherTagErrors(t *testing.T) {
+	client := &taggedChainClient{latest: 100, tagErr: errors.New("connection refused")}
+	watcher := New(Config{
+		Client:       client,
+		ChainProfile: ChainProfile{HeadTag: BlockTagSafe, ConfirmationDepth: 5},
+	})
+
+	_, err := watcher.ChainHead()
+	require.Error(t, err)
+	assert.Equal(t, 0, client.numByNumber)
+}
+
+func TestChainHeadConfirmationDepthAtGenesis(t *testing.T) {
+	client := &taggedChainClient{latest: 3}
+	watcher := New(Config{
+		Client:       client,
+		ChainProfile: ChainProfile{HeadTag: BlockTagLatest, ConfirmationDepth: 5},
+	})
+
+	head, err := watcher.ChainHead()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), head.Number.Int64())
+}
+
+func TestBlocksBehind(t *testing.T) {
+	client := &taggedChainClient{latest: 100}
+	stack := simplestack.New(10, []*miniheader.MiniHeader{})
+	watcher := New(Config{Client: client, Stack: stack})
+
+	// Nothing is known about the chain yet.
+	blocksBehind, err := watcher.BlocksBehind()
+	require.NoError(t, err)
+	assert.Equal(t, 0, blocksBehind)
+
+	require.NoError(t, stack.Push(client.header(90)))
+	_, err = watcher.ChainHead()
+	require.NoError(t, err)
+	blocksBehind, err = watcher.BlocksBehind()
+	require.NoError(t, err)
+	assert.Equal(t, 10, blocksBehind)
+
+	require.NoError(t, stack.Push(client.header(100)))
+	blocksBehind, err = watcher.BlocksBehind()
+	require.NoError(t, err)
+	assert.Equal(t, 0, blocksBehind)
+}
+
+func TestParseBlockTag(t *testing.T) {
+	for _, tag := range []string{"latest", "safe", "finalized"} {
+		parsed, err := ParseBlockTag(tag)
+		require.NoError(t, err)
+		assert.Equal(t, BlockTag(tag), parsed)
+	}
+	_, err := ParseBlockTag("pending")
+	assert.Error(t, err)
+}
+
+func TestChainProfileForChainID(t *testing.T) {
+	assert.Equal(t, DefaultChainProfile, ChainProfileForChainID(1))
+	assert.Equal(t, DefaultChainProfile, ChainProfileForChainID(123456789))
+	assert.Equal(t, BlockTagSafe, ChainProfileForChainID(42161).HeadTag)
+}
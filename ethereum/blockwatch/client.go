@@ -10,6 +10,7 @@ import (
 	"github.com/0xProject/0x-mesh/constants"
 	"github.com/0xProject/0x-mesh/ethereum/ethrpcclient"
 	"github.com/0xProject/0x-mesh/ethereum/miniheader"
+	"github.com/0xProject/0x-mesh/ethereum/ratelimit"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -30,6 +31,13 @@ type Client interface {
 	FilterLogs(q ethereum.FilterQuery) ([]types.Log, error)
 }
 
+// TaggedHeaderClient is implemented by Clients which can fetch block headers by
+// tag (e.g. "finalized"). The Watcher uses it if the ChainProfile's HeadTag is
+// not BlockTagLatest.
+type TaggedHeaderClient interface {
+	HeaderByTag(tag BlockTag) (*miniheader.MiniHeader, error)
+}
+
 // RpcClient is a Client for fetching Ethereum blocks from a specific JSON-RPC endpoint.
 type RpcClient struct {
 	ethRPCClient ethrpcclient.Client
@@ -48,6 +56,7 @@ type GetBlockByNumberResponse struct {
 	ParentHash common.Hash `json:"parentHash"`
 	Number     string      `json:"number"`
 	Timestamp  string      `json:"timestamp"`
+	Difficulty string      `json:"difficulty"`
 }
 
 // UnknownBlockNumberError is the error returned from a filter logs RPC call when the block number
@@ -64,12 +73,20 @@ func (e UnknownBlockNumberError) Error() string {
 // HeaderByNumber fetches a block header by its number. If no `number` is supplied, it will return the latest
 // block header. If no block exists with this number it will return a `ethereum.NotFound` error.
 func (rc *RpcClient) HeaderByNumber(number *big.Int) (*miniheader.MiniHeader, error) {
-	var blockParam string
 	if number == nil {
-		blockParam = "latest"
-	} else {
-		blockParam = hexutil.EncodeBig(number)
+		return rc.headerByBlockParam(string(BlockTagLatest), nil)
 	}
+	return rc.headerByBlockParam(hexutil.EncodeBig(number), number)
+}
+
+// HeaderByTag fetches the header of the block with the given tag (e.g.
+// "finalized"). If the chain does not have a block with this tag yet, it will
+// return a `ethereum.NotFound` error.
+func (rc *RpcClient) HeaderByTag(tag BlockTag) (*miniheader.MiniHeader, error) {
+	return rc.headerByBlockParam(string(tag), nil)
+}
+
+func (rc *RpcClient) headerByBlockParam(blockParam string, number *big.Int) (*miniheader.MiniHeader, error) {
 	shouldIncludeTransactions := false
 
 	// Note(fabio): We use a raw RPC call here instead of `EthClient`'s `BlockByNumber()` method because block
@@ -78,7 +95,8 @@ func (rc *RpcClient) HeaderByNumber(number *big.Int) (*miniheader.MiniHeader, er
 	// RPC response rather than re-compute it from the block header.
 	// Source: https://github.com/ethereum/go-ethereum/pull/18166
 	var header GetBlockByNumberResponse
-	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	ctx := ratelimit.WithSubsystem(context.Background(), ratelimit.SubsystemBlockWatch)
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
 	defer cancel()
 	err := rc.ethRPCClient.CallContext(ctx, &header, "eth_getBlockByNumber", blockParam, shouldIncludeTransactions)
 	if err != nil {
@@ -107,6 +125,14 @@ func (rc *RpcClient) HeaderByNumber(number *big.Int) (*miniheader.MiniHeader, er
 		Number:    blockNum,
 		Timestamp: time.Unix(unixTimestamp.Int64(), 0),
 	}
+	// Some providers omit the difficulty, in which case it is left unknown.
+	if header.Difficulty != "" {
+		difficulty, ok := math.ParseBig256(header.Difficulty)
+		if !ok {
+			return nil, errors.New("Failed to parse big.Int value from hex-encoded block difficulty returned from eth_getBlockByNumber")
+		}
+		miniHeader.Difficulty = difficulty
+	}
 	return miniHeader, nil
 }
 
@@ -123,7 +149,8 @@ func (e UnknownBlockHashError) Error() string {
 // HeaderByHash fetches a block header by its block hash. If no block exists with this number it will return
 // a `ethereum.NotFound` error.
 func (rc *RpcClient) HeaderByHash(hash common.Hash) (*miniheader.MiniHeader, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	ctx := ratelimit.WithSubsystem(context.Background(), ratelimit.SubsystemBlockWatch)
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
 	defer cancel()
 	header, err := rc.ethRPCClient.HeaderByHash(ctx, hash)
 	if err != nil {
@@ -136,10 +163,11 @@ func (rc *RpcClient) HeaderByHash(hash common.Hash) (*miniheader.MiniHeader, err
 		return nil, err
 	}
 	miniHeader := &miniheader.MiniHeader{
-		Hash:      header.Hash(),
-		Parent:    header.ParentHash,
-		Number:    header.Number,
-		Timestamp: time.Unix(int64(header.Time), 0),
+		Hash:       header.Hash(),
+		Parent:     header.ParentHash,
+		Number:     header.Number,
+		Timestamp:  time.Unix(int64(header.Time), 0),
+		Difficulty: header.Difficulty,
 	}
 	return miniHeader, nil
 }
@@ -157,7 +185,8 @@ func (e FilterUnknownBlockError) Error() string {
 
 // FilterLogs returns the logs that satisfy the supplied filter query.
 func (rc *RpcClient) FilterLogs(q ethereum.FilterQuery) ([]types.Log, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	ctx := ratelimit.WithSubsystem(context.Background(), ratelimit.SubsystemBlockWatch)
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
 	defer cancel()
 	logs, err := rc.ethRPCClient.FilterLogs(ctx, q)
 	if err != nil {
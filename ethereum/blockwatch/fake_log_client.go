@@ -30,9 +30,17 @@ func newFakeLogClient(rangeToResponse map[string]filterLogsResponse) (*fakeLogCl
 	return &fakeLogClient{count: 0, rangeToResponse: rangeToResponse}, nil
 }
 
-// HeaderByNumber fetches a block header by its number
+// HeaderByNumber returns a block header with the given number whose timestamp
+// is the block number in seconds.
 func (fc *fakeLogClient) HeaderByNumber(number *big.Int) (*miniheader.MiniHeader, error) {
-	return nil, errors.New("NOT_IMPLEMENTED")
+	if number == nil {
+		return nil, errors.New("NOT_IMPLEMENTED")
+	}
+	return &miniheader.MiniHeader{
+		Hash:      common.BigToHash(number),
+		Number:    number,
+		Timestamp: time.Unix(number.Int64(), 0),
+	}, nil
 }
 
 // HeaderByHash fetches a block header by its block hash
@@ -0,0 +1,77 @@
+package blockwatch
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/0xProject/0x-mesh/ethereum/miniheader"
+)
+
+var (
+	// minVerifiableDifficulty is the lowest difficulty for which we check that
+	// the difficulty of the next block is plausible. It is the minimum
+	// difficulty of Ethereum's proof of work. Proof of authority chains use
+	// tiny difficulties (e.g. 1 and 2 for Clique) which change arbitrarily
+	// between blocks, and the difficulty is 0 after the merge.
+	minVerifiableDifficulty = big.NewInt(131072)
+	// maxDifficultyChangeDivisor bounds the change in difficulty between two
+	// blocks. Proof of work adjusts the difficulty by at most 99/2048 of the
+	// parent's difficulty (plus the difficulty bomb), so a change of more than
+	// 1/16 means that the headers don't belong to the same chain.
+	maxDifficultyChangeDivisor = big.NewInt(16)
+)
+
+// InconsistentHeaderError is returned when the Ethereum RPC provider serves a
+// block header which cannot belong to the same chain as its parent or as the
+// blocks retained by the Watcher, e.g. because the provider is malfunctioning
+// or malicious. The Watcher does not store any of the blocks it fetched during
+// the sync in which the header was served.
+type InconsistentHeaderError struct {
+	Header *miniheader.MiniHeader
+	Reason string
+}
+
+func (e InconsistentHeaderError) Error() string {
+	return fmt.Sprintf("Ethereum RPC provider served inconsistent block header %s (number %s): %s", e.Header.Hash.Hex(), e.Header.Number, e.Reason)
+}
+
+// verifyChildHeader checks that header can be the child of parent. The caller
+// must have checked that header.Parent is the hash of parent.
+func verifyChildHeader(parent, header *miniheader.MiniHeader) error {
+	expectedNumber := big.NewInt(0).Add(parent.Number, big.NewInt(1))
+	if header.Number.Cmp(expectedNumber) != 0 {
+		return InconsistentHeaderError{
+			Header: header,
+			Reason: fmt.Sprintf("block number should be %s", expectedNumber),
+		}
+	}
+	if header.Timestamp.Before(parent.Timestamp) {
+		return InconsistentHeaderError{
+			Header: header,
+			Reason: fmt.Sprintf("timestamp %s is before the timestamp of its parent %s", header.Timestamp, parent.Timestamp),
+		}
+	}
+	if !isPlausibleDifficulty(parent.Difficulty, header.Difficulty) {
+		return InconsistentHeaderError{
+			Header: header,
+			Reason: fmt.Sprintf("difficulty %s is implausible for a child of a block with difficulty %s", header.Difficulty, parent.Difficulty),
+		}
+	}
+	return nil
+}
+
+// isPlausibleDifficulty returns false if a block with the given difficulty
+// cannot be the child of a block with the parent difficulty under proof of
+// work. It returns true if either difficulty is unknown or too low to be
+// checked (see minVerifiableDifficulty).
+func isPlausibleDifficulty(parentDifficulty, difficulty *big.Int) bool {
+	if parentDifficulty == nil || difficulty == nil {
+		return true
+	}
+	if parentDifficulty.Cmp(minVerifiableDifficulty) < 0 || difficulty.Cmp(minVerifiableDifficulty) < 0 {
+		return true
+	}
+	maxChange := big.NewInt(0).Div(parentDifficulty, maxDifficultyChangeDivisor)
+	change := big.NewInt(0).Sub(difficulty, parentDifficulty)
+	return change.Abs(change).Cmp(maxChange) <= 0
+}
@@ -0,0 +1,137 @@
+package blockwatch
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	// circuitBreakerThreshold is the number of consecutive failed attempts to
+	// sync to the latest block after which the circuit breaker opens.
+	circuitBreakerThreshold = 5
+	// maxPollingBackoff is the longest amount of time the Watcher waits before
+	// polling again while the Ethereum RPC provider is returning errors.
+	maxPollingBackoff = 1 * time.Minute
+)
+
+// CircuitState describes whether the Watcher considers its Ethereum RPC
+// provider to be healthy.
+type CircuitState int
+
+const (
+	// CircuitClosed means that the provider is healthy and the Watcher polls at
+	// the normal interval.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen means that the provider has returned too many consecutive
+	// errors. The Watcher keeps polling with exponential backoff until a sync
+	// succeeds.
+	CircuitOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// Health is a snapshot of the Watcher's view of its Ethereum RPC provider.
+type Health struct {
+	State               CircuitState
+	ConsecutiveFailures int
+	LastError           error
+	LastErrorTime       time.Time
+	// InconsistentHeaders is the number of times the provider has served a
+	// block header which cannot belong to the chain (see
+	// InconsistentHeaderError) since it was last changed. A provider which
+	// serves inconsistent headers is malfunctioning or malicious and should
+	// be replaced.
+	InconsistentHeaders int
+}
+
+// RecoveryEvent is emitted when the Watcher successfully syncs to the latest
+// block after the circuit breaker was open.
+type RecoveryEvent struct {
+	// Downtime is the amount of time between the first failure and the
+	// successful sync.
+	Downtime time.Duration
+	// BlocksBackfilled is the number of blocks that were added while catching
+	// back up to the latest block.
+	BlocksBackfilled int
+}
+
+// healthTracker keeps track of consecutive sync failures and the resulting
+// circuit breaker state. It is safe for concurrent use.
+type healthTracker struct {
+	mu               sync.RWMutex
+	health           Health
+	firstFailureTime time.Time
+}
+
+// recordFailure records a failed sync attempt. It returns true if this
+// failure caused the circuit breaker to open.
+func (h *healthTracker) recordFailure(err error) (opened bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	if h.health.ConsecutiveFailures == 0 {
+		h.firstFailureTime = now
+	}
+	h.health.ConsecutiveFailures++
+	h.health.LastError = err
+	h.health.LastErrorTime = now
+	if h.health.State == CircuitClosed && h.health.ConsecutiveFailures >= circuitBreakerThreshold {
+		h.health.State = CircuitOpen
+		return true
+	}
+	return false
+}
+
+// recordSuccess records a successful sync attempt. If the circuit breaker was
+// open, it is closed and recordSuccess returns true along with the time since
+// the first failure.
+func (h *healthTracker) recordSuccess() (recovered bool, downtime time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	recovered = h.health.State == CircuitOpen
+	if recovered {
+		downtime = time.Since(h.firstFailureTime)
+	}
+	h.health.State = CircuitClosed
+	h.health.ConsecutiveFailures = 0
+	return recovered, downtime
+}
+
+// recordInconsistentHeader records that the provider served an inconsistent
+// header. It returns true if this is the first inconsistent header since the
+// provider was last changed.
+func (h *healthTracker) recordInconsistentHeader() (first bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.health.InconsistentHeaders++
+	return h.health.InconsistentHeaders == 1
+}
+
+// resetInconsistentHeaders resets the number of inconsistent headers, e.g.
+// after the provider was changed.
+func (h *healthTracker) resetInconsistentHeaders() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.health.InconsistentHeaders = 0
+}
+
+func (h *healthTracker) get() Health {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.health
+}
+
+func (h *healthTracker) isFailing() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.health.ConsecutiveFailures > 0
+}
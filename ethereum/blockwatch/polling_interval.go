@@ -0,0 +1,110 @@
+package blockwatch
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/0xProject/0x-mesh/ethereum/miniheader"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// adaptivePollingFactor is the fraction of the average block interval the
+	// Watcher waits between polls if adaptive polling is enabled. Polling more
+	// often than blocks are produced keeps the delay until a new block is
+	// noticed short without polling much more than necessary.
+	adaptivePollingFactor = 0.5
+	// blockIntervalSmoothing is the weight of the most recent observation in
+	// the moving average of the block interval.
+	blockIntervalSmoothing = 0.2
+)
+
+// pollingIntervalTracker keeps track of the interval at which the Watcher
+// polls for new blocks. If adaptive polling is enabled (i.e. maxInterval is
+// not 0), the interval follows the average interval between the blocks that
+// were observed, based on their timestamps, bounded by minInterval and
+// maxInterval.
+type pollingIntervalTracker struct {
+	mu          sync.RWMutex
+	interval    time.Duration
+	minInterval time.Duration
+	maxInterval time.Duration
+	// lastObservedHeader is the header the next block interval is measured
+	// from.
+	lastObservedHeader *miniheader.MiniHeader
+	// avgBlockInterval is the moving average of the observed block interval. It
+	// is 0 until the first interval was observed.
+	avgBlockInterval time.Duration
+}
+
+func newPollingIntervalTracker(config Config) *pollingIntervalTracker {
+	tracker := &pollingIntervalTracker{
+		interval:    config.PollingInterval,
+		minInterval: config.MinPollingInterval,
+		maxInterval: config.MaxPollingInterval,
+	}
+	if tracker.isAdaptive() {
+		tracker.interval = tracker.clamp(tracker.interval)
+	}
+	return tracker
+}
+
+func (t *pollingIntervalTracker) isAdaptive() bool {
+	return t.maxInterval != 0
+}
+
+func (t *pollingIntervalTracker) get() time.Duration {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.interval
+}
+
+func (t *pollingIntervalTracker) clamp(interval time.Duration) time.Duration {
+	if interval < t.minInterval {
+		return t.minInterval
+	}
+	if interval > t.maxInterval {
+		return t.maxInterval
+	}
+	return interval
+}
+
+// observe updates the polling interval based on the interval between the
+// given latest header and the last header that was observed. Headers with the
+// same timestamp as the last one (which is common on chains that produce
+// several blocks per second) are measured against the last header with an
+// earlier timestamp.
+func (t *pollingIntervalTracker) observe(latestHeader *miniheader.MiniHeader) {
+	if !t.isAdaptive() || latestHeader == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	lastHeader := t.lastObservedHeader
+	if lastHeader == nil || latestHeader.Number.Cmp(lastHeader.Number) < 0 {
+		// This is the first header or there was a re-org.
+		t.lastObservedHeader = latestHeader
+		return
+	}
+	elapsed := latestHeader.Timestamp.Sub(lastHeader.Timestamp)
+	if latestHeader.Number.Cmp(lastHeader.Number) == 0 || elapsed <= 0 {
+		return
+	}
+	t.lastObservedHeader = latestHeader
+	numBlocks := new(big.Int).Sub(latestHeader.Number, lastHeader.Number).Int64()
+	blockInterval := elapsed / time.Duration(numBlocks)
+	if t.avgBlockInterval == 0 {
+		t.avgBlockInterval = blockInterval
+	} else {
+		t.avgBlockInterval = time.Duration(blockIntervalSmoothing*float64(blockInterval) + (1-blockIntervalSmoothing)*float64(t.avgBlockInterval))
+	}
+	interval := t.clamp(time.Duration(adaptivePollingFactor * float64(t.avgBlockInterval)))
+	if interval != t.interval {
+		log.WithFields(log.Fields{
+			"pollingInterval":  interval.String(),
+			"avgBlockInterval": t.avgBlockInterval.String(),
+		}).Trace("adjusted block polling interval")
+		t.interval = interval
+	}
+}
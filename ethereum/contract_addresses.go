@@ -21,6 +21,12 @@ type ContractAddresses struct {
 	ChaiBridge          common.Address `json:"chaiBridge"`
 	ChaiToken           common.Address `json:"chaiToken"`
 	MaximumGasPrice     common.Address `json:"maximumGasPrice"`
+	// Multicall is the address of a Multicall2 contract. It is only used to
+	// validate orders on chains where DevUtils is not deployed.
+	Multicall common.Address `json:"multicall"`
+	// ExchangeProxy is the address of the v4 ExchangeProxy contract. It is
+	// only used by order filters for v4 orders.
+	ExchangeProxy common.Address `json:"exchangeProxy"`
 }
 
 // GanacheAddresses The addresses that the 0x contracts were deployed to on the Ganache snapshot (chainID = 1337).
@@ -43,6 +49,8 @@ func NewContractAddressesForChainID(chainID int) (ContractAddresses, error) {
 			ChaiBridge:          common.HexToAddress("0x77c31eba23043b9a72d13470f3a3a311344d7438"),
 			ChaiToken:           common.HexToAddress("0x06af07097c9eeb7fd685c692751d5c66db49c215"),
 			MaximumGasPrice:     common.HexToAddress("0xe2bfd35306495d11e3c9db0d8de390cda24563cf"),
+			Multicall:           common.HexToAddress("0x5ba1e12693dc8f9c48aad8770482f4739beed696"),
+			ExchangeProxy:       common.HexToAddress("0xdef1c0ded9bec7f1a1670819833240f027b25eff"),
 		}, nil
 	case 3:
 		return ContractAddresses{
@@ -58,6 +66,8 @@ func NewContractAddressesForChainID(chainID int) (ContractAddresses, error) {
 			ChaiBridge:          common.HexToAddress("0x0000000000000000000000000000000000000000"),
 			ChaiToken:           common.HexToAddress("0x0000000000000000000000000000000000000000"),
 			MaximumGasPrice:     common.HexToAddress("0x407b4128e9ecad8769b2332312a9f655cb9f5f3a"),
+			Multicall:           common.HexToAddress("0x5ba1e12693dc8f9c48aad8770482f4739beed696"),
+			ExchangeProxy:       common.HexToAddress("0xdef1c0ded9bec7f1a1670819833240f027b25eff"),
 		}, nil
 	case 4:
 		return ContractAddresses{
@@ -73,6 +83,8 @@ func NewContractAddressesForChainID(chainID int) (ContractAddresses, error) {
 			ChaiBridge:          common.HexToAddress("0x0000000000000000000000000000000000000000"),
 			ChaiToken:           common.HexToAddress("0x0000000000000000000000000000000000000000"),
 			MaximumGasPrice:     common.HexToAddress("0x47697b44bd89051e93b4d5857ba8e024800a74ac"),
+			Multicall:           common.HexToAddress("0x5ba1e12693dc8f9c48aad8770482f4739beed696"),
+			ExchangeProxy:       common.HexToAddress("0xdef1c0ded9bec7f1a1670819833240f027b25eff"),
 		}, nil
 	case 42:
 		return ContractAddresses{
@@ -88,6 +100,8 @@ func NewContractAddressesForChainID(chainID int) (ContractAddresses, error) {
 			ChaiBridge:          common.HexToAddress("0x0000000000000000000000000000000000000000"),
 			ChaiToken:           common.HexToAddress("0x0000000000000000000000000000000000000000"),
 			MaximumGasPrice:     common.HexToAddress("0x67a094cf028221ffdd93fc658f963151d05e2a74"),
+			Multicall:           common.HexToAddress("0x5ba1e12693dc8f9c48aad8770482f4739beed696"),
+			ExchangeProxy:       common.HexToAddress("0xdef1c0ded9bec7f1a1670819833240f027b25eff"),
 		}, nil
 	case 1337:
 		return ganacheAddresses(), nil
@@ -103,8 +117,10 @@ func ValidateContractAddressesForChainID(chainID int, addresses ContractAddresse
 	if addresses.Exchange == constants.NullAddress {
 		return fmt.Errorf("cannot add contract addresses for chain ID %d: Exchange address is required", chainID)
 	}
-	if addresses.DevUtils == constants.NullAddress {
-		return fmt.Errorf("cannot add contract addresses for chain ID %d: DevUtils address is required", chainID)
+	if addresses.DevUtils == constants.NullAddress && addresses.Multicall == constants.NullAddress {
+		// Orders can be validated without DevUtils by calling the Exchange and
+		// token contracts directly, but only through a Multicall contract.
+		return fmt.Errorf("cannot add contract addresses for chain ID %d: either DevUtils or Multicall address is required", chainID)
 	}
 	if addresses.ERC20Proxy == constants.NullAddress {
 		return fmt.Errorf("cannot add contract addresses for chain ID %d: ERC20Proxy address is required", chainID)
@@ -138,5 +154,8 @@ func ganacheAddresses() ContractAddresses {
 		ChaiBridge:          common.HexToAddress("0x0000000000000000000000000000000000000000"),
 		ChaiToken:           common.HexToAddress("0x0000000000000000000000000000000000000000"),
 		MaximumGasPrice:     common.HexToAddress("0x2c530e4ecc573f11bd72cf5fdf580d134d25f15f"),
+		Multicall:           common.HexToAddress("0x0000000000000000000000000000000000000000"),
+		// The ExchangeProxy is not deployed on the Ganache snapshot.
+		ExchangeProxy: common.HexToAddress("0x0000000000000000000000000000000000000000"),
 	}
 }
@@ -0,0 +1,67 @@
+// +build !js
+
+package ethrpcclient
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialAddsHeadersAndJWT(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	receivedHeaders := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedHeaders <- r.Header
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer server.Close()
+
+	client, err := Dial(server.URL, DialConfig{
+		Headers:   http.Header{"X-Api-Key": []string{"foo"}},
+		JWTSecret: secret,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	var result string
+	require.NoError(t, client.CallContext(context.Background(), &result, "eth_chainId"))
+	assert.Equal(t, "0x1", result)
+
+	headers := <-receivedHeaders
+	assert.Equal(t, "foo", headers.Get("X-Api-Key"))
+	authorization := headers.Get("Authorization")
+	require.True(t, strings.HasPrefix(authorization, "Bearer "))
+	parts := strings.Split(strings.TrimPrefix(authorization, "Bearer "), ".")
+	require.Len(t, parts, 3)
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(parts[0] + "." + parts[1]))
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), parts[2])
+}
+
+func TestNewJWT(t *testing.T) {
+	token, err := newJWT([]byte("secret"), time.Unix(1600000000, 0))
+	require.NoError(t, err)
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+	claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	assert.Equal(t, `{"iat":1600000000}`, string(claims))
+}
+
+func TestDialConfigRequiresHTTP(t *testing.T) {
+	_, err := Dial("ws://localhost:8546", DialConfig{
+		Headers: http.Header{"Authorization": []string{"Bearer foo"}},
+	})
+	assert.Equal(t, ErrDialConfigRequiresHTTP, err)
+}
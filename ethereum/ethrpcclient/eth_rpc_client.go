@@ -102,10 +102,11 @@ func (ec *client) HeaderByNumber(ctx context.Context, number *big.Int) (*minihea
 		return nil, err
 	}
 	miniHeader := &miniheader.MiniHeader{
-		Hash:      header.Hash(),
-		Parent:    header.ParentHash,
-		Number:    header.Number,
-		Timestamp: time.Unix(int64(header.Time), 0),
+		Hash:       header.Hash(),
+		Parent:     header.ParentHash,
+		Number:     header.Number,
+		Timestamp:  time.Unix(int64(header.Time), 0),
+		Difficulty: header.Difficulty,
 	}
 	return miniHeader, nil
 }
@@ -0,0 +1,203 @@
+package ethrpcclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ipcScheme is the optional scheme of IPC endpoints (e.g.
+	// ipc:///home/geth/.ethereum/geth.ipc).
+	ipcScheme = "ipc://"
+	// windowsPipePrefix is the prefix of Windows named pipes.
+	windowsPipePrefix = `\\.\pipe\`
+	// minIPCRedialBackoff is how long to wait before redialing after the first
+	// failed attempt to reconnect.
+	minIPCRedialBackoff = 100 * time.Millisecond
+	// maxIPCRedialBackoff is the maximum amount of time to wait between
+	// attempts to reconnect.
+	maxIPCRedialBackoff = 10 * time.Second
+)
+
+// ErrIPCDisconnected is returned by IPCClient if the connection to the IPC
+// endpoint was lost and it is waiting before trying to reconnect.
+var ErrIPCDisconnected = errors.New("disconnected from Ethereum IPC endpoint (waiting to reconnect)")
+
+// ErrIPCClientClosed is returned by IPCClient after it was closed.
+var ErrIPCClientClosed = errors.New("Ethereum IPC client is closed")
+
+// IsIPCEndpoint returns true if the given Ethereum RPC URL is the path of a
+// Unix socket or Windows named pipe rather than an HTTP(S) or WebSocket URL.
+// Paths can optionally be prefixed with ipc://.
+func IsIPCEndpoint(rawurl string) bool {
+	return strings.HasPrefix(rawurl, ipcScheme) ||
+		strings.HasPrefix(rawurl, "/") ||
+		strings.HasPrefix(rawurl, "./") ||
+		strings.HasPrefix(rawurl, windowsPipePrefix) ||
+		(!strings.Contains(rawurl, "://") && strings.HasSuffix(rawurl, ".ipc"))
+}
+
+// IPCClient is an RPC client for the IPC endpoint of a co-located Ethereum
+// node (e.g. geth or Nethermind). IPC avoids the overhead of HTTP, which
+// matters for validation-heavy workloads. Unlike a client returned by
+// rpc.Dial, IPCClient re-establishes the connection when it is lost (e.g.
+// because the node was restarted), redialing with exponential backoff while
+// the node is unavailable. Requests made while it is waiting to redial fail
+// with ErrIPCDisconnected.
+type IPCClient struct {
+	path string
+	// dial is rpc.DialIPC, except in tests.
+	dial func(ctx context.Context, path string) (*rpc.Client, error)
+
+	mu sync.Mutex
+	// client is nil while disconnected.
+	client *rpc.Client
+	// nextDial is the earliest time at which to redial after a failed attempt.
+	nextDial time.Time
+	backoff  time.Duration
+	closed   bool
+}
+
+// Ensure that we implement the ethclient.RPCClient interface.
+var _ ethclient.RPCClient = &IPCClient{}
+
+// DialIPC connects to the IPC endpoint at the given path (see IsIPCEndpoint).
+// It returns an error if the first connection attempt fails.
+func DialIPC(ctx context.Context, path string) (*IPCClient, error) {
+	c := &IPCClient{
+		path: strings.TrimPrefix(path, ipcScheme),
+		dial: rpc.DialIPC,
+	}
+	client, err := c.dial(ctx, c.path)
+	if err != nil {
+		return nil, err
+	}
+	c.client = client
+	return c, nil
+}
+
+// getClient returns the current connection, redialing if the connection was
+// lost and the backoff elapsed.
+func (c *IPCClient) getClient(ctx context.Context) (*rpc.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrIPCClientClosed
+	}
+	if c.client != nil {
+		return c.client, nil
+	}
+	if time.Now().Before(c.nextDial) {
+		return nil, ErrIPCDisconnected
+	}
+	client, err := c.dial(ctx, c.path)
+	if err != nil {
+		if c.backoff == 0 {
+			c.backoff = minIPCRedialBackoff
+		} else if c.backoff *= 2; c.backoff > maxIPCRedialBackoff {
+			c.backoff = maxIPCRedialBackoff
+		}
+		c.nextDial = time.Now().Add(c.backoff)
+		log.WithError(err).WithFields(log.Fields{
+			"path":    c.path,
+			"backoff": c.backoff,
+		}).Warn("could not reconnect to Ethereum IPC endpoint")
+		return nil, err
+	}
+	log.WithField("path", c.path).Info("reconnected to Ethereum IPC endpoint")
+	c.client = client
+	c.backoff = 0
+	return client, nil
+}
+
+// handleError drops the given connection if err indicates that it was lost,
+// so that the next request redials.
+func (c *IPCClient) handleError(client *rpc.Client, err error) {
+	if err == nil || !isConnectionError(err) {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != client {
+		// Another request already dropped the connection.
+		return
+	}
+	log.WithError(err).WithField("path", c.path).Warn("lost connection to Ethereum IPC endpoint")
+	c.client = nil
+	client.Close()
+}
+
+// isConnectionError returns true if err was caused by a broken connection
+// rather than by the request itself (e.g. an error returned by the node or a
+// context which was cancelled).
+func isConnectionError(err error) bool {
+	if _, ok := err.(rpc.Error); ok {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, rpc.ErrClientQuit) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.As(err, &netErr)
+}
+
+// CallContext performs a JSON-RPC call with the given arguments (see
+// rpc.Client.CallContext).
+func (c *IPCClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	client, err := c.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	err = client.CallContext(ctx, result, method, args...)
+	c.handleError(client, err)
+	return err
+}
+
+// BatchCallContext sends all given requests as a single batch (see
+// rpc.Client.BatchCallContext).
+func (c *IPCClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	client, err := c.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	err = client.BatchCallContext(ctx, b)
+	c.handleError(client, err)
+	return err
+}
+
+// EthSubscribe registers a subscription under the "eth" namespace (see
+// rpc.Client.EthSubscribe). Subscriptions end when the connection is lost and
+// are not re-established.
+func (c *IPCClient) EthSubscribe(ctx context.Context, channel interface{}, args ...interface{}) (*rpc.ClientSubscription, error) {
+	client, err := c.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := client.EthSubscribe(ctx, channel, args...)
+	c.handleError(client, err)
+	return sub, err
+}
+
+// Close closes the connection. Requests made after Close fail with
+// ErrIPCClientClosed.
+func (c *IPCClient) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	if c.client != nil {
+		c.client.Close()
+		c.client = nil
+	}
+}
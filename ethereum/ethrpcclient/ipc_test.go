@@ -0,0 +1,89 @@
+// +build !js
+
+package ethrpcclient
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEthService struct{}
+
+func (testEthService) ChainId() string {
+	return "0x539"
+}
+
+// serveIPC starts an RPC server on a Unix socket at the given path and
+// returns a function which stops it.
+func serveIPC(t *testing.T, path string) func() {
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("eth", testEthService{}))
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	go func() {
+		_ = server.ServeListener(listener)
+	}()
+	return func() {
+		_ = listener.Close()
+		server.Stop()
+	}
+}
+
+func TestIsIPCEndpoint(t *testing.T) {
+	for _, rawurl := range []string{"/home/geth/.ethereum/geth.ipc", "ipc:///tmp/geth.ipc", "./geth.ipc", "geth.ipc", `\\.\pipe\geth.ipc`} {
+		assert.True(t, IsIPCEndpoint(rawurl), rawurl)
+	}
+	for _, rawurl := range []string{"http://localhost:8545", "https://example.com/geth.ipc", "ws://localhost:8546"} {
+		assert.False(t, IsIPCEndpoint(rawurl), rawurl)
+	}
+}
+
+func TestIPCClientReconnects(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ethrpcclient")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "geth.ipc")
+	ctx := context.Background()
+
+	stop := serveIPC(t, path)
+	client, err := DialIPC(ctx, "ipc://"+path)
+	require.NoError(t, err)
+	defer client.Close()
+	var chainID string
+	require.NoError(t, client.CallContext(ctx, &chainID, "eth_chainId"))
+	assert.Equal(t, "0x539", chainID)
+
+	// Requests fail while the node is down.
+	stop()
+	require.NoError(t, os.RemoveAll(path))
+	assert.Error(t, client.CallContext(ctx, &chainID, "eth_chainId"))
+	assert.Error(t, client.CallContext(ctx, &chainID, "eth_chainId"))
+
+	// Once the node is back up, the client reconnects.
+	stop = serveIPC(t, path)
+	defer stop()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err = client.CallContext(ctx, &chainID, "eth_chainId")
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	require.NoError(t, err)
+	assert.Equal(t, "0x539", chainID)
+}
+
+func TestDialEndpointRejectsDialConfigForIPC(t *testing.T) {
+	_, err := DialEndpoint(context.Background(), "/tmp/geth.ipc", DialConfig{JWTSecret: []byte("secret")})
+	assert.Equal(t, ErrDialConfigRequiresHTTP, err)
+}
@@ -0,0 +1,73 @@
+package ethrpcclient
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// SwappableClient is an RPC client which forwards all requests to another RPC
+// client that can be replaced while requests are in flight (e.g. to rotate the
+// API key of an Ethereum RPC provider without restarting Mesh).
+type SwappableClient struct {
+	// mu is held for reading for the duration of every request, so that Swap
+	// can wait until all in-flight requests to the old client have finished.
+	mu        sync.RWMutex
+	rpcClient ethclient.RPCClient
+}
+
+// Ensure that we implement the ethclient.RPCClient interface.
+var _ ethclient.RPCClient = &SwappableClient{}
+
+// NewSwappableClient returns a SwappableClient which forwards requests to the
+// given RPC client.
+func NewSwappableClient(rpcClient ethclient.RPCClient) *SwappableClient {
+	return &SwappableClient{
+		rpcClient: rpcClient,
+	}
+}
+
+// Swap replaces the RPC client that requests are forwarded to. It blocks new
+// requests and waits until all in-flight requests to the old client have
+// finished, then closes the old client. Subscriptions created with the old
+// client end once it is closed.
+func (c *SwappableClient) Swap(rpcClient ethclient.RPCClient) {
+	c.mu.Lock()
+	oldRPCClient := c.rpcClient
+	c.rpcClient = rpcClient
+	c.mu.Unlock()
+	oldRPCClient.Close()
+}
+
+// CallContext performs a JSON-RPC call with the given arguments (see
+// rpc.Client.CallContext).
+func (c *SwappableClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.rpcClient.CallContext(ctx, result, method, args...)
+}
+
+// BatchCallContext sends all given requests as a single batch (see
+// rpc.Client.BatchCallContext).
+func (c *SwappableClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.rpcClient.BatchCallContext(ctx, b)
+}
+
+// EthSubscribe registers a subscription under the "eth" namespace (see
+// rpc.Client.EthSubscribe).
+func (c *SwappableClient) EthSubscribe(ctx context.Context, channel interface{}, args ...interface{}) (*rpc.ClientSubscription, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.rpcClient.EthSubscribe(ctx, channel, args...)
+}
+
+// Close closes the current RPC client.
+func (c *SwappableClient) Close() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	c.rpcClient.Close()
+}
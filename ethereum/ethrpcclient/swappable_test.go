@@ -0,0 +1,89 @@
+// +build !js
+
+package ethrpcclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingRPCClient is an RPC client whose calls block until unblock is closed.
+type blockingRPCClient struct {
+	name    string
+	called  chan struct{}
+	unblock chan struct{}
+	closed  chan struct{}
+}
+
+func newBlockingRPCClient(name string) *blockingRPCClient {
+	return &blockingRPCClient{
+		name:    name,
+		called:  make(chan struct{}, 1),
+		unblock: make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (c *blockingRPCClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	c.called <- struct{}{}
+	<-c.unblock
+	*(result.(*string)) = c.name
+	return nil
+}
+
+func (c *blockingRPCClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	return nil
+}
+
+func (c *blockingRPCClient) EthSubscribe(ctx context.Context, channel interface{}, args ...interface{}) (*rpc.ClientSubscription, error) {
+	return nil, nil
+}
+
+func (c *blockingRPCClient) Close() {
+	close(c.closed)
+}
+
+func TestSwappableClientDrainsInFlightRequests(t *testing.T) {
+	oldRPCClient := newBlockingRPCClient("old")
+	newRPCClient := newBlockingRPCClient("new")
+	close(newRPCClient.unblock)
+	client := NewSwappableClient(oldRPCClient)
+	ctx := context.Background()
+
+	inFlightResult := make(chan string, 1)
+	go func() {
+		var result string
+		require.NoError(t, client.CallContext(ctx, &result, "eth_chainId"))
+		inFlightResult <- result
+	}()
+	<-oldRPCClient.called
+
+	swapped := make(chan struct{})
+	go func() {
+		client.Swap(newRPCClient)
+		close(swapped)
+	}()
+	select {
+	case <-swapped:
+		t.Fatal("Swap returned before the in-flight request finished")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(oldRPCClient.unblock)
+	assert.Equal(t, "old", <-inFlightResult)
+	<-swapped
+	select {
+	case <-oldRPCClient.closed:
+	default:
+		t.Fatal("old RPC client was not closed")
+	}
+
+	var result string
+	require.NoError(t, client.CallContext(ctx, &result, "eth_chainId"))
+	assert.Equal(t, "new", result)
+}
@@ -14,7 +14,10 @@ type MiniHeader struct {
 	Parent    common.Hash
 	Number    *big.Int
 	Timestamp time.Time
-	Logs      []types.Log
+	// Difficulty is the difficulty of the block. It is nil if it is unknown
+	// (e.g. for headers stored by previous versions of Mesh).
+	Difficulty *big.Int
+	Logs       []types.Log
 }
 
 // ID returns the MiniHeader's ID
@@ -0,0 +1,280 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Subsystem identifies the part of Mesh which sent an Ethereum RPC request.
+// The request budget is shared between subsystems according to a Budget.
+type Subsystem string
+
+const (
+	// SubsystemBlockWatch is used for requests sent to poll for new blocks and
+	// their logs.
+	SubsystemBlockWatch Subsystem = "blockwatch"
+	// SubsystemOrderValidation is used for requests sent to validate and
+	// re-validate orders. Requests without a subsystem are attributed to it.
+	SubsystemOrderValidation Subsystem = "orderValidation"
+	// SubsystemAPI is used for requests triggered by API calls (e.g. AddOrders).
+	SubsystemAPI Subsystem = "api"
+)
+
+// recentGrantsWindow is the number of most recent grants considered when
+// checking whether a subsystem received its share of the per second limit.
+const recentGrantsWindow = 100
+
+type subsystemContextKey struct{}
+
+// WithSubsystem returns a copy of ctx which attributes Ethereum RPC requests
+// to the given subsystem.
+func WithSubsystem(ctx context.Context, subsystem Subsystem) context.Context {
+	return context.WithValue(ctx, subsystemContextKey{}, subsystem)
+}
+
+// SubsystemFromContext returns the subsystem carried by ctx or
+// SubsystemOrderValidation if there is none.
+func SubsystemFromContext(ctx context.Context) Subsystem {
+	if ctx == nil {
+		return SubsystemOrderValidation
+	}
+	if subsystem, ok := ctx.Value(subsystemContextKey{}).(Subsystem); ok {
+		return subsystem
+	}
+	return SubsystemOrderValidation
+}
+
+// Budget determines how the Ethereum RPC request limits are shared between
+// subsystems.
+//
+// Each subsystem is guaranteed its share of the per second limit while it has
+// requests waiting. Capacity which is not used by a subsystem is given to the
+// others, highest priority first. The same shares of the 24 hour limit are
+// reserved for each subsystem: a subsystem can only use budget reserved for a
+// subsystem with a higher priority after that subsystem used it up, which
+// means that e.g. a burst of order validation cannot exhaust the requests
+// needed for block polling.
+type Budget struct {
+	// Shares maps each subsystem to its share of the limits. The shares must
+	// add up to 1.
+	Shares map[Subsystem]float64
+	// Priority lists all subsystems, highest priority first.
+	Priority []Subsystem
+}
+
+// DefaultBudget is the Budget used by New. Block polling has the highest
+// priority because falling behind the latest block affects all orders.
+var DefaultBudget = Budget{
+	Shares: map[Subsystem]float64{
+		SubsystemBlockWatch:      0.2,
+		SubsystemAPI:             0.2,
+		SubsystemOrderValidation: 0.6,
+	},
+	Priority: []Subsystem{SubsystemBlockWatch, SubsystemAPI, SubsystemOrderValidation},
+}
+
+// Validate returns an error if the budget doesn't include a share and a
+// priority for every subsystem or if the shares don't add up to 1.
+func (b Budget) Validate() error {
+	allSubsystems := []Subsystem{SubsystemBlockWatch, SubsystemOrderValidation, SubsystemAPI}
+	if len(b.Priority) != len(allSubsystems) {
+		return fmt.Errorf("budget priority must list each of the subsystems %q, %q and %q exactly once", SubsystemBlockWatch, SubsystemAPI, SubsystemOrderValidation)
+	}
+	total := 0.0
+	for _, subsystem := range allSubsystems {
+		share, found := b.Shares[subsystem]
+		if !found {
+			return fmt.Errorf("budget is missing a share for subsystem %q", subsystem)
+		}
+		if share < 0 {
+			return fmt.Errorf("budget share for subsystem %q cannot be negative", subsystem)
+		}
+		total += share
+		if b.priorityOf(subsystem) == -1 {
+			return fmt.Errorf("budget priority must list each of the subsystems %q, %q and %q exactly once", SubsystemBlockWatch, SubsystemAPI, SubsystemOrderValidation)
+		}
+	}
+	if len(b.Shares) != len(allSubsystems) {
+		return fmt.Errorf("budget shares must only include the subsystems %q, %q and %q", SubsystemBlockWatch, SubsystemAPI, SubsystemOrderValidation)
+	}
+	if math.Abs(total-1) > 0.001 {
+		return fmt.Errorf("budget shares must add up to 1 (got %g)", total)
+	}
+	return nil
+}
+
+// priorityOf returns the index of the given subsystem in b.Priority (lower is
+// more important) or -1 if it isn't included.
+func (b Budget) priorityOf(subsystem Subsystem) int {
+	for i, s := range b.Priority {
+		if s == subsystem {
+			return i
+		}
+	}
+	return -1
+}
+
+// reservedForHigherPriority returns the number of requests out of maxRequests
+// which are reserved for subsystems with a higher priority than the given
+// subsystem and which they haven't used yet.
+func (b Budget) reservedForHigherPriority(subsystem Subsystem, maxRequests int, granted map[Subsystem]int) int {
+	priority := b.priorityOf(subsystem)
+	if priority == -1 {
+		priority = len(b.Priority)
+	}
+	reserved := 0
+	for _, higher := range b.Priority[:priority] {
+		unused := int(b.Shares[higher]*float64(maxRequests)) - granted[higher]
+		if unused > 0 {
+			reserved += unused
+		}
+	}
+	return reserved
+}
+
+type waiter struct {
+	subsystem Subsystem
+	ready     chan struct{}
+	granted   bool
+}
+
+// scheduler hands out the tokens of a per second limiter to waiting requests
+// according to a Budget. Tokens are only reserved from the limiter when the
+// previous one was handed out, so that they always go to the request which is
+// most important at the time they become available (and not to whichever
+// request happened to ask first).
+type scheduler struct {
+	limiter      *rate.Limiter
+	budget       Budget
+	mu           sync.Mutex
+	waiters      []*waiter
+	timerPending bool
+	recentGrants []Subsystem
+	nextGrant    int
+}
+
+func newScheduler(limiter *rate.Limiter, budget Budget) *scheduler {
+	return &scheduler{
+		limiter:      limiter,
+		budget:       budget,
+		recentGrants: make([]Subsystem, 0, recentGrantsWindow),
+	}
+}
+
+// wait blocks until a request from the given subsystem is allowed or ctx is
+// done.
+func (s *scheduler) wait(ctx context.Context, subsystem Subsystem) error {
+	w := &waiter{
+		subsystem: subsystem,
+		ready:     make(chan struct{}),
+	}
+	s.mu.Lock()
+	s.waiters = append(s.waiters, w)
+	s.dispatchLocked()
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if w.granted {
+			return nil
+		}
+		for i, other := range s.waiters {
+			if other == w {
+				s.waiters = append(s.waiters[:i], s.waiters[i+1:]...)
+				break
+			}
+		}
+		return ctx.Err()
+	}
+}
+
+// dispatchLocked hands out tokens until there are no more waiters or the next
+// token is not available yet, in which case it schedules itself to run again
+// once it is. s.mu must be held.
+func (s *scheduler) dispatchLocked() {
+	for !s.timerPending && len(s.waiters) > 0 {
+		delay := s.limiter.Reserve().Delay()
+		if delay == 0 {
+			s.grantLocked()
+			continue
+		}
+		s.timerPending = true
+		time.AfterFunc(delay, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.timerPending = false
+			if len(s.waiters) > 0 {
+				s.grantLocked()
+			}
+			s.dispatchLocked()
+		})
+	}
+}
+
+// grantLocked allows the most important waiting request. That is the oldest
+// request of the highest priority subsystem which received less than its
+// share of the recent grants or, if every waiting subsystem received its
+// share, the oldest request of the highest priority subsystem. s.mu must be
+// held and there must be at least one waiter.
+func (s *scheduler) grantLocked() {
+	chosen := -1
+	for _, subsystem := range s.budget.Priority {
+		index := s.oldestWaiterLocked(subsystem)
+		if index == -1 {
+			continue
+		}
+		if chosen == -1 {
+			chosen = index
+		}
+		if s.belowShareLocked(subsystem) {
+			chosen = index
+			break
+		}
+	}
+	if chosen == -1 {
+		// None of the waiters belong to a subsystem in the budget.
+		chosen = 0
+	}
+	w := s.waiters[chosen]
+	s.waiters = append(s.waiters[:chosen], s.waiters[chosen+1:]...)
+	w.granted = true
+	close(w.ready)
+
+	if len(s.recentGrants) < recentGrantsWindow {
+		s.recentGrants = append(s.recentGrants, w.subsystem)
+	} else {
+		s.recentGrants[s.nextGrant] = w.subsystem
+		s.nextGrant = (s.nextGrant + 1) % recentGrantsWindow
+	}
+}
+
+func (s *scheduler) oldestWaiterLocked(subsystem Subsystem) int {
+	for i, w := range s.waiters {
+		if w.subsystem == subsystem {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *scheduler) belowShareLocked(subsystem Subsystem) bool {
+	if len(s.recentGrants) == 0 {
+		return true
+	}
+	numGrants := 0
+	for _, granted := range s.recentGrants {
+		if granted == subsystem {
+			numGrants++
+		}
+	}
+	return float64(numGrants)/float64(len(s.recentGrants)) < s.budget.Shares[subsystem]
+}
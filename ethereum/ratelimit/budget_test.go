@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/0xProject/0x-mesh/meshdb"
+	"github.com/benbjohnson/clock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestSubsystemFromContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, SubsystemOrderValidation, SubsystemFromContext(ctx))
+	assert.Equal(t, SubsystemBlockWatch, SubsystemFromContext(WithSubsystem(ctx, SubsystemBlockWatch)))
+}
+
+func TestBudgetValidate(t *testing.T) {
+	require.NoError(t, DefaultBudget.Validate())
+
+	invalidBudgets := []Budget{
+		{
+			Shares:   map[Subsystem]float64{SubsystemBlockWatch: 0.5, SubsystemAPI: 0.5},
+			Priority: DefaultBudget.Priority,
+		},
+		{
+			Shares:   map[Subsystem]float64{SubsystemBlockWatch: 0.5, SubsystemAPI: 0.5, SubsystemOrderValidation: 0.5},
+			Priority: DefaultBudget.Priority,
+		},
+		{
+			Shares:   DefaultBudget.Shares,
+			Priority: []Subsystem{SubsystemBlockWatch, SubsystemAPI},
+		},
+		{
+			Shares:   DefaultBudget.Shares,
+			Priority: []Subsystem{SubsystemBlockWatch, SubsystemAPI, SubsystemAPI},
+		},
+	}
+	for i, budget := range invalidBudgets {
+		assert.Error(t, budget.Validate(), "budget %d", i)
+	}
+}
+
+// A subsystem cannot use requests which are reserved for subsystems with a
+// higher priority, but higher priority subsystems can use requests reserved for
+// lower priority subsystems.
+func TestSubsystemBudgetReservesRequestsForHigherPriority(t *testing.T) {
+	meshDB, err := meshdb.New("/tmp/meshdb_testing/"+uuid.New().String(), contractAddresses)
+	require.NoError(t, err)
+	defer meshDB.Close()
+	initMetadata(t, meshDB)
+
+	aClock := clock.NewMock()
+	aClock.Set(GetUTCMidnightOfDate(time.Now()))
+	budget := Budget{
+		Shares: map[Subsystem]float64{
+			SubsystemBlockWatch:      0.5,
+			SubsystemAPI:             0.2,
+			SubsystemOrderValidation: 0.3,
+		},
+		Priority: []Subsystem{SubsystemBlockWatch, SubsystemAPI, SubsystemOrderValidation},
+	}
+	rateLimiter, err := NewWithBudget(10, math.MaxFloat64, meshDB, aClock, budget)
+	require.NoError(t, err)
+
+	validationCtx := WithSubsystem(context.Background(), SubsystemOrderValidation)
+	apiCtx := WithSubsystem(context.Background(), SubsystemAPI)
+	blockWatchCtx := WithSubsystem(context.Background(), SubsystemBlockWatch)
+
+	// Order validation can only use its own share.
+	for i := 0; i < 3; i++ {
+		require.NoError(t, rateLimiter.Wait(validationCtx))
+	}
+	assert.Equal(t, ErrSubsystemBudgetExceeded, rateLimiter.Wait(validationCtx))
+
+	// The API can use its own share, but not the share of block watching.
+	for i := 0; i < 2; i++ {
+		require.NoError(t, rateLimiter.Wait(apiCtx))
+	}
+	assert.Equal(t, ErrSubsystemBudgetExceeded, rateLimiter.Wait(apiCtx))
+
+	// Block watching can use the rest of the requests.
+	for i := 0; i < 5; i++ {
+		require.NoError(t, rateLimiter.Wait(blockWatchCtx))
+	}
+	assert.Equal(t, ErrTooManyRequestsIn24Hours, rateLimiter.Wait(blockWatchCtx))
+}
+
+// Subsystems below their share of the per second limit are granted requests
+// before other subsystems, even if they started waiting later.
+func TestSchedulerGrantsSubsystemsBelowTheirShareFirst(t *testing.T) {
+	limiter := rate.NewLimiter(rate.Limit(20), 1)
+	s := newScheduler(limiter, DefaultBudget)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Use up the burst and queue up lots of order validation requests.
+	require.NoError(t, s.wait(ctx, SubsystemOrderValidation))
+	validationCtx, cancelValidation := context.WithCancel(ctx)
+	defer cancelValidation()
+	for i := 0; i < 40; i++ {
+		go func() {
+			_ = s.wait(validationCtx, SubsystemOrderValidation)
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// Requests are granted every 50ms. If they were granted in order, the
+	// request from the block watcher would have to wait for about 2 seconds.
+	requestedAt := time.Now()
+	require.NoError(t, s.wait(ctx, SubsystemBlockWatch))
+	assert.WithinDuration(t, requestedAt, time.Now(), 50*time.Millisecond+grantTimingTolerance)
+}
@@ -16,6 +16,11 @@ import (
 
 var ErrTooManyRequestsIn24Hours = errors.New("too many Ethereum RPC requests have been sent this 24 hour period")
 
+// ErrSubsystemBudgetExceeded is returned when a subsystem used up its share of
+// the 24 hour limit and the rest of the limit is reserved for subsystems with
+// a higher priority.
+var ErrSubsystemBudgetExceeded = errors.New("subsystem has used up its share of Ethereum RPC requests for this 24 hour period")
+
 // RateLimiter is the interface one must satisfy to be considered a RateLimiter
 type RateLimiter interface {
 	Wait(ctx context.Context) error
@@ -27,18 +32,33 @@ type RateLimiter interface {
 // rateLimiter is a rate-limiter for requests
 type rateLimiter struct {
 	maxRequestsPer24Hrs   int
-	perSecondLimiter      *rate.Limiter
+	perSecondScheduler    *scheduler
+	budget                Budget
 	currentUTCCheckpoint  time.Time // Start of current UTC 24hr period
 	grantedInLast24hrsUTC int       // Number of granted requests issued in last 24hr UTC
-	meshDB                *meshdb.MeshDB
-	aClock                clock.Clock
-	wasStartedOnce        bool       // Whether the rate limiter has previously been started
-	startMutex            sync.Mutex // Mutex around the start check
-	mu                    sync.Mutex
+	// grantedBySubsystem is the number of granted requests issued in the last
+	// 24hr UTC by subsystem. Unlike grantedInLast24hrsUTC, it is not stored in
+	// the DB.
+	grantedBySubsystem map[Subsystem]int
+	meshDB             *meshdb.MeshDB
+	aClock             clock.Clock
+	wasStartedOnce     bool       // Whether the rate limiter has previously been started
+	startMutex         sync.Mutex // Mutex around the start check
+	mu                 sync.Mutex
 }
 
-// New instantiates a new RateLimiter
+// New instantiates a new RateLimiter which shares the limits between
+// subsystems according to DefaultBudget.
 func New(maxRequestsPer24Hrs int, maxRequestsPerSecond float64, meshDB *meshdb.MeshDB, aClock clock.Clock) (RateLimiter, error) {
+	return NewWithBudget(maxRequestsPer24Hrs, maxRequestsPerSecond, meshDB, aClock, DefaultBudget)
+}
+
+// NewWithBudget instantiates a new RateLimiter which shares the limits between
+// subsystems according to the given budget.
+func NewWithBudget(maxRequestsPer24Hrs int, maxRequestsPerSecond float64, meshDB *meshdb.MeshDB, aClock clock.Clock, budget Budget) (RateLimiter, error) {
+	if err := budget.Validate(); err != nil {
+		return nil, err
+	}
 	metadata, err := meshDB.GetMetadata()
 	if err != nil {
 		return nil, err
@@ -69,13 +89,23 @@ func New(maxRequestsPer24Hrs int, maxRequestsPerSecond float64, meshDB *meshdb.M
 	limit := rate.Limit(maxRequestsPerSecond)
 	perSecondLimiter := rate.NewLimiter(limit, int(math.Max(1, maxRequestsPerSecond/2)))
 
+	// We don't know which subsystems sent the requests that were already granted
+	// in the current 24hr period, so we assume they were sent according to the
+	// budget.
+	grantedBySubsystem := map[Subsystem]int{}
+	for subsystem, share := range budget.Shares {
+		grantedBySubsystem[subsystem] = int(share * float64(storedGrantedInLast24HrsUTC))
+	}
+
 	return &rateLimiter{
 		aClock:                aClock,
 		maxRequestsPer24Hrs:   maxRequestsPer24Hrs,
-		perSecondLimiter:      perSecondLimiter,
+		perSecondScheduler:    newScheduler(perSecondLimiter, budget),
+		budget:                budget,
 		meshDB:                meshDB,
 		currentUTCCheckpoint:  storedUTCCheckpoint,
 		grantedInLast24hrsUTC: storedGrantedInLast24HrsUTC,
+		grantedBySubsystem:    grantedBySubsystem,
 	}, nil
 }
 
@@ -110,6 +140,7 @@ func (r *rateLimiter) Start(ctx context.Context, checkpointInterval time.Duratio
 				r.mu.Lock()
 				r.currentUTCCheckpoint = nextUTCCheckpoint
 				r.grantedInLast24hrsUTC = 0
+				r.grantedBySubsystem = map[Subsystem]int{}
 				r.mu.Unlock()
 			}
 		}
@@ -145,22 +176,29 @@ func (r *rateLimiter) Start(ctx context.Context, checkpointInterval time.Duratio
 	}
 }
 
-// Wait blocks until the rateLimiter allows for another request to be sent. It
-// returns an error if the deadline of the given context is before the request
-// would be granted. It also returns an error if too many requests have been
-// sent during this 24 hour period.
+// Wait blocks until the rateLimiter allows for another request to be sent. The
+// request is attributed to the subsystem carried by ctx (see WithSubsystem).
+// It returns an error if the given context is done before the request is
+// granted. It also returns an error if too many requests have been sent during
+// this 24 hour period, either in total or by the subsystem (see Budget).
 func (r *rateLimiter) Wait(ctx context.Context) error {
+	subsystem := SubsystemFromContext(ctx)
 	r.mu.Lock()
 	if r.grantedInLast24hrsUTC >= r.maxRequestsPer24Hrs {
 		r.mu.Unlock()
 		return ErrTooManyRequestsIn24Hours
 	}
+	if r.grantedInLast24hrsUTC+r.budget.reservedForHigherPriority(subsystem, r.maxRequestsPer24Hrs, r.grantedBySubsystem) >= r.maxRequestsPer24Hrs {
+		r.mu.Unlock()
+		return ErrSubsystemBudgetExceeded
+	}
 	r.mu.Unlock()
-	if err := r.perSecondLimiter.Wait(ctx); err != nil {
+	if err := r.perSecondScheduler.wait(ctx, subsystem); err != nil {
 		return err
 	}
 	r.mu.Lock()
 	r.grantedInLast24hrsUTC++
+	r.grantedBySubsystem[subsystem]++
 	r.mu.Unlock()
 	return nil
 }
@@ -93,7 +93,17 @@ func TestScenario2(t *testing.T) {
 	// what we're trying to test.
 	aClock := clock.NewMock()
 	aClock.Set(startOfCurrentUTCDay.Add(3 * time.Hour))
-	rateLimiter, err := New(defaultMaxRequestsPer24Hrs, math.MaxFloat64, meshDB, aClock)
+	// No requests are reserved for other subsystems so that all of the remaining
+	// requests can be granted.
+	budget := Budget{
+		Shares: map[Subsystem]float64{
+			SubsystemBlockWatch:      0,
+			SubsystemAPI:             0,
+			SubsystemOrderValidation: 1,
+		},
+		Priority: DefaultBudget.Priority,
+	}
+	rateLimiter, err := NewWithBudget(defaultMaxRequestsPer24Hrs, math.MaxFloat64, meshDB, aClock, budget)
 	require.NoError(t, err)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -12,7 +12,13 @@ func GetPrivateKeyFromPath(path string) (p2pcrypto.PrivKey, error) {
 	if err != nil {
 		return nil, err
 	}
-	decodedKey, err := p2pcrypto.ConfigDecodeKey(string(keyBytes))
+	return decodePrivateKey(string(keyBytes))
+}
+
+// decodePrivateKey decodes a private key in the format written by
+// GenerateAndSavePrivateKey.
+func decodePrivateKey(encodedKey string) (p2pcrypto.PrivKey, error) {
+	decodedKey, err := p2pcrypto.ConfigDecodeKey(encodedKey)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,60 @@
+package keys
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+)
+
+// pskLength is the length of a pre-shared key in bytes.
+const pskLength = 32
+
+// pskV1Header is the header of a pre-shared key file in the "swarm key"
+// format used by go-ipfs and other libp2p implementations.
+const pskV1Header = "/key/swarm/psk/1.0.0/\n/base16/\n"
+
+// GetPSKFromPath reads the pre-shared key of a private network from the file
+// at the given path. The file must be in the "swarm key" format written by
+// GenerateAndSavePSK, so keys generated for go-ipfs private networks can be
+// used as well.
+func GetPSKFromPath(path string) (*[pskLength]byte, error) {
+	keyBytes, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(keyBytes, []byte(pskV1Header)) {
+		return nil, fmt.Errorf("could not decode pre-shared key: expected header %q", pskV1Header)
+	}
+	encodedKey := bytes.TrimSpace(bytes.TrimPrefix(keyBytes, []byte(pskV1Header)))
+	decodedKey, err := hex.DecodeString(string(encodedKey))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode pre-shared key: %s", err.Error())
+	}
+	if len(decodedKey) != pskLength {
+		return nil, fmt.Errorf("could not decode pre-shared key: expected %d bytes but got %d", pskLength, len(decodedKey))
+	}
+	var psk [pskLength]byte
+	copy(psk[:], decodedKey)
+	return &psk, nil
+}
+
+// GenerateAndSavePSK generates a random pre-shared key for a private network
+// and writes it to the given path. All nodes of the private network need a
+// copy of the file.
+func GenerateAndSavePSK(path string) (*[pskLength]byte, error) {
+	dir := filepath.Dir(path)
+	if err := mkdirAll(dir); err != nil {
+		return nil, err
+	}
+	var psk [pskLength]byte
+	if _, err := rand.Read(psk[:]); err != nil {
+		return nil, err
+	}
+	encodedKey := pskV1Header + hex.EncodeToString(psk[:]) + "\n"
+	if err := writeFile(path, []byte(encodedKey)); err != nil {
+		return nil, err
+	}
+	return &psk, nil
+}
@@ -0,0 +1,33 @@
+package keys
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateAndGetPSK(t *testing.T) {
+	path := "/tmp/keys/" + uuid.New().String()
+	generatedKey, err := GenerateAndSavePSK(path)
+	require.NoError(t, err)
+	require.NotNil(t, generatedKey)
+	gotKey, err := GetPSKFromPath(path)
+	require.NoError(t, err)
+	assert.Equal(t, generatedKey, gotKey)
+}
+
+func TestGetPSKInvalid(t *testing.T) {
+	nonExistentPath := "/tmp/keys/" + uuid.New().String()
+	_, err := GetPSKFromPath(nonExistentPath)
+	assert.True(t, os.IsNotExist(err), "error should be a NotExist error, but got: (%T) %s", err, err)
+
+	require.NoError(t, os.MkdirAll("/tmp/keys", os.ModePerm))
+	invalidPath := "/tmp/keys/" + uuid.New().String()
+	require.NoError(t, ioutil.WriteFile(invalidPath, []byte(pskV1Header+"not hex\n"), os.ModePerm))
+	_, err = GetPSKFromPath(invalidPath)
+	assert.Error(t, err)
+}
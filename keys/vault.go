@@ -0,0 +1,120 @@
+package keys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
+)
+
+// vaultRequestTimeout is the maximum amount of time to wait for a response
+// from Vault.
+const vaultRequestTimeout = 30 * time.Second
+
+// VaultConfig is the configuration for loading a private key from a HashiCorp
+// Vault KV secret.
+type VaultConfig struct {
+	// Address is the URL of the Vault server (e.g. https://vault.example.com:8200).
+	Address string
+	// Token is the Vault token used to authenticate the request.
+	Token string
+	// Namespace is the Vault Enterprise namespace of the secret. It is optional.
+	Namespace string
+	// Path is the API path of the secret, without the "/v1/" prefix (e.g.
+	// "secret/data/mesh" for the KV version 2 secrets engine or "secret/mesh"
+	// for version 1).
+	Path string
+	// Field is the field of the secret which contains the private key. The
+	// private key must be encoded in the same format as the private key file
+	// written by GenerateAndSavePrivateKey.
+	Field string
+	// HTTPClient is the client used to send the request. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+}
+
+// ErrVaultFieldNotFound is returned by GetPrivateKeyFromVault if the secret
+// does not have the configured field.
+type ErrVaultFieldNotFound struct {
+	Path  string
+	Field string
+}
+
+func (e ErrVaultFieldNotFound) Error() string {
+	return fmt.Sprintf("Vault secret %q does not have a %q field", e.Path, e.Field)
+}
+
+// vaultSecretResponse is the response to reading a secret from Vault. For the
+// KV version 2 secrets engine, the fields of the secret are nested in another
+// "data" object.
+type vaultSecretResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []string                   `json:"errors"`
+}
+
+// GetPrivateKeyFromVault reads the private key from the given Vault KV secret.
+// The private key is only held in memory and is never written to disk.
+func GetPrivateKeyFromVault(ctx context.Context, config VaultConfig) (p2pcrypto.PrivKey, error) {
+	if config.Address == "" {
+		return nil, errors.New("Vault address is required")
+	}
+	if config.Token == "" {
+		return nil, errors.New("Vault token is required")
+	}
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, vaultRequestTimeout)
+	defer cancel()
+	url := strings.TrimSuffix(config.Address, "/") + "/v1/" + strings.TrimPrefix(config.Path, "/")
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("X-Vault-Token", config.Token)
+	if config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", config.Namespace)
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	var secret vaultSecretResponse
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return nil, fmt.Errorf("could not parse response from Vault (status %d): %s", res.StatusCode, err.Error())
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not read secret %q from Vault (status %d): %s", config.Path, res.StatusCode, strings.Join(secret.Errors, "; "))
+	}
+
+	fields := secret.Data
+	if nestedData, found := fields["data"]; found {
+		var nestedFields map[string]json.RawMessage
+		if err := json.Unmarshal(nestedData, &nestedFields); err == nil {
+			fields = nestedFields
+		}
+	}
+	rawKey, found := fields[config.Field]
+	if !found {
+		return nil, ErrVaultFieldNotFound{Path: config.Path, Field: config.Field}
+	}
+	var encodedKey string
+	if err := json.Unmarshal(rawKey, &encodedKey); err != nil {
+		return nil, fmt.Errorf("field %q of Vault secret %q is not a string", config.Field, config.Path)
+	}
+	return decodePrivateKey(strings.TrimSpace(encodedKey))
+}
@@ -0,0 +1,96 @@
+// +build !js
+
+package keys
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newVaultServer(t *testing.T, token string, path string, response interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		if r.URL.Path != "/v1/"+path {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(response))
+	}))
+}
+
+func newEncodedPrivateKey(t *testing.T) (p2pcrypto.PrivKey, string) {
+	privKey, _, err := p2pcrypto.GenerateSecp256k1Key(rand.Reader)
+	require.NoError(t, err)
+	keyBytes, err := p2pcrypto.MarshalPrivateKey(privKey)
+	require.NoError(t, err)
+	return privKey, p2pcrypto.ConfigEncodeKey(keyBytes)
+}
+
+func TestGetPrivateKeyFromVault(t *testing.T) {
+	privKey, encodedKey := newEncodedPrivateKey(t)
+	testCases := []struct {
+		name     string
+		path     string
+		response interface{}
+	}{
+		{
+			name: "KV version 1",
+			path: "secret/mesh",
+			response: map[string]interface{}{
+				"data": map[string]string{"privkey": encodedKey},
+			},
+		},
+		{
+			name: "KV version 2",
+			path: "secret/data/mesh",
+			response: map[string]interface{}{
+				"data": map[string]interface{}{
+					"data":     map[string]string{"privkey": encodedKey},
+					"metadata": map[string]interface{}{"version": 3},
+				},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		server := newVaultServer(t, "s.token", testCase.path, testCase.response)
+		gotKey, err := GetPrivateKeyFromVault(context.Background(), VaultConfig{
+			Address: server.URL,
+			Token:   "s.token",
+			Path:    testCase.path,
+			Field:   "privkey",
+		})
+		server.Close()
+		require.NoError(t, err, testCase.name)
+		assert.True(t, privKey.Equals(gotKey), testCase.name)
+	}
+}
+
+func TestGetPrivateKeyFromVaultErrors(t *testing.T) {
+	_, encodedKey := newEncodedPrivateKey(t)
+	server := newVaultServer(t, "s.token", "secret/mesh", map[string]interface{}{
+		"data": map[string]string{"privkey": encodedKey},
+	})
+	defer server.Close()
+
+	_, err := GetPrivateKeyFromVault(context.Background(), VaultConfig{Address: server.URL, Token: "s.wrong", Path: "secret/mesh", Field: "privkey"})
+	assert.Error(t, err)
+
+	_, err = GetPrivateKeyFromVault(context.Background(), VaultConfig{Address: server.URL, Token: "s.token", Path: "secret/other", Field: "privkey"})
+	assert.Error(t, err)
+
+	_, err = GetPrivateKeyFromVault(context.Background(), VaultConfig{Address: server.URL, Token: "s.token", Path: "secret/mesh", Field: "key"})
+	assert.Equal(t, ErrVaultFieldNotFound{Path: "secret/mesh", Field: "key"}, err)
+}
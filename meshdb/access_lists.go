@@ -0,0 +1,116 @@
+package meshdb
+
+import (
+	"time"
+
+	"github.com/0xProject/0x-mesh/db"
+)
+
+// IPRangeBan is the database representation of a ban on a range of IP
+// addresses.
+type IPRangeBan struct {
+	// IPRange is the banned range in CIDR notation.
+	IPRange   string
+	Reason    string
+	CreatedAt time.Time
+}
+
+// ID returns the IPRangeBan's ID
+func (b IPRangeBan) ID() []byte {
+	return []byte(b.IPRange)
+}
+
+// IPRangeBansCollection represents a DB collection of IP range bans
+type IPRangeBansCollection struct {
+	*db.Collection
+}
+
+func setupIPRangeBans(database *db.DB) (*IPRangeBansCollection, error) {
+	col, err := database.NewCollection("ipRangeBan", &IPRangeBan{})
+	if err != nil {
+		return nil, err
+	}
+	return &IPRangeBansCollection{col}, nil
+}
+
+// AllowlistEntry is the database representation of an entry of the peer
+// allowlist.
+type AllowlistEntry struct {
+	// Value is either a peer ID or an IP range in CIDR notation.
+	Value     string
+	CreatedAt time.Time
+}
+
+// ID returns the AllowlistEntry's ID
+func (e AllowlistEntry) ID() []byte {
+	return []byte(e.Value)
+}
+
+// AllowlistCollection represents a DB collection of allowlist entries
+type AllowlistCollection struct {
+	*db.Collection
+}
+
+func setupAllowlist(database *db.DB) (*AllowlistCollection, error) {
+	col, err := database.NewCollection("allowlistEntry", &AllowlistEntry{})
+	if err != nil {
+		return nil, err
+	}
+	return &AllowlistCollection{col}, nil
+}
+
+// SaveIPRangeBan inserts the given IPRangeBan or replaces the existing ban for
+// the same IP range.
+func (m *MeshDB) SaveIPRangeBan(ban *IPRangeBan) error {
+	var existing IPRangeBan
+	if err := m.IPRangeBans.FindByID(ban.ID(), &existing); err != nil {
+		if _, ok := err.(db.NotFoundError); ok {
+			return m.IPRangeBans.Insert(ban)
+		}
+		return err
+	}
+	return m.IPRangeBans.Update(ban)
+}
+
+// DeleteIPRangeBan removes the ban for the given IP range. It returns a
+// db.NotFoundError if the IP range is not banned.
+func (m *MeshDB) DeleteIPRangeBan(ipRange string) error {
+	return m.IPRangeBans.Delete([]byte(ipRange))
+}
+
+// FindAllIPRangeBans returns all IPRangeBans.
+func (m *MeshDB) FindAllIPRangeBans() ([]*IPRangeBan, error) {
+	bans := []*IPRangeBan{}
+	if err := m.IPRangeBans.FindAll(&bans); err != nil {
+		return nil, err
+	}
+	return bans, nil
+}
+
+// SaveAllowlistEntry inserts the given AllowlistEntry. It is a no-op if the
+// entry already exists.
+func (m *MeshDB) SaveAllowlistEntry(entry *AllowlistEntry) error {
+	var existing AllowlistEntry
+	if err := m.Allowlist.FindByID(entry.ID(), &existing); err != nil {
+		if _, ok := err.(db.NotFoundError); ok {
+			return m.Allowlist.Insert(entry)
+		}
+		return err
+	}
+	return nil
+}
+
+// DeleteAllowlistEntry removes the given entry from the allowlist. It returns a
+// db.NotFoundError if the entry does not exist.
+func (m *MeshDB) DeleteAllowlistEntry(value string) error {
+	return m.Allowlist.Delete([]byte(value))
+}
+
+// FindAllAllowlistEntries returns all AllowlistEntries.
+func (m *MeshDB) FindAllAllowlistEntries() ([]*AllowlistEntry, error) {
+	entries := []*AllowlistEntry{}
+	if err := m.Allowlist.FindAll(&entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
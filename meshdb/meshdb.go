@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sync"
 	"time"
 
 	"github.com/0xProject/0x-mesh/constants"
@@ -25,6 +26,20 @@ const (
 
 var ErrDBFilledWithPinnedOrders = errors.New("the database is full of pinned orders; no orders can be removed in order to make space")
 
+// OrderSource is one of the ways in which the node can receive an order.
+type OrderSource string
+
+const (
+	// OrderSourceRPC is used for orders added via AddOrders (e.g. through the
+	// JSON-RPC API).
+	OrderSourceRPC OrderSource = "rpc"
+	// OrderSourceGossip is used for orders received from peers via GossipSub.
+	OrderSourceGossip OrderSource = "gossip"
+	// OrderSourceOrderSync is used for orders received from peers via the
+	// ordersync protocol.
+	OrderSourceOrderSync OrderSource = "ordersync"
+)
+
 // Order is the database representation a 0x order along with some relevant metadata
 type Order struct {
 	Hash        common.Hash
@@ -41,6 +56,21 @@ type Order struct {
 	// IsPinned indicates whether or not the order is pinned. Pinned orders are
 	// not removed from the database unless they become unfillable.
 	IsPinned bool
+	// AddedLocally indicates whether the order was added via AddOrders (e.g.
+	// through the JSON-RPC API) rather than received from a peer. Only such
+	// orders can be removed with RemoveOrders.
+	AddedLocally bool
+	// Sources are all of the sources that delivered the order, in the order in
+	// which they first delivered it. Orders stored before sources were tracked
+	// have no sources.
+	Sources []OrderSource
+	// OrderFilterHash is the hash of the order filter that was in use when the
+	// order was added (see orderfilter.Filter.Hash).
+	OrderFilterHash string
+	// IsProvisional indicates whether the order was validated while Mesh was
+	// catching up to the latest block, i.e. against state that may be stale.
+	// Provisional orders are re-validated once Mesh has caught up.
+	IsProvisional bool
 }
 
 // ID returns the Order's ID
@@ -48,12 +78,71 @@ func (o Order) ID() []byte {
 	return o.Hash.Bytes()
 }
 
+// orderColdFields are the fields of an Order which rarely change after it was
+// added. See db.HotFieldsModel.
+type orderColdFields struct {
+	Hash            common.Hash
+	SignedOrder     *zeroex.SignedOrder
+	IsPinned        bool
+	AddedLocally    bool
+	Sources         []OrderSource
+	OrderFilterHash string
+}
+
+// orderHotFields are the fields of an Order which are updated whenever it is
+// revalidated. See db.HotFieldsModel.
+type orderHotFields struct {
+	LastUpdated              time.Time
+	FillableTakerAssetAmount *big.Int
+	IsRemoved                bool
+	IsProvisional            bool
+}
+
+// ColdFields returns the fields of the Order which rarely change. Together
+// with HotFields, it allows revalidating an order without rewriting the
+// signed order.
+func (o Order) ColdFields() interface{} {
+	return orderColdFields{
+		Hash:            o.Hash,
+		SignedOrder:     o.SignedOrder,
+		IsPinned:        o.IsPinned,
+		AddedLocally:    o.AddedLocally,
+		Sources:         o.Sources,
+		OrderFilterHash: o.OrderFilterHash,
+	}
+}
+
+// HotFields returns the fields of the Order which change whenever it is
+// revalidated.
+func (o Order) HotFields() interface{} {
+	return orderHotFields{
+		LastUpdated:              o.LastUpdated,
+		FillableTakerAssetAmount: o.FillableTakerAssetAmount,
+		IsRemoved:                o.IsRemoved,
+		IsProvisional:            o.IsProvisional,
+	}
+}
+
+// HasSource returns true if the order was delivered by the given source.
+func (o Order) HasSource(source OrderSource) bool {
+	for _, s := range o.Sources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
 // Metadata is the database representation of MeshDB instance metadata
 type Metadata struct {
 	EthereumChainID                   int
 	MaxExpirationTime                 *big.Int
 	EthRPCRequestsSentInCurrentUTCDay int
 	StartOfCurrentUTCDay              time.Time
+	// TopicMigrationStartTime is when the node first started with a topic
+	// migration window (see core.Config.TopicMigrationWindow). It is zero if
+	// it never did.
+	TopicMigrationStartTime time.Time
 }
 
 // ID returns the id used for the metadata collection (one per DB)
@@ -67,7 +156,15 @@ type MeshDB struct {
 	metadata                 *MetadataCollection
 	MiniHeaders              *MiniHeadersCollection
 	Orders                   *OrdersCollection
+	OrderSyncRecords         *OrderSyncRecordsCollection
+	PeerBans                 *PeerBansCollection
+	IPRangeBans              *IPRangeBansCollection
+	Allowlist                *AllowlistCollection
+	OrderStateRecords        *OrderStateRecordsCollection
+	OrderEventRecords        *OrderEventRecordsCollection
+	OrderAnnotations         *OrderAnnotationsCollection
 	MiniHeaderRetentionLimit int
+	orderAnnotationsMu       sync.Mutex
 }
 
 // MiniHeadersCollection represents a DB collection of mini Ethereum block headers
@@ -85,6 +182,7 @@ type OrdersCollection struct {
 	LastUpdatedIndex                             *db.Index
 	IsRemovedIndex                               *db.Index
 	ExpirationTimeIndex                          *db.Index
+	IsProvisionalIndex                           *db.Index
 }
 
 // MetadataCollection represents a DB collection used to store instance metadata
@@ -114,11 +212,53 @@ func New(path string, contractAddresses ethereum.ContractAddresses) (*MeshDB, er
 		return nil, err
 	}
 
+	orderSyncRecords, err := setupOrderSyncRecords(database)
+	if err != nil {
+		return nil, err
+	}
+
+	peerBans, err := setupPeerBans(database)
+	if err != nil {
+		return nil, err
+	}
+
+	ipRangeBans, err := setupIPRangeBans(database)
+	if err != nil {
+		return nil, err
+	}
+
+	allowlist, err := setupAllowlist(database)
+	if err != nil {
+		return nil, err
+	}
+
+	orderStateRecords, err := setupOrderStateRecords(database)
+	if err != nil {
+		return nil, err
+	}
+
+	orderEventRecords, err := setupOrderEventRecords(database)
+	if err != nil {
+		return nil, err
+	}
+
+	orderAnnotations, err := setupOrderAnnotations(database)
+	if err != nil {
+		return nil, err
+	}
+
 	return &MeshDB{
 		database:                 database,
 		metadata:                 metadata,
 		MiniHeaders:              miniHeaders,
 		Orders:                   orders,
+		OrderSyncRecords:         orderSyncRecords,
+		PeerBans:                 peerBans,
+		IPRangeBans:              ipRangeBans,
+		Allowlist:                allowlist,
+		OrderStateRecords:        orderStateRecords,
+		OrderEventRecords:        orderEventRecords,
+		OrderAnnotations:         orderAnnotations,
 		MiniHeaderRetentionLimit: defaultMiniHeaderRetentionLimit,
 	}, nil
 }
@@ -138,7 +278,7 @@ func setupOrders(database *db.DB, contractAddresses ethereum.ContractAddresses)
 		// unsigned 256 bit integer is 80, so we pad with zeroes such that the
 		// length of the number is always 80.
 		signedOrder := m.(*Order).SignedOrder
-		index := []byte(fmt.Sprintf("%s|%s", signedOrder.MakerAddress.Hex(), uint256ToConstantLengthBytes(signedOrder.Salt)))
+		index := []byte(fmt.Sprintf("%s|%s", addressKey(signedOrder.MakerAddress), uint256ToConstantLengthBytes(signedOrder.Salt)))
 		return index
 	})
 	// TODO(fabio): Optimize this index callback since it gets called many times under-the-hood.
@@ -154,7 +294,7 @@ func setupOrders(database *db.DB, contractAddresses ethereum.ContractAddresses)
 		}
 		indexValues := make([][]byte, len(singleAssetDatas))
 		for i, singleAssetData := range singleAssetDatas {
-			indexValue := []byte(order.SignedOrder.MakerAddress.Hex() + "|" + singleAssetData.Address.Hex() + "|")
+			indexValue := []byte(addressKey(order.SignedOrder.MakerAddress) + "|" + addressKey(singleAssetData.Address) + "|")
 			if singleAssetData.TokenID != nil {
 				indexValue = append(indexValue, singleAssetData.TokenID.Bytes()...)
 			}
@@ -169,7 +309,7 @@ func setupOrders(database *db.DB, contractAddresses ethereum.ContractAddresses)
 			// by null bytes ("0x0"). We still want to index this value so we can look
 			// up orders without a maker fee.
 			return [][]byte{
-				[]byte(order.SignedOrder.MakerAddress.Hex() + "|" + common.ToHex(constants.NullBytes) + "|"),
+				[]byte(addressKey(order.SignedOrder.MakerAddress) + "|" + common.ToHex(constants.NullBytes) + "|"),
 			}
 		}
 		singleAssetDatas, err := parseContractAddressesAndTokenIdsFromAssetData(order.SignedOrder.MakerFeeAssetData, contractAddresses)
@@ -181,7 +321,7 @@ func setupOrders(database *db.DB, contractAddresses ethereum.ContractAddresses)
 
 		indexValues := make([][]byte, len(singleAssetDatas))
 		for i, singleAssetData := range singleAssetDatas {
-			indexValue := []byte(order.SignedOrder.MakerAddress.Hex() + "|" + singleAssetData.Address.Hex() + "|")
+			indexValue := []byte(addressKey(order.SignedOrder.MakerAddress) + "|" + addressKey(singleAssetData.Address) + "|")
 			if singleAssetData.TokenID != nil {
 				indexValue = append(indexValue, singleAssetData.TokenID.Bytes()...)
 			}
@@ -211,6 +351,22 @@ func setupOrders(database *db.DB, contractAddresses ethereum.ContractAddresses)
 		return []byte(fmt.Sprintf("%s|%s", pinnedString, expTimeString))
 	})
 
+	isProvisionalIndex := col.AddIndex("isProvisional", func(m db.Model) []byte {
+		order := m.(*Order)
+		// false = 0; true = 1
+		if order.IsProvisional {
+			return []byte{1}
+		}
+		return []byte{0}
+	})
+
+	// Orders stored by earlier versions of Mesh include their hot fields.
+	if migrated, err := col.SplitHotFields(); err != nil {
+		return nil, err
+	} else if migrated > 0 {
+		log.WithField("orders", migrated).Info("split the hot fields of stored orders")
+	}
+
 	return &OrdersCollection{
 		Collection:                                   col,
 		MakerAddressTokenAddressTokenIDIndex:         makerAddressTokenAddressTokenIDIndex,
@@ -219,6 +375,7 @@ func setupOrders(database *db.DB, contractAddresses ethereum.ContractAddresses)
 		LastUpdatedIndex:                             lastUpdatedIndex,
 		IsRemovedIndex:                               isRemovedIndex,
 		ExpirationTimeIndex:                          expirationTimeIndex,
+		IsProvisionalIndex:                           isProvisionalIndex,
 	}, nil
 }
 
@@ -255,6 +412,23 @@ func (m *MeshDB) Close() {
 	m.database.Close()
 }
 
+// DiskUsage returns the total size in bytes of the files used by the database.
+func (m *MeshDB) DiskUsage() (int64, error) {
+	return m.database.DiskUsage()
+}
+
+// SetSlowQueryThreshold causes all database queries which take at least
+// threshold to run to be logged. A threshold of 0 disables the slow-query log.
+func (m *MeshDB) SetSlowQueryThreshold(threshold time.Duration) {
+	m.database.SetSlowQueryThreshold(threshold)
+}
+
+// Compact compacts the database so that the disk space used by deleted orders
+// and other models is reclaimed.
+func (m *MeshDB) Compact() error {
+	return m.database.Compact()
+}
+
 // FindAllMiniHeadersSortedByNumber returns all MiniHeaders sorted in ascending block number order
 func (m *MeshDB) FindAllMiniHeadersSortedByNumber() ([]*miniheader.MiniHeader, error) {
 	miniHeaders := []*miniheader.MiniHeader{}
@@ -394,7 +568,7 @@ func (m *MeshDB) clearMiniHeadersOnce(filter *db.Filter) (removed int, err error
 
 // FindOrdersByMakerAddress finds all orders belonging to a particular maker address
 func (m *MeshDB) FindOrdersByMakerAddress(makerAddress common.Address) ([]*Order, error) {
-	prefix := []byte(makerAddress.Hex() + "|")
+	prefix := []byte(addressKey(makerAddress) + "|")
 	filter := m.Orders.MakerAddressTokenAddressTokenIDIndex.PrefixFilter(prefix)
 	orders := []*Order{}
 	if err := m.Orders.NewQuery(filter).Run(&orders); err != nil {
@@ -406,7 +580,7 @@ func (m *MeshDB) FindOrdersByMakerAddress(makerAddress common.Address) ([]*Order
 // FindOrdersByMakerAddressTokenAddressAndTokenID finds all orders belonging to a particular maker
 // address where makerAssetData encodes for a particular token contract and optionally a token ID
 func (m *MeshDB) FindOrdersByMakerAddressTokenAddressAndTokenID(makerAddress, tokenAddress common.Address, tokenID *big.Int) ([]*Order, error) {
-	prefix := []byte(makerAddress.Hex() + "|" + tokenAddress.Hex() + "|")
+	prefix := []byte(addressKey(makerAddress) + "|" + addressKey(tokenAddress) + "|")
 	if tokenID != nil {
 		prefix = append(prefix, tokenID.Bytes()...)
 	}
@@ -425,9 +599,9 @@ func (m *MeshDB) FindOrdersByMakerAddressTokenAddressAndTokenID(makerAddress, to
 func (m *MeshDB) FindOrdersByMakerAddressMakerFeeAssetAddressAndTokenID(makerAddress, makerFeeAssetAddress common.Address, tokenID *big.Int) ([]*Order, error) {
 	var prefix []byte
 	if makerFeeAssetAddress == constants.NullAddress {
-		prefix = []byte(makerAddress.Hex() + "|" + common.ToHex(constants.NullBytes) + "|")
+		prefix = []byte(addressKey(makerAddress) + "|" + common.ToHex(constants.NullBytes) + "|")
 	} else {
-		prefix = []byte(makerAddress.Hex() + "|" + makerFeeAssetAddress.Hex() + "|")
+		prefix = []byte(addressKey(makerAddress) + "|" + addressKey(makerFeeAssetAddress) + "|")
 		if tokenID != nil {
 			prefix = append(prefix, tokenID.Bytes()...)
 		}
@@ -448,8 +622,8 @@ func (m *MeshDB) FindOrdersByMakerAddressAndMaxSalt(makerAddress common.Address,
 	// is inclusive of the value supplied. In order to make this helper method more useful to our
 	// particular use-case, we add 1 to the supplied salt (making the query inclusive instead)
 	saltPlusOne := new(big.Int).Add(salt, big.NewInt(1))
-	start := []byte(fmt.Sprintf("%s|%080s", makerAddress.Hex(), "0"))
-	limit := []byte(fmt.Sprintf("%s|%s", makerAddress.Hex(), uint256ToConstantLengthBytes(saltPlusOne)))
+	start := []byte(fmt.Sprintf("%s|%080s", addressKey(makerAddress), "0"))
+	limit := []byte(fmt.Sprintf("%s|%s", addressKey(makerAddress), uint256ToConstantLengthBytes(saltPlusOne)))
 	filter := m.Orders.MakerAddressAndSaltIndex.RangeFilter(start, limit)
 	orders := []*Order{}
 	if err := m.Orders.NewQuery(filter).Run(&orders); err != nil {
@@ -481,6 +655,24 @@ func (m *MeshDB) FindRemovedOrders() ([]*Order, error) {
 	return removedOrders, nil
 }
 
+// FindProvisionalOrders finds all orders that were validated while Mesh was
+// catching up to the latest block (see Order.IsProvisional).
+func (m *MeshDB) FindProvisionalOrders() ([]*Order, error) {
+	var provisionalOrders []*Order
+	isProvisionalFilter := m.Orders.IsProvisionalIndex.ValueFilter([]byte{1})
+	if err := m.Orders.NewQuery(isProvisionalFilter).Run(&provisionalOrders); err != nil {
+		return nil, err
+	}
+	return provisionalOrders, nil
+}
+
+// CountProvisionalOrders returns the number of orders that were validated
+// while Mesh was catching up to the latest block.
+func (m *MeshDB) CountProvisionalOrders() (int, error) {
+	isProvisionalFilter := m.Orders.IsProvisionalIndex.ValueFilter([]byte{1})
+	return m.Orders.NewQuery(isProvisionalFilter).Count()
+}
+
 // GetMetadata returns the metadata (or a db.NotFoundError if no metadata has been found).
 func (m *MeshDB) GetMetadata() (*Metadata, error) {
 	var metadata Metadata
@@ -531,6 +723,11 @@ func parseContractAddressesAndTokenIdsFromAssetData(assetData []byte, contractAd
 
 	assetDataName, err := assetDataDecoder.GetName(assetData)
 	if err != nil {
+		if _, ok := err.(zeroex.UnrecognizedAssetDataError); ok {
+			// We can't index assetData of unrecognized asset proxies, but orders
+			// involving them can still be stored.
+			return singleAssetDatas, nil
+		}
 		return nil, err
 	}
 	switch assetDataName {
@@ -608,11 +805,31 @@ func parseContractAddressesAndTokenIdsFromAssetData(assetData []byte, contractAd
 		}
 		singleAssetDatas = append(singleAssetDatas, a)
 	default:
-		return nil, fmt.Errorf("unrecognized assetData type name found: %s", assetDataName)
+		nestedAssetDatas, err := assetDataDecoder.DecodeNestedAssetData(assetData)
+		if err != nil {
+			return nil, fmt.Errorf("unrecognized assetData type name found: %s", assetDataName)
+		}
+		for _, assetData := range nestedAssetDatas {
+			as, err := parseContractAddressesAndTokenIdsFromAssetData(assetData, contractAddresses)
+			if err != nil {
+				return nil, err
+			}
+			singleAssetDatas = append(singleAssetDatas, as...)
+		}
 	}
 	return singleAssetDatas, nil
 }
 
+// addressKey returns the representation of the given address in index values,
+// i.e. the checksummed hex encoding. Since it is derived from the parsed
+// address instead of the string an order was submitted with, orders are
+// indexed and looked up the same way regardless of the casing used by clients.
+// Indexes have always been built this way, so existing databases don't need
+// to be migrated.
+func addressKey(address common.Address) string {
+	return address.Hex()
+}
+
 func uint256ToConstantLengthBytes(v *big.Int) []byte {
 	return []byte(fmt.Sprintf("%080s", v.String()))
 }
@@ -1,7 +1,10 @@
 package meshdb
 
 import (
+	"bytes"
 	"math/big"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -98,6 +101,84 @@ func TestOrderCRUDOperations(t *testing.T) {
 	assert.IsType(t, db.NotFoundError{}, err)
 }
 
+// TestOrderHotAndColdFields checks that every field of Order is stored either
+// as a hot field or as a cold field (but not both), so that new fields aren't
+// silently dropped when an order is stored.
+func TestOrderHotAndColdFields(t *testing.T) {
+	t.Parallel()
+	splitFields := map[string]reflect.Type{}
+	for _, fieldsType := range []reflect.Type{reflect.TypeOf(orderColdFields{}), reflect.TypeOf(orderHotFields{})} {
+		for i := 0; i < fieldsType.NumField(); i++ {
+			field := fieldsType.Field(i)
+			_, found := splitFields[field.Name]
+			require.False(t, found, "field %s is both a hot and a cold field", field.Name)
+			splitFields[field.Name] = field.Type
+		}
+	}
+	orderType := reflect.TypeOf(Order{})
+	require.Equal(t, orderType.NumField(), len(splitFields), "orderColdFields and orderHotFields have fields which are not in Order")
+	for i := 0; i < orderType.NumField(); i++ {
+		field := orderType.Field(i)
+		fieldType, found := splitFields[field.Name]
+		require.True(t, found, "field %s is neither a hot nor a cold field", field.Name)
+		assert.Equal(t, field.Type, fieldType, "field %s has a different type", field.Name)
+	}
+}
+
+func TestFindOrdersByMakerAddressIgnoresCasing(t *testing.T) {
+	meshDB, err := New("/tmp/meshdb_testing/"+uuid.New().String(), contractAddresses)
+	require.NoError(t, err)
+	defer meshDB.Close()
+
+	makerAddress := constants.GanacheAccount0
+	signedOrder, err := zeroex.SignTestOrder(&zeroex.Order{
+		ChainID:               big.NewInt(constants.TestChainID),
+		ExchangeAddress:       contractAddresses.Exchange,
+		MakerAddress:          makerAddress,
+		TakerAddress:          constants.NullAddress,
+		SenderAddress:         constants.NullAddress,
+		FeeRecipientAddress:   constants.NullAddress,
+		MakerAssetData:        common.Hex2Bytes("f47261b000000000000000000000000034d402f14d58e001d8efbe6585051bf9706aa064"),
+		MakerFeeAssetData:     constants.NullBytes,
+		TakerAssetData:        common.Hex2Bytes("f47261b0000000000000000000000000871dd7c2b4b25e1aa18728e9d5f2af4c4e431f5c"),
+		TakerFeeAssetData:     constants.NullBytes,
+		Salt:                  big.NewInt(1548619145450),
+		MakerFee:              big.NewInt(0),
+		TakerFee:              big.NewInt(0),
+		MakerAssetAmount:      big.NewInt(1000),
+		TakerAssetAmount:      big.NewInt(2000),
+		ExpirationTimeSeconds: big.NewInt(1548619325),
+	})
+	require.NoError(t, err)
+
+	// Decode the order from JSON with an uppercase maker address, as a client
+	// might submit it.
+	signedOrderJSON, err := signedOrder.MarshalJSON()
+	require.NoError(t, err)
+	lowercaseMakerAddress := zeroex.NormalizedAddressHex(makerAddress)
+	uppercaseMakerAddress := "0x" + strings.ToUpper(lowercaseMakerAddress[2:])
+	signedOrderJSON = bytes.Replace(signedOrderJSON, []byte(lowercaseMakerAddress), []byte(uppercaseMakerAddress), 1)
+	var decodedSignedOrder zeroex.SignedOrder
+	require.NoError(t, decodedSignedOrder.UnmarshalJSON(signedOrderJSON))
+	orderHash, err := decodedSignedOrder.ComputeOrderHash()
+	require.NoError(t, err)
+	require.NoError(t, meshDB.Orders.Insert(&Order{
+		Hash:                     orderHash,
+		SignedOrder:              &decodedSignedOrder,
+		FillableTakerAssetAmount: big.NewInt(1),
+		LastUpdated:              time.Now().UTC(),
+	}))
+
+	for _, rawMakerAddress := range []string{lowercaseMakerAddress, uppercaseMakerAddress, makerAddress.Hex()} {
+		parsedMakerAddress, err := zeroex.ParseAddress(rawMakerAddress)
+		require.NoError(t, err)
+		orders, err := meshDB.FindOrdersByMakerAddress(parsedMakerAddress)
+		require.NoError(t, err)
+		require.Len(t, orders, 1, rawMakerAddress)
+		assert.Equal(t, orderHash, orders[0].Hash, rawMakerAddress)
+	}
+}
+
 func TestParseContractAddressesAndTokenIdsFromAssetData(t *testing.T) {
 	// ERC20 AssetData
 	erc20AssetData := common.Hex2Bytes("f47261b000000000000000000000000038ae374ecf4db50b0ff37125b591a04997106a32")
@@ -499,3 +580,295 @@ func TestPruneMiniHeadersAboveRetentionLimit(t *testing.T) {
 	remainingMiniHeaders, err := meshDB.MiniHeaders.Count()
 	assert.Equal(t, defaultMiniHeaderRetentionLimit, remainingMiniHeaders, "wrong number of MiniHeaders remaining")
 }
+
+func TestOrderSyncRecords(t *testing.T) {
+	meshDB, err := New("/tmp/meshdb_testing/"+uuid.New().String(), contractAddresses)
+	require.NoError(t, err)
+	defer meshDB.Close()
+
+	// Use a whole second so that the start time of newRecordB only differs in
+	// its fractional seconds.
+	now := time.Now().UTC().Truncate(time.Second)
+	oldRecord := &OrderSyncRecord{
+		ProviderID:     "providerA",
+		StartTime:      now.Add(-48 * time.Hour),
+		OrdersReceived: 10,
+		OrdersAccepted: 10,
+		OrdersRejected: map[string]int{},
+		Succeeded:      true,
+	}
+	newRecordA := &OrderSyncRecord{
+		ProviderID:     "providerA",
+		StartTime:      now,
+		OrdersReceived: 5,
+		OrdersAccepted: 3,
+		OrdersRejected: map[string]int{"OrderExpired": 2},
+		Succeeded:      true,
+	}
+	newRecordB := &OrderSyncRecord{
+		ProviderID:     "providerB",
+		StartTime:      now.Add(500 * time.Millisecond),
+		OrdersRejected: map[string]int{},
+		Error:          "context deadline exceeded",
+	}
+	for _, record := range []*OrderSyncRecord{oldRecord, newRecordA, newRecordB} {
+		require.NoError(t, meshDB.OrderSyncRecords.Insert(record))
+	}
+
+	recordsForA, err := meshDB.FindOrderSyncRecordsByProviderID("providerA")
+	require.NoError(t, err)
+	assert.Len(t, recordsForA, 2)
+
+	removed, err := meshDB.ClearOrderSyncRecordsBefore(now.Add(-24 * time.Hour))
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	assert.Equal(t, oldRecord.ID(), removed[0].ID())
+	remaining, err := meshDB.FindAllOrderSyncRecords()
+	require.NoError(t, err)
+	require.Len(t, remaining, 2)
+	assert.Equal(t, "providerA", remaining[0].ProviderID)
+	assert.Equal(t, map[string]int{"OrderExpired": 2}, remaining[0].OrdersRejected)
+	assert.Equal(t, "providerB", remaining[1].ProviderID)
+
+	visited := []string{}
+	require.NoError(t, meshDB.ForEachOrderSyncRecord(func(record *OrderSyncRecord) error {
+		visited = append(visited, record.ProviderID)
+		return nil
+	}))
+	assert.Equal(t, []string{"providerA", "providerB"}, visited)
+}
+
+func TestPeerBans(t *testing.T) {
+	meshDB, err := New("/tmp/meshdb_testing/"+uuid.New().String(), contractAddresses)
+	require.NoError(t, err)
+	defer meshDB.Close()
+
+	now := time.Now().UTC()
+	require.NoError(t, meshDB.SavePeerBan(&PeerBan{PeerID: "peerA", Reason: "spam", CreatedAt: now}))
+	require.NoError(t, meshDB.SavePeerBan(&PeerBan{PeerID: "peerB", Reason: "expired", Expiry: now.Add(-time.Minute), CreatedAt: now}))
+	// Saving a ban for the same peer replaces the existing one.
+	require.NoError(t, meshDB.SavePeerBan(&PeerBan{PeerID: "peerA", Reason: "invalid orders", CreatedAt: now}))
+
+	bans, err := meshDB.FindAllPeerBans()
+	require.NoError(t, err)
+	require.Len(t, bans, 1)
+	assert.Equal(t, "peerA", bans[0].PeerID)
+	assert.Equal(t, "invalid orders", bans[0].Reason)
+
+	// Expired bans are removed.
+	count, err := meshDB.PeerBans.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	require.NoError(t, meshDB.DeletePeerBan("peerA"))
+	bans, err = meshDB.FindAllPeerBans()
+	require.NoError(t, err)
+	assert.Len(t, bans, 0)
+}
+
+func TestAccessLists(t *testing.T) {
+	meshDB, err := New("/tmp/meshdb_testing/"+uuid.New().String(), contractAddresses)
+	require.NoError(t, err)
+	defer meshDB.Close()
+
+	now := time.Now().UTC()
+	require.NoError(t, meshDB.SaveIPRangeBan(&IPRangeBan{IPRange: "10.0.0.0/8", Reason: "spam", CreatedAt: now}))
+	// Saving a ban for the same IP range replaces the existing one.
+	require.NoError(t, meshDB.SaveIPRangeBan(&IPRangeBan{IPRange: "10.0.0.0/8", Reason: "invalid orders", CreatedAt: now}))
+	ipRangeBans, err := meshDB.FindAllIPRangeBans()
+	require.NoError(t, err)
+	require.Len(t, ipRangeBans, 1)
+	assert.Equal(t, "10.0.0.0/8", ipRangeBans[0].IPRange)
+	assert.Equal(t, "invalid orders", ipRangeBans[0].Reason)
+	require.NoError(t, meshDB.DeleteIPRangeBan("10.0.0.0/8"))
+	ipRangeBans, err = meshDB.FindAllIPRangeBans()
+	require.NoError(t, err)
+	assert.Len(t, ipRangeBans, 0)
+
+	require.NoError(t, meshDB.SaveAllowlistEntry(&AllowlistEntry{Value: "peerA", CreatedAt: now}))
+	require.NoError(t, meshDB.SaveAllowlistEntry(&AllowlistEntry{Value: "192.168.0.0/16", CreatedAt: now}))
+	// Saving an existing entry is a no-op.
+	require.NoError(t, meshDB.SaveAllowlistEntry(&AllowlistEntry{Value: "peerA", CreatedAt: now}))
+	entries, err := meshDB.FindAllAllowlistEntries()
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+	require.NoError(t, meshDB.DeleteAllowlistEntry("peerA"))
+	entries, err = meshDB.FindAllAllowlistEntries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "192.168.0.0/16", entries[0].Value)
+}
+
+func TestOrderAnnotations(t *testing.T) {
+	meshDB, err := New("/tmp/meshdb_testing/"+uuid.New().String(), contractAddresses)
+	require.NoError(t, err)
+	defer meshDB.Close()
+
+	orders := insertRawOrders(t, meshDB, []*zeroex.Order{
+		{
+			ChainID:               big.NewInt(constants.TestChainID),
+			ExchangeAddress:       contractAddresses.Exchange,
+			MakerAddress:          constants.GanacheAccount0,
+			TakerAddress:          constants.NullAddress,
+			SenderAddress:         constants.NullAddress,
+			FeeRecipientAddress:   constants.NullAddress,
+			MakerAssetData:        common.Hex2Bytes("025717920000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c480000000000000000000000000000000000000000000000000000000000000001"),
+			MakerFeeAssetData:     constants.NullBytes,
+			TakerAssetData:        constants.NullBytes,
+			TakerFeeAssetData:     constants.NullBytes,
+			Salt:                  big.NewInt(1),
+			MakerFee:              big.NewInt(0),
+			TakerFee:              big.NewInt(0),
+			MakerAssetAmount:      big.NewInt(1),
+			TakerAssetAmount:      big.NewInt(1),
+			ExpirationTimeSeconds: big.NewInt(1548619325),
+		},
+	}, false)
+	orderHash := orders[0].Hash
+	otherOrderHash := common.HexToHash("0x1")
+
+	annotations, err := meshDB.UpdateOrderAnnotations(orderHash, map[string]string{"strategy": "a", "quoteID": "1"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"strategy": "a", "quoteID": "1"}, annotations)
+
+	// Annotations are merged and empty values remove them.
+	annotations, err = meshDB.UpdateOrderAnnotations(orderHash, map[string]string{"strategy": "b", "quoteID": ""})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"strategy": "b"}, annotations)
+
+	_, err = meshDB.UpdateOrderAnnotations(otherOrderHash, map[string]string{"strategy": "c"})
+	require.NoError(t, err)
+	found, err := meshDB.FindOrderAnnotations([]common.Hash{orderHash, otherOrderHash, common.HexToHash("0x2")})
+	require.NoError(t, err)
+	assert.Equal(t, map[common.Hash]map[string]string{
+		orderHash:      {"strategy": "b"},
+		otherOrderHash: {"strategy": "c"},
+	}, found)
+
+	// The annotations of orders which are not stored are orphaned.
+	numDeleted, err := meshDB.DeleteOrphanedOrderAnnotations()
+	require.NoError(t, err)
+	assert.Equal(t, 1, numDeleted)
+	count, err := meshDB.OrderAnnotations.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// Removing all annotations deletes the record.
+	annotations, err = meshDB.UpdateOrderAnnotations(orderHash, map[string]string{"strategy": ""})
+	require.NoError(t, err)
+	assert.Empty(t, annotations)
+	count, err = meshDB.OrderAnnotations.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestOrderStateHistory(t *testing.T) {
+	meshDB, err := New("/tmp/meshdb_testing/"+uuid.New().String(), contractAddresses)
+	require.NoError(t, err)
+	defer meshDB.Close()
+
+	orderA := common.HexToHash("0xa")
+	orderB := common.HexToHash("0xb")
+	now := time.Now().UTC()
+	require.NoError(t, meshDB.SaveOrderStateRecords([]*OrderStateRecord{
+		{OrderHash: orderA, BlockNumber: big.NewInt(10), EndState: "ADDED", FillableTakerAssetAmount: big.NewInt(100), Timestamp: now},
+		{OrderHash: orderB, BlockNumber: big.NewInt(11), EndState: "ADDED", FillableTakerAssetAmount: big.NewInt(50), Timestamp: now},
+	}))
+	require.NoError(t, meshDB.SaveOrderStateRecords([]*OrderStateRecord{
+		{OrderHash: orderA, BlockNumber: big.NewInt(12), EndState: "FILLED", FillableTakerAssetAmount: big.NewInt(40), Timestamp: now},
+		{OrderHash: orderB, BlockNumber: big.NewInt(13), EndState: "CANCELLED", FillableTakerAssetAmount: big.NewInt(0), Timestamp: now},
+		// Replaces the previous record for the same order and block.
+		{OrderHash: orderB, BlockNumber: big.NewInt(13), EndState: "FULLY_FILLED", FillableTakerAssetAmount: big.NewInt(0), Timestamp: now},
+	}))
+
+	_, err = meshDB.FindOrderStateAtBlock(orderA, big.NewInt(9))
+	assert.IsType(t, db.NotFoundError{}, err)
+	record, err := meshDB.FindOrderStateAtBlock(orderA, big.NewInt(11))
+	require.NoError(t, err)
+	assert.Equal(t, "ADDED", record.EndState)
+	record, err = meshDB.FindOrderStateAtBlock(orderB, big.NewInt(20))
+	require.NoError(t, err)
+	assert.Equal(t, "FULLY_FILLED", record.EndState)
+
+	fillable, err := meshDB.FindOrdersFillableAtBlock(big.NewInt(12))
+	require.NoError(t, err)
+	require.Len(t, fillable, 2)
+	assert.Equal(t, orderA, fillable[0].OrderHash)
+	assert.Equal(t, big.NewInt(40), fillable[0].FillableTakerAssetAmount)
+	assert.Equal(t, orderB, fillable[1].OrderHash)
+	fillable, err = meshDB.FindOrdersFillableAtBlock(big.NewInt(13))
+	require.NoError(t, err)
+	require.Len(t, fillable, 1)
+	assert.Equal(t, orderA, fillable[0].OrderHash)
+
+	// Compaction keeps only the latest record of orders which are still
+	// fillable.
+	require.NoError(t, meshDB.CompactOrderStateRecordsBefore(big.NewInt(14)))
+	count, err := meshDB.OrderStateRecords.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	fillable, err = meshDB.FindOrdersFillableAtBlock(big.NewInt(14))
+	require.NoError(t, err)
+	require.Len(t, fillable, 1)
+	assert.Equal(t, orderA, fillable[0].OrderHash)
+
+	// Existing records are not replaced by InsertMissingOrderStateRecords.
+	numInserted, err := meshDB.InsertMissingOrderStateRecords([]*OrderStateRecord{
+		{OrderHash: orderA, BlockNumber: big.NewInt(12), EndState: "CANCELLED", FillableTakerAssetAmount: big.NewInt(0), Timestamp: now},
+		{OrderHash: orderA, BlockNumber: big.NewInt(15), EndState: "FULLY_FILLED", FillableTakerAssetAmount: big.NewInt(0), Timestamp: now},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, numInserted)
+	record, err = meshDB.FindOrderStateAtBlock(orderA, big.NewInt(14))
+	require.NoError(t, err)
+	assert.Equal(t, "FILLED", record.EndState)
+	record, err = meshDB.FindOrderStateAtBlock(orderA, big.NewInt(15))
+	require.NoError(t, err)
+	assert.Equal(t, "FULLY_FILLED", record.EndState)
+}
+
+func TestOrderEventLog(t *testing.T) {
+	meshDB, err := New("/tmp/meshdb_testing/"+uuid.New().String(), contractAddresses)
+	require.NoError(t, err)
+	defer meshDB.Close()
+
+	oldest, latest, err := meshDB.GetOrderEventLogBounds()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), oldest)
+	assert.Equal(t, uint64(0), latest)
+
+	orderEvents := make([]*zeroex.OrderEvent, 12)
+	for i := range orderEvents {
+		orderEvents[i] = &zeroex.OrderEvent{
+			Timestamp:                time.Now().UTC(),
+			OrderHash:                common.BigToHash(big.NewInt(int64(i))),
+			EndState:                 zeroex.ESOrderAdded,
+			FillableTakerAssetAmount: big.NewInt(int64(i)),
+			ContractEvents:           []*zeroex.ContractEvent{},
+			SequenceNumber:           uint64(i + 1),
+		}
+	}
+	require.NoError(t, meshDB.AppendOrderEvents(orderEvents[:10]))
+	require.NoError(t, meshDB.AppendOrderEvents(orderEvents[10:]))
+
+	found, err := meshDB.FindOrderEventsSince(8, 10)
+	require.NoError(t, err)
+	require.Len(t, found, 4)
+	for i, orderEvent := range found {
+		assert.Equal(t, uint64(9+i), orderEvent.SequenceNumber)
+		assert.Equal(t, orderEvents[8+i].OrderHash, orderEvent.OrderHash)
+	}
+	found, err = meshDB.FindOrderEventsSince(0, 3)
+	require.NoError(t, err)
+	require.Len(t, found, 3)
+	assert.Equal(t, uint64(1), found[0].SequenceNumber)
+
+	require.NoError(t, meshDB.DeleteOrderEventsBefore(8))
+	oldest, latest, err = meshDB.GetOrderEventLogBounds()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(8), oldest)
+	assert.Equal(t, uint64(12), latest)
+	found, err = meshDB.FindOrderEventsSince(0, 100)
+	require.NoError(t, err)
+	assert.Len(t, found, 5)
+}
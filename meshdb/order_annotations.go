@@ -0,0 +1,131 @@
+package meshdb
+
+import (
+	"time"
+
+	"github.com/0xProject/0x-mesh/db"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// OrderAnnotations is the database representation of the key/value
+// annotations attached to an order by the owner of the node. Annotations are
+// stored separately from orders so that they are never overwritten by order
+// updates and never shared with peers.
+type OrderAnnotations struct {
+	OrderHash   common.Hash
+	Annotations map[string]string
+	UpdatedAt   time.Time
+}
+
+// ID returns the OrderAnnotations' ID
+func (a OrderAnnotations) ID() []byte {
+	return a.OrderHash.Bytes()
+}
+
+// OrderAnnotationsCollection represents a DB collection of order annotations
+type OrderAnnotationsCollection struct {
+	*db.Collection
+}
+
+func setupOrderAnnotations(database *db.DB) (*OrderAnnotationsCollection, error) {
+	col, err := database.NewCollection("orderAnnotations", &OrderAnnotations{})
+	if err != nil {
+		return nil, err
+	}
+	return &OrderAnnotationsCollection{col}, nil
+}
+
+// UpdateOrderAnnotations merges the given annotations into the existing
+// annotations of the order with the given hash and returns the result.
+// Annotations with an empty value are removed. If no annotations remain, the
+// record is deleted.
+func (m *MeshDB) UpdateOrderAnnotations(orderHash common.Hash, annotations map[string]string) (map[string]string, error) {
+	m.orderAnnotationsMu.Lock()
+	defer m.orderAnnotationsMu.Unlock()
+
+	var existing OrderAnnotations
+	found := true
+	if err := m.OrderAnnotations.FindByID(orderHash.Bytes(), &existing); err != nil {
+		if _, ok := err.(db.NotFoundError); !ok {
+			return nil, err
+		}
+		found = false
+	}
+	merged := map[string]string{}
+	for key, value := range existing.Annotations {
+		merged[key] = value
+	}
+	for key, value := range annotations {
+		if value == "" {
+			delete(merged, key)
+		} else {
+			merged[key] = value
+		}
+	}
+
+	if len(merged) == 0 {
+		if found {
+			if err := m.OrderAnnotations.Delete(orderHash.Bytes()); err != nil {
+				return nil, err
+			}
+		}
+		return merged, nil
+	}
+	record := &OrderAnnotations{
+		OrderHash:   orderHash,
+		Annotations: merged,
+		UpdatedAt:   time.Now().UTC(),
+	}
+	if found {
+		if err := m.OrderAnnotations.Update(record); err != nil {
+			return nil, err
+		}
+	} else if err := m.OrderAnnotations.Insert(record); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// FindOrderAnnotations returns the annotations of the orders with the given
+// hashes, keyed by order hash. Orders without annotations are omitted.
+func (m *MeshDB) FindOrderAnnotations(orderHashes []common.Hash) (map[common.Hash]map[string]string, error) {
+	annotationsByOrderHash := map[common.Hash]map[string]string{}
+	for _, orderHash := range orderHashes {
+		var record OrderAnnotations
+		if err := m.OrderAnnotations.FindByID(orderHash.Bytes(), &record); err != nil {
+			if _, ok := err.(db.NotFoundError); ok {
+				continue
+			}
+			return nil, err
+		}
+		annotationsByOrderHash[orderHash] = record.Annotations
+	}
+	return annotationsByOrderHash, nil
+}
+
+// DeleteOrphanedOrderAnnotations deletes the annotations of orders which were
+// permanently deleted and returns the number of orders whose annotations were
+// deleted.
+func (m *MeshDB) DeleteOrphanedOrderAnnotations() (int, error) {
+	m.orderAnnotationsMu.Lock()
+	defer m.orderAnnotationsMu.Unlock()
+
+	var records []*OrderAnnotations
+	if err := m.OrderAnnotations.FindAll(&records); err != nil {
+		return 0, err
+	}
+	numDeleted := 0
+	for _, record := range records {
+		var order Order
+		if err := m.Orders.FindByID(record.OrderHash.Bytes(), &order); err == nil {
+			continue
+		} else if _, ok := err.(db.NotFoundError); !ok {
+			return numDeleted, err
+		}
+		if err := m.OrderAnnotations.Delete(record.ID()); err != nil {
+			return numDeleted, err
+		}
+		numDeleted++
+	}
+	return numDeleted, nil
+}
@@ -0,0 +1,125 @@
+package meshdb
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/0xProject/0x-mesh/db"
+	"github.com/0xProject/0x-mesh/zeroex"
+)
+
+// OrderEventRecord is the database representation of an order event that was
+// emitted by Mesh. Records are kept in the order event log so that subscribers
+// can fetch events they have missed by sequence number.
+type OrderEventRecord struct {
+	SequenceNumber uint64
+	OrderEvent     *zeroex.OrderEvent
+}
+
+// ID returns the OrderEventRecord's ID
+func (r OrderEventRecord) ID() []byte {
+	return sequenceNumberKey(r.SequenceNumber)
+}
+
+// OrderEventRecordsCollection represents a DB collection of order events
+type OrderEventRecordsCollection struct {
+	*db.Collection
+	sequenceNumberIndex *db.Index
+}
+
+func setupOrderEventRecords(database *db.DB) (*OrderEventRecordsCollection, error) {
+	col, err := database.NewCollection("orderEventRecord", &OrderEventRecord{})
+	if err != nil {
+		return nil, err
+	}
+	sequenceNumberIndex := col.AddIndex("sequenceNumber", func(m db.Model) []byte {
+		return sequenceNumberKey(m.(*OrderEventRecord).SequenceNumber)
+	})
+	return &OrderEventRecordsCollection{
+		Collection:          col,
+		sequenceNumberIndex: sequenceNumberIndex,
+	}, nil
+}
+
+// sequenceNumberKey returns a constant-length key for the given sequence
+// number so that keys are sorted in the same order as sequence numbers.
+func sequenceNumberKey(sequenceNumber uint64) []byte {
+	return []byte(fmt.Sprintf("%020d", sequenceNumber))
+}
+
+// AppendOrderEvents adds the given order events to the order event log. Each
+// order event must already have a sequence number.
+func (m *MeshDB) AppendOrderEvents(orderEvents []*zeroex.OrderEvent) error {
+	txn := m.OrderEventRecords.OpenTransaction()
+	defer func() {
+		_ = txn.Discard()
+	}()
+	for _, orderEvent := range orderEvents {
+		record := &OrderEventRecord{
+			SequenceNumber: orderEvent.SequenceNumber,
+			OrderEvent:     orderEvent,
+		}
+		if err := txn.Insert(record); err != nil {
+			return err
+		}
+	}
+	return txn.Commit()
+}
+
+// FindOrderEventsSince returns up to max order events with a sequence number
+// greater than the given sequence number, sorted by sequence number.
+func (m *MeshDB) FindOrderEventsSince(sequenceNumber uint64, max int) ([]*zeroex.OrderEvent, error) {
+	start := sequenceNumberKey(sequenceNumber + 1)
+	limit := sequenceNumberKey(math.MaxUint64)
+	filter := m.OrderEventRecords.sequenceNumberIndex.RangeFilter(start, limit)
+	records := []*OrderEventRecord{}
+	if err := m.OrderEventRecords.NewQuery(filter).Max(max).Run(&records); err != nil {
+		return nil, err
+	}
+	orderEvents := make([]*zeroex.OrderEvent, len(records))
+	for i, record := range records {
+		orderEvents[i] = record.OrderEvent
+	}
+	return orderEvents, nil
+}
+
+// GetOrderEventLogBounds returns the lowest and highest sequence numbers in the
+// order event log. Both are 0 if the log is empty.
+func (m *MeshDB) GetOrderEventLogBounds() (oldest uint64, latest uint64, err error) {
+	all := m.OrderEventRecords.sequenceNumberIndex.All()
+	oldestRecords := []*OrderEventRecord{}
+	if err := m.OrderEventRecords.NewQuery(all).Max(1).Run(&oldestRecords); err != nil {
+		return 0, 0, err
+	}
+	if len(oldestRecords) == 0 {
+		return 0, 0, nil
+	}
+	latestRecords := []*OrderEventRecord{}
+	if err := m.OrderEventRecords.NewQuery(all).Reverse().Max(1).Run(&latestRecords); err != nil {
+		return 0, 0, err
+	}
+	return oldestRecords[0].SequenceNumber, latestRecords[0].SequenceNumber, nil
+}
+
+// DeleteOrderEventsBefore removes all order events with a sequence number
+// lower than the given sequence number from the order event log.
+func (m *MeshDB) DeleteOrderEventsBefore(sequenceNumber uint64) error {
+	filter := m.OrderEventRecords.sequenceNumberIndex.RangeFilter(sequenceNumberKey(0), sequenceNumberKey(sequenceNumber))
+	records := []*OrderEventRecord{}
+	if err := m.OrderEventRecords.NewQuery(filter).Run(&records); err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	txn := m.OrderEventRecords.OpenTransaction()
+	defer func() {
+		_ = txn.Discard()
+	}()
+	for _, record := range records {
+		if err := txn.Delete(record.ID()); err != nil {
+			return err
+		}
+	}
+	return txn.Commit()
+}
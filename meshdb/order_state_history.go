@@ -0,0 +1,225 @@
+package meshdb
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/0xProject/0x-mesh/db"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// OrderStateRecord is the database representation of the state of an order
+// after a state transition (e.g. the order was added, filled or canceled) at a
+// specific block. Only the last transition of an order in any given block is
+// kept.
+type OrderStateRecord struct {
+	OrderHash   common.Hash
+	BlockNumber *big.Int
+	// EndState is the end state of the order event which caused the transition
+	// (see zeroex.OrderEventEndState).
+	EndState                 string
+	FillableTakerAssetAmount *big.Int
+	Timestamp                time.Time
+}
+
+// ID returns the OrderStateRecord's ID
+func (r OrderStateRecord) ID() []byte {
+	return orderHashAndBlockNumberKey(r.OrderHash, r.BlockNumber)
+}
+
+// IsFillable returns true if the order was fillable for a non-zero amount after
+// this transition.
+func (r OrderStateRecord) IsFillable() bool {
+	return r.FillableTakerAssetAmount != nil && r.FillableTakerAssetAmount.Sign() > 0
+}
+
+// OrderStateRecordsCollection represents a DB collection of order state
+// transitions
+type OrderStateRecordsCollection struct {
+	*db.Collection
+	orderHashAndBlockNumberIndex *db.Index
+	blockNumberIndex             *db.Index
+}
+
+func setupOrderStateRecords(database *db.DB) (*OrderStateRecordsCollection, error) {
+	col, err := database.NewCollection("orderStateRecord", &OrderStateRecord{})
+	if err != nil {
+		return nil, err
+	}
+	orderHashAndBlockNumberIndex := col.AddIndex("orderHashAndBlockNumber", func(m db.Model) []byte {
+		record := m.(*OrderStateRecord)
+		return orderHashAndBlockNumberKey(record.OrderHash, record.BlockNumber)
+	})
+	blockNumberIndex := col.AddIndex("blockNumber", func(m db.Model) []byte {
+		return uint256ToConstantLengthBytes(m.(*OrderStateRecord).BlockNumber)
+	})
+	return &OrderStateRecordsCollection{
+		Collection:                   col,
+		orderHashAndBlockNumberIndex: orderHashAndBlockNumberIndex,
+		blockNumberIndex:             blockNumberIndex,
+	}, nil
+}
+
+func orderHashAndBlockNumberKey(orderHash common.Hash, blockNumber *big.Int) []byte {
+	return []byte(fmt.Sprintf("%s|%s", orderHash.Hex(), uint256ToConstantLengthBytes(blockNumber)))
+}
+
+// SaveOrderStateRecords stores the given OrderStateRecords. If there already is
+// a record for the same order and block, it is replaced. If records contains
+// more than one record for the same order and block, the last one is stored.
+func (m *MeshDB) SaveOrderStateRecords(records []*OrderStateRecord) error {
+	recordsByID := map[string]*OrderStateRecord{}
+	for _, record := range records {
+		recordsByID[string(record.ID())] = record
+	}
+
+	txn := m.OrderStateRecords.OpenTransaction()
+	defer func() {
+		_ = txn.Discard()
+	}()
+	for _, record := range recordsByID {
+		var existing OrderStateRecord
+		if err := m.OrderStateRecords.FindByID(record.ID(), &existing); err != nil {
+			if _, ok := err.(db.NotFoundError); !ok {
+				return err
+			}
+			if err := txn.Insert(record); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := txn.Update(record); err != nil {
+			return err
+		}
+	}
+	return txn.Commit()
+}
+
+// InsertMissingOrderStateRecords stores the given OrderStateRecords unless
+// there already is a record for the same order and block. It returns the
+// number of records which were inserted. If records contains more than one
+// record for the same order and block, the last one is used.
+func (m *MeshDB) InsertMissingOrderStateRecords(records []*OrderStateRecord) (int, error) {
+	recordsByID := map[string]*OrderStateRecord{}
+	for _, record := range records {
+		recordsByID[string(record.ID())] = record
+	}
+
+	txn := m.OrderStateRecords.OpenTransaction()
+	defer func() {
+		_ = txn.Discard()
+	}()
+	numInserted := 0
+	for _, record := range recordsByID {
+		var existing OrderStateRecord
+		if err := m.OrderStateRecords.FindByID(record.ID(), &existing); err == nil {
+			continue
+		} else if _, ok := err.(db.NotFoundError); !ok {
+			return 0, err
+		}
+		if err := txn.Insert(record); err != nil {
+			return 0, err
+		}
+		numInserted++
+	}
+	if err := txn.Commit(); err != nil {
+		return 0, err
+	}
+	return numInserted, nil
+}
+
+// FindOrderStateAtBlock returns the state of the given order at the given
+// block, i.e. its most recent OrderStateRecord at or before the block. It
+// returns a db.NotFoundError if there is no such record.
+func (m *MeshDB) FindOrderStateAtBlock(orderHash common.Hash, blockNumber *big.Int) (*OrderStateRecord, error) {
+	start := orderHashAndBlockNumberKey(orderHash, big.NewInt(0))
+	limit := orderHashAndBlockNumberKey(orderHash, big.NewInt(0).Add(blockNumber, big.NewInt(1)))
+	filter := m.OrderStateRecords.orderHashAndBlockNumberIndex.RangeFilter(start, limit)
+	records := []*OrderStateRecord{}
+	if err := m.OrderStateRecords.NewQuery(filter).Reverse().Max(1).Run(&records); err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, db.NotFoundError{ID: orderHash.Bytes()}
+	}
+	return records[0], nil
+}
+
+// FindOrdersFillableAtBlock returns the most recent OrderStateRecord at or
+// before the given block for every order which was fillable at that block. The
+// records are sorted by order hash.
+func (m *MeshDB) FindOrdersFillableAtBlock(blockNumber *big.Int) ([]*OrderStateRecord, error) {
+	latestRecords, err := m.findLatestOrderStateRecordsBefore(big.NewInt(0).Add(blockNumber, big.NewInt(1)))
+	if err != nil {
+		return nil, err
+	}
+	fillableRecords := []*OrderStateRecord{}
+	for _, record := range latestRecords {
+		if record.IsFillable() {
+			fillableRecords = append(fillableRecords, record)
+		}
+	}
+	sort.Slice(fillableRecords, func(i, j int) bool {
+		return fillableRecords[i].OrderHash.Hex() < fillableRecords[j].OrderHash.Hex()
+	})
+	return fillableRecords, nil
+}
+
+// findLatestOrderStateRecordsBefore returns the most recent OrderStateRecord
+// before the given block for each order, keyed by order hash.
+func (m *MeshDB) findLatestOrderStateRecordsBefore(blockNumber *big.Int) (map[common.Hash]*OrderStateRecord, error) {
+	records, err := m.findOrderStateRecordsBefore(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	latestRecords := map[common.Hash]*OrderStateRecord{}
+	for _, record := range records {
+		// Records are sorted by ascending block number so later records replace
+		// earlier ones.
+		latestRecords[record.OrderHash] = record
+	}
+	return latestRecords, nil
+}
+
+func (m *MeshDB) findOrderStateRecordsBefore(blockNumber *big.Int) ([]*OrderStateRecord, error) {
+	start := uint256ToConstantLengthBytes(big.NewInt(0))
+	limit := uint256ToConstantLengthBytes(blockNumber)
+	filter := m.OrderStateRecords.blockNumberIndex.RangeFilter(start, limit)
+	records := []*OrderStateRecord{}
+	if err := m.OrderStateRecords.NewQuery(filter).Run(&records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// CompactOrderStateRecordsBefore compacts the order state history before the
+// given block. For each order, only the most recent record before the block is
+// kept, and only if the order was still fillable at that point. The fillable
+// orders at or after the given block are unaffected, but the history of orders
+// which were no longer fillable before the block is removed entirely.
+func (m *MeshDB) CompactOrderStateRecordsBefore(blockNumber *big.Int) error {
+	records, err := m.findOrderStateRecordsBefore(blockNumber)
+	if err != nil {
+		return err
+	}
+	latestRecords := map[common.Hash]*OrderStateRecord{}
+	for _, record := range records {
+		latestRecords[record.OrderHash] = record
+	}
+
+	txn := m.OrderStateRecords.OpenTransaction()
+	defer func() {
+		_ = txn.Discard()
+	}()
+	for _, record := range records {
+		if latestRecords[record.OrderHash] == record && record.IsFillable() {
+			continue
+		}
+		if err := txn.Delete(record.ID()); err != nil {
+			return err
+		}
+	}
+	return txn.Commit()
+}
@@ -0,0 +1,127 @@
+package meshdb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/0xProject/0x-mesh/db"
+)
+
+// orderSyncRecordsChunkSize is the number of OrderSyncRecords read from the
+// database at a time by ForEachOrderSyncRecord.
+const orderSyncRecordsChunkSize = 1000
+
+// OrderSyncRecord is the database representation of the outcome of a single
+// attempt to get orders from a provider via the ordersync protocol.
+type OrderSyncRecord struct {
+	ProviderID  string
+	Subprotocol string
+	StartTime   time.Time
+	Duration    time.Duration
+	// Latency is the round trip time of the first ordersync request. It is 0
+	// if the provider never responded.
+	Latency        time.Duration
+	OrdersReceived int
+	OrdersAccepted int
+	// OrdersRejected is the number of rejected orders keyed by the code of the
+	// rejection reason.
+	OrdersRejected map[string]int
+	Succeeded      bool
+	Error          string
+}
+
+// ID returns the OrderSyncRecord's ID
+func (r OrderSyncRecord) ID() []byte {
+	return []byte(fmt.Sprintf("%s|%s", r.ProviderID, startTimeKey(r.StartTime)))
+}
+
+// startTimeKey returns a constant-length key for the given start time so that
+// keys are sorted in the same order as start times.
+func startTimeKey(startTime time.Time) []byte {
+	return []byte(fmt.Sprintf("%020d", startTime.UnixNano()))
+}
+
+// OrderSyncRecordsCollection represents a DB collection of ordersync outcomes
+type OrderSyncRecordsCollection struct {
+	*db.Collection
+	providerIDIndex *db.Index
+	startTimeIndex  *db.Index
+}
+
+func setupOrderSyncRecords(database *db.DB) (*OrderSyncRecordsCollection, error) {
+	col, err := database.NewCollection("orderSyncRecord", &OrderSyncRecord{})
+	if err != nil {
+		return nil, err
+	}
+	providerIDIndex := col.AddIndex("providerID", func(m db.Model) []byte {
+		return []byte(m.(*OrderSyncRecord).ProviderID)
+	})
+	startTimeIndex := col.AddIndex("startTime", func(m db.Model) []byte {
+		return startTimeKey(m.(*OrderSyncRecord).StartTime)
+	})
+	return &OrderSyncRecordsCollection{
+		Collection:      col,
+		providerIDIndex: providerIDIndex,
+		startTimeIndex:  startTimeIndex,
+	}, nil
+}
+
+// FindOrderSyncRecordsByProviderID returns all OrderSyncRecords for the given
+// provider.
+func (m *MeshDB) FindOrderSyncRecordsByProviderID(providerID string) ([]*OrderSyncRecord, error) {
+	records := []*OrderSyncRecord{}
+	filter := m.OrderSyncRecords.providerIDIndex.ValueFilter([]byte(providerID))
+	if err := m.OrderSyncRecords.NewQuery(filter).Run(&records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// FindAllOrderSyncRecords returns all OrderSyncRecords sorted by ascending
+// start time.
+func (m *MeshDB) FindAllOrderSyncRecords() ([]*OrderSyncRecord, error) {
+	records := []*OrderSyncRecord{}
+	if err := m.OrderSyncRecords.NewQuery(m.OrderSyncRecords.startTimeIndex.All()).Run(&records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// ForEachOrderSyncRecord calls fn for each OrderSyncRecord in order of
+// ascending start time. Only orderSyncRecordsChunkSize records are held in
+// memory at a time. It stops and returns the error if fn returns one.
+func (m *MeshDB) ForEachOrderSyncRecord(fn func(record *OrderSyncRecord) error) error {
+	var records []*OrderSyncRecord
+	query := m.OrderSyncRecords.NewQuery(m.OrderSyncRecords.startTimeIndex.All())
+	return query.RunInChunks(&records, orderSyncRecordsChunkSize, func() error {
+		for _, record := range records {
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ClearOrderSyncRecordsBefore removes all OrderSyncRecords with a start time
+// before the given cutoff and returns the removed records.
+func (m *MeshDB) ClearOrderSyncRecordsBefore(cutoff time.Time) ([]*OrderSyncRecord, error) {
+	txn := m.OrderSyncRecords.OpenTransaction()
+	defer func() {
+		_ = txn.Discard()
+	}()
+	filter := m.OrderSyncRecords.startTimeIndex.RangeFilter(startTimeKey(time.Unix(0, 0)), startTimeKey(cutoff))
+	records := []*OrderSyncRecord{}
+	if err := m.OrderSyncRecords.NewQuery(filter).Run(&records); err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if err := txn.Delete(record.ID()); err != nil {
+			return nil, err
+		}
+	}
+	if err := txn.Commit(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
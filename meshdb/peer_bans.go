@@ -0,0 +1,75 @@
+package meshdb
+
+import (
+	"time"
+
+	"github.com/0xProject/0x-mesh/db"
+)
+
+// PeerBan is the database representation of a ban on a specific peer.
+type PeerBan struct {
+	PeerID string
+	Reason string
+	// Expiry is the time at which the ban is lifted. A zero Expiry means the ban
+	// never expires.
+	Expiry    time.Time
+	CreatedAt time.Time
+}
+
+// ID returns the PeerBan's ID
+func (b PeerBan) ID() []byte {
+	return []byte(b.PeerID)
+}
+
+// PeerBansCollection represents a DB collection of peer bans
+type PeerBansCollection struct {
+	*db.Collection
+}
+
+func setupPeerBans(database *db.DB) (*PeerBansCollection, error) {
+	col, err := database.NewCollection("peerBan", &PeerBan{})
+	if err != nil {
+		return nil, err
+	}
+	return &PeerBansCollection{col}, nil
+}
+
+// SavePeerBan inserts the given PeerBan or replaces the existing ban for the
+// same peer.
+func (m *MeshDB) SavePeerBan(ban *PeerBan) error {
+	var existing PeerBan
+	if err := m.PeerBans.FindByID(ban.ID(), &existing); err != nil {
+		if _, ok := err.(db.NotFoundError); ok {
+			return m.PeerBans.Insert(ban)
+		}
+		return err
+	}
+	return m.PeerBans.Update(ban)
+}
+
+// DeletePeerBan removes the ban for the given peer. It returns a
+// db.NotFoundError if the peer is not banned.
+func (m *MeshDB) DeletePeerBan(peerID string) error {
+	return m.PeerBans.Delete([]byte(peerID))
+}
+
+// FindAllPeerBans returns all PeerBans which have not expired and removes any
+// which have.
+func (m *MeshDB) FindAllPeerBans() ([]*PeerBan, error) {
+	allBans := []*PeerBan{}
+	if err := m.PeerBans.FindAll(&allBans); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	activeBans := []*PeerBan{}
+	for _, ban := range allBans {
+		if !ban.Expiry.IsZero() && !ban.Expiry.After(now) {
+			if err := m.DeletePeerBan(ban.PeerID); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		activeBans = append(activeBans, ban)
+	}
+	return activeBans, nil
+}
@@ -0,0 +1,105 @@
+// Package metrics pushes the metrics of a Mesh node to monitoring systems. It
+// is meant for environments in which metrics cannot be scraped (e.g. nodes
+// behind a NAT or short-lived nodes run by a serverless wrapper). Metrics are
+// pushed periodically by one or more Emitters, e.g. to a Prometheus
+// pushgateway or to a StatsD (or Datadog) agent.
+package metrics
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Metric is a single measurement. All metrics are gauges: their values are
+// pushed as they are and can go up or down.
+type Metric struct {
+	// Name is the name of the metric in snake case (e.g. "num_orders").
+	// Emitters may add a prefix to it.
+	Name string
+	// Labels distinguish multiple measurements of the same metric (e.g. the
+	// bandwidth used by different protocols). They are called tags by StatsD.
+	Labels map[string]string
+	Value  float64
+}
+
+// Emitter pushes metrics to a monitoring system.
+type Emitter interface {
+	// Emit pushes the given metrics.
+	Emit(ctx context.Context, metrics []*Metric) error
+	// Name identifies the emitter in logs.
+	Name() string
+}
+
+// Config is the configuration for Run.
+type Config struct {
+	// Emitters are the emitters to push metrics to.
+	Emitters []Emitter
+	// FlushInterval is how often metrics are pushed.
+	FlushInterval time.Duration
+	// Collect returns the metrics to push.
+	Collect func() ([]*Metric, error)
+}
+
+// Run collects and pushes metrics to all emitters every config.FlushInterval
+// until the context is canceled. Errors are logged but don't stop Run, since
+// the monitoring system might only be unavailable for a while.
+func Run(ctx context.Context, config Config) {
+	if len(config.Emitters) == 0 {
+		return
+	}
+	ticker := time.NewTicker(config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		flush(ctx, config)
+	}
+}
+
+// flush collects the metrics once and pushes them to all emitters.
+func flush(ctx context.Context, config Config) {
+	metrics, err := config.Collect()
+	if err != nil {
+		log.WithError(err).Warn("could not collect metrics")
+		return
+	}
+	for _, emitter := range config.Emitters {
+		if err := emitter.Emit(ctx, metrics); err != nil {
+			log.WithFields(log.Fields{
+				"error":   err.Error(),
+				"emitter": emitter.Name(),
+			}).Warn("could not push metrics")
+		}
+	}
+}
+
+// sortedLabelNames returns the names of the given labels in alphabetical
+// order so that emitters produce a deterministic output.
+func sortedLabelNames(labels map[string]string) []string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sanitizeName replaces all characters which are not allowed in metric and
+// label names by both Prometheus and StatsD with underscores.
+func sanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
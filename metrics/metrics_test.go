@@ -0,0 +1,109 @@
+// +build !js
+
+package metrics
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMetrics = []*Metric{
+	{Name: "num_orders", Value: 42},
+	{Name: "num_dial_failures", Labels: map[string]string{"reason": "timeout"}, Value: 3},
+	{Name: "num_dial_failures", Labels: map[string]string{"reason": "refused"}, Value: 1.5},
+}
+
+func TestPushGatewayEmitter(t *testing.T) {
+	var (
+		method string
+		path   string
+		body   string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.Path, string(data)
+	}))
+	defer server.Close()
+
+	emitter, err := NewPushGatewayEmitter(server.URL+"/", "mesh", "16Uiu2", "mesh")
+	require.NoError(t, err)
+	require.NoError(t, emitter.Emit(context.Background(), testMetrics))
+
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "/metrics/job/mesh/instance/16Uiu2", path)
+	expectedBody := `# TYPE mesh_num_orders gauge
+mesh_num_orders 42
+# TYPE mesh_num_dial_failures gauge
+mesh_num_dial_failures{reason="timeout"} 3
+mesh_num_dial_failures{reason="refused"} 1.5
+`
+	assert.Equal(t, expectedBody, body)
+}
+
+func TestPushGatewayEmitterErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad metrics", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	emitter, err := NewPushGatewayEmitter(server.URL, "mesh", "", "")
+	require.NoError(t, err)
+	err = emitter.Emit(context.Background(), testMetrics)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "400")
+}
+
+func TestNewPushGatewayEmitterInvalidConfig(t *testing.T) {
+	_, err := NewPushGatewayEmitter("localhost:9091", "mesh", "", "")
+	assert.Error(t, err)
+	_, err = NewPushGatewayEmitter("http://localhost:9091", "", "", "")
+	assert.Error(t, err)
+}
+
+func TestStatsDEmitter(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	emitter, err := NewStatsDEmitter(conn.LocalAddr().String(), "mesh")
+	require.NoError(t, err)
+	require.NoError(t, emitter.Emit(context.Background(), testMetrics))
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	packet := make([]byte, maxStatsDPacketSize)
+	n, _, err := conn.ReadFrom(packet)
+	require.NoError(t, err)
+	expectedPacket := "mesh.num_orders:42|g\nmesh.num_dial_failures:3|g|#reason:timeout\nmesh.num_dial_failures:1.5|g|#reason:refused"
+	assert.Equal(t, expectedPacket, string(packet[:n]))
+}
+
+func TestStatsDEmitterSplitsPackets(t *testing.T) {
+	emitter, err := NewStatsDEmitter("localhost:8125", "mesh")
+	require.NoError(t, err)
+
+	metrics := []*Metric{}
+	for i := 0; i < 200; i++ {
+		metrics = append(metrics, &Metric{Name: "num_orders", Labels: map[string]string{"source": "p2p,rpc"}, Value: float64(i)})
+	}
+	packets := emitter.encode(metrics)
+	require.True(t, len(packets) > 1, "expected metrics to be split into multiple packets")
+	numLines := 0
+	for _, packet := range packets {
+		assert.True(t, len(packet) <= maxStatsDPacketSize, "packet exceeds the maximum size")
+		lines := strings.Split(string(packet), "\n")
+		for _, line := range lines {
+			assert.True(t, strings.HasSuffix(line, "|#source:p2p_rpc"), "unexpected line %q", line)
+		}
+		numLines += len(lines)
+	}
+	assert.Equal(t, len(metrics), numLines)
+}
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pushGatewayRequestTimeout is the timeout for requests to the pushgateway.
+const pushGatewayRequestTimeout = 10 * time.Second
+
+// PushGatewayEmitter pushes metrics to a Prometheus pushgateway.
+type PushGatewayEmitter struct {
+	url    string
+	prefix string
+	client *http.Client
+}
+
+var _ Emitter = &PushGatewayEmitter{}
+
+// NewPushGatewayEmitter returns an Emitter which pushes metrics to the
+// Prometheus pushgateway at the given URL (e.g. "http://localhost:9091"). The
+// metrics are grouped by the given job and instance, and each push replaces
+// the metrics of the previous push. The names of the metrics are prefixed with
+// prefix and an underscore.
+func NewPushGatewayEmitter(gatewayURL string, job string, instance string, prefix string) (*PushGatewayEmitter, error) {
+	parsedURL, err := url.Parse(gatewayURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pushgateway URL: %s", err.Error())
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return nil, fmt.Errorf("invalid pushgateway URL: unsupported scheme %q", parsedURL.Scheme)
+	}
+	if job == "" {
+		return nil, fmt.Errorf("pushgateway job cannot be empty")
+	}
+	groupingKey := "/metrics/job/" + url.PathEscape(job)
+	if instance != "" {
+		groupingKey += "/instance/" + url.PathEscape(instance)
+	}
+	return &PushGatewayEmitter{
+		url:    strings.TrimSuffix(gatewayURL, "/") + groupingKey,
+		prefix: prefix,
+		client: &http.Client{Timeout: pushGatewayRequestTimeout},
+	}, nil
+}
+
+// Name implements Emitter.
+func (e *PushGatewayEmitter) Name() string {
+	return "pushgateway"
+}
+
+// Emit implements Emitter.
+func (e *PushGatewayEmitter) Emit(ctx context.Context, metrics []*Metric) error {
+	req, err := http.NewRequest(http.MethodPut, e.url, bytes.NewReader(e.encode(metrics)))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	res, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("pushgateway responded with status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// encode encodes the metrics in the Prometheus text exposition format.
+func (e *PushGatewayEmitter) encode(metrics []*Metric) []byte {
+	var buf bytes.Buffer
+	typed := map[string]bool{}
+	for _, metric := range metrics {
+		name := sanitizeName(metric.Name)
+		if e.prefix != "" {
+			name = sanitizeName(e.prefix) + "_" + name
+		}
+		// All samples of a metric must follow its TYPE line.
+		if !typed[name] {
+			fmt.Fprintf(&buf, "# TYPE %s gauge\n", name)
+			typed[name] = true
+		}
+		buf.WriteString(name)
+		if len(metric.Labels) > 0 {
+			buf.WriteString("{")
+			for i, labelName := range sortedLabelNames(metric.Labels) {
+				if i > 0 {
+					buf.WriteString(",")
+				}
+				fmt.Fprintf(&buf, "%s=%s", sanitizeName(labelName), strconv.Quote(metric.Labels[labelName]))
+			}
+			buf.WriteString("}")
+		}
+		fmt.Fprintf(&buf, " %s\n", strconv.FormatFloat(metric.Value, 'g', -1, 64))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// maxStatsDPacketSize is the maximum size of the UDP packets sent to StatsD.
+// Larger packets might be fragmented or dropped.
+const maxStatsDPacketSize = 1432
+
+// StatsDEmitter sends metrics to a StatsD agent over UDP. Labels are sent as
+// tags in the format used by DogStatsD (Datadog) and Telegraf.
+type StatsDEmitter struct {
+	addr   string
+	prefix string
+	conn   net.Conn
+}
+
+var _ Emitter = &StatsDEmitter{}
+
+// NewStatsDEmitter returns an Emitter which sends metrics to the StatsD agent
+// at the given address (e.g. "localhost:8125"). The names of the metrics are
+// prefixed with prefix and a dot.
+func NewStatsDEmitter(addr string, prefix string) (*StatsDEmitter, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, fmt.Errorf("invalid StatsD address: %s", err.Error())
+	}
+	return &StatsDEmitter{
+		addr:   addr,
+		prefix: prefix,
+	}, nil
+}
+
+// Name implements Emitter.
+func (e *StatsDEmitter) Name() string {
+	return "statsd"
+}
+
+// Emit implements Emitter.
+func (e *StatsDEmitter) Emit(ctx context.Context, metrics []*Metric) error {
+	if e.conn == nil {
+		// Dialing UDP doesn't send anything, so this only fails if the address
+		// cannot be resolved.
+		conn, err := net.Dial("udp", e.addr)
+		if err != nil {
+			return err
+		}
+		e.conn = conn
+	}
+	for _, packet := range e.encode(metrics) {
+		if _, err := e.conn.Write(packet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encode encodes the metrics as StatsD gauges and splits them into packets
+// of at most maxStatsDPacketSize bytes.
+func (e *StatsDEmitter) encode(metrics []*Metric) [][]byte {
+	packets := [][]byte{}
+	var packet bytes.Buffer
+	for _, metric := range metrics {
+		name := sanitizeName(metric.Name)
+		if e.prefix != "" {
+			name = e.prefix + "." + name
+		}
+		line := name + ":" + strconv.FormatFloat(metric.Value, 'f', -1, 64) + "|g"
+		if len(metric.Labels) > 0 {
+			tags := make([]string, 0, len(metric.Labels))
+			for _, labelName := range sortedLabelNames(metric.Labels) {
+				// Commas separate tags and cannot be escaped.
+				value := strings.Replace(metric.Labels[labelName], ",", "_", -1)
+				tags = append(tags, sanitizeName(labelName)+":"+value)
+			}
+			line += "|#" + strings.Join(tags, ",")
+		}
+		if packet.Len() > 0 && packet.Len()+1+len(line) > maxStatsDPacketSize {
+			packets = append(packets, append([]byte{}, packet.Bytes()...))
+			packet.Reset()
+		}
+		if packet.Len() > 0 {
+			packet.WriteString("\n")
+		}
+		packet.WriteString(line)
+	}
+	if packet.Len() > 0 {
+		packets = append(packets, packet.Bytes())
+	}
+	return packets
+}
@@ -0,0 +1,332 @@
+package orderfilter
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// AssetDataKeyword is the keyword which custom order schemas can use in the
+// schema of an assetData property (e.g. makerAssetData) to constrain the
+// decoded asset data instead of its hex encoding. Its value is a schema for
+// the decoded asset data, which is an object with the following properties:
+//
+//    assetProxyId             the 0x-prefixed asset proxy ID (e.g. "0xf47261b0")
+//    assetType                "ERC20", "ERC721", "ERC1155", "MultiAsset",
+//                             "StaticCall" or "ERC20Bridge"
+//    tokenAddress             ERC20, ERC721, ERC1155 and ERC20Bridge
+//    tokenId                  ERC721
+//    tokenIds, values         ERC1155
+//    callbackData             ERC1155
+//    amounts, nestedAssets    MultiAsset (nestedAssets are decoded as well)
+//    staticCallTargetAddress  StaticCall
+//    staticCallData           StaticCall
+//    expectedReturnDataHash   StaticCall
+//    bridgeAddress            ERC20Bridge
+//    bridgeData               ERC20Bridge
+//
+// Addresses and bytes are lowercase 0x-prefixed hex strings and numbers are
+// base 10 strings. Asset data of other asset proxies (including custom ones)
+// only has an assetProxyId, and asset data which can't be decoded doesn't
+// match any schema which uses the keyword.
+//
+// For example, the following schema only accepts orders which sell the ERC721
+// token with ID 1 of a given contract:
+//
+//    {"properties":{"makerAssetData":{"assetData":{"properties":{
+//      "assetType":{"const":"ERC721"},
+//      "tokenAddress":{"const":"0x1dc4c1cefef38a777b15aa20260a54e584b16c48"},
+//      "tokenId":{"const":"1"}}}}}}
+const AssetDataKeyword = "assetData"
+
+// decodedAssetDataSuffix is appended to the name of an assetData property to
+// get the name of the property which holds the decoded asset data while an
+// order is validated against a schema which uses AssetDataKeyword. It contains
+// a character which is not valid in an identifier, so that it can't clash
+// with the properties of an order.
+const decodedAssetDataSuffix = "#decoded"
+
+// assetDataProperties are the properties of an order whose schemas may use
+// AssetDataKeyword.
+var assetDataProperties = []string{"makerAssetData", "takerAssetData", "makerFeeAssetData", "takerFeeAssetData"}
+
+// ErrMisplacedAssetDataKeyword is returned when creating a Filter whose custom
+// order schema uses AssetDataKeyword anywhere but directly in the schema of an
+// assetData property. It would otherwise be ignored like any unknown keyword.
+var ErrMisplacedAssetDataKeyword = errors.New(`the "assetData" keyword can only be used directly in the schemas of makerAssetData, takerAssetData, makerFeeAssetData and takerFeeAssetData`)
+
+// assetTypes are the values of the assetType property of decoded asset data
+// by asset proxy ID.
+var assetTypes = map[string]string{
+	zeroex.ERC20AssetDataID:       "ERC20",
+	zeroex.ERC721AssetDataID:      "ERC721",
+	zeroex.ERC1155AssetDataID:     "ERC1155",
+	zeroex.MultiAssetDataID:       "MultiAsset",
+	zeroex.StaticCallAssetDataID:  "StaticCall",
+	zeroex.ERC20BridgeAssetDataID: "ERC20Bridge",
+}
+
+var (
+	assetDataDecoderOnce sync.Once
+	assetDataDecoder     *zeroex.AssetDataDecoder
+)
+
+func getAssetDataDecoder() *zeroex.AssetDataDecoder {
+	assetDataDecoderOnce.Do(func() {
+		assetDataDecoder = zeroex.NewAssetDataDecoder()
+	})
+	return assetDataDecoder
+}
+
+// expandAssetDataKeywords rewrites the given custom order schema so that it
+// can be compiled by a standard JSON Schema validator. The schema S of each
+// AssetDataKeyword in the schema of an assetData property is moved to the
+// sibling property which withDecodedAssetData adds to orders, e.g.
+//
+//    {"properties":{"makerAssetData":{"assetData":S}}}
+//
+// becomes
+//
+//    {"properties":{"makerAssetData":{},"makerAssetData#decoded":{"type":"object","allOf":[S]}}}
+//
+// This keeps the meaning of the keyword inside of allOf, anyOf, oneOf and not.
+// It returns false and the schema as is if it doesn't use the keyword, and
+// leaves reporting invalid JSON to the schema compiler.
+func expandAssetDataKeywords(customOrderSchema string) (string, bool, error) {
+	var schema interface{}
+	if err := json.Unmarshal([]byte(customOrderSchema), &schema); err != nil {
+		return customOrderSchema, false, nil
+	}
+	expanded, err := expandAssetDataKeywordsIn(schema)
+	if err != nil {
+		return "", false, err
+	}
+	if !expanded {
+		return customOrderSchema, false, nil
+	}
+	encoded, err := json.Marshal(schema)
+	if err != nil {
+		return "", false, err
+	}
+	return string(encoded), true, nil
+}
+
+// expandAssetDataKeywordsIn expands the AssetDataKeywords in the given decoded
+// schema in place. It returns true if it expanded any.
+func expandAssetDataKeywordsIn(schema interface{}) (bool, error) {
+	switch schema := schema.(type) {
+	case map[string]interface{}:
+		if _, found := schema[AssetDataKeyword]; found {
+			return false, ErrMisplacedAssetDataKeyword
+		}
+		expanded := false
+		if properties, ok := schema["properties"].(map[string]interface{}); ok {
+			for _, property := range assetDataProperties {
+				propertySchema, ok := properties[property].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				decodedSchema, found := propertySchema[AssetDataKeyword]
+				if !found {
+					continue
+				}
+				delete(propertySchema, AssetDataKeyword)
+				properties[property+decodedAssetDataSuffix] = map[string]interface{}{
+					"type":  "object",
+					"allOf": []interface{}{decodedSchema},
+				}
+				expanded = true
+			}
+		}
+		for keyword, value := range schema {
+			switch keyword {
+			case "const", "enum", "default", "examples":
+				// These are data rather than schemas.
+				continue
+			case "properties", "patternProperties", "definitions", "dependencies":
+				// The keys of these are names rather than keywords.
+				if values, ok := value.(map[string]interface{}); ok {
+					for _, value := range values {
+						expandedValue, err := expandAssetDataKeywordsIn(value)
+						if err != nil {
+							return false, err
+						}
+						expanded = expanded || expandedValue
+					}
+					continue
+				}
+			}
+			expandedValue, err := expandAssetDataKeywordsIn(value)
+			if err != nil {
+				return false, err
+			}
+			expanded = expanded || expandedValue
+		}
+		return expanded, nil
+	case []interface{}:
+		expanded := false
+		for _, value := range schema {
+			expandedValue, err := expandAssetDataKeywordsIn(value)
+			if err != nil {
+				return false, err
+			}
+			expanded = expanded || expandedValue
+		}
+		return expanded, nil
+	}
+	return false, nil
+}
+
+// withDecodedAssetData returns the given JSON encoded order with the decoded
+// asset data of each assetData property added as a sibling property (see
+// expandAssetDataKeywords). The decoded asset data is null if it can't be
+// decoded. The order is returned as is if it is not a JSON object.
+func withDecodedAssetData(orderJSON []byte) []byte {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(orderJSON, &fields); err != nil {
+		return orderJSON
+	}
+	for _, property := range assetDataProperties {
+		var assetData string
+		if err := json.Unmarshal(fields[property], &assetData); err != nil {
+			continue
+		}
+		encoded, err := json.Marshal(decodeAssetDataForSchema(assetData))
+		if err != nil {
+			return orderJSON
+		}
+		fields[property+decodedAssetDataSuffix] = encoded
+	}
+	encoded, err := json.Marshal(fields)
+	if err != nil {
+		return orderJSON
+	}
+	return encoded
+}
+
+// withDecodedAssetDataInMessage is like withDecodedAssetData, but for the
+// order of a JSON encoded order message.
+func withDecodedAssetDataInMessage(messageJSON []byte) []byte {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(messageJSON, &fields); err != nil {
+		return messageJSON
+	}
+	orderJSON, found := fields["order"]
+	if !found {
+		return messageJSON
+	}
+	fields["order"] = withDecodedAssetData(orderJSON)
+	encoded, err := json.Marshal(fields)
+	if err != nil {
+		return messageJSON
+	}
+	return encoded
+}
+
+// decodeAssetDataForSchema decodes the given hex encoded asset data into the
+// object described by AssetDataKeyword. It returns nil if the asset data
+// can't be decoded.
+func decodeAssetDataForSchema(assetDataHex string) map[string]interface{} {
+	if !hexRegex.MatchString(assetDataHex) {
+		return nil
+	}
+	assetData, err := hexutil.Decode(strings.ToLower(assetDataHex))
+	if err != nil || len(assetData) < 4 {
+		return nil
+	}
+	assetProxyID := common.Bytes2Hex(assetData[:4])
+	decoded := map[string]interface{}{
+		"assetProxyId": "0x" + assetProxyID,
+	}
+	assetType, found := assetTypes[assetProxyID]
+	if !found {
+		return decoded
+	}
+	decoded["assetType"] = assetType
+	decoder := getAssetDataDecoder()
+	switch assetProxyID {
+	case zeroex.ERC20AssetDataID:
+		var erc20AssetData zeroex.ERC20AssetData
+		if err := decoder.Decode(assetData, &erc20AssetData); err != nil {
+			return nil
+		}
+		decoded["tokenAddress"] = encodeAddressForSchema(erc20AssetData.Address)
+	case zeroex.ERC721AssetDataID:
+		var erc721AssetData zeroex.ERC721AssetData
+		if err := decoder.Decode(assetData, &erc721AssetData); err != nil {
+			return nil
+		}
+		decoded["tokenAddress"] = encodeAddressForSchema(erc721AssetData.Address)
+		decoded["tokenId"] = erc721AssetData.TokenId.String()
+	case zeroex.ERC1155AssetDataID:
+		var erc1155AssetData zeroex.ERC1155AssetData
+		if err := decoder.Decode(assetData, &erc1155AssetData); err != nil {
+			return nil
+		}
+		decoded["tokenAddress"] = encodeAddressForSchema(erc1155AssetData.Address)
+		decoded["tokenIds"] = encodeNumbersForSchema(erc1155AssetData.Ids)
+		decoded["values"] = encodeNumbersForSchema(erc1155AssetData.Values)
+		decoded["callbackData"] = hexutil.Encode(erc1155AssetData.CallbackData)
+	case zeroex.MultiAssetDataID:
+		var multiAssetData zeroex.MultiAssetData
+		if err := decoder.Decode(assetData, &multiAssetData); err != nil {
+			return nil
+		}
+		nestedAssets := make([]interface{}, len(multiAssetData.NestedAssetData))
+		for i, nestedAssetData := range multiAssetData.NestedAssetData {
+			if nestedAsset := decodeAssetDataForSchema(hexutil.Encode(nestedAssetData)); nestedAsset != nil {
+				nestedAssets[i] = nestedAsset
+			}
+		}
+		decoded["amounts"] = encodeNumbersForSchema(multiAssetData.Amounts)
+		decoded["nestedAssets"] = nestedAssets
+	case zeroex.StaticCallAssetDataID:
+		var staticCallAssetData zeroex.StaticCallAssetData
+		if err := decoder.Decode(assetData, &staticCallAssetData); err != nil {
+			return nil
+		}
+		decoded["staticCallTargetAddress"] = encodeAddressForSchema(staticCallAssetData.StaticCallTargetAddress)
+		decoded["staticCallData"] = hexutil.Encode(staticCallAssetData.StaticCallData)
+		decoded["expectedReturnDataHash"] = hexutil.Encode(staticCallAssetData.ExpectedReturnHashData[:])
+	case zeroex.ERC20BridgeAssetDataID:
+		var erc20BridgeAssetData zeroex.ERC20BridgeAssetData
+		if err := decoder.Decode(assetData, &erc20BridgeAssetData); err != nil {
+			return nil
+		}
+		decoded["tokenAddress"] = encodeAddressForSchema(erc20BridgeAssetData.TokenAddress)
+		decoded["bridgeAddress"] = encodeAddressForSchema(erc20BridgeAssetData.BridgeAddress)
+		decoded["bridgeData"] = hexutil.Encode(erc20BridgeAssetData.BridgeData)
+	}
+	return decoded
+}
+
+func encodeAddressForSchema(address common.Address) string {
+	return strings.ToLower(address.Hex())
+}
+
+func encodeNumbersForSchema(numbers []*big.Int) []interface{} {
+	encoded := make([]interface{}, len(numbers))
+	for i, number := range numbers {
+		encoded[i] = number.String()
+	}
+	return encoded
+}
+
+// decodedAssetDataPointer returns the pointer of the assetData property if the
+// given JSON pointer refers to decoded asset data (see withDecodedAssetData),
+// so that errors for decoded asset data refer to the order itself.
+func decodedAssetDataPointer(pointer string) string {
+	for _, property := range assetDataProperties {
+		prefix := "/" + property + decodedAssetDataSuffix
+		if pointer == prefix || strings.HasPrefix(pointer, prefix+"/") {
+			return "/" + property
+		}
+	}
+	return pointer
+}
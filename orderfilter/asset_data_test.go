@@ -0,0 +1,119 @@
+// +build !js
+
+package orderfilter
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testERC721AssetData = "0x025717920000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c480000000000000000000000000000000000000000000000000000000000000001"
+	testMultiAssetData  = "0x94cfcdd7000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000004600000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000024f47261b00000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c48000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000044025717920000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c48000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000"
+)
+
+func TestDecodeAssetDataForSchema(t *testing.T) {
+	t.Parallel()
+
+	erc721 := map[string]interface{}{
+		"assetProxyId": "0x02571792",
+		"assetType":    "ERC721",
+		"tokenAddress": "0x1dc4c1cefef38a777b15aa20260a54e584b16c48",
+		"tokenId":      "1",
+	}
+	assert.Equal(t, erc721, decodeAssetDataForSchema(testERC721AssetData))
+	assert.Equal(t, map[string]interface{}{
+		"assetProxyId": "0x94cfcdd7",
+		"assetType":    "MultiAsset",
+		"amounts":      []interface{}{"70", "1"},
+		"nestedAssets": []interface{}{
+			map[string]interface{}{
+				"assetProxyId": "0xf47261b0",
+				"assetType":    "ERC20",
+				"tokenAddress": "0x1dc4c1cefef38a777b15aa20260a54e584b16c48",
+			},
+			erc721,
+		},
+	}, decodeAssetDataForSchema(testMultiAssetData))
+	assert.Equal(t, map[string]interface{}{"assetProxyId": "0x12345678"}, decodeAssetDataForSchema("0x12345678"))
+	assert.Nil(t, decodeAssetDataForSchema("0x025717920000"))
+	assert.Nil(t, decodeAssetDataForSchema("0x0257"))
+	assert.Nil(t, decodeAssetDataForSchema("hi"))
+}
+
+func TestAssetDataKeyword(t *testing.T) {
+	t.Parallel()
+
+	erc721OrderJSON := withOrderField(t, "makerAssetData", fmt.Sprintf("%q", testERC721AssetData))
+	multiAssetOrderJSON := withOrderField(t, "makerAssetData", fmt.Sprintf("%q", testMultiAssetData))
+	invalidAssetDataOrderJSON := withOrderField(t, "makerAssetData", `"0x025717920000"`)
+
+	zrxSchema := `{"properties":{"makerAssetData":{"assetData":{"properties":{"tokenAddress":{"const":"0xe41d2489571d322189246dafa5ebde1f4699f498"}}}}}}`
+	erc721Schema := `{"properties":{"makerAssetData":{"assetData":{"properties":{"assetType":{"const":"ERC721"},"tokenId":{"enum":["1","2"]}},"required":["tokenId"]}}}}`
+	nestedERC721Schema := `{"properties":{"makerAssetData":{"assetData":{"properties":{"nestedAssets":{"contains":{"properties":{"assetType":{"const":"ERC721"}},"required":["assetType"]}}},"required":["nestedAssets"]}}}}`
+	anyOfSchema := `{"anyOf":[{"properties":{"makerAssetData":{"assetData":{"properties":{"assetType":{"const":"ERC721"}}}}}},{"properties":{"takerAssetData":{"assetData":{"properties":{"assetType":{"const":"ERC721"}}}}}}]}`
+	notSchema := `{"not":{"properties":{"makerAssetData":{"assetData":{"properties":{"assetType":{"const":"MultiAsset"}}}}}}}`
+
+	testCases := []struct {
+		note              string
+		customOrderSchema string
+		orderJSON         []byte
+		expectedValid     bool
+	}{
+		{"erc20 token address", zrxSchema, standardValidOrderJSON, true},
+		{"other asset type", zrxSchema, erc721OrderJSON, false},
+		{"erc721 token ID", erc721Schema, erc721OrderJSON, true},
+		{"erc721 schema with erc20", erc721Schema, standardValidOrderJSON, false},
+		{"nested erc721", nestedERC721Schema, multiAssetOrderJSON, true},
+		{"nested erc721 with erc721", nestedERC721Schema, erc721OrderJSON, false},
+		{"anyOf", anyOfSchema, erc721OrderJSON, true},
+		{"anyOf neither", anyOfSchema, standardValidOrderJSON, false},
+		{"not", notSchema, erc721OrderJSON, true},
+		{"not multi asset", notSchema, multiAssetOrderJSON, false},
+		{"undecodable asset data", `{"properties":{"makerAssetData":{"assetData":{}}}}`, invalidAssetDataOrderJSON, false},
+	}
+	for _, tc := range testCases {
+		filter, err := New(constants.TestChainID, tc.customOrderSchema, contractAddresses, ProtocolVersionV3)
+		require.NoError(t, err, tc.note)
+		result, err := filter.ValidateOrderJSON(tc.orderJSON)
+		require.NoError(t, err, tc.note)
+		assert.Equal(t, tc.expectedValid, result.Valid(), "%s: %v", tc.note, result.Errors())
+		matches, err := filter.MatchOrderMessageJSON(orderMessageJSON(tc.orderJSON))
+		require.NoError(t, err, tc.note)
+		assert.Equal(t, tc.expectedValid, matches, tc.note)
+	}
+}
+
+func TestAssetDataKeywordFieldErrors(t *testing.T) {
+	t.Parallel()
+
+	filter, err := New(constants.TestChainID, `{"properties":{"makerAssetData":{"assetData":{"properties":{"assetType":{"const":"ERC721"}}}}}}`, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	result, err := filter.ValidateOrderJSON(standardValidOrderJSON)
+	require.NoError(t, err)
+	fieldError := findFieldError(FieldErrors(standardValidOrderJSON, result), "/makerAssetData")
+	require.NotNil(t, fieldError, "missing field error for /makerAssetData")
+	assert.Equal(t, "const", fieldError.Constraint)
+	assert.Equal(t, "0xf47261b0000000000000000000000000e41d2489571d322189246dafa5ebde1f4699f498", fieldError.Actual)
+}
+
+func TestMisplacedAssetDataKeyword(t *testing.T) {
+	t.Parallel()
+
+	for _, customOrderSchema := range []string{
+		`{"assetData":{}}`,
+		`{"properties":{"makerAddress":{"assetData":{}}}}`,
+		`{"properties":{"makerAssetData":{"anyOf":[{"assetData":{}}]}}}`,
+	} {
+		_, err := New(constants.TestChainID, customOrderSchema, contractAddresses, ProtocolVersionV3)
+		assert.Equal(t, ErrMisplacedAssetDataKeyword, err, customOrderSchema)
+	}
+
+	// A property called "assetData" is not the keyword.
+	_, err := New(constants.TestChainID, `{"properties":{"assetData":{"type":"string"}}}`, contractAddresses, ProtocolVersionV3)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,93 @@
+package orderfilter
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/0xProject/0x-mesh/zeroex"
+)
+
+// batchValidationWorkers is the number of goroutines which validate the orders
+// of a batch concurrently.
+var batchValidationWorkers = runtime.NumCPU()
+
+// BatchValidationResult is the result of validating a single order of a batch
+// with ValidateOrdersJSON or ValidateOrders.
+type BatchValidationResult struct {
+	// Valid is true if the order passes the filter.
+	Valid bool
+	// FieldErrors describe why the order doesn't pass the filter. They are only
+	// set by ValidateOrdersJSON.
+	FieldErrors []*FieldError
+	// RejectReasons are the reasons why the order doesn't pass the filter, as
+	// returned by MatchOrder. They are only set by ValidateOrders.
+	RejectReasons []string
+	// Err is set if there was a problem with validation (e.g. because the order
+	// is not valid JSON). Valid is false in that case.
+	Err error
+}
+
+// ValidateOrdersJSON validates many JSON encoded orders at once and returns a
+// result for each of them, in the same order. It is equivalent to calling
+// ValidateOrderJSON for every order, but the orders are validated
+// concurrently.
+func (f *Filter) ValidateOrdersJSON(ordersJSON [][]byte) []*BatchValidationResult {
+	results := make([]*BatchValidationResult, len(ordersJSON))
+	forEachConcurrently(len(ordersJSON), func(i int) {
+		result, err := f.ValidateOrderJSON(ordersJSON[i])
+		if err != nil {
+			results[i] = &BatchValidationResult{Err: err}
+			return
+		}
+		if result.Valid() {
+			results[i] = &BatchValidationResult{Valid: true}
+			return
+		}
+		results[i] = &BatchValidationResult{FieldErrors: FieldErrors(ordersJSON[i], result)}
+	})
+	return results
+}
+
+// ValidateOrders checks whether many orders pass the filter at once and returns
+// a result for each of them, in the same order. It is equivalent to calling
+// MatchOrder for every order, but the orders are checked concurrently.
+func (f *Filter) ValidateOrders(orders []*zeroex.SignedOrder) []*BatchValidationResult {
+	results := make([]*BatchValidationResult, len(orders))
+	forEachConcurrently(len(orders), func(i int) {
+		matches, rejectReasons, err := f.MatchOrder(orders[i])
+		results[i] = &BatchValidationResult{Valid: matches, RejectReasons: rejectReasons, Err: err}
+	})
+	return results
+}
+
+// forEachConcurrently calls fn for every index from 0 to n-1 using up to
+// batchValidationWorkers goroutines and returns once all calls returned.
+func forEachConcurrently(n int, fn func(i int)) {
+	workers := batchValidationWorkers
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+	var nextIndex int64 = -1
+	wg := &sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&nextIndex, 1))
+				if i >= n {
+					return
+				}
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,67 @@
+package orderfilter
+
+import (
+	"testing"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateOrdersJSON(t *testing.T) {
+	defer func(workers int) { batchValidationWorkers = workers }(batchValidationWorkers)
+	batchValidationWorkers = 4
+
+	filter, err := New(constants.TestChainID, `{"properties":{"senderAddress":{"type":"string","pattern":"0x00000000000000000000000000000000ba5eba11"}}}`, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+
+	ordersJSON := [][]byte{}
+	for i := 0; i < 50; i++ {
+		ordersJSON = append(ordersJSON, standardValidOrderJSON, orderWithSpecificSenderAddressJSON)
+	}
+	ordersJSON = append(ordersJSON, []byte(`{"makerAddress":`))
+	results := filter.ValidateOrdersJSON(ordersJSON)
+	require.Len(t, results, len(ordersJSON))
+	for i := 0; i < 100; i += 2 {
+		// standardValidOrderJSON has a different sender address.
+		assert.False(t, results[i].Valid)
+		assert.NoError(t, results[i].Err)
+		assert.NotNil(t, findFieldError(results[i].FieldErrors, "/senderAddress"))
+
+		assert.True(t, results[i+1].Valid)
+		assert.NoError(t, results[i+1].Err)
+		assert.Empty(t, results[i+1].FieldErrors)
+	}
+	assert.False(t, results[100].Valid)
+	assert.Error(t, results[100].Err)
+
+	assert.Empty(t, filter.ValidateOrdersJSON(nil))
+}
+
+func TestValidateOrders(t *testing.T) {
+	defer func(workers int) { batchValidationWorkers = workers }(batchValidationWorkers)
+	batchValidationWorkers = 4
+
+	filter, err := New(constants.TestChainID, `{"properties":{"senderAddress":{"type":"string","pattern":"0x00000000000000000000000000000000ba5eba11"}}}`, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+
+	standardOrder := &zeroex.SignedOrder{}
+	require.NoError(t, standardOrder.UnmarshalJSON(standardValidOrderJSON))
+	orderWithSpecificSenderAddress := &zeroex.SignedOrder{}
+	require.NoError(t, orderWithSpecificSenderAddress.UnmarshalJSON(orderWithSpecificSenderAddressJSON))
+	orders := []*zeroex.SignedOrder{}
+	for i := 0; i < 50; i++ {
+		orders = append(orders, standardOrder, orderWithSpecificSenderAddress)
+	}
+	results := filter.ValidateOrders(orders)
+	require.Len(t, results, len(orders))
+	for i, result := range results {
+		require.NoError(t, result.Err)
+		matches, rejectReasons, err := filter.MatchOrder(orders[i])
+		require.NoError(t, err)
+		assert.Equal(t, matches, result.Valid)
+		assert.Equal(t, rejectReasons, result.RejectReasons)
+		assert.Equal(t, i%2 == 1, result.Valid)
+	}
+}
@@ -0,0 +1,161 @@
+package orderfilter
+
+import (
+	"sort"
+
+	canonicaljson "github.com/gibson042/canonicaljson-go"
+)
+
+// Keywords whose value is a single subschema.
+var subschemaKeywords = []string{"not", "additionalItems", "additionalProperties", "contains", "propertyNames", "if", "then", "else"}
+
+// Keywords whose value is an object which maps names to subschemas.
+var subschemaMapKeywords = []string{"properties", "patternProperties", "definitions", "dependencies"}
+
+// Keywords whose value is an array in which neither the order nor duplicates
+// matter.
+var unorderedSetKeywords = []string{"anyOf", "allOf", "enum", "required", "type"}
+
+// normalizeSchema returns an equivalent schema in which arrays whose order
+// doesn't matter (e.g. the subschemas of anyOf or the values of enum) are
+// sorted by their canonical encoding and deduplicated. Together with the
+// canonical JSON encoding, which takes care of whitespace and the order of
+// keys, schemas which only differ in this way end up with the same encoding.
+// The values of const, enum and default are data rather than schemas and are
+// left as they are.
+func normalizeSchema(schema interface{}) interface{} {
+	object, ok := schema.(map[string]interface{})
+	if !ok {
+		// Booleans are valid schemas as well.
+		return schema
+	}
+	normalized := make(map[string]interface{}, len(object))
+	for keyword, value := range object {
+		normalized[keyword] = value
+	}
+	for _, keyword := range subschemaKeywords {
+		if value, found := object[keyword]; found {
+			normalized[keyword] = normalizeSchema(value)
+		}
+	}
+	for _, keyword := range subschemaMapKeywords {
+		subschemas, ok := object[keyword].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		normalizedSubschemas := make(map[string]interface{}, len(subschemas))
+		for name, subschema := range subschemas {
+			if dependencies, ok := subschema.([]interface{}); ok {
+				// Property dependencies are a list of property names.
+				normalizedSubschemas[name] = sortedUniqueValues(dependencies)
+			} else {
+				normalizedSubschemas[name] = normalizeSchema(subschema)
+			}
+		}
+		normalized[keyword] = normalizedSubschemas
+	}
+	// The items of a tuple are ordered, but each of them is a subschema.
+	switch items := object["items"].(type) {
+	case []interface{}:
+		normalizedItems := make([]interface{}, len(items))
+		for i, item := range items {
+			normalizedItems[i] = normalizeSchema(item)
+		}
+		normalized["items"] = normalizedItems
+	case map[string]interface{}, bool:
+		normalized["items"] = normalizeSchema(items)
+	}
+	// The subschemas of oneOf may be reordered, but not deduplicated, since an
+	// instance which matches a duplicated subschema matches more than one.
+	if subschemas, ok := object["oneOf"].([]interface{}); ok {
+		normalizedSubschemas := normalizeSchemas(subschemas)
+		sort.Slice(normalizedSubschemas, func(i, j int) bool {
+			return canonicalEncoding(normalizedSubschemas[i]) < canonicalEncoding(normalizedSubschemas[j])
+		})
+		normalized["oneOf"] = normalizedSubschemas
+	}
+	for _, keyword := range unorderedSetKeywords {
+		values, ok := object[keyword].([]interface{})
+		if !ok {
+			continue
+		}
+		if keyword == "anyOf" || keyword == "allOf" {
+			values = normalizeSchemas(values)
+		}
+		normalized[keyword] = sortedUniqueValues(values)
+	}
+	return normalized
+}
+
+// normalizeSchemas normalizes each of the given schemas.
+func normalizeSchemas(schemas []interface{}) []interface{} {
+	normalized := make([]interface{}, len(schemas))
+	for i, schema := range schemas {
+		normalized[i] = normalizeSchema(schema)
+	}
+	return normalized
+}
+
+// sortedUniqueValues returns the given JSON values sorted by their canonical
+// encoding and without duplicates.
+func sortedUniqueValues(values []interface{}) []interface{} {
+	byEncoding := make(map[string]interface{}, len(values))
+	encodings := make([]string, 0, len(values))
+	for _, value := range values {
+		encoding := canonicalEncoding(value)
+		if _, found := byEncoding[encoding]; found {
+			continue
+		}
+		byEncoding[encoding] = value
+		encodings = append(encodings, encoding)
+	}
+	sort.Strings(encodings)
+	unique := make([]interface{}, len(encodings))
+	for i, encoding := range encodings {
+		unique[i] = byEncoding[encoding]
+	}
+	return unique
+}
+
+// canonicalEncoding returns the canonical JSON encoding of the given value.
+func canonicalEncoding(value interface{}) string {
+	encoded, _ := canonicaljson.Marshal(value)
+	return string(encoded)
+}
+
+// Equals returns true if both filters accept the same orders, because they use
+// the same chain, protocol version, exchange addresses and max expiration
+// duration (see WithMaxExpirationDuration) and their custom order schemas are
+// the same after normalization. The topic version doesn't matter (see LegacyTopicsAccepted).
+// Filters which are equal and use the same topic version always have the same
+// topic, even if their schemas differ in whitespace, the order of keys or the
+// order of the subschemas of anyOf.
+func (f *Filter) Equals(other *Filter) bool {
+	if f == other {
+		return true
+	}
+	if other == nil {
+		return false
+	}
+	return f.chainID == other.chainID &&
+		f.protocolVersion == other.protocolVersion &&
+		sameExchangeAddresses(f.exchangeAddresses, other.exchangeAddresses) &&
+		f.maxExpirationDuration == other.maxExpirationDuration &&
+		string(canonicalSchemaJSON(f.rawCustomOrderSchema)) == string(canonicalSchemaJSON(other.rawCustomOrderSchema))
+}
+
+// Canonicalize returns a filter which is equal to the given one (see Equals)
+// but whose custom order schema is replaced by its normalized canonical
+// encoding, i.e. the schema encoded in the topic. This is the filter that
+// peers reconstruct with NewFromTopic.
+func (f *Filter) Canonicalize() (*Filter, error) {
+	filter, err := NewWithExchangeAddresses(f.chainID, string(canonicalSchemaJSON(f.rawCustomOrderSchema)), f.exchangeAddresses, f.protocolVersion)
+	if err != nil {
+		return nil, err
+	}
+	filter.constraints = f.constraints
+	filter.composite = f.composite
+	filter.maxExpirationDuration = f.maxExpirationDuration
+	filter.topicVersion = f.topicVersion
+	return filter, nil
+}
@@ -0,0 +1,77 @@
+package orderfilter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterEquals(t *testing.T) {
+	t.Parallel()
+
+	schema := `{"anyOf":[{"properties":{"takerFee":{"const":"0"}}},{"properties":{"senderAddress":{"enum":["0x00000000000000000000000000000000ba5eba11","0x0000000000000000000000000000000000000000"]}}}],"required":["takerFee","makerFee"]}`
+	testCases := []struct {
+		note          string
+		schema        string
+		expectedEqual bool
+	}{
+		{"same schema", schema, true},
+		{"different whitespace and key order", `{ "required": ["takerFee", "makerFee"], "anyOf": [ {"properties": {"takerFee": {"const": "0"}}}, {"properties": {"senderAddress": {"enum": ["0x00000000000000000000000000000000ba5eba11", "0x0000000000000000000000000000000000000000"]}}} ] }`, true},
+		{"different anyOf order", `{"anyOf":[{"properties":{"senderAddress":{"enum":["0x00000000000000000000000000000000ba5eba11","0x0000000000000000000000000000000000000000"]}}},{"properties":{"takerFee":{"const":"0"}}}],"required":["takerFee","makerFee"]}`, true},
+		{"different enum and required order", `{"anyOf":[{"properties":{"takerFee":{"const":"0"}}},{"properties":{"senderAddress":{"enum":["0x0000000000000000000000000000000000000000","0x00000000000000000000000000000000ba5eba11"]}}}],"required":["makerFee","takerFee"]}`, true},
+		{"duplicate anyOf subschema", `{"anyOf":[{"properties":{"takerFee":{"const":"0"}}},{"properties":{"takerFee":{"const":"0"}}},{"properties":{"senderAddress":{"enum":["0x00000000000000000000000000000000ba5eba11","0x0000000000000000000000000000000000000000"]}}}],"required":["takerFee","makerFee"]}`, true},
+		{"different const", `{"anyOf":[{"properties":{"takerFee":{"const":"1"}}},{"properties":{"senderAddress":{"enum":["0x00000000000000000000000000000000ba5eba11","0x0000000000000000000000000000000000000000"]}}}],"required":["takerFee","makerFee"]}`, false},
+		{"oneOf instead of anyOf", `{"oneOf":[{"properties":{"takerFee":{"const":"0"}}},{"properties":{"senderAddress":{"enum":["0x00000000000000000000000000000000ba5eba11","0x0000000000000000000000000000000000000000"]}}}],"required":["takerFee","makerFee"]}`, false},
+	}
+	filter, err := New(constants.TestChainID, schema, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	for _, tc := range testCases {
+		other, err := New(constants.TestChainID, tc.schema, contractAddresses, ProtocolVersionV3)
+		require.NoError(t, err, tc.note)
+		assert.Equal(t, tc.expectedEqual, filter.Equals(other), tc.note)
+		assert.Equal(t, tc.expectedEqual, filter.Topic() == other.Topic(), tc.note)
+	}
+
+	otherChain, err := New(constants.TestChainID+1, schema, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	assert.False(t, filter.Equals(otherChain))
+	assert.False(t, filter.Equals(filter.WithMaxExpirationDuration(time.Hour)))
+	assert.False(t, filter.Equals(nil))
+}
+
+func TestNormalizeSchemaKeepsOneOfDuplicates(t *testing.T) {
+	t.Parallel()
+
+	// A duplicated subschema of oneOf can never match exactly once, so the
+	// duplicate must not be removed.
+	withDuplicate, err := New(constants.TestChainID, `{"oneOf":[{"properties":{"takerFee":{"const":"0"}}},{"properties":{"takerFee":{"const":"0"}}}]}`, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	withoutDuplicate, err := New(constants.TestChainID, `{"oneOf":[{"properties":{"takerFee":{"const":"0"}}}]}`, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	assert.False(t, withDuplicate.Equals(withoutDuplicate))
+}
+
+func TestFilterCanonicalize(t *testing.T) {
+	t.Parallel()
+
+	filter, err := New(constants.TestChainID, `{ "properties": { "makerAssetData": { "enum": ["0xf47261b0000000000000000000000000e41d2489571d322189246dafa5ebde1f4699f498", "0xf47261b0000000000000000000000000c02aaa39b223fe8d0a0e5c4f27ead9083c756cc2"] } } }`, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	filter = filter.WithMaxExpirationDuration(time.Hour)
+	canonical, err := filter.Canonicalize()
+	require.NoError(t, err)
+	assert.True(t, filter.Equals(canonical))
+	assert.Equal(t, filter.Topic(), canonical.Topic())
+	assert.Equal(t, `{"properties":{"makerAssetData":{"enum":["0xf47261b0000000000000000000000000c02aaa39b223fe8d0a0e5c4f27ead9083c756cc2","0xf47261b0000000000000000000000000e41d2489571d322189246dafa5ebde1f4699f498"]}}}`, canonical.rawCustomOrderSchema)
+	assert.Equal(t, time.Hour, canonical.MaxExpirationDuration())
+
+	// The canonical filter is what peers reconstruct from the topic.
+	topicFilter, err := NewFromTopic(filter.Topic(), contractAddresses)
+	require.NoError(t, err)
+	assert.True(t, canonical.WithMaxExpirationDuration(0).Equals(topicFilter))
+	result, err := canonical.ValidateOrderJSON(orderJSONExpiringAt(time.Now().Add(time.Minute)))
+	require.NoError(t, err)
+	assert.True(t, result.Valid())
+}
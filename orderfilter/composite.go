@@ -0,0 +1,192 @@
+package orderfilter
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// The JSON Schema keywords used to combine the custom order schemas of the
+// filters passed to And, Or and Not.
+const (
+	compositeAnd = "allOf"
+	compositeOr  = "anyOf"
+	compositeNot = "not"
+)
+
+// ErrNoFilters is returned by And and Or if no filters were given.
+var ErrNoFilters = errors.New("at least one filter is required")
+
+// IncompatibleFiltersError is returned by And and Or if the given filters
+// cannot be combined because they don't use the same chain, protocol version
+// and exchange addresses.
+type IncompatibleFiltersError struct {
+	reason string
+}
+
+func (e IncompatibleFiltersError) Error() string {
+	return fmt.Sprintf("filters cannot be combined: %s", e.reason)
+}
+
+// composite describes how a Filter created with And, Or or Not is composed of
+// other filters.
+type composite struct {
+	operator string
+	operands []*Filter
+}
+
+// And returns a Filter which only accepts orders that pass all of the given
+// filters. The filters must use the same chain and exchange addresses.
+//
+// The custom order schema of the returned filter is an allOf over the custom
+// order schemas of the given filters. They are sorted by their canonical
+// encoding, so the topic doesn't depend on the order of the filters and any
+// node can reconstruct the filter with NewFromTopic. If only one filter is
+// given, it is returned as is.
+func And(filters ...*Filter) (*Filter, error) {
+	return newComposite(compositeAnd, filters)
+}
+
+// Or returns a Filter which accepts orders that pass any of the given filters,
+// e.g. one of several pair-specific filters. The filters must use the same
+// chain and exchange addresses. See And for how the topic is encoded.
+func Or(filters ...*Filter) (*Filter, error) {
+	return newComposite(compositeOr, filters)
+}
+
+// Not returns a Filter which accepts the orders that use the chain and
+// exchange addresses of the given filter but don't pass it. The custom order
+// schema of the returned filter is the negation of the one of the given filter.
+func Not(filter *Filter) (*Filter, error) {
+	return newComposite(compositeNot, []*Filter{filter})
+}
+
+func newComposite(operator string, filters []*Filter) (*Filter, error) {
+	if len(filters) == 0 {
+		return nil, ErrNoFilters
+	}
+	if operator != compositeNot && len(filters) == 1 {
+		return filters[0], nil
+	}
+	first := filters[0]
+	for _, filter := range filters[1:] {
+		if filter.chainID != first.chainID {
+			return nil, IncompatibleFiltersError{
+				reason: fmt.Sprintf("chain IDs %d and %d are different", first.chainID, filter.chainID),
+			}
+		}
+		if filter.protocolVersion != first.protocolVersion {
+			return nil, IncompatibleFiltersError{
+				reason: fmt.Sprintf("protocol versions %d and %d are different", first.protocolVersion, filter.protocolVersion),
+			}
+		}
+		if !sameExchangeAddresses(filter.exchangeAddresses, first.exchangeAddresses) {
+			return nil, IncompatibleFiltersError{reason: "exchange addresses are different"}
+		}
+	}
+
+	var customOrderSchema string
+	if operator == compositeNot {
+		customOrderSchema = fmt.Sprintf(`{%q:%s}`, operator, canonicalSchemaJSON(first.rawCustomOrderSchema))
+	} else {
+		schemas := make([]string, len(filters))
+		for i, filter := range filters {
+			schemas[i] = string(canonicalSchemaJSON(filter.rawCustomOrderSchema))
+		}
+		sort.Strings(schemas)
+		customOrderSchema = fmt.Sprintf(`{%q:[%s]}`, operator, strings.Join(schemas, ","))
+	}
+	filter, err := NewWithExchangeAddresses(first.chainID, customOrderSchema, first.exchangeAddresses, first.protocolVersion)
+	if err != nil {
+		return nil, err
+	}
+	filter.composite = &composite{
+		operator: operator,
+		operands: append([]*Filter{}, filters...),
+	}
+	filter.maxExpirationDuration = compositeMaxExpirationDuration(operator, filters)
+	return filter, nil
+}
+
+// sameExchangeAddresses returns true if a and b contain the same unique
+// exchange addresses, regardless of their order.
+func sameExchangeAddresses(a []common.Address, b []common.Address) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, address := range a {
+		found := false
+		for _, other := range b {
+			if address == other {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// compositeOrderRejectReasons matches the given order against the filters the
+// composite filter was created from instead of its custom order schema. This
+// way, constraints which are not part of the schema (i.e. expiresWithin) are
+// still enforced. It returns the reasons why the order doesn't pass the
+// composite filter or nil if it does. For Or, these are the reasons of all
+// filters.
+func (f *Filter) compositeOrderRejectReasons(order *zeroex.SignedOrder) ([]string, error) {
+	switch f.composite.operator {
+	case compositeAnd:
+		for _, operand := range f.composite.operands {
+			rejectReasons, err := operand.orderRejectReasons(order)
+			if err != nil || len(rejectReasons) > 0 {
+				return rejectReasons, err
+			}
+		}
+		return nil, nil
+	case compositeOr:
+		var allRejectReasons []string
+		for _, operand := range f.composite.operands {
+			rejectReasons, err := operand.orderRejectReasons(order)
+			if err != nil {
+				return nil, err
+			}
+			if len(rejectReasons) == 0 {
+				return nil, nil
+			}
+			for _, reason := range rejectReasons {
+				allRejectReasons = appendRejectReason(allRejectReasons, reason)
+			}
+		}
+		return allRejectReasons, nil
+	case compositeNot:
+		// Only the custom order schema is negated. Orders for other chains or
+		// exchanges never pass the filter.
+		var rejectReasons []string
+		if order.ChainID == nil || order.ChainID.Cmp(big.NewInt(int64(f.chainID))) != 0 {
+			rejectReasons = append(rejectReasons, RejectionReasonSchema+":chainId")
+		}
+		if !f.acceptsExchangeAddress(order.ExchangeAddress) {
+			rejectReasons = append(rejectReasons, RejectionReasonSchema+":exchangeAddress")
+		}
+		if len(rejectReasons) > 0 {
+			return rejectReasons, nil
+		}
+		operandRejectReasons, err := f.composite.operands[0].orderRejectReasons(order)
+		if err != nil {
+			return nil, err
+		}
+		if len(operandRejectReasons) == 0 {
+			return []string{RejectionReasonNot}, nil
+		}
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown composite filter operator: %q", f.composite.operator)
+	}
+}
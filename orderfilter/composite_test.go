@@ -0,0 +1,132 @@
+package orderfilter
+
+import (
+	"testing"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompositeFilters(t *testing.T) {
+	t.Parallel()
+
+	order := &zeroex.SignedOrder{}
+	require.NoError(t, order.UnmarshalJSON(standardValidOrderJSON))
+	specificSenderOrder := &zeroex.SignedOrder{}
+	require.NoError(t, specificSenderOrder.UnmarshalJSON(orderWithSpecificSenderAddressJSON))
+
+	noTakerFees, err := New(constants.TestChainID, `{"properties":{"takerFee":{"const":"0"}}}`, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	specificSender, err := New(constants.TestChainID, `{"properties":{"senderAddress":{"const":"0x00000000000000000000000000000000ba5eba11"}}}`, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	expiresWithin, err := ParseConstraints("expiresWithin 30d")
+	require.NoError(t, err)
+	expiresSoon, err := NewFromConstraints(constants.TestChainID, expiresWithin, contractAddresses)
+	require.NoError(t, err)
+
+	and, err := And(noTakerFees, specificSender)
+	require.NoError(t, err)
+	or, err := Or(noTakerFees, specificSender)
+	require.NoError(t, err)
+	notSpecificSender, err := Not(specificSender)
+	require.NoError(t, err)
+	andExpiresSoon, err := And(noTakerFees, expiresSoon)
+	require.NoError(t, err)
+	orExpiresSoon, err := Or(specificSender, expiresSoon)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		note          string
+		filter        *Filter
+		order         *zeroex.SignedOrder
+		orderJSON     []byte
+		expectedMatch bool
+	}{
+		{"and without a specific sender", and, order, standardValidOrderJSON, false},
+		{"and with a specific sender", and, specificSenderOrder, orderWithSpecificSenderAddressJSON, true},
+		{"or without a specific sender", or, order, standardValidOrderJSON, true},
+		{"or with a specific sender", or, specificSenderOrder, orderWithSpecificSenderAddressJSON, true},
+		{"not without a specific sender", notSpecificSender, order, standardValidOrderJSON, true},
+		{"not with a specific sender", notSpecificSender, specificSenderOrder, orderWithSpecificSenderAddressJSON, false},
+	}
+	for _, tc := range testCases {
+		matches, _, err := tc.filter.MatchOrder(tc.order)
+		require.NoError(t, err, tc.note)
+		assert.Equal(t, tc.expectedMatch, matches, tc.note)
+
+		// Filters reconstructed from the topic must accept the same orders.
+		topicFilter, err := NewFromTopic(tc.filter.Topic(), contractAddresses)
+		require.NoError(t, err, tc.note)
+		result, err := topicFilter.ValidateOrderJSON(tc.orderJSON)
+		require.NoError(t, err, tc.note)
+		assert.Equal(t, tc.expectedMatch, result.Valid(), tc.note)
+	}
+
+	// The expiresWithin constraint is not part of the topic, but composite
+	// filters still enforce it.
+	matches, rejectReasons, err := andExpiresSoon.MatchOrder(order)
+	require.NoError(t, err)
+	assert.False(t, matches)
+	assert.Equal(t, []string{RejectionReasonMaxExpiration}, rejectReasons)
+	matches, rejectReasons, err = orExpiresSoon.MatchOrder(specificSenderOrder)
+	require.NoError(t, err)
+	assert.True(t, matches)
+	assert.Empty(t, rejectReasons)
+	matches, rejectReasons, err = orExpiresSoon.MatchOrder(order)
+	require.NoError(t, err)
+	assert.False(t, matches)
+	assert.Contains(t, rejectReasons, RejectionReasonMaxExpiration)
+}
+
+func TestCompositeFilterTopic(t *testing.T) {
+	t.Parallel()
+
+	noTakerFees, err := New(constants.TestChainID, `{"properties":{"takerFee":{"const":"0"}}}`, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	specificSender, err := New(constants.TestChainID, `{ "properties": { "senderAddress": { "const": "0x00000000000000000000000000000000ba5eba11" } } }`, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+
+	// The topic doesn't depend on the order of the filters or the formatting
+	// of their schemas.
+	orA, err := Or(noTakerFees, specificSender)
+	require.NoError(t, err)
+	orB, err := Or(specificSender, noTakerFees)
+	require.NoError(t, err)
+	assert.Equal(t, orA.Topic(), orB.Topic())
+	expectedSchema := `{"anyOf":[{"properties":{"senderAddress":{"const":"0x00000000000000000000000000000000ba5eba11"}}},{"properties":{"takerFee":{"const":"0"}}}]}`
+	expectedFilter, err := New(constants.TestChainID, expectedSchema, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	assert.Equal(t, expectedFilter.Topic(), orA.Topic())
+
+	and, err := And(noTakerFees, specificSender)
+	require.NoError(t, err)
+	assert.NotEqual(t, orA.Topic(), and.Topic())
+
+	single, err := Or(noTakerFees)
+	require.NoError(t, err)
+	assert.Equal(t, noTakerFees, single)
+}
+
+func TestCompositeFilterErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := And()
+	assert.Equal(t, ErrNoFilters, err)
+	_, err = Or()
+	assert.Equal(t, ErrNoFilters, err)
+
+	defaultFilter, err := New(constants.TestChainID, DefaultCustomOrderSchema, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	otherChain, err := New(constants.TestChainID+1, DefaultCustomOrderSchema, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	_, err = And(defaultFilter, otherChain)
+	assert.IsType(t, IncompatibleFiltersError{}, err)
+
+	otherExchange, err := NewWithExchangeAddresses(constants.TestChainID, DefaultCustomOrderSchema, []common.Address{common.HexToAddress("0x61935cbdd02287b511119ddb11aeb42f1593b7ef")}, ProtocolVersionV3)
+	require.NoError(t, err)
+	_, err = Or(defaultFilter, otherExchange)
+	assert.IsType(t, IncompatibleFiltersError{}, err)
+}
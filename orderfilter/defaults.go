@@ -0,0 +1,112 @@
+package orderfilter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/0xProject/0x-mesh/constants"
+)
+
+// NoFieldDefaults is the value of the field list passed to ParseFieldDefaults
+// which disables defaulting.
+const NoFieldDefaults = "none"
+
+// defaultableFields are the optional order fields which can be defaulted,
+// together with the value used when they are omitted. Client libraries
+// commonly omit them when they are zero, but the order schema requires them
+// and the order hash depends on them.
+var defaultableFields = map[string]interface{}{
+	"takerAddress":        strings.ToLower(constants.NullAddress.Hex()),
+	"senderAddress":       strings.ToLower(constants.NullAddress.Hex()),
+	"feeRecipientAddress": strings.ToLower(constants.NullAddress.Hex()),
+}
+
+// NonDefaultableFieldError is returned by ParseFieldDefaults if one of the
+// fields can't be defaulted.
+type NonDefaultableFieldError struct {
+	Field string
+}
+
+func (e NonDefaultableFieldError) Error() string {
+	return fmt.Sprintf("order field %q can't be defaulted (must be one of %s)", e.Field, strings.Join(DefaultableFields(), ", "))
+}
+
+// FieldDefaults are the values used for optional order fields which are
+// omitted, keyed by the name of the field in the JSON encoding of an order.
+type FieldDefaults map[string]interface{}
+
+// DefaultableFields returns the names of the order fields which can be
+// defaulted, in alphabetical order.
+func DefaultableFields() []string {
+	fields := make([]string, 0, len(defaultableFields))
+	for field := range defaultableFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// StandardFieldDefaults returns FieldDefaults for all of the fields which can
+// be defaulted.
+func StandardFieldDefaults() FieldDefaults {
+	defaults := make(FieldDefaults, len(defaultableFields))
+	for field, value := range defaultableFields {
+		defaults[field] = value
+	}
+	return defaults
+}
+
+// ParseFieldDefaults returns FieldDefaults for the given comma-separated list
+// of field names. NoFieldDefaults (or an empty list) disables defaulting.
+func ParseFieldDefaults(fields string) (FieldDefaults, error) {
+	defaults := FieldDefaults{}
+	if strings.TrimSpace(fields) == NoFieldDefaults {
+		return defaults, nil
+	}
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		value, found := defaultableFields[field]
+		if !found {
+			return nil, NonDefaultableFieldError{Field: field}
+		}
+		defaults[field] = value
+	}
+	return defaults, nil
+}
+
+// Apply returns a copy of the given JSON encoded signed order in which the
+// fields that are omitted or null are set to their default value. It must be
+// applied before the order is validated against the schema and hashed, since
+// both use the defaulted fields. Fields which are present are left as is. It
+// returns an error if the input is not a JSON object.
+func (d FieldDefaults) Apply(signedOrderJSON []byte) ([]byte, error) {
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(signedOrderJSON))
+	// Use json.Number so that large numbers (e.g. the salt) don't lose
+	// precision.
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		return nil, errors.New("signed order must be a JSON object")
+	}
+	changed := false
+	for field, value := range d {
+		if fields[field] == nil {
+			fields[field] = value
+			changed = true
+		}
+	}
+	if !changed {
+		return signedOrderJSON, nil
+	}
+	return json.Marshal(fields)
+}
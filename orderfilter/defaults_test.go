@@ -0,0 +1,77 @@
+package orderfilter
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldDefaultsApply(t *testing.T) {
+	t.Parallel()
+
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(standardValidOrderJSON))
+	decoder.UseNumber()
+	require.NoError(t, decoder.Decode(&fields))
+	delete(fields, "takerAddress")
+	delete(fields, "feeRecipientAddress")
+	fields["senderAddress"] = nil
+	orderJSONWithOmittedFields, err := json.Marshal(fields)
+	require.NoError(t, err)
+
+	filter, err := New(constants.TestChainID, DefaultCustomOrderSchema, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	result, err := filter.ValidateOrderJSON(orderJSONWithOmittedFields)
+	require.NoError(t, err)
+	assert.False(t, result.Valid())
+
+	defaultedOrderJSON, err := StandardFieldDefaults().Apply(orderJSONWithOmittedFields)
+	require.NoError(t, err)
+	result, err = filter.ValidateOrderJSON(defaultedOrderJSON)
+	require.NoError(t, err)
+	assert.True(t, result.Valid(), "defaulted order should be valid: %v", result.Errors())
+
+	// The defaulted order must have the same hash as the original order.
+	var expectedOrder, actualOrder zeroex.SignedOrder
+	require.NoError(t, expectedOrder.UnmarshalJSON(standardValidOrderJSON))
+	require.NoError(t, actualOrder.UnmarshalJSON(defaultedOrderJSON))
+	expectedOrderHash, err := expectedOrder.ComputeOrderHash()
+	require.NoError(t, err)
+	actualOrderHash, err := actualOrder.ComputeOrderHash()
+	require.NoError(t, err)
+	assert.Equal(t, expectedOrderHash, actualOrderHash)
+
+	// Fields which are present are left as is.
+	defaultedOrderJSON, err = StandardFieldDefaults().Apply(orderWithSpecificSenderAddressJSON)
+	require.NoError(t, err)
+	assert.Equal(t, orderWithSpecificSenderAddressJSON, defaultedOrderJSON)
+
+	_, err = StandardFieldDefaults().Apply([]byte(`null`))
+	assert.Error(t, err)
+}
+
+func TestParseFieldDefaults(t *testing.T) {
+	t.Parallel()
+
+	defaults, err := ParseFieldDefaults("takerAddress, senderAddress")
+	require.NoError(t, err)
+	assert.Len(t, defaults, 2)
+	assert.Contains(t, defaults, "takerAddress")
+	assert.Contains(t, defaults, "senderAddress")
+
+	defaults, err = ParseFieldDefaults(NoFieldDefaults)
+	require.NoError(t, err)
+	assert.Empty(t, defaults)
+
+	defaults, err = ParseFieldDefaults("")
+	require.NoError(t, err)
+	assert.Empty(t, defaults)
+
+	_, err = ParseFieldDefaults("takerAddress,makerAddress")
+	assert.Equal(t, NonDefaultableFieldError{Field: "makerAddress"}, err)
+}
@@ -0,0 +1,405 @@
+package orderfilter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+	canonicaljson "github.com/gibson042/canonicaljson-go"
+)
+
+// FilterDescription is a human-readable summary of the constraints which a
+// Filter applies to orders (see Filter.Describe). It is derived from the
+// canonical custom order schema, so filters which are equal (see Equals) have
+// the same description regardless of how they were created.
+type FilterDescription struct {
+	ChainID           int              `json:"chainID"`
+	ProtocolVersion   ProtocolVersion  `json:"protocolVersion"`
+	ExchangeAddresses []common.Address `json:"exchangeAddresses"`
+	// MaxExpirationDuration is 0 if the expiration time of orders is not
+	// limited (see WithMaxExpirationDuration).
+	MaxExpirationDuration time.Duration `json:"maxExpirationDuration"`
+	// AssetPairs are the pairs of assets which orders must trade, in either
+	// direction. It is empty if the filter doesn't restrict the traded assets
+	// to specific pairs.
+	AssetPairs []AssetPair `json:"assetPairs"`
+	// Predicates are the other constraints of the custom order schema. Orders
+	// must satisfy all of them.
+	Predicates []string `json:"predicates"`
+	Topic      string   `json:"topic"`
+	// CustomOrderSchema is the canonical encoding of the custom order schema,
+	// i.e. the schema encoded in the topic.
+	CustomOrderSchema string `json:"customOrderSchema"`
+}
+
+// AssetPair is a pair of assets which may be traded for each other. Each asset
+// is described in words, e.g. "ERC20 token 0x...".
+type AssetPair struct {
+	Base  string `json:"base"`
+	Quote string `json:"quote"`
+}
+
+var (
+	// erc20AssetDataPatternRegex and erc721AssetDataPatternRegex match the
+	// patterns created by assetDataPattern.
+	erc20AssetDataPatternRegex  = regexp.MustCompile(fmt.Sprintf(`^\^0x%s0{24}([0-9a-f]{40})\$$`, zeroex.ERC20AssetDataID))
+	erc721AssetDataPatternRegex = regexp.MustCompile(fmt.Sprintf(`^\^0x%s0{24}([0-9a-f]{40})\[0-9a-f\]\{64\}\$$`, zeroex.ERC721AssetDataID))
+	// wholeNumberValuesPatternRegex matches the patterns created by
+	// wholeNumberValuesSchema.
+	wholeNumberValuesPatternRegex = regexp.MustCompile(`^\^0\*\(([0-9]+(?:\|[0-9]+)*)\)\$$`)
+)
+
+// Describe returns a summary of the constraints which the filter applies to
+// orders. It is meant for operators comparing the filters of nodes which don't
+// share orders, without having to decode topics by hand. Use String to get a
+// plain-text explanation.
+func (f *Filter) Describe() *FilterDescription {
+	description := &FilterDescription{
+		ChainID:               f.chainID,
+		ProtocolVersion:       f.protocolVersion,
+		ExchangeAddresses:     f.ExchangeAddresses(),
+		MaxExpirationDuration: f.maxExpirationDuration,
+		AssetPairs:            []AssetPair{},
+		Predicates:            []string{},
+		Topic:                 f.Topic(),
+		CustomOrderSchema:     string(canonicalSchemaJSON(f.rawCustomOrderSchema)),
+	}
+	var schema interface{} = map[string]interface{}{}
+	_ = canonicaljson.Unmarshal([]byte(description.CustomOrderSchema), &schema)
+	for _, conjunct := range conjuncts(schema) {
+		if pairs, ok := assetPairsOf(conjunct); ok {
+			description.AssetPairs = append(description.AssetPairs, pairs...)
+			continue
+		}
+		description.Predicates = append(description.Predicates, describeConjunct(conjunct)...)
+	}
+	return description
+}
+
+// String returns a plain-text explanation of the filter.
+func (d *FilterDescription) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Accepts v%d orders on chain %d\n", d.ProtocolVersion, d.ChainID)
+	addresses := make([]string, len(d.ExchangeAddresses))
+	for i, address := range d.ExchangeAddresses {
+		addresses[i] = address.Hex()
+	}
+	fmt.Fprintf(&b, "Exchange addresses: %s\n", strings.Join(addresses, ", "))
+	if d.MaxExpirationDuration != 0 {
+		fmt.Fprintf(&b, "Max expiration: orders must expire within %s\n", d.MaxExpirationDuration)
+	}
+	if len(d.AssetPairs) != 0 {
+		b.WriteString("Asset pairs (either direction):\n")
+		for _, pair := range d.AssetPairs {
+			fmt.Fprintf(&b, "  - %s / %s\n", pair.Base, pair.Quote)
+		}
+	}
+	if len(d.Predicates) == 0 && len(d.AssetPairs) == 0 {
+		b.WriteString("Constraints: none (all orders which match the built-in order schema are accepted)\n")
+	} else if len(d.Predicates) != 0 {
+		b.WriteString("Constraints (all must hold):\n")
+		for _, predicate := range d.Predicates {
+			fmt.Fprintf(&b, "  - %s\n", predicate)
+		}
+	}
+	fmt.Fprintf(&b, "Topic: %s\n", d.Topic)
+	return b.String()
+}
+
+// conjuncts splits the given schema into schemas which must all match, i.e.
+// the subschemas of a top-level allOf and the remaining keywords.
+func conjuncts(schema interface{}) []interface{} {
+	object, ok := schema.(map[string]interface{})
+	if !ok {
+		return []interface{}{schema}
+	}
+	result := []interface{}{}
+	rest := map[string]interface{}{}
+	for keyword, value := range object {
+		if keyword != "allOf" {
+			rest[keyword] = value
+		}
+	}
+	if len(rest) != 0 {
+		result = append(result, rest)
+	}
+	if subschemas, ok := object["allOf"].([]interface{}); ok {
+		for _, subschema := range subschemas {
+			result = append(result, conjuncts(subschema)...)
+		}
+	}
+	return result
+}
+
+// assetPairsOf returns the asset pairs described by the given schema if it
+// only constrains makerAssetData and takerAssetData, like the schemas created
+// by CustomOrderSchemaForTokenPairs. Both directions of a pair are reported
+// as one pair.
+func assetPairsOf(schema interface{}) ([]AssetPair, bool) {
+	object, ok := schema.(map[string]interface{})
+	if !ok || len(object) != 1 {
+		return nil, false
+	}
+	var alternatives []interface{}
+	if anyOf, ok := object["anyOf"].([]interface{}); ok {
+		alternatives = anyOf
+	} else if _, ok := object["properties"]; ok {
+		alternatives = []interface{}{object}
+	} else {
+		return nil, false
+	}
+	seen := map[[2]string]struct{}{}
+	pairs := []AssetPair{}
+	for _, alternative := range alternatives {
+		alternativeObject, ok := alternative.(map[string]interface{})
+		if !ok || len(alternativeObject) != 1 {
+			return nil, false
+		}
+		properties, ok := alternativeObject["properties"].(map[string]interface{})
+		if !ok || len(properties) != 2 {
+			return nil, false
+		}
+		maker, ok := describeAsset(properties["makerAssetData"])
+		if !ok {
+			return nil, false
+		}
+		taker, ok := describeAsset(properties["takerAssetData"])
+		if !ok {
+			return nil, false
+		}
+		if _, found := seen[[2]string{taker, maker}]; found {
+			continue
+		}
+		seen[[2]string{maker, taker}] = struct{}{}
+		pairs = append(pairs, AssetPair{Base: maker, Quote: taker})
+	}
+	return pairs, true
+}
+
+// describeAsset returns the asset which the given assetData property schema
+// accepts if it only accepts a single asset.
+func describeAsset(schema interface{}) (string, bool) {
+	object, ok := schema.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	if value, ok := object["const"].(string); ok && len(object) == 1 {
+		return describeAssetData(value), true
+	}
+	pattern, ok := object["pattern"].(string)
+	if !ok || len(object) != 2 || object["type"] != "string" {
+		return "", false
+	}
+	if match := erc20AssetDataPatternRegex.FindStringSubmatch(pattern); match != nil {
+		return "ERC20 token " + checksummedAddress(match[1]), true
+	}
+	if match := erc721AssetDataPatternRegex.FindStringSubmatch(pattern); match != nil {
+		return "ERC721 token " + checksummedAddress(match[1]) + " (any token ID)", true
+	}
+	return "", false
+}
+
+// describeAssetData returns the asset of the given assetData if it is ERC20
+// assetData and the assetData itself otherwise.
+func describeAssetData(assetData string) string {
+	erc20Prefix := "0x" + zeroex.ERC20AssetDataID + strings.Repeat("0", 24)
+	if len(assetData) == len(erc20Prefix)+40 && strings.HasPrefix(strings.ToLower(assetData), erc20Prefix) {
+		return "ERC20 token " + checksummedAddress(assetData[len(erc20Prefix):])
+	}
+	return assetData
+}
+
+func checksummedAddress(hexWithoutPrefix string) string {
+	return common.HexToAddress("0x" + hexWithoutPrefix).Hex()
+}
+
+// describeConjunct returns predicates which describe the given schema. The
+// constraints on the individual properties of a properties schema are
+// reported as separate predicates.
+func describeConjunct(schema interface{}) []string {
+	object, ok := schema.(map[string]interface{})
+	if !ok {
+		return []string{describeSchema(schema)}
+	}
+	predicates := []string{}
+	rest := map[string]interface{}{}
+	for keyword, value := range object {
+		if keyword != "properties" {
+			rest[keyword] = value
+		}
+	}
+	if properties, ok := object["properties"].(map[string]interface{}); ok {
+		for _, name := range sortedKeys(properties) {
+			predicates = append(predicates, describeProperty(name, properties[name]))
+		}
+	}
+	if len(rest) != 0 {
+		predicates = append(predicates, describeSchema(rest))
+	}
+	return predicates
+}
+
+// describeSchema returns an expression which describes the given order
+// schema. Keywords which can't be described in words are rendered as JSON.
+func describeSchema(schema interface{}) string {
+	switch schema := schema.(type) {
+	case bool:
+		if schema {
+			return "any order"
+		}
+		return "no order"
+	case map[string]interface{}:
+		if len(schema) == 0 {
+			return "any order"
+		}
+		parts := []string{}
+		for _, keyword := range sortedKeys(schema) {
+			value := schema[keyword]
+			switch keyword {
+			case "properties":
+				properties, ok := value.(map[string]interface{})
+				if !ok {
+					parts = append(parts, describeKeyword(keyword, value))
+					continue
+				}
+				for _, name := range sortedKeys(properties) {
+					parts = append(parts, describeProperty(name, properties[name]))
+				}
+			case "allOf", "anyOf", "oneOf":
+				subschemas, ok := value.([]interface{})
+				if !ok {
+					parts = append(parts, describeKeyword(keyword, value))
+					continue
+				}
+				parts = append(parts, joinDescriptions(subschemas, describeSchema, combinator(keyword)))
+			case "not":
+				parts = append(parts, "not ("+describeSchema(value)+")")
+			default:
+				parts = append(parts, describeKeyword(keyword, value))
+			}
+		}
+		return strings.Join(parts, " and ")
+	default:
+		return canonicalEncoding(schema)
+	}
+}
+
+// describeProperty returns an expression which describes the given schema
+// for the property with the given name, e.g. `takerFee == "0"`.
+func describeProperty(name string, schema interface{}) string {
+	object, ok := schema.(map[string]interface{})
+	if !ok {
+		if schema == false {
+			return name + " must be omitted"
+		}
+		return name + " is anything"
+	}
+	if len(object) == 0 {
+		return name + " is anything"
+	}
+	parts := []string{}
+	for _, keyword := range sortedKeys(object) {
+		value := object[keyword]
+		switch keyword {
+		case "const":
+			parts = append(parts, fmt.Sprintf("%s == %s", name, canonicalEncoding(value)))
+		case "enum":
+			values, ok := value.([]interface{})
+			if !ok {
+				parts = append(parts, fmt.Sprintf("%s satisfies %s", name, canonicalEncoding(object)))
+				continue
+			}
+			encoded := make([]string, len(values))
+			for i, v := range values {
+				encoded[i] = canonicalEncoding(v)
+			}
+			parts = append(parts, fmt.Sprintf("%s in [%s]", name, strings.Join(encoded, ", ")))
+		case "pattern":
+			pattern, _ := value.(string)
+			if match := wholeNumberValuesPatternRegex.FindStringSubmatch(pattern); match != nil {
+				values := strings.Split(match[1], "|")
+				for i, v := range values {
+					values[i] = strconv.Quote(v)
+				}
+				if len(values) == 1 {
+					parts = append(parts, fmt.Sprintf("%s == %s", name, values[0]))
+				} else {
+					parts = append(parts, fmt.Sprintf("%s in [%s]", name, strings.Join(values, ", ")))
+				}
+				continue
+			}
+			parts = append(parts, fmt.Sprintf("%s matches /%v/", name, value))
+		case "type":
+			if value == "string" && object["pattern"] != nil {
+				// Implied by the pattern.
+				continue
+			}
+			parts = append(parts, fmt.Sprintf("%s is of type %s", name, canonicalEncoding(value)))
+		case "format":
+			parts = append(parts, fmt.Sprintf("%s has format %q", name, value))
+		case "minimum":
+			parts = append(parts, fmt.Sprintf("%s >= %s", name, canonicalEncoding(value)))
+		case "maximum":
+			parts = append(parts, fmt.Sprintf("%s <= %s", name, canonicalEncoding(value)))
+		case "exclusiveMinimum":
+			parts = append(parts, fmt.Sprintf("%s > %s", name, canonicalEncoding(value)))
+		case "exclusiveMaximum":
+			parts = append(parts, fmt.Sprintf("%s < %s", name, canonicalEncoding(value)))
+		case "not":
+			parts = append(parts, "not ("+describeProperty(name, value)+")")
+		case "allOf", "anyOf", "oneOf":
+			subschemas, ok := value.([]interface{})
+			if !ok {
+				parts = append(parts, fmt.Sprintf("%s satisfies %s", name, canonicalEncoding(object)))
+				continue
+			}
+			describe := func(subschema interface{}) string { return describeProperty(name, subschema) }
+			parts = append(parts, joinDescriptions(subschemas, describe, combinator(keyword)))
+		default:
+			parts = append(parts, fmt.Sprintf("%s satisfies {%q:%s}", name, keyword, canonicalEncoding(value)))
+		}
+	}
+	return strings.Join(parts, " and ")
+}
+
+func describeKeyword(keyword string, value interface{}) string {
+	return fmt.Sprintf("order satisfies {%q:%s}", keyword, canonicalEncoding(value))
+}
+
+// combinator returns the word which joins the descriptions of the subschemas
+// of the given keyword.
+func combinator(keyword string) string {
+	switch keyword {
+	case "anyOf":
+		return " or "
+	case "oneOf":
+		return " xor "
+	default:
+		return " and "
+	}
+}
+
+func joinDescriptions(subschemas []interface{}, describe func(interface{}) string, separator string) string {
+	descriptions := make([]string, len(subschemas))
+	for i, subschema := range subschemas {
+		descriptions[i] = "(" + describe(subschema) + ")"
+	}
+	if len(descriptions) == 1 {
+		return strings.TrimSuffix(strings.TrimPrefix(descriptions[0], "("), ")")
+	}
+	return strings.Join(descriptions, separator)
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
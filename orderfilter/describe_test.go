@@ -0,0 +1,55 @@
+package orderfilter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribe(t *testing.T) {
+	t.Parallel()
+
+	defaultFilter, err := New(constants.TestChainID, DefaultCustomOrderSchema, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	description := defaultFilter.Describe()
+	assert.Equal(t, constants.TestChainID, description.ChainID)
+	assert.Equal(t, ProtocolVersionV3, description.ProtocolVersion)
+	assert.Equal(t, defaultFilter.ExchangeAddresses(), description.ExchangeAddresses)
+	assert.Equal(t, defaultFilter.Topic(), description.Topic)
+	assert.Empty(t, description.AssetPairs)
+	assert.Empty(t, description.Predicates)
+	assert.Contains(t, description.String(), "Constraints: none")
+
+	pairsFilter, err := NewForTokenPairs(constants.TestChainID, []TokenPair{
+		{Base: Token{Standard: ERC20, Address: zrxAddress}, Quote: Token{Standard: ERC20, Address: wethAddress}},
+	}, contractAddresses)
+	require.NoError(t, err)
+	description = pairsFilter.Describe()
+	require.Len(t, description.AssetPairs, 1)
+	pair := description.AssetPairs[0]
+	assert.ElementsMatch(t, []string{"ERC20 token " + zrxAddress.Hex(), "ERC20 token " + wethAddress.Hex()}, []string{pair.Base, pair.Quote})
+	assert.Empty(t, description.Predicates)
+
+	customOrderSchema, err := CustomOrderSchemaFromPresets([]string{PresetNoTakerFees}, PresetOptions{})
+	require.NoError(t, err)
+	presetFilter, err := New(constants.TestChainID, customOrderSchema, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	composite, err := And(pairsFilter, presetFilter.WithMaxExpirationDuration(24*time.Hour))
+	require.NoError(t, err)
+	description = composite.Describe()
+	assert.Len(t, description.AssetPairs, 1)
+	assert.Equal(t, []string{`takerFee == "0"`}, description.Predicates)
+	assert.Equal(t, 24*time.Hour, description.MaxExpirationDuration)
+	text := description.String()
+	assert.True(t, strings.Contains(text, `takerFee == "0"`), text)
+	assert.True(t, strings.Contains(text, "24h0m0s"), text)
+
+	// Equal filters have the same description.
+	reordered, err := New(constants.TestChainID, `{ "properties": { "takerFee": { "type": "string", "pattern": "^0*(0)$" } } }`, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	assert.Equal(t, presetFilter.Describe(), reordered.Describe())
+}
@@ -0,0 +1,403 @@
+package orderfilter
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0xProject/0x-mesh/zeroex"
+	canonicaljson "github.com/gibson042/canonicaljson-go"
+)
+
+// The operators supported by the order filter DSL.
+const (
+	dslOpEquals        = "=="
+	dslOpNotEquals     = "!="
+	dslOpIn            = "in"
+	dslOpStartsWith    = "startsWith"
+	dslOpExpiresWithin = "expiresWithin"
+)
+
+type dslFieldKind int
+
+const (
+	dslAddressField dslFieldKind = iota
+	dslAssetDataField
+	dslNumberField
+)
+
+// dslField describes an order field which can be constrained with the order
+// filter DSL.
+type dslField struct {
+	kind      dslFieldKind
+	operators []string
+	bytes     func(order *zeroex.SignedOrder) []byte
+	number    func(order *zeroex.SignedOrder) *big.Int
+}
+
+var (
+	dslAddressOperators   = []string{dslOpEquals, dslOpNotEquals, dslOpIn}
+	dslAssetDataOperators = []string{dslOpEquals, dslOpNotEquals, dslOpIn, dslOpStartsWith}
+	dslNumberOperators    = []string{dslOpEquals, dslOpNotEquals, dslOpIn}
+)
+
+// dslFields are the order fields which can be constrained with the order
+// filter DSL, keyed by their name in the JSON encoding of orders.
+var dslFields = map[string]*dslField{
+	"makerAddress":          addressDSLField(func(o *zeroex.SignedOrder) []byte { return o.MakerAddress.Bytes() }),
+	"takerAddress":          addressDSLField(func(o *zeroex.SignedOrder) []byte { return o.TakerAddress.Bytes() }),
+	"senderAddress":         addressDSLField(func(o *zeroex.SignedOrder) []byte { return o.SenderAddress.Bytes() }),
+	"feeRecipientAddress":   addressDSLField(func(o *zeroex.SignedOrder) []byte { return o.FeeRecipientAddress.Bytes() }),
+	"makerAssetData":        assetDataDSLField(func(o *zeroex.SignedOrder) []byte { return o.MakerAssetData }),
+	"takerAssetData":        assetDataDSLField(func(o *zeroex.SignedOrder) []byte { return o.TakerAssetData }),
+	"makerFeeAssetData":     assetDataDSLField(func(o *zeroex.SignedOrder) []byte { return o.MakerFeeAssetData }),
+	"takerFeeAssetData":     assetDataDSLField(func(o *zeroex.SignedOrder) []byte { return o.TakerFeeAssetData }),
+	"makerAssetAmount":      numberDSLField(func(o *zeroex.SignedOrder) *big.Int { return o.MakerAssetAmount }),
+	"takerAssetAmount":      numberDSLField(func(o *zeroex.SignedOrder) *big.Int { return o.TakerAssetAmount }),
+	"makerFee":              numberDSLField(func(o *zeroex.SignedOrder) *big.Int { return o.MakerFee }),
+	"takerFee":              numberDSLField(func(o *zeroex.SignedOrder) *big.Int { return o.TakerFee }),
+	"expirationTimeSeconds": numberDSLField(func(o *zeroex.SignedOrder) *big.Int { return o.ExpirationTimeSeconds }),
+}
+
+func addressDSLField(getter func(*zeroex.SignedOrder) []byte) *dslField {
+	return &dslField{kind: dslAddressField, operators: dslAddressOperators, bytes: getter}
+}
+
+func assetDataDSLField(getter func(*zeroex.SignedOrder) []byte) *dslField {
+	return &dslField{kind: dslAssetDataField, operators: dslAssetDataOperators, bytes: getter}
+}
+
+func numberDSLField(getter func(*zeroex.SignedOrder) *big.Int) *dslField {
+	return &dslField{kind: dslNumberField, operators: dslNumberOperators, number: getter}
+}
+
+var (
+	dslHexPattern      = regexp.MustCompile(`^0x([0-9a-fA-F]{2})*$`)
+	dslHexPrefix       = regexp.MustCompile(`^0x[0-9a-fA-F]+$`)
+	dslWholeNumber     = regexp.MustCompile(`^\d+$`)
+	dslDurationPattern = regexp.MustCompile(`^(\d+)(s|m|h|d)$`)
+)
+
+// DSLSyntaxError is returned by ParseConstraints if a constraint is invalid.
+type DSLSyntaxError struct {
+	// Constraint is the invalid constraint as written in the DSL.
+	Constraint string
+	Reason     string
+}
+
+func (e DSLSyntaxError) Error() string {
+	return fmt.Sprintf("invalid order filter constraint %q: %s", e.Constraint, e.Reason)
+}
+
+// constraint is a single parsed constraint of the order filter DSL.
+type constraint struct {
+	field    string
+	operator string
+	// values holds the normalized values of the constraint: lowercase hex for
+	// addresses and assetData and decimal strings for numbers.
+	values []string
+	// duration is the duration of an expiresWithin constraint.
+	duration time.Duration
+}
+
+// Constraints is a set of order constraints written in a small textual DSL.
+// Constraints are separated by semicolons or newlines and each of them has
+// the form `<field> <operator> <value>`, e.g.:
+//
+//	makerAssetData startsWith 0xf47261b0; takerFee == 0; expiresWithin 30d
+//
+// The supported operators are == and != (all fields), in (all fields, e.g.
+// `makerAddress in [0x..., 0x...]`) and startsWith (assetData fields only).
+// Additionally, `expiresWithin <duration>` (with a unit of s, m, h or d)
+// only allows orders which expire within the given duration.
+//
+// Constraints are compiled both into a custom order schema (see
+// CustomOrderSchema), which determines the topic and is used to validate
+// orders received from peers, and into a Go predicate (see MatchOrder), which
+// is much faster than validating orders against the schema. Since JSON Schema
+// cannot express constraints which depend on the current time, expiresWithin
+// is only enforced by the predicate.
+type Constraints struct {
+	constraints []*constraint
+}
+
+// ParseConstraints parses the given constraints written in the order filter
+// DSL. Lines starting with # are ignored.
+func ParseConstraints(source string) (*Constraints, error) {
+	constraints := []*constraint{}
+	for _, line := range strings.Split(source, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for _, statement := range strings.Split(line, ";") {
+			statement = strings.TrimSpace(statement)
+			if statement == "" {
+				continue
+			}
+			parsed, err := parseConstraint(statement)
+			if err != nil {
+				return nil, err
+			}
+			constraints = append(constraints, parsed)
+		}
+	}
+	return &Constraints{constraints: constraints}, nil
+}
+
+func parseConstraint(statement string) (*constraint, error) {
+	syntaxError := func(format string, args ...interface{}) error {
+		return DSLSyntaxError{Constraint: statement, Reason: fmt.Sprintf(format, args...)}
+	}
+	parts := strings.Fields(statement)
+	if parts[0] == dslOpExpiresWithin {
+		var matches []string
+		if len(parts) == 2 {
+			matches = dslDurationPattern.FindStringSubmatch(parts[1])
+		}
+		if matches == nil {
+			return nil, syntaxError("expected a duration like 30d, 12h, 15m or 60s")
+		}
+		amount, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil || amount == 0 {
+			return nil, syntaxError("expected a positive duration")
+		}
+		unit := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour, "d": 24 * time.Hour}[matches[2]]
+		// Check the bound before multiplying so that the duration can't
+		// overflow.
+		if amount > int64(math.MaxInt64/unit) {
+			return nil, syntaxError("duration is too long")
+		}
+		return &constraint{operator: dslOpExpiresWithin, duration: time.Duration(amount) * unit}, nil
+	}
+	if len(parts) < 3 {
+		return nil, syntaxError("expected <field> <operator> <value>")
+	}
+	fieldName, operator := parts[0], parts[1]
+	field, found := dslFields[fieldName]
+	if !found {
+		return nil, syntaxError("unknown field %q", fieldName)
+	}
+	if !containsString(field.operators, operator) {
+		return nil, syntaxError("operator %q is not supported for %s (expected one of %s)", operator, fieldName, strings.Join(field.operators, ", "))
+	}
+	// The value is everything after the operator. Both the field and the
+	// operator are at the start of the (trimmed) statement.
+	rawValue := strings.TrimSpace(statement[len(fieldName):])
+	rawValue = strings.TrimSpace(rawValue[len(operator):])
+	rawValues := []string{rawValue}
+	if operator == dslOpIn {
+		if !strings.HasPrefix(rawValue, "[") || !strings.HasSuffix(rawValue, "]") {
+			return nil, syntaxError("expected a list of values like [a, b]")
+		}
+		rawValues = strings.Split(strings.TrimSuffix(strings.TrimPrefix(rawValue, "["), "]"), ",")
+	}
+	values := make([]string, 0, len(rawValues))
+	for _, value := range rawValues {
+		value = strings.TrimSpace(value)
+		normalized, err := normalizeDSLValue(field, operator, value)
+		if err != nil {
+			return nil, syntaxError(err.Error())
+		}
+		values = append(values, normalized)
+	}
+	values = sortedUnique(values)
+	if len(values) == 0 {
+		return nil, syntaxError("expected at least one value")
+	}
+	return &constraint{field: fieldName, operator: operator, values: values}, nil
+}
+
+func normalizeDSLValue(field *dslField, operator string, value string) (string, error) {
+	switch {
+	case field.kind == dslAddressField:
+		address, err := zeroex.ParseAddress(value)
+		if err != nil {
+			return "", err
+		}
+		return zeroex.NormalizedAddressHex(address), nil
+	case field.kind == dslAssetDataField && operator == dslOpStartsWith:
+		if !dslHexPrefix.MatchString(value) {
+			return "", fmt.Errorf("%q is not a hex prefix", value)
+		}
+		return strings.ToLower(value), nil
+	case field.kind == dslAssetDataField:
+		if !dslHexPattern.MatchString(value) {
+			return "", fmt.Errorf("%q is not hex encoded bytes", value)
+		}
+		return strings.ToLower(value), nil
+	default:
+		if !dslWholeNumber.MatchString(value) {
+			return "", fmt.Errorf("%q is not a whole number", value)
+		}
+		number, _ := new(big.Int).SetString(value, 10)
+		return number.String(), nil
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// CustomOrderSchema returns the custom order schema which is equivalent to the
+// constraints (except for expiresWithin). The result can be passed to New like
+// any hand-written custom order schema. The schema doesn't depend on the order
+// in which the constraints were written, so nodes which use the same
+// constraints end up with the same topic.
+func (c *Constraints) CustomOrderSchema() (string, error) {
+	schemasByKey := map[string]interface{}{}
+	for _, constraint := range c.constraints {
+		if constraint.operator == dslOpExpiresWithin {
+			continue
+		}
+		schema := propertiesSchema(map[string]interface{}{
+			constraint.field: constraint.propertySchema(),
+		})
+		key, err := canonicaljson.Marshal(schema)
+		if err != nil {
+			return "", err
+		}
+		schemasByKey[string(key)] = schema
+	}
+	if len(schemasByKey) == 0 {
+		return DefaultCustomOrderSchema, nil
+	}
+	keys := make([]string, 0, len(schemasByKey))
+	for key := range schemasByKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var customOrderSchema interface{}
+	if len(keys) == 1 {
+		customOrderSchema = schemasByKey[keys[0]]
+	} else {
+		schemas := make([]interface{}, len(keys))
+		for i, key := range keys {
+			schemas[i] = schemasByKey[key]
+		}
+		customOrderSchema = map[string]interface{}{"allOf": schemas}
+	}
+	encoded, err := json.Marshal(customOrderSchema)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+func (c *constraint) propertySchema() map[string]interface{} {
+	if dslFields[c.field].kind == dslNumberField {
+		return c.numberPropertySchema()
+	}
+	switch c.operator {
+	case dslOpEquals:
+		return map[string]interface{}{"const": c.values[0]}
+	case dslOpNotEquals:
+		return map[string]interface{}{"not": map[string]interface{}{"const": c.values[0]}}
+	case dslOpIn:
+		return map[string]interface{}{"enum": c.values}
+	default: // dslOpStartsWith
+		return map[string]interface{}{"type": "string", "pattern": "^" + c.values[0]}
+	}
+}
+
+// numberPropertySchema returns the schema for a constraint on a number field.
+func (c *constraint) numberPropertySchema() map[string]interface{} {
+	if c.operator == dslOpNotEquals {
+		return map[string]interface{}{"not": wholeNumberValuesSchema(c.values)}
+	}
+	return wholeNumberValuesSchema(c.values)
+}
+
+// wholeNumberValuesSchema returns a schema which accepts the given whole
+// numbers (in their canonical encoding). Numbers are compared by value when
+// constraints are checked natively, so the schema must also accept them with
+// leading zeros (which the default order schema allows). A const or enum
+// would only accept the canonical encoding.
+func wholeNumberValuesSchema(values []string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "pattern": "^0*(" + strings.Join(values, "|") + ")$"}
+}
+
+// MaxExpirationDuration returns the shortest duration of the expiresWithin
+// constraints or 0 if there are none.
+func (c *Constraints) MaxExpirationDuration() time.Duration {
+	var maxDuration time.Duration
+	for _, constraint := range c.constraints {
+		if constraint.operator == dslOpExpiresWithin && (maxDuration == 0 || constraint.duration < maxDuration) {
+			maxDuration = constraint.duration
+		}
+	}
+	return maxDuration
+}
+
+// MatchOrder returns true if the given order satisfies all of the constraints
+// at the given time. It doesn't check the fields which are validated by the
+// default order schema.
+func (c *Constraints) MatchOrder(order *zeroex.SignedOrder, now time.Time) bool {
+	for _, constraint := range c.constraints {
+		if !constraint.matchOrder(order, now) {
+			return false
+		}
+	}
+	return true
+}
+
+// rejectReasons returns the reasons why the given order doesn't satisfy the
+// constraints at the given time or nil if it does. Failing expiresWithin
+// constraints are reported as RejectionReasonMaxExpiration and all others as
+// violations of the schema of their field.
+func (c *Constraints) rejectReasons(order *zeroex.SignedOrder, now time.Time) []string {
+	var rejectReasons []string
+	for _, constraint := range c.constraints {
+		if constraint.matchOrder(order, now) {
+			continue
+		}
+		if constraint.operator == dslOpExpiresWithin {
+			rejectReasons = appendRejectReason(rejectReasons, RejectionReasonMaxExpiration)
+		} else {
+			rejectReasons = appendRejectReason(rejectReasons, RejectionReasonSchema+":"+constraint.field)
+		}
+	}
+	return rejectReasons
+}
+
+func (c *constraint) matchOrder(order *zeroex.SignedOrder, now time.Time) bool {
+	if c.operator == dslOpExpiresWithin {
+		if order.ExpirationTimeSeconds == nil {
+			return false
+		}
+		maxExpirationTime := big.NewInt(now.Add(c.duration).Unix())
+		return order.ExpirationTimeSeconds.Cmp(maxExpirationTime) <= 0
+	}
+	field := dslFields[c.field]
+	var actual string
+	if field.kind == dslNumberField {
+		number := field.number(order)
+		if number == nil {
+			return false
+		}
+		actual = number.String()
+	} else {
+		actual = "0x" + hex.EncodeToString(field.bytes(order))
+	}
+	switch c.operator {
+	case dslOpEquals:
+		return actual == c.values[0]
+	case dslOpNotEquals:
+		return actual != c.values[0]
+	case dslOpIn:
+		return containsString(c.values, actual)
+	default: // dslOpStartsWith
+		return strings.HasPrefix(actual, c.values[0])
+	}
+}
@@ -0,0 +1,158 @@
+package orderfilter
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstraintsCustomOrderSchema(t *testing.T) {
+	// Constraints which are equivalent to a preset result in the same schema.
+	constraints, err := ParseConstraints("takerFee == 0")
+	require.NoError(t, err)
+	schema, err := constraints.CustomOrderSchema()
+	require.NoError(t, err)
+	presetSchema, err := CustomOrderSchemaFromPresets([]string{PresetNoTakerFees}, PresetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, presetSchema, schema)
+
+	// The schema doesn't depend on the order of the constraints, duplicates or
+	// the casing of hex values.
+	constraintsA, err := ParseConstraints("makerAssetData startsWith 0xF47261B0; takerFee == 0; expiresWithin 30d")
+	require.NoError(t, err)
+	constraintsB, err := ParseConstraints("# Only ERC20 orders without taker fees\ntakerFee == 0\nmakerAssetData startsWith 0xf47261b0;takerFee == 0")
+	require.NoError(t, err)
+	schemaA, err := constraintsA.CustomOrderSchema()
+	require.NoError(t, err)
+	schemaB, err := constraintsB.CustomOrderSchema()
+	require.NoError(t, err)
+	assert.Equal(t, schemaA, schemaB)
+	assert.Equal(t, 30*24*time.Hour, constraintsA.MaxExpirationDuration())
+	assert.Equal(t, time.Duration(0), constraintsB.MaxExpirationDuration())
+
+	emptyConstraints, err := ParseConstraints("expiresWithin 1h")
+	require.NoError(t, err)
+	schema, err = emptyConstraints.CustomOrderSchema()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultCustomOrderSchema, schema)
+}
+
+func TestConstraintsMatchOrder(t *testing.T) {
+	order := &zeroex.SignedOrder{}
+	require.NoError(t, order.UnmarshalJSON(standardValidOrderJSON))
+	now := time.Now()
+
+	testCases := []struct {
+		constraints   string
+		expectedMatch bool
+	}{
+		{"", true},
+		{"takerFee == 0", true},
+		{"takerFee != 0", false},
+		{"makerAssetAmount in [1, 100000000000000000000]", true},
+		{"makerAssetAmount in [1, 2]", false},
+		{"makerAssetData startsWith 0xf47261b0", true},
+		{"makerAssetData startsWith 0xF47261B0000", true},
+		{"makerAssetData startsWith 0x02571792", false},
+		{"makerFeeAssetData == 0x", true},
+		{"takerAssetData == 0xf47261b0000000000000000000000000c02aaa39b223fe8d0a0e5c4f27ead9083c756cc2", true},
+		{"makerAddress == 0xA3ECE5D5B6319FA785EFC10D3112769A46C6E149", true},
+		{"makerAddress in [0x0000000000000000000000000000000000000000, 0xa3ece5d5b6319fa785efc10d3112769a46c6e149]", true},
+		{"makerAddress != 0xa3ece5d5b6319fa785efc10d3112769a46c6e149", false},
+		{"takerFee == 0; makerAssetData startsWith 0x02571792", false},
+		{"expiresWithin 30d", false},
+	}
+	for _, tc := range testCases {
+		constraints, err := ParseConstraints(tc.constraints)
+		require.NoError(t, err, tc.constraints)
+		assert.Equal(t, tc.expectedMatch, constraints.MatchOrder(order, now), tc.constraints)
+
+		// The compiled predicate must agree with the schema, except for
+		// expiresWithin which is not part of the schema.
+		if constraints.MaxExpirationDuration() != 0 {
+			continue
+		}
+		filter, err := NewFromConstraints(constants.TestChainID, constraints, contractAddresses)
+		require.NoError(t, err, tc.constraints)
+		result, err := filter.ValidateOrderJSON(standardValidOrderJSON)
+		require.NoError(t, err, tc.constraints)
+		assert.Equal(t, tc.expectedMatch, result.Valid(), "%s: %v", tc.constraints, result.Errors())
+		matches, _, err := filter.MatchOrder(order)
+		require.NoError(t, err, tc.constraints)
+		assert.Equal(t, tc.expectedMatch, matches, tc.constraints)
+	}
+
+	// expiresWithin only allows orders which expire soon enough.
+	constraints, err := ParseConstraints("expiresWithin 1h")
+	require.NoError(t, err)
+	order.ExpirationTimeSeconds = big.NewInt(now.Add(30 * time.Minute).Unix())
+	assert.True(t, constraints.MatchOrder(order, now))
+	order.ExpirationTimeSeconds = big.NewInt(now.Add(2 * time.Hour).Unix())
+	assert.False(t, constraints.MatchOrder(order, now))
+}
+
+func TestConstraintsNonCanonicalNumbers(t *testing.T) {
+	// The default order schema allows numbers with leading zeros, which
+	// encode the same value as the canonical number. The schema compiled from
+	// the constraints must agree with the compiled predicate for them.
+	orderJSON := bytes.Replace(standardValidOrderJSON, []byte(`"takerFee":"0"`), []byte(`"takerFee":"000"`), 1)
+	orderJSON = bytes.Replace(orderJSON, []byte(`"makerAssetAmount":"100000000000000000000"`), []byte(`"makerAssetAmount":"0100000000000000000000"`), 1)
+	require.NotEqual(t, standardValidOrderJSON, orderJSON)
+	order := &zeroex.SignedOrder{}
+	require.NoError(t, order.UnmarshalJSON(orderJSON))
+
+	testCases := []struct {
+		constraints   string
+		expectedMatch bool
+	}{
+		{"takerFee == 0", true},
+		{"takerFee != 0", false},
+		{"takerFee in [0, 1]", true},
+		{"takerFee == 10", false},
+		{"makerAssetAmount == 100000000000000000000", true},
+		{"makerAssetAmount != 100000000000000000000", false},
+		{"makerAssetAmount in [1, 10000000000000000000]", false},
+	}
+	for _, tc := range testCases {
+		constraints, err := ParseConstraints(tc.constraints)
+		require.NoError(t, err, tc.constraints)
+		assert.Equal(t, tc.expectedMatch, constraints.MatchOrder(order, time.Now()), tc.constraints)
+		filter, err := NewFromConstraints(constants.TestChainID, constraints, contractAddresses)
+		require.NoError(t, err, tc.constraints)
+		result, err := filter.ValidateOrderJSON(orderJSON)
+		require.NoError(t, err, tc.constraints)
+		assert.Equal(t, tc.expectedMatch, result.Valid(), "%s: %v", tc.constraints, result.Errors())
+	}
+}
+
+func TestParseConstraintsErrors(t *testing.T) {
+	invalidConstraints := []string{
+		"takerFee",
+		"takerFee >= 0",
+		"unknownField == 0",
+		"takerFee == -1",
+		"takerFee == 0x10",
+		"takerFee startsWith 1",
+		"makerAddress == 0x1234",
+		"makerAssetData == 0xf47",
+		"makerAssetData startsWith f47261b0",
+		"makerAddress in 0xa3ece5d5b6319fa785efc10d3112769a46c6e149",
+		"expiresWithin 30",
+		"expiresWithin 0d",
+		"expiresWithin 30 d",
+		// Would overflow time.Duration.
+		"expiresWithin 106752d",
+		"expiresWithin 9223372036854775807s",
+	}
+	for _, source := range invalidConstraints {
+		_, err := ParseConstraints(source)
+		require.Error(t, err, source)
+		assert.IsType(t, DSLSyntaxError{}, err, source)
+	}
+}
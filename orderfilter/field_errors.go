@@ -0,0 +1,132 @@
+package orderfilter
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// FieldError is a machine-readable description of a single violation of the
+// order schema. It can be used to point users to the offending field of an
+// order.
+type FieldError struct {
+	// Pointer is the JSON pointer (RFC 6901) to the offending field, e.g.
+	// "/takerAddress". If a required field is missing or a field is not
+	// allowed, it points to that field. The pointer to the order itself is "".
+	Pointer string `json:"pointer"`
+	// Constraint is the JSON schema keyword which was violated, e.g. "pattern",
+	// "required" or "enum".
+	Constraint string `json:"constraint"`
+	// Expected is the limit imposed by the constraint, e.g. the pattern, the
+	// allowed values or the minimum. It is omitted for constraints without a
+	// limit (such as "required").
+	Expected interface{} `json:"expected,omitempty"`
+	// Actual is the value of the offending field. It is omitted if the field is
+	// missing.
+	Actual interface{} `json:"actual,omitempty"`
+	// Message is a human-readable description of the violation.
+	Message string `json:"message"`
+}
+
+// builtInPatterns are the patterns used by the built-in schemas (see
+// addressSchema, wholeNumberSchema and hexSchema).
+var builtInPatterns = map[string]struct{}{
+	`^0x[0-9a-fA-F]{40}$`:              {},
+	`^\d+$`:                            {},
+	`^0x(([0-9a-fA-F][0-9a-fA-F])+)?$`: {},
+}
+
+// wholeNumberPointers are the pointers to the fields whose built-in schema is
+// /wholeNumber, which is an anyOf.
+var wholeNumberPointers = map[string]struct{}{
+	"/makerFee":              {},
+	"/takerFee":              {},
+	"/makerAssetAmount":      {},
+	"/takerAssetAmount":      {},
+	"/salt":                  {},
+	"/expirationTimeSeconds": {},
+}
+
+// ViolatesBuiltInSchema returns true if the field error is (most likely) a
+// violation of the built-in order schema, i.e. the order is malformed or for a
+// different chain or exchange, rather than a violation of the custom order
+// schema. Violations of custom order schemas which use the same constraints as
+// the built-in schemas (e.g. a required field) can't be told apart and are
+// considered violations of the built-in schema.
+func (e *FieldError) ViolatesBuiltInSchema() bool {
+	switch e.Pointer {
+	case "/chainId", "/exchangeAddress":
+		return true
+	}
+	switch e.Constraint {
+	case "required", "type":
+		return true
+	case "pattern":
+		pattern, ok := e.Expected.(string)
+		if !ok {
+			return false
+		}
+		_, found := builtInPatterns[pattern]
+		return found
+	case "anyOf":
+		_, found := wholeNumberPointers[e.Pointer]
+		return found
+	default:
+		return false
+	}
+}
+
+// appendJSONPointerToken appends the given reference token to a JSON pointer,
+// escaping it as required by RFC 6901.
+func appendJSONPointerToken(pointer string, token string) string {
+	token = strings.Replace(token, "~", "~0", -1)
+	token = strings.Replace(token, "/", "~1", -1)
+	return pointer + "/" + token
+}
+
+// valueAtJSONPointer returns the value in the given JSON document which the
+// pointer refers to. Numbers are returned as json.Number so that large values
+// are not rounded.
+func valueAtJSONPointer(document []byte, pointer string) (interface{}, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, false
+	}
+	if pointer == "" {
+		return value, true
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.Replace(token, "~1", "/", -1)
+		token = strings.Replace(token, "~0", "~", -1)
+		switch current := value.(type) {
+		case map[string]interface{}:
+			child, found := current[token]
+			if !found {
+				return nil, false
+			}
+			value = child
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(current) {
+				return nil, false
+			}
+			value = current[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// setActualValues sets the Actual value of each of the given field errors to
+// the value of the offending field in orderJSON.
+func setActualValues(orderJSON []byte, fieldErrors []*FieldError) {
+	for _, fieldError := range fieldErrors {
+		if actual, found := valueAtJSONPointer(orderJSON, fieldError.Pointer); found {
+			fieldError.Actual = actual
+		}
+	}
+}
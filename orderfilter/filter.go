@@ -4,9 +4,9 @@ package orderfilter
 
 import (
 	"fmt"
-	"strings"
+	"time"
 
-	"github.com/0xProject/0x-mesh/ethereum"
+	"github.com/ethereum/go-ethereum/common"
 	jsonschema "github.com/xeipuuv/gojsonschema"
 )
 
@@ -18,8 +18,16 @@ var (
 	orderSchemaLoader       = jsonschema.NewStringLoader(orderSchema)
 	signedOrderSchemaLoader = jsonschema.NewStringLoader(signedOrderSchema)
 
+	// Built-in schemas for v4 orders
+	bytes32SchemaLoader       = jsonschema.NewStringLoader(bytes32Schema)
+	v4SignatureSchemaLoader   = jsonschema.NewStringLoader(v4SignatureSchema)
+	limitOrderV4SchemaLoader  = jsonschema.NewStringLoader(limitOrderV4Schema)
+	rfqOrderV4SchemaLoader    = jsonschema.NewStringLoader(rfqOrderV4Schema)
+	signedOrderV4SchemaLoader = jsonschema.NewStringLoader(signedOrderV4Schema)
+
 	// Root schemas
 	rootOrderSchemaLoader        = jsonschema.NewStringLoader(rootOrderSchema)
+	rootOrderV4SchemaLoader      = jsonschema.NewStringLoader(rootOrderV4Schema)
 	rootOrderMessageSchemaLoader = jsonschema.NewStringLoader(rootOrderMessageSchema)
 )
 
@@ -31,45 +39,95 @@ var builtInSchemas = []jsonschema.JSONLoader{
 	signedOrderSchemaLoader,
 }
 
+var builtInV4Schemas = []jsonschema.JSONLoader{
+	addressSchemaLoader,
+	wholeNumberSchemaLoader,
+	hexSchemaLoader,
+	bytes32SchemaLoader,
+	v4SignatureSchemaLoader,
+	limitOrderV4SchemaLoader,
+	rfqOrderV4SchemaLoader,
+	signedOrderV4SchemaLoader,
+}
+
 type Filter struct {
 	encodedSchema        string
 	chainID              int
 	rawCustomOrderSchema string
 	orderSchema          *jsonschema.Schema
-	messageSchema        *jsonschema.Schema
+	validator            *nativeValidator
+	exchangeAddresses    []common.Address
+	// constraints are set if the filter was created with NewFromConstraints.
+	constraints *Constraints
+	// composite is set if the filter was created with And, Or or Not.
+	composite *composite
+	// maxExpirationDuration is set with WithMaxExpirationDuration.
+	maxExpirationDuration time.Duration
+	// topicVersion is the version of the topic of the filter. It is only set
+	// by NewFromTopic, and 0 means the current version.
+	topicVersion int
+	// protocolVersion is the version of the 0x protocol whose orders pass the
+	// filter.
+	protocolVersion ProtocolVersion
+	// decodesAssetData is true if the custom order schema uses
+	// AssetDataKeyword, which means that orders are validated with their
+	// decoded asset data.
+	decodesAssetData bool
+	// metrics counts the outcomes of MatchOrderMessageJSON and
+	// ValidateOrderJSON.
+	metrics *filterMetrics
 }
 
-func New(chainID int, customOrderSchema string, contractAddresses ethereum.ContractAddresses) (*Filter, error) {
-	orderLoader, err := newLoader(chainID, customOrderSchema, contractAddresses)
+// NewWithExchangeAddresses is like New, but the filter accepts orders for any
+// of the given exchange addresses (e.g. for different versions of the 0x
+// Exchange contract) instead of only the one in the contract addresses. For v4
+// orders, the exchange addresses are the accepted verifying contracts.
+func NewWithExchangeAddresses(chainID int, customOrderSchema string, exchangeAddresses []common.Address, protocolVersion ProtocolVersion) (*Filter, error) {
+	if err := validateProtocolVersion(protocolVersion); err != nil {
+		return nil, err
+	}
+	exchangeAddresses, err := uniqueExchangeAddresses(exchangeAddresses)
 	if err != nil {
 		return nil, err
 	}
-	compiledRootOrderSchema, err := orderLoader.Compile(rootOrderSchemaLoader)
+	if err := validateSchemaFormats(customOrderSchema); err != nil {
+		return nil, err
+	}
+	expandedCustomOrderSchema, decodesAssetData, err := expandAssetDataKeywords(customOrderSchema)
 	if err != nil {
 		return nil, err
 	}
-
-	messageLoader, err := newLoader(chainID, customOrderSchema, contractAddresses)
-	if err := messageLoader.AddSchemas(rootOrderSchemaLoader); err != nil {
+	orderLoader, err := newLoader(chainID, expandedCustomOrderSchema, exchangeAddresses, protocolVersion)
+	if err != nil {
+		return nil, err
+	}
+	rootSchemaLoader := rootOrderSchemaLoader
+	if protocolVersion == ProtocolVersionV4 {
+		rootSchemaLoader = rootOrderV4SchemaLoader
+	}
+	compiledRootOrderSchema, err := orderLoader.Compile(rootSchemaLoader)
+	if err != nil {
 		return nil, err
 	}
-	compiledRootOrderMessageSchema, err := messageLoader.Compile(rootOrderMessageSchemaLoader)
+	validator, err := newNativeValidator(chainID, expandedCustomOrderSchema, exchangeAddresses, protocolVersion)
 	if err != nil {
 		return nil, err
 	}
+	validator.decodesAssetData = decodesAssetData
 	return &Filter{
 		chainID:              chainID,
 		rawCustomOrderSchema: customOrderSchema,
 		orderSchema:          compiledRootOrderSchema,
-		messageSchema:        compiledRootOrderMessageSchema,
+		validator:            validator,
+		exchangeAddresses:    exchangeAddresses,
+		protocolVersion:      protocolVersion,
+		decodesAssetData:     decodesAssetData,
+		metrics:              newFilterMetrics(),
 	}, nil
 }
 
-func loadExchangeAddress(loader *jsonschema.SchemaLoader, chainID int, contractAddresses ethereum.ContractAddresses) error {
-	// Note that exchangeAddressSchema accepts both checksummed and
-	// non-checksummed (i.e. all lowercase) addresses.
-	exchangeAddressSchema := fmt.Sprintf(`{"enum":[%q,%q]}`, contractAddresses.Exchange.Hex(), strings.ToLower(contractAddresses.Exchange.Hex()))
-	return loader.AddSchema("/exchangeAddress", jsonschema.NewStringLoader(exchangeAddressSchema))
+func loadExchangeAddress(loader *jsonschema.SchemaLoader, exchangeAddresses []common.Address) error {
+	return loader.AddSchema("/exchangeAddress", jsonschema.NewStringLoader(newExchangeAddressSchema(exchangeAddresses)))
 }
 
 func loadChainID(loader *jsonschema.SchemaLoader, chainID int) error {
@@ -77,15 +135,19 @@ func loadChainID(loader *jsonschema.SchemaLoader, chainID int) error {
 	return loader.AddSchema("/chainId", jsonschema.NewStringLoader(chainIDSchema))
 }
 
-func newLoader(chainID int, customOrderSchema string, contractAddresses ethereum.ContractAddresses) (*jsonschema.SchemaLoader, error) {
+func newLoader(chainID int, customOrderSchema string, exchangeAddresses []common.Address, protocolVersion ProtocolVersion) (*jsonschema.SchemaLoader, error) {
 	loader := jsonschema.NewSchemaLoader()
 	if err := loadChainID(loader, chainID); err != nil {
 		return nil, err
 	}
-	if err := loadExchangeAddress(loader, chainID, contractAddresses); err != nil {
+	if err := loadExchangeAddress(loader, exchangeAddresses); err != nil {
 		return nil, err
 	}
-	if err := loader.AddSchemas(builtInSchemas...); err != nil {
+	schemas := builtInSchemas
+	if protocolVersion == ProtocolVersionV4 {
+		schemas = builtInV4Schemas
+	}
+	if err := loader.AddSchemas(schemas...); err != nil {
 		return nil, err
 	}
 	if err := loader.AddSchema("/customOrder", jsonschema.NewStringLoader(customOrderSchema)); err != nil {
@@ -7,9 +7,10 @@ import (
 	"fmt"
 	"strings"
 	"syscall/js"
+	"time"
 
-	"github.com/0xProject/0x-mesh/ethereum"
 	"github.com/0xProject/0x-mesh/packages/browser/go/jsutil"
+	"github.com/ethereum/go-ethereum/common"
 )
 
 type Filter struct {
@@ -18,33 +19,90 @@ type Filter struct {
 	encodedSchema        string
 	chainID              int
 	rawCustomOrderSchema string
+	exchangeAddresses    []common.Address
+	// constraints are set if the filter was created with NewFromConstraints.
+	constraints *Constraints
+	// composite is set if the filter was created with And, Or or Not.
+	composite *composite
+	// maxExpirationDuration is set with WithMaxExpirationDuration.
+	maxExpirationDuration time.Duration
+	// topicVersion is the version of the topic of the filter. It is only set
+	// by NewFromTopic, and 0 means the current version.
+	topicVersion int
+	// protocolVersion is the version of the 0x protocol whose orders pass the
+	// filter.
+	protocolVersion ProtocolVersion
+	// decodesAssetData is true if the custom order schema uses
+	// AssetDataKeyword, which means that orders are validated with their
+	// decoded asset data.
+	decodesAssetData bool
+	// metrics counts the outcomes of MatchOrderMessageJSON and
+	// ValidateOrderJSON.
+	metrics *filterMetrics
 }
 
-func New(chainID int, customOrderSchema string, contractAddresses ethereum.ContractAddresses) (*Filter, error) {
+// NewWithExchangeAddresses is like New, but the filter accepts orders for any
+// of the given exchange addresses (e.g. for different versions of the 0x
+// Exchange contract) instead of only the one in the contract addresses. For v4
+// orders, the exchange addresses are the accepted verifying contracts.
+func NewWithExchangeAddresses(chainID int, customOrderSchema string, exchangeAddresses []common.Address, protocolVersion ProtocolVersion) (*Filter, error) {
+	if err := validateProtocolVersion(protocolVersion); err != nil {
+		return nil, err
+	}
+	exchangeAddresses, err := uniqueExchangeAddresses(exchangeAddresses)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSchemaFormats(customOrderSchema); err != nil {
+		return nil, err
+	}
+	expandedCustomOrderSchema, decodesAssetData, err := expandAssetDataKeywords(customOrderSchema)
+	if err != nil {
+		return nil, err
+	}
 	chainIDSchema := fmt.Sprintf(`{"$id": "/chainId", "const":%d}`, chainID)
-	exchangeAddressSchema := fmt.Sprintf(`{"$id": "/exchangeAddress", "enum":[%q,%q]}`, contractAddresses.Exchange.Hex(), strings.ToLower(contractAddresses.Exchange.Hex()))
+	// Add the $id to the beginning of the schema object.
+	exchangeAddressSchema := `{"$id": "/exchangeAddress", ` + strings.TrimPrefix(newExchangeAddressSchema(exchangeAddresses), "{")
 
 	if jsutil.IsNullOrUndefined(js.Global().Get("createSchemaValidator")) {
 		return nil, errors.New(`"createSchemaValidator" has not been set on the Javascript "global" object`)
 	}
 	// NOTE(jalextowle): The order of the schemas within the two arrays
 	// defines their order of compilation.
-	schemaValidator := js.Global().Call(
-		"createSchemaValidator",
-		customOrderSchema,
-		[]interface{}{
+	schemas := []interface{}{
+		addressSchema,
+		wholeNumberSchema,
+		hexSchema,
+		chainIDSchema,
+		exchangeAddressSchema,
+		orderSchema,
+		signedOrderSchema,
+	}
+	rootSchema := rootOrderSchema
+	if protocolVersion == ProtocolVersionV4 {
+		schemas = []interface{}{
 			addressSchema,
 			wholeNumberSchema,
 			hexSchema,
 			chainIDSchema,
 			exchangeAddressSchema,
-			orderSchema,
-			signedOrderSchema,
-		},
+			bytes32Schema,
+			v4SignatureSchema,
+			limitOrderV4Schema,
+			rfqOrderV4Schema,
+			signedOrderV4Schema,
+		}
+		rootSchema = rootOrderV4Schema
+	}
+	schemaValidator := js.Global().Call(
+		"createSchemaValidator",
+		expandedCustomOrderSchema,
+		schemas,
 		[]interface{}{
-			rootOrderSchema,
+			rootSchema,
 			rootOrderMessageSchema,
-		})
+		},
+		getJSFormatCheckers())
 	orderValidator := schemaValidator.Get("orderValidator")
 	if jsutil.IsNullOrUndefined(orderValidator) {
 		return nil, errors.New(`"orderValidator" has not been set on the provided "schemaValidator"`)
@@ -58,5 +116,9 @@ func New(chainID int, customOrderSchema string, contractAddresses ethereum.Contr
 		messageValidator:     messageValidator,
 		chainID:              chainID,
 		rawCustomOrderSchema: customOrderSchema,
+		exchangeAddresses:    exchangeAddresses,
+		protocolVersion:      protocolVersion,
+		decodesAssetData:     decodesAssetData,
+		metrics:              newFilterMetrics(),
 	}, nil
 }
@@ -149,7 +149,7 @@ func TestFilterValidateOrder(t *testing.T) {
 
 	for i, tc := range testCases {
 		tcInfo := fmt.Sprintf("test case %d\nchainID: %d\nschema: %s", i, tc.chainID, tc.customOrderSchema)
-		filter, err := New(tc.chainID, tc.customOrderSchema, contractAddresses)
+		filter, err := New(tc.chainID, tc.customOrderSchema, contractAddresses, ProtocolVersionV3)
 		require.NoError(t, err, tcInfo)
 		signedOrder, err := zeroex.SignTestOrder(tc.order)
 		require.NoError(t, err)
@@ -236,7 +236,7 @@ func TestFilterValidateOrderJSON(t *testing.T) {
 
 	for i, tc := range testCases {
 		tcInfo := fmt.Sprintf("test case %d\nchainID: %d\nschema: %s\nnote: %s", i, tc.chainID, tc.customOrderSchema, tc.note)
-		filter, err := New(tc.chainID, tc.customOrderSchema, contractAddresses)
+		filter, err := New(tc.chainID, tc.customOrderSchema, contractAddresses, ProtocolVersionV3)
 		require.NoError(t, err)
 		actualResult, err := filter.ValidateOrderJSON(tc.orderJSON)
 		require.NoError(t, err, tc.customOrderSchema)
@@ -256,6 +256,57 @@ func TestFilterValidateOrderJSON(t *testing.T) {
 	}
 }
 
+func TestFieldErrors(t *testing.T) {
+	t.Parallel()
+
+	filter, err := New(constants.TestChainID, DefaultCustomOrderSchema, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+
+	invalidTakerAddressJSON := []byte(strings.Replace(string(standardValidOrderJSON), `"takerAddress":"0x0000000000000000000000000000000000000000"`, `"takerAddress":"hi"`, 1))
+	result, err := filter.ValidateOrderJSON(invalidTakerAddressJSON)
+	require.NoError(t, err)
+	fieldError := findFieldError(FieldErrors(invalidTakerAddressJSON, result), "/takerAddress")
+	require.NotNil(t, fieldError, "missing field error for /takerAddress")
+	assert.Equal(t, "pattern", fieldError.Constraint)
+	assert.Equal(t, "^0x[0-9a-fA-F]{40}$", fieldError.Expected)
+	assert.Equal(t, "hi", fieldError.Actual)
+
+	missingMakerAddressJSON := []byte(strings.Replace(string(standardValidOrderJSON), `"makerAddress":"0xa3ece5d5b6319fa785efc10d3112769a46c6e149",`, "", 1))
+	result, err = filter.ValidateOrderJSON(missingMakerAddressJSON)
+	require.NoError(t, err)
+	fieldError = findFieldError(FieldErrors(missingMakerAddressJSON, result), "/makerAddress")
+	require.NotNil(t, fieldError, "missing field error for /makerAddress")
+	assert.Equal(t, "required", fieldError.Constraint)
+	assert.Nil(t, fieldError.Actual)
+
+	result, err = filter.ValidateOrderJSON(standardValidOrderJSON)
+	require.NoError(t, err)
+	assert.Empty(t, FieldErrors(standardValidOrderJSON, result))
+}
+
+func TestValueAtJSONPointer(t *testing.T) {
+	t.Parallel()
+
+	document := []byte(`{"a":{"b/c":[1,"two"]},"m~n":true}`)
+	value, found := valueAtJSONPointer(document, appendJSONPointerToken(appendJSONPointerToken("/a", "b/c"), "1"))
+	require.True(t, found)
+	assert.Equal(t, "two", value)
+	value, found = valueAtJSONPointer(document, appendJSONPointerToken("", "m~n"))
+	require.True(t, found)
+	assert.Equal(t, true, value)
+	_, found = valueAtJSONPointer(document, "/a/b~1c/2")
+	assert.False(t, found)
+}
+
+func findFieldError(fieldErrors []*FieldError, pointer string) *FieldError {
+	for _, fieldError := range fieldErrors {
+		if fieldError.Pointer == pointer {
+			return fieldError
+		}
+	}
+	return nil
+}
+
 func TestFilterMatchOrderMessageJSON(t *testing.T) {
 	t.Parallel()
 
@@ -322,11 +373,39 @@ func TestFilterMatchOrderMessageJSON(t *testing.T) {
 			orderMessageJSON:  []byte(`{"messageType":"order","order":{"makerAddress":"0x6ecbe1db9ef729cbe972c83fb886247691fb6beb","makerAssetData":"0xf47261b0000000000000000000000000871dd7c2b4b25e1aa18728e9d5f2af4c4e431f5c","makerAssetAmount":"100000000000000000000","makerFee":"0","takerAddress":"0x0000000000000000000000000000000000000000","takerAssetData":"0xf47261b00000000000000000000000000b1ba0af832d7c05fd64161e0db78e85978e8082","takerAssetAmount":"50000000000000000000","takerFee":"0","senderAddress":"0x0000000000000000000000000000000000000000","exchangeAddress":"0x48bacb9266a570d521063ef5dd96e61686dbe788","feeRecipientAddress":"0xa258b39954cef5cb142fd567a46cddb31a670124","expirationTimeSeconds":"1575499721","salt":"1548619145450","makerFeeAssetData":"0x","takerFeeAssetData":"0x","chainID":42,"signature":"0x1b0d147219c5c92262f0902727a8d72b09ea5165ac2ede14bccbfbf6559343d8305978e22516dc1ea75e10af2c8954cd45da562ec907ce5723a62728272c566a3f02"},"topics":["/0x-orders/version/3/chain/1337/schema/e30="]}`),
 			expectedResult:    false,
 		},
+		{
+			note:              "order update hint",
+			chainID:           constants.TestChainID,
+			customOrderSchema: DefaultCustomOrderSchema,
+			orderMessageJSON:  []byte(`{"messageType":"orderUpdateHint","orderHashes":["0xabababababababababababababababababababababababababababababababab"],"blockNumber":"12345","topics":["/0x-orders/version/3/chain/1337/schema/e30="]}`),
+			expectedResult:    true,
+		},
+		{
+			note:              "order update hint with invalid order hash",
+			chainID:           constants.TestChainID,
+			customOrderSchema: DefaultCustomOrderSchema,
+			orderMessageJSON:  []byte(`{"messageType":"orderUpdateHint","orderHashes":["0x1234"],"blockNumber":"12345","topics":["/0x-orders/version/3/chain/1337/schema/e30="]}`),
+			expectedResult:    false,
+		},
+		{
+			note:              "order update hint without order hashes",
+			chainID:           constants.TestChainID,
+			customOrderSchema: DefaultCustomOrderSchema,
+			orderMessageJSON:  []byte(`{"messageType":"orderUpdateHint","orderHashes":[],"blockNumber":"12345","topics":["/0x-orders/version/3/chain/1337/schema/e30="]}`),
+			expectedResult:    false,
+		},
+		{
+			note:              "order update hint with additional properties",
+			chainID:           constants.TestChainID,
+			customOrderSchema: DefaultCustomOrderSchema,
+			orderMessageJSON:  []byte(`{"messageType":"orderUpdateHint","orderHashes":["0xabababababababababababababababababababababababababababababababab"],"blockNumber":"12345","reason":"filled","topics":["/0x-orders/version/3/chain/1337/schema/e30="]}`),
+			expectedResult:    false,
+		},
 	}
 
 	for i, tc := range testCases {
 		tcInfo := fmt.Sprintf("test case %d\nchainID: %d\nschema: %s\nnote: %s", i, tc.chainID, tc.customOrderSchema, tc.note)
-		filter, err := New(tc.chainID, tc.customOrderSchema, contractAddresses)
+		filter, err := New(tc.chainID, tc.customOrderSchema, contractAddresses, ProtocolVersionV3)
 		require.NoError(t, err)
 		actualResult, err := filter.MatchOrderMessageJSON(tc.orderMessageJSON)
 		require.NoError(t, err, tc.customOrderSchema)
@@ -366,7 +445,7 @@ func TestFilterTopic(t *testing.T) {
 
 	for i, tc := range testCases {
 		tcInfo := fmt.Sprintf("test case %d\nchainID: %d\nschema: %s", i, tc.chainID, tc.customOrderSchema)
-		originalFilter, err := New(tc.chainID, tc.customOrderSchema, contractAddresses)
+		originalFilter, err := New(tc.chainID, tc.customOrderSchema, contractAddresses, ProtocolVersionV3)
 		require.NoError(t, err, tcInfo)
 		result, err := originalFilter.ValidateOrderJSON(tc.orderJSON)
 		require.NoError(t, err, tcInfo)
@@ -381,6 +460,39 @@ func TestFilterTopic(t *testing.T) {
 	}
 }
 
+func TestNewFromTopicCache(t *testing.T) {
+	defer SetTopicFilterCacheSize(DefaultTopicFilterCacheSize)
+	SetTopicFilterCacheSize(1)
+
+	topic := "/0x-orders/version/3/chain/1337/schema/eyJwcm9wZXJ0aWVzIjp7InNlbmRlckFkZHJlc3MiOnsicGF0dGVybiI6IjB4MDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDAwMDBiYTVlYmExMSIsInR5cGUiOiJzdHJpbmcifX19"
+	filter, err := NewFromTopic(topic, contractAddresses)
+	require.NoError(t, err)
+	cachedFilter, err := NewFromTopic(topic, contractAddresses)
+	require.NoError(t, err)
+	assert.True(t, filter == cachedFilter, "filter should have been cached")
+
+	// The contract addresses are part of the cache key.
+	otherContractAddresses := contractAddresses
+	otherContractAddresses.Exchange = common.HexToAddress("0x61935cbdd02287b511119ddb11aeb42f1593b7ef")
+	otherFilter, err := NewFromTopic(topic, otherContractAddresses)
+	require.NoError(t, err)
+	assert.False(t, filter == otherFilter, "filter for other contract addresses should not be shared")
+
+	// The cache only holds one filter, so the first one was evicted.
+	evictedFilter, err := NewFromTopic(topic, contractAddresses)
+	require.NoError(t, err)
+	assert.False(t, filter == evictedFilter, "filter should have been evicted")
+	assert.Equal(t, filter.Topic(), evictedFilter.Topic())
+
+	// Filters are not cached if the cache is disabled.
+	SetTopicFilterCacheSize(0)
+	filter, err = NewFromTopic(topic, contractAddresses)
+	require.NoError(t, err)
+	uncachedFilter, err := NewFromTopic(topic, contractAddresses)
+	require.NoError(t, err)
+	assert.False(t, filter == uncachedFilter, "filter should not have been cached")
+}
+
 func TestDefaultOrderSchemaTopic(t *testing.T) {
 	chainID := 1337
 	defaultTopic, err := GetDefaultTopic(chainID, contractAddresses)
@@ -388,3 +500,66 @@ func TestDefaultOrderSchemaTopic(t *testing.T) {
 	expectedTopic := "/0x-orders/version/3/chain/1337/schema/e30="
 	assert.Equal(t, expectedTopic, defaultTopic, "the topic for the default filter should not change")
 }
+
+func TestFilterHash(t *testing.T) {
+	defaultFilter, err := GetDefaultFilter(constants.TestChainID, contractAddresses)
+	require.NoError(t, err)
+	customFilter, err := New(constants.TestChainID, `{"properties":{"senderAddress":{"type":"string","pattern":"0x00000000000000000000000000000000ba5eba11"}}}`, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	reorderedCustomFilter, err := New(constants.TestChainID, `{"properties": {"senderAddress": {"pattern": "0x00000000000000000000000000000000ba5eba11", "type": "string"}}}`, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+
+	assert.Len(t, defaultFilter.Hash(), 64)
+	assert.NotEqual(t, defaultFilter.Hash(), customFilter.Hash(), "different schemas should have different hashes")
+	assert.Equal(t, customFilter.Hash(), reorderedCustomFilter.Hash(), "semantically equivalent schemas should have the same hash")
+}
+
+func TestNewWithExchangeAddresses(t *testing.T) {
+	t.Parallel()
+
+	otherExchangeAddress := common.HexToAddress("0x61935cbdd02287b511119ddb11aeb42f1593b7ef")
+	unknownExchangeAddress := common.HexToAddress("0x080bf510fcbf18b91105470639e9561022937712")
+	filter, err := NewWithExchangeAddresses(constants.TestChainID, DefaultCustomOrderSchema, []common.Address{
+		contractAddresses.Exchange,
+		otherExchangeAddress,
+		otherExchangeAddress,
+	}, ProtocolVersionV3)
+	require.NoError(t, err)
+	assert.Equal(t, []common.Address{contractAddresses.Exchange, otherExchangeAddress}, filter.ExchangeAddresses(), "duplicate exchange addresses should be removed")
+	assert.Equal(t, defaultFilterTopic(t), filter.Topic(), "exchange addresses should not affect the topic")
+
+	testCases := []struct {
+		exchangeAddress string
+		expectedValid   bool
+	}{
+		{zeroex.NormalizedAddressHex(contractAddresses.Exchange), true},
+		{zeroex.NormalizedAddressHex(otherExchangeAddress), true},
+		{otherExchangeAddress.Hex(), true},
+		{zeroex.NormalizedAddressHex(unknownExchangeAddress), false},
+	}
+	for _, tc := range testCases {
+		orderJSON := []byte(strings.Replace(string(standardValidOrderJSON), zeroex.NormalizedAddressHex(contractAddresses.Exchange), tc.exchangeAddress, 1))
+		result, err := filter.ValidateOrderJSON(orderJSON)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expectedValid, result.Valid(), tc.exchangeAddress)
+
+		var signedOrder zeroex.SignedOrder
+		require.NoError(t, signedOrder.UnmarshalJSON(orderJSON))
+		matches, _, err := filter.MatchOrder(&signedOrder)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expectedValid, matches, tc.exchangeAddress)
+
+		matches, err = filter.MatchOrderMessageJSON([]byte(fmt.Sprintf(`{"messageType":"order","order":%s,"topics":[%q]}`, orderJSON, filter.Topic())))
+		require.NoError(t, err)
+		assert.Equal(t, tc.expectedValid, matches, tc.exchangeAddress)
+	}
+
+	_, err = NewWithExchangeAddresses(constants.TestChainID, DefaultCustomOrderSchema, nil, ProtocolVersionV3)
+	assert.Equal(t, ErrNoExchangeAddresses, err)
+}
+
+func defaultFilterTopic(t *testing.T) string {
+	topic, err := GetDefaultTopic(constants.TestChainID, contractAddresses)
+	require.NoError(t, err)
+	return topic
+}
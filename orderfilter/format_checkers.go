@@ -0,0 +1,25 @@
+// +build !js
+
+package orderfilter
+
+import (
+	jsonschema "github.com/xeipuuv/gojsonschema"
+)
+
+// gojsonschemaFormatChecker adapts a FormatChecker to gojsonschema.
+type gojsonschemaFormatChecker FormatChecker
+
+// IsFormat implements jsonschema.FormatChecker.
+func (checker gojsonschemaFormatChecker) IsFormat(input interface{}) bool {
+	value, ok := input.(string)
+	if !ok {
+		return true
+	}
+	return checker(value)
+}
+
+// addFormatChecker makes the given format available to gojsonschema. Its
+// format checkers are global, so this affects every compiled schema.
+func addFormatChecker(name string, checker FormatChecker) {
+	jsonschema.FormatCheckers.Add(name, gojsonschemaFormatChecker(checker))
+}
@@ -0,0 +1,35 @@
+// +build js, wasm
+
+package orderfilter
+
+import (
+	"syscall/js"
+)
+
+// jsFormatCheckers are the registered formats as Javascript functions, which
+// are passed to createSchemaValidator and added to AJV. They are never
+// released, since formats cannot be unregistered.
+var jsFormatCheckers = map[string]interface{}{}
+
+// addFormatChecker makes the given format available to AJV. formatsMu must be
+// locked.
+func addFormatChecker(name string, checker FormatChecker) {
+	jsFormatCheckers[name] = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) == 0 || args[0].Type() != js.TypeString {
+			return true
+		}
+		return checker(args[0].String())
+	})
+}
+
+// getJSFormatCheckers returns the Javascript functions for the registered
+// formats by name.
+func getJSFormatCheckers() map[string]interface{} {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	checkers := make(map[string]interface{}, len(jsFormatCheckers))
+	for name, checker := range jsFormatCheckers {
+		checkers[name] = checker
+	}
+	return checkers
+}
@@ -0,0 +1,230 @@
+package orderfilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Names of the built-in formats for Ethereum types, which custom order schemas
+// can use with the "format" keyword (e.g. {"format":"ethereum-address"}).
+const (
+	// FormatEthereumAddress accepts 0x-prefixed hex encoded addresses,
+	// regardless of their case.
+	FormatEthereumAddress = "ethereum-address"
+	// FormatChecksummedAddress accepts addresses which are either all
+	// lowercase, all uppercase or have a valid EIP-55 checksum. It rejects
+	// mixed-case addresses with an invalid checksum (i.e. typos).
+	FormatChecksummedAddress = "checksummed-address"
+	// FormatBytes32Hex accepts 0x-prefixed hex encoded 32 byte values (e.g.
+	// order hashes).
+	FormatBytes32Hex = "bytes32-hex"
+	// FormatUint256String accepts base 10 encoded integers between 0 and
+	// 2^256-1.
+	FormatUint256String = "uint256-string"
+	// FormatAssetData accepts 0x-prefixed hex encoded assetData for one of the
+	// standard asset proxies (ERC20, ERC721, ERC1155, MultiAsset, StaticCall
+	// and ERC20Bridge).
+	FormatAssetData = "asset-data"
+)
+
+// standardFormats are the formats defined by JSON Schema which are supported
+// by both gojsonschema and AJV.
+var standardFormats = []string{
+	"date", "date-time", "email", "hostname", "ipv4", "ipv6", "json-pointer",
+	"regex", "relative-json-pointer", "time", "uri", "uri-reference",
+	"uri-template", "uuid",
+}
+
+var (
+	addressRegex = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+	bytes32Regex = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+	uintRegex    = regexp.MustCompile(`^\d{1,78}$`)
+	hexRegex     = regexp.MustCompile(`^0x([0-9a-fA-F]{2})*$`)
+	maxUint256   = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	// standardAssetProxyIDs are the IDs accepted by FormatAssetData. Custom
+	// asset proxies are not included, since whether they are registered
+	// differs between nodes.
+	standardAssetProxyIDs = map[string]struct{}{
+		zeroex.ERC20AssetDataID:       {},
+		zeroex.ERC721AssetDataID:      {},
+		zeroex.ERC1155AssetDataID:     {},
+		zeroex.MultiAssetDataID:       {},
+		zeroex.StaticCallAssetDataID:  {},
+		zeroex.ERC20BridgeAssetDataID: {},
+	}
+)
+
+// FormatChecker returns true if the given string has the format.
+type FormatChecker func(value string) bool
+
+// FormatAlreadyRegisteredError is returned by RegisterFormat if a format with
+// the same name is already supported.
+type FormatAlreadyRegisteredError struct {
+	Name string
+}
+
+func (e FormatAlreadyRegisteredError) Error() string {
+	return fmt.Sprintf("format %q is already registered", e.Name)
+}
+
+// UnsupportedFormatError is returned when creating a Filter whose custom order
+// schema uses a format which is neither a standard JSON Schema format nor
+// registered with RegisterFormat. Unknown formats would otherwise be ignored,
+// which means that the filter would accept orders that peers which support
+// the format reject.
+type UnsupportedFormatError struct {
+	Name string
+}
+
+func (e UnsupportedFormatError) Error() string {
+	return fmt.Sprintf("custom order schema uses unsupported format %q", e.Name)
+}
+
+var (
+	formatsMu sync.RWMutex
+	// formats are the registered formats by name.
+	formats = map[string]FormatChecker{}
+)
+
+func init() {
+	builtInFormats := map[string]FormatChecker{
+		FormatEthereumAddress:    isEthereumAddress,
+		FormatChecksummedAddress: isChecksummedAddress,
+		FormatBytes32Hex:         bytes32Regex.MatchString,
+		FormatUint256String:      isUint256String,
+		FormatAssetData:          isStandardAssetData,
+	}
+	for name, checker := range builtInFormats {
+		if err := RegisterFormat(name, checker); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterFormat adds a format which custom order schemas can use with the
+// "format" keyword. The format only applies to strings; values of other types
+// are always accepted. Formats must be registered before the filters which use
+// them are created, and every node which joins the topic of such a filter
+// must register the same format, since the topic only contains its name.
+func RegisterFormat(name string, checker FormatChecker) error {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	if _, found := formats[name]; found || isStandardFormat(name) {
+		return FormatAlreadyRegisteredError{Name: name}
+	}
+	formats[name] = checker
+	addFormatChecker(name, checker)
+	return nil
+}
+
+// Formats returns the names of the formats registered with RegisterFormat,
+// including the built-in ones, in alphabetical order.
+func Formats() []string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isStandardFormat(name string) bool {
+	for _, standardFormat := range standardFormats {
+		if name == standardFormat {
+			return true
+		}
+	}
+	return false
+}
+
+// isSupportedFormat returns true if the given format is a standard format or
+// was registered with RegisterFormat.
+func isSupportedFormat(name string) bool {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	_, found := formats[name]
+	return found || isStandardFormat(name)
+}
+
+// validateSchemaFormats returns an UnsupportedFormatError if the given custom
+// order schema uses a format which is not supported. It doesn't report
+// invalid JSON, which is left to the schema compiler.
+func validateSchemaFormats(customOrderSchema string) error {
+	var schema interface{}
+	if err := json.Unmarshal([]byte(customOrderSchema), &schema); err != nil {
+		return nil
+	}
+	return validateSchemaFormatsOf(schema)
+}
+
+func validateSchemaFormatsOf(schema interface{}) error {
+	switch schema := schema.(type) {
+	case map[string]interface{}:
+		for keyword, value := range schema {
+			switch keyword {
+			case "const", "enum", "default", "examples":
+				// These are data rather than schemas.
+				continue
+			case "format":
+				// A property called "format" has a schema rather than a name.
+				if name, ok := value.(string); ok {
+					if !isSupportedFormat(name) {
+						return UnsupportedFormatError{Name: name}
+					}
+					continue
+				}
+			}
+			if err := validateSchemaFormatsOf(value); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, value := range schema {
+			if err := validateSchemaFormatsOf(value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isEthereumAddress(value string) bool {
+	return addressRegex.MatchString(value)
+}
+
+func isChecksummedAddress(value string) bool {
+	if !addressRegex.MatchString(value) {
+		return false
+	}
+	hex := value[2:]
+	if hex == strings.ToLower(hex) || hex == strings.ToUpper(hex) {
+		return true
+	}
+	return common.HexToAddress(value).Hex() == value
+}
+
+func isUint256String(value string) bool {
+	if !uintRegex.MatchString(value) {
+		return false
+	}
+	number, ok := new(big.Int).SetString(value, 10)
+	return ok && number.Cmp(maxUint256) <= 0
+}
+
+func isStandardAssetData(value string) bool {
+	if !hexRegex.MatchString(value) || len(value) < len("0x")+8 {
+		return false
+	}
+	_, found := standardAssetProxyIDs[strings.ToLower(value[2:10])]
+	return found
+}
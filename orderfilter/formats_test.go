@@ -0,0 +1,101 @@
+package orderfilter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatCheckers(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		format        string
+		value         string
+		expectedValid bool
+	}{
+		{FormatEthereumAddress, "0xa3ece5d5b6319fa785efc10d3112769a46c6e149", true},
+		{FormatEthereumAddress, "0xA3ECE5D5B6319FA785EFC10D3112769A46C6E149", true},
+		{FormatEthereumAddress, "a3ece5d5b6319fa785efc10d3112769a46c6e149", false},
+		{FormatEthereumAddress, "0xa3ece5d5b6319fa785efc10d3112769a46c6e1", false},
+		{FormatChecksummedAddress, common.HexToAddress("0xa3ece5d5b6319fa785efc10d3112769a46c6e149").Hex(), true},
+		{FormatChecksummedAddress, "0xa3ece5d5b6319fa785efc10d3112769a46c6e149", true},
+		{FormatChecksummedAddress, "0xA3ECE5D5B6319FA785EFC10D3112769A46C6E149", true},
+		{FormatChecksummedAddress, "0xA3ece5d5b6319fa785efc10d3112769a46c6e149", false},
+		{FormatBytes32Hex, "0x" + strings.Repeat("ab", 32), true},
+		{FormatBytes32Hex, "0x" + strings.Repeat("ab", 31), false},
+		{FormatUint256String, "0", true},
+		{FormatUint256String, maxUint256.String(), true},
+		{FormatUint256String, "115792089237316195423570985008687907853269984665640564039457584007913129639936", false},
+		{FormatUint256String, "-1", false},
+		{FormatUint256String, "1.5", false},
+		{FormatAssetData, "0xf47261b0000000000000000000000000871dd7c2b4b25e1aa18728e9d5f2af4c4e431f5c", true},
+		{FormatAssetData, "0xF47261B0", true},
+		{FormatAssetData, "0x12345678", false},
+		{FormatAssetData, "0xf47261", false},
+		{FormatAssetData, "0xf47261b00", false},
+	}
+	for _, tc := range testCases {
+		tcInfo := fmt.Sprintf("format: %s, value: %s", tc.format, tc.value)
+		formatsMu.RLock()
+		checker := formats[tc.format]
+		formatsMu.RUnlock()
+		require.NotNil(t, checker, tcInfo)
+		assert.Equal(t, tc.expectedValid, checker(tc.value), tcInfo)
+	}
+}
+
+func TestFormatsInCustomOrderSchema(t *testing.T) {
+	t.Parallel()
+
+	filter, err := New(constants.TestChainID, `{"properties":{"makerAddress":{"format":"checksummed-address"},"salt":{"format":"uint256-string"},"makerAssetData":{"format":"asset-data"},"takerAssetData":{"format":"bytes32-hex"}}}`, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	result, err := filter.ValidateOrderJSON(standardValidOrderJSON)
+	require.NoError(t, err)
+	assert.False(t, result.Valid())
+	fieldErrors := FieldErrors(standardValidOrderJSON, result)
+	fieldError := findFieldError(fieldErrors, "/takerAssetData")
+	require.NotNil(t, fieldError, "missing field error for /takerAssetData")
+	assert.Equal(t, "format", fieldError.Constraint)
+	assert.Nil(t, findFieldError(fieldErrors, "/makerAddress"))
+	assert.Nil(t, findFieldError(fieldErrors, "/salt"))
+	assert.Nil(t, findFieldError(fieldErrors, "/makerAssetData"))
+
+	// Formats are part of the canonical schema and therefore of the topic.
+	topicFilter, err := NewFromTopic(filter.Topic(), contractAddresses)
+	require.NoError(t, err)
+	assert.True(t, filter.Equals(topicFilter))
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(constants.TestChainID, `{"properties":{"makerAddress":{"format":"not-a-format"}}}`, contractAddresses, ProtocolVersionV3)
+	assert.Equal(t, UnsupportedFormatError{Name: "not-a-format"}, err)
+
+	// Standard formats and properties called "format" are fine.
+	_, err = New(constants.TestChainID, `{"properties":{"format":{"type":"string"},"makerAddress":{"format":"hostname"}}}`, contractAddresses, ProtocolVersionV3)
+	assert.NoError(t, err)
+}
+
+func TestRegisterFormat(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, RegisterFormat("test-null-address", func(value string) bool {
+		return value == "0x0000000000000000000000000000000000000000"
+	}))
+	assert.Contains(t, Formats(), "test-null-address")
+	assert.Equal(t, FormatAlreadyRegisteredError{Name: "test-null-address"}, RegisterFormat("test-null-address", isEthereumAddress))
+	assert.Equal(t, FormatAlreadyRegisteredError{Name: "email"}, RegisterFormat("email", isEthereumAddress))
+
+	filter, err := New(constants.TestChainID, `{"properties":{"takerAddress":{"format":"test-null-address"}}}`, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	result, err := filter.ValidateOrderJSON(standardValidOrderJSON)
+	require.NoError(t, err)
+	assert.True(t, result.Valid())
+}
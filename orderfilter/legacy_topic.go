@@ -0,0 +1,132 @@
+package orderfilter
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// legacyTopicVersions are the previous topic versions which NewFromTopic can
+// parse. They use the same format as the current version.
+var legacyTopicVersions = []int{2}
+
+// LegacyTopicPolicy determines how NewFromTopic handles topics of a previous
+// topic version (see SetLegacyTopicPolicy).
+type LegacyTopicPolicy int
+
+const (
+	// LegacyTopicsRejected causes NewFromTopic to return a
+	// WrongTopicVersionError for legacy topics.
+	LegacyTopicsRejected LegacyTopicPolicy = iota
+	// LegacyTopicsAccepted causes NewFromTopic to return a filter which keeps
+	// the legacy topic version, i.e. its Topic is the given legacy topic.
+	LegacyTopicsAccepted
+	// LegacyTopicsTranslated causes NewFromTopic to return a filter for the
+	// current topic version, i.e. its Topic is the current topic with the same
+	// chain ID and custom order schema as the given legacy topic.
+	LegacyTopicsTranslated
+)
+
+var legacyTopicPolicyNames = map[LegacyTopicPolicy]string{
+	LegacyTopicsRejected:   "reject",
+	LegacyTopicsAccepted:   "accept",
+	LegacyTopicsTranslated: "translate",
+}
+
+func (p LegacyTopicPolicy) String() string {
+	if name, found := legacyTopicPolicyNames[p]; found {
+		return name
+	}
+	return fmt.Sprintf("LegacyTopicPolicy(%d)", int(p))
+}
+
+// ParseLegacyTopicPolicy parses a LegacyTopicPolicy from its name, which is
+// one of "reject", "accept" or "translate".
+func ParseLegacyTopicPolicy(name string) (LegacyTopicPolicy, error) {
+	for policy, policyName := range legacyTopicPolicyNames {
+		if strings.EqualFold(strings.TrimSpace(name), policyName) {
+			return policy, nil
+		}
+	}
+	return LegacyTopicsRejected, fmt.Errorf("unknown legacy topic policy: %q (expected \"reject\", \"accept\" or \"translate\")", name)
+}
+
+var (
+	legacyTopicPolicyMu sync.RWMutex
+	legacyTopicPolicy   = LegacyTopicsRejected
+)
+
+// SetLegacyTopicPolicy sets how NewFromTopic handles topics of a previous
+// topic version. By default, they are rejected.
+func SetLegacyTopicPolicy(policy LegacyTopicPolicy) {
+	legacyTopicPolicyMu.Lock()
+	defer legacyTopicPolicyMu.Unlock()
+	legacyTopicPolicy = policy
+}
+
+// GetLegacyTopicPolicy returns the policy set with SetLegacyTopicPolicy.
+func GetLegacyTopicPolicy() LegacyTopicPolicy {
+	legacyTopicPolicyMu.RLock()
+	defer legacyTopicPolicyMu.RUnlock()
+	return legacyTopicPolicy
+}
+
+// isLegacyTopicVersion returns true if NewFromTopic can parse topics of the
+// given previous topic version.
+func isLegacyTopicVersion(version int) bool {
+	for _, legacyVersion := range legacyTopicVersions {
+		if version == legacyVersion {
+			return true
+		}
+	}
+	return false
+}
+
+// topicVersionForPolicy returns the topic version of a filter created by
+// NewFromTopic from a topic with the given version, or a
+// WrongTopicVersionError if the topic is not accepted under the given policy.
+func topicVersionForPolicy(version int, policy LegacyTopicPolicy) (int, error) {
+	if version == pubsubTopicVersion {
+		return pubsubTopicVersion, nil
+	}
+	if isLegacyTopicVersion(version) {
+		switch policy {
+		case LegacyTopicsAccepted:
+			return version, nil
+		case LegacyTopicsTranslated:
+			return pubsubTopicVersion, nil
+		}
+	}
+	return 0, WrongTopicVersionError{
+		expectedVersion: pubsubTopicVersion,
+		actualVersion:   version,
+	}
+}
+
+// IsLegacy returns true if the topic of the filter uses a previous topic
+// version, which is only the case for filters created by NewFromTopic with
+// LegacyTopicsAccepted.
+func (f *Filter) IsLegacy() bool {
+	return f.topicVersion != 0 && f.topicVersion != pubsubTopicVersion
+}
+
+// LegacyTopics returns the topics which nodes running a previous version of
+// Mesh use for the filter, newest first. Publishing orders to these topics
+// as well bridges them to such nodes during a rolling upgrade of the network.
+func (f *Filter) LegacyTopics() []string {
+	if f.encodedSchema == "" {
+		f.encodedSchema = f.generateEncodedSchema()
+	}
+	topics := []string{}
+	if f.protocolVersion != ProtocolVersionV3 {
+		// There are no legacy topics for v4 orders.
+		return topics
+	}
+	for _, version := range legacyTopicVersions {
+		if version == f.topicVersion {
+			continue
+		}
+		topics = append(topics, fmt.Sprintf(fullTopicFormat, version, f.chainID, f.encodedSchema))
+	}
+	return topics
+}
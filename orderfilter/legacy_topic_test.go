@@ -0,0 +1,57 @@
+package orderfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFromLegacyTopic(t *testing.T) {
+	defer SetLegacyTopicPolicy(LegacyTopicsRejected)
+
+	legacyTopic := "/0x-orders/version/2/chain/1337/schema/e30="
+	currentTopic := "/0x-orders/version/3/chain/1337/schema/e30="
+
+	// Legacy topics are rejected by default.
+	_, err := NewFromTopic(legacyTopic, contractAddresses)
+	assert.Equal(t, WrongTopicVersionError{expectedVersion: 3, actualVersion: 2}, err)
+
+	SetLegacyTopicPolicy(LegacyTopicsAccepted)
+	acceptedFilter, err := NewFromTopic(legacyTopic, contractAddresses)
+	require.NoError(t, err)
+	assert.True(t, acceptedFilter.IsLegacy())
+	assert.Equal(t, legacyTopic, acceptedFilter.Topic())
+	assert.Empty(t, acceptedFilter.LegacyTopics())
+	result, err := acceptedFilter.ValidateOrderJSON(standardValidOrderJSON)
+	require.NoError(t, err)
+	assert.True(t, result.Valid())
+
+	SetLegacyTopicPolicy(LegacyTopicsTranslated)
+	translatedFilter, err := NewFromTopic(legacyTopic, contractAddresses)
+	require.NoError(t, err)
+	assert.False(t, translatedFilter == acceptedFilter, "filters for different policies should not be shared")
+	assert.False(t, translatedFilter.IsLegacy())
+	assert.Equal(t, currentTopic, translatedFilter.Topic())
+	assert.Equal(t, []string{legacyTopic}, translatedFilter.LegacyTopics())
+	assert.True(t, acceptedFilter.Equals(translatedFilter))
+
+	// Unknown versions are rejected regardless of the policy.
+	_, err = NewFromTopic("/0x-orders/version/1/chain/1337/schema/e30=", contractAddresses)
+	assert.Equal(t, WrongTopicVersionError{expectedVersion: 3, actualVersion: 1}, err)
+}
+
+func TestParseLegacyTopicPolicy(t *testing.T) {
+	t.Parallel()
+
+	for _, policy := range []LegacyTopicPolicy{LegacyTopicsRejected, LegacyTopicsAccepted, LegacyTopicsTranslated} {
+		parsed, err := ParseLegacyTopicPolicy(policy.String())
+		require.NoError(t, err)
+		assert.Equal(t, policy, parsed)
+	}
+	parsed, err := ParseLegacyTopicPolicy(" Translate ")
+	require.NoError(t, err)
+	assert.Equal(t, LegacyTopicsTranslated, parsed)
+	_, err = ParseLegacyTopicPolicy("bridge")
+	assert.Error(t, err)
+}
@@ -0,0 +1,124 @@
+package orderfilter
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/0xProject/0x-mesh/zeroex"
+)
+
+// errNilOrder is returned by MatchOrder if the order is nil.
+var errNilOrder = errors.New("signed order must not be nil")
+
+// MatchOrder returns true if the order passes the filter. Otherwise, it
+// returns the reasons why the order was rejected, in the same format as the
+// reasons counted in Metrics (e.g. "schema:makerAssetData" or
+// RejectionReasonMaxExpiration). It only returns an error if there was a
+// problem with validation. For details about orders that do not pass the
+// filter, use ValidateOrder. Since order is a v3 order, it never passes
+// filters for v4 orders.
+//
+// Unlike ValidateOrder, MatchOrder checks the fields of the order directly
+// instead of validating its JSON encoding against the order schema. The order
+// is only encoded if the filter has a custom order schema which doesn't accept
+// all orders.
+func (f *Filter) MatchOrder(order *zeroex.SignedOrder) (bool, []string, error) {
+	rejectReasons, err := f.orderRejectReasons(order)
+	if err != nil {
+		f.metrics.recordOrder(false, "", err)
+		return false, nil, err
+	}
+	if len(rejectReasons) > 0 {
+		f.metrics.recordOrder(false, rejectReasons[0], nil)
+		return false, rejectReasons, nil
+	}
+	f.metrics.recordOrder(true, "", nil)
+	return true, nil, nil
+}
+
+// MatchesOrder returns true if the order passes the filter. Unlike MatchOrder,
+// the check is not counted in Metrics, so it can be used for orders which were
+// already accepted (e.g. to tell which of several filters an order passes).
+// Orders which cannot be checked don't pass the filter.
+func (f *Filter) MatchesOrder(order *zeroex.SignedOrder) bool {
+	rejectReasons, err := f.orderRejectReasons(order)
+	return err == nil && len(rejectReasons) == 0
+}
+
+// orderRejectReasons returns the reasons why the order doesn't pass the
+// filter or nil if it does.
+func (f *Filter) orderRejectReasons(order *zeroex.SignedOrder) ([]string, error) {
+	if order == nil {
+		return nil, errNilOrder
+	}
+	if f.protocolVersion != ProtocolVersionV3 {
+		return []string{RejectionReasonProtocolVersion}, nil
+	}
+	now := time.Now()
+	var rejectReasons []string
+	if f.composite != nil {
+		var err error
+		rejectReasons, err = f.compositeOrderRejectReasons(order)
+		if err != nil {
+			return nil, err
+		}
+	} else if rejectReasons = f.builtInRejectReasons(order); len(rejectReasons) == 0 {
+		if f.constraints != nil {
+			// Fast path: the constraints can be checked without encoding the
+			// order.
+			rejectReasons = f.constraints.rejectReasons(order, now)
+		} else {
+			var err error
+			rejectReasons, err = f.customOrderSchemaRejectReasons(order)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if f.exceedsMaxExpiration(order.ExpirationTimeSeconds, now) {
+		rejectReasons = appendRejectReason(rejectReasons, RejectionReasonMaxExpiration)
+	}
+	return rejectReasons, nil
+}
+
+// builtInRejectReasons checks the order against the built-in /signedOrder
+// schema. Go orders always have the structure required by it, so only the
+// fields with fixed values and the numbers need to be checked.
+func (f *Filter) builtInRejectReasons(order *zeroex.SignedOrder) []string {
+	var rejectReasons []string
+	if order.ChainID == nil || order.ChainID.Cmp(big.NewInt(int64(f.chainID))) != 0 {
+		rejectReasons = append(rejectReasons, RejectionReasonSchema+":chainId")
+	}
+	if !f.acceptsExchangeAddress(order.ExchangeAddress) {
+		rejectReasons = append(rejectReasons, RejectionReasonSchema+":exchangeAddress")
+	}
+	numbers := []struct {
+		field string
+		value *big.Int
+	}{
+		{"makerAssetAmount", order.MakerAssetAmount},
+		{"makerFee", order.MakerFee},
+		{"takerAssetAmount", order.TakerAssetAmount},
+		{"takerFee", order.TakerFee},
+		{"expirationTimeSeconds", order.ExpirationTimeSeconds},
+		{"salt", order.Salt},
+	}
+	for _, number := range numbers {
+		if number.value == nil || number.value.Sign() < 0 || number.value.Cmp(maxUint256) > 0 {
+			rejectReasons = append(rejectReasons, RejectionReasonSchema+":"+number.field)
+		}
+	}
+	return rejectReasons
+}
+
+// appendRejectReason appends the given reason unless it is already one of
+// rejectReasons.
+func appendRejectReason(rejectReasons []string, reason string) []string {
+	for _, existing := range rejectReasons {
+		if existing == reason {
+			return rejectReasons
+		}
+	}
+	return append(rejectReasons, reason)
+}
@@ -0,0 +1,69 @@
+// +build !js
+
+package orderfilter
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchOrderRejectReasons(t *testing.T) {
+	t.Parallel()
+
+	order := &zeroex.SignedOrder{}
+	require.NoError(t, order.UnmarshalJSON(standardValidOrderJSON))
+	specificSenderOrder := &zeroex.SignedOrder{}
+	require.NoError(t, specificSenderOrder.UnmarshalJSON(orderWithSpecificSenderAddressJSON))
+	otherChainOrder := &zeroex.SignedOrder{}
+	require.NoError(t, otherChainOrder.UnmarshalJSON(standardValidOrderJSON))
+	otherChainOrder.ChainID = big.NewInt(42)
+	takerFeeOrder := &zeroex.SignedOrder{}
+	require.NoError(t, takerFeeOrder.UnmarshalJSON(standardValidOrderJSON))
+	takerFeeOrder.TakerFee = big.NewInt(1)
+
+	defaultFilter, err := New(constants.TestChainID, DefaultCustomOrderSchema, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	specificSender, err := New(constants.TestChainID, `{"properties":{"senderAddress":{"const":"0x00000000000000000000000000000000ba5eba11"}}}`, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	noTakerFeesConstraints, err := ParseConstraints("takerFee == 0; expiresWithin 1h")
+	require.NoError(t, err)
+	noTakerFees, err := NewFromConstraints(constants.TestChainID, noTakerFeesConstraints, contractAddresses)
+	require.NoError(t, err)
+	notSpecificSender, err := Not(specificSender)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		note                  string
+		filter                *Filter
+		order                 *zeroex.SignedOrder
+		expectedRejectReasons []string
+	}{
+		{"default filter", defaultFilter, order, nil},
+		{"other chain", defaultFilter, otherChainOrder, []string{"schema:chainId"}},
+		{"custom order schema", specificSender, order, []string{"schema:senderAddress"}},
+		{"custom order schema with a specific sender", specificSender, specificSenderOrder, nil},
+		{"constraints", noTakerFees, takerFeeOrder, []string{"schema:takerFee", RejectionReasonMaxExpiration}},
+		{"not", notSpecificSender, specificSenderOrder, []string{RejectionReasonNot}},
+		{"not for another chain", notSpecificSender, otherChainOrder, []string{"schema:chainId"}},
+	}
+	for _, tc := range testCases {
+		matches, rejectReasons, err := tc.filter.MatchOrder(tc.order)
+		require.NoError(t, err, tc.note)
+		assert.Equal(t, len(tc.expectedRejectReasons) == 0, matches, tc.note)
+		assert.Equal(t, tc.expectedRejectReasons, rejectReasons, tc.note)
+	}
+
+	_, _, err = defaultFilter.MatchOrder(nil)
+	assert.Equal(t, errNilOrder, err)
+
+	assert.Equal(t, CheckMetrics{
+		NumAccepted: 1,
+		NumRejected: map[string]int64{"schema:chainId": 1},
+		NumErrors:   1,
+	}, defaultFilter.Metrics().Orders)
+}
@@ -0,0 +1,119 @@
+package orderfilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// MaxExpirationConstraint is the Constraint of the FieldError which describes
+// that an order expires too far in the future (see WithMaxExpirationDuration).
+const MaxExpirationConstraint = dslOpExpiresWithin
+
+// WithMaxExpirationDuration returns a copy of the filter which also rejects
+// orders that expire more than the given duration in the future. A duration of
+// 0 removes the limit. Since JSON Schema cannot express constraints which
+// depend on the current time, the limit is not part of the custom order schema
+// and doesn't change the topic. Instead, it is enforced by MatchOrder,
+// ValidateOrder, ValidateOrderJSON and MatchOrderMessageJSON, so that orders
+// which the node would never store are not propagated to its peers.
+func (f *Filter) WithMaxExpirationDuration(maxExpirationDuration time.Duration) *Filter {
+	filter := *f
+	filter.maxExpirationDuration = maxExpirationDuration
+	filter.metrics = newFilterMetrics()
+	return &filter
+}
+
+// MaxExpirationDuration returns the maximum duration until orders which pass
+// the filter expire or 0 if there is no limit.
+func (f *Filter) MaxExpirationDuration() time.Duration {
+	return f.maxExpirationDuration
+}
+
+// maxExpirationTime returns the latest expiration time of orders which pass the
+// filter at the given time or nil if there is no limit.
+func (f *Filter) maxExpirationTime(now time.Time) *big.Int {
+	if f.maxExpirationDuration == 0 {
+		return nil
+	}
+	return big.NewInt(now.Add(f.maxExpirationDuration).Unix())
+}
+
+// exceedsMaxExpiration returns true if an order with the given expiration time
+// expires later than the filter allows at the given time. Missing expiration
+// times are rejected by the order schema instead.
+func (f *Filter) exceedsMaxExpiration(expirationTimeSeconds *big.Int, now time.Time) bool {
+	maxExpirationTime := f.maxExpirationTime(now)
+	return maxExpirationTime != nil && expirationTimeSeconds != nil && expirationTimeSeconds.Cmp(maxExpirationTime) == 1
+}
+
+// maxExpirationMessage returns the description of the violation of the max
+// expiration duration of the filter.
+func (f *Filter) maxExpirationMessage() string {
+	return fmt.Sprintf("order expires more than %s in the future", f.maxExpirationDuration)
+}
+
+// expirationTimeFromOrderJSON returns the expiration time of the given JSON
+// encoded order or nil if it is missing or malformed, in which case the order
+// doesn't pass the order schema anyway. The field which holds the expiration
+// time depends on the protocol version of the filter.
+func (f *Filter) expirationTimeFromOrderJSON(orderJSON []byte) *big.Int {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(orderJSON, &fields); err != nil {
+		return nil
+	}
+	var expirationTime string
+	if err := json.Unmarshal(fields[expirationTimeField(f.protocolVersion)], &expirationTime); err != nil {
+		return nil
+	}
+	expirationTimeSeconds, ok := new(big.Int).SetString(expirationTime, 10)
+	if !ok {
+		return nil
+	}
+	return expirationTimeSeconds
+}
+
+// orderMessageExceedsMaxExpiration returns true if the given JSON encoded
+// message is an order message whose order expires later than the filter allows
+// at the given time.
+func (f *Filter) orderMessageExceedsMaxExpiration(messageJSON []byte, now time.Time) bool {
+	if f.maxExpirationDuration == 0 {
+		return false
+	}
+	var message struct {
+		MessageType string          `json:"messageType"`
+		Order       json.RawMessage `json:"order"`
+	}
+	if err := json.Unmarshal(messageJSON, &message); err != nil || message.MessageType != "order" {
+		return false
+	}
+	return f.exceedsMaxExpiration(f.expirationTimeFromOrderJSON(message.Order), now)
+}
+
+// compositeMaxExpirationDuration returns the max expiration duration of a
+// filter created with And, Or or Not from the given filters, so that it is
+// enforced when validating JSON encoded orders and messages as well.
+func compositeMaxExpirationDuration(operator string, filters []*Filter) time.Duration {
+	var maxExpirationDuration time.Duration
+	switch operator {
+	case compositeAnd:
+		// The shortest limit of any of the filters.
+		for _, filter := range filters {
+			if filter.maxExpirationDuration != 0 && (maxExpirationDuration == 0 || filter.maxExpirationDuration < maxExpirationDuration) {
+				maxExpirationDuration = filter.maxExpirationDuration
+			}
+		}
+	case compositeOr:
+		// The longest limit, but only if all of the filters have one.
+		for _, filter := range filters {
+			if filter.maxExpirationDuration == 0 {
+				return 0
+			}
+			if filter.maxExpirationDuration > maxExpirationDuration {
+				maxExpirationDuration = filter.maxExpirationDuration
+			}
+		}
+	}
+	return maxExpirationDuration
+}
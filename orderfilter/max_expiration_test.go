@@ -0,0 +1,111 @@
+package orderfilter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// orderJSONExpiringAt returns standardValidOrderJSON with the given expiration
+// time.
+func orderJSONExpiringAt(expirationTime time.Time) []byte {
+	return []byte(strings.Replace(
+		string(standardValidOrderJSON),
+		`"expirationTimeSeconds":"1559856615025"`,
+		fmt.Sprintf(`"expirationTimeSeconds":"%d"`, expirationTime.Unix()),
+		1,
+	))
+}
+
+func TestWithMaxExpirationDuration(t *testing.T) {
+	t.Parallel()
+
+	defaultFilter, err := New(constants.TestChainID, DefaultCustomOrderSchema, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	filter := defaultFilter.WithMaxExpirationDuration(time.Hour)
+	assert.Equal(t, time.Hour, filter.MaxExpirationDuration())
+	// The original filter and the topic are unchanged.
+	assert.Equal(t, time.Duration(0), defaultFilter.MaxExpirationDuration())
+	assert.Equal(t, defaultFilter.Topic(), filter.Topic())
+
+	testCases := []struct {
+		note          string
+		orderJSON     []byte
+		expectedValid bool
+	}{
+		{"expires soon enough", orderJSONExpiringAt(time.Now().Add(30 * time.Minute)), true},
+		{"expires too late", orderJSONExpiringAt(time.Now().Add(2 * time.Hour)), false},
+	}
+	for _, tc := range testCases {
+		result, err := filter.ValidateOrderJSON(tc.orderJSON)
+		require.NoError(t, err, tc.note)
+		assert.Equal(t, tc.expectedValid, result.Valid(), tc.note)
+
+		order := &zeroex.SignedOrder{}
+		require.NoError(t, order.UnmarshalJSON(tc.orderJSON), tc.note)
+		matches, _, err := filter.MatchOrder(order)
+		require.NoError(t, err, tc.note)
+		assert.Equal(t, tc.expectedValid, matches, tc.note)
+
+		messageJSON := []byte(fmt.Sprintf(`{"messageType":"order","order":%s,"topics":[%q]}`, tc.orderJSON, filter.Topic()))
+		matches, err = filter.MatchOrderMessageJSON(messageJSON)
+		require.NoError(t, err, tc.note)
+		assert.Equal(t, tc.expectedValid, matches, tc.note)
+
+		// The default filter doesn't limit the expiration time.
+		matches, err = defaultFilter.MatchOrderMessageJSON(messageJSON)
+		require.NoError(t, err, tc.note)
+		assert.True(t, matches, tc.note)
+	}
+}
+
+func TestMaxExpirationFieldError(t *testing.T) {
+	t.Parallel()
+
+	filter, err := New(constants.TestChainID, DefaultCustomOrderSchema, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	filter = filter.WithMaxExpirationDuration(time.Hour)
+	expirationTime := time.Now().Add(2 * time.Hour)
+	orderJSON := orderJSONExpiringAt(expirationTime)
+	result, err := filter.ValidateOrderJSON(orderJSON)
+	require.NoError(t, err)
+	fieldErrors := FieldErrors(orderJSON, result)
+	require.Len(t, fieldErrors, 1)
+	assert.Equal(t, "/expirationTimeSeconds", fieldErrors[0].Pointer)
+	assert.Equal(t, MaxExpirationConstraint, fieldErrors[0].Constraint)
+	assert.Equal(t, fmt.Sprint(expirationTime.Unix()), fieldErrors[0].Actual)
+	assert.False(t, fieldErrors[0].ViolatesBuiltInSchema())
+}
+
+func TestMaxExpirationDurationOfDerivedFilters(t *testing.T) {
+	t.Parallel()
+
+	constraints, err := ParseConstraints("takerFee == 0; expiresWithin 1h")
+	require.NoError(t, err)
+	expiresWithinHour, err := NewFromConstraints(constants.TestChainID, constraints, contractAddresses)
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, expiresWithinHour.MaxExpirationDuration())
+
+	defaultFilter, err := New(constants.TestChainID, DefaultCustomOrderSchema, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	expiresWithinDay := defaultFilter.WithMaxExpirationDuration(24 * time.Hour)
+
+	and, err := And(expiresWithinHour, expiresWithinDay)
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, and.MaxExpirationDuration())
+	or, err := Or(expiresWithinHour, expiresWithinDay)
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, or.MaxExpirationDuration())
+	orUnlimited, err := Or(expiresWithinHour, defaultFilter)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), orUnlimited.MaxExpirationDuration())
+	not, err := Not(expiresWithinHour)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), not.MaxExpirationDuration())
+}
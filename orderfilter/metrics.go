@@ -0,0 +1,178 @@
+package orderfilter
+
+import (
+	"strings"
+	"sync"
+)
+
+// Reasons why a filter rejected a message or an order (see Metrics).
+const (
+	// RejectionReasonSchema is the reason for messages which don't match the
+	// message schema. Orders which don't match the order schema are counted
+	// with the top-level field of the first violation instead, e.g.
+	// "schema:makerAssetData", or just "schema" if the order itself is
+	// invalid.
+	RejectionReasonSchema = "schema"
+	// RejectionReasonMaxExpiration is the reason for messages and orders which
+	// match the schema but expire later than the filter allows.
+	RejectionReasonMaxExpiration = "maxExpiration"
+	// RejectionReasonProtocolVersion is the reason for orders passed to
+	// MatchOrder, which are always v3 orders, if the filter is for v4 orders.
+	RejectionReasonProtocolVersion = "protocolVersion"
+	// RejectionReasonNot is the reason for orders which are rejected by a
+	// filter created with Not because they pass the negated filter.
+	RejectionReasonNot = "not"
+	// RejectionReasonOther replaces any new reasons once maxRejectionReasons
+	// different reasons were counted. The fields of rejected orders are chosen
+	// by whoever sent them, so the number of reasons has to be bounded.
+	RejectionReasonOther = "other"
+)
+
+// maxRejectionReasons is the maximum number of different reasons which are
+// counted separately for each kind of check.
+const maxRejectionReasons = 64
+
+// CheckMetrics counts the outcomes of one kind of check of a filter.
+type CheckMetrics struct {
+	NumAccepted int64
+	// NumRejected is the number of rejections by reason (e.g.
+	// RejectionReasonMaxExpiration).
+	NumRejected map[string]int64
+	// NumErrors is the number of checks which returned an error, e.g. because
+	// the input was not valid JSON.
+	NumErrors int64
+}
+
+// Metrics describes how many messages and orders a filter accepted and
+// rejected since it was created.
+type Metrics struct {
+	// Messages are the checks of messages received from peers with
+	// MatchOrderMessageJSON.
+	Messages CheckMetrics
+	// Orders are the checks of orders with ValidateOrderJSON and MatchOrder
+	// (e.g. orders added via the API or synced with peers).
+	Orders CheckMetrics
+}
+
+// checkCounters are the counters behind CheckMetrics.
+type checkCounters struct {
+	numAccepted int64
+	numRejected map[string]int64
+	numErrors   int64
+}
+
+func (c *checkCounters) record(valid bool, reason string, err error) {
+	switch {
+	case err != nil:
+		c.numErrors++
+	case valid:
+		c.numAccepted++
+	default:
+		if _, found := c.numRejected[reason]; !found && len(c.numRejected) >= maxRejectionReasons {
+			reason = RejectionReasonOther
+		}
+		c.numRejected[reason]++
+	}
+}
+
+func (c *checkCounters) checkMetrics() CheckMetrics {
+	numRejected := make(map[string]int64, len(c.numRejected))
+	for reason, count := range c.numRejected {
+		numRejected[reason] = count
+	}
+	return CheckMetrics{
+		NumAccepted: c.numAccepted,
+		NumRejected: numRejected,
+		NumErrors:   c.numErrors,
+	}
+}
+
+// filterMetrics counts the outcomes of the checks of a filter. A nil
+// *filterMetrics ignores all outcomes.
+type filterMetrics struct {
+	mu       sync.Mutex
+	messages checkCounters
+	orders   checkCounters
+}
+
+func newFilterMetrics() *filterMetrics {
+	return &filterMetrics{
+		messages: checkCounters{numRejected: map[string]int64{}},
+		orders:   checkCounters{numRejected: map[string]int64{}},
+	}
+}
+
+// recordMessage counts the outcome of a check of a message. The check had an
+// error if err is not nil and rejected the message with the given reason if
+// valid is false.
+func (m *filterMetrics) recordMessage(valid bool, reason string, err error) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages.record(valid, reason, err)
+}
+
+// recordOrder is like recordMessage, but for a check of an order.
+func (m *filterMetrics) recordOrder(valid bool, reason string, err error) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.orders.record(valid, reason, err)
+}
+
+// Metrics returns how many messages and orders the filter accepted and
+// rejected. Filters created with WithMaxExpirationDuration, And, Or or Not
+// have their own metrics.
+func (f *Filter) Metrics() Metrics {
+	if f.metrics == nil {
+		return Metrics{
+			Messages: CheckMetrics{NumRejected: map[string]int64{}},
+			Orders:   CheckMetrics{NumRejected: map[string]int64{}},
+		}
+	}
+	f.metrics.mu.Lock()
+	defer f.metrics.mu.Unlock()
+	return Metrics{
+		Messages: f.metrics.messages.checkMetrics(),
+		Orders:   f.metrics.orders.checkMetrics(),
+	}
+}
+
+// rejectionReasonForFieldErrors returns the reason for rejecting an order with
+// the given field errors. Violations of the schema take precedence over the
+// max expiration duration, since the order would be rejected for them anyway.
+func rejectionReasonForFieldErrors(fieldErrors []*FieldError) string {
+	return rejectReasonsForFieldErrors(fieldErrors)[0]
+}
+
+// rejectReasonsForFieldErrors returns all reasons for rejecting an order with
+// the given field errors, starting with the violations of the schema. Each
+// field is only included once.
+func rejectReasonsForFieldErrors(fieldErrors []*FieldError) []string {
+	var rejectReasons []string
+	exceedsMaxExpiration := false
+	for _, fieldError := range fieldErrors {
+		if fieldError.Constraint == MaxExpirationConstraint {
+			exceedsMaxExpiration = true
+			continue
+		}
+		if fieldError.Pointer == "" {
+			continue
+		}
+		field := strings.SplitN(strings.TrimPrefix(fieldError.Pointer, "/"), "/", 2)[0]
+		field = strings.Replace(field, "~1", "/", -1)
+		field = strings.Replace(field, "~0", "~", -1)
+		rejectReasons = appendRejectReason(rejectReasons, RejectionReasonSchema+":"+field)
+	}
+	if len(rejectReasons) == 0 && !exceedsMaxExpiration {
+		rejectReasons = append(rejectReasons, RejectionReasonSchema)
+	}
+	if exceedsMaxExpiration {
+		rejectReasons = append(rejectReasons, RejectionReasonMaxExpiration)
+	}
+	return rejectReasons
+}
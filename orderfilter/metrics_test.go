@@ -0,0 +1,90 @@
+// +build !js
+
+package orderfilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterMetrics(t *testing.T) {
+	t.Parallel()
+
+	defaultFilter, err := New(constants.TestChainID, DefaultCustomOrderSchema, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	filter := defaultFilter.WithMaxExpirationDuration(time.Hour)
+	validOrderJSON := orderJSONExpiringAt(time.Now().Add(time.Minute))
+	lateOrderJSON := orderJSONExpiringAt(time.Now().Add(2 * time.Hour))
+	invalidOrderJSON := []byte(`{"makerAddress":"0x1234"}`)
+	// Based on validOrderJSON so that it is rejected by the schema rather than
+	// by the max expiration check.
+	var invalidAssetDataOrder map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(validOrderJSON, &invalidAssetDataOrder))
+	invalidAssetDataOrder["makerAssetData"] = json.RawMessage(`"not hex"`)
+	invalidAssetDataJSON, err := json.Marshal(invalidAssetDataOrder)
+	require.NoError(t, err)
+
+	for _, messageJSON := range [][]byte{
+		orderMessageJSON(validOrderJSON),
+		orderMessageJSON(validOrderJSON),
+		orderMessageJSON(lateOrderJSON),
+		orderMessageJSON(invalidOrderJSON),
+	} {
+		_, err := filter.MatchOrderMessageJSON(messageJSON)
+		require.NoError(t, err)
+	}
+	_, err = filter.MatchOrderMessageJSON([]byte("not json"))
+	require.Error(t, err)
+
+	for _, orderJSON := range [][]byte{validOrderJSON, lateOrderJSON, invalidAssetDataJSON} {
+		_, err := filter.ValidateOrderJSON(orderJSON)
+		require.NoError(t, err)
+	}
+	_, err = filter.ValidateOrderJSON([]byte("not json"))
+	require.Error(t, err)
+
+	assert.Equal(t, Metrics{
+		Messages: CheckMetrics{
+			NumAccepted: 2,
+			NumRejected: map[string]int64{
+				RejectionReasonSchema:        1,
+				RejectionReasonMaxExpiration: 1,
+			},
+			NumErrors: 1,
+		},
+		Orders: CheckMetrics{
+			NumAccepted: 1,
+			NumRejected: map[string]int64{
+				RejectionReasonMaxExpiration: 1,
+				"schema:makerAssetData":      1,
+			},
+			NumErrors: 1,
+		},
+	}, filter.Metrics())
+
+	// The filter created with WithMaxExpirationDuration has its own metrics.
+	assert.Equal(t, CheckMetrics{NumRejected: map[string]int64{}}, defaultFilter.Metrics().Messages)
+}
+
+func TestFilterMetricsLimitsRejectionReasons(t *testing.T) {
+	t.Parallel()
+
+	metrics := newFilterMetrics()
+	for i := 0; i < maxRejectionReasons+10; i++ {
+		metrics.recordOrder(false, fmt.Sprintf("schema:field%d", i), nil)
+	}
+	// Reasons which were already counted are still counted separately.
+	metrics.recordOrder(false, "schema:field0", nil)
+
+	filter := &Filter{metrics: metrics}
+	numRejected := filter.Metrics().Orders.NumRejected
+	assert.Len(t, numRejected, maxRejectionReasons+1)
+	assert.Equal(t, int64(2), numRejected["schema:field0"])
+	assert.Equal(t, int64(10), numRejected[RejectionReasonOther])
+}
@@ -0,0 +1,393 @@
+// +build !js
+
+package orderfilter
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+	jsonschema "github.com/xeipuuv/gojsonschema"
+)
+
+const (
+	// rootCustomOrderSchema is used to validate orders against /customOrder
+	// alone. Custom order schemas may refer to the built-in schemas, so it is
+	// compiled with the same loader as the other root schemas.
+	rootCustomOrderSchema = `{"$id":"/rootCustomOrder","allOf":[{"$ref":"/customOrder"}]}`
+	// maxOrderHashesPerHintMessage must match the maxItems of orderHashes in
+	// rootOrderMessageSchema.
+	maxOrderHashesPerHintMessage = 100
+	// maxHintBlockNumberDigits must match the pattern of blockNumber in
+	// rootOrderMessageSchema.
+	maxHintBlockNumberDigits = 20
+)
+
+var rootCustomOrderSchemaLoader = jsonschema.NewStringLoader(rootCustomOrderSchema)
+
+// valueValidator returns true if the given decoded JSON value (see
+// decodeJSONValue) is valid.
+type valueValidator func(value interface{}) bool
+
+// nativeValidator validates orders and order messages against the root
+// schemas. The built-in schemas are implemented in Go because validating them
+// with gojsonschema (and its regular expressions) dominates the time it takes
+// to handle a message. Only the /customOrder schema is still validated with
+// gojsonschema, and only if it doesn't accept all orders anyway.
+//
+// nativeValidator only tells whether a value is valid. The full schemas are
+// still used to describe why a value is invalid (see ValidateOrderJSON).
+type nativeValidator struct {
+	signedOrder valueValidator
+	// customOrderSchema is nil if the custom order schema accepts all orders.
+	customOrderSchema *jsonschema.Schema
+	// decodesAssetData is true if customOrderSchema was expanded by
+	// expandAssetDataKeywords, which means that orders have to be passed
+	// through withDecodedAssetData.
+	decodesAssetData bool
+}
+
+func newNativeValidator(chainID int, customOrderSchema string, exchangeAddresses []common.Address, protocolVersion ProtocolVersion) (*nativeValidator, error) {
+	validator := &nativeValidator{
+		signedOrder: signedOrderValidator(chainID, exchangeAddresses),
+	}
+	if protocolVersion == ProtocolVersionV4 {
+		validator.signedOrder = signedOrderV4Validator(chainID, exchangeAddresses)
+	}
+	if acceptsAllOrders(customOrderSchema) {
+		return validator, nil
+	}
+	loader, err := newLoader(chainID, customOrderSchema, exchangeAddresses, protocolVersion)
+	if err != nil {
+		return nil, err
+	}
+	validator.customOrderSchema, err = loader.Compile(rootCustomOrderSchemaLoader)
+	if err != nil {
+		return nil, err
+	}
+	return validator, nil
+}
+
+// acceptsAllOrders returns true if the given custom order schema is the empty
+// schema (e.g. DefaultCustomOrderSchema).
+func acceptsAllOrders(customOrderSchema string) bool {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(customOrderSchema), &schema); err != nil {
+		return false
+	}
+	return schema != nil && len(schema) == 0
+}
+
+// isValidOrderJSON returns true if the given JSON encoded order is valid
+// according to rootOrderSchema. It only returns an error if orderJSON is not
+// valid JSON.
+func (v *nativeValidator) isValidOrderJSON(orderJSON []byte) (bool, error) {
+	order, err := decodeJSONValue(orderJSON)
+	if err != nil {
+		return false, err
+	}
+	if !v.signedOrder(order) {
+		return false, nil
+	}
+	if v.customOrderSchema == nil {
+		return true, nil
+	}
+	if v.decodesAssetData {
+		orderJSON = withDecodedAssetData(orderJSON)
+	}
+	result, err := v.customOrderSchema.Validate(jsonschema.NewBytesLoader(orderJSON))
+	if err != nil {
+		return false, err
+	}
+	return result.Valid(), nil
+}
+
+// isValidOrderMessageJSON returns true if the given JSON encoded message is
+// valid according to rootOrderMessageSchema. It only returns an error if
+// messageJSON is not valid JSON.
+func (v *nativeValidator) isValidOrderMessageJSON(messageJSON []byte) (bool, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(messageJSON, &fields); err != nil {
+		if _, ok := err.(*json.UnmarshalTypeError); ok {
+			// Valid JSON, but not an object.
+			return false, nil
+		}
+		return false, err
+	}
+	var messageType string
+	if err := json.Unmarshal(fields["messageType"], &messageType); err != nil {
+		return false, nil
+	}
+	switch messageType {
+	case "order":
+		orderJSON, found := fields["order"]
+		if !found || !isValidRawField(fields, "topics", isValidTopics) {
+			return false, nil
+		}
+		return v.isValidOrderJSON(orderJSON)
+	case "orderUpdateHint":
+		// Hints may not have any additional properties.
+		if len(fields) != 4 {
+			return false, nil
+		}
+		return isValidRawField(fields, "orderHashes", isValidOrderHashes) &&
+			isValidRawField(fields, "blockNumber", isValidHintBlockNumber) &&
+			isValidRawField(fields, "topics", isValidTopics), nil
+	default:
+		return false, nil
+	}
+}
+
+// isValidRawField returns true if the given field exists and is valid.
+func isValidRawField(fields map[string]json.RawMessage, name string, validator valueValidator) bool {
+	raw, found := fields[name]
+	if !found {
+		return false
+	}
+	value, err := decodeJSONValue(raw)
+	if err != nil {
+		return false
+	}
+	return validator(value)
+}
+
+// decodeJSONValue decodes the given JSON document. Numbers are decoded as
+// json.Number so that they are compared exactly.
+func decodeJSONValue(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// signedOrderValidator returns a valueValidator which is equivalent to the
+// /signedOrder schema (including /chainId and /exchangeAddress).
+func signedOrderValidator(chainID int, exchangeAddresses []common.Address) valueValidator {
+	isValidExchangeAddress := exchangeAddressValidator(exchangeAddresses)
+	isValidChainID := chainIDValidator(chainID)
+	return objectValidator(map[string]valueValidator{
+		"makerAddress":          isValidAddress,
+		"takerAddress":          isValidAddress,
+		"makerFee":              isValidWholeNumber,
+		"takerFee":              isValidWholeNumber,
+		"senderAddress":         isValidAddress,
+		"makerAssetAmount":      isValidWholeNumber,
+		"takerAssetAmount":      isValidWholeNumber,
+		"makerAssetData":        isValidHex,
+		"takerAssetData":        isValidHex,
+		"makerFeeAssetData":     isValidHex,
+		"takerFeeAssetData":     isValidHex,
+		"salt":                  isValidWholeNumber,
+		"feeRecipientAddress":   isValidAddress,
+		"expirationTimeSeconds": isValidWholeNumber,
+		"exchangeAddress":       isValidExchangeAddress,
+		"chainId":               isValidChainID,
+		"signature":             isValidHex,
+	})
+}
+
+// signedOrderV4Validator returns a valueValidator which is equivalent to the
+// /signedOrderV4 schema (including /chainId and /exchangeAddress).
+func signedOrderV4Validator(chainID int, exchangeAddresses []common.Address) valueValidator {
+	isValidExchangeAddress := exchangeAddressValidator(exchangeAddresses)
+	isValidChainID := chainIDValidator(chainID)
+	isValidLimitOrder := objectValidator(map[string]valueValidator{
+		"makerToken":          isValidAddress,
+		"takerToken":          isValidAddress,
+		"makerAmount":         isValidWholeNumber,
+		"takerAmount":         isValidWholeNumber,
+		"takerTokenFeeAmount": isValidWholeNumber,
+		"maker":               isValidAddress,
+		"taker":               isValidAddress,
+		"sender":              isValidAddress,
+		"feeRecipient":        isValidAddress,
+		"pool":                isValidBytes32,
+		"expiry":              isValidWholeNumber,
+		"salt":                isValidWholeNumber,
+		"chainId":             isValidChainID,
+		"verifyingContract":   isValidExchangeAddress,
+	})
+	isValidRFQOrder := objectValidator(map[string]valueValidator{
+		"makerToken":        isValidAddress,
+		"takerToken":        isValidAddress,
+		"makerAmount":       isValidWholeNumber,
+		"takerAmount":       isValidWholeNumber,
+		"maker":             isValidAddress,
+		"taker":             isValidAddress,
+		"txOrigin":          isValidAddress,
+		"pool":              isValidBytes32,
+		"expiry":            isValidWholeNumber,
+		"salt":              isValidWholeNumber,
+		"chainId":           isValidChainID,
+		"verifyingContract": isValidExchangeAddress,
+	})
+	isValidSignature := objectValidator(map[string]valueValidator{
+		"signatureType": numberValidator(func(number *big.Rat) bool {
+			return number.Cmp(big.NewRat(2, 1)) == 0 || number.Cmp(big.NewRat(3, 1)) == 0
+		}),
+		"v": numberValidator(func(number *big.Rat) bool {
+			return number.IsInt() && number.Sign() >= 0 && number.Cmp(big.NewRat(255, 1)) <= 0
+		}),
+		"r": isValidBytes32,
+		"s": isValidBytes32,
+	})
+	return func(value interface{}) bool {
+		// Exactly one of the order types must match, like oneOf in the schema.
+		if isValidLimitOrder(value) == isValidRFQOrder(value) {
+			return false
+		}
+		return objectValidator(map[string]valueValidator{"signature": isValidSignature})(value)
+	}
+}
+
+// exchangeAddressValidator returns a valueValidator which is equivalent to the
+// /exchangeAddress schema.
+func exchangeAddressValidator(exchangeAddresses []common.Address) valueValidator {
+	// Both checksummed and non-checksummed addresses are accepted.
+	validExchangeAddresses := map[string]struct{}{}
+	for _, exchangeAddress := range exchangeAddresses {
+		validExchangeAddresses[exchangeAddress.Hex()] = struct{}{}
+		validExchangeAddresses[zeroex.NormalizedAddressHex(exchangeAddress)] = struct{}{}
+	}
+	return stringValidator(func(s string) bool {
+		_, found := validExchangeAddresses[s]
+		return found
+	})
+}
+
+// chainIDValidator returns a valueValidator which is equivalent to the
+// /chainId schema.
+func chainIDValidator(chainID int) valueValidator {
+	return numberValidator(func(number *big.Rat) bool {
+		return number.Cmp(new(big.Rat).SetInt64(int64(chainID))) == 0
+	})
+}
+
+// objectValidator returns a valueValidator which only accepts objects that
+// have all of the given properties, each of which must be valid. Additional
+// properties are allowed.
+func objectValidator(properties map[string]valueValidator) valueValidator {
+	return func(value interface{}) bool {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for name, validator := range properties {
+			property, found := object[name]
+			if !found || !validator(property) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// stringValidator returns a valueValidator which only accepts strings that are
+// valid according to isValid.
+func stringValidator(isValid func(s string) bool) valueValidator {
+	return func(value interface{}) bool {
+		s, ok := value.(string)
+		return ok && isValid(s)
+	}
+}
+
+// numberValidator returns a valueValidator which only accepts numbers that
+// are valid according to isValid.
+func numberValidator(isValid func(number *big.Rat) bool) valueValidator {
+	return func(value interface{}) bool {
+		number, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		rat, ok := new(big.Rat).SetString(number.String())
+		return ok && isValid(rat)
+	}
+}
+
+// arrayValidator returns a valueValidator which only accepts arrays with
+// minItems to maxItems items (or any number of items if maxItems is -1), each
+// of which must be valid.
+func arrayValidator(minItems int, maxItems int, items valueValidator) valueValidator {
+	return func(value interface{}) bool {
+		array, ok := value.([]interface{})
+		if !ok || len(array) < minItems || (maxItems != -1 && len(array) > maxItems) {
+			return false
+		}
+		for _, item := range array {
+			if !items(item) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+var (
+	// isValidAddress is equivalent to the /address schema.
+	isValidAddress = stringValidator(func(s string) bool {
+		return isPrefixedHex(s, 40)
+	})
+	// isValidHex is equivalent to the /hex schema.
+	isValidHex = stringValidator(func(s string) bool {
+		return len(s)%2 == 0 && isPrefixedHex(s, len(s)-2)
+	})
+	// isValidBytes32 is equivalent to the /bytes32 schema.
+	isValidBytes32 = stringValidator(func(s string) bool {
+		return isPrefixedHex(s, 64)
+	})
+	// isValidWholeNumber is equivalent to the /wholeNumber schema. Note that it
+	// accepts negative numbers (but not negative strings), just like the
+	// schema.
+	isValidWholeNumber = func(value interface{}) bool {
+		switch value := value.(type) {
+		case string:
+			return isDigits(value, 1, len(value))
+		case json.Number:
+			rat, ok := new(big.Rat).SetString(value.String())
+			return ok && rat.IsInt()
+		default:
+			return false
+		}
+	}
+	isValidOrderHashes = arrayValidator(1, maxOrderHashesPerHintMessage, stringValidator(func(s string) bool {
+		return isPrefixedHex(s, 64)
+	}))
+	isValidHintBlockNumber = stringValidator(func(s string) bool {
+		return isDigits(s, 1, maxHintBlockNumberDigits)
+	})
+	isValidTopics = arrayValidator(1, -1, stringValidator(func(string) bool { return true }))
+)
+
+// isPrefixedHex returns true if s consists of "0x" followed by exactly
+// numDigits hexadecimal digits.
+func isPrefixedHex(s string, numDigits int) bool {
+	if numDigits < 0 || len(s) != numDigits+2 || s[0] != '0' || s[1] != 'x' {
+		return false
+	}
+	for i := 2; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// isDigits returns true if s consists of minDigits to maxDigits decimal
+// digits.
+func isDigits(s string, minDigits int, maxDigits int) bool {
+	if len(s) < minDigits || len(s) > maxDigits {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
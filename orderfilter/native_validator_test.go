@@ -0,0 +1,136 @@
+// +build !js
+
+package orderfilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	jsonschema "github.com/xeipuuv/gojsonschema"
+)
+
+// withOrderField returns a copy of standardValidOrderJSON in which the given
+// field is set to the given JSON value or removed if value is "".
+func withOrderField(t *testing.T, field string, value string) []byte {
+	var order map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(standardValidOrderJSON, &order))
+	if value == "" {
+		delete(order, field)
+	} else {
+		order[field] = json.RawMessage(value)
+	}
+	orderJSON, err := json.Marshal(order)
+	require.NoError(t, err)
+	return orderJSON
+}
+
+func orderMessageJSON(orderJSON []byte) []byte {
+	return []byte(fmt.Sprintf(`{"messageType":"order","order":%s,"topics":["/0x-orders/version/3/chain/1337/schema/e30="]}`, orderJSON))
+}
+
+func TestNativeValidatorMatchesSchemas(t *testing.T) {
+	t.Parallel()
+
+	senderAddressSchema := `{"properties":{"senderAddress":{"const":"0x00000000000000000000000000000000ba5eba11"}}}`
+	referencingSchema := `{"properties":{"makerFee":{"$ref":"/wholeNumber","not":{"const":"0"}}}}`
+	orders := map[string][]byte{
+		"valid order":                    standardValidOrderJSON,
+		"specific sender address":        orderWithSpecificSenderAddressJSON,
+		"checksummed exchange address":   withOrderField(t, "exchangeAddress", `"0x48BaCB9266a570d521063EF5dD96e61686DbE788"`),
+		"wrong exchange address":         withOrderField(t, "exchangeAddress", `"0x0000000000000000000000000000000000000000"`),
+		"chain ID as a decimal":          withOrderField(t, "chainId", `1337.0`),
+		"chain ID as a string":           withOrderField(t, "chainId", `"1337"`),
+		"wrong chain ID":                 withOrderField(t, "chainId", `42`),
+		"whole number as an integer":     withOrderField(t, "makerFee", `5`),
+		"whole number as a decimal":      withOrderField(t, "makerFee", `5.5`),
+		"negative whole number":          withOrderField(t, "makerFee", `"-5"`),
+		"empty whole number":             withOrderField(t, "salt", `""`),
+		"short address":                  withOrderField(t, "takerAddress", `"0x1234"`),
+		"address without prefix":         withOrderField(t, "takerAddress", `"000000000000000000000000000000000000000000"`),
+		"hex with an odd length":         withOrderField(t, "makerFeeAssetData", `"0x123"`),
+		"hex with invalid characters":    withOrderField(t, "makerFeeAssetData", `"0xzz"`),
+		"empty hex":                      withOrderField(t, "makerFeeAssetData", `""`),
+		"null signature":                 withOrderField(t, "signature", `null`),
+		"missing signature":              withOrderField(t, "signature", ""),
+		"additional property":            withOrderField(t, "foo", `"bar"`),
+		"array instead of order":         []byte(`[]`),
+		"string instead of order":        []byte(`"order"`),
+		"null instead of order":          []byte(`null`),
+		"empty object instead of order":  []byte(`{}`),
+		"integer instead of order":       []byte(`1`),
+		"nested order instead of order":  []byte(fmt.Sprintf(`{"order":%s}`, standardValidOrderJSON)),
+		"address with uppercase prefix":  withOrderField(t, "makerAddress", `"0XA3ECE5D5B6319FA785EFC10D3112769A46C6E149"`),
+		"address with uppercase letters": withOrderField(t, "makerAddress", `"0xA3ECE5D5B6319FA785EFC10D3112769A46C6E149"`),
+	}
+	messages := map[string][]byte{
+		"array":                         []byte(`[]`),
+		"string":                        []byte(`"order"`),
+		"null":                          []byte(`null`),
+		"empty object":                  []byte(`{}`),
+		"message type is not a string":  []byte(`{"messageType":1,"orderHashes":["0xabababababababababababababababababababababababababababababababab"],"blockNumber":"1","topics":["a"]}`),
+		"topics is not an array":        []byte(fmt.Sprintf(`{"messageType":"order","order":%s,"topics":"a"}`, standardValidOrderJSON)),
+		"topic is not a string":         []byte(fmt.Sprintf(`{"messageType":"order","order":%s,"topics":[1]}`, standardValidOrderJSON)),
+		"hint":                          []byte(`{"messageType":"orderUpdateHint","orderHashes":["0xabababababababababababababababababababababababababababababababab"],"blockNumber":"12345","topics":["a"]}`),
+		"hint with a numeric block":     []byte(`{"messageType":"orderUpdateHint","orderHashes":["0xabababababababababababababababababababababababababababababababab"],"blockNumber":12345,"topics":["a"]}`),
+		"hint with a long block number": []byte(`{"messageType":"orderUpdateHint","orderHashes":["0xabababababababababababababababababababababababababababababababab"],"blockNumber":"123456789012345678901","topics":["a"]}`),
+		"hint with an empty block":      []byte(`{"messageType":"orderUpdateHint","orderHashes":["0xabababababababababababababababababababababababababababababababab"],"blockNumber":"","topics":["a"]}`),
+		"hint without topics":           []byte(`{"messageType":"orderUpdateHint","orderHashes":["0xabababababababababababababababababababababababababababababababab"],"blockNumber":"12345"}`),
+		"hint with an order":            []byte(fmt.Sprintf(`{"messageType":"orderUpdateHint","orderHashes":["0xabababababababababababababababababababababababababababababababab"],"blockNumber":"12345","order":%s}`, standardValidOrderJSON)),
+	}
+	messages["hint with too many order hashes"] = []byte(fmt.Sprintf(`{"messageType":"orderUpdateHint","orderHashes":[%s"0xabababababababababababababababababababababababababababababababab"],"blockNumber":"12345","topics":["a"]}`,
+		strings.Repeat(`"0xabababababababababababababababababababababababababababababababab",`, maxOrderHashesPerHintMessage)))
+	for note, orderJSON := range orders {
+		messages["order message with "+note] = orderMessageJSON(orderJSON)
+	}
+
+	for _, customOrderSchema := range []string{DefaultCustomOrderSchema, senderAddressSchema, referencingSchema} {
+		filter, err := New(constants.TestChainID, customOrderSchema, contractAddresses, ProtocolVersionV3)
+		require.NoError(t, err)
+		messageSchema := compileMessageSchema(t, customOrderSchema)
+
+		for note, orderJSON := range orders {
+			tcInfo := fmt.Sprintf("schema: %s\norder: %s", customOrderSchema, note)
+			expected, err := filter.orderSchema.Validate(jsonschema.NewBytesLoader(orderJSON))
+			require.NoError(t, err, tcInfo)
+			actual, err := filter.validator.isValidOrderJSON(orderJSON)
+			require.NoError(t, err, tcInfo)
+			assert.Equal(t, expected.Valid(), actual, tcInfo)
+		}
+		for note, messageJSON := range messages {
+			tcInfo := fmt.Sprintf("schema: %s\nmessage: %s", customOrderSchema, note)
+			expected, err := messageSchema.Validate(jsonschema.NewBytesLoader(messageJSON))
+			require.NoError(t, err, tcInfo)
+			actual, err := filter.MatchOrderMessageJSON(messageJSON)
+			require.NoError(t, err, tcInfo)
+			assert.Equal(t, expected.Valid(), actual, tcInfo)
+		}
+	}
+}
+
+func TestNativeValidatorInvalidJSON(t *testing.T) {
+	t.Parallel()
+
+	filter, err := New(constants.TestChainID, DefaultCustomOrderSchema, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	_, err = filter.MatchOrderMessageJSON([]byte(`{"messageType":`))
+	assert.Error(t, err)
+	_, err = filter.ValidateOrderJSON([]byte(`{"makerAddress":`))
+	assert.Error(t, err)
+}
+
+// compileMessageSchema compiles rootOrderMessageSchema, which the native
+// validator implements, for the given custom order schema.
+func compileMessageSchema(t *testing.T, customOrderSchema string) *jsonschema.Schema {
+	loader, err := newLoader(constants.TestChainID, customOrderSchema, []common.Address{contractAddresses.Exchange}, ProtocolVersionV3)
+	require.NoError(t, err)
+	require.NoError(t, loader.AddSchemas(rootOrderSchemaLoader))
+	messageSchema, err := loader.Compile(rootOrderMessageSchemaLoader)
+	require.NoError(t, err)
+	return messageSchema
+}
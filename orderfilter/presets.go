@@ -0,0 +1,190 @@
+package orderfilter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// PresetNoTakerFees only allows orders with a takerFee of 0.
+	PresetNoTakerFees = "no-taker-fees"
+	// PresetERC20Only only allows orders which trade one ERC20 token for
+	// another. Fees, if any, must also be paid in ERC20 tokens.
+	PresetERC20Only = "erc20-only"
+	// PresetWhitelistedPairs only allows orders which trade one of the tokens
+	// in PresetOptions.TokenAddresses (or in the dynamic token list with
+	// PresetOptions.TokenListFormat) for another.
+	PresetWhitelistedPairs = "whitelisted-pairs"
+)
+
+var (
+	// erc20AssetDataPattern matches the ERC20 assetData for any token.
+	erc20AssetDataPattern = fmt.Sprintf("^0x%s0{24}[0-9a-fA-F]{40}$", zeroex.ERC20AssetDataID)
+	// erc20OrEmptyAssetDataPattern matches the ERC20 assetData for any token or
+	// empty assetData (which is used when there is no fee).
+	erc20OrEmptyAssetDataPattern = fmt.Sprintf("^0x(%s0{24}[0-9a-fA-F]{40})?$", zeroex.ERC20AssetDataID)
+)
+
+// ErrNoTokensForWhitelistedPairs is returned by CustomOrderSchemaFromPresets if
+// the whitelisted-pairs preset is used without any token addresses.
+var ErrNoTokensForWhitelistedPairs = errors.New("the whitelisted-pairs preset requires at least one token address")
+
+// ErrTokensAndTokenList is returned by CustomOrderSchemaFromPresets if both
+// PresetOptions.TokenAddresses and PresetOptions.TokenListFormat are set.
+var ErrTokensAndTokenList = errors.New("token addresses and a dynamic token list cannot be used together")
+
+// UnknownPresetError is returned by CustomOrderSchemaFromPresets if one of the
+// presets does not exist.
+type UnknownPresetError struct {
+	Preset string
+}
+
+func (e UnknownPresetError) Error() string {
+	return fmt.Sprintf("unknown order filter preset: %q", e.Preset)
+}
+
+// PresetOptions holds the parameters used by presets which need them.
+type PresetOptions struct {
+	// TokenAddresses are the ERC20 tokens that may be traded when using the
+	// whitelisted-pairs preset.
+	TokenAddresses []common.Address
+	// TokenListFormat is the format of a dynamic token list (see
+	// DynamicTokenList.Format) which is used by the whitelisted-pairs preset
+	// instead of TokenAddresses. Unlike TokenAddresses, the tokens in the list
+	// are not part of the schema, so they can change without changing the
+	// topic.
+	TokenListFormat string
+}
+
+// CustomOrderSchemaFromPresets returns a custom order schema which only
+// allows orders that match all of the given presets. The result can be passed
+// to New like any hand-written custom order schema. Presets are sorted and
+// token addresses are deduplicated first, so that nodes which use the same
+// presets and tokens always end up with exactly the same schema (and therefore
+// the same topic), regardless of the order they were configured in.
+func CustomOrderSchemaFromPresets(presets []string, opts PresetOptions) (string, error) {
+	presets = sortedUnique(presets)
+	if len(presets) == 0 {
+		return DefaultCustomOrderSchema, nil
+	}
+	schemas := make([]interface{}, len(presets))
+	for i, preset := range presets {
+		schema, err := presetSchema(preset, opts)
+		if err != nil {
+			return "", err
+		}
+		schemas[i] = schema
+	}
+	var customOrderSchema interface{}
+	if len(schemas) == 1 {
+		customOrderSchema = schemas[0]
+	} else {
+		customOrderSchema = map[string]interface{}{"allOf": schemas}
+	}
+	encoded, err := json.Marshal(customOrderSchema)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+func presetSchema(preset string, opts PresetOptions) (map[string]interface{}, error) {
+	switch preset {
+	case PresetNoTakerFees:
+		return propertiesSchema(map[string]interface{}{
+			"takerFee": wholeNumberValuesSchema([]string{"0"}),
+		}), nil
+	case PresetERC20Only:
+		return propertiesSchema(map[string]interface{}{
+			"makerAssetData":    map[string]interface{}{"type": "string", "pattern": erc20AssetDataPattern},
+			"takerAssetData":    map[string]interface{}{"type": "string", "pattern": erc20AssetDataPattern},
+			"makerFeeAssetData": map[string]interface{}{"type": "string", "pattern": erc20OrEmptyAssetDataPattern},
+			"takerFeeAssetData": map[string]interface{}{"type": "string", "pattern": erc20OrEmptyAssetDataPattern},
+		}), nil
+	case PresetWhitelistedPairs:
+		if opts.TokenListFormat != "" {
+			if len(opts.TokenAddresses) != 0 {
+				return nil, ErrTokensAndTokenList
+			}
+			return propertiesSchema(map[string]interface{}{
+				"makerAssetData": map[string]interface{}{"type": "string", "format": opts.TokenListFormat},
+				"takerAssetData": map[string]interface{}{"type": "string", "format": opts.TokenListFormat},
+			}), nil
+		}
+		if len(opts.TokenAddresses) == 0 {
+			return nil, ErrNoTokensForWhitelistedPairs
+		}
+		tokens := make([]string, len(opts.TokenAddresses))
+		for i, tokenAddress := range opts.TokenAddresses {
+			tokens[i] = zeroex.NormalizedAddressHex(tokenAddress)
+		}
+		tokens = sortedUnique(tokens)
+		allowedAssetData := make([]string, len(tokens))
+		for i, token := range tokens {
+			allowedAssetData[i] = "0x" + zeroex.ERC20AssetDataID + strings.Repeat("0", 24) + strings.TrimPrefix(token, "0x")
+		}
+		return propertiesSchema(map[string]interface{}{
+			"makerAssetData": map[string]interface{}{"enum": allowedAssetData},
+			"takerAssetData": map[string]interface{}{"enum": allowedAssetData},
+		}), nil
+	default:
+		return nil, UnknownPresetError{Preset: preset}
+	}
+}
+
+func propertiesSchema(properties map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"properties": properties}
+}
+
+func sortedUnique(values []string) []string {
+	seen := map[string]struct{}{}
+	result := []string{}
+	for _, value := range values {
+		if _, found := seen[value]; found {
+			continue
+		}
+		seen[value] = struct{}{}
+		result = append(result, value)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// ParseTokenList parses the token addresses in a token list file. The file
+// may either be a JSON array of addresses or a token list in the format
+// described at https://tokenlists.org, in which case only tokens with the
+// given chainID are included.
+func ParseTokenList(data []byte, chainID int) ([]common.Address, error) {
+	var rawAddresses []string
+	if err := json.Unmarshal(data, &rawAddresses); err != nil {
+		var tokenList struct {
+			Tokens []struct {
+				ChainID int    `json:"chainId"`
+				Address string `json:"address"`
+			} `json:"tokens"`
+		}
+		if err := json.Unmarshal(data, &tokenList); err != nil {
+			return nil, fmt.Errorf("could not parse token list: %s", err.Error())
+		}
+		for _, token := range tokenList.Tokens {
+			if token.ChainID == chainID {
+				rawAddresses = append(rawAddresses, token.Address)
+			}
+		}
+	}
+	tokenAddresses := make([]common.Address, len(rawAddresses))
+	for i, rawAddress := range rawAddresses {
+		tokenAddress, err := zeroex.ParseAddress(rawAddress)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse token list: %s", err.Error())
+		}
+		tokenAddresses[i] = tokenAddress
+	}
+	return tokenAddresses, nil
+}
@@ -0,0 +1,103 @@
+package orderfilter
+
+import (
+	"testing"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	zrxAddress  = common.HexToAddress("0xe41d2489571d322189246dafa5ebde1f4699f498")
+	wethAddress = common.HexToAddress("0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2")
+	daiAddress  = common.HexToAddress("0x6b175474e89094c44da98b954eedeac495271d0f")
+)
+
+func TestCustomOrderSchemaFromPresets(t *testing.T) {
+	testCases := []struct {
+		presets       []string
+		opts          PresetOptions
+		expectedValid bool
+	}{
+		{
+			presets:       []string{},
+			expectedValid: true,
+		},
+		{
+			presets:       []string{PresetNoTakerFees},
+			expectedValid: true,
+		},
+		{
+			presets:       []string{PresetERC20Only},
+			expectedValid: true,
+		},
+		{
+			presets:       []string{PresetWhitelistedPairs},
+			opts:          PresetOptions{TokenAddresses: []common.Address{zrxAddress, wethAddress}},
+			expectedValid: true,
+		},
+		{
+			presets:       []string{PresetWhitelistedPairs},
+			opts:          PresetOptions{TokenAddresses: []common.Address{zrxAddress, daiAddress}},
+			expectedValid: false,
+		},
+		{
+			presets:       []string{PresetNoTakerFees, PresetERC20Only, PresetWhitelistedPairs},
+			opts:          PresetOptions{TokenAddresses: []common.Address{wethAddress, zrxAddress}},
+			expectedValid: true,
+		},
+	}
+
+	for i, tc := range testCases {
+		customOrderSchema, err := CustomOrderSchemaFromPresets(tc.presets, tc.opts)
+		require.NoError(t, err, "test case %d", i)
+		filter, err := New(constants.TestChainID, customOrderSchema, contractAddresses, ProtocolVersionV3)
+		require.NoError(t, err, "test case %d", i)
+		result, err := filter.ValidateOrderJSON(standardValidOrderJSON)
+		require.NoError(t, err, "test case %d", i)
+		assert.Equal(t, tc.expectedValid, result.Valid(), "test case %d: %v", i, result.Errors())
+	}
+}
+
+func TestCustomOrderSchemaFromPresetsIsCanonical(t *testing.T) {
+	schema, err := CustomOrderSchemaFromPresets(
+		[]string{PresetNoTakerFees, PresetWhitelistedPairs},
+		PresetOptions{TokenAddresses: []common.Address{zrxAddress, wethAddress}},
+	)
+	require.NoError(t, err)
+	reorderedSchema, err := CustomOrderSchemaFromPresets(
+		[]string{PresetWhitelistedPairs, PresetNoTakerFees, PresetNoTakerFees},
+		PresetOptions{TokenAddresses: []common.Address{wethAddress, zrxAddress, wethAddress}},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, schema, reorderedSchema)
+}
+
+func TestCustomOrderSchemaFromPresetsErrors(t *testing.T) {
+	_, err := CustomOrderSchemaFromPresets([]string{"no-maker-fees"}, PresetOptions{})
+	assert.Equal(t, UnknownPresetError{Preset: "no-maker-fees"}, err)
+	_, err = CustomOrderSchemaFromPresets([]string{PresetWhitelistedPairs}, PresetOptions{})
+	assert.Equal(t, ErrNoTokensForWhitelistedPairs, err)
+}
+
+func TestParseTokenList(t *testing.T) {
+	tokenAddresses, err := ParseTokenList([]byte(`["0xe41d2489571d322189246dafa5ebde1f4699f498", "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"]`), 1)
+	require.NoError(t, err)
+	assert.Equal(t, []common.Address{zrxAddress, wethAddress}, tokenAddresses)
+
+	tokenList := `{
+		"name": "Example",
+		"tokens": [
+			{"chainId": 1, "address": "0xe41d2489571d322189246dafa5ebde1f4699f498", "symbol": "ZRX"},
+			{"chainId": 42, "address": "0x6b175474e89094c44da98b954eedeac495271d0f", "symbol": "DAI"}
+		]
+	}`
+	tokenAddresses, err = ParseTokenList([]byte(tokenList), 1)
+	require.NoError(t, err)
+	assert.Equal(t, []common.Address{zrxAddress}, tokenAddresses)
+
+	_, err = ParseTokenList([]byte(`["0xnotanaddress"]`), 1)
+	assert.Error(t, err)
+}
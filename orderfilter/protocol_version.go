@@ -0,0 +1,88 @@
+package orderfilter
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ProtocolVersion is the version of the 0x protocol whose orders a Filter
+// accepts. Orders of different protocol versions have a different structure,
+// so they are validated against different built-in schemas and shared on
+// different topics.
+type ProtocolVersion int
+
+const (
+	// ProtocolVersionV3 is for v3 signed orders, which are filled through the
+	// Exchange contract and describe the traded assets with assetData.
+	ProtocolVersionV3 ProtocolVersion = 3
+	// ProtocolVersionV4 is for v4 limit and RFQ orders, which are filled
+	// through the ExchangeProxy contract, trade ERC20 tokens by address and
+	// have a structured signature.
+	ProtocolVersionV4 ProtocolVersion = 4
+)
+
+const (
+	v4FullTopicFormat      = "/0x-v4-orders/version/%d/chain/%d/schema/%s"
+	v4TopicVersionFormat   = "/0x-v4-orders/version/%d%s"
+	v4TopicPrefix          = "/0x-v4-orders/"
+	v4FullRendezvousFormat = "/0x-v4-custom-filter-rendezvous/version/%d/chain/%d/schema/%s"
+)
+
+// UnsupportedProtocolVersionError is returned when creating a Filter for a
+// protocol version which is not supported.
+type UnsupportedProtocolVersionError struct {
+	Version ProtocolVersion
+}
+
+func (e UnsupportedProtocolVersionError) Error() string {
+	return fmt.Sprintf("unsupported 0x protocol version: %d", e.Version)
+}
+
+// ErrNoExchangeProxy is returned by New if a Filter for v4 orders is created
+// with contract addresses that don't include the ExchangeProxy.
+var ErrNoExchangeProxy = errors.New("contract addresses must include the ExchangeProxy for v4 orders")
+
+// ProtocolVersion returns the version of the 0x protocol whose orders pass the
+// filter.
+func (f *Filter) ProtocolVersion() ProtocolVersion {
+	return f.protocolVersion
+}
+
+func validateProtocolVersion(protocolVersion ProtocolVersion) error {
+	switch protocolVersion {
+	case ProtocolVersionV3, ProtocolVersionV4:
+		return nil
+	default:
+		return UnsupportedProtocolVersionError{Version: protocolVersion}
+	}
+}
+
+// exchangeAddress returns the address of the contract through which orders of
+// the given protocol version are filled, i.e. the exchangeAddress of v3 orders
+// and the verifyingContract of v4 orders.
+func exchangeAddress(protocolVersion ProtocolVersion, contractAddresses ethereum.ContractAddresses) (common.Address, error) {
+	switch protocolVersion {
+	case ProtocolVersionV3:
+		return contractAddresses.Exchange, nil
+	case ProtocolVersionV4:
+		if contractAddresses.ExchangeProxy == constants.NullAddress {
+			return common.Address{}, ErrNoExchangeProxy
+		}
+		return contractAddresses.ExchangeProxy, nil
+	default:
+		return common.Address{}, UnsupportedProtocolVersionError{Version: protocolVersion}
+	}
+}
+
+// expirationTimeField returns the name of the field which holds the
+// expiration time of orders of the given protocol version.
+func expirationTimeField(protocolVersion ProtocolVersion) string {
+	if protocolVersion == ProtocolVersionV4 {
+		return "expiry"
+	}
+	return "expirationTimeSeconds"
+}
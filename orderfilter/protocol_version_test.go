@@ -0,0 +1,129 @@
+// +build !js
+
+package orderfilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	jsonschema "github.com/xeipuuv/gojsonschema"
+)
+
+var (
+	v4ContractAddresses = withExchangeProxy(contractAddresses, common.HexToAddress("0x5315e44798395d4a952530d131249fe00f554565"))
+	v4SignatureJSON     = `{"signatureType":2,"v":27,"r":"0x61a3ed31b43c8780e905a260a35faefcc527be7516aa11c0256729b5b351bc33","s":"0x40349190569279751135161d22529dc25add4f6069af05be04cacbda2ace2254"}`
+	limitOrderV4JSON    = []byte(`{"makerToken":"0x871dd7c2b4b25e1aa18728e9d5f2af4c4e431f5c","takerToken":"0x0b1ba0af832d7c05fd64161e0db78e85978e8082","makerAmount":"100","takerAmount":"42","takerTokenFeeAmount":"0","maker":"0xa3ece5d5b6319fa785efc10d3112769a46c6e149","taker":"0x0000000000000000000000000000000000000000","sender":"0x0000000000000000000000000000000000000000","feeRecipient":"0x0000000000000000000000000000000000000000","pool":"0x0000000000000000000000000000000000000000000000000000000000000000","expiry":"1559856615","salt":"2001","chainId":1337,"verifyingContract":"0x5315e44798395d4a952530d131249fe00f554565","signature":` + v4SignatureJSON + `}`)
+	rfqOrderV4JSON      = []byte(`{"makerToken":"0x871dd7c2b4b25e1aa18728e9d5f2af4c4e431f5c","takerToken":"0x0b1ba0af832d7c05fd64161e0db78e85978e8082","makerAmount":"100","takerAmount":"42","maker":"0xa3ece5d5b6319fa785efc10d3112769a46c6e149","taker":"0x0000000000000000000000000000000000000000","txOrigin":"0x6ecbe1db9ef729cbe972c83fb886247691fb6beb","pool":"0x0000000000000000000000000000000000000000000000000000000000000000","expiry":"1559856615","salt":"2001","chainId":1337,"verifyingContract":"0x5315e44798395d4a952530d131249fe00f554565","signature":` + v4SignatureJSON + `}`)
+)
+
+// withField returns a copy of the given JSON encoded order in which the given
+// field is set to the given JSON value or removed if value is "".
+func withField(t *testing.T, orderJSON []byte, field string, value string) []byte {
+	var order map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(orderJSON, &order))
+	if value == "" {
+		delete(order, field)
+	} else {
+		order[field] = json.RawMessage(value)
+	}
+	newOrderJSON, err := json.Marshal(order)
+	require.NoError(t, err)
+	return newOrderJSON
+}
+
+func withExchangeProxy(contractAddresses ethereum.ContractAddresses, exchangeProxy common.Address) ethereum.ContractAddresses {
+	contractAddresses.ExchangeProxy = exchangeProxy
+	return contractAddresses
+}
+
+func TestNewWithProtocolVersion(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(constants.TestChainID, DefaultCustomOrderSchema, contractAddresses, ProtocolVersionV4)
+	assert.Equal(t, ErrNoExchangeProxy, err)
+	_, err = New(constants.TestChainID, DefaultCustomOrderSchema, v4ContractAddresses, ProtocolVersion(2))
+	assert.Equal(t, UnsupportedProtocolVersionError{Version: 2}, err)
+
+	filter, err := New(constants.TestChainID, DefaultCustomOrderSchema, v4ContractAddresses, ProtocolVersionV4)
+	require.NoError(t, err)
+	assert.Equal(t, ProtocolVersionV4, filter.ProtocolVersion())
+	assert.Equal(t, []common.Address{v4ContractAddresses.ExchangeProxy}, filter.ExchangeAddresses())
+}
+
+func TestV4OrderSchema(t *testing.T) {
+	t.Parallel()
+
+	v3Filter, err := New(constants.TestChainID, DefaultCustomOrderSchema, v4ContractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	v4Filter, err := New(constants.TestChainID, DefaultCustomOrderSchema, v4ContractAddresses, ProtocolVersionV4)
+	require.NoError(t, err)
+	customV4Filter, err := New(constants.TestChainID, `{"properties":{"makerToken":{"const":"0x871dd7c2b4b25e1aa18728e9d5f2af4c4e431f5c"}}}`, v4ContractAddresses, ProtocolVersionV4)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		note           string
+		filter         *Filter
+		orderJSON      []byte
+		expectedResult bool
+	}{
+		{"v4 limit order", v4Filter, limitOrderV4JSON, true},
+		{"v4 RFQ order", v4Filter, rfqOrderV4JSON, true},
+		{"v3 order for v4 filter", v4Filter, standardValidOrderJSON, false},
+		{"v4 order for v3 filter", v3Filter, limitOrderV4JSON, false},
+		{"v4 order matching custom schema", customV4Filter, limitOrderV4JSON, true},
+		{"v4 order not matching custom schema", customV4Filter, withField(t, limitOrderV4JSON, "makerToken", `"0x0b1ba0af832d7c05fd64161e0db78e85978e8082"`), false},
+		{"missing signature", v4Filter, withField(t, limitOrderV4JSON, "signature", ""), false},
+		{"v3 signature", v4Filter, withField(t, limitOrderV4JSON, "signature", `"0x1c52f75daa4bd2ad9e6e8a7c35adbd089d709e48ae86463f2abfafa3578747fafc264a04d02fa26227e90476d57bca94e24af32f1cc8da444bba21092ca56cd85603"`), false},
+		{"unsupported signature type", v4Filter, []byte(strings.Replace(string(limitOrderV4JSON), `"signatureType":2`, `"signatureType":4`, 1)), false},
+		{"wrong verifying contract", v4Filter, withField(t, limitOrderV4JSON, "verifyingContract", `"0x48bacb9266a570d521063ef5dd96e61686dbe788"`), false},
+		{"wrong chain ID", v4Filter, withField(t, rfqOrderV4JSON, "chainId", "1"), false},
+		{"malformed pool", v4Filter, withField(t, rfqOrderV4JSON, "pool", `"0x00"`), false},
+		{"both limit and RFQ order", v4Filter, withField(t, limitOrderV4JSON, "txOrigin", `"0x6ecbe1db9ef729cbe972c83fb886247691fb6beb"`), false},
+	}
+	for _, tc := range testCases {
+		result, err := tc.filter.ValidateOrderJSON(tc.orderJSON)
+		require.NoError(t, err, tc.note)
+		assert.Equal(t, tc.expectedResult, result.Valid(), tc.note)
+
+		// The native validator must agree with the schema.
+		schemaResult, err := tc.filter.orderSchema.Validate(jsonschema.NewBytesLoader(tc.orderJSON))
+		require.NoError(t, err, tc.note)
+		assert.Equal(t, tc.expectedResult, schemaResult.Valid(), tc.note)
+
+		matches, err := tc.filter.MatchOrderMessageJSON([]byte(fmt.Sprintf(`{"messageType":"order","order":%s,"topics":[%q]}`, tc.orderJSON, tc.filter.Topic())))
+		require.NoError(t, err, tc.note)
+		assert.Equal(t, tc.expectedResult, matches, tc.note)
+	}
+}
+
+func TestV4Topic(t *testing.T) {
+	t.Parallel()
+
+	v3Filter, err := New(constants.TestChainID, DefaultCustomOrderSchema, v4ContractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	v4Filter, err := New(constants.TestChainID, DefaultCustomOrderSchema, v4ContractAddresses, ProtocolVersionV4)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(v4Filter.Topic(), "/0x-v4-orders/version/3/"), v4Filter.Topic())
+	assert.NotEqual(t, v3Filter.Topic(), v4Filter.Topic())
+	assert.NotEqual(t, v3Filter.Rendezvous(), v4Filter.Rendezvous())
+	assert.False(t, v3Filter.Equals(v4Filter))
+	assert.Empty(t, v4Filter.LegacyTopics())
+
+	topicFilter, err := NewFromTopic(v4Filter.Topic(), v4ContractAddresses)
+	require.NoError(t, err)
+	assert.Equal(t, ProtocolVersionV4, topicFilter.ProtocolVersion())
+	assert.True(t, v4Filter.Equals(topicFilter))
+
+	_, err = NewFromTopic(strings.Replace(v4Filter.Topic(), "/version/3/", "/version/2/", 1), v4ContractAddresses)
+	assert.IsType(t, WrongTopicVersionError{}, err)
+
+	_, err = Or(v3Filter, v4Filter)
+	assert.IsType(t, IncompatibleFiltersError{}, err)
+}
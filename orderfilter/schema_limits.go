@@ -0,0 +1,180 @@
+package orderfilter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp/syntax"
+	"sync"
+)
+
+// SchemaLimits bound the size and complexity of custom order schemas which
+// NewFromTopic accepts. Topics are received from untrusted peers, so the
+// limits are checked before the schema is compiled. A limit of 0 disables the
+// corresponding check.
+type SchemaLimits struct {
+	// MaxBytes is the maximum length of the decoded schema in bytes.
+	MaxBytes int
+	// MaxDepth is the maximum nesting depth of JSON objects and arrays.
+	MaxDepth int
+	// MaxRefs is the maximum number of $ref keywords.
+	MaxRefs int
+	// MaxPatternComplexity is the maximum number of instructions in the
+	// compiled program of any regular expression in the schema (i.e. the
+	// values of pattern and the keys of patternProperties). Counted repetitions
+	// are expanded, so e.g. "[0-9a-f]{40}" is about 40 instructions.
+	MaxPatternComplexity int
+}
+
+// DefaultSchemaLimits are the limits used by NewFromTopic unless others are
+// set with SetSchemaLimits. They comfortably fit the schemas generated by
+// presets, token pairs and constraints.
+var DefaultSchemaLimits = SchemaLimits{
+	MaxBytes:             64 * 1024,
+	MaxDepth:             32,
+	MaxRefs:              32,
+	MaxPatternComplexity: 1000,
+}
+
+// ErrSchemaLimitExceeded is returned by NewFromTopic if the custom order schema
+// of the topic exceeds one of the SchemaLimits.
+type ErrSchemaLimitExceeded struct {
+	// Limit is the name of the exceeded field of SchemaLimits.
+	Limit  string
+	Max    int
+	Actual int
+}
+
+func (e ErrSchemaLimitExceeded) Error() string {
+	return fmt.Sprintf("custom order schema exceeds %s (%d > %d)", e.Limit, e.Actual, e.Max)
+}
+
+var (
+	schemaLimitsMu sync.RWMutex
+	schemaLimits   = DefaultSchemaLimits
+)
+
+// SetSchemaLimits sets the limits which NewFromTopic enforces on custom order
+// schemas. Filters which were already created are not affected.
+func SetSchemaLimits(limits SchemaLimits) {
+	schemaLimitsMu.Lock()
+	defer schemaLimitsMu.Unlock()
+	schemaLimits = limits
+}
+
+// GetSchemaLimits returns the limits set with SetSchemaLimits.
+func GetSchemaLimits() SchemaLimits {
+	schemaLimitsMu.RLock()
+	defer schemaLimitsMu.RUnlock()
+	return schemaLimits
+}
+
+// Check returns an ErrSchemaLimitExceeded if the given custom order schema
+// exceeds the limits, or an error if it is not valid JSON or contains an
+// invalid regular expression. The checks are ordered so that a schema is only
+// decoded after its size and depth were found to be within the limits.
+func (l SchemaLimits) Check(customOrderSchema []byte) error {
+	if l.MaxBytes != 0 && len(customOrderSchema) > l.MaxBytes {
+		return ErrSchemaLimitExceeded{Limit: "MaxBytes", Max: l.MaxBytes, Actual: len(customOrderSchema)}
+	}
+	depth, err := jsonDepth(customOrderSchema, l.MaxDepth)
+	if err != nil {
+		return err
+	}
+	if l.MaxDepth != 0 && depth > l.MaxDepth {
+		return ErrSchemaLimitExceeded{Limit: "MaxDepth", Max: l.MaxDepth, Actual: depth}
+	}
+	var schema interface{}
+	if err := json.Unmarshal(customOrderSchema, &schema); err != nil {
+		return err
+	}
+	var refs int
+	var patterns []string
+	walkSchemaKeywords(schema, &refs, &patterns)
+	if l.MaxRefs != 0 && refs > l.MaxRefs {
+		return ErrSchemaLimitExceeded{Limit: "MaxRefs", Max: l.MaxRefs, Actual: refs}
+	}
+	for _, pattern := range patterns {
+		complexity, err := patternComplexity(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q in custom order schema: %s", pattern, err.Error())
+		}
+		if l.MaxPatternComplexity != 0 && complexity > l.MaxPatternComplexity {
+			return ErrSchemaLimitExceeded{Limit: "MaxPatternComplexity", Max: l.MaxPatternComplexity, Actual: complexity}
+		}
+	}
+	return nil
+}
+
+// jsonDepth returns the maximum nesting depth of objects and arrays in the
+// given JSON. It reads tokens instead of decoding the JSON, so that deeply
+// nested input is never decoded recursively, and stops as soon as the depth
+// exceeds maxDepth (unless maxDepth is 0).
+func jsonDepth(data []byte, maxDepth int) (int, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	depth, max := 0, 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return max, nil
+		} else if err != nil {
+			return 0, err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+			if depth > max {
+				max = depth
+			}
+			if maxDepth != 0 && max > maxDepth {
+				return max, nil
+			}
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
+
+// walkSchemaKeywords counts the $ref keywords in the given decoded schema and
+// collects its regular expressions.
+func walkSchemaKeywords(schema interface{}, refs *int, patterns *[]string) {
+	switch schema := schema.(type) {
+	case map[string]interface{}:
+		for key, value := range schema {
+			switch key {
+			case "$ref":
+				*refs++
+			case "pattern":
+				if pattern, ok := value.(string); ok {
+					*patterns = append(*patterns, pattern)
+				}
+			case "patternProperties":
+				if properties, ok := value.(map[string]interface{}); ok {
+					for pattern := range properties {
+						*patterns = append(*patterns, pattern)
+					}
+				}
+			}
+			walkSchemaKeywords(value, refs, patterns)
+		}
+	case []interface{}:
+		for _, value := range schema {
+			walkSchemaKeywords(value, refs, patterns)
+		}
+	}
+}
+
+// patternComplexity returns the number of instructions in the compiled
+// program of the given regular expression.
+func patternComplexity(pattern string) (int, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return 0, err
+	}
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return 0, err
+	}
+	return len(prog.Inst), nil
+}
@@ -0,0 +1,74 @@
+package orderfilter
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func topicForSchema(customOrderSchema string) string {
+	return fmt.Sprintf(fullTopicFormat, pubsubTopicVersion, 1337, base64.URLEncoding.EncodeToString([]byte(customOrderSchema)))
+}
+
+func TestNewFromTopicSchemaLimits(t *testing.T) {
+	defer SetSchemaLimits(DefaultSchemaLimits)
+
+	testCases := []struct {
+		name              string
+		customOrderSchema string
+		expectedErr       error
+	}{
+		{
+			name:              "default schema",
+			customOrderSchema: DefaultCustomOrderSchema,
+		},
+		{
+			name:              "too large",
+			customOrderSchema: fmt.Sprintf(`{"description":%q}`, strings.Repeat("a", DefaultSchemaLimits.MaxBytes)),
+			expectedErr:       ErrSchemaLimitExceeded{Limit: "MaxBytes", Max: DefaultSchemaLimits.MaxBytes, Actual: DefaultSchemaLimits.MaxBytes + 18},
+		},
+		{
+			name:              "too deeply nested",
+			customOrderSchema: strings.Repeat(`{"not":`, 40) + "{}" + strings.Repeat("}", 40),
+			expectedErr:       ErrSchemaLimitExceeded{Limit: "MaxDepth", Max: DefaultSchemaLimits.MaxDepth, Actual: DefaultSchemaLimits.MaxDepth + 1},
+		},
+		{
+			name:              "too many refs",
+			customOrderSchema: `{"allOf":[` + strings.TrimSuffix(strings.Repeat(`{"$ref":"/address"},`, 33), ",") + `]}`,
+			expectedErr:       ErrSchemaLimitExceeded{Limit: "MaxRefs", Max: DefaultSchemaLimits.MaxRefs, Actual: 33},
+		},
+		{
+			name:              "complex pattern",
+			customOrderSchema: `{"properties":{"makerAddress":{"pattern":"^(0x[0-9a-f]{40}){25}$"}}}`,
+			expectedErr:       ErrSchemaLimitExceeded{Limit: "MaxPatternComplexity", Max: DefaultSchemaLimits.MaxPatternComplexity, Actual: 1104},
+		},
+		{
+			name:              "complex pattern property",
+			customOrderSchema: `{"patternProperties":{"^(0x[0-9a-f]{40}){25}$":{}}}`,
+			expectedErr:       ErrSchemaLimitExceeded{Limit: "MaxPatternComplexity", Max: DefaultSchemaLimits.MaxPatternComplexity, Actual: 1104},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewFromTopic(topicForSchema(tc.customOrderSchema), contractAddresses)
+			if tc.expectedErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tc.expectedErr, err)
+			}
+		})
+	}
+
+	// Invalid patterns are rejected before compilation too.
+	_, err := NewFromTopic(topicForSchema(`{"properties":{"makerAddress":{"pattern":"^(0x"}}}`), contractAddresses)
+	assert.Error(t, err)
+
+	// Limits of 0 disable the checks.
+	SetSchemaLimits(SchemaLimits{})
+	_, err = NewFromTopic(topicForSchema(`{"properties":{"makerAddress":{"pattern":"^(0x[0-9a-f]{40}){25}$"}}}`), contractAddresses)
+	require.NoError(t, err)
+}
@@ -8,9 +8,25 @@ const (
 	orderSchema       = `{"$id":"/order","properties":{"makerAddress":{"$ref":"/address"},"takerAddress":{"$ref":"/address"},"makerFee":{"$ref":"/wholeNumber"},"takerFee":{"$ref":"/wholeNumber"},"senderAddress":{"$ref":"/address"},"makerAssetAmount":{"$ref":"/wholeNumber"},"takerAssetAmount":{"$ref":"/wholeNumber"},"makerAssetData":{"$ref":"/hex"},"takerAssetData":{"$ref":"/hex"},"makerFeeAssetData":{"$ref":"/hex"},"takerFeeAssetData":{"$ref":"/hex"},"salt":{"$ref":"/wholeNumber"},"feeRecipientAddress":{"$ref":"/address"},"expirationTimeSeconds":{"$ref":"/wholeNumber"},"exchangeAddress":{"$ref":"/exchangeAddress"},"chainId":{"$ref":"/chainId"}},"required":["makerAddress","takerAddress","makerFee","takerFee","senderAddress","makerAssetAmount","takerAssetAmount","makerAssetData","takerAssetData","makerFeeAssetData","takerFeeAssetData","salt","feeRecipientAddress","expirationTimeSeconds","exchangeAddress","chainId"],"type":"object"}`
 	signedOrderSchema = `{"$id":"/signedOrder","allOf":[{"$ref":"/order"},{"properties":{"signature":{"$ref":"/hex"}},"required":["signature"]}]}`
 
+	// Built-in schemas for v4 orders
+	bytes32Schema = `{"$id":"/bytes32","type":"string","pattern":"^0x[0-9a-fA-F]{64}$"}`
+	// v4SignatureSchema only allows the EIP712 (2) and EthSign (3) signature
+	// types, which are the ones that can be validated off-chain.
+	v4SignatureSchema   = `{"$id":"/v4Signature","properties":{"signatureType":{"enum":[2,3]},"v":{"type":"integer","minimum":0,"maximum":255},"r":{"$ref":"/bytes32"},"s":{"$ref":"/bytes32"}},"required":["signatureType","v","r","s"],"type":"object"}`
+	limitOrderV4Schema  = `{"$id":"/limitOrderV4","properties":{"makerToken":{"$ref":"/address"},"takerToken":{"$ref":"/address"},"makerAmount":{"$ref":"/wholeNumber"},"takerAmount":{"$ref":"/wholeNumber"},"takerTokenFeeAmount":{"$ref":"/wholeNumber"},"maker":{"$ref":"/address"},"taker":{"$ref":"/address"},"sender":{"$ref":"/address"},"feeRecipient":{"$ref":"/address"},"pool":{"$ref":"/bytes32"},"expiry":{"$ref":"/wholeNumber"},"salt":{"$ref":"/wholeNumber"},"chainId":{"$ref":"/chainId"},"verifyingContract":{"$ref":"/exchangeAddress"}},"required":["makerToken","takerToken","makerAmount","takerAmount","takerTokenFeeAmount","maker","taker","sender","feeRecipient","pool","expiry","salt","chainId","verifyingContract"],"type":"object"}`
+	rfqOrderV4Schema    = `{"$id":"/rfqOrderV4","properties":{"makerToken":{"$ref":"/address"},"takerToken":{"$ref":"/address"},"makerAmount":{"$ref":"/wholeNumber"},"takerAmount":{"$ref":"/wholeNumber"},"maker":{"$ref":"/address"},"taker":{"$ref":"/address"},"txOrigin":{"$ref":"/address"},"pool":{"$ref":"/bytes32"},"expiry":{"$ref":"/wholeNumber"},"salt":{"$ref":"/wholeNumber"},"chainId":{"$ref":"/chainId"},"verifyingContract":{"$ref":"/exchangeAddress"}},"required":["makerToken","takerToken","makerAmount","takerAmount","maker","taker","txOrigin","pool","expiry","salt","chainId","verifyingContract"],"type":"object"}`
+	signedOrderV4Schema = `{"$id":"/signedOrderV4","allOf":[{"oneOf":[{"$ref":"/limitOrderV4"},{"$ref":"/rfqOrderV4"}]},{"properties":{"signature":{"$ref":"/v4Signature"}},"required":["signature"]}]}`
+
 	// Root schemas
-	rootOrderSchema        = `{"$id":"/rootOrder","allOf":[{"$ref":"/customOrder"},{"$ref":"/signedOrder"}]}`
-	rootOrderMessageSchema = `{"$id":"/rootOrderMessage","properties":{"messageType":{"type":"string","pattern":"order"},"order":{"$ref":"/rootOrder"},"topics":{"type":"array","minItems":1,"items":{"type":"string"}}},"required":["messageType","order","topics"]}`
+	rootOrderSchema = `{"$id":"/rootOrder","allOf":[{"$ref":"/customOrder"},{"$ref":"/signedOrder"}]}`
+	// rootOrderV4Schema replaces rootOrderSchema in filters for v4 orders. It
+	// has the same $id, so that rootOrderMessageSchema can be used for both
+	// protocol versions.
+	rootOrderV4Schema = `{"$id":"/rootOrder","allOf":[{"$ref":"/customOrder"},{"$ref":"/signedOrderV4"}]}`
+	// rootOrderMessageSchema accepts order messages and order update hint
+	// messages (see encoding.OrderUpdateHint). Hints may contain at most 100
+	// order hashes.
+	rootOrderMessageSchema = `{"$id":"/rootOrderMessage","oneOf":[{"properties":{"messageType":{"type":"string","pattern":"^order$"},"order":{"$ref":"/rootOrder"},"topics":{"type":"array","minItems":1,"items":{"type":"string"}}},"required":["messageType","order","topics"]},{"properties":{"messageType":{"type":"string","pattern":"^orderUpdateHint$"},"orderHashes":{"type":"array","minItems":1,"maxItems":100,"items":{"type":"string","pattern":"^0x[0-9a-fA-F]{64}$"}},"blockNumber":{"type":"string","pattern":"^\\d{1,20}$"},"topics":{"type":"array","minItems":1,"items":{"type":"string"}}},"required":["messageType","orderHashes","blockNumber","topics"],"additionalProperties":false}]}`
 
 	// DefaultCustomOrderSchema is the default schema for /customOrder. It
 	// includes all 0x orders and doesn't add any additional requirements.
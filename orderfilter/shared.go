@@ -2,11 +2,17 @@ package orderfilter
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/0xProject/0x-mesh/ethereum"
 	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
 	canonicaljson "github.com/gibson042/canonicaljson-go"
 	peer "github.com/libp2p/go-libp2p-core/peer"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
@@ -31,8 +37,63 @@ func (e WrongTopicVersionError) Error() string {
 	return fmt.Sprintf("wrong topic version: expected %d but got %d", e.expectedVersion, e.actualVersion)
 }
 
+// ErrNoExchangeAddresses is returned by NewWithExchangeAddresses if no
+// exchange addresses were given.
+var ErrNoExchangeAddresses = errors.New("at least one exchange address is required")
+
+// New returns a Filter which only accepts orders of the given protocol version
+// that match the given custom order schema and use the given chain and the
+// Exchange contract (or the ExchangeProxy for v4 orders) in the given contract
+// addresses.
+func New(chainID int, customOrderSchema string, contractAddresses ethereum.ContractAddresses, protocolVersion ProtocolVersion) (*Filter, error) {
+	exchangeAddress, err := exchangeAddress(protocolVersion, contractAddresses)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithExchangeAddresses(chainID, customOrderSchema, []common.Address{exchangeAddress}, protocolVersion)
+}
+
+// uniqueExchangeAddresses returns the given exchange addresses without
+// duplicates.
+func uniqueExchangeAddresses(exchangeAddresses []common.Address) ([]common.Address, error) {
+	if len(exchangeAddresses) == 0 {
+		return nil, ErrNoExchangeAddresses
+	}
+	seen := map[common.Address]struct{}{}
+	unique := []common.Address{}
+	for _, exchangeAddress := range exchangeAddresses {
+		if _, found := seen[exchangeAddress]; found {
+			continue
+		}
+		seen[exchangeAddress] = struct{}{}
+		unique = append(unique, exchangeAddress)
+	}
+	return unique, nil
+}
+
+// newExchangeAddressSchema returns the /exchangeAddress schema for the given
+// unique exchange addresses. It accepts both checksummed and non-checksummed
+// (i.e. all lowercase) addresses. If there is more than one exchange address,
+// the schema is a oneOf over the schemas for the individual addresses.
+func newExchangeAddressSchema(exchangeAddresses []common.Address) string {
+	schemas := make([]string, len(exchangeAddresses))
+	for i, exchangeAddress := range exchangeAddresses {
+		schemas[i] = fmt.Sprintf(`{"enum":[%q,%q]}`, exchangeAddress.Hex(), zeroex.NormalizedAddressHex(exchangeAddress))
+	}
+	if len(schemas) == 1 {
+		return schemas[0]
+	}
+	return fmt.Sprintf(`{"oneOf":[%s]}`, strings.Join(schemas, ","))
+}
+
+// ExchangeAddresses returns the exchange addresses which orders must use to
+// pass the filter.
+func (f *Filter) ExchangeAddresses() []common.Address {
+	return append([]common.Address{}, f.exchangeAddresses...)
+}
+
 func GetDefaultFilter(chainID int, contractAddresses ethereum.ContractAddresses) (*Filter, error) {
-	return New(chainID, DefaultCustomOrderSchema, contractAddresses)
+	return New(chainID, DefaultCustomOrderSchema, contractAddresses, ProtocolVersionV3)
 }
 
 func GetDefaultTopic(chainID int, contractAddresses ethereum.ContractAddresses) (string, error) {
@@ -43,25 +104,73 @@ func GetDefaultTopic(chainID int, contractAddresses ethereum.ContractAddresses)
 	return defaultFilter.Topic(), nil
 }
 
-// MatchOrder returns true if the order passes the filter. It only returns an
-// error if there was a problem with validation. For details about
-// orders that do not pass the filter, use ValidateOrder.
-func (f *Filter) MatchOrder(order *zeroex.SignedOrder) (bool, error) {
-	result, err := f.ValidateOrder(order)
+// NewFromConstraints returns a Filter whose custom order schema is compiled
+// from the given constraints (see Constraints.CustomOrderSchema). Such filters
+// have the same topic as any other filter with the same schema, but MatchOrder
+// uses the compiled constraints instead of validating orders against the
+// schema. The shortest expiresWithin constraint is used as the max expiration
+// duration of the filter (see WithMaxExpirationDuration). Constraints only
+// apply to v3 orders.
+func NewFromConstraints(chainID int, constraints *Constraints, contractAddresses ethereum.ContractAddresses) (*Filter, error) {
+	customOrderSchema, err := constraints.CustomOrderSchema()
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+	filter, err := New(chainID, customOrderSchema, contractAddresses, ProtocolVersionV3)
+	if err != nil {
+		return nil, err
+	}
+	filter.constraints = constraints
+	filter.maxExpirationDuration = constraints.MaxExpirationDuration()
+	return filter, nil
+}
+
+// acceptsExchangeAddress returns true if orders with the given exchange address
+// can pass the filter.
+func (f *Filter) acceptsExchangeAddress(exchangeAddress common.Address) bool {
+	for _, acceptedAddress := range f.exchangeAddresses {
+		if exchangeAddress == acceptedAddress {
+			return true
+		}
 	}
-	return result.Valid(), nil
+	return false
 }
 
+// NewFromTopic returns the Filter for the given topic. Topics of a previous
+// topic version are handled according to the legacy topic policy (see
+// SetLegacyTopicPolicy). Topics for v4 orders use a different prefix and
+// don't have previous versions. Filters are cached (see SetTopicFilterCacheSize), so
+// calling it again with the same topic and contract addresses returns the
+// same, already compiled Filter. Callers must not modify the returned Filter.
+// Since topics are received from untrusted peers, custom order schemas which
+// exceed the schema limits (see SetSchemaLimits) are rejected with an
+// ErrSchemaLimitExceeded before they are compiled.
 func NewFromTopic(topic string, contractAddresses ethereum.ContractAddresses) (*Filter, error) {
-	// TODO(albrow): Use a cache for topic -> filter
+	policy := GetLegacyTopicPolicy()
+	limits := GetSchemaLimits()
+	cacheKey := topicFilterCacheKey{topic: topic, contractAddresses: contractAddresses, legacyTopicPolicy: policy, schemaLimits: limits}
+	if filter, found := getCachedTopicFilter(cacheKey); found {
+		return filter, nil
+	}
+	protocolVersion := ProtocolVersionV3
+	versionFormat := topicVersionFormat
+	if strings.HasPrefix(topic, v4TopicPrefix) {
+		protocolVersion = ProtocolVersionV4
+		versionFormat = v4TopicVersionFormat
+	}
 	var version int
 	var chainIDAndSchema string
-	if _, err := fmt.Sscanf(topic, topicVersionFormat, &version, &chainIDAndSchema); err != nil {
+	if _, err := fmt.Sscanf(topic, versionFormat, &version, &chainIDAndSchema); err != nil {
 		return nil, fmt.Errorf("could not parse topic version for topic: %q", topic)
 	}
-	if version != pubsubTopicVersion {
+	var topicVersion int
+	if protocolVersion == ProtocolVersionV3 {
+		var err error
+		topicVersion, err = topicVersionForPolicy(version, policy)
+		if err != nil {
+			return nil, err
+		}
+	} else if version != pubsubTopicVersion {
 		return nil, WrongTopicVersionError{
 			expectedVersion: pubsubTopicVersion,
 			actualVersion:   version,
@@ -76,13 +185,25 @@ func NewFromTopic(topic string, contractAddresses ethereum.ContractAddresses) (*
 	if err != nil {
 		return nil, fmt.Errorf("could not base64-decode order schema: %q", base64EncodedSchema)
 	}
-	return New(chainID, string(customOrderSchema), contractAddresses)
+	if err := limits.Check(customOrderSchema); err != nil {
+		return nil, err
+	}
+	filter, err := New(chainID, string(customOrderSchema), contractAddresses, protocolVersion)
+	if err != nil {
+		return nil, err
+	}
+	filter.topicVersion = topicVersion
+	cacheTopicFilter(cacheKey, filter)
+	return filter, nil
 }
 
 func (f *Filter) Rendezvous() string {
 	if f.encodedSchema == "" {
 		f.encodedSchema = f.generateEncodedSchema()
 	}
+	if f.protocolVersion == ProtocolVersionV4 {
+		return fmt.Sprintf(v4FullRendezvousFormat, rendezvousVersion, f.chainID, f.encodedSchema)
+	}
 	return fmt.Sprintf(fullRendezvousFormat, rendezvousVersion, f.chainID, f.encodedSchema)
 }
 
@@ -90,7 +211,23 @@ func (f *Filter) Topic() string {
 	if f.encodedSchema == "" {
 		f.encodedSchema = f.generateEncodedSchema()
 	}
-	return fmt.Sprintf(fullTopicFormat, pubsubTopicVersion, f.chainID, f.encodedSchema)
+	topicVersion := pubsubTopicVersion
+	if f.IsLegacy() {
+		topicVersion = f.topicVersion
+	}
+	if f.protocolVersion == ProtocolVersionV4 {
+		return fmt.Sprintf(v4FullTopicFormat, topicVersion, f.chainID, f.encodedSchema)
+	}
+	return fmt.Sprintf(fullTopicFormat, topicVersion, f.chainID, f.encodedSchema)
+}
+
+// Hash returns a fingerprint of the filter. It is the hex-encoded SHA-256 hash
+// of the topic, so two filters have the same hash if and only if they have the
+// same chain ID and semantically equivalent custom order schemas. It can be
+// used to distinguish orders that were admitted under different filters.
+func (f *Filter) Hash() string {
+	hash := sha256.Sum256([]byte(f.Topic()))
+	return hex.EncodeToString(hash[:])
 }
 
 // Dummy declaration to ensure that ValidatePubSubMessage matches the expected
@@ -99,13 +236,36 @@ var _ pubsub.Validator = (&Filter{}).ValidatePubSubMessage
 
 // ValidatePubSubMessage is an implementation of pubsub.Validator and will
 // return true if the contents of the message pass the message JSON Schema.
+// Messages with v3 orders must also be encoded strictly in the wire format
+// (see zeroex.UnmarshalSignedOrderWire), so that messages which some nodes
+// would decode into a different order are not propagated.
 func (f *Filter) ValidatePubSubMessage(ctx context.Context, sender peer.ID, msg *pubsub.Message) bool {
 	isValid, err := f.MatchOrderMessageJSON(msg.Data)
 	if err != nil {
 		log.WithError(err).Error("MatchOrderMessageJSON returned an error")
 		return false
 	}
-	return isValid
+	if !isValid {
+		return false
+	}
+	return f.protocolVersion != ProtocolVersionV3 || hasWireOrder(msg.Data)
+}
+
+// hasWireOrder returns true if the given message is not an order message or
+// its order is encoded in the wire format.
+func hasWireOrder(messageJSON []byte) bool {
+	var message struct {
+		MessageType string          `json:"messageType"`
+		Order       json.RawMessage `json:"order"`
+	}
+	if err := json.Unmarshal(messageJSON, &message); err != nil {
+		return false
+	}
+	if message.MessageType != "order" {
+		return true
+	}
+	_, err := zeroex.UnmarshalSignedOrderWire(message.Order)
+	return err == nil
 }
 
 func (f *Filter) generateEncodedSchema() string {
@@ -128,8 +288,17 @@ func (f *Filter) generateEncodedSchema() string {
 	//         "foo":"bar"
 	//     }
 	//
+	// The schema is normalized first (see normalizeSchema), so that the order
+	// of e.g. the subschemas of anyOf or the values of enum doesn't matter
+	// either.
+	return base64.URLEncoding.EncodeToString(canonicalSchemaJSON(f.rawCustomOrderSchema))
+}
+
+// canonicalSchemaJSON returns the canonical JSON encoding of the given custom
+// order schema (see generateEncodedSchema).
+func canonicalSchemaJSON(customOrderSchema string) []byte {
 	var holder interface{} = struct{}{}
-	_ = canonicaljson.Unmarshal([]byte(f.rawCustomOrderSchema), &holder)
-	canonicalOrderSchemaJSON, _ := canonicaljson.Marshal(holder)
-	return base64.URLEncoding.EncodeToString(canonicalOrderSchemaJSON)
+	_ = canonicaljson.Unmarshal([]byte(customOrderSchema), &holder)
+	canonicalOrderSchemaJSON, _ := canonicaljson.Marshal(normalizeSchema(holder))
+	return canonicalOrderSchemaJSON
 }
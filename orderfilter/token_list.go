@@ -0,0 +1,128 @@
+package orderfilter
+
+import (
+	"bytes"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// tokenListFormatPrefix is the prefix of the names of the formats registered
+// for dynamic token lists.
+const tokenListFormatPrefix = "token-list-"
+
+var (
+	dynamicTokenListsMu sync.Mutex
+	// dynamicTokenLists are the dynamic token lists by source.
+	dynamicTokenLists = map[string]*DynamicTokenList{}
+)
+
+// DynamicTokenList is a list of ERC20 tokens which can be updated without
+// changing the filters that use it (e.g. a token list which is fetched from a
+// URL and refreshed periodically). Custom order schemas reference the list by
+// its format (see Format), whose name only depends on where the list comes
+// from, so the topic of a filter stays the same when the tokens in the list
+// change. Only ERC20 assetData for one of the tokens in the list has the
+// format.
+type DynamicTokenList struct {
+	format string
+	mu     sync.RWMutex
+	tokens map[common.Address]struct{}
+}
+
+// GetDynamicTokenList returns the dynamic token list for the given source
+// (e.g. the URL it is fetched from). The list is empty until SetTokens is
+// called. Every call with the same source returns the same list.
+func GetDynamicTokenList(source string) (*DynamicTokenList, error) {
+	dynamicTokenListsMu.Lock()
+	defer dynamicTokenListsMu.Unlock()
+	if list, found := dynamicTokenLists[source]; found {
+		return list, nil
+	}
+	list := &DynamicTokenList{
+		format: TokenListFormat(source),
+		tokens: map[common.Address]struct{}{},
+	}
+	if err := RegisterFormat(list.format, list.isAllowedAssetData); err != nil {
+		return nil, err
+	}
+	dynamicTokenLists[source] = list
+	return list, nil
+}
+
+// TokenListFormat returns the name of the format of the dynamic token list
+// for the given source. It contains a hash of the source rather than the
+// source itself, since sources may contain credentials.
+func TokenListFormat(source string) string {
+	return tokenListFormatPrefix + hex.EncodeToString(crypto.Keccak256([]byte(source))[:8])
+}
+
+// Format returns the name of the format which custom order schemas can use to
+// only allow assetData for one of the tokens in the list (e.g.
+// {"properties":{"makerAssetData":{"format":"token-list-..."}}}).
+func (l *DynamicTokenList) Format() string {
+	return l.format
+}
+
+// SetTokens replaces the tokens in the list. It returns the tokens which were
+// removed from the list.
+func (l *DynamicTokenList) SetTokens(tokenAddresses []common.Address) []common.Address {
+	tokens := make(map[common.Address]struct{}, len(tokenAddresses))
+	for _, tokenAddress := range tokenAddresses {
+		tokens[tokenAddress] = struct{}{}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	removed := []common.Address{}
+	for tokenAddress := range l.tokens {
+		if _, found := tokens[tokenAddress]; !found {
+			removed = append(removed, tokenAddress)
+		}
+	}
+	l.tokens = tokens
+	sortAddresses(removed)
+	return removed
+}
+
+// Tokens returns the tokens in the list, sorted by address.
+func (l *DynamicTokenList) Tokens() []common.Address {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	tokens := make([]common.Address, 0, len(l.tokens))
+	for tokenAddress := range l.tokens {
+		tokens = append(tokens, tokenAddress)
+	}
+	sortAddresses(tokens)
+	return tokens
+}
+
+// Contains returns true if the given token is in the list.
+func (l *DynamicTokenList) Contains(tokenAddress common.Address) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, found := l.tokens[tokenAddress]
+	return found
+}
+
+// isAllowedAssetData is the FormatChecker of the list.
+func (l *DynamicTokenList) isAllowedAssetData(value string) bool {
+	if !hexRegex.MatchString(value) || len(value) != len("0x")+8+64 {
+		return false
+	}
+	if strings.ToLower(value[2:10]) != zeroex.ERC20AssetDataID || strings.Trim(value[10:34], "0") != "" {
+		return false
+	}
+	return l.Contains(common.HexToAddress(value[34:]))
+}
+
+func sortAddresses(addresses []common.Address) {
+	sort.Slice(addresses, func(i, j int) bool {
+		return bytes.Compare(addresses[i][:], addresses[j][:]) < 0
+	})
+}
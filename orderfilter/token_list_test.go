@@ -0,0 +1,52 @@
+package orderfilter
+
+import (
+	"testing"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamicTokenList(t *testing.T) {
+	source := "https://example.com/TestDynamicTokenList.json"
+	tokenList, err := GetDynamicTokenList(source)
+	require.NoError(t, err)
+	sameTokenList, err := GetDynamicTokenList(source)
+	require.NoError(t, err)
+	assert.Equal(t, tokenList, sameTokenList)
+	assert.Equal(t, TokenListFormat(source), tokenList.Format())
+	assert.Contains(t, Formats(), tokenList.Format())
+
+	customOrderSchema, err := CustomOrderSchemaFromPresets([]string{PresetWhitelistedPairs}, PresetOptions{TokenListFormat: tokenList.Format()})
+	require.NoError(t, err)
+	filter, err := New(constants.TestChainID, customOrderSchema, contractAddresses, ProtocolVersionV3)
+	require.NoError(t, err)
+	topic := filter.Topic()
+
+	// The list is empty until tokens are set.
+	result, err := filter.ValidateOrderJSON(standardValidOrderJSON)
+	require.NoError(t, err)
+	assert.False(t, result.Valid())
+
+	assert.Empty(t, tokenList.SetTokens([]common.Address{zrxAddress, wethAddress, daiAddress}))
+	result, err = filter.ValidateOrderJSON(standardValidOrderJSON)
+	require.NoError(t, err)
+	assert.True(t, result.Valid(), result.Errors())
+
+	// Removing a token changes which orders are valid but not the topic.
+	removed := tokenList.SetTokens([]common.Address{zrxAddress, daiAddress})
+	assert.Equal(t, []common.Address{wethAddress}, removed)
+	assert.Equal(t, []common.Address{daiAddress, zrxAddress}, tokenList.Tokens())
+	result, err = filter.ValidateOrderJSON(standardValidOrderJSON)
+	require.NoError(t, err)
+	assert.False(t, result.Valid())
+	assert.Equal(t, topic, filter.Topic())
+
+	_, err = CustomOrderSchemaFromPresets([]string{PresetWhitelistedPairs}, PresetOptions{
+		TokenAddresses:  []common.Address{zrxAddress},
+		TokenListFormat: tokenList.Format(),
+	})
+	assert.Equal(t, ErrTokensAndTokenList, err)
+}
@@ -0,0 +1,118 @@
+package orderfilter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/0xProject/0x-mesh/ethereum"
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TokenStandard is the token standard of a Token, which determines the format
+// of its assetData.
+type TokenStandard string
+
+const (
+	// ERC20 is the token standard of fungible tokens.
+	ERC20 TokenStandard = "erc20"
+	// ERC721 is the token standard of non-fungible tokens. Orders for any token
+	// ID of an ERC721 contract are accepted.
+	ERC721 TokenStandard = "erc721"
+)
+
+// Token is one side of a TokenPair.
+type Token struct {
+	Standard TokenStandard
+	Address  common.Address
+}
+
+// TokenPair is a pair of tokens which may be traded for each other, in either
+// direction.
+type TokenPair struct {
+	Base  Token
+	Quote Token
+}
+
+// ErrNoTokenPairs is returned by NewForTokenPairs and
+// CustomOrderSchemaForTokenPairs if no token pairs were given.
+var ErrNoTokenPairs = errors.New("at least one token pair is required")
+
+// UnknownTokenStandardError is returned by NewForTokenPairs and
+// CustomOrderSchemaForTokenPairs if one of the tokens uses a token standard
+// which is not supported.
+type UnknownTokenStandardError struct {
+	Standard TokenStandard
+}
+
+func (e UnknownTokenStandardError) Error() string {
+	return fmt.Sprintf("unknown token standard: %q", e.Standard)
+}
+
+// NewForTokenPairs returns a Filter which only accepts orders that trade the
+// tokens of one of the given pairs for each other (see
+// CustomOrderSchemaForTokenPairs) and use the given chain and the Exchange
+// contract in the given contract addresses.
+func NewForTokenPairs(chainID int, pairs []TokenPair, contractAddresses ethereum.ContractAddresses) (*Filter, error) {
+	customOrderSchema, err := CustomOrderSchemaForTokenPairs(pairs)
+	if err != nil {
+		return nil, err
+	}
+	return New(chainID, customOrderSchema, contractAddresses, ProtocolVersionV3)
+}
+
+// CustomOrderSchemaForTokenPairs returns a custom order schema which only
+// allows orders whose makerAssetData and takerAssetData are for the tokens of
+// one of the given pairs, in either direction. Like CustomOrderSchemaFromPresets,
+// the result doesn't depend on the order of the pairs, the order of the tokens
+// within a pair or the casing of the addresses, so that nodes which allow the
+// same pairs always end up with the same topic.
+func CustomOrderSchemaForTokenPairs(pairs []TokenPair) (string, error) {
+	if len(pairs) == 0 {
+		return "", ErrNoTokenPairs
+	}
+	directions := []string{}
+	for _, pair := range pairs {
+		basePattern, err := assetDataPattern(pair.Base)
+		if err != nil {
+			return "", err
+		}
+		quotePattern, err := assetDataPattern(pair.Quote)
+		if err != nil {
+			return "", err
+		}
+		for _, direction := range [][2]string{{basePattern, quotePattern}, {quotePattern, basePattern}} {
+			encoded, err := json.Marshal(propertiesSchema(map[string]interface{}{
+				"makerAssetData": map[string]interface{}{"type": "string", "pattern": direction[0]},
+				"takerAssetData": map[string]interface{}{"type": "string", "pattern": direction[1]},
+			}))
+			if err != nil {
+				return "", err
+			}
+			directions = append(directions, string(encoded))
+		}
+	}
+	// Both directions of a pair with the same base and quote token are the
+	// same, so there might be only one.
+	directions = sortedUnique(directions)
+	if len(directions) == 1 {
+		return directions[0], nil
+	}
+	return fmt.Sprintf(`{"anyOf":[%s]}`, strings.Join(directions, ",")), nil
+}
+
+// assetDataPattern returns the pattern which matches the assetData of the
+// given token.
+func assetDataPattern(token Token) (string, error) {
+	paddedAddress := strings.Repeat("0", 24) + strings.TrimPrefix(zeroex.NormalizedAddressHex(token.Address), "0x")
+	switch token.Standard {
+	case ERC20:
+		return fmt.Sprintf("^0x%s%s$", zeroex.ERC20AssetDataID, paddedAddress), nil
+	case ERC721:
+		return fmt.Sprintf("^0x%s%s[0-9a-f]{64}$", zeroex.ERC721AssetDataID, paddedAddress), nil
+	default:
+		return "", UnknownTokenStandardError{Standard: token.Standard}
+	}
+}
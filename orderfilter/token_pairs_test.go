@@ -0,0 +1,79 @@
+package orderfilter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewForTokenPairs(t *testing.T) {
+	t.Parallel()
+
+	zrx := Token{Standard: ERC20, Address: zrxAddress}
+	weth := Token{Standard: ERC20, Address: wethAddress}
+	dai := Token{Standard: ERC20, Address: daiAddress}
+	kitties := Token{Standard: ERC721, Address: common.HexToAddress("0x06012c8cf97bead5deae237070f9587f8e7a266d")}
+	erc721OrderJSON := []byte(strings.Replace(
+		string(standardValidOrderJSON),
+		`"makerAssetData":"0xf47261b0000000000000000000000000e41d2489571d322189246dafa5ebde1f4699f498"`,
+		`"makerAssetData":"0x0257179200000000000000000000000006012c8cf97bead5deae237070f9587f8e7a266d0000000000000000000000000000000000000000000000000000000000000001"`,
+		1,
+	))
+
+	testCases := []struct {
+		note          string
+		pairs         []TokenPair
+		orderJSON     []byte
+		expectedValid bool
+	}{
+		{"same direction", []TokenPair{{Base: zrx, Quote: weth}}, standardValidOrderJSON, true},
+		{"opposite direction", []TokenPair{{Base: weth, Quote: zrx}}, standardValidOrderJSON, true},
+		{"other pair", []TokenPair{{Base: dai, Quote: weth}}, standardValidOrderJSON, false},
+		{"one of several pairs", []TokenPair{{Base: dai, Quote: weth}, {Base: zrx, Quote: weth}}, standardValidOrderJSON, true},
+		{"erc721", []TokenPair{{Base: kitties, Quote: weth}}, erc721OrderJSON, true},
+		{"erc721 as erc20", []TokenPair{{Base: Token{Standard: ERC20, Address: kitties.Address}, Quote: weth}}, erc721OrderJSON, false},
+	}
+	for _, tc := range testCases {
+		filter, err := NewForTokenPairs(constants.TestChainID, tc.pairs, contractAddresses)
+		require.NoError(t, err, tc.note)
+		result, err := filter.ValidateOrderJSON(tc.orderJSON)
+		require.NoError(t, err, tc.note)
+		assert.Equal(t, tc.expectedValid, result.Valid(), "%s: %v", tc.note, result.Errors())
+	}
+}
+
+func TestCustomOrderSchemaForTokenPairsIsCanonical(t *testing.T) {
+	t.Parallel()
+
+	zrx := Token{Standard: ERC20, Address: zrxAddress}
+	weth := Token{Standard: ERC20, Address: wethAddress}
+	dai := Token{Standard: ERC20, Address: daiAddress}
+	checksummedWETH := Token{Standard: ERC20, Address: common.HexToAddress(strings.ToUpper(wethAddress.Hex()))}
+
+	schema, err := CustomOrderSchemaForTokenPairs([]TokenPair{{Base: zrx, Quote: weth}, {Base: dai, Quote: weth}})
+	require.NoError(t, err)
+	reorderedSchema, err := CustomOrderSchemaForTokenPairs([]TokenPair{{Base: checksummedWETH, Quote: dai}, {Base: weth, Quote: zrx}, {Base: zrx, Quote: weth}})
+	require.NoError(t, err)
+	assert.Equal(t, schema, reorderedSchema)
+
+	// A pair of a token with itself only has one direction.
+	schema, err = CustomOrderSchemaForTokenPairs([]TokenPair{{Base: weth, Quote: weth}})
+	require.NoError(t, err)
+	assert.Equal(t, `{"properties":{"makerAssetData":{"pattern":"^0xf47261b0000000000000000000000000c02aaa39b223fe8d0a0e5c4f27ead9083c756cc2$","type":"string"},"takerAssetData":{"pattern":"^0xf47261b0000000000000000000000000c02aaa39b223fe8d0a0e5c4f27ead9083c756cc2$","type":"string"}}}`, schema)
+}
+
+func TestCustomOrderSchemaForTokenPairsErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := CustomOrderSchemaForTokenPairs(nil)
+	assert.Equal(t, ErrNoTokenPairs, err)
+
+	weth := Token{Standard: ERC20, Address: wethAddress}
+	erc1155 := Token{Standard: "erc1155", Address: daiAddress}
+	_, err = CustomOrderSchemaForTokenPairs([]TokenPair{{Base: weth, Quote: erc1155}})
+	assert.Equal(t, UnknownTokenStandardError{Standard: "erc1155"}, err)
+}
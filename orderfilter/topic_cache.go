@@ -0,0 +1,78 @@
+package orderfilter
+
+import (
+	"sync"
+
+	"github.com/0xProject/0x-mesh/ethereum"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// DefaultTopicFilterCacheSize is the default maximum number of filters which
+// are cached by NewFromTopic.
+const DefaultTopicFilterCacheSize = 128
+
+// topicFilterCacheKey identifies a filter created by NewFromTopic. The
+// contract addresses and the legacy topic policy are part of the key because
+// the filter depends on them. The schema limits are part of the key so that
+// filters accepted under more lenient limits are not returned after the limits
+// were lowered.
+type topicFilterCacheKey struct {
+	topic             string
+	contractAddresses ethereum.ContractAddresses
+	legacyTopicPolicy LegacyTopicPolicy
+	schemaLimits      SchemaLimits
+}
+
+var (
+	topicFilterCacheMu sync.RWMutex
+	// topicFilterCache is nil if the cache is disabled. lru.New only returns an
+	// error if size is <= 0, so we can safely ignore it.
+	topicFilterCache, _ = lru.New(DefaultTopicFilterCacheSize)
+)
+
+// SetTopicFilterCacheSize sets the maximum number of filters which are cached
+// by NewFromTopic. If size is 0, the cache is disabled and every call compiles
+// a new filter. Filters which don't fit into the new size are evicted.
+func SetTopicFilterCacheSize(size int) {
+	topicFilterCacheMu.Lock()
+	defer topicFilterCacheMu.Unlock()
+	if size <= 0 {
+		topicFilterCache = nil
+		return
+	}
+	if topicFilterCache == nil {
+		topicFilterCache, _ = lru.New(size)
+		return
+	}
+	topicFilterCache.Resize(size)
+}
+
+// getCachedTopicFilter returns the cached filter for the given topic and
+// contract addresses, if any.
+func getCachedTopicFilter(key topicFilterCacheKey) (*Filter, bool) {
+	topicFilterCacheMu.RLock()
+	defer topicFilterCacheMu.RUnlock()
+	if topicFilterCache == nil {
+		return nil, false
+	}
+	filter, found := topicFilterCache.Get(key)
+	if !found {
+		return nil, false
+	}
+	return filter.(*Filter), true
+}
+
+// cacheTopicFilter adds the given filter to the cache. Cached filters are
+// shared between callers, so the encoded schema (which is otherwise computed
+// lazily) is computed first to keep the filter from being modified afterwards.
+func cacheTopicFilter(key topicFilterCacheKey, filter *Filter) {
+	if filter.encodedSchema == "" {
+		filter.encodedSchema = filter.generateEncodedSchema()
+	}
+	topicFilterCacheMu.RLock()
+	defer topicFilterCacheMu.RUnlock()
+	if topicFilterCache == nil {
+		return
+	}
+	topicFilterCache.Add(key, filter)
+}
@@ -3,22 +3,204 @@
 package orderfilter
 
 import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
 	"github.com/0xProject/0x-mesh/zeroex"
 	jsonschema "github.com/xeipuuv/gojsonschema"
 )
 
+// ValidateOrderJSON validates a JSON encoded signed order. Valid orders are
+// only checked by the native validator. The full order schema is used to
+// describe why an order is invalid.
 func (f *Filter) ValidateOrderJSON(orderJSON []byte) (*jsonschema.Result, error) {
-	return f.orderSchema.Validate(jsonschema.NewBytesLoader(orderJSON))
+	result, err := f.validateOrderJSON(orderJSON)
+	if err != nil {
+		f.metrics.recordOrder(false, "", err)
+		return nil, err
+	}
+	if result.Valid() {
+		f.metrics.recordOrder(true, "", nil)
+	} else {
+		f.metrics.recordOrder(false, rejectionReasonForFieldErrors(FieldErrors(orderJSON, result)), nil)
+	}
+	return result, nil
+}
+
+func (f *Filter) validateOrderJSON(orderJSON []byte) (*jsonschema.Result, error) {
+	var result *jsonschema.Result
+	if valid, err := f.validator.isValidOrderJSON(orderJSON); err == nil && valid {
+		result = &jsonschema.Result{}
+	} else {
+		schemaOrderJSON := orderJSON
+		if f.decodesAssetData {
+			schemaOrderJSON = withDecodedAssetData(orderJSON)
+		}
+		result, err = f.orderSchema.Validate(jsonschema.NewBytesLoader(schemaOrderJSON))
+		if err != nil {
+			return nil, err
+		}
+	}
+	f.addMaxExpirationError(orderJSON, result)
+	return result, nil
+}
+
+// maxExpirationError is the jsonschema.ResultError added to the result of
+// ValidateOrderJSON if the order expires later than the filter allows.
+type maxExpirationError struct {
+	jsonschema.ResultErrorFields
+}
+
+// maxExpirationErrorType is the type of maxExpirationErrors.
+const maxExpirationErrorType = "expires_within"
+
+// addMaxExpirationError adds a maxExpirationError to the result if the given
+// order expires later than the filter allows.
+func (f *Filter) addMaxExpirationError(orderJSON []byte, result *jsonschema.Result) {
+	if f.maxExpirationDuration == 0 {
+		return
+	}
+	now := time.Now()
+	expirationTimeSeconds := f.expirationTimeFromOrderJSON(orderJSON)
+	if !f.exceedsMaxExpiration(expirationTimeSeconds, now) {
+		return
+	}
+	resultErr := &maxExpirationError{}
+	resultErr.SetType(maxExpirationErrorType)
+	resultErr.SetContext(jsonschema.NewJsonContext(expirationTimeField(f.protocolVersion), jsonschema.NewJsonContext("(root)", nil)))
+	resultErr.SetValue(expirationTimeSeconds.String())
+	resultErr.SetDescriptionFormat(f.maxExpirationMessage())
+	result.AddError(resultErr, jsonschema.ErrorDetails{"max": f.maxExpirationTime(now).String()})
+}
+
+// gojsonschemaConstraints maps the types of the errors returned by gojsonschema
+// to the JSON schema keywords which were violated.
+var gojsonschemaConstraints = map[string]string{
+	"required":                        "required",
+	"invalid_type":                    "type",
+	"const":                           "const",
+	"enum":                            "enum",
+	"does_not_match_pattern":          "pattern",
+	"format":                          "format",
+	"string_gte":                      "minLength",
+	"string_lte":                      "maxLength",
+	"number_gte":                      "minimum",
+	"number_gt":                       "exclusiveMinimum",
+	"number_lte":                      "maximum",
+	"number_lt":                       "exclusiveMaximum",
+	"multiple_of":                     "multipleOf",
+	"number_any_of":                   "anyOf",
+	"number_one_of":                   "oneOf",
+	"number_all_of":                   "allOf",
+	"number_not":                      "not",
+	"additional_property_not_allowed": "additionalProperties",
+	"array_min_items":                 "minItems",
+	"array_max_items":                 "maxItems",
+	maxExpirationErrorType:            MaxExpirationConstraint,
+}
+
+// gojsonschemaLimitKeys are the keys of the error details returned by
+// gojsonschema which hold the limit imposed by the violated constraint.
+var gojsonschemaLimitKeys = []string{"pattern", "allowed", "expected", "min", "max", "multiple", "format"}
+
+// FieldErrors converts the errors in the result of ValidateOrderJSON into
+// FieldErrors.
+func FieldErrors(orderJSON []byte, result *jsonschema.Result) []*FieldError {
+	fieldErrors := make([]*FieldError, 0, len(result.Errors()))
+	for _, resultErr := range result.Errors() {
+		details := resultErr.Details()
+		// Contexts are formatted as "(root)/makerAddress".
+		pointer := decodedAssetDataPointer(strings.TrimPrefix(resultErr.Context().String("/"), "(root)"))
+		switch resultErr.Type() {
+		case "required", "additional_property_not_allowed":
+			if property, ok := details["property"].(string); ok {
+				pointer = appendJSONPointerToken(pointer, property)
+			}
+		}
+		constraint, found := gojsonschemaConstraints[resultErr.Type()]
+		if !found {
+			constraint = resultErr.Type()
+		}
+		fieldError := &FieldError{
+			Pointer:    pointer,
+			Constraint: constraint,
+			Message:    resultErr.Description(),
+		}
+		for _, key := range gojsonschemaLimitKeys {
+			if limit, found := details[key]; found {
+				fieldError.Expected = normalizeGojsonschemaLimit(limit)
+				break
+			}
+		}
+		fieldErrors = append(fieldErrors, fieldError)
+	}
+	setActualValues(orderJSON, fieldErrors)
+	return fieldErrors
+}
+
+// normalizeGojsonschemaLimit converts limits which gojsonschema stores as
+// compiled patterns or rational numbers into values that can be encoded as
+// JSON.
+func normalizeGojsonschemaLimit(limit interface{}) interface{} {
+	switch limit := limit.(type) {
+	case *big.Rat:
+		if limit.IsInt() {
+			return json.Number(limit.Num().String())
+		}
+		return json.Number(limit.FloatString(18))
+	case fmt.Stringer:
+		return limit.String()
+	default:
+		return limit
+	}
 }
 
 func (f *Filter) MatchOrderMessageJSON(messageJSON []byte) (bool, error) {
-	result, err := f.messageSchema.Validate(jsonschema.NewBytesLoader(messageJSON))
-	if err != nil {
+	valid, err := f.validator.isValidOrderMessageJSON(messageJSON)
+	if err != nil || !valid {
+		f.metrics.recordMessage(false, RejectionReasonSchema, err)
 		return false, err
 	}
-	return result.Valid(), nil
+	if f.orderMessageExceedsMaxExpiration(messageJSON, time.Now()) {
+		f.metrics.recordMessage(false, RejectionReasonMaxExpiration, nil)
+		return false, nil
+	}
+	f.metrics.recordMessage(true, "", nil)
+	return true, nil
+}
+
+// customOrderSchemaRejectReasons returns the reasons why the given order
+// doesn't pass the custom order schema or nil if it does. The order is only
+// encoded if the custom order schema doesn't accept all orders.
+func (f *Filter) customOrderSchemaRejectReasons(order *zeroex.SignedOrder) ([]string, error) {
+	if f.validator.customOrderSchema == nil {
+		return nil, nil
+	}
+	orderJSON, err := zeroex.MarshalSignedOrderWire(order)
+	if err != nil {
+		return nil, err
+	}
+	schemaOrderJSON := orderJSON
+	if f.decodesAssetData {
+		schemaOrderJSON = withDecodedAssetData(orderJSON)
+	}
+	result, err := f.validator.customOrderSchema.Validate(jsonschema.NewBytesLoader(schemaOrderJSON))
+	if err != nil {
+		return nil, err
+	}
+	if result.Valid() {
+		return nil, nil
+	}
+	return rejectReasonsForFieldErrors(FieldErrors(orderJSON, result)), nil
 }
 
 func (f *Filter) ValidateOrder(order *zeroex.SignedOrder) (*jsonschema.Result, error) {
-	return f.orderSchema.Validate(jsonschema.NewGoLoader(order))
+	orderJSON, err := zeroex.MarshalSignedOrderWire(order)
+	if err != nil {
+		return nil, err
+	}
+	return f.ValidateOrderJSON(orderJSON)
 }
@@ -3,7 +3,11 @@
 package orderfilter
 
 import (
+	"encoding/json"
 	"errors"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/0xProject/0x-mesh/packages/browser/go/jsutil"
 	"github.com/0xProject/0x-mesh/zeroex"
@@ -33,7 +37,25 @@ func (s *SchemaValidationResult) Errors() []*SchemaValidationError {
 // ValidateOrderJSON Validates a JSON encoded signed order using the AJV javascript library.
 // This libarary is used to increase the performance of Mesh nodes that run in the browser.
 func (f *Filter) ValidateOrderJSON(orderJSON []byte) (*SchemaValidationResult, error) {
-	jsResult := f.orderValidator.Invoke(string(orderJSON))
+	result, err := f.validateOrderJSON(orderJSON)
+	if err != nil {
+		f.metrics.recordOrder(false, "", err)
+		return nil, err
+	}
+	if result.Valid() {
+		f.metrics.recordOrder(true, "", nil)
+	} else {
+		f.metrics.recordOrder(false, rejectionReasonForFieldErrors(FieldErrors(orderJSON, result)), nil)
+	}
+	return result, nil
+}
+
+func (f *Filter) validateOrderJSON(orderJSON []byte) (*SchemaValidationResult, error) {
+	schemaOrderJSON := orderJSON
+	if f.decodesAssetData {
+		schemaOrderJSON = withDecodedAssetData(orderJSON)
+	}
+	jsResult := f.orderValidator.Invoke(string(schemaOrderJSON))
 	fatal := jsResult.Get("fatal")
 	if !jsutil.IsNullOrUndefined(fatal) {
 		return nil, errors.New(fatal.String())
@@ -44,20 +66,151 @@ func (f *Filter) ValidateOrderJSON(orderJSON []byte) (*SchemaValidationResult, e
 	for i := 0; i < jsErrors.Length(); i++ {
 		convertedErrors = append(convertedErrors, &SchemaValidationError{errors.New(jsErrors.Index(i).String())})
 	}
-	return &SchemaValidationResult{valid: valid, errors: convertedErrors}, nil
+	result := &SchemaValidationResult{valid: valid, errors: convertedErrors}
+	if err := f.addMaxExpirationError(orderJSON, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// addMaxExpirationError adds an error to the result if the given order expires
+// later than the filter allows. The error is encoded like the errors returned
+// by AJV, so that FieldErrors can convert it.
+func (f *Filter) addMaxExpirationError(orderJSON []byte, result *SchemaValidationResult) error {
+	if f.maxExpirationDuration == 0 {
+		return nil
+	}
+	now := time.Now()
+	if !f.exceedsMaxExpiration(f.expirationTimeFromOrderJSON(orderJSON), now) {
+		return nil
+	}
+	encoded, err := json.Marshal(ajvError{
+		Keyword:  MaxExpirationConstraint,
+		DataPath: "." + expirationTimeField(f.protocolVersion),
+		Params:   map[string]interface{}{"limit": f.maxExpirationTime(now).String()},
+		Message:  f.maxExpirationMessage(),
+	})
+	if err != nil {
+		return err
+	}
+	result.valid = false
+	result.errors = append(result.errors, &SchemaValidationError{errors.New(string(encoded))})
+	return nil
+}
+
+// ajvError is the JSON encoding of an error returned by AJV.
+type ajvError struct {
+	Keyword  string                 `json:"keyword"`
+	DataPath string                 `json:"dataPath"`
+	Params   map[string]interface{} `json:"params"`
+	Message  string                 `json:"message"`
+}
+
+// ajvLimitParams are the params of AJV errors which hold the limit imposed by
+// the violated constraint.
+var ajvLimitParams = []string{"pattern", "allowedValues", "allowedValue", "type", "limit", "multipleOf", "format"}
+
+// ajvDataPathSegmentRegex matches the segments of an AJV data path, e.g.
+// ".makerAddress", "[0]" or "['weird/key']".
+var ajvDataPathSegmentRegex = regexp.MustCompile(`\.([^.\[]+)|\[(\d+)\]|\['((?:[^'\\]|\\.)*)'\]`)
+
+// ajvDataPathToJSONPointer converts an AJV data path into a JSON pointer.
+func ajvDataPathToJSONPointer(dataPath string) string {
+	pointer := ""
+	for _, match := range ajvDataPathSegmentRegex.FindAllStringSubmatch(dataPath, -1) {
+		switch {
+		case match[1] != "":
+			pointer = appendJSONPointerToken(pointer, match[1])
+		case match[2] != "":
+			pointer = appendJSONPointerToken(pointer, match[2])
+		default:
+			pointer = appendJSONPointerToken(pointer, strings.Replace(match[3], "\\'", "'", -1))
+		}
+	}
+	return pointer
+}
+
+// FieldErrors converts the errors in the result of ValidateOrderJSON into
+// FieldErrors. Errors which were not returned by AJV are skipped.
+func FieldErrors(orderJSON []byte, result *SchemaValidationResult) []*FieldError {
+	fieldErrors := make([]*FieldError, 0, len(result.Errors()))
+	for _, resultErr := range result.Errors() {
+		var decoded ajvError
+		if err := json.Unmarshal([]byte(resultErr.String()), &decoded); err != nil {
+			continue
+		}
+		pointer := decodedAssetDataPointer(ajvDataPathToJSONPointer(decoded.DataPath))
+		switch decoded.Keyword {
+		case "required":
+			if property, ok := decoded.Params["missingProperty"].(string); ok {
+				pointer = appendJSONPointerToken(pointer, property)
+			}
+		case "additionalProperties":
+			if property, ok := decoded.Params["additionalProperty"].(string); ok {
+				pointer = appendJSONPointerToken(pointer, property)
+			}
+		}
+		fieldError := &FieldError{
+			Pointer:    pointer,
+			Constraint: decoded.Keyword,
+			Message:    decoded.Message,
+		}
+		for _, key := range ajvLimitParams {
+			if limit, found := decoded.Params[key]; found {
+				fieldError.Expected = limit
+				break
+			}
+		}
+		fieldErrors = append(fieldErrors, fieldError)
+	}
+	setActualValues(orderJSON, fieldErrors)
+	return fieldErrors
 }
 
 func (f *Filter) MatchOrderMessageJSON(messageJSON []byte) (bool, error) {
-	jsResult := f.messageValidator.Invoke(string(messageJSON))
+	schemaMessageJSON := messageJSON
+	if f.decodesAssetData {
+		schemaMessageJSON = withDecodedAssetDataInMessage(messageJSON)
+	}
+	jsResult := f.messageValidator.Invoke(string(schemaMessageJSON))
 	fatal := jsResult.Get("fatal")
 	if !jsutil.IsNullOrUndefined(fatal) {
-		return false, errors.New(fatal.String())
+		err := errors.New(fatal.String())
+		f.metrics.recordMessage(false, "", err)
+		return false, err
+	}
+	if !jsResult.Get("success").Bool() {
+		f.metrics.recordMessage(false, RejectionReasonSchema, nil)
+		return false, nil
+	}
+	if f.orderMessageExceedsMaxExpiration(messageJSON, time.Now()) {
+		f.metrics.recordMessage(false, RejectionReasonMaxExpiration, nil)
+		return false, nil
+	}
+	f.metrics.recordMessage(true, "", nil)
+	return true, nil
+}
+
+// customOrderSchemaRejectReasons returns the reasons why the given order
+// doesn't pass the custom order schema or nil if it does. AJV only compiles the
+// full order schema, so the order is always encoded and validated against it.
+func (f *Filter) customOrderSchemaRejectReasons(order *zeroex.SignedOrder) ([]string, error) {
+	orderJSON, err := zeroex.MarshalSignedOrderWire(order)
+	if err != nil {
+		return nil, err
+	}
+	result, err := f.validateOrderJSON(orderJSON)
+	if err != nil {
+		return nil, err
+	}
+	if result.Valid() {
+		return nil, nil
 	}
-	return jsResult.Get("success").Bool(), nil
+	return rejectReasonsForFieldErrors(FieldErrors(orderJSON, result)), nil
 }
 
 func (f *Filter) ValidateOrder(order *zeroex.SignedOrder) (*SchemaValidationResult, error) {
-	orderJSON, err := order.MarshalJSON()
+	orderJSON, err := zeroex.MarshalSignedOrderWire(order)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,206 @@
+package banner
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	filter "github.com/libp2p/go-maddr-filter"
+	ma "github.com/multiformats/go-multiaddr"
+	log "github.com/sirupsen/logrus"
+)
+
+// IPRangeBan is a ban on a range of IP addresses. Like peer bans, IP range
+// bans are managed by the node operator.
+type IPRangeBan struct {
+	IPRange net.IPNet
+	Reason  string
+}
+
+// AllowlistEntry is an entry of the allowlist. It allows either the peer with
+// the given PeerID or all peers with an IP address in IPRange to connect.
+type AllowlistEntry struct {
+	PeerID  peer.ID
+	IPRange *net.IPNet
+}
+
+// String returns the peer ID or the IP range (in CIDR notation) of the entry.
+// It can be parsed with ParseAllowlistEntry.
+func (e AllowlistEntry) String() string {
+	if e.IPRange != nil {
+		return e.IPRange.String()
+	}
+	return e.PeerID.Pretty()
+}
+
+// ParseIPRange parses an IP range in CIDR notation (e.g. "10.0.0.0/8") or a
+// single IP address, which is treated as a range which only contains that
+// address.
+func ParseIPRange(s string) (net.IPNet, error) {
+	if ip := net.ParseIP(s); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		return net.IPNet{IP: ip, Mask: getAllMaskForIP(ip)}, nil
+	}
+	_, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		return net.IPNet{}, fmt.Errorf("invalid IP range: %q", s)
+	}
+	return *ipNet, nil
+}
+
+// ParseAllowlistEntry parses a peer ID or an IP range (see ParseIPRange).
+func ParseAllowlistEntry(s string) (AllowlistEntry, error) {
+	if ipRange, err := ParseIPRange(s); err == nil {
+		return AllowlistEntry{IPRange: &ipRange}, nil
+	}
+	peerID, err := peer.IDB58Decode(s)
+	if err != nil {
+		return AllowlistEntry{}, fmt.Errorf("allowlist entry must be a peer ID or an IP range: %q", s)
+	}
+	return AllowlistEntry{PeerID: peerID}, nil
+}
+
+// BanIPRange bans all IP addresses in the given range and closes any open
+// connections to them. The node will no longer dial or accept connections
+// from these addresses. If the range is already banned, the existing ban is
+// replaced. It returns ErrProtectedIP without banning anything if the range
+// contains a protected IP address (e.g. of a bootstrap node).
+func (banner *Banner) BanIPRange(ban IPRangeBan) error {
+	banner.protectedIPsMut.RLock()
+	for _, protectedIP := range banner.protectedIPs.Slice() {
+		if ban.IPRange.Contains(net.ParseIP(protectedIP)) {
+			banner.protectedIPsMut.RUnlock()
+			return ErrProtectedIP
+		}
+	}
+	banner.protectedIPsMut.RUnlock()
+
+	banner.ipRangeBansMut.Lock()
+	banner.unbanIPNet(ban.IPRange)
+	banner.config.Filters.AddFilter(ban.IPRange, filter.ActionDeny)
+	banner.ipRangeBans[ban.IPRange.String()] = ban
+	banner.ipRangeBansMut.Unlock()
+	log.WithFields(log.Fields{
+		"ipRange": ban.IPRange.String(),
+		"reason":  ban.Reason,
+	}).Info("banning IP range")
+	banner.closeConns(func(conn network.Conn) bool {
+		return ipRangeContainsMaddr(ban.IPRange, conn.RemoteMultiaddr())
+	})
+	return nil
+}
+
+// UnbanIPRange lifts the ban on the given IP range. It returns false if the
+// range was not banned with BanIPRange. IP addresses which were banned
+// individually (e.g. due to high bandwidth usage) are not affected.
+func (banner *Banner) UnbanIPRange(ipRange net.IPNet) bool {
+	banner.ipRangeBansMut.Lock()
+	defer banner.ipRangeBansMut.Unlock()
+	if _, found := banner.ipRangeBans[ipRange.String()]; !found {
+		return false
+	}
+	delete(banner.ipRangeBans, ipRange.String())
+	banner.unbanIPNet(ipRange)
+	return true
+}
+
+// IPRangeBans returns all IP range bans sorted by IP range.
+func (banner *Banner) IPRangeBans() []IPRangeBan {
+	banner.ipRangeBansMut.RLock()
+	defer banner.ipRangeBansMut.RUnlock()
+	bans := make([]IPRangeBan, 0, len(banner.ipRangeBans))
+	for _, ban := range banner.ipRangeBans {
+		bans = append(bans, ban)
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].IPRange.String() < bans[j].IPRange.String()
+	})
+	return bans
+}
+
+// AddToAllowlist adds the given entry to the allowlist. As long as the
+// allowlist is not empty, the node only dials and accepts connections from
+// peers which are on it, and connections to any other peers are closed. Note
+// that this includes bootstrap nodes and relays.
+func (banner *Banner) AddToAllowlist(entry AllowlistEntry) {
+	banner.allowlistMut.Lock()
+	banner.allowlist[entry.String()] = entry
+	banner.allowlistMut.Unlock()
+	log.WithField("entry", entry.String()).Info("added entry to allowlist")
+	banner.closeConns(func(conn network.Conn) bool {
+		return !banner.IsAllowed(conn.RemotePeer(), conn.RemoteMultiaddr())
+	})
+}
+
+// RemoveFromAllowlist removes the given entry from the allowlist. It returns
+// false if the entry was not on the allowlist. Open connections to peers which
+// are no longer allowed are closed.
+func (banner *Banner) RemoveFromAllowlist(entry AllowlistEntry) bool {
+	banner.allowlistMut.Lock()
+	_, found := banner.allowlist[entry.String()]
+	delete(banner.allowlist, entry.String())
+	banner.allowlistMut.Unlock()
+	if found {
+		banner.closeConns(func(conn network.Conn) bool {
+			return !banner.IsAllowed(conn.RemotePeer(), conn.RemoteMultiaddr())
+		})
+	}
+	return found
+}
+
+// Allowlist returns all entries of the allowlist sorted by their string
+// representation.
+func (banner *Banner) Allowlist() []AllowlistEntry {
+	banner.allowlistMut.RLock()
+	defer banner.allowlistMut.RUnlock()
+	entries := make([]AllowlistEntry, 0, len(banner.allowlist))
+	for _, entry := range banner.allowlist {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].String() < entries[j].String()
+	})
+	return entries
+}
+
+// IsAllowed returns true if the allowlist is empty, or if the given peer ID or
+// the IP address of the given multiaddress is on it. maddr can be nil if the
+// address of the peer is unknown.
+func (banner *Banner) IsAllowed(peerID peer.ID, maddr ma.Multiaddr) bool {
+	banner.allowlistMut.RLock()
+	defer banner.allowlistMut.RUnlock()
+	if len(banner.allowlist) == 0 {
+		return true
+	}
+	for _, entry := range banner.allowlist {
+		if entry.IPRange == nil {
+			if entry.PeerID == peerID {
+				return true
+			}
+		} else if maddr != nil && ipRangeContainsMaddr(*entry.IPRange, maddr) {
+			return true
+		}
+	}
+	return false
+}
+
+// closeConns closes all open connections for which shouldClose returns true.
+func (banner *Banner) closeConns(shouldClose func(conn network.Conn) bool) {
+	for _, conn := range banner.config.Host.Network().Conns() {
+		if shouldClose(conn) {
+			_ = conn.Close()
+		}
+	}
+}
+
+func ipRangeContainsMaddr(ipRange net.IPNet, maddr ma.Multiaddr) bool {
+	ip, err := ipFromMaddr(maddr)
+	if err != nil {
+		return false
+	}
+	return ipRange.Contains(ip)
+}
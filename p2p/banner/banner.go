@@ -11,6 +11,7 @@ import (
 	"github.com/albrow/stringset"
 	"github.com/libp2p/go-libp2p-core/host"
 	"github.com/libp2p/go-libp2p-core/metrics"
+	"github.com/libp2p/go-libp2p-core/peer"
 	filter "github.com/libp2p/go-maddr-filter"
 	ma "github.com/multiformats/go-multiaddr"
 	log "github.com/sirupsen/logrus"
@@ -37,6 +38,12 @@ type Banner struct {
 	protectedIPsMut sync.RWMutex
 	protectedIPs    stringset.Set
 	violations      *violationsTracker
+	peerBansMut     sync.RWMutex
+	peerBans        map[peer.ID]PeerBan
+	ipRangeBansMut  sync.RWMutex
+	ipRangeBans     map[string]IPRangeBan
+	allowlistMut    sync.RWMutex
+	allowlist       map[string]AllowlistEntry
 }
 
 type Config struct {
@@ -52,6 +59,9 @@ func New(ctx context.Context, config Config) *Banner {
 		config:       config,
 		protectedIPs: stringset.New(),
 		violations:   newViolationsTracker(ctx),
+		peerBans:     map[peer.ID]PeerBan{},
+		ipRangeBans:  map[string]IPRangeBan{},
+		allowlist:    map[string]AllowlistEntry{},
 	}
 	if config.LogBandwidthUsageStats {
 		go banner.continuouslyLogBandwidthUsage(ctx)
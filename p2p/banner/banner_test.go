@@ -3,7 +3,9 @@ package banner
 import (
 	"net"
 	"testing"
+	"time"
 
+	"github.com/libp2p/go-libp2p-core/peer"
 	ma "github.com/multiformats/go-multiaddr"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -56,3 +58,124 @@ func newMaddr(t *testing.T, s string) ma.Multiaddr {
 	require.NoError(t, err)
 	return maddr
 }
+
+func TestPeerBans(t *testing.T) {
+	banner := &Banner{
+		peerBans: map[peer.ID]PeerBan{},
+	}
+	now := time.Now()
+	permanentBan := PeerBan{
+		PeerID: peer.ID("peer-a"),
+		Reason: "spam",
+	}
+	temporaryBan := PeerBan{
+		PeerID: peer.ID("peer-b"),
+		Reason: "invalid orders",
+		Expiry: now.Add(time.Hour),
+	}
+	expiredBan := PeerBan{
+		PeerID: peer.ID("peer-c"),
+		Reason: "bandwidth",
+		Expiry: now.Add(-time.Hour),
+	}
+	for _, ban := range []PeerBan{temporaryBan, permanentBan, expiredBan} {
+		banner.peerBans[ban.PeerID] = ban
+	}
+
+	assert.False(t, permanentBan.IsExpired(now))
+	assert.False(t, temporaryBan.IsExpired(now))
+	assert.True(t, expiredBan.IsExpired(now))
+
+	assert.True(t, banner.IsPeerBanned(permanentBan.PeerID))
+	assert.True(t, banner.IsPeerBanned(temporaryBan.PeerID))
+	assert.False(t, banner.IsPeerBanned(expiredBan.PeerID))
+	assert.False(t, banner.IsPeerBanned(peer.ID("peer-d")))
+	assert.Equal(t, []PeerBan{permanentBan, temporaryBan}, banner.PeerBans())
+
+	assert.True(t, banner.UnbanPeer(permanentBan.PeerID))
+	assert.False(t, banner.UnbanPeer(permanentBan.PeerID))
+	assert.False(t, banner.IsPeerBanned(permanentBan.PeerID))
+	assert.Equal(t, []PeerBan{temporaryBan}, banner.PeerBans())
+}
+
+func TestParseAllowlistEntry(t *testing.T) {
+	peerID, err := peer.IDB58Decode("16Uiu2HAm9brLYhoM1wCTRtGRR7ZqXhk8kfEt6a2rSFSZpeV8eB7L")
+	require.NoError(t, err)
+	testCases := []struct {
+		input            string
+		expectedPeerID   peer.ID
+		expectedIPRange  string
+		expectedToString string
+	}{
+		{
+			input:            "16Uiu2HAm9brLYhoM1wCTRtGRR7ZqXhk8kfEt6a2rSFSZpeV8eB7L",
+			expectedPeerID:   peerID,
+			expectedToString: "16Uiu2HAm9brLYhoM1wCTRtGRR7ZqXhk8kfEt6a2rSFSZpeV8eB7L",
+		},
+		{
+			input:            "10.0.0.0/8",
+			expectedIPRange:  "10.0.0.0/8",
+			expectedToString: "10.0.0.0/8",
+		},
+		{
+			input:            "10.1.2.3/8",
+			expectedIPRange:  "10.0.0.0/8",
+			expectedToString: "10.0.0.0/8",
+		},
+		{
+			input:            "159.65.4.82",
+			expectedIPRange:  "159.65.4.82/32",
+			expectedToString: "159.65.4.82/32",
+		},
+		{
+			input:            "fe80::1",
+			expectedIPRange:  "fe80::1/128",
+			expectedToString: "fe80::1/128",
+		},
+	}
+	for i, tc := range testCases {
+		entry, err := ParseAllowlistEntry(tc.input)
+		require.NoError(t, err, "test case %d (%s)", i, tc.input)
+		assert.Equal(t, tc.expectedToString, entry.String(), "test case %d (%s)", i, tc.input)
+		if tc.expectedIPRange == "" {
+			assert.Nil(t, entry.IPRange, "test case %d (%s)", i, tc.input)
+			assert.Equal(t, tc.expectedPeerID, entry.PeerID, "test case %d (%s)", i, tc.input)
+		} else {
+			require.NotNil(t, entry.IPRange, "test case %d (%s)", i, tc.input)
+			assert.Equal(t, tc.expectedIPRange, entry.IPRange.String(), "test case %d (%s)", i, tc.input)
+		}
+	}
+
+	for _, invalid := range []string{"", "10.0.0.0/33", "not a peer ID"} {
+		_, err := ParseAllowlistEntry(invalid)
+		assert.Error(t, err, "input: %q", invalid)
+	}
+}
+
+func TestIsAllowed(t *testing.T) {
+	banner := &Banner{
+		allowlist: map[string]AllowlistEntry{},
+	}
+	peerA := peer.ID("peer-a")
+	peerB := peer.ID("peer-b")
+	maddrInRange := newMaddr(t, "/ip4/10.1.2.3/tcp/60558")
+	maddrOutOfRange := newMaddr(t, "/ip4/159.65.4.82/tcp/60558")
+
+	// All peers are allowed while the allowlist is empty.
+	assert.True(t, banner.IsAllowed(peerB, maddrOutOfRange))
+	assert.True(t, banner.IsAllowed(peerB, nil))
+
+	peerEntry := AllowlistEntry{PeerID: peerA}
+	ipRange, err := ParseIPRange("10.0.0.0/8")
+	require.NoError(t, err)
+	ipRangeEntry := AllowlistEntry{IPRange: &ipRange}
+	for _, entry := range []AllowlistEntry{peerEntry, ipRangeEntry} {
+		banner.allowlist[entry.String()] = entry
+	}
+	assert.True(t, banner.IsAllowed(peerA, maddrOutOfRange))
+	assert.True(t, banner.IsAllowed(peerA, nil))
+	assert.True(t, banner.IsAllowed(peerB, maddrInRange))
+	assert.False(t, banner.IsAllowed(peerB, maddrOutOfRange))
+	assert.False(t, banner.IsAllowed(peerB, nil))
+	assert.Equal(t, []AllowlistEntry{ipRangeEntry, peerEntry}, banner.Allowlist())
+}
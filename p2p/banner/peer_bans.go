@@ -0,0 +1,84 @@
+package banner
+
+import (
+	"sort"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	log "github.com/sirupsen/logrus"
+)
+
+// PeerBan is a ban on a specific peer ID. Unlike IP bans, peer bans are
+// typically managed by the node operator and include a human-readable reason.
+type PeerBan struct {
+	PeerID peer.ID
+	Reason string
+	// Expiry is the time at which the ban is lifted. A zero Expiry means the ban
+	// never expires.
+	Expiry time.Time
+}
+
+// IsExpired returns true if the ban has an expiry which is not after now.
+func (b PeerBan) IsExpired(now time.Time) bool {
+	return !b.Expiry.IsZero() && !b.Expiry.After(now)
+}
+
+// BanPeer bans the given peer ID and closes any open connections to it. If the
+// peer is already banned, the existing ban is replaced. Banned peers are
+// disconnected as soon as they connect and messages they authored or forwarded
+// are dropped.
+func (banner *Banner) BanPeer(ban PeerBan) {
+	banner.peerBansMut.Lock()
+	banner.peerBans[ban.PeerID] = ban
+	banner.peerBansMut.Unlock()
+	log.WithFields(log.Fields{
+		"remotePeerID": ban.PeerID.String(),
+		"reason":       ban.Reason,
+		"expiry":       ban.Expiry,
+	}).Info("banning peer")
+	_ = banner.config.Host.Network().ClosePeer(ban.PeerID)
+}
+
+// UnbanPeer lifts the ban on the given peer ID. It returns false if the peer
+// was not banned.
+func (banner *Banner) UnbanPeer(peerID peer.ID) bool {
+	banner.peerBansMut.Lock()
+	defer banner.peerBansMut.Unlock()
+	_, found := banner.peerBans[peerID]
+	delete(banner.peerBans, peerID)
+	return found
+}
+
+// IsPeerBanned returns true if there is an active (i.e. not expired) ban for
+// the given peer ID.
+func (banner *Banner) IsPeerBanned(peerID peer.ID) bool {
+	banner.peerBansMut.RLock()
+	ban, found := banner.peerBans[peerID]
+	banner.peerBansMut.RUnlock()
+	if !found {
+		return false
+	}
+	if ban.IsExpired(time.Now()) {
+		banner.UnbanPeer(peerID)
+		return false
+	}
+	return true
+}
+
+// PeerBans returns all active peer bans sorted by peer ID.
+func (banner *Banner) PeerBans() []PeerBan {
+	banner.peerBansMut.RLock()
+	defer banner.peerBansMut.RUnlock()
+	now := time.Now()
+	bans := make([]PeerBan, 0, len(banner.peerBans))
+	for _, ban := range banner.peerBans {
+		if ban.IsExpired(now) {
+			continue
+		}
+		bans = append(bans, ban)
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].PeerID < bans[j].PeerID
+	})
+	return bans
+}
@@ -2,6 +2,7 @@ package p2p
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
@@ -51,6 +52,55 @@ var DefaultBootstrapList = []string{
 	"/ip4/18.204.221.103/tcp/4001/ipfs/12D3KooWQS6Gsr2kLZvF7DVtoRFtj24aar5jvz88LvJePrawM3EM",
 }
 
+// Bootstrap environments
+const (
+	// BootstrapEnvironmentProduction uses the bootstrap nodes run by 0x.
+	BootstrapEnvironmentProduction = "production"
+	// BootstrapEnvironmentDevelopment has no hard-coded bootstrap peers. It is
+	// meant for local and private networks, which provide their own bootstrap
+	// list.
+	BootstrapEnvironmentDevelopment = "development"
+)
+
+// bootstrapListKey identifies a hard-coded bootstrap list. A chainID of 0
+// matches any chain.
+type bootstrapListKey struct {
+	chainID     int
+	environment string
+}
+
+// bootstrapLists are the hard-coded bootstrap lists. The 0x bootstrap nodes
+// serve all chains, so the production list is used for any chain unless a
+// list for the specific chain is added.
+var bootstrapLists = map[bootstrapListKey][]string{
+	{chainID: 0, environment: BootstrapEnvironmentProduction}:  DefaultBootstrapList,
+	{chainID: 0, environment: BootstrapEnvironmentDevelopment}: {},
+}
+
+// ErrUnknownBootstrapEnvironment is returned by GetBootstrapList if there is no
+// bootstrap list for the given environment.
+type ErrUnknownBootstrapEnvironment struct {
+	Environment string
+}
+
+func (e ErrUnknownBootstrapEnvironment) Error() string {
+	return fmt.Sprintf("unknown bootstrap environment: %q (must be %q or %q)", e.Environment, BootstrapEnvironmentProduction, BootstrapEnvironmentDevelopment)
+}
+
+// GetBootstrapList returns the hard-coded bootstrap list for the given chain
+// ID and environment. A list for the specific chain takes precedence over the
+// list for any chain. The returned list may be empty.
+func GetBootstrapList(chainID int, environment string) ([]string, error) {
+	list, found := bootstrapLists[bootstrapListKey{chainID: chainID, environment: environment}]
+	if !found {
+		list, found = bootstrapLists[bootstrapListKey{chainID: 0, environment: environment}]
+	}
+	if !found {
+		return nil, ErrUnknownBootstrapEnvironment{Environment: environment}
+	}
+	return append([]string{}, list...), nil
+}
+
 func BootstrapListToAddrInfos(bootstrapList []string) ([]peer.AddrInfo, error) {
 	maddrs := make([]ma.Multiaddr, len(bootstrapList))
 	for i, addrString := range bootstrapList {
@@ -0,0 +1,259 @@
+package p2p
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// maxSignedBootstrapListSize is the maximum size of a signed bootstrap list
+	// which is fetched from a URL.
+	maxSignedBootstrapListSize = 1024 * 1024
+	// defaultBootstrapListRefreshInterval is the default value for
+	// SignedBootstrapListConfig.RefreshInterval.
+	defaultBootstrapListRefreshInterval = 1 * time.Hour
+)
+
+// ErrInvalidBootstrapListSignature is returned by SignedBootstrapList.Verify if
+// the list was not signed with the expected key.
+var ErrInvalidBootstrapListSignature = errors.New("signed bootstrap list has an invalid signature")
+
+// ErrBootstrapListMismatch is returned by SignedBootstrapList.Verify if the
+// list is for a different chain or environment than expected.
+type ErrBootstrapListMismatch struct {
+	ChainID     int
+	Environment string
+}
+
+func (e ErrBootstrapListMismatch) Error() string {
+	return fmt.Sprintf("signed bootstrap list is for chain ID %d and environment %q", e.ChainID, e.Environment)
+}
+
+// SignedBootstrapListConfig configures the signed bootstrap lists which a Node
+// fetches periodically (see Config.SignedBootstrapLists).
+type SignedBootstrapListConfig struct {
+	// URLs are the URLs to fetch signed bootstrap lists from. All of them are
+	// fetched and the newest valid list is used, so they can serve as mirrors
+	// of each other.
+	URLs []string
+	// PublicKey is the public key of the publisher of the lists. Lists which
+	// are not signed with the corresponding private key are ignored.
+	PublicKey p2pcrypto.PubKey
+	// ChainID and Environment must match the lists. They prevent a list for one
+	// network from being used for another.
+	ChainID     int
+	Environment string
+	// RefreshInterval is how often the lists are fetched. Defaults to 1 hour.
+	RefreshInterval time.Duration
+}
+
+// BootstrapListPayload is the signed content of a SignedBootstrapList.
+type BootstrapListPayload struct {
+	ChainID     int    `json:"chainID"`
+	Environment string `json:"environment"`
+	// UpdatedAt is when the list was published. Lists which are not newer than
+	// the list a node already uses are ignored, so that an outdated list can't
+	// be replayed.
+	UpdatedAt     time.Time `json:"updatedAt"`
+	BootstrapList []string  `json:"bootstrapList"`
+}
+
+// SignedBootstrapList is a bootstrap list which is signed by its publisher, so
+// that it can be served from any URL.
+type SignedBootstrapList struct {
+	// Payload is the JSON encoded BootstrapListPayload. It is kept as is so that
+	// the signature is verified against the exact bytes which were signed.
+	Payload json.RawMessage `json:"payload"`
+	// Signature is the signature of Payload. It is encoded as base64.
+	Signature []byte `json:"signature"`
+}
+
+// SignBootstrapList signs the given payload with the given private key.
+func SignBootstrapList(privKey p2pcrypto.PrivKey, payload *BootstrapListPayload) (*SignedBootstrapList, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := privKey.Sign(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedBootstrapList{
+		Payload:   encoded,
+		Signature: signature,
+	}, nil
+}
+
+// Verify checks that the list was signed with the private key corresponding to
+// the given public key and is for the given chain ID and environment. It
+// returns the payload if so.
+func (s *SignedBootstrapList) Verify(pubKey p2pcrypto.PubKey, chainID int, environment string) (*BootstrapListPayload, error) {
+	valid, err := pubKey.Verify(s.Payload, s.Signature)
+	if err != nil || !valid {
+		return nil, ErrInvalidBootstrapListSignature
+	}
+	var payload BootstrapListPayload
+	if err := json.Unmarshal(s.Payload, &payload); err != nil {
+		return nil, err
+	}
+	if payload.ChainID != chainID || payload.Environment != environment {
+		return nil, ErrBootstrapListMismatch{ChainID: payload.ChainID, Environment: payload.Environment}
+	}
+	if _, err := BootstrapListToAddrInfos(payload.BootstrapList); err != nil {
+		return nil, err
+	}
+	return &payload, nil
+}
+
+// fetchSignedBootstrapList fetches the signed bootstrap list at the given URL.
+func fetchSignedBootstrapList(ctx context.Context, client *http.Client, url string) (*SignedBootstrapList, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, maxSignedBootstrapListSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxSignedBootstrapListSize {
+		return nil, fmt.Errorf("signed bootstrap list cannot be larger than %d bytes", maxSignedBootstrapListSize)
+	}
+	var signedList SignedBootstrapList
+	if err := json.Unmarshal(body, &signedList); err != nil {
+		return nil, err
+	}
+	return &signedList, nil
+}
+
+// getBootstrapList returns the bootstrap list which is currently in use.
+func (n *Node) getBootstrapList() []string {
+	n.bootstrapListMu.Lock()
+	defer n.bootstrapListMu.Unlock()
+	return n.config.BootstrapList
+}
+
+// updateSignedBootstrapList fetches the signed bootstrap lists and switches to
+// the newest valid one if it is newer than the list in use. It returns true if
+// the list changed. Lists which can't be fetched or verified are skipped, so
+// the node keeps using the last known list if the publisher is unavailable.
+func (n *Node) updateSignedBootstrapList(ctx context.Context) bool {
+	signedListsConfig := n.config.SignedBootstrapLists
+	var newest *BootstrapListPayload
+	for _, url := range signedListsConfig.URLs {
+		fetchCtx, cancel := context.WithTimeout(ctx, defaultNetworkTimeout)
+		signedList, err := fetchSignedBootstrapList(fetchCtx, n.httpClient, url)
+		cancel()
+		if err != nil {
+			log.WithError(err).WithField("url", url).Warn("could not fetch signed bootstrap list")
+			continue
+		}
+		payload, err := signedList.Verify(signedListsConfig.PublicKey, signedListsConfig.ChainID, signedListsConfig.Environment)
+		if err != nil {
+			log.WithError(err).WithField("url", url).Warn("ignoring invalid signed bootstrap list")
+			continue
+		}
+		if newest == nil || payload.UpdatedAt.After(newest.UpdatedAt) {
+			newest = payload
+		}
+	}
+	if newest == nil {
+		return false
+	}
+
+	n.bootstrapListMu.Lock()
+	defer n.bootstrapListMu.Unlock()
+	if !newest.UpdatedAt.After(n.bootstrapListUpdatedAt) {
+		return false
+	}
+	n.config.BootstrapList = newest.BootstrapList
+	n.bootstrapListUpdatedAt = newest.UpdatedAt
+	log.WithFields(map[string]interface{}{
+		"updatedAt":     newest.UpdatedAt,
+		"bootstrapList": newest.BootstrapList,
+	}).Info("updated bootstrap list from signed bootstrap list")
+	return true
+}
+
+// connectToBootstrapPeers connects to all peers in the bootstrap list and
+// protects their IP addresses. If a bootstrap peer can't be reached at any of
+// its addresses (e.g. because its IP address rotated), its current addresses
+// are looked up in the DHT.
+func (n *Node) connectToBootstrapPeers(ctx context.Context) error {
+	bootstrapList := n.getBootstrapList()
+	if err := ConnectToBootstrapList(ctx, n.host, bootstrapList); err != nil {
+		return err
+	}
+	bootstrapAddrInfos, err := BootstrapListToAddrInfos(bootstrapList)
+	if err != nil {
+		return err
+	}
+	for _, addrInfo := range bootstrapAddrInfos {
+		for _, addr := range addrInfo.Addrs {
+			_ = n.banner.ProtectIP(addr)
+		}
+		if addrInfo.ID == n.host.ID() || n.host.Network().Connectedness(addrInfo.ID) == network.Connected {
+			continue
+		}
+		n.connectToMovedBootstrapPeer(ctx, addrInfo.ID)
+	}
+	return nil
+}
+
+// connectToMovedBootstrapPeer looks up the current addresses of the given
+// bootstrap peer in the DHT and connects to it. The IP addresses are only
+// protected once the connection proved that they belong to the peer.
+func (n *Node) connectToMovedBootstrapPeer(ctx context.Context, peerID peer.ID) {
+	findCtx, cancel := context.WithTimeout(ctx, defaultNetworkTimeout)
+	defer cancel()
+	addrInfo, err := n.dht.FindPeer(findCtx, peerID)
+	if err != nil {
+		log.WithError(err).WithField("peerID", peerID).Warn("could not find current addresses of bootstrap peer")
+		return
+	}
+	if err := n.host.Connect(findCtx, addrInfo); err != nil {
+		log.WithError(err).WithField("peerInfo", addrInfo).Warn("failed to connect to bootstrap peer at current addresses")
+		return
+	}
+	for _, addr := range addrInfo.Addrs {
+		_ = n.banner.ProtectIP(addr)
+	}
+	log.WithField("peerInfo", addrInfo).Info("connected to bootstrap peer at addresses found in the DHT")
+}
+
+// refreshBootstrapListLoop periodically fetches the signed bootstrap lists and
+// reconnects to any bootstrap peers we are not connected to until ctx is
+// canceled.
+func (n *Node) refreshBootstrapListLoop(ctx context.Context) {
+	ticker := time.NewTicker(n.config.SignedBootstrapLists.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		n.updateSignedBootstrapList(ctx)
+		if err := n.connectToBootstrapPeers(ctx); err != nil {
+			log.WithError(err).Warn("could not connect to bootstrap peers")
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// +build !js
+
+package p2p
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testBootstrapPeer0 = "/ip4/3.214.190.67/tcp/60558/ipfs/16Uiu2HAmGx8Z6gdq5T5AQE54GMtqDhDFhizywTy1o28NJbAMMumF"
+	testBootstrapPeer1 = "/ip4/18.200.96.60/tcp/60558/ipfs/16Uiu2HAkwsDZk4LzXy2rnWANRsyBjB4fhjnsNeJmjgsBqxPGTL32"
+)
+
+func TestGetBootstrapList(t *testing.T) {
+	bootstrapList, err := GetBootstrapList(1, BootstrapEnvironmentProduction)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultBootstrapList, bootstrapList)
+
+	bootstrapList, err = GetBootstrapList(1337, BootstrapEnvironmentDevelopment)
+	require.NoError(t, err)
+	assert.Empty(t, bootstrapList)
+
+	_, err = GetBootstrapList(1, "staging")
+	assert.Equal(t, ErrUnknownBootstrapEnvironment{Environment: "staging"}, err)
+}
+
+func TestSignedBootstrapListVerify(t *testing.T) {
+	privKey, pubKey, err := p2pcrypto.GenerateSecp256k1Key(rand.Reader)
+	require.NoError(t, err)
+	_, otherPubKey, err := p2pcrypto.GenerateSecp256k1Key(rand.Reader)
+	require.NoError(t, err)
+
+	payload := &BootstrapListPayload{
+		ChainID:       1,
+		Environment:   BootstrapEnvironmentProduction,
+		UpdatedAt:     time.Date(2020, time.March, 1, 0, 0, 0, 0, time.UTC),
+		BootstrapList: []string{testBootstrapPeer0},
+	}
+	signedList, err := SignBootstrapList(privKey, payload)
+	require.NoError(t, err)
+
+	// The signature must survive encoding the list as JSON.
+	encoded, err := json.Marshal(signedList)
+	require.NoError(t, err)
+	var decoded SignedBootstrapList
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	actualPayload, err := decoded.Verify(pubKey, 1, BootstrapEnvironmentProduction)
+	require.NoError(t, err)
+	assert.Equal(t, payload, actualPayload)
+
+	_, err = decoded.Verify(otherPubKey, 1, BootstrapEnvironmentProduction)
+	assert.Equal(t, ErrInvalidBootstrapListSignature, err)
+	_, err = decoded.Verify(pubKey, 3, BootstrapEnvironmentProduction)
+	assert.Equal(t, ErrBootstrapListMismatch{ChainID: 1, Environment: BootstrapEnvironmentProduction}, err)
+
+	decoded.Payload = []byte(`{"chainID":1,"environment":"production","bootstrapList":["/ip4/1.2.3.4/tcp/60558"]}`)
+	_, err = decoded.Verify(pubKey, 1, BootstrapEnvironmentProduction)
+	assert.Equal(t, ErrInvalidBootstrapListSignature, err)
+}
+
+func TestUpdateSignedBootstrapList(t *testing.T) {
+	privKey, pubKey, err := p2pcrypto.GenerateSecp256k1Key(rand.Reader)
+	require.NoError(t, err)
+	otherPrivKey, _, err := p2pcrypto.GenerateSecp256k1Key(rand.Reader)
+	require.NoError(t, err)
+
+	updatedAt := time.Date(2020, time.March, 1, 0, 0, 0, 0, time.UTC)
+	newServer := func(privKey p2pcrypto.PrivKey, updatedAt time.Time, bootstrapList []string) *httptest.Server {
+		signedList, err := SignBootstrapList(privKey, &BootstrapListPayload{
+			ChainID:       1,
+			Environment:   BootstrapEnvironmentProduction,
+			UpdatedAt:     updatedAt,
+			BootstrapList: bootstrapList,
+		})
+		require.NoError(t, err)
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(signedList)
+		}))
+	}
+	olderServer := newServer(privKey, updatedAt, []string{testBootstrapPeer0})
+	defer olderServer.Close()
+	newerServer := newServer(privKey, updatedAt.Add(time.Hour), []string{testBootstrapPeer1})
+	defer newerServer.Close()
+	forgedServer := newServer(otherPrivKey, updatedAt.Add(2*time.Hour), []string{testBootstrapPeer0})
+	defer forgedServer.Close()
+	unavailableServer := httptest.NewServer(http.NotFoundHandler())
+	defer unavailableServer.Close()
+
+	node := &Node{
+		config: Config{
+			BootstrapList: []string{testBootstrapPeer0},
+			SignedBootstrapLists: &SignedBootstrapListConfig{
+				URLs:        []string{unavailableServer.URL, olderServer.URL, newerServer.URL, forgedServer.URL},
+				PublicKey:   pubKey,
+				ChainID:     1,
+				Environment: BootstrapEnvironmentProduction,
+			},
+		},
+		httpClient: http.DefaultClient,
+	}
+
+	// The newest list with a valid signature is used.
+	assert.True(t, node.updateSignedBootstrapList(context.Background()))
+	assert.Equal(t, []string{testBootstrapPeer1}, node.getBootstrapList())
+
+	// Lists which are not newer than the list in use are ignored.
+	node.config.SignedBootstrapLists.URLs = []string{olderServer.URL}
+	assert.False(t, node.updateSignedBootstrapList(context.Background()))
+	assert.Equal(t, []string{testBootstrapPeer1}, node.getBootstrapList())
+
+	// The last known list is kept if no list can be fetched.
+	node.config.SignedBootstrapLists.URLs = []string{unavailableServer.URL}
+	assert.False(t, node.updateSignedBootstrapList(context.Background()))
+	assert.Equal(t, []string{testBootstrapPeer1}, node.getBootstrapList())
+}
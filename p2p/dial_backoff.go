@@ -0,0 +1,166 @@
+package p2p
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/libp2p/go-libp2p-core/peer"
+	swarm "github.com/libp2p/go-libp2p-swarm"
+)
+
+const (
+	// minDialBackoff is how long we wait before dialing a peer again after the
+	// first failed dial. It doubles after each consecutive failure.
+	minDialBackoff = peerDiscoveryInterval
+	// maxDialBackoff is the maximum amount of time we wait before dialing a
+	// peer again.
+	maxDialBackoff = 30 * time.Minute
+	// dialBackoffCacheSize is the maximum number of peers for which we remember
+	// failed dials. The least recently failed peers are forgotten first.
+	dialBackoffCacheSize = 1000
+)
+
+// Reasons for failed dials, as reported in DialStats.
+const (
+	DialFailureTimeout           = "timeout"
+	DialFailureConnectionRefused = "connectionRefused"
+	DialFailureUnreachable       = "unreachable"
+	DialFailureBackoff           = "backoff"
+	DialFailureOther             = "other"
+)
+
+// DialStats contains the number of dials the node has attempted since it was
+// started, grouped by outcome.
+type DialStats struct {
+	// NumSuccesses is the number of successful dials.
+	NumSuccesses int64
+	// NumFailures is the number of failed dials by reason (one of the
+	// DialFailure constants).
+	NumFailures map[string]int64
+	// NumSkipped is the number of times peer discovery skipped dialing a peer
+	// because a recent dial to it failed.
+	NumSkipped int64
+	// NumPeersInBackoff is the number of peers that peer discovery is currently
+	// not dialing because a recent dial to them failed.
+	NumPeersInBackoff int
+}
+
+type failedDial struct {
+	numFailures int
+	retryAt     time.Time
+}
+
+// dialBackoff keeps track of recently failed dials so that peer discovery
+// doesn't keep dialing peers that are unreachable. Each consecutive failure
+// doubles the amount of time before the peer is dialed again. It also counts
+// the outcome of every dial for DialStats.
+type dialBackoff struct {
+	mu           sync.Mutex
+	failedDials  *lru.Cache
+	numSuccesses int64
+	numFailures  map[string]int64
+	numSkipped   int64
+}
+
+func newDialBackoff() *dialBackoff {
+	// lru.New only returns an error if size is <= 0, so we can safely ignore
+	// it.
+	failedDials, _ := lru.New(dialBackoffCacheSize)
+	return &dialBackoff{
+		failedDials: failedDials,
+		numFailures: map[string]int64{},
+	}
+}
+
+// shouldSkip returns true if the given peer should not be dialed because a
+// recent dial failed. It counts the skipped dial if so.
+func (d *dialBackoff) shouldSkip(peerID peer.ID, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	value, found := d.failedDials.Peek(peerID)
+	if !found || !now.Before(value.(*failedDial).retryAt) {
+		return false
+	}
+	d.numSkipped++
+	return true
+}
+
+// recordResult records the outcome of dialing the given peer. A successful
+// dial clears any backoff for the peer.
+func (d *dialBackoff) recordResult(peerID peer.ID, dialErr error, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if dialErr == nil {
+		d.numSuccesses++
+		d.failedDials.Remove(peerID)
+		return
+	}
+	d.numFailures[dialFailureReason(dialErr)]++
+	failure := &failedDial{}
+	if value, found := d.failedDials.Get(peerID); found {
+		failure = value.(*failedDial)
+	}
+	failure.numFailures++
+	failure.retryAt = now.Add(backoffForFailures(failure.numFailures))
+	d.failedDials.Add(peerID, failure)
+}
+
+func (d *dialBackoff) stats(now time.Time) DialStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	numFailures := make(map[string]int64, len(d.numFailures))
+	for reason, count := range d.numFailures {
+		numFailures[reason] = count
+	}
+	numPeersInBackoff := 0
+	for _, key := range d.failedDials.Keys() {
+		if value, found := d.failedDials.Peek(key); found && now.Before(value.(*failedDial).retryAt) {
+			numPeersInBackoff++
+		}
+	}
+	return DialStats{
+		NumSuccesses:      d.numSuccesses,
+		NumFailures:       numFailures,
+		NumSkipped:        d.numSkipped,
+		NumPeersInBackoff: numPeersInBackoff,
+	}
+}
+
+// backoffForFailures returns how long to wait before dialing a peer again
+// after the given number of consecutive failures.
+func backoffForFailures(numFailures int) time.Duration {
+	backoff := minDialBackoff
+	for i := 1; i < numFailures; i++ {
+		backoff *= 2
+		if backoff >= maxDialBackoff {
+			return maxDialBackoff
+		}
+	}
+	return backoff
+}
+
+// dialFailureReason classifies a dial error. libp2p usually wraps the
+// underlying errors for each address in a single error, so we have to look at
+// the error message.
+func dialFailureReason(dialErr error) string {
+	if dialErr == swarm.ErrDialBackoff {
+		return DialFailureBackoff
+	}
+	if dialErr == context.DeadlineExceeded {
+		return DialFailureTimeout
+	}
+	message := dialErr.Error()
+	switch {
+	case strings.Contains(message, "connection refused"):
+		return DialFailureConnectionRefused
+	case strings.Contains(message, "i/o timeout"), strings.Contains(message, "context deadline exceeded"):
+		return DialFailureTimeout
+	case strings.Contains(message, "no route to host"), strings.Contains(message, "network is unreachable"), strings.Contains(message, "no good addresses"):
+		return DialFailureUnreachable
+	default:
+		return DialFailureOther
+	}
+}
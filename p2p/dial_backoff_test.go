@@ -0,0 +1,77 @@
+// +build !js
+
+package p2p
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	swarm "github.com/libp2p/go-libp2p-swarm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffForFailures(t *testing.T) {
+	assert.Equal(t, minDialBackoff, backoffForFailures(1))
+	assert.Equal(t, 2*minDialBackoff, backoffForFailures(2))
+	assert.Equal(t, 4*minDialBackoff, backoffForFailures(3))
+	assert.Equal(t, maxDialBackoff, backoffForFailures(100))
+}
+
+func TestDialBackoff(t *testing.T) {
+	backoff := newDialBackoff()
+	peerID := peer.ID("peer")
+	now := time.Now()
+
+	assert.False(t, backoff.shouldSkip(peerID, now))
+
+	// After a failed dial, the peer is skipped until the backoff has elapsed.
+	backoff.recordResult(peerID, errors.New("dial tcp 127.0.0.1:60558: connect: connection refused"), now)
+	assert.True(t, backoff.shouldSkip(peerID, now.Add(minDialBackoff-time.Millisecond)))
+	assert.False(t, backoff.shouldSkip(peerID, now.Add(minDialBackoff)))
+
+	// Consecutive failures double the backoff.
+	now = now.Add(minDialBackoff)
+	backoff.recordResult(peerID, context.DeadlineExceeded, now)
+	assert.True(t, backoff.shouldSkip(peerID, now.Add(2*minDialBackoff-time.Millisecond)))
+	assert.False(t, backoff.shouldSkip(peerID, now.Add(2*minDialBackoff)))
+
+	// A successful dial clears the backoff.
+	backoff.recordResult(peerID, nil, now)
+	assert.False(t, backoff.shouldSkip(peerID, now))
+
+	expected := DialStats{
+		NumSuccesses: 1,
+		NumFailures: map[string]int64{
+			DialFailureConnectionRefused: 1,
+			DialFailureTimeout:           1,
+		},
+		NumSkipped:        2,
+		NumPeersInBackoff: 0,
+	}
+	assert.Equal(t, expected, backoff.stats(now))
+
+	backoff.recordResult(peer.ID("other"), swarm.ErrDialBackoff, now)
+	stats := backoff.stats(now)
+	assert.Equal(t, 1, stats.NumPeersInBackoff)
+	assert.Equal(t, int64(1), stats.NumFailures[DialFailureBackoff])
+}
+
+func TestDialFailureReason(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected string
+	}{
+		{swarm.ErrDialBackoff, DialFailureBackoff},
+		{context.DeadlineExceeded, DialFailureTimeout},
+		{errors.New("failed to dial : all dials failed\n  * [/ip4/1.2.3.4/tcp/60558] dial tcp4 1.2.3.4:60558: i/o timeout"), DialFailureTimeout},
+		{errors.New("dial tcp4 1.2.3.4:60558: connect: connection refused"), DialFailureConnectionRefused},
+		{errors.New("dial tcp4 1.2.3.4:60558: connect: no route to host"), DialFailureUnreachable},
+		{errors.New("failed to negotiate security protocol"), DialFailureOther},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, dialFailureReason(tc.err), tc.err.Error())
+	}
+}
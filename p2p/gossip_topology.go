@@ -0,0 +1,323 @@
+package p2p
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pb "github.com/libp2p/go-libp2p-pubsub/pb"
+)
+
+const (
+	// GossipStatsWindow is the duration over which GossipSubTopology counts
+	// control messages.
+	GossipStatsWindow = 5 * time.Minute
+	// gossipStatsBucketDuration is the granularity with which control messages
+	// are counted. Counts are dropped one bucket at a time once they are older
+	// than GossipStatsWindow.
+	gossipStatsBucketDuration = time.Minute
+)
+
+// GossipSubTopology is a snapshot of the GossipSub overlay of the node.
+type GossipSubTopology struct {
+	// Topics are the topics the node has joined or recently published to,
+	// sorted by name.
+	Topics []*GossipSubTopic
+	// Control counts the control messages sent and received by the node within
+	// the last GossipStatsWindow.
+	Control GossipSubControlStats
+}
+
+// GossipSubTopic describes the peers of the node for a single topic.
+type GossipSubTopic struct {
+	Topic string
+	// Joined is true if the node is subscribed to the topic.
+	Joined bool
+	// Peers are all connected peers which are subscribed to the topic.
+	Peers []peer.ID
+	// MeshPeers are the peers in the node's mesh for the topic, i.e. the peers
+	// it forwards all messages for the topic to. Only joined topics have a
+	// mesh.
+	MeshPeers []peer.ID
+	// FanoutPeers are the peers the node sent its own messages for the topic to
+	// within the fanout TTL of GossipSub. Only topics which the node publishes
+	// to without joining them have fanout peers.
+	FanoutPeers []peer.ID
+	// IHaveSent and IHaveReceived are the number of message IDs advertised in
+	// IHAVE messages for the topic within the last GossipStatsWindow.
+	IHaveSent     int
+	IHaveReceived int
+}
+
+// GossipSubControlStats counts GossipSub control messages. IHAVE and IWANT
+// messages are counted by the number of message IDs they contain.
+type GossipSubControlStats struct {
+	IHaveSent     int
+	IHaveReceived int
+	IWantSent     int
+	IWantReceived int
+	GraftSent     int
+	GraftReceived int
+	PruneSent     int
+	PruneReceived int
+}
+
+// gossipStatsBucket holds the control messages counted within one
+// gossipStatsBucketDuration.
+type gossipStatsBucket struct {
+	start                time.Time
+	control              GossipSubControlStats
+	iHaveSentByTopic     map[string]int
+	iHaveReceivedByTopic map[string]int
+}
+
+// gossipTracer is a pubsub.EventTracer which keeps track of the GossipSub
+// mesh and fanout of the node and counts control messages. GossipSub doesn't
+// expose its internal state, so it is reconstructed from the trace events.
+type gossipTracer struct {
+	mu      sync.Mutex
+	joined  map[string]struct{}
+	mesh    map[string]map[peer.ID]struct{}
+	fanout  map[string]map[peer.ID]time.Time
+	buckets []*gossipStatsBucket
+}
+
+var _ pubsub.EventTracer = &gossipTracer{}
+
+func newGossipTracer() *gossipTracer {
+	return &gossipTracer{
+		joined: map[string]struct{}{},
+		mesh:   map[string]map[peer.ID]struct{}{},
+		fanout: map[string]map[peer.ID]time.Time{},
+	}
+}
+
+// Trace implements pubsub.EventTracer.
+func (t *gossipTracer) Trace(evt *pb.TraceEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	switch evt.GetType() {
+	case pb.TraceEvent_JOIN:
+		topic := evt.GetJoin().GetTopic()
+		t.joined[topic] = struct{}{}
+		// GossipSub turns the fanout of a topic into its mesh when joining it.
+		delete(t.fanout, topic)
+	case pb.TraceEvent_LEAVE:
+		topic := evt.GetLeave().GetTopic()
+		delete(t.joined, topic)
+		delete(t.mesh, topic)
+	case pb.TraceEvent_GRAFT:
+		peerID, err := peer.IDFromBytes(evt.GetGraft().GetPeerID())
+		if err != nil {
+			return
+		}
+		topic := evt.GetGraft().GetTopic()
+		if _, found := t.mesh[topic]; !found {
+			t.mesh[topic] = map[peer.ID]struct{}{}
+		}
+		t.mesh[topic][peerID] = struct{}{}
+	case pb.TraceEvent_PRUNE:
+		peerID, err := peer.IDFromBytes(evt.GetPrune().GetPeerID())
+		if err != nil {
+			return
+		}
+		delete(t.mesh[evt.GetPrune().GetTopic()], peerID)
+	case pb.TraceEvent_REMOVE_PEER:
+		peerID, err := peer.IDFromBytes(evt.GetRemovePeer().GetPeerID())
+		if err != nil {
+			return
+		}
+		for _, peers := range t.mesh {
+			delete(peers, peerID)
+		}
+		for _, peers := range t.fanout {
+			delete(peers, peerID)
+		}
+	case pb.TraceEvent_SEND_RPC:
+		peerID, err := peer.IDFromBytes(evt.GetSendRPC().GetSendTo())
+		if err != nil {
+			return
+		}
+		meta := evt.GetSendRPC().GetMeta()
+		for _, message := range meta.GetMessages() {
+			for _, topic := range message.GetTopics() {
+				if _, joined := t.joined[topic]; joined {
+					continue
+				}
+				if _, found := t.fanout[topic]; !found {
+					t.fanout[topic] = map[peer.ID]time.Time{}
+				}
+				t.fanout[topic][peerID] = now
+			}
+		}
+		t.countControl(now, meta.GetControl(), true)
+	case pb.TraceEvent_RECV_RPC:
+		t.countControl(now, evt.GetRecvRPC().GetMeta().GetControl(), false)
+	}
+}
+
+// countControl counts the given control messages. t.mu must be locked.
+func (t *gossipTracer) countControl(now time.Time, control *pb.TraceEvent_ControlMeta, sent bool) {
+	if control == nil {
+		return
+	}
+	bucket := t.currentBucket(now)
+	iHaveByTopic := bucket.iHaveReceivedByTopic
+	if sent {
+		iHaveByTopic = bucket.iHaveSentByTopic
+	}
+	numIHave := 0
+	for _, iHave := range control.GetIhave() {
+		numIHave += len(iHave.GetMessageIDs())
+		iHaveByTopic[iHave.GetTopic()] += len(iHave.GetMessageIDs())
+	}
+	numIWant := 0
+	for _, iWant := range control.GetIwant() {
+		numIWant += len(iWant.GetMessageIDs())
+	}
+	if sent {
+		bucket.control.IHaveSent += numIHave
+		bucket.control.IWantSent += numIWant
+		bucket.control.GraftSent += len(control.GetGraft())
+		bucket.control.PruneSent += len(control.GetPrune())
+	} else {
+		bucket.control.IHaveReceived += numIHave
+		bucket.control.IWantReceived += numIWant
+		bucket.control.GraftReceived += len(control.GetGraft())
+		bucket.control.PruneReceived += len(control.GetPrune())
+	}
+}
+
+// currentBucket returns the bucket for the given time and drops buckets which
+// are older than GossipStatsWindow. t.mu must be locked.
+func (t *gossipTracer) currentBucket(now time.Time) *gossipStatsBucket {
+	t.dropExpiredBuckets(now)
+	if len(t.buckets) > 0 {
+		if last := t.buckets[len(t.buckets)-1]; now.Sub(last.start) < gossipStatsBucketDuration {
+			return last
+		}
+	}
+	bucket := &gossipStatsBucket{
+		start:                now,
+		iHaveSentByTopic:     map[string]int{},
+		iHaveReceivedByTopic: map[string]int{},
+	}
+	t.buckets = append(t.buckets, bucket)
+	return bucket
+}
+
+// dropExpiredBuckets drops the buckets which are older than
+// GossipStatsWindow. t.mu must be locked.
+func (t *gossipTracer) dropExpiredBuckets(now time.Time) {
+	numExpired := 0
+	for _, bucket := range t.buckets {
+		if now.Sub(bucket.start) < GossipStatsWindow {
+			break
+		}
+		numExpired++
+	}
+	t.buckets = t.buckets[numExpired:]
+}
+
+// topology returns the mesh and fanout peers of every topic the node has
+// joined or recently published to, as well as the control message counts. The
+// Peers of the topics are not set.
+func (t *gossipTracer) topology(now time.Time) *GossipSubTopology {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dropExpiredBuckets(now)
+
+	topics := map[string]*GossipSubTopic{}
+	getTopic := func(name string) *GossipSubTopic {
+		topic, found := topics[name]
+		if !found {
+			topic = &GossipSubTopic{
+				Topic:       name,
+				Peers:       []peer.ID{},
+				MeshPeers:   []peer.ID{},
+				FanoutPeers: []peer.ID{},
+			}
+			topics[name] = topic
+		}
+		return topic
+	}
+	for name := range t.joined {
+		getTopic(name).Joined = true
+	}
+	for name, peers := range t.mesh {
+		topic := getTopic(name)
+		for peerID := range peers {
+			topic.MeshPeers = append(topic.MeshPeers, peerID)
+		}
+	}
+	for name, peers := range t.fanout {
+		for peerID, lastSent := range peers {
+			if now.Sub(lastSent) >= pubsub.GossipSubFanoutTTL {
+				delete(peers, peerID)
+				continue
+			}
+			topic := getTopic(name)
+			topic.FanoutPeers = append(topic.FanoutPeers, peerID)
+		}
+		if len(peers) == 0 {
+			delete(t.fanout, name)
+		}
+	}
+
+	topology := &GossipSubTopology{Topics: []*GossipSubTopic{}}
+	for _, bucket := range t.buckets {
+		addControlStats(&topology.Control, bucket.control)
+		for name, count := range bucket.iHaveSentByTopic {
+			getTopic(name).IHaveSent += count
+		}
+		for name, count := range bucket.iHaveReceivedByTopic {
+			getTopic(name).IHaveReceived += count
+		}
+	}
+	for _, topic := range topics {
+		sortPeerIDs(topic.MeshPeers)
+		sortPeerIDs(topic.FanoutPeers)
+		topology.Topics = append(topology.Topics, topic)
+	}
+	sort.Slice(topology.Topics, func(i, j int) bool {
+		return topology.Topics[i].Topic < topology.Topics[j].Topic
+	})
+	return topology
+}
+
+// addControlStats adds the counts of b to a.
+func addControlStats(a *GossipSubControlStats, b GossipSubControlStats) {
+	a.IHaveSent += b.IHaveSent
+	a.IHaveReceived += b.IHaveReceived
+	a.IWantSent += b.IWantSent
+	a.IWantReceived += b.IWantReceived
+	a.GraftSent += b.GraftSent
+	a.GraftReceived += b.GraftReceived
+	a.PruneSent += b.PruneSent
+	a.PruneReceived += b.PruneReceived
+}
+
+// sortPeerIDs sorts the given peer IDs in place.
+func sortPeerIDs(peerIDs []peer.ID) {
+	sort.Slice(peerIDs, func(i, j int) bool {
+		return bytes.Compare([]byte(peerIDs[i]), []byte(peerIDs[j])) == -1
+	})
+}
+
+// GossipSubTopology returns a snapshot of the GossipSub overlay of the node:
+// the mesh and fanout peers of every topic it has joined or recently published
+// to, along with recent statistics about IHAVE, IWANT, GRAFT and PRUNE control
+// messages. It can be used to analyze how messages propagate through the
+// network.
+func (n *Node) GossipSubTopology() *GossipSubTopology {
+	topology := n.gossipTracer.topology(time.Now())
+	for _, topic := range topology.Topics {
+		topic.Peers = n.pubsub.ListPeers(topic.Topic)
+		sortPeerIDs(topic.Peers)
+	}
+	return topology
+}
@@ -0,0 +1,100 @@
+// +build !js
+
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGossipTracerTopology(t *testing.T) {
+	t.Parallel()
+
+	peerA, err := peer.IDB58Decode("QmYyQSo1c1Ym7orWxLYvCrM2EmxFTANf8wXmmE7DWjhx5N")
+	require.NoError(t, err)
+	peerB, err := peer.IDB58Decode("QmcgpsyWgH8Y8ajJz1Cu72KnS5uo2Aa2LpzU7kinSupNKC")
+	require.NoError(t, err)
+	joinedTopic := "/0x-orders/version/3/chain/1337/schema/e30="
+	fanoutTopic := "/0x-orders/version/3/chain/1337/schema/eyJ9"
+
+	tracer := newGossipTracer()
+	tracer.Trace(&pb.TraceEvent{
+		Type: pb.TraceEvent_JOIN.Enum(),
+		Join: &pb.TraceEvent_Join{Topic: &joinedTopic},
+	})
+	for _, peerID := range []peer.ID{peerA, peerB} {
+		tracer.Trace(&pb.TraceEvent{
+			Type:  pb.TraceEvent_GRAFT.Enum(),
+			Graft: &pb.TraceEvent_Graft{PeerID: []byte(peerID), Topic: &joinedTopic},
+		})
+	}
+	tracer.Trace(&pb.TraceEvent{
+		Type:  pb.TraceEvent_PRUNE.Enum(),
+		Prune: &pb.TraceEvent_Prune{PeerID: []byte(peerB), Topic: &joinedTopic},
+	})
+	tracer.Trace(&pb.TraceEvent{
+		Type: pb.TraceEvent_SEND_RPC.Enum(),
+		SendRPC: &pb.TraceEvent_SendRPC{
+			SendTo: []byte(peerB),
+			Meta: &pb.TraceEvent_RPCMeta{
+				Messages: []*pb.TraceEvent_MessageMeta{{Topics: []string{fanoutTopic}}},
+				Control: &pb.TraceEvent_ControlMeta{
+					Ihave: []*pb.TraceEvent_ControlIHaveMeta{{Topic: &joinedTopic, MessageIDs: [][]byte{{1}, {2}, {3}}}},
+					Graft: []*pb.TraceEvent_ControlGraftMeta{{Topic: &joinedTopic}},
+				},
+			},
+		},
+	})
+	tracer.Trace(&pb.TraceEvent{
+		Type: pb.TraceEvent_RECV_RPC.Enum(),
+		RecvRPC: &pb.TraceEvent_RecvRPC{
+			ReceivedFrom: []byte(peerA),
+			Meta: &pb.TraceEvent_RPCMeta{
+				Control: &pb.TraceEvent_ControlMeta{
+					Iwant: []*pb.TraceEvent_ControlIWantMeta{{MessageIDs: [][]byte{{1}, {2}}}},
+				},
+			},
+		},
+	})
+
+	topology := tracer.topology(time.Now())
+	expectedTopics := []*GossipSubTopic{
+		{
+			Topic:       joinedTopic,
+			Joined:      true,
+			Peers:       []peer.ID{},
+			MeshPeers:   []peer.ID{peerA},
+			FanoutPeers: []peer.ID{},
+			IHaveSent:   3,
+		},
+		{
+			Topic:       fanoutTopic,
+			Peers:       []peer.ID{},
+			MeshPeers:   []peer.ID{},
+			FanoutPeers: []peer.ID{peerB},
+		},
+	}
+	assert.Equal(t, expectedTopics, topology.Topics)
+	assert.Equal(t, GossipSubControlStats{IHaveSent: 3, GraftSent: 1, IWantReceived: 2}, topology.Control)
+
+	// Fanout peers and control message counts expire.
+	topology = tracer.topology(time.Now().Add(GossipStatsWindow))
+	require.Len(t, topology.Topics, 1)
+	assert.Equal(t, joinedTopic, topology.Topics[0].Topic)
+	assert.Equal(t, 0, topology.Topics[0].IHaveSent)
+	assert.Equal(t, GossipSubControlStats{}, topology.Control)
+
+	// Peers which disconnect are removed from the mesh.
+	tracer.Trace(&pb.TraceEvent{
+		Type:       pb.TraceEvent_REMOVE_PEER.Enum(),
+		RemovePeer: &pb.TraceEvent_RemovePeer{PeerID: []byte(peerA)},
+	})
+	topology = tracer.topology(time.Now())
+	require.Len(t, topology.Topics, 1)
+	assert.Empty(t, topology.Topics[0].MeshPeers)
+}
@@ -9,6 +9,8 @@ import (
 type Message struct {
 	// From is the peer ID of the peer who sent the message.
 	From peer.ID
+	// Topic is the (possibly sharded) topic the message was received on.
+	Topic string
 	// Data is the underlying data for the message.
 	Data []byte
 }
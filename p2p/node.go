@@ -9,12 +9,16 @@ import (
 	"fmt"
 	"io/ioutil"
 	mathrand "math/rand"
+	"net"
+	"net/http"
 	"path/filepath"
 	"sync"
 	"time"
 
+	"github.com/0xProject/0x-mesh/common/supervisor"
 	"github.com/0xProject/0x-mesh/constants"
 	"github.com/0xProject/0x-mesh/p2p/banner"
+	"github.com/0xProject/0x-mesh/p2p/peerscore"
 	"github.com/0xProject/0x-mesh/p2p/ratevalidator"
 	"github.com/0xProject/0x-mesh/p2p/validatorset"
 	"github.com/albrow/stringset"
@@ -30,6 +34,7 @@ import (
 	"github.com/libp2p/go-libp2p-core/routing"
 	discovery "github.com/libp2p/go-libp2p-discovery"
 	dht "github.com/libp2p/go-libp2p-kad-dht"
+	pnet "github.com/libp2p/go-libp2p-pnet"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	swarm "github.com/libp2p/go-libp2p-swarm"
 	filter "github.com/libp2p/go-maddr-filter"
@@ -87,6 +92,30 @@ const (
 	defaultPerPeerPubSubMessageBurst = maxShareBatch * 5
 )
 
+// ErrPeerBanned is returned when attempting to connect to a banned peer.
+var ErrPeerBanned = errors.New("cannot connect to banned peer")
+
+// ErrPeerNotAllowed is returned when attempting to connect to a peer which is
+// not on the allowlist while the allowlist is in use.
+var ErrPeerNotAllowed = errors.New("cannot connect to peer which is not on the allowlist")
+
+// ErrQUICNotSupported is returned by New if Config.EnableQUIC is set in an
+// environment in which QUIC cannot be used (i.e. browsers).
+var ErrQUICNotSupported = errors.New("the QUIC transport is not supported in this environment")
+
+// ErrQUICRequiresSecurity is returned by New if both Config.EnableQUIC and
+// Config.Insecure are set. QUIC connections are always encrypted.
+var ErrQUICRequiresSecurity = errors.New("the QUIC transport cannot be used if config.Insecure is true")
+
+// ErrQUICPrivateNetwork is returned by New if both Config.EnableQUIC and
+// Config.PrivateNetworkKey are set. The QUIC transport does not support
+// private networks.
+var ErrQUICPrivateNetwork = errors.New("the QUIC transport cannot be used in a private network")
+
+// ErrProxyNotSupported is returned by New if Config.ProxyURL is set in an
+// environment in which outbound connections cannot be proxied (i.e. browsers).
+var ErrProxyNotSupported = errors.New("outbound connections cannot be routed through a proxy in this environment")
+
 // Node is the main type for the p2p package. It represents a particpant in the
 // 0x Mesh network who is capable of sending, receiving, validating, and storing
 // messages.
@@ -99,8 +128,31 @@ type Node struct {
 	dht              *dht.IpfsDHT
 	routingDiscovery discovery.Discovery
 	pubsub           *pubsub.PubSub
-	sub              *pubsub.Subscription
+	topicValidator   pubsub.Validator
+	// topicsMu guards the topics and rendezvous points in config as well as
+	// subs and cancelSubs, all of which can be changed by SetTopics.
+	topicsMu         sync.RWMutex
+	subs             []*pubsub.Subscription
+	cancelSubs       context.CancelFunc
+	incoming         chan *pubsub.Message
 	banner           *banner.Banner
+	rateValidator    *ratevalidator.Validator
+	bandwidthCounter *metrics.BandwidthCounter
+	dialBackoff      *dialBackoff
+	limiter          *resourceLimiter
+	peerMetadata     *peerMetadataExchange
+	gossipTracer     *gossipTracer
+	httpClient       *http.Client
+	// bootstrapListMu guards the bootstrap list in config and
+	// bootstrapListUpdatedAt, which are changed whenever a newer signed
+	// bootstrap list is fetched.
+	bootstrapListMu        sync.Mutex
+	bootstrapListUpdatedAt time.Time
+	// watermarksMu guards lowWatermark and highWatermark, which can be changed
+	// with SetPeerCountWatermarks.
+	watermarksMu  sync.RWMutex
+	lowWatermark  int
+	highWatermark int
 }
 
 // Config contains configuration options for a Node.
@@ -108,21 +160,54 @@ type Config struct {
 	// SubscribeTopic is the topic to subscribe to for new messages. Only messages
 	// that are published on this topic will be received and processed.
 	SubscribeTopic string
+	// AdditionalSubscribeTopics are topics to subscribe to in addition to
+	// SubscribeTopic, e.g. the topics of additional order filters. They are
+	// split into shards like SubscribeTopic and are not changed by SetTopics
+	// (see SetAdditionalSubscribeTopics). Messages can be published to them
+	// with SendToTopicShard.
+	AdditionalSubscribeTopics []string
 	// PublishTopics are the topics to publish messages to. Messages may be
 	// published to more than one topic (e.g. a topic for all orders and a topic
 	// for orders with a specific asset).
 	PublishTopics []string
+	// NumTopicShards is the number of shards that each topic is split into. If
+	// greater than 1, messages are published to and received from the shard
+	// topics (see ShardTopic) instead of the topics themselves. This allows
+	// nodes with limited bandwidth to only receive a subset of all messages.
+	// Cannot be greater than MaxTopicShards.
+	NumTopicShards int
+	// SubscribeShards are the shards of SubscribeTopic to subscribe to. Only
+	// messages published to one of these shards will be received. If empty,
+	// all shards are subscribed to. Only used if NumTopicShards is greater than
+	// 1.
+	SubscribeShards []int
 	// TCPPort is the port on which to listen for incoming TCP connections.
 	TCPPort int
 	// WebSocketsPort is the port on which to listen for incoming WebSockets
 	// connections.
 	WebSocketsPort int
+	// EnableQUIC enables the QUIC transport, which establishes connections with
+	// fewer round trips than TCP and makes it easier to connect to peers behind
+	// NATs. Connections to peers which don't support QUIC are still made over
+	// TCP or WebSockets. It is ignored if ProxyURL is set, since QUIC uses UDP
+	// which cannot be proxied, and it cannot be combined with Insecure. Not
+	// supported in browsers.
+	EnableQUIC bool
+	// QUICPort is the UDP port on which to listen for incoming QUIC
+	// connections. Only used if EnableQUIC is true.
+	QUICPort int
 	// Insecure controls whether or not messages should be encrypted. It should
 	// always be set to false in production.
 	Insecure bool
 	// PrivateKey is the private key which will be used for signing messages and
 	// generating a peer ID.
 	PrivateKey p2pcrypto.PrivKey
+	// PrivateNetworkKey is an optional pre-shared key. If set, the node only
+	// connects to peers which have the same key, forming a private network.
+	// All connections are additionally encrypted with the key, so peers
+	// without it cannot even complete the handshake. It cannot be combined
+	// with EnableQUIC.
+	PrivateNetworkKey *[32]byte
 	// MessageHandler is an interface responsible for validating, storing, and
 	// finding new messages to share.
 	MessageHandler MessageHandler
@@ -134,8 +219,13 @@ type Config struct {
 	// peers to bootstrap the DHT for peer discovery.
 	UseBootstrapList bool
 	// BootstrapList is a list of multiaddress strings to use for bootstrapping
-	// the DHT. If empty, the default list will be used.
+	// the DHT. If nil, the default list will be used (see also
+	// GetBootstrapList).
 	BootstrapList []string
+	// SignedBootstrapLists optionally configures URLs of signed bootstrap lists
+	// which are fetched on startup and periodically afterwards. The newest
+	// valid list replaces BootstrapList. Only used if UseBootstrapList is true.
+	SignedBootstrapLists *SignedBootstrapListConfig
 	// DataDir is the directory to use for storing data.
 	DataDir string
 	// GlobalPubSubMessageLimit is the maximum number of messages per second that
@@ -161,8 +251,49 @@ type Config struct {
 	// CustomMessageValidator is a custom validator for GossipSub messages. All
 	// incoming and outgoing messages will be dropped unless they are valid
 	// according to this custom validator, which will be run in addition to the
-	// default validators.
+	// default validators. Peers that deliver messages which are invalid
+	// according to this validator are penalized (see PeerScore).
 	CustomMessageValidator pubsub.Validator
+	// PeerScore are the parameters used to score peers based on the messages
+	// they deliver. Peers with a low score are graylisted and eventually
+	// banned. If nil, peerscore.DefaultParams is used.
+	PeerScore *peerscore.Params
+	// MaxMessageSize is the maximum size (in bytes) of GossipSub messages. Larger
+	// messages are dropped and counted (see NumOversizedMessagesDropped). It
+	// cannot exceed constants.MaxPubSubTransportMessageSizeInBytes. Defaults to
+	// constants.MaxMessageSizeInBytes.
+	MaxMessageSize int
+	// MaxInboundStreamsPerPeer is the maximum number of inbound streams for
+	// protocols registered with SetStreamHandler (e.g. ordersync) that each
+	// peer can have open at once. Additional streams are reset. Defaults to 8.
+	MaxInboundStreamsPerPeer int
+	// MaxInboundStreamsPerProtocol is the maximum number of inbound streams
+	// that can be open at once for each protocol registered with
+	// SetStreamHandler. Additional streams are reset. Defaults to 256.
+	MaxInboundStreamsPerProtocol int
+	// MaxConnectionsPerPeer is the maximum number of inbound connections each
+	// peer can have open at once. Additional connections are closed. Defaults
+	// to 4.
+	MaxConnectionsPerPeer int
+	// MaxConnections is the maximum number of connections the node keeps open
+	// at once. Inbound connections beyond this limit are closed immediately,
+	// whereas the connection manager only prunes connections periodically.
+	// Defaults to 4 times the maximum number of peers.
+	MaxConnections int
+	// ProxyURL is the URL of a SOCKS5 proxy (e.g. a local Tor client at
+	// "socks5://127.0.0.1:9050") through which all outbound TCP connections
+	// are dialed. Credentials can be included in the URL. If it is set, the
+	// WebSocket and QUIC transports are disabled because their dials cannot be
+	// proxied, and the node does not look up its public IP address. Not
+	// supported in browsers.
+	ProxyURL string
+	// Metadata is optional information about the node (e.g. a name and a
+	// contact URL) which is sent to each peer after connecting. The metadata
+	// received from peers is returned by ConnectedPeers.
+	Metadata *PeerMetadata
+	// OrderFeedbackHandler is called for each OrderFeedback received from a
+	// peer. If it is nil, the node does not accept order feedback.
+	OrderFeedbackHandler OrderFeedbackHandler
 }
 
 func getPeerstoreDir(datadir string) string {
@@ -181,6 +312,18 @@ func New(ctx context.Context, config Config) (*Node, error) {
 	} else if len(config.RendezvousPoints) == 0 {
 		return nil, errors.New("config.RendezvousPoints is required")
 	}
+	if config.SignedBootstrapLists != nil {
+		if len(config.SignedBootstrapLists.URLs) == 0 {
+			return nil, errors.New("config.SignedBootstrapLists.URLs is required")
+		} else if config.SignedBootstrapLists.PublicKey == nil {
+			return nil, errors.New("config.SignedBootstrapLists.PublicKey is required")
+		}
+		signedBootstrapLists := *config.SignedBootstrapLists
+		if signedBootstrapLists.RefreshInterval == 0 {
+			signedBootstrapLists.RefreshInterval = defaultBootstrapListRefreshInterval
+		}
+		config.SignedBootstrapLists = &signedBootstrapLists
+	}
 	if config.GlobalPubSubMessageLimit == 0 {
 		config.GlobalPubSubMessageLimit = defaultGlobalPubSubMessageLimit
 	}
@@ -193,6 +336,39 @@ func New(ctx context.Context, config Config) (*Node, error) {
 	if config.PerPeerPubSubMessageBurst == 0 {
 		config.PerPeerPubSubMessageBurst = defaultPerPeerPubSubMessageBurst
 	}
+	if config.MaxMessageSize == 0 {
+		config.MaxMessageSize = constants.MaxMessageSizeInBytes
+	} else if config.MaxMessageSize > constants.MaxPubSubTransportMessageSizeInBytes {
+		return nil, fmt.Errorf("config.MaxMessageSize cannot be greater than %d", constants.MaxPubSubTransportMessageSizeInBytes)
+	}
+	if config.MaxInboundStreamsPerPeer == 0 {
+		config.MaxInboundStreamsPerPeer = defaultMaxInboundStreamsPerPeer
+	}
+	if config.PeerScore == nil {
+		config.PeerScore = peerscore.DefaultParams()
+	} else if err := config.PeerScore.Validate(); err != nil {
+		return nil, fmt.Errorf("config.PeerScore is invalid: %s", err)
+	}
+	if config.MaxInboundStreamsPerProtocol == 0 {
+		config.MaxInboundStreamsPerProtocol = defaultMaxInboundStreamsPerProtocol
+	}
+	if config.MaxConnectionsPerPeer == 0 {
+		config.MaxConnectionsPerPeer = defaultMaxConnectionsPerPeer
+	}
+	if config.MaxConnections == 0 {
+		config.MaxConnections = defaultMaxConnections
+	}
+	if config.EnableQUIC && config.Insecure {
+		return nil, ErrQUICRequiresSecurity
+	}
+	if config.EnableQUIC && config.PrivateNetworkKey != nil {
+		return nil, ErrQUICPrivateNetwork
+	}
+	subscribeShards, err := normalizeShards(config.NumTopicShards, config.SubscribeShards)
+	if err != nil {
+		return nil, err
+	}
+	config.SubscribeShards = subscribeShards
 
 	// We need to declare the newDHT function ahead of time so we can use it in
 	// the libp2p.Routing option.
@@ -212,6 +388,10 @@ func New(ctx context.Context, config Config) (*Node, error) {
 	if err != nil {
 		return nil, err
 	}
+	httpClient, err := getHTTPClient(config)
+	if err != nil {
+		return nil, err
+	}
 
 	// Initialize filters.
 	filters := filter.NewFilters()
@@ -231,6 +411,13 @@ func New(ctx context.Context, config Config) (*Node, error) {
 	if config.Insecure {
 		opts = append(opts, libp2p.NoSecurity)
 	}
+	if config.PrivateNetworkKey != nil {
+		protector, err := pnet.NewV1ProtectorFromBytes(config.PrivateNetworkKey)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, libp2p.PrivateNetwork(protector))
+	}
 
 	// Initialize the host.
 	basicHost, err := libp2p.New(ctx, opts...)
@@ -244,34 +431,50 @@ func New(ctx context.Context, config Config) (*Node, error) {
 		_ = basicHost.Close()
 	}()
 
+	// Configure banner.
+	banner := banner.New(ctx, banner.Config{
+		Host:                   basicHost,
+		Filters:                filters,
+		BandwidthCounter:       bandwidthCounter,
+		MaxBytesPerSecond:      defaultMaxBytesPerSecond,
+		LogBandwidthUsageStats: true,
+	})
+
+	// Set up the peer metadata exchange.
+	limiter := newResourceLimiter(config)
+	peerMetadata, err := newPeerMetadataExchange(ctx, basicHost, config.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	basicHost.SetStreamHandler(peerMetadataProtocolID, limiter.limitStreamHandler(peerMetadataProtocolID, peerMetadata.handleStream))
+	if config.OrderFeedbackHandler != nil {
+		basicHost.SetStreamHandler(orderFeedbackProtocolID, limiter.limitStreamHandler(orderFeedbackProtocolID, handleOrderFeedbackStream(config.OrderFeedbackHandler)))
+	}
+
 	// Set up the notifee.
 	basicHost.Network().Notify(&notifee{
-		ctx:         ctx,
-		connManager: connManager,
+		ctx:          ctx,
+		connManager:  connManager,
+		banner:       banner,
+		limiter:      limiter,
+		peerMetadata: peerMetadata,
 	})
 
 	// Set up DHT for peer discovery.
 	routingDiscovery := discovery.NewRoutingDiscovery(kadDHT)
 
 	// Set up pubsub and custom validators.
-	pubsubOpts := getPubSubOptions()
+	gossipTracer := newGossipTracer()
+	pubsubOpts := append(getPubSubOptions(), pubsub.WithEventTracer(gossipTracer))
 	ps, err := pubsub.NewGossipSub(ctx, basicHost, pubsubOpts...)
 	if err != nil {
 		return nil, err
 	}
-	if err := registerValidators(ctx, basicHost, config, ps); err != nil {
+	rateValidator, topicValidator, err := registerValidators(ctx, basicHost, config, ps, banner)
+	if err != nil {
 		return nil, err
 	}
 
-	// Configure banner.
-	banner := banner.New(ctx, banner.Config{
-		Host:                   basicHost,
-		Filters:                filters,
-		BandwidthCounter:       bandwidthCounter,
-		MaxBytesPerSecond:      defaultMaxBytesPerSecond,
-		LogBandwidthUsageStats: true,
-	})
-
 	// Create the Node.
 	node := &Node{
 		ctx:              ctx,
@@ -282,17 +485,48 @@ func New(ctx context.Context, config Config) (*Node, error) {
 		dht:              kadDHT,
 		routingDiscovery: routingDiscovery,
 		pubsub:           ps,
+		topicValidator:   topicValidator,
 		banner:           banner,
+		bandwidthCounter: bandwidthCounter,
+		rateValidator:    rateValidator,
+		dialBackoff:      newDialBackoff(),
+		limiter:          limiter,
+		peerMetadata:     peerMetadata,
+		gossipTracer:     gossipTracer,
+		httpClient:       httpClient,
+		lowWatermark:     peerCountLow,
+		highWatermark:    peerCountHigh,
 	}
 
 	return node, nil
 }
 
 // registerValidators registers all the validators we use for incoming and
-// outgoing GossipSub messages.
-func registerValidators(ctx context.Context, basicHost host.Host, config Config, ps *pubsub.PubSub) error {
+// outgoing GossipSub messages. It returns the rate limiting validator so that
+// its stats can be reported, as well as the combined validator so that it can
+// be registered for new topics (see SetTopics).
+func registerValidators(ctx context.Context, basicHost host.Host, config Config, ps *pubsub.PubSub, banner *banner.Banner) (*ratevalidator.Validator, pubsub.Validator, error) {
 	validators := validatorset.New()
 
+	// Drop any messages delivered by graylisted peers.
+	scorer, err := peerscore.New(ctx, peerscore.Config{
+		MyPeerID: basicHost.ID(),
+		Banner:   banner,
+		Params:   config.PeerScore,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	validators.Add("peer scores", scorer.Validate)
+
+	// Drop any messages authored or forwarded by banned peers.
+	validators.Add("peer bans", func(ctx context.Context, sender peer.ID, msg *pubsub.Message) bool {
+		if banner.IsPeerBanned(sender) {
+			return false
+		}
+		return !banner.IsPeerBanned(msg.GetFrom())
+	})
+
 	// Add the rate limiting validator.
 	rateValidator, err := ratevalidator.New(ctx, ratevalidator.Config{
 		MyPeerID:       basicHost.ID(),
@@ -300,16 +534,18 @@ func registerValidators(ctx context.Context, basicHost host.Host, config Config,
 		GlobalBurst:    config.GlobalPubSubMessageBurst,
 		PerPeerLimit:   config.PerPeerPubSubMessageLimit,
 		PerPeerBurst:   config.PerPeerPubSubMessageBurst,
-		MaxMessageSize: constants.MaxOrderSizeInBytes,
+		MaxMessageSize: config.MaxMessageSize,
 	})
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	validators.Add("message rate limiting", rateValidator.Validate)
 
-	// Add the custom validator if there is one.
+	// Add the custom validator if there is one. Only the result of this
+	// validator counts toward peer scores, since the other validators also
+	// drop messages from well-behaved peers (e.g. during bursts).
 	if config.CustomMessageValidator != nil {
-		validators.Add("custom", config.CustomMessageValidator)
+		validators.Add("custom", scorer.Track(config.CustomMessageValidator))
 	}
 
 	// Register the set of validators for all topics that we publish and/or
@@ -326,13 +562,29 @@ func registerValidators(ctx context.Context, basicHost host.Host, config Config,
 	// in the database that don't match the current filter. In most cases, the
 	// subscribe topic will be one of the publish topics so it doesn't matter much
 	// in practice in the current implementation.
-	allTopics := stringset.NewFromSlice(append(config.PublishTopics, config.SubscribeTopic))
-	for topic := range allTopics {
+	for topic := range validatedTopics(config) {
 		if err := ps.RegisterTopicValidator(topic, validators.Validate, pubsub.WithValidatorInline(true)); err != nil {
-			return err
+			return nil, nil, err
 		}
 	}
-	return nil
+	return rateValidator, validators.Validate, nil
+}
+
+// validatedTopics returns the topics that validators are registered for,
+// i.e. all topics that we publish and/or subscribe to.
+func validatedTopics(config Config) stringset.Set {
+	allTopics := stringset.NewFromSlice(append(append(append([]string{}, config.PublishTopics...), config.SubscribeTopic), config.AdditionalSubscribeTopics...))
+	if config.NumTopicShards > 1 {
+		// Messages are only ever sent to and received from the shard topics.
+		shardTopics := stringset.New()
+		for topic := range allTopics {
+			for shard := 0; shard < config.NumTopicShards; shard++ {
+				shardTopics.Add(ShardTopic(topic, config.NumTopicShards, shard))
+			}
+		}
+		allTopics = shardTopics
+	}
+	return allTopics
 }
 
 func getPrivateKey(path string) (p2pcrypto.PrivKey, error) {
@@ -375,25 +627,22 @@ func (n *Node) ID() peer.ID {
 // from its peers. It blocks until an error is encountered or `Stop` is called.
 func (n *Node) Start() error {
 	// Use the default bootstrap list if none was provided.
-	if len(n.config.BootstrapList) == 0 {
+	n.bootstrapListMu.Lock()
+	if n.config.BootstrapList == nil {
 		n.config.BootstrapList = DefaultBootstrapList
 	}
+	n.bootstrapListMu.Unlock()
 
-	// If needed, connect to all peers in the bootstrap list.
+	// If needed, connect to all peers in the bootstrap list and protect their
+	// IP addresses. A signed bootstrap list takes precedence if one can be
+	// fetched.
 	if n.config.UseBootstrapList {
-		if err := ConnectToBootstrapList(n.ctx, n.host, n.config.BootstrapList); err != nil {
-			return err
+		if n.config.SignedBootstrapLists != nil {
+			n.updateSignedBootstrapList(n.ctx)
 		}
-		// Protect the IP addresses for each bootstrap node.
-		bootstrapAddrInfos, err := BootstrapListToAddrInfos(n.config.BootstrapList)
-		if err != nil {
+		if err := n.connectToBootstrapPeers(n.ctx); err != nil {
 			return err
 		}
-		for _, addrInfo := range bootstrapAddrInfos {
-			for _, addr := range addrInfo.Addrs {
-				_ = n.banner.ProtectIP(addr)
-			}
-		}
 	}
 
 	// Immediately attempt to connect to some peers at the rendezvous points.
@@ -433,12 +682,24 @@ func (n *Node) Start() error {
 			//
 			// Note(albrow): Advertise doesn't return an error, so we have no
 			// choice but to assume it worked.
-			for _, rendezvousPoint := range n.config.RendezvousPoints {
+			for _, rendezvousPoint := range n.rendezvousPoints() {
 				discovery.Advertise(n.ctx, n.routingDiscovery, rendezvousPoint, discovery.TTL(advertiseTTL))
 			}
 		}
 	}()
 
+	// Periodically refresh the signed bootstrap list.
+	if n.config.UseBootstrapList && n.config.SignedBootstrapLists != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				log.Debug("closing p2p bootstrap list refresh loop")
+			}()
+			n.refreshBootstrapListLoop(innerCtx)
+		}()
+	}
+
 	// Start message handler loop.
 	messageHandlerErrChan := make(chan error, 1)
 	wg.Add(1)
@@ -447,7 +708,7 @@ func (n *Node) Start() error {
 		defer func() {
 			log.Debug("closing p2p message handler loop")
 		}()
-		messageHandlerErrChan <- n.startMessageHandler(innerCtx)
+		messageHandlerErrChan <- supervisor.Run(innerCtx, supervisor.Config{Name: "p2p.messageHandler"}, n.startMessageHandler)
 	}()
 
 	// Start peer discovery loop.
@@ -458,7 +719,7 @@ func (n *Node) Start() error {
 		defer func() {
 			log.Debug("closing p2p peer discovery loop")
 		}()
-		peerDiscoveryErrChan <- n.startPeerDiscovery(innerCtx)
+		peerDiscoveryErrChan <- supervisor.Run(innerCtx, supervisor.Config{Name: "p2p.peerDiscovery"}, n.startPeerDiscovery)
 	}()
 
 	// If any error channel returns a non-nil error, we cancel the inner context
@@ -513,9 +774,40 @@ func (n *Node) GetNumPeers() int {
 	return n.connManager.GetInfo().ConnCount
 }
 
-// SetStreamHandler registers a handler for a custom protocol.
+// NumOversizedMessagesDropped returns the number of GossipSub messages that
+// were dropped because they exceeded config.MaxMessageSize.
+func (n *Node) NumOversizedMessagesDropped() int64 {
+	return n.rateValidator.NumOversizedMessages()
+}
+
+// BandwidthByProtocol returns the total bandwidth used by the node for each
+// protocol since it was started.
+func (n *Node) BandwidthByProtocol() map[protocol.ID]metrics.Stats {
+	return n.bandwidthCounter.GetBandwidthByProtocol()
+}
+
+// BandwidthByPeer returns the total bandwidth used by the node for each peer
+// since it was started.
+func (n *Node) BandwidthByPeer() map[peer.ID]metrics.Stats {
+	return n.bandwidthCounter.GetBandwidthByPeer()
+}
+
+// DialStats returns the number of dials attempted by the node, grouped by
+// outcome.
+func (n *Node) DialStats() DialStats {
+	return n.dialBackoff.stats(time.Now())
+}
+
+// ResourceLimitStats returns the number of times each resource limit was hit.
+func (n *Node) ResourceLimitStats() ResourceLimitStats {
+	return n.limiter.stats()
+}
+
+// SetStreamHandler registers a handler for a custom protocol. Inbound streams
+// which exceed MaxInboundStreamsPerPeer or MaxInboundStreamsPerProtocol are
+// reset without calling the handler.
 func (n *Node) SetStreamHandler(pid protocol.ID, handler network.StreamHandler) {
-	n.host.SetStreamHandler(pid, handler)
+	n.host.SetStreamHandler(pid, n.limiter.limitStreamHandler(pid, handler))
 }
 
 func (n *Node) NewStream(ctx context.Context, p peer.ID, pids ...protocol.ID) (network.Stream, error) {
@@ -528,20 +820,102 @@ func (n *Node) Neighbors() []peer.ID {
 	return n.host.Network().Peers()
 }
 
+// Disconnect closes all connections to the peer with the given ID. Unlike
+// BanPeer, the peer is allowed to reconnect.
+func (n *Node) Disconnect(peerID peer.ID) error {
+	return n.host.Network().ClosePeer(peerID)
+}
+
 // Connect ensures there is a connection between this host and the peer with
 // given peerInfo. If there is not an active connection, Connect will dial the
 // peer, and block until a connection is open, timeout is exceeded, or an error
 // is returned.
 func (n *Node) Connect(peerInfo peer.AddrInfo, timeout time.Duration) error {
+	if n.banner.IsPeerBanned(peerInfo.ID) {
+		return ErrPeerBanned
+	}
+	if !n.isAllowed(peerInfo) {
+		return ErrPeerNotAllowed
+	}
 	connectCtx, cancel := context.WithTimeout(n.ctx, timeout)
 	defer cancel()
+	// Peers that were explicitly requested are always dialed, even if a recent
+	// dial failed.
 	err := n.host.Connect(connectCtx, peerInfo)
+	n.dialBackoff.recordResult(peerInfo.ID, err, time.Now())
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// BanPeer bans the given peer and disconnects from it. Banned peers cannot
+// connect to this node and any GossipSub messages they author or forward are
+// dropped.
+func (n *Node) BanPeer(ban banner.PeerBan) {
+	n.banner.BanPeer(ban)
+}
+
+// UnbanPeer lifts the ban on the given peer. It returns false if the peer was
+// not banned.
+func (n *Node) UnbanPeer(peerID peer.ID) bool {
+	return n.banner.UnbanPeer(peerID)
+}
+
+// PeerBans returns all active peer bans.
+func (n *Node) PeerBans() []banner.PeerBan {
+	return n.banner.PeerBans()
+}
+
+// BanIPRange bans all IP addresses in the given range and disconnects from
+// any peers connected via one of them. It returns banner.ErrProtectedIP if the
+// range contains the IP address of a bootstrap node.
+func (n *Node) BanIPRange(ban banner.IPRangeBan) error {
+	return n.banner.BanIPRange(ban)
+}
+
+// UnbanIPRange lifts the ban on the given IP range. It returns false if the
+// range was not banned.
+func (n *Node) UnbanIPRange(ipRange net.IPNet) bool {
+	return n.banner.UnbanIPRange(ipRange)
+}
+
+// IPRangeBans returns all IP range bans.
+func (n *Node) IPRangeBans() []banner.IPRangeBan {
+	return n.banner.IPRangeBans()
+}
+
+// AddToAllowlist adds the given entry to the allowlist. While the allowlist is
+// not empty, the node only connects to peers which are on it.
+func (n *Node) AddToAllowlist(entry banner.AllowlistEntry) {
+	n.banner.AddToAllowlist(entry)
+}
+
+// RemoveFromAllowlist removes the given entry from the allowlist. It returns
+// false if the entry was not on the allowlist.
+func (n *Node) RemoveFromAllowlist(entry banner.AllowlistEntry) bool {
+	return n.banner.RemoveFromAllowlist(entry)
+}
+
+// Allowlist returns all entries of the allowlist.
+func (n *Node) Allowlist() []banner.AllowlistEntry {
+	return n.banner.Allowlist()
+}
+
+// isAllowed returns true if the allowlist allows the given peer ID or any of
+// the given addresses.
+func (n *Node) isAllowed(peerInfo peer.AddrInfo) bool {
+	if n.banner.IsAllowed(peerInfo.ID, nil) {
+		return true
+	}
+	for _, addr := range peerInfo.Addrs {
+		if n.banner.IsAllowed(peerInfo.ID, addr) {
+			return true
+		}
+	}
+	return false
+}
+
 // startMessageHandler continuously receives and processes incoming messages
 // until there is an error or the context is canceled. It also checks bandwidth
 // usage on some iterations.
@@ -588,6 +962,7 @@ func (n *Node) startPeerDiscovery(ctx context.Context) error {
 		case <-ctx.Done():
 			return nil
 		case <-ticker.C:
+			n.trimPeers(time.Now())
 			if err := n.findNewPeers(ctx); err != nil {
 				return err
 			}
@@ -596,13 +971,14 @@ func (n *Node) startPeerDiscovery(ctx context.Context) error {
 }
 
 func (n *Node) findNewPeers(ctx context.Context) error {
-	for _, rendezvousPoint := range n.config.RendezvousPoints {
+	lowWatermark, _ := n.PeerCountWatermarks()
+	for _, rendezvousPoint := range n.rendezvousPoints() {
 		currentPeerCount := n.connManager.GetInfo().ConnCount
-		if currentPeerCount >= peerCountLow {
+		if currentPeerCount >= lowWatermark {
 			// We already have enough peers. Nothing to do.
 			return nil
 		}
-		maxNewPeers := peerCountLow - currentPeerCount
+		maxNewPeers := lowWatermark - currentPeerCount
 		log.WithFields(map[string]interface{}{
 			"currentPeerCount": currentPeerCount,
 			"maxNewPeers":      maxNewPeers,
@@ -617,14 +993,26 @@ func (n *Node) findNewPeers(ctx context.Context) error {
 		connectCtx, cancel := context.WithTimeout(ctx, defaultNetworkTimeout)
 		defer cancel()
 		for peer := range peerChan {
-			if peer.ID == n.host.ID() || len(peer.Addrs) == 0 {
+			if peer.ID == n.host.ID() || len(peer.Addrs) == 0 || n.banner.IsPeerBanned(peer.ID) || !n.isAllowed(peer) {
+				continue
+			}
+			if n.host.Network().Connectedness(peer.ID) == network.Connected {
+				continue
+			}
+			if n.dialBackoff.shouldSkip(peer.ID, time.Now()) {
+				log.WithFields(map[string]interface{}{
+					"peerInfo":        peer,
+					"rendezvousPoint": rendezvousPoint,
+				}).Trace("skipping peer because a recent dial failed")
 				continue
 			}
 			log.WithFields(map[string]interface{}{
 				"peerInfo":        peer,
 				"rendezvousPoint": rendezvousPoint,
 			}).Trace("found peer via rendezvous")
-			if err := n.host.Connect(connectCtx, peer); err != nil {
+			err := n.host.Connect(connectCtx, peer)
+			n.dialBackoff.recordResult(peer.ID, err, time.Now())
+			if err != nil {
 				// We still want to try connecting to the other peers. Log the error and
 				// keep going.
 				logPeerConnectionError(peer, err)
@@ -696,13 +1084,32 @@ func (n *Node) receiveBatch(ctx context.Context) ([]*Message, error) {
 	}
 }
 
-// Send sends a message continaing the given data to all connected peers.
+// Send sends a message continaing the given data to all connected peers. If
+// topics are sharded (i.e. config.NumTopicShards is greater than 1), the
+// message is sent to shard 0. Use SendToShard to send a message to a specific
+// shard.
 func (n *Node) Send(data []byte) error {
+	return n.SendToShard(data, 0)
+}
+
+// SendToShard sends a message containing the given data to the given shard of
+// each of the publish topics. If topics are not sharded, the shard is ignored
+// and it behaves exactly like Send.
+func (n *Node) SendToShard(data []byte, shard int) error {
+	if n.config.NumTopicShards > 1 && (shard < 0 || shard >= n.config.NumTopicShards) {
+		return fmt.Errorf("invalid shard %d (must be between 0 and %d)", shard, n.config.NumTopicShards-1)
+	}
 	// Note: If there is an error, we still try to publish to any remaining
 	// topics. We always return the first error that was encountered (if any),
 	// which is assigned to firstErr.
 	var firstErr error
-	for _, topic := range n.config.PublishTopics {
+	n.topicsMu.RLock()
+	publishTopics := n.config.PublishTopics
+	n.topicsMu.RUnlock()
+	for _, topic := range publishTopics {
+		if n.config.NumTopicShards > 1 {
+			topic = ShardTopic(topic, n.config.NumTopicShards, shard)
+		}
 		err := n.pubsub.Publish(topic, data)
 		if err != nil && firstErr == nil {
 			firstErr = err
@@ -711,19 +1118,249 @@ func (n *Node) Send(data []byte) error {
 	return firstErr
 }
 
+// SendToTopicShard sends a message containing the given data to the given shard
+// of a single topic, which must be one of the additional subscribe topics.
+// Unlike SendToShard, it doesn't send the message to the publish topics. If
+// topics are not sharded, the shard is ignored.
+func (n *Node) SendToTopicShard(topic string, data []byte, shard int) error {
+	if n.config.NumTopicShards > 1 && (shard < 0 || shard >= n.config.NumTopicShards) {
+		return fmt.Errorf("invalid shard %d (must be between 0 and %d)", shard, n.config.NumTopicShards-1)
+	}
+	n.topicsMu.RLock()
+	additionalSubscribeTopics := n.config.AdditionalSubscribeTopics
+	n.topicsMu.RUnlock()
+	if !stringset.NewFromSlice(additionalSubscribeTopics).Contains(topic) {
+		return fmt.Errorf("cannot send to topic %q: not an additional subscribe topic", topic)
+	}
+	if n.config.NumTopicShards > 1 {
+		topic = ShardTopic(topic, n.config.NumTopicShards, shard)
+	}
+	return n.pubsub.Publish(topic, data)
+}
+
+// TopicShards returns the number of shards that each topic is split into and
+// the shards that this node is subscribed to. If topics are not sharded,
+// numShards is 0 or 1 and shards is empty.
+func (n *Node) TopicShards() (numShards int, shards []int) {
+	return n.config.NumTopicShards, append([]int{}, n.config.SubscribeShards...)
+}
+
+// rendezvousPoints returns the rendezvous points that this node advertises
+// itself on and looks for new peers on.
+func (n *Node) rendezvousPoints() []string {
+	n.topicsMu.RLock()
+	defer n.topicsMu.RUnlock()
+	return n.config.RendezvousPoints
+}
+
+// subscribeTopics returns the topics that a node with the given config should
+// subscribe to.
+func subscribeTopics(config Config) []string {
+	unshardedTopics := append([]string{config.SubscribeTopic}, config.AdditionalSubscribeTopics...)
+	if config.NumTopicShards <= 1 {
+		return unshardedTopics
+	}
+	topics := make([]string, 0, len(unshardedTopics)*len(config.SubscribeShards))
+	for _, topic := range unshardedTopics {
+		for _, shard := range config.SubscribeShards {
+			topics = append(topics, ShardTopic(topic, config.NumTopicShards, shard))
+		}
+	}
+	return topics
+}
+
+// subscribe subscribes to all of the subscribe topics and forwards all
+// messages to n.incoming until n.ctx is canceled.
+func (n *Node) subscribe() error {
+	n.topicsMu.Lock()
+	defer n.topicsMu.Unlock()
+	incoming := make(chan *pubsub.Message)
+	subs, cancelSubs, err := n.subscribeAndForward(subscribeTopics(n.config), incoming)
+	if err != nil {
+		return err
+	}
+	n.subs = subs
+	n.cancelSubs = cancelSubs
+	n.incoming = incoming
+	return nil
+}
+
+// subscribeAndForward subscribes to the given topics and forwards all messages
+// to incoming until the returned function is called or n.ctx is canceled.
+func (n *Node) subscribeAndForward(topics []string, incoming chan *pubsub.Message) ([]*pubsub.Subscription, context.CancelFunc, error) {
+	subs := []*pubsub.Subscription{}
+	for _, topic := range topics {
+		sub, err := n.pubsub.Subscribe(topic)
+		if err != nil {
+			for _, sub := range subs {
+				sub.Cancel()
+			}
+			return nil, nil, err
+		}
+		subs = append(subs, sub)
+	}
+	ctx, cancel := context.WithCancel(n.ctx)
+	for _, sub := range subs {
+		go func(sub *pubsub.Subscription) {
+			for {
+				msg, err := sub.Next(ctx)
+				if err != nil {
+					if err != context.Canceled {
+						log.WithError(err).WithField("topic", sub.Topic()).Error("could not receive message from subscription")
+					}
+					return
+				}
+				select {
+				case incoming <- msg:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(sub)
+	}
+	return subs, cancel, nil
+}
+
+// SetTopics changes the topics that the node subscribes and publishes to as
+// well as the rendezvous points it uses for peer discovery, e.g. because the
+// order filter changed. Validators are registered for the new topics before
+// they are subscribed to and the node stays subscribed to the old topic until
+// it is subscribed to the new one. The new rendezvous points are advertised
+// right away.
+func (n *Node) SetTopics(subscribeTopic string, publishTopics []string, rendezvousPoints []string) error {
+	if len(rendezvousPoints) == 0 {
+		return errors.New("rendezvousPoints is required")
+	}
+	n.topicsMu.Lock()
+	defer n.topicsMu.Unlock()
+
+	newConfig := n.config
+	newConfig.SubscribeTopic = subscribeTopic
+	newConfig.PublishTopics = append([]string{}, publishTopics...)
+	newConfig.RendezvousPoints = append([]string{}, rendezvousPoints...)
+	if err := n.applyTopics(newConfig); err != nil {
+		return err
+	}
+
+	// Advertise doesn't return an error, so we have no choice but to assume it
+	// worked.
+	for _, rendezvousPoint := range rendezvousPoints {
+		discovery.Advertise(n.ctx, n.routingDiscovery, rendezvousPoint, discovery.TTL(advertiseTTL))
+	}
+	log.WithFields(map[string]interface{}{
+		"subscribeTopic":   subscribeTopic,
+		"publishTopics":    publishTopics,
+		"rendezvousPoints": rendezvousPoints,
+	}).Info("changed p2p topics")
+	return nil
+}
+
+// SetAdditionalSubscribeTopics replaces the additional subscribe topics (see
+// Config.AdditionalSubscribeTopics), e.g. to stop subscribing to the topics of
+// a previous topic version once a migration is over. Like SetTopics, it
+// registers validators for the new topics before subscribing to them.
+func (n *Node) SetAdditionalSubscribeTopics(topics []string) error {
+	n.topicsMu.Lock()
+	defer n.topicsMu.Unlock()
+
+	newConfig := n.config
+	newConfig.AdditionalSubscribeTopics = append([]string{}, topics...)
+	if err := n.applyTopics(newConfig); err != nil {
+		return err
+	}
+	log.WithField("additionalSubscribeTopics", topics).Info("changed additional p2p topics")
+	return nil
+}
+
+// applyTopics changes the topics that the node subscribes and publishes to to
+// the ones in newConfig. n.topicsMu must be held for writing.
+func (n *Node) applyTopics(newConfig Config) error {
+	oldTopics := validatedTopics(n.config)
+	newTopics := validatedTopics(newConfig)
+
+	registeredTopics := []string{}
+	unregisterNewTopics := func() {
+		for _, topic := range registeredTopics {
+			_ = n.pubsub.UnregisterTopicValidator(topic)
+		}
+	}
+	for topic := range newTopics {
+		if oldTopics.Contains(topic) {
+			continue
+		}
+		if err := n.pubsub.RegisterTopicValidator(topic, n.topicValidator, pubsub.WithValidatorInline(true)); err != nil {
+			unregisterNewTopics()
+			return err
+		}
+		registeredTopics = append(registeredTopics, topic)
+	}
+
+	// If the node hasn't subscribed yet, it will subscribe to the new topics
+	// as soon as it starts receiving messages.
+	if n.incoming != nil && !sameTopics(subscribeTopics(newConfig), subscribeTopics(n.config)) {
+		subs, cancelSubs, err := n.subscribeAndForward(subscribeTopics(newConfig), n.incoming)
+		if err != nil {
+			unregisterNewTopics()
+			return err
+		}
+		n.cancelSubs()
+		for _, sub := range n.subs {
+			sub.Cancel()
+		}
+		n.subs = subs
+		n.cancelSubs = cancelSubs
+	}
+
+	for topic := range oldTopics {
+		if newTopics.Contains(topic) {
+			continue
+		}
+		if err := n.pubsub.UnregisterTopicValidator(topic); err != nil {
+			log.WithError(err).WithField("topic", topic).Warn("could not unregister topic validator")
+		}
+	}
+	if err := n.peerMetadata.setTopics(append([]string{newConfig.SubscribeTopic}, newConfig.AdditionalSubscribeTopics...)); err != nil {
+		log.WithError(err).Warn("could not update topics in peer metadata")
+	}
+	n.config.SubscribeTopic = newConfig.SubscribeTopic
+	n.config.PublishTopics = newConfig.PublishTopics
+	n.config.AdditionalSubscribeTopics = newConfig.AdditionalSubscribeTopics
+	n.config.RendezvousPoints = newConfig.RendezvousPoints
+	return nil
+}
+
 // receive returns the next pending message. It blocks if no messages are
 // available. If the given context is canceled, it returns nil, ctx.Err().
 func (n *Node) receive(ctx context.Context) (*Message, error) {
-	if n.sub == nil {
-		var err error
-		n.sub, err = n.pubsub.Subscribe(n.config.SubscribeTopic)
-		if err != nil {
+	if n.incoming == nil {
+		if err := n.subscribe(); err != nil {
 			return nil, err
 		}
 	}
-	msg, err := n.sub.Next(ctx)
-	if err != nil {
-		return nil, err
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case msg := <-n.incoming:
+		var topic string
+		if topics := msg.GetTopicIDs(); len(topics) > 0 {
+			topic = topics[0]
+		}
+		return &Message{From: msg.GetFrom(), Topic: topic, Data: msg.Data}, nil
+	}
+}
+
+// sameTopics returns true if a and b contain the same topics, regardless of
+// their order.
+func sameTopics(a []string, b []string) bool {
+	setA := stringset.NewFromSlice(a)
+	setB := stringset.NewFromSlice(b)
+	if len(setA) != len(setB) {
+		return false
+	}
+	for topic := range setA {
+		if !setB.Contains(topic) {
+			return false
+		}
 	}
-	return &Message{From: msg.GetFrom(), Data: msg.Data}, nil
+	return true
 }
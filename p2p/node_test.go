@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/0xProject/0x-mesh/p2p/banner"
+	"github.com/0xProject/0x-mesh/p2p/peerscore"
 	"github.com/google/uuid"
 	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
 	p2pnet "github.com/libp2p/go-libp2p-core/network"
@@ -215,17 +216,210 @@ func TestPingPong(t *testing.T) {
 	time.Sleep(5 * time.Second)
 
 	// Send ping from node0 to node1
-	pingMessage := &Message{From: node0.host.ID(), Data: []byte("ping\n")}
+	pingMessage := &Message{From: node0.host.ID(), Topic: testTopic, Data: []byte("ping\n")}
 	require.NoError(t, node0.Send(pingMessage.Data))
 	const pingPongTimeout = 20 * time.Second
 	expectMessage(t, node1, pingMessage, pingPongTimeout)
 
 	// Send pong from node1 to node0
-	pongMessage := &Message{From: node1.host.ID(), Data: []byte("pong\n")}
+	pongMessage := &Message{From: node1.host.ID(), Topic: testTopic, Data: []byte("pong\n")}
 	require.NoError(t, node1.Send(pongMessage.Data))
 	expectMessage(t, node0, pongMessage, pingPongTimeout)
 }
 
+func TestQUIC(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	newQUICTestNode := func() *Node {
+		return newTestNodeWithConfig(t, ctx, nil, Config{
+			SubscribeTopic:   testTopic,
+			PublishTopics:    []string{testTopic},
+			MessageHandler:   &dummyMessageHandler{},
+			RendezvousPoints: testRendezvousPoints,
+			DataDir:          "/tmp/0x-mesh/p2p-testing/" + uuid.New().String(),
+			EnableQUIC:       true,
+		})
+	}
+	node0 := newQUICTestNode()
+	node1 := newQUICTestNode()
+
+	// Only give node0 the QUIC addresses of node1 so that it has to connect
+	// over QUIC.
+	quicAddrs := []ma.Multiaddr{}
+	for _, addr := range node1.Multiaddrs() {
+		if _, err := addr.ValueForProtocol(ma.P_QUIC); err == nil {
+			quicAddrs = append(quicAddrs, addr)
+		}
+	}
+	require.NotEmpty(t, quicAddrs, "node1 is not listening for QUIC connections")
+	require.NoError(t, node0.Connect(peer.AddrInfo{ID: node1.ID(), Addrs: quicAddrs}, testConnectionTimeout))
+	conns := node0.host.Network().ConnsToPeer(node1.ID())
+	require.NotEmpty(t, conns)
+	_, err := conns[0].RemoteMultiaddr().ValueForProtocol(ma.P_QUIC)
+	assert.NoError(t, err, "connection is not using QUIC")
+
+	// QUIC connections are always encrypted.
+	_, err = New(ctx, Config{
+		SubscribeTopic: testTopic,
+		MessageHandler: &dummyMessageHandler{},
+		DataDir:        "/tmp/0x-mesh/p2p-testing/" + uuid.New().String(),
+		EnableQUIC:     true,
+		Insecure:       true,
+	})
+	assert.Equal(t, ErrQUICRequiresSecurity, err)
+}
+
+func TestPeerScoreConfig(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	peerScore := peerscore.DefaultParams()
+	peerScore.GraylistThreshold = 1
+	_, err := New(ctx, Config{
+		SubscribeTopic:   testTopic,
+		MessageHandler:   &dummyMessageHandler{},
+		RendezvousPoints: testRendezvousPoints,
+		DataDir:          "/tmp/0x-mesh/p2p-testing/" + uuid.New().String(),
+		PeerScore:        peerScore,
+	})
+	assert.EqualError(t, err, "config.PeerScore is invalid: GraylistThreshold cannot be positive")
+}
+
+func TestPrivateNetwork(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	newPrivateTestNode := func(psk *[32]byte) *Node {
+		return newTestNodeWithConfig(t, ctx, nil, Config{
+			SubscribeTopic:    testTopic,
+			PublishTopics:     []string{testTopic},
+			MessageHandler:    &dummyMessageHandler{},
+			RendezvousPoints:  testRendezvousPoints,
+			DataDir:           "/tmp/0x-mesh/p2p-testing/" + uuid.New().String(),
+			PrivateNetworkKey: psk,
+		})
+	}
+	newPSK := func() *[32]byte {
+		var psk [32]byte
+		_, err := rand.Read(psk[:])
+		require.NoError(t, err)
+		return &psk
+	}
+	psk := newPSK()
+	node0 := newPrivateTestNode(psk)
+	node1 := newPrivateTestNode(psk)
+	connectTestNodes(t, node0, node1)
+
+	// Nodes with a different key or without a key cannot connect to the
+	// private network.
+	for _, outsider := range []*Node{newPrivateTestNode(newPSK()), newTestNode(t, ctx, nil)} {
+		err := outsider.Connect(peer.AddrInfo{ID: node0.ID(), Addrs: node0.Multiaddrs()}, testConnectionTimeout)
+		assert.Error(t, err)
+	}
+
+	_, err := New(ctx, Config{
+		SubscribeTopic:    testTopic,
+		MessageHandler:    &dummyMessageHandler{},
+		RendezvousPoints:  testRendezvousPoints,
+		DataDir:           "/tmp/0x-mesh/p2p-testing/" + uuid.New().String(),
+		EnableQUIC:        true,
+		PrivateNetworkKey: psk,
+	})
+	assert.Equal(t, ErrQUICPrivateNetwork, err)
+}
+
+func TestSetTopics(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	notifee := &testNotifee{
+		streams: make(chan p2pnet.Stream),
+	}
+	node0 := newTestNode(t, ctx, notifee)
+	node1 := newTestNode(t, ctx, notifee)
+	require.NoError(t, node1.subscribe())
+	connectTestNodes(t, node0, node1)
+	waitForGossipSubStreams(t, ctx, notifee, 4, testStreamTimeout)
+	time.Sleep(5 * time.Second)
+
+	// Make sure that node1 is subscribed to the old topic before changing it.
+	pingMessage := &Message{From: node0.host.ID(), Topic: testTopic, Data: []byte("ping\n")}
+	require.NoError(t, node0.Send(pingMessage.Data))
+	const messageTimeout = 20 * time.Second
+	expectMessage(t, node1, pingMessage, messageTimeout)
+
+	const newTopic = "0x-mesh-testing-new-topic"
+	newRendezvousPoints := []string{"0x-mesh-testing-new-rendezvous"}
+	require.NoError(t, node0.SetTopics(newTopic, []string{newTopic}, newRendezvousPoints))
+	require.NoError(t, node1.SetTopics(newTopic, []string{newTopic}, newRendezvousPoints))
+	assert.Equal(t, newRendezvousPoints, node1.rendezvousPoints())
+	time.Sleep(5 * time.Second)
+
+	pongMessage := &Message{From: node0.host.ID(), Topic: newTopic, Data: []byte("pong\n")}
+	require.NoError(t, node0.Send(pongMessage.Data))
+	expectMessage(t, node1, pongMessage, messageTimeout)
+}
+
+func TestAdditionalSubscribeTopics(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const additionalTopic = "0x-mesh-testing-additional-topic"
+	notifee := &testNotifee{
+		streams: make(chan p2pnet.Stream),
+	}
+	newNode := func() *Node {
+		return newTestNodeWithConfig(t, ctx, notifee, Config{
+			SubscribeTopic:            testTopic,
+			AdditionalSubscribeTopics: []string{additionalTopic},
+			PublishTopics:             []string{testTopic},
+			MessageHandler:            &dummyMessageHandler{},
+			RendezvousPoints:          testRendezvousPoints,
+			DataDir:                   "/tmp/0x-mesh/p2p-testing/" + uuid.New().String(),
+		})
+	}
+	node0 := newNode()
+	node1 := newNode()
+	require.NoError(t, node1.subscribe())
+	connectTestNodes(t, node0, node1)
+	waitForGossipSubStreams(t, ctx, notifee, 4, testStreamTimeout)
+	time.Sleep(5 * time.Second)
+
+	message := &Message{From: node0.host.ID(), Topic: additionalTopic, Data: []byte("additional\n")}
+	require.NoError(t, node0.SendToTopicShard(additionalTopic, message.Data, 0))
+	expectMessage(t, node1, message, 20*time.Second)
+
+	assert.Error(t, node0.SendToTopicShard("0x-mesh-testing-unknown-topic", message.Data, 0))
+}
+
+func TestSubscribeTopicsWithShards(t *testing.T) {
+	t.Parallel()
+
+	topics := subscribeTopics(Config{
+		SubscribeTopic:            "a",
+		AdditionalSubscribeTopics: []string{"b"},
+		NumTopicShards:            4,
+		SubscribeShards:           []int{1, 3},
+	})
+	assert.Equal(t, []string{
+		ShardTopic("a", 4, 1),
+		ShardTopic("a", 4, 3),
+		ShardTopic("b", 4, 1),
+		ShardTopic("b", 4, 3),
+	}, topics)
+}
+
 func expectMessage(t *testing.T, node *Node, expected *Message, timeout time.Duration) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
@@ -389,6 +583,39 @@ func TestProtectIP(t *testing.T) {
 	require.NoError(t, node1.Connect(node0AddrInfo, testConnectionTimeout))
 }
 
+func TestAllowlist(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	node0 := newTestNode(t, ctx, nil)
+	node1 := newTestNode(t, ctx, nil)
+	node2 := newTestNode(t, ctx, nil)
+	go startNodeAndCheckError(t, node0)
+	go startNodeAndCheckError(t, node1)
+	go startNodeAndCheckError(t, node2)
+
+	node1AddrInfo := peer.AddrInfo{
+		ID:    node1.ID(),
+		Addrs: node1.Multiaddrs(),
+	}
+	node2AddrInfo := peer.AddrInfo{
+		ID:    node2.ID(),
+		Addrs: node2.Multiaddrs(),
+	}
+
+	// Once node2 is on the allowlist, node0 should only be able to connect to
+	// node2.
+	node0.AddToAllowlist(banner.AllowlistEntry{PeerID: node2.ID()})
+	require.Equal(t, ErrPeerNotAllowed, node0.Connect(node1AddrInfo, testConnectionTimeout))
+	require.NoError(t, node0.Connect(node2AddrInfo, testConnectionTimeout))
+
+	// Removing node2 from the allowlist makes it empty, so node0 should be able
+	// to connect to any peer again.
+	require.True(t, node0.RemoveFromAllowlist(banner.AllowlistEntry{PeerID: node2.ID()}))
+	require.False(t, node0.RemoveFromAllowlist(banner.AllowlistEntry{PeerID: node2.ID()}))
+	require.NoError(t, node0.Connect(node1AddrInfo, testConnectionTimeout))
+}
+
 func TestRateValidatorGlobal(t *testing.T) {
 	t.Parallel()
 
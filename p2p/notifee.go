@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/0xProject/0x-mesh/p2p/banner"
 	connmgr "github.com/libp2p/go-libp2p-connmgr"
 	p2pnet "github.com/libp2p/go-libp2p-core/network"
 	ma "github.com/multiformats/go-multiaddr"
@@ -20,8 +21,11 @@ const (
 
 // notifee receives notifications for network-related events.
 type notifee struct {
-	ctx         context.Context
-	connManager *connmgr.BasicConnMgr
+	ctx          context.Context
+	connManager  *connmgr.BasicConnMgr
+	banner       *banner.Banner
+	limiter      *resourceLimiter
+	peerMetadata *peerMetadataExchange
 }
 
 var _ p2pnet.Notifiee = &notifee{}
@@ -38,6 +42,43 @@ func (n *notifee) Connected(network p2pnet.Network, conn p2pnet.Conn) {
 		"remotePeerID":       conn.RemotePeer(),
 		"remoteMultiaddress": conn.RemoteMultiaddr(),
 	}).Trace("connected to peer")
+	if n.banner.IsPeerBanned(conn.RemotePeer()) {
+		log.WithFields(map[string]interface{}{
+			"remotePeerID":       conn.RemotePeer(),
+			"remoteMultiaddress": conn.RemoteMultiaddr(),
+		}).Debug("closing connection to banned peer")
+		// Notifiees must not block, so we close the connection in a separate
+		// goroutine.
+		go func() {
+			_ = conn.Close()
+		}()
+		return
+	}
+	if !n.banner.IsAllowed(conn.RemotePeer(), conn.RemoteMultiaddr()) {
+		log.WithFields(map[string]interface{}{
+			"remotePeerID":       conn.RemotePeer(),
+			"remoteMultiaddress": conn.RemoteMultiaddr(),
+		}).Debug("closing connection to peer which is not on the allowlist")
+		go func() {
+			_ = conn.Close()
+		}()
+		return
+	}
+	if limit := n.limiter.checkConnection(network, conn); limit != "" {
+		log.WithFields(map[string]interface{}{
+			"remotePeerID":       conn.RemotePeer(),
+			"remoteMultiaddress": conn.RemoteMultiaddr(),
+			"limit":              limit,
+		}).Debug("closing inbound connection because a resource limit was hit")
+		go func() {
+			_ = conn.Close()
+		}()
+		return
+	}
+	// Metadata is only exchanged once per peer, even if there is more than one
+	// connection to it. Counting the connections is not enough because both
+	// peers may dial each other at the same time.
+	n.peerMetadata.fetchOnce(conn.RemotePeer())
 }
 
 // Disconnected is called when a connection closed
@@ -46,6 +87,9 @@ func (n *notifee) Disconnected(network p2pnet.Network, conn p2pnet.Conn) {
 		"remotePeerID":       conn.RemotePeer(),
 		"remoteMultiaddress": conn.RemoteMultiaddr(),
 	}).Trace("disconnected from peer")
+	if len(network.ConnsToPeer(conn.RemotePeer())) == 0 {
+		n.peerMetadata.forget(conn.RemotePeer())
+	}
 }
 
 // OpenedStream is called when a stream opened
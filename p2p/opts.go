@@ -12,10 +12,12 @@ import (
 	leveldbStore "github.com/ipfs/go-ds-leveldb"
 	libp2p "github.com/libp2p/go-libp2p"
 	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peerstore"
 	dht "github.com/libp2p/go-libp2p-kad-dht"
 	dhtopts "github.com/libp2p/go-libp2p-kad-dht/opts"
 	"github.com/libp2p/go-libp2p-peerstore/pstoreds"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	libp2pquic "github.com/libp2p/go-libp2p-quic-transport"
 	tcp "github.com/libp2p/go-tcp-transport"
 	ws "github.com/libp2p/go-ws-transport"
 	ma "github.com/multiformats/go-multiaddr"
@@ -45,6 +47,10 @@ func getHostOptions(ctx context.Context, config Config) ([]libp2p.Option, error)
 		return nil, err
 	}
 
+	if config.ProxyURL != "" {
+		return getProxiedHostOptions(ctx, config, tcpBindAddr)
+	}
+
 	// HACK(albrow): As a workaround for AutoNAT issues, ping ifconfig.me to
 	// determine our public IP address on boot. This will work for nodes that
 	// would be reachable via a public IP address but don't know what it is (e.g.
@@ -61,14 +67,22 @@ func getHostOptions(ctx context.Context, config Config) ([]libp2p.Option, error)
 	if err != nil {
 		return nil, err
 	}
+	listenAddrs := []ma.Multiaddr{tcpBindAddr, wsBindAddr}
 	advertiseAddrs := []ma.Multiaddr{tcpAdvertiseAddr, wsAdvertiseAddr}
-
-	// Set up the peerstore to use LevelDB.
-	store, err := leveldbStore.NewDatastore(getPeerstoreDir(config.DataDir), nil)
-	if err != nil {
-		return nil, err
+	if config.EnableQUIC {
+		quicBindAddr, err := ma.NewMultiaddr(fmt.Sprintf("/ip4/0.0.0.0/udp/%d/quic", config.QUICPort))
+		if err != nil {
+			return nil, err
+		}
+		quicAdvertiseAddr, err := ma.NewMultiaddr(fmt.Sprintf("/ip4/%s/udp/%d/quic", publicIP, config.QUICPort))
+		if err != nil {
+			return nil, err
+		}
+		listenAddrs = append(listenAddrs, quicBindAddr)
+		advertiseAddrs = append(advertiseAddrs, quicAdvertiseAddr)
 	}
-	pstore, err := pstoreds.NewPeerstore(ctx, store, pstoreds.DefaultOpts())
+
+	pstore, err := newPeerstore(ctx, config)
 	if err != nil {
 		return nil, err
 	}
@@ -80,15 +94,66 @@ func getHostOptions(ctx context.Context, config Config) ([]libp2p.Option, error)
 	}
 	newWebsocketTransport := ws.NewWithOptions(ws.TLSClientConfig(tlsConfig))
 
-	return []libp2p.Option{
+	opts := []libp2p.Option{
 		libp2p.Transport(tcp.NewTCPTransport),
 		libp2p.Transport(newWebsocketTransport),
-		libp2p.ListenAddrs(tcpBindAddr, wsBindAddr),
+		libp2p.ListenAddrs(listenAddrs...),
 		libp2p.AddrsFactory(newAddrsFactory(advertiseAddrs)),
 		libp2p.Peerstore(pstore),
+	}
+	if config.EnableQUIC {
+		// The QUIC transport is constructed with the private key of the host,
+		// which it uses for the TLS handshake.
+		opts = append(opts, libp2p.Transport(libp2pquic.NewTransport))
+	}
+	return opts, nil
+}
+
+// getProxiedHostOptions returns the host options used if config.ProxyURL is
+// set. Outbound TCP connections are dialed through the SOCKS5 proxy. The
+// WebSocket and QUIC transports are disabled because their dials cannot be
+// proxied. We don't look up our public IP address either, since that
+// would require a request which bypasses the proxy.
+func getProxiedHostOptions(ctx context.Context, config Config, tcpBindAddr ma.Multiaddr) ([]libp2p.Option, error) {
+	dialer, err := newProxyDialer(config.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	pstore, err := newPeerstore(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return []libp2p.Option{
+		libp2p.Transport(newProxyTCPTransport(dialer)),
+		libp2p.ListenAddrs(tcpBindAddr),
+		libp2p.Peerstore(pstore),
+	}, nil
+}
+
+// getHTTPClient returns the client used to fetch signed bootstrap lists. If
+// config.ProxyURL is set, requests are sent through the proxy too.
+func getHTTPClient(config Config) (*http.Client, error) {
+	if config.ProxyURL == "" {
+		return http.DefaultClient, nil
+	}
+	dialer, err := newProxyDialer(config.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport: &http.Transport{Dial: dialer.Dial},
 	}, nil
 }
 
+// newPeerstore returns a peerstore which is persisted with LevelDB.
+func newPeerstore(ctx context.Context, config Config) (peerstore.Peerstore, error) {
+	store, err := leveldbStore.NewDatastore(getPeerstoreDir(config.DataDir), nil)
+	if err != nil {
+		return nil, err
+	}
+	return pstoreds.NewPeerstore(ctx, store, pstoreds.DefaultOpts())
+}
+
 func getPubSubOptions() []pubsub.Option {
 	// Use the default options.
 	return nil
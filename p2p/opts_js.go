@@ -4,6 +4,7 @@ package p2p
 
 import (
 	"context"
+	"net/http"
 
 	libp2p "github.com/libp2p/go-libp2p"
 	"github.com/libp2p/go-libp2p-core/host"
@@ -27,6 +28,12 @@ const (
 )
 
 func getHostOptions(ctx context.Context, config Config) ([]libp2p.Option, error) {
+	if config.ProxyURL != "" {
+		return nil, ErrProxyNotSupported
+	}
+	if config.EnableQUIC {
+		return nil, ErrQUICNotSupported
+	}
 	return []libp2p.Option{
 		libp2p.Transport(ws.New),
 		// Don't listen on any addresses by default. We can't accept incoming
@@ -42,6 +49,12 @@ func getPubSubOptions() []pubsub.Option {
 	}
 }
 
+// getHTTPClient returns the client used to fetch signed bootstrap lists.
+// Proxies are not supported in browser environments.
+func getHTTPClient(config Config) (*http.Client, error) {
+	return http.DefaultClient, nil
+}
+
 // NewDHT returns a new Kademlia DHT instance configured to work with 0x Mesh
 // in browser environments.
 func NewDHT(ctx context.Context, storageDir string, host host.Host) (*dht.IpfsDHT, error) {
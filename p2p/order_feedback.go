@@ -0,0 +1,153 @@
+package p2p
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// orderFeedbackProtocolID is the protocol used to send OrderFeedback. The
+	// sender opens a stream, writes the JSON encoded feedback and closes the
+	// stream. There is no response.
+	orderFeedbackProtocolID = protocol.ID("/0x-mesh/order-feedback/version/1")
+	// orderFeedbackTimeout is the maximum amount of time it can take to send
+	// or receive feedback.
+	orderFeedbackTimeout = defaultNetworkTimeout
+	// maxOrderFeedbackSize is the maximum size of the JSON encoded feedback.
+	// Larger feedback is ignored.
+	maxOrderFeedbackSize = 64 * 1024
+	// MaxOrderFeedbackCodes is the maximum number of different rejection codes
+	// in OrderFeedback.
+	MaxOrderFeedbackCodes = 32
+	// MaxOrderFeedbackHashesPerCode is the maximum number of order hashes for
+	// each rejection code in OrderFeedback.
+	MaxOrderFeedbackHashesPerCode = 16
+	// maxOrderFeedbackCodeLength is the maximum length (in bytes) of a
+	// rejection code.
+	maxOrderFeedbackCodeLength = 64
+	// orderHashLength is the length of a hex encoded order hash, including the
+	// "0x" prefix.
+	orderHashLength = 66
+)
+
+// OrderFeedbackHandler is called for each OrderFeedback received from a peer.
+// The feedback has already been validated.
+type OrderFeedbackHandler func(from peer.ID, feedback *OrderFeedback)
+
+// OrderFeedback tells the author of orders which were shared through GossipSub
+// which of them the sender rejected and why, so that the author can stop
+// sharing orders the network won't accept. Rejections are aggregated by code.
+// Feedback received from peers is self-reported and is not verified in any
+// way.
+type OrderFeedback struct {
+	Rejections []*OrderRejections `json:"rejections"`
+}
+
+// OrderRejections describes the orders which were rejected for the same
+// reason.
+type OrderRejections struct {
+	// Code is the code of the reason for the rejection (e.g. "OrderExpired").
+	Code string `json:"code"`
+	// Count is the number of orders which were rejected with this code.
+	Count int `json:"count"`
+	// OrderHashes are the hex encoded hashes of up to
+	// MaxOrderFeedbackHashesPerCode of the rejected orders.
+	OrderHashes []string `json:"orderHashes"`
+}
+
+// validate returns an error if the feedback exceeds one of the limits.
+func (f *OrderFeedback) validate() error {
+	if len(f.Rejections) > MaxOrderFeedbackCodes {
+		return fmt.Errorf("cannot contain more than %d rejection codes", MaxOrderFeedbackCodes)
+	}
+	for _, rejections := range f.Rejections {
+		if rejections == nil {
+			return fmt.Errorf("rejections cannot be null")
+		}
+		if rejections.Code == "" || len(rejections.Code) > maxOrderFeedbackCodeLength {
+			return fmt.Errorf("rejection code must be between 1 and %d bytes", maxOrderFeedbackCodeLength)
+		}
+		if len(rejections.OrderHashes) > MaxOrderFeedbackHashesPerCode {
+			return fmt.Errorf("cannot contain more than %d order hashes per rejection code", MaxOrderFeedbackHashesPerCode)
+		}
+		if rejections.Count < len(rejections.OrderHashes) {
+			return fmt.Errorf("count cannot be less than the number of order hashes")
+		}
+		for _, orderHash := range rejections.OrderHashes {
+			if len(orderHash) != orderHashLength {
+				return fmt.Errorf("invalid order hash: %q", orderHash)
+			}
+		}
+	}
+	return nil
+}
+
+// SendOrderFeedback sends the given feedback to the given peer. It returns an
+// error if the peer cannot be reached or does not accept order feedback (see
+// Config.OrderFeedbackHandler).
+func (n *Node) SendOrderFeedback(ctx context.Context, peerID peer.ID, feedback *OrderFeedback) error {
+	if err := feedback.validate(); err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(feedback)
+	if err != nil {
+		return err
+	}
+	if len(encoded) > maxOrderFeedbackSize {
+		return fmt.Errorf("order feedback cannot be larger than %d bytes when encoded", maxOrderFeedbackSize)
+	}
+	ctx, cancel := context.WithTimeout(ctx, orderFeedbackTimeout)
+	defer cancel()
+	stream, err := n.host.NewStream(ctx, peerID, orderFeedbackProtocolID)
+	if err != nil {
+		return err
+	}
+	_ = stream.SetWriteDeadline(time.Now().Add(orderFeedbackTimeout))
+	if _, err := stream.Write(encoded); err != nil {
+		_ = stream.Reset()
+		return err
+	}
+	return stream.Close()
+}
+
+// handleOrderFeedbackStream returns a stream handler which passes the feedback
+// received from peers to the given handler. Feedback which is too large or
+// invalid is ignored.
+func handleOrderFeedbackStream(handler OrderFeedbackHandler) network.StreamHandler {
+	return func(stream network.Stream) {
+		defer func() {
+			_ = stream.Close()
+		}()
+		logger := log.WithField("remotePeerID", stream.Conn().RemotePeer())
+		_ = stream.SetReadDeadline(time.Now().Add(orderFeedbackTimeout))
+		encoded, err := ioutil.ReadAll(io.LimitReader(stream, maxOrderFeedbackSize+1))
+		if err != nil {
+			logger.WithError(err).Trace("could not receive order feedback")
+			_ = stream.Reset()
+			return
+		}
+		if len(encoded) > maxOrderFeedbackSize {
+			logger.Debug("ignoring order feedback which is too large")
+			return
+		}
+		var feedback OrderFeedback
+		if err := json.Unmarshal(encoded, &feedback); err != nil {
+			logger.WithError(err).Debug("ignoring order feedback which could not be decoded")
+			return
+		}
+		if err := feedback.validate(); err != nil {
+			logger.WithError(err).Debug("ignoring invalid order feedback")
+			return
+		}
+		handler(stream.Conn().RemotePeer(), &feedback)
+	}
+}
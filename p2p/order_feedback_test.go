@@ -0,0 +1,93 @@
+// +build !js
+
+package p2p
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOrderHash = "0x8e2a3e4bd3d1d8d2d1a3d4a0c3d2c8bb4b8bd5c9b49c1d15c11f8e2ab3c6f1b2"
+
+func TestOrderFeedback(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type receivedFeedback struct {
+		from     peer.ID
+		feedback *OrderFeedback
+	}
+	received := make(chan receivedFeedback, 1)
+	node0 := newTestNodeWithConfig(t, ctx, nil, Config{
+		SubscribeTopic:   testTopic,
+		PublishTopics:    []string{testTopic},
+		MessageHandler:   &dummyMessageHandler{},
+		RendezvousPoints: testRendezvousPoints,
+		DataDir:          "/tmp/0x-mesh/p2p-testing/" + uuid.New().String(),
+		OrderFeedbackHandler: func(from peer.ID, feedback *OrderFeedback) {
+			received <- receivedFeedback{from: from, feedback: feedback}
+		},
+	})
+	node1 := newTestNode(t, ctx, nil)
+	go startNodeAndCheckError(t, node0)
+	go startNodeAndCheckError(t, node1)
+	connectTestNodes(t, node0, node1)
+
+	feedback := &OrderFeedback{
+		Rejections: []*OrderRejections{
+			{
+				Code:        "OrderExpired",
+				Count:       3,
+				OrderHashes: []string{testOrderHash},
+			},
+		},
+	}
+	require.NoError(t, node1.SendOrderFeedback(ctx, node0.ID(), feedback))
+	select {
+	case actual := <-received:
+		assert.Equal(t, node1.ID(), actual.from)
+		assert.Equal(t, feedback, actual.feedback)
+	case <-time.After(testStreamTimeout):
+		t.Fatal("timed out waiting for order feedback")
+	}
+
+	// node1 has no OrderFeedbackHandler, so it doesn't accept order feedback.
+	assert.Error(t, node0.SendOrderFeedback(ctx, node1.ID(), feedback))
+}
+
+func TestOrderFeedbackValidate(t *testing.T) {
+	t.Parallel()
+
+	validRejections := func() *OrderRejections {
+		return &OrderRejections{Code: "OrderExpired", Count: 1, OrderHashes: []string{testOrderHash}}
+	}
+	assert.NoError(t, (&OrderFeedback{Rejections: []*OrderRejections{validRejections()}}).validate())
+	assert.Error(t, (&OrderFeedback{Rejections: make([]*OrderRejections, MaxOrderFeedbackCodes+1)}).validate())
+	assert.Error(t, (&OrderFeedback{Rejections: []*OrderRejections{nil}}).validate())
+
+	emptyCode := validRejections()
+	emptyCode.Code = ""
+	longCode := validRejections()
+	longCode.Code = strings.Repeat("a", maxOrderFeedbackCodeLength+1)
+	tooManyHashes := validRejections()
+	tooManyHashes.OrderHashes = make([]string, MaxOrderFeedbackHashesPerCode+1)
+	for i := range tooManyHashes.OrderHashes {
+		tooManyHashes.OrderHashes[i] = testOrderHash
+	}
+	tooManyHashes.Count = len(tooManyHashes.OrderHashes)
+	countTooLow := validRejections()
+	countTooLow.Count = 0
+	invalidHash := validRejections()
+	invalidHash.OrderHashes = []string{"0x1234"}
+	for _, rejections := range []*OrderRejections{emptyCode, longCode, tooManyHashes, countTooLow, invalidHash} {
+		assert.Error(t, (&OrderFeedback{Rejections: []*OrderRejections{rejections}}).validate())
+	}
+}
@@ -0,0 +1,289 @@
+package p2p
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// peerMetadataProtocolID is the protocol used to exchange PeerMetadata.
+	// Whenever we connect to a peer, we open a stream and the peer responds
+	// with its JSON encoded metadata.
+	peerMetadataProtocolID = protocol.ID("/0x-mesh/peer-metadata/version/1")
+	// peerMetadataKey is the peerstore key under which the JSON encoded
+	// metadata of each peer is stored.
+	peerMetadataKey = "0x-mesh-peer-metadata"
+	// peerMetadataTimeout is the maximum amount of time it can take to
+	// exchange metadata with a peer.
+	peerMetadataTimeout = defaultNetworkTimeout
+	// maxPeerMetadataSize is the maximum size of the JSON encoded metadata.
+	// Larger metadata is ignored.
+	maxPeerMetadataSize = 16 * 1024
+	// maxPeerNameLength and maxPeerContactURLLength are the maximum lengths
+	// (in bytes) of PeerMetadata.Name and PeerMetadata.ContactURL.
+	maxPeerNameLength       = 64
+	maxPeerContactURLLength = 256
+	// maxPeerMetadataItems is the maximum number of chain IDs and of topics in
+	// PeerMetadata.
+	maxPeerMetadataItems = 16
+	// maxPeerMetadataStreamAttempts is the number of times we try to open a
+	// stream to request the metadata of a peer. The connection on which the
+	// first stream is opened may be closed right away, e.g. because both peers
+	// dialed each other at the same time.
+	maxPeerMetadataStreamAttempts = 3
+	// peerMetadataRetryInterval is how long to wait before trying to open
+	// another stream.
+	peerMetadataRetryInterval = 100 * time.Millisecond
+)
+
+// PeerMetadata is optional information about a node which is sent to each of
+// its peers after connecting. It is provided by the operator of the node and
+// helps network participants get in touch with each other, e.g. during
+// incidents. Metadata received from peers is self-reported and is not
+// verified in any way.
+type PeerMetadata struct {
+	// Name is a human-readable name for the node.
+	Name string `json:"name,omitempty"`
+	// ContactURL is a URL (e.g. a website or a "mailto:" URL) which can be used
+	// to contact the operator of the node.
+	ContactURL string `json:"contactURL,omitempty"`
+	// ChainIDs are the IDs of the chains the node supports.
+	ChainIDs []int `json:"chainIDs,omitempty"`
+	// Topics are the topics (i.e. order filters) the node subscribes to.
+	Topics []string `json:"topics,omitempty"`
+}
+
+// validate returns an error if the metadata exceeds one of the limits.
+func (m *PeerMetadata) validate() error {
+	if len(m.Name) > maxPeerNameLength {
+		return fmt.Errorf("name cannot be longer than %d bytes", maxPeerNameLength)
+	}
+	if len(m.ContactURL) > maxPeerContactURLLength {
+		return fmt.Errorf("contact URL cannot be longer than %d bytes", maxPeerContactURLLength)
+	}
+	if len(m.ChainIDs) > maxPeerMetadataItems {
+		return fmt.Errorf("cannot contain more than %d chain IDs", maxPeerMetadataItems)
+	}
+	if len(m.Topics) > maxPeerMetadataItems {
+		return fmt.Errorf("cannot contain more than %d topics", maxPeerMetadataItems)
+	}
+	return nil
+}
+
+// peerMetadataExchange sends our metadata to peers and stores the metadata
+// received from peers in the peerstore.
+type peerMetadataExchange struct {
+	ctx      context.Context
+	host     host.Host
+	mu       sync.RWMutex
+	metadata PeerMetadata
+	encoded  []byte
+	// fetched contains the connected peers whose metadata was already
+	// requested.
+	fetchedMu sync.Mutex
+	fetched   map[peer.ID]struct{}
+}
+
+func newPeerMetadataExchange(ctx context.Context, host host.Host, metadata *PeerMetadata) (*peerMetadataExchange, error) {
+	if metadata == nil {
+		metadata = &PeerMetadata{}
+	}
+	encoded, err := encodePeerMetadata(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("config.Metadata is invalid: %s", err)
+	}
+	return &peerMetadataExchange{
+		ctx:      ctx,
+		host:     host,
+		metadata: *metadata,
+		encoded:  encoded,
+		fetched:  map[peer.ID]struct{}{},
+	}, nil
+}
+
+// encodePeerMetadata validates and encodes the given metadata.
+func encodePeerMetadata(metadata *PeerMetadata) ([]byte, error) {
+	if err := metadata.validate(); err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	if len(encoded) > maxPeerMetadataSize {
+		return nil, fmt.Errorf("cannot be larger than %d bytes when encoded", maxPeerMetadataSize)
+	}
+	return encoded, nil
+}
+
+// setTopics changes the topics in our metadata. Peers which are already
+// connected keep the metadata they received when they connected.
+func (e *peerMetadataExchange) setTopics(topics []string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	metadata := e.metadata
+	metadata.Topics = topics
+	encoded, err := encodePeerMetadata(&metadata)
+	if err != nil {
+		return err
+	}
+	e.metadata = metadata
+	e.encoded = encoded
+	return nil
+}
+
+// handleStream sends our metadata to the peer which opened the stream.
+func (e *peerMetadataExchange) handleStream(stream network.Stream) {
+	defer func() {
+		_ = stream.Close()
+	}()
+	_ = stream.SetWriteDeadline(time.Now().Add(peerMetadataTimeout))
+	e.mu.RLock()
+	encoded := e.encoded
+	e.mu.RUnlock()
+	if _, err := stream.Write(encoded); err != nil {
+		log.WithFields(map[string]interface{}{
+			"error":        err.Error(),
+			"remotePeerID": stream.Conn().RemotePeer(),
+		}).Trace("could not send peer metadata")
+		_ = stream.Reset()
+	}
+}
+
+// fetchOnce requests the metadata of the given peer in the background unless
+// it was already requested since we connected to the peer.
+func (e *peerMetadataExchange) fetchOnce(peerID peer.ID) {
+	e.fetchedMu.Lock()
+	defer e.fetchedMu.Unlock()
+	if _, found := e.fetched[peerID]; found {
+		return
+	}
+	e.fetched[peerID] = struct{}{}
+	go e.fetch(peerID)
+}
+
+// forget is called when we are no longer connected to the given peer so that
+// its metadata is requested again when it reconnects.
+func (e *peerMetadataExchange) forget(peerID peer.ID) {
+	e.fetchedMu.Lock()
+	defer e.fetchedMu.Unlock()
+	delete(e.fetched, peerID)
+}
+
+// fetch requests the metadata of the given peer and stores it in the
+// peerstore. Peers which don't support the protocol or send invalid metadata
+// are ignored.
+func (e *peerMetadataExchange) fetch(peerID peer.ID) {
+	ctx, cancel := context.WithTimeout(e.ctx, peerMetadataTimeout)
+	defer cancel()
+	logger := log.WithField("remotePeerID", peerID)
+	stream, err := e.openStream(ctx, peerID)
+	if err != nil {
+		logger.WithError(err).Trace("could not request peer metadata")
+		return
+	}
+	defer func() {
+		_ = stream.Close()
+	}()
+	_ = stream.SetReadDeadline(time.Now().Add(peerMetadataTimeout))
+	encoded, err := ioutil.ReadAll(io.LimitReader(stream, maxPeerMetadataSize+1))
+	if err != nil {
+		logger.WithError(err).Trace("could not receive peer metadata")
+		return
+	}
+	if len(encoded) > maxPeerMetadataSize {
+		logger.Debug("ignoring peer metadata which is too large")
+		return
+	}
+	var metadata PeerMetadata
+	if err := json.Unmarshal(encoded, &metadata); err != nil {
+		logger.WithError(err).Debug("ignoring peer metadata which could not be decoded")
+		return
+	}
+	if err := metadata.validate(); err != nil {
+		logger.WithError(err).Debug("ignoring invalid peer metadata")
+		return
+	}
+	// The encoded metadata is stored because values in the persistent
+	// peerstore must be gob-encodable.
+	if err := e.host.Peerstore().Put(peerID, peerMetadataKey, encoded); err != nil {
+		logger.WithError(err).Warn("could not store peer metadata")
+	}
+}
+
+// openStream opens a stream to request the metadata of the given peer. It
+// retries up to maxPeerMetadataStreamAttempts times as long as we are still
+// connected to the peer.
+func (e *peerMetadataExchange) openStream(ctx context.Context, peerID peer.ID) (network.Stream, error) {
+	for attempt := 1; ; attempt++ {
+		stream, err := e.host.NewStream(ctx, peerID, peerMetadataProtocolID)
+		if err == nil {
+			return stream, nil
+		}
+		if attempt == maxPeerMetadataStreamAttempts || e.host.Network().Connectedness(peerID) != network.Connected {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(peerMetadataRetryInterval):
+		}
+	}
+}
+
+// get returns the metadata of the given peer which was stored in the
+// peerstore. It returns nil if we didn't receive any metadata from the peer.
+func (e *peerMetadataExchange) get(peerID peer.ID) *PeerMetadata {
+	value, err := e.host.Peerstore().Get(peerID, peerMetadataKey)
+	if err != nil {
+		return nil
+	}
+	encoded, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	var metadata PeerMetadata
+	if err := json.Unmarshal(encoded, &metadata); err != nil {
+		return nil
+	}
+	return &metadata
+}
+
+// ConnectedPeer describes a peer the node is connected to.
+type ConnectedPeer struct {
+	ID    peer.ID
+	Addrs []string
+	// Metadata is nil if the peer didn't send any metadata (e.g. because it
+	// doesn't support the protocol or we are still waiting for it).
+	Metadata *PeerMetadata
+}
+
+// ConnectedPeers returns the peers the node is currently connected to along
+// with their metadata.
+func (n *Node) ConnectedPeers() []*ConnectedPeer {
+	peerIDs := n.host.Network().Peers()
+	peers := make([]*ConnectedPeer, len(peerIDs))
+	for i, peerID := range peerIDs {
+		addrs := []string{}
+		for _, conn := range n.host.Network().ConnsToPeer(peerID) {
+			addrs = append(addrs, conn.RemoteMultiaddr().String())
+		}
+		peers[i] = &ConnectedPeer{
+			ID:       peerID,
+			Addrs:    addrs,
+			Metadata: n.peerMetadata.get(peerID),
+		}
+	}
+	return peers
+}
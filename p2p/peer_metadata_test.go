@@ -0,0 +1,86 @@
+// +build !js
+
+package p2p
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerMetadataExchange(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	node0 := newTestNode(t, ctx, nil)
+	node1Metadata := &PeerMetadata{
+		Name:       "node1",
+		ContactURL: "mailto:ops@example.com",
+		ChainIDs:   []int{1337},
+		Topics:     []string{testTopic},
+	}
+	node1 := newTestNodeWithConfig(t, ctx, nil, Config{
+		SubscribeTopic:   testTopic,
+		PublishTopics:    []string{testTopic},
+		MessageHandler:   &dummyMessageHandler{},
+		RendezvousPoints: testRendezvousPoints,
+		DataDir:          "/tmp/0x-mesh/p2p-testing/" + uuid.New().String(),
+		Metadata:         node1Metadata,
+	})
+	go startNodeAndCheckError(t, node0)
+	go startNodeAndCheckError(t, node1)
+	connectTestNodes(t, node0, node1)
+
+	// Metadata is exchanged in the background after connecting. Nodes of other
+	// tests may connect as well, so look for the peers by ID.
+	var node1AsPeer, node0AsPeer *ConnectedPeer
+	deadline := time.Now().Add(testStreamTimeout)
+	for {
+		node1AsPeer = findConnectedPeer(node0, node1.ID())
+		node0AsPeer = findConnectedPeer(node1, node0.ID())
+		if node1AsPeer != nil && node1AsPeer.Metadata != nil && node0AsPeer != nil && node0AsPeer.Metadata != nil {
+			break
+		}
+		require.True(t, time.Now().Before(deadline), "timed out waiting for peer metadata")
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	assert.NotEmpty(t, node1AsPeer.Addrs)
+	assert.Equal(t, node1Metadata, node1AsPeer.Metadata)
+	// node0 has no metadata configured, so it sends empty metadata.
+	assert.Equal(t, &PeerMetadata{}, node0AsPeer.Metadata)
+}
+
+func findConnectedPeer(node *Node, id peer.ID) *ConnectedPeer {
+	for _, connectedPeer := range node.ConnectedPeers() {
+		if connectedPeer.ID == id {
+			return connectedPeer
+		}
+	}
+	return nil
+}
+
+func TestPeerMetadataValidate(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, (&PeerMetadata{Name: "node", ContactURL: "https://example.com"}).validate())
+	assert.Error(t, (&PeerMetadata{Name: strings.Repeat("a", maxPeerNameLength+1)}).validate())
+	assert.Error(t, (&PeerMetadata{ContactURL: strings.Repeat("a", maxPeerContactURLLength+1)}).validate())
+	assert.Error(t, (&PeerMetadata{ChainIDs: make([]int, maxPeerMetadataItems+1)}).validate())
+	assert.Error(t, (&PeerMetadata{Topics: make([]string, maxPeerMetadataItems+1)}).validate())
+
+	_, err := New(context.Background(), Config{
+		MessageHandler:   &dummyMessageHandler{},
+		RendezvousPoints: testRendezvousPoints,
+		DataDir:          "/tmp/0x-mesh/p2p-testing/" + uuid.New().String(),
+		Metadata:         &PeerMetadata{Name: strings.Repeat("a", maxPeerNameLength+1)},
+	})
+	assert.Error(t, err)
+}
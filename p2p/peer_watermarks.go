@@ -0,0 +1,102 @@
+package p2p
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/metrics"
+	"github.com/libp2p/go-libp2p-core/peer"
+	log "github.com/sirupsen/logrus"
+)
+
+// InvalidPeerCountWatermarksError is returned by SetPeerCountWatermarks if the
+// watermarks are out of range.
+type InvalidPeerCountWatermarksError struct {
+	Low  int
+	High int
+}
+
+func (e InvalidPeerCountWatermarksError) Error() string {
+	return fmt.Sprintf("invalid peer count watermarks (low: %d, high: %d): must satisfy 0 < low <= %d and low <= high <= %d", e.Low, e.High, peerCountLow, peerCountHigh)
+}
+
+// PeerCountWatermarks returns the number of peers the node tries to stay
+// connected to (low) and the number of peers above which it closes
+// connections until it is back at low (high).
+func (n *Node) PeerCountWatermarks() (low int, high int) {
+	n.watermarksMu.RLock()
+	defer n.watermarksMu.RUnlock()
+	return n.lowWatermark, n.highWatermark
+}
+
+// SetPeerCountWatermarks changes the peer count watermarks (see
+// PeerCountWatermarks) without restarting the node. They can be lowered to
+// reduce the resources used by the node, but cannot be raised above their
+// initial values, which are also the watermarks of the connection manager. If
+// the node is connected to more than high peers, it closes the connections to
+// the least valuable ones the next time it looks for new peers.
+func (n *Node) SetPeerCountWatermarks(low int, high int) error {
+	if low <= 0 || low > peerCountLow || high < low || high > peerCountHigh {
+		return InvalidPeerCountWatermarksError{Low: low, High: high}
+	}
+	n.watermarksMu.Lock()
+	defer n.watermarksMu.Unlock()
+	n.lowWatermark = low
+	n.highWatermark = high
+	return nil
+}
+
+// BandwidthTotals returns the total bandwidth used by the node since it was
+// started, together with the current rates.
+func (n *Node) BandwidthTotals() metrics.Stats {
+	return n.bandwidthCounter.GetBandwidthTotals()
+}
+
+// trimPeers closes the connections to the peers with the lowest connection
+// manager score if the node is connected to more peers than the high
+// watermark, until it is back at the low watermark. The connection manager
+// only does this for its own watermarks, which can't be changed. Like the
+// connection manager, it never closes connections to peers which were first
+// seen less than peerGraceDuration before now.
+func (n *Node) trimPeers(now time.Time) {
+	low, high := n.PeerCountWatermarks()
+	peers := n.host.Network().Peers()
+	if len(peers) <= high {
+		return
+	}
+	candidates := make([]peer.ID, 0, len(peers))
+	values := make(map[peer.ID]int, len(peers))
+	for _, peerID := range peers {
+		tagInfo := n.connManager.GetTagInfo(peerID)
+		if tagInfo == nil {
+			continue
+		}
+		if now.Sub(tagInfo.FirstSeen) < peerGraceDuration {
+			continue
+		}
+		candidates = append(candidates, peerID)
+		values[peerID] = tagInfo.Value
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return values[candidates[i]] < values[candidates[j]]
+	})
+	numToClose := len(peers) - low
+	if numToClose > len(candidates) {
+		numToClose = len(candidates)
+	}
+	log.WithFields(map[string]interface{}{
+		"peerCount":     len(peers),
+		"lowWatermark":  low,
+		"highWatermark": high,
+		"numToClose":    numToClose,
+	}).Debug("closing connections to peers above the high watermark")
+	for _, peerID := range candidates[:numToClose] {
+		if err := n.host.Network().ClosePeer(peerID); err != nil {
+			log.WithFields(map[string]interface{}{
+				"error":        err.Error(),
+				"remotePeerID": peerID,
+			}).Warn("could not close connection to peer")
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// +build !js
+
+package p2p
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetPeerCountWatermarks(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	node := newTestNode(t, ctx, nil)
+	low, high := node.PeerCountWatermarks()
+	assert.Equal(t, peerCountLow, low)
+	assert.Equal(t, peerCountHigh, high)
+
+	for _, watermarks := range [][2]int{
+		{0, 10},
+		{20, 10},
+		{peerCountLow + 1, peerCountHigh},
+		{10, peerCountHigh + 1},
+	} {
+		err := node.SetPeerCountWatermarks(watermarks[0], watermarks[1])
+		assert.Equal(t, InvalidPeerCountWatermarksError{Low: watermarks[0], High: watermarks[1]}, err)
+	}
+	require.NoError(t, node.SetPeerCountWatermarks(10, 15))
+	low, high = node.PeerCountWatermarks()
+	assert.Equal(t, 10, low)
+	assert.Equal(t, 15, high)
+}
+
+func TestTrimPeers(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	node0 := newTestNode(t, ctx, nil)
+	node1 := newTestNode(t, ctx, nil)
+	node2 := newTestNode(t, ctx, nil)
+	connectTestNodes(t, node0, node1)
+	connectTestNodes(t, node0, node2)
+	require.Len(t, node0.host.Network().Peers(), 2)
+	require.NoError(t, node0.SetPeerCountWatermarks(1, 1))
+
+	// Peers are not trimmed during their grace period.
+	node0.trimPeers(time.Now())
+	assert.Len(t, node0.host.Network().Peers(), 2)
+
+	node0.trimPeers(time.Now().Add(peerGraceDuration))
+	assert.Len(t, node0.host.Network().Peers(), 1)
+}
@@ -0,0 +1,299 @@
+// Package peerscore keeps track of a score for each peer based on the
+// GossipSub messages it delivers. Peers whose score drops below a threshold
+// are graylisted (i.e. all of their messages are dropped) and, below a lower
+// threshold, banned so that they no longer take up a slot in our mesh.
+//
+// The scoring is modeled after the peer scoring in GossipSub v1.1, which we
+// can't use yet because our go-libp2p-pubsub fork is based on v0.2 (see the
+// replace directive in go.mod).
+package peerscore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/0xProject/0x-mesh/p2p/banner"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	log "github.com/sirupsen/logrus"
+)
+
+// Dummy declaration to ensure that Validate can be used as a pubsub.Validator
+var _ pubsub.Validator = (&Scorer{}).Validate
+
+// Params are the parameters used to compute peer scores. The score of a peer
+// is the sum of its scores in each topic, where the topic score is:
+//
+//	ValidMessageWeight * min(validMessages, ValidMessageCap) +
+//	InvalidMessageWeight * invalidMessages^2
+//
+// validMessages and invalidMessages are counters that are incremented for
+// every valid or invalid message the peer delivers first, and decay over time.
+type Params struct {
+	// ValidMessageWeight is the weight of the valid message counter. It must
+	// not be negative.
+	ValidMessageWeight float64
+	// ValidMessageCap is the maximum value of the valid message counter that
+	// counts toward the topic score. It must not be negative.
+	ValidMessageCap float64
+	// InvalidMessageWeight is the weight of the square of the invalid message
+	// counter. It must not be positive.
+	InvalidMessageWeight float64
+	// TopicScoreCap is the maximum score a peer can get from all topics
+	// combined. It only limits positive scores, so that a peer cannot build up
+	// enough of a score to make up for sending invalid messages later on. A
+	// TopicScoreCap of 0 means there is no cap.
+	TopicScoreCap float64
+	// DecayInterval is how often the counters decay.
+	DecayInterval time.Duration
+	// Decay is the factor that the counters are multiplied by every
+	// DecayInterval. It must be between 0 and 1.
+	Decay float64
+	// DecayToZero is the value below which a decayed counter is reset to 0.
+	DecayToZero float64
+	// GraylistThreshold is the score below which all messages from a peer are
+	// dropped without being validated. It must not be positive.
+	GraylistThreshold float64
+	// BanThreshold is the score below which a peer is banned for BanDuration.
+	// It must not be greater than GraylistThreshold.
+	BanThreshold float64
+	// BanDuration is how long peers are banned for after their score drops
+	// below BanThreshold. The score of a banned peer is reset so that it starts
+	// over once the ban expires.
+	BanDuration time.Duration
+}
+
+// DefaultParams returns the default Params. With the defaults, a peer is
+// graylisted after delivering around 10 invalid messages in a short amount of
+// time and banned for an hour after around 20.
+func DefaultParams() *Params {
+	return &Params{
+		ValidMessageWeight:   0.1,
+		ValidMessageCap:      100,
+		InvalidMessageWeight: -1,
+		TopicScoreCap:        10,
+		DecayInterval:        time.Minute,
+		Decay:                0.9,
+		DecayToZero:          0.01,
+		GraylistThreshold:    -100,
+		BanThreshold:         -400,
+		BanDuration:          time.Hour,
+	}
+}
+
+// Validate returns an error if the params are invalid.
+func (p *Params) Validate() error {
+	switch {
+	case p.ValidMessageWeight < 0:
+		return errors.New("ValidMessageWeight cannot be negative")
+	case p.ValidMessageCap < 0:
+		return errors.New("ValidMessageCap cannot be negative")
+	case p.InvalidMessageWeight > 0:
+		return errors.New("InvalidMessageWeight cannot be positive")
+	case p.TopicScoreCap < 0:
+		return errors.New("TopicScoreCap cannot be negative")
+	case p.DecayInterval <= 0:
+		return errors.New("DecayInterval must be positive")
+	case p.Decay <= 0 || p.Decay >= 1:
+		return errors.New("Decay must be between 0 and 1")
+	case p.DecayToZero <= 0 || p.DecayToZero >= 1:
+		return errors.New("DecayToZero must be between 0 and 1")
+	case p.GraylistThreshold > 0:
+		return errors.New("GraylistThreshold cannot be positive")
+	case p.BanThreshold > p.GraylistThreshold:
+		return errors.New("BanThreshold cannot be greater than GraylistThreshold")
+	case p.BanDuration <= 0:
+		return errors.New("BanDuration must be positive")
+	}
+	return nil
+}
+
+// Banner is used to ban peers whose score drops below Params.BanThreshold.
+// It is implemented by *banner.Banner.
+type Banner interface {
+	BanPeer(ban banner.PeerBan)
+}
+
+// Config is a set of configuration options for the Scorer.
+type Config struct {
+	// MyPeerID is the peer ID of the host. Our own messages are never scored.
+	MyPeerID peer.ID
+	// Banner is used to ban peers.
+	Banner Banner
+	// Params are the parameters used to compute peer scores.
+	Params *Params
+}
+
+// Scorer keeps track of the scores of peers.
+type Scorer struct {
+	config Config
+	mu     sync.Mutex
+	// topicStats contains the counters for each peer and topic.
+	topicStats map[peer.ID]map[string]*topicStats
+}
+
+type topicStats struct {
+	validMessages   float64
+	invalidMessages float64
+}
+
+// New creates and returns a new Scorer. The counters are decayed in the
+// background until the context is canceled.
+func New(ctx context.Context, config Config) (*Scorer, error) {
+	if config.MyPeerID.String() == "" {
+		return nil, errors.New("config.MyPeerID is required")
+	} else if config.Banner == nil {
+		return nil, errors.New("config.Banner is required")
+	} else if config.Params == nil {
+		return nil, errors.New("config.Params is required")
+	}
+	if err := config.Params.Validate(); err != nil {
+		return nil, fmt.Errorf("config.Params is invalid: %s", err)
+	}
+	scorer := &Scorer{
+		config:     config,
+		topicStats: map[peer.ID]map[string]*topicStats{},
+	}
+	go scorer.periodicallyDecay(ctx)
+	return scorer, nil
+}
+
+// Validate drops all messages from peers that are graylisted.
+func (s *Scorer) Validate(ctx context.Context, sender peer.ID, msg *pubsub.Message) bool {
+	if sender == s.config.MyPeerID {
+		return true
+	}
+	if score := s.Score(sender); score < s.config.Params.GraylistThreshold {
+		log.WithFields(log.Fields{
+			"from":  sender.String(),
+			"score": score,
+		}).Trace("dropping GossipSub message from graylisted peer")
+		return false
+	}
+	return true
+}
+
+// Track returns a validator which runs the given validator and updates the
+// score of the peer that delivered the message based on the result.
+func (s *Scorer) Track(validator pubsub.Validator) pubsub.Validator {
+	return func(ctx context.Context, sender peer.ID, msg *pubsub.Message) bool {
+		isValid := validator(ctx, sender, msg)
+		if sender == s.config.MyPeerID {
+			return isValid
+		}
+		// Don't penalize peers for messages which could not be validated
+		// because we are shutting down.
+		select {
+		case <-ctx.Done():
+			return isValid
+		default:
+		}
+		s.deliver(sender, msg.GetTopicIDs(), isValid)
+		return isValid
+	}
+}
+
+// Score returns the current score of the given peer.
+func (s *Scorer) Score(peerID peer.ID) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.score(peerID)
+}
+
+// score returns the current score of the given peer. s.mu must be held.
+func (s *Scorer) score(peerID peer.ID) float64 {
+	params := s.config.Params
+	score := 0.0
+	for _, stats := range s.topicStats[peerID] {
+		validMessages := stats.validMessages
+		if validMessages > params.ValidMessageCap {
+			validMessages = params.ValidMessageCap
+		}
+		score += params.ValidMessageWeight*validMessages + params.InvalidMessageWeight*stats.invalidMessages*stats.invalidMessages
+	}
+	if params.TopicScoreCap > 0 && score > params.TopicScoreCap {
+		score = params.TopicScoreCap
+	}
+	return score
+}
+
+// deliver updates the counters of the given peer after it delivered a message
+// for the given topics, and bans the peer if its score drops below the ban
+// threshold.
+func (s *Scorer) deliver(peerID peer.ID, topics []string, isValid bool) {
+	s.mu.Lock()
+	peerStats, found := s.topicStats[peerID]
+	if !found {
+		peerStats = map[string]*topicStats{}
+		s.topicStats[peerID] = peerStats
+	}
+	for _, topic := range topics {
+		stats, found := peerStats[topic]
+		if !found {
+			stats = &topicStats{}
+			peerStats[topic] = stats
+		}
+		if isValid {
+			stats.validMessages++
+		} else {
+			stats.invalidMessages++
+		}
+	}
+	score := s.score(peerID)
+	shouldBan := score < s.config.Params.BanThreshold
+	if shouldBan {
+		delete(s.topicStats, peerID)
+	}
+	s.mu.Unlock()
+
+	if shouldBan {
+		s.config.Banner.BanPeer(banner.PeerBan{
+			PeerID: peerID,
+			Reason: fmt.Sprintf("peer score %.2f is below the ban threshold", score),
+			Expiry: time.Now().Add(s.config.Params.BanDuration),
+		})
+	}
+}
+
+// decay multiplies all counters by Params.Decay and removes the counters that
+// reach Params.DecayToZero.
+func (s *Scorer) decay() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	params := s.config.Params
+	decayCounter := func(counter float64) float64 {
+		counter *= params.Decay
+		if counter < params.DecayToZero {
+			return 0
+		}
+		return counter
+	}
+	for peerID, peerStats := range s.topicStats {
+		for topic, stats := range peerStats {
+			stats.validMessages = decayCounter(stats.validMessages)
+			stats.invalidMessages = decayCounter(stats.invalidMessages)
+			if stats.validMessages == 0 && stats.invalidMessages == 0 {
+				delete(peerStats, topic)
+			}
+		}
+		if len(peerStats) == 0 {
+			delete(s.topicStats, peerID)
+		}
+	}
+}
+
+func (s *Scorer) periodicallyDecay(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Params.DecayInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.decay()
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package peerscore
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0xProject/0x-mesh/p2p/banner"
+	"github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var peerIDStrings = []string{
+	"16Uiu2HAmGd949LwaV4KNvK2WDSiMVy7xEmW983VH75CMmefmMpP7",
+	"16Uiu2HAmVqV4kepwSiNRmvKiBxwpt4EQJi3pAe9auSMyGjzA1eBZ",
+}
+
+var peerIDs []peer.ID
+
+func init() {
+	for _, peerIDString := range peerIDStrings {
+		peerID, _ := peer.IDB58Decode(peerIDString)
+		peerIDs = append(peerIDs, peerID)
+	}
+}
+
+const testTopic = "test-topic"
+
+type testBanner struct {
+	mu   sync.Mutex
+	bans []banner.PeerBan
+}
+
+func (b *testBanner) BanPeer(ban banner.PeerBan) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.bans = append(b.bans, ban)
+}
+
+func (b *testBanner) getBans() []banner.PeerBan {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]banner.PeerBan{}, b.bans...)
+}
+
+func newTestScorer(t *testing.T, ctx context.Context, params *Params) (*Scorer, *testBanner) {
+	testBanner := &testBanner{}
+	scorer, err := New(ctx, Config{
+		MyPeerID: peerIDs[0],
+		Banner:   testBanner,
+		Params:   params,
+	})
+	require.NoError(t, err)
+	return scorer, testBanner
+}
+
+func newTestMessage(topics ...string) *pubsub.Message {
+	return &pubsub.Message{
+		Message: &pb.Message{
+			Data:     []byte("test message"),
+			TopicIDs: topics,
+		},
+	}
+}
+
+func alwaysValid(ctx context.Context, sender peer.ID, msg *pubsub.Message) bool {
+	return true
+}
+
+func neverValid(ctx context.Context, sender peer.ID, msg *pubsub.Message) bool {
+	return false
+}
+
+func TestScorerGraylistAndBan(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	params := DefaultParams()
+	params.GraylistThreshold = -4
+	params.BanThreshold = -9
+	scorer, testBanner := newTestScorer(t, ctx, params)
+	validate := scorer.Track(neverValid)
+	sender := peerIDs[1]
+	msg := newTestMessage(testTopic)
+
+	// The penalty is the square of the number of invalid messages.
+	assert.False(t, validate(ctx, sender, msg))
+	assert.Equal(t, -1.0, scorer.Score(sender))
+	assert.True(t, scorer.Validate(ctx, sender, msg), "peer should not be graylisted yet")
+	assert.False(t, validate(ctx, sender, msg))
+	assert.False(t, validate(ctx, sender, msg))
+	assert.Equal(t, -9.0, scorer.Score(sender))
+	assert.False(t, scorer.Validate(ctx, sender, msg), "peer should be graylisted")
+	assert.Empty(t, testBanner.getBans())
+
+	// The fourth invalid message drops the score below the ban threshold.
+	assert.False(t, validate(ctx, sender, msg))
+	bans := testBanner.getBans()
+	require.Len(t, bans, 1)
+	assert.Equal(t, sender, bans[0].PeerID)
+	assert.WithinDuration(t, time.Now().Add(params.BanDuration), bans[0].Expiry, time.Second)
+	assert.Equal(t, 0.0, scorer.Score(sender), "score should be reset after the peer is banned")
+}
+
+func TestScorerIgnoresOwnMessages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	scorer, _ := newTestScorer(t, ctx, DefaultParams())
+	myPeerID := peerIDs[0]
+	msg := newTestMessage(testTopic)
+	assert.False(t, scorer.Track(neverValid)(ctx, myPeerID, msg))
+	assert.Equal(t, 0.0, scorer.Score(myPeerID))
+}
+
+func TestScorerCaps(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	params := DefaultParams()
+	params.ValidMessageWeight = 1
+	params.ValidMessageCap = 3
+	params.TopicScoreCap = 5
+	scorer, _ := newTestScorer(t, ctx, params)
+	validate := scorer.Track(alwaysValid)
+	sender := peerIDs[1]
+
+	// The valid message counter is capped for each topic.
+	for i := 0; i < 5; i++ {
+		assert.True(t, validate(ctx, sender, newTestMessage("topic-a")))
+	}
+	assert.Equal(t, 3.0, scorer.Score(sender))
+
+	// The sum of the topic scores is capped.
+	for i := 0; i < 5; i++ {
+		assert.True(t, validate(ctx, sender, newTestMessage("topic-b")))
+	}
+	assert.Equal(t, 5.0, scorer.Score(sender))
+
+	// The cap doesn't limit penalties for invalid messages.
+	assert.False(t, scorer.Track(neverValid)(ctx, sender, newTestMessage("topic-a")))
+	assert.Equal(t, 5.0, scorer.Score(sender))
+	assert.False(t, scorer.Track(neverValid)(ctx, sender, newTestMessage("topic-a")))
+	assert.False(t, scorer.Track(neverValid)(ctx, sender, newTestMessage("topic-a")))
+	assert.Equal(t, -3.0, scorer.Score(sender))
+}
+
+func TestScorerDecay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	params := DefaultParams()
+	params.Decay = 0.5
+	params.DecayToZero = 0.3
+	scorer, _ := newTestScorer(t, ctx, params)
+	sender := peerIDs[1]
+	msg := newTestMessage(testTopic)
+	validate := scorer.Track(neverValid)
+	assert.False(t, validate(ctx, sender, msg))
+	assert.False(t, validate(ctx, sender, msg))
+	assert.Equal(t, -4.0, scorer.Score(sender))
+
+	scorer.decay()
+	assert.Equal(t, -1.0, scorer.Score(sender))
+	scorer.decay()
+	assert.Equal(t, -0.25, scorer.Score(sender))
+
+	// The counter is reset once it drops below DecayToZero.
+	scorer.decay()
+	assert.Equal(t, 0.0, scorer.Score(sender))
+	scorer.mu.Lock()
+	assert.Empty(t, scorer.topicStats)
+	scorer.mu.Unlock()
+}
+
+func TestParamsValidate(t *testing.T) {
+	assert.NoError(t, DefaultParams().Validate())
+
+	testCases := []struct {
+		name   string
+		modify func(*Params)
+	}{
+		{"positive InvalidMessageWeight", func(p *Params) { p.InvalidMessageWeight = 1 }},
+		{"negative ValidMessageWeight", func(p *Params) { p.ValidMessageWeight = -1 }},
+		{"Decay of 1", func(p *Params) { p.Decay = 1 }},
+		{"zero DecayInterval", func(p *Params) { p.DecayInterval = 0 }},
+		{"positive GraylistThreshold", func(p *Params) { p.GraylistThreshold = 1 }},
+		{"BanThreshold above GraylistThreshold", func(p *Params) { p.BanThreshold = p.GraylistThreshold + 1 }},
+		{"zero BanDuration", func(p *Params) { p.BanDuration = 0 }},
+	}
+	for _, testCase := range testCases {
+		params := DefaultParams()
+		testCase.modify(params)
+		assert.Error(t, params.Validate(), testCase.name)
+	}
+}
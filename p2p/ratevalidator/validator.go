@@ -3,6 +3,7 @@ package ratevalidator
 import (
 	"context"
 	"errors"
+	"sync/atomic"
 	"time"
 
 	"github.com/karlseguin/ccache"
@@ -35,6 +36,9 @@ type Validator struct {
 	config        Config
 	globalLimiter *trackingRateLimiter
 	peerLimiters  *ccache.Cache
+	// numOversizedMessages is the number of messages that were dropped because
+	// they exceeded config.MaxMessageSize. It must be accessed atomically.
+	numOversizedMessages int64
 }
 
 // Config is a set of configuration options for the validator.
@@ -103,6 +107,12 @@ func (v *Validator) Validate(ctx context.Context, peerID peer.ID, msg *pubsub.Me
 	}
 
 	if data := msg.GetData(); data != nil && len(data) > v.config.MaxMessageSize {
+		atomic.AddInt64(&v.numOversizedMessages, 1)
+		log.WithFields(log.Fields{
+			"from":                  peerID.String(),
+			"maxMessageSizeInBytes": v.config.MaxMessageSize,
+			"actualSizeInBytes":     len(data),
+		}).Trace("dropping GossipSub message that exceeds maximum size")
 		return false
 	}
 
@@ -141,6 +151,12 @@ func (v *Validator) isClosed() bool {
 	}
 }
 
+// NumOversizedMessages returns the number of messages that have been dropped
+// because they exceeded the maximum message size.
+func (v *Validator) NumOversizedMessages() int64 {
+	return atomic.LoadInt64(&v.numOversizedMessages)
+}
+
 func (v *Validator) periodicallyLogStats(ctx context.Context) {
 	ticker := time.NewTicker(logStatsInterval)
 	for {
@@ -125,4 +125,6 @@ func TestValidatorMaxMessageSize(t *testing.T) {
 		})
 		assert.False(t, isValid, "message should be invalid")
 	}
+
+	assert.Equal(t, int64(1), validator.NumOversizedMessages())
 }
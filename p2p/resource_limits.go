@@ -0,0 +1,168 @@
+package p2p
+
+import (
+	"sync"
+
+	"github.com/0xProject/0x-mesh/common/supervisor"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultMaxInboundStreamsPerPeer is the default value for
+	// Config.MaxInboundStreamsPerPeer.
+	defaultMaxInboundStreamsPerPeer = 8
+	// defaultMaxInboundStreamsPerProtocol is the default value for
+	// Config.MaxInboundStreamsPerProtocol.
+	defaultMaxInboundStreamsPerProtocol = 256
+	// defaultMaxConnectionsPerPeer is the default value for
+	// Config.MaxConnectionsPerPeer.
+	defaultMaxConnectionsPerPeer = 4
+	// defaultMaxConnections is the default value for Config.MaxConnections. The
+	// connection manager prunes connections once there are more than
+	// peerCountHigh, so this only needs to leave enough headroom for
+	// connections which are still being negotiated.
+	defaultMaxConnections = 4 * peerCountHigh
+)
+
+// Resource limits, as reported in ResourceLimitStats.
+const (
+	LimitInboundStreamsPerPeer     = "inboundStreamsPerPeer"
+	LimitInboundStreamsPerProtocol = "inboundStreamsPerProtocol"
+	LimitConnectionsPerPeer        = "connectionsPerPeer"
+	LimitConnections               = "connections"
+)
+
+// ResourceLimitStats contains the number of times each resource limit was hit
+// since the node was started.
+type ResourceLimitStats struct {
+	// NumLimitHits is the number of streams or connections that were rejected,
+	// by limit (one of the Limit constants).
+	NumLimitHits map[string]int64
+	// NumInboundStreams is the number of inbound streams for custom protocols
+	// (e.g. ordersync) that are currently open.
+	NumInboundStreams int
+}
+
+// resourceLimiter limits the number of inbound streams for protocols
+// registered with SetStreamHandler and the number of connections. It protects
+// the node from peers which open thousands of streams or connections at once.
+type resourceLimiter struct {
+	mu                           sync.Mutex
+	maxInboundStreamsPerPeer     int
+	maxInboundStreamsPerProtocol int
+	maxConnectionsPerPeer        int
+	maxConnections               int
+	streamsPerPeer               map[peer.ID]int
+	streamsPerProtocol           map[protocol.ID]int
+	numLimitHits                 map[string]int64
+}
+
+func newResourceLimiter(config Config) *resourceLimiter {
+	return &resourceLimiter{
+		maxInboundStreamsPerPeer:     config.MaxInboundStreamsPerPeer,
+		maxInboundStreamsPerProtocol: config.MaxInboundStreamsPerProtocol,
+		maxConnectionsPerPeer:        config.MaxConnectionsPerPeer,
+		maxConnections:               config.MaxConnections,
+		streamsPerPeer:               map[peer.ID]int{},
+		streamsPerProtocol:           map[protocol.ID]int{},
+		numLimitHits:                 map[string]int64{},
+	}
+}
+
+// reserveStream reserves an inbound stream for the given peer and protocol. It
+// returns the limit that was hit or an empty string if the stream was
+// reserved. Reserved streams must be released with releaseStream.
+func (l *resourceLimiter) reserveStream(peerID peer.ID, pid protocol.ID) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.streamsPerPeer[peerID] >= l.maxInboundStreamsPerPeer {
+		l.numLimitHits[LimitInboundStreamsPerPeer]++
+		return LimitInboundStreamsPerPeer
+	}
+	if l.streamsPerProtocol[pid] >= l.maxInboundStreamsPerProtocol {
+		l.numLimitHits[LimitInboundStreamsPerProtocol]++
+		return LimitInboundStreamsPerProtocol
+	}
+	l.streamsPerPeer[peerID]++
+	l.streamsPerProtocol[pid]++
+	return ""
+}
+
+func (l *resourceLimiter) releaseStream(peerID peer.ID, pid protocol.ID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.streamsPerPeer[peerID]--
+	if l.streamsPerPeer[peerID] <= 0 {
+		delete(l.streamsPerPeer, peerID)
+	}
+	l.streamsPerProtocol[pid]--
+	if l.streamsPerProtocol[pid] <= 0 {
+		delete(l.streamsPerProtocol, pid)
+	}
+}
+
+// limitStreamHandler wraps the given handler so that inbound streams which
+// exceed the limits are reset before the handler is called. If the handler
+// panics, the stream is reset and a crash report is logged.
+func (l *resourceLimiter) limitStreamHandler(pid protocol.ID, handler network.StreamHandler) network.StreamHandler {
+	return func(stream network.Stream) {
+		peerID := stream.Conn().RemotePeer()
+		if limit := l.reserveStream(peerID, pid); limit != "" {
+			log.WithFields(map[string]interface{}{
+				"remotePeerID": peerID,
+				"protocol":     pid,
+				"limit":        limit,
+			}).Debug("resetting inbound stream because a resource limit was hit")
+			_ = stream.Reset()
+			return
+		}
+		defer l.releaseStream(peerID, pid)
+		defer func() {
+			if r := recover(); r != nil {
+				supervisor.ReportCrash("p2p.streamHandler:"+string(pid), r)
+				_ = stream.Reset()
+			}
+		}()
+		handler(stream)
+	}
+}
+
+// checkConnection returns the limit that was hit by the given connection or
+// an empty string if it is within the limits. Only inbound connections count
+// towards the limits, since we never want to close connections we opened.
+func (l *resourceLimiter) checkConnection(net network.Network, conn network.Conn) string {
+	if conn.Stat().Direction != network.DirInbound {
+		return ""
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(net.ConnsToPeer(conn.RemotePeer())) > l.maxConnectionsPerPeer {
+		l.numLimitHits[LimitConnectionsPerPeer]++
+		return LimitConnectionsPerPeer
+	}
+	if len(net.Conns()) > l.maxConnections {
+		l.numLimitHits[LimitConnections]++
+		return LimitConnections
+	}
+	return ""
+}
+
+func (l *resourceLimiter) stats() ResourceLimitStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	numLimitHits := make(map[string]int64, len(l.numLimitHits))
+	for limit, count := range l.numLimitHits {
+		numLimitHits[limit] = count
+	}
+	numInboundStreams := 0
+	for _, count := range l.streamsPerProtocol {
+		numInboundStreams += count
+	}
+	return ResourceLimitStats{
+		NumLimitHits:      numLimitHits,
+		NumInboundStreams: numInboundStreams,
+	}
+}
@@ -0,0 +1,56 @@
+// +build !js
+
+package p2p
+
+import (
+	"testing"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceLimiterStreams(t *testing.T) {
+	limiter := newResourceLimiter(Config{
+		MaxInboundStreamsPerPeer:     2,
+		MaxInboundStreamsPerProtocol: 3,
+	})
+	pid := protocol.ID("/0x-orders/1.0.0")
+	peerA := peer.ID("peerA")
+	peerB := peer.ID("peerB")
+
+	assert.Equal(t, "", limiter.reserveStream(peerA, pid))
+	assert.Equal(t, "", limiter.reserveStream(peerA, pid))
+	assert.Equal(t, LimitInboundStreamsPerPeer, limiter.reserveStream(peerA, pid))
+	assert.Equal(t, "", limiter.reserveStream(peerB, pid))
+	assert.Equal(t, LimitInboundStreamsPerProtocol, limiter.reserveStream(peerB, pid))
+
+	// Other protocols have their own limit.
+	assert.Equal(t, "", limiter.reserveStream(peerB, protocol.ID("/other/1.0.0")))
+
+	// Releasing a stream frees up space for new streams.
+	limiter.releaseStream(peerA, pid)
+	assert.Equal(t, "", limiter.reserveStream(peerA, pid))
+
+	stats := limiter.stats()
+	assert.Equal(t, map[string]int64{
+		LimitInboundStreamsPerPeer:     1,
+		LimitInboundStreamsPerProtocol: 1,
+	}, stats.NumLimitHits)
+	assert.Equal(t, 4, stats.NumInboundStreams)
+}
+
+func TestResourceLimiterReleaseAllStreams(t *testing.T) {
+	limiter := newResourceLimiter(Config{
+		MaxInboundStreamsPerPeer:     1,
+		MaxInboundStreamsPerProtocol: 1,
+	})
+	pid := protocol.ID("/0x-orders/1.0.0")
+	peerID := peer.ID("peer")
+
+	assert.Equal(t, "", limiter.reserveStream(peerID, pid))
+	limiter.releaseStream(peerID, pid)
+	assert.Empty(t, limiter.streamsPerPeer)
+	assert.Empty(t, limiter.streamsPerProtocol)
+	assert.Equal(t, 0, limiter.stats().NumInboundStreams)
+}
@@ -0,0 +1,93 @@
+package p2p
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxTopicShards is the maximum number of shards that a topic can be split
+	// into.
+	MaxTopicShards   = 256
+	shardTopicFormat = "%s/shards/%d/shard/%d"
+)
+
+// ShardTopic returns the topic for the given shard of topic when the topic is
+// split into numShards shards. The number of shards is part of the topic so
+// that nodes which use a different number of shards never share a topic.
+func ShardTopic(topic string, numShards int, shard int) string {
+	return fmt.Sprintf(shardTopicFormat, topic, numShards, shard)
+}
+
+// UnshardTopic returns the topic which the given shard topic belongs to if
+// topics are split into numShards shards (see ShardTopic). Otherwise, it
+// returns the given topic as is.
+func UnshardTopic(shardTopic string, numShards int) string {
+	if numShards <= 1 {
+		return shardTopic
+	}
+	i := strings.LastIndex(shardTopic, "/shards/")
+	if i == -1 {
+		return shardTopic
+	}
+	topic := shardTopic[:i]
+	var shard int
+	if _, err := fmt.Sscanf(shardTopic[i:], "/shards/"+strconv.Itoa(numShards)+"/shard/%d", &shard); err != nil {
+		return shardTopic
+	}
+	if ShardTopic(topic, numShards, shard) != shardTopic {
+		return shardTopic
+	}
+	return topic
+}
+
+// ShardForKey returns the shard (in the range [0, numShards)) for the given
+// key (e.g. an order hash). It only depends on the first 4 bytes of the key. If
+// numShards is less than or equal to 1, it always returns 0.
+func ShardForKey(key []byte, numShards int) int {
+	if numShards <= 1 {
+		return 0
+	}
+	prefix := make([]byte, 4)
+	copy(prefix, key)
+	return int(binary.BigEndian.Uint32(prefix) % uint32(numShards))
+}
+
+// normalizeShards validates the given topic sharding config and returns the
+// sorted set of shards to subscribe to. If shards is empty, all shards are
+// returned.
+func normalizeShards(numShards int, shards []int) ([]int, error) {
+	if numShards < 0 || numShards > MaxTopicShards {
+		return nil, fmt.Errorf("config.NumTopicShards must be between 0 and %d", MaxTopicShards)
+	}
+	if numShards <= 1 {
+		if len(shards) != 0 {
+			return nil, fmt.Errorf("config.SubscribeShards requires config.NumTopicShards to be greater than 1")
+		}
+		return nil, nil
+	}
+	if len(shards) == 0 {
+		allShards := make([]int, numShards)
+		for i := range allShards {
+			allShards[i] = i
+		}
+		return allShards, nil
+	}
+	seen := map[int]struct{}{}
+	normalized := []int{}
+	for _, shard := range shards {
+		if shard < 0 || shard >= numShards {
+			return nil, fmt.Errorf("config.SubscribeShards contains invalid shard %d (must be between 0 and %d)", shard, numShards-1)
+		}
+		if _, found := seen[shard]; found {
+			continue
+		}
+		seen[shard] = struct{}{}
+		normalized = append(normalized, shard)
+	}
+	sort.Ints(normalized)
+	return normalized, nil
+}
@@ -0,0 +1,50 @@
+// +build !js
+
+package p2p
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardForKey(t *testing.T) {
+	assert.Equal(t, 0, ShardForKey([]byte{0xff, 0xff, 0xff, 0xff}, 0))
+	assert.Equal(t, 0, ShardForKey([]byte{0xff, 0xff, 0xff, 0xff}, 1))
+	assert.Equal(t, 3, ShardForKey([]byte{0x00, 0x00, 0x00, 0x0b, 0xaa}, 4))
+	assert.Equal(t, 255, ShardForKey([]byte{0xff, 0xff, 0xff, 0xff}, 256))
+	// Keys shorter than 4 bytes are right-padded with zeroes.
+	assert.Equal(t, ShardForKey([]byte{0x01, 0x02, 0x00, 0x00}, 7), ShardForKey([]byte{0x01, 0x02}, 7))
+}
+
+func TestUnshardTopic(t *testing.T) {
+	const topic = "/0x-orders/version/3/chain/1337/schema/e30="
+	assert.Equal(t, topic, UnshardTopic(ShardTopic(topic, 4, 3), 4))
+	assert.Equal(t, topic, UnshardTopic(topic, 4))
+	assert.Equal(t, topic, UnshardTopic(topic, 0))
+	// Shard topics for a different number of shards are not unsharded.
+	assert.Equal(t, ShardTopic(topic, 8, 3), UnshardTopic(ShardTopic(topic, 8, 3), 4))
+	assert.Equal(t, topic+"/shards/4/shard/x", UnshardTopic(topic+"/shards/4/shard/x", 4))
+}
+
+func TestNormalizeShards(t *testing.T) {
+	shards, err := normalizeShards(0, nil)
+	require.NoError(t, err)
+	assert.Empty(t, shards)
+
+	shards, err = normalizeShards(4, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3}, shards)
+
+	shards, err = normalizeShards(4, []int{3, 1, 3})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 3}, shards)
+
+	_, err = normalizeShards(4, []int{4})
+	assert.Error(t, err)
+	_, err = normalizeShards(1, []int{0})
+	assert.Error(t, err)
+	_, err = normalizeShards(MaxTopicShards+1, nil)
+	assert.Error(t, err)
+}
@@ -57,8 +57,6 @@ func (s *Set) Validate(ctx context.Context, sender peer.ID, msg *pubsub.Message)
 		// Otherwise continue by running this validator.
 		isValid := validator.validator(ctx, sender, msg)
 		if !isValid {
-			// TODO(albrow): Should we reduce a peer's score as a penalty for invalid
-			//               messages?
 			log.WithField("validatorName", validator.name).Trace("pubsub message validation failed")
 			return false
 		}
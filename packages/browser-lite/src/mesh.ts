@@ -1,6 +1,7 @@
 import { SignedOrder } from '@0x/order-utils';
 import * as BrowserFS from 'browserfs';
 
+import { defaultAddOrdersBatchDelayMs, defaultMaxAddOrdersBatchSize, OrderBatcher } from './order_batcher';
 import { createSchemaValidator } from './schema_validator';
 import './wasm_exec';
 
@@ -34,6 +35,7 @@ import {
     RejectedOrderInfo,
     RejectedOrderKind,
     RejectedOrderStatus,
+    SchemaFieldError,
     Stats,
     ValidationResults,
     Verbosity,
@@ -76,6 +78,7 @@ export {
     RejectedOrderInfo,
     RejectedOrderKind,
     RejectedOrderStatus,
+    SchemaFieldError,
     Stats,
     ValidationResults,
     Verbosity,
@@ -138,6 +141,7 @@ window.addEventListener(loadEventName, () => {
 export class Mesh {
     private readonly _config: Config;
     private _wrapper?: MeshWrapper;
+    private _orderBatcher?: OrderBatcher;
     private _errHandler?: (err: Error) => void;
     private _orderEventsHandler?: (events: WrapperOrderEvent[]) => void;
 
@@ -188,6 +192,16 @@ export class Mesh {
     public async startAsync(): Promise<void> {
         await waitForLoadAsync();
         this._wrapper = await zeroExMesh.newWrapperAsync(configToWrapperConfig(this._config));
+        const wrapper = this._wrapper;
+        this._orderBatcher = new OrderBatcher(
+            async (orders, pinned) => wrapper.addOrdersAsync(orders, pinned),
+            this._config.addOrdersBatchDelayMs === undefined
+                ? defaultAddOrdersBatchDelayMs
+                : this._config.addOrdersBatchDelayMs,
+            this._config.maxAddOrdersBatchSize === undefined
+                ? defaultMaxAddOrdersBatchSize
+                : this._config.maxAddOrdersBatchSize,
+        );
         if (this._orderEventsHandler !== undefined) {
             this._wrapper.onOrderEvents(this._orderEventsHandler);
         }
@@ -281,6 +295,10 @@ export class Mesh {
      * the order; it will not be rejected for any invalid orders (check
      * results.rejected instead).
      *
+     * Orders added in quick succession are batched together before being
+     * added to Mesh (see Config.addOrdersBatchDelayMs). The returned results
+     * only include the orders passed to this call.
+     *
      * @param   orders      An array of orders to add.
      * @param   pinned      Whether or not the orders should be pinned. Pinned
      * orders will not be affected by any DDoS prevention or incentive
@@ -291,14 +309,14 @@ export class Mesh {
      */
     public async addOrdersAsync(orders: SignedOrder[], pinned: boolean = true): Promise<ValidationResults> {
         await waitForLoadAsync();
-        if (this._wrapper === undefined) {
-            // If this is called after startAsync, this._wrapper is always
+        if (this._orderBatcher === undefined) {
+            // If this is called after startAsync, this._orderBatcher is always
             // defined. This check is here just in case and satisfies the
             // compiler.
             return Promise.reject(new Error('Mesh is still loading. Try again soon.'));
         }
         const meshOrders = orders.map(signedOrderToWrapperSignedOrder);
-        const meshResults = await this._wrapper.addOrdersAsync(meshOrders, pinned);
+        const meshResults = await this._orderBatcher.addOrdersAsync(meshOrders, pinned);
         return wrapperValidationResultsToValidationResults(meshResults);
     }
 }
@@ -0,0 +1,190 @@
+import { WrapperRejectedOrderInfo, WrapperSignedOrder, WrapperValidationResults } from './types';
+
+// The default amount of time (in milliseconds) to wait for more orders before
+// submitting a batch.
+export const defaultAddOrdersBatchDelayMs = 50;
+
+// The default maximum number of orders in a single batch.
+export const defaultMaxAddOrdersBatchSize = 500;
+
+type AddOrdersFunc = (orders: WrapperSignedOrder[], pinned: boolean) => Promise<WrapperValidationResults>;
+
+interface PendingRequest {
+    orders: WrapperSignedOrder[];
+    resolve: (results: WrapperValidationResults) => void;
+    reject: (err: Error) => void;
+}
+
+interface Batch {
+    requests: PendingRequest[];
+    numOrders: number;
+    timeout: any;
+}
+
+/**
+ * Combines orders added in quick succession into a single call to Mesh so that
+ * adding orders one at a time does not result in many small validation batches
+ * and GossipSub messages. Each caller receives only the validation results for
+ * the orders it added.
+ */
+export class OrderBatcher {
+    private readonly _addOrdersAsync: AddOrdersFunc;
+    private readonly _delayMs: number;
+    private readonly _maxBatchSize: number;
+    // Pinned and unpinned orders are batched separately.
+    private readonly _batches: Map<boolean, Batch> = new Map();
+
+    /**
+     * Instantiates a new OrderBatcher.
+     *
+     * @param   addOrdersAsync  The function used to add a batch of orders.
+     * @param   delayMs         How long to wait for more orders before
+     * submitting a batch. If less than or equal to 0, orders are not batched.
+     * @param   maxBatchSize    The maximum number of orders in a batch. A
+     * batch is submitted immediately once it reaches this size.
+     */
+    constructor(addOrdersAsync: AddOrdersFunc, delayMs: number, maxBatchSize: number) {
+        this._addOrdersAsync = addOrdersAsync;
+        this._delayMs = delayMs;
+        this._maxBatchSize = maxBatchSize;
+    }
+
+    /**
+     * Adds the given orders to the current batch and resolves with the
+     * validation results for those orders once the batch has been submitted.
+     *
+     * @param   orders  The orders to add.
+     * @param   pinned  Whether or not the orders should be pinned.
+     */
+    public async addOrdersAsync(orders: WrapperSignedOrder[], pinned: boolean): Promise<WrapperValidationResults> {
+        if (this._delayMs <= 0) {
+            return this._addOrdersAsync(orders, pinned);
+        }
+        // Requests are never split across batches. If this request doesn't fit
+        // in the current batch, submit the current batch first.
+        const existingBatch = this._batches.get(pinned);
+        if (existingBatch !== undefined && existingBatch.numOrders + orders.length > this._maxBatchSize) {
+            this._flush(pinned);
+        }
+        return new Promise<WrapperValidationResults>((resolve, reject) => {
+            let batch = this._batches.get(pinned);
+            if (batch === undefined) {
+                batch = {
+                    requests: [],
+                    numOrders: 0,
+                    timeout: setTimeout(() => this._flush(pinned), this._delayMs),
+                };
+                this._batches.set(pinned, batch);
+            }
+            batch.requests.push({ orders, resolve, reject });
+            batch.numOrders += orders.length;
+            if (batch.numOrders >= this._maxBatchSize) {
+                this._flush(pinned);
+            }
+        });
+    }
+
+    private _flush(pinned: boolean): void {
+        const batch = this._batches.get(pinned);
+        if (batch === undefined) {
+            return;
+        }
+        this._batches.delete(pinned);
+        clearTimeout(batch.timeout);
+
+        const orders: WrapperSignedOrder[] = [];
+        for (const request of batch.requests) {
+            orders.push(...request.orders);
+        }
+        this._addOrdersAsync(orders, pinned)
+            .then(results => {
+                const resultsByRequest = splitResults(results, batch.requests);
+                batch.requests.forEach((request, i) => request.resolve(resultsByRequest[i]));
+            })
+            .catch(err => {
+                for (const request of batch.requests) {
+                    request.reject(err);
+                }
+            });
+    }
+}
+
+/**
+ * Splits the validation results for a batch into the results for each of the
+ * requests in the batch.
+ *
+ * Results are matched to orders by orderKey. Mesh can't include the order in
+ * the results for orders it could not parse, so the remaining rejected results
+ * are matched by index instead: Mesh rejects unparseable orders before
+ * validating the other orders and in the order they were sent, so the n-th
+ * unmatched rejected result belongs to the n-th order which has no result.
+ */
+export function splitResults(
+    results: WrapperValidationResults,
+    requests: Array<{ orders: WrapperSignedOrder[] }>,
+): WrapperValidationResults[] {
+    const resultsByRequest: WrapperValidationResults[] = requests.map(() => ({ accepted: [], rejected: [] }));
+    // Maps each order key to the indexes of the requests which contain it.
+    const requestIndexesByKey = new Map<string, number[]>();
+    requests.forEach((request, i) => {
+        for (const order of request.orders) {
+            const key = orderKey(order);
+            const requestIndexes = requestIndexesByKey.get(key) || [];
+            if (requestIndexes.indexOf(i) === -1) {
+                requestIndexes.push(i);
+            }
+            requestIndexesByKey.set(key, requestIndexes);
+        }
+    });
+
+    // Mesh only returns one result for duplicate orders, so every request
+    // which contains the order receives it.
+    const matchedKeys = new Set<string>();
+    const requestIndexesForOrder = (order: WrapperSignedOrder | null | undefined): number[] | undefined => {
+        if (order == null) {
+            return undefined;
+        }
+        const key = orderKey(order);
+        const requestIndexes = requestIndexesByKey.get(key);
+        if (requestIndexes !== undefined) {
+            matchedKeys.add(key);
+        }
+        return requestIndexes;
+    };
+    for (const info of results.accepted) {
+        for (const i of requestIndexesForOrder(info.signedOrder) || []) {
+            resultsByRequest[i].accepted.push(info);
+        }
+    }
+    const unmatchedRejected: WrapperRejectedOrderInfo[] = [];
+    for (const info of results.rejected) {
+        const requestIndexes = requestIndexesForOrder(info.signedOrder);
+        if (requestIndexes === undefined) {
+            unmatchedRejected.push(info);
+            continue;
+        }
+        for (const i of requestIndexes) {
+            resultsByRequest[i].rejected.push(info);
+        }
+    }
+
+    let next = 0;
+    requests.forEach((request, i) => {
+        for (const order of request.orders) {
+            if (next < unmatchedRejected.length && !matchedKeys.has(orderKey(order))) {
+                resultsByRequest[i].rejected.push(unmatchedRejected[next]);
+                next++;
+            }
+        }
+    });
+    return resultsByRequest;
+}
+
+/**
+ * Returns a key which identifies the given order. It is used instead of the
+ * order hash because Mesh does not compute a hash for orders that fail schema
+ * validation.
+ */
+function orderKey(order: WrapperSignedOrder): string {
+    return [order.exchangeAddress, order.makerAddress, order.salt, order.signature].join(':').toLowerCase();
+}
@@ -24,13 +24,19 @@ export interface SchemaValidator {
  * @param schemas These are all of the schemas that can be compiled before the
  *        customOrderSchema.
  * @param rootSchemas The root schemas. These must be compiled last.
+ * @param formats The custom formats (e.g. "ethereum-address") which can be
+ *        used by the custom order schema, by name.
  */
 export function createSchemaValidator(
     customOrderSchemaString: string,
     schemas: string[],
     rootSchemas: string[],
+    formats: { [name: string]: (value: string) => boolean } = {},
 ): SchemaValidator {
     const AJV = new ajv();
+    for (const name of Object.keys(formats)) {
+        AJV.addFormat(name, { type: 'string', validate: formats[name] });
+    }
     for (const schema of schemas) {
         AJV.addSchema(JSON.parse(schema));
     }
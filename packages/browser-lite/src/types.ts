@@ -107,8 +107,23 @@ export interface Config {
     // bootstrapList is a list of multiaddresses to use for bootstrapping the
     // DHT (e.g.,
     // "/ip4/3.214.190.67/tcp/60558/ipfs/16Uiu2HAmGx8Z6gdq5T5AQE54GMtqDhDFhizywTy1o28NJbAMMumF").
-    // Defaults to the hard-coded default bootstrap list.
+    // Defaults to the hard-coded bootstrap list for ethereumChainID and
+    // bootstrapEnvironment.
     bootstrapList?: string[];
+    // Selects the hard-coded bootstrap list which is used if bootstrapList is
+    // not set. 'production' uses the bootstrap nodes run by 0x and
+    // 'development' has no hard-coded bootstrap peers. Defaults to
+    // 'production'.
+    bootstrapEnvironment?: 'production' | 'development';
+    // URLs of signed bootstrap lists. The newest list which is signed with
+    // bootstrapListPublicKey and matches ethereumChainID and
+    // bootstrapEnvironment replaces the bootstrap list. The lists are fetched
+    // on startup and every hour.
+    bootstrapListURLs?: string[];
+    // The base64 encoded libp2p public key which the lists at
+    // bootstrapListURLs must be signed with. Required if bootstrapListURLs is
+    // set.
+    bootstrapListPublicKey?: string;
     // The polling interval (in seconds) to wait before checking for a new
     // Ethereum block that might contain transactions that impact the
     // fillability of orders stored by Mesh. Different chains have different
@@ -116,6 +131,12 @@ export interface Config {
     // Mainnet) and POA chains faster (e.g., Kovan) so one should adjust the
     // polling interval accordingly. Defaults to 5.
     blockPollingIntervalSeconds?: number;
+    // The block tag ('latest', 'safe' or 'finalized') of the block Mesh treats
+    // as the head of the chain. Using 'safe' or 'finalized' means that Mesh
+    // only reacts to blocks which can't (or are unlikely to) be re-orged, which
+    // is useful for chains with non-standard finality such as L2s. Defaults to
+    // a tag chosen based on ethereumChainID.
+    ethereumBlockTag?: 'latest' | 'safe' | 'finalized';
     // The maximum request Content-Length accepted by the backing Ethereum RPC
     // endpoint used by Mesh. Geth & Infura both limit a request's content
     // length to 1024 * 512 Bytes. Parity and Alchemy have much higher limits.
@@ -144,13 +165,33 @@ export interface Config {
     // 30 rps for Infura's free tier, and can be increased to 100 rpc for pro
     // users, and potentially higher on alternative infrastructure.
     ethereumRPCMaxRequestsPerSecond?: number;
+    // The shares of the Ethereum RPC request limits which are reserved for
+    // polling for new blocks, validating orders and requests triggered by API
+    // calls (e.g. addOrdersAsync) respectively. They must add up to 1 and
+    // default to 0.2, 0.6 and 0.2. While a subsystem has requests waiting, it is
+    // guaranteed its share of ethereumRPCMaxRequestsPerSecond and requests which
+    // are not used by a subsystem go to the others. A subsystem can only use
+    // its own share of ethereumRPCMaxRequestsPer24HrUTC and the shares of
+    // subsystems with a lower priority.
+    ethereumRPCBlockWatchShare?: number;
+    ethereumRPCOrderValidationShare?: number;
+    ethereumRPCAPIShare?: number;
+    // A comma-separated list of the subsystems "blockwatch", "api" and
+    // "orderValidation", highest priority first. When several subsystems have
+    // received their share of the Ethereum RPC requests, the one with the
+    // highest priority is granted the next request. Defaults to
+    // "blockwatch,api,orderValidation".
+    ethereumRPCSubsystemPriority?: string;
     // A set of custom addresses to use for the configured network ID. The
     // contract addresses for most common networks are already included by
     // default, so this is typically only needed for testing on custom networks.
     // The given addresses are added to the default list of addresses for known
     // chains and overriding any contract addresses for known chains is not
     // allowed. The addresses for exchange, devUtils, erc20Proxy, and
-    // erc721Proxy are required for each chain. For example:
+    // erc721Proxy are required for each chain. On chains without a DevUtils
+    // deployment, the address of a Multicall2 contract (multicall) may be given
+    // instead of devUtils. In that case, orders involving ERC1155 or
+    // ERC20Bridge assetData are not supported. For example:
     //
     //    {
     //        exchange: "0x48bacb9266a570d521063ef5dd96e61686dbe788",
@@ -185,6 +226,82 @@ export interface Config {
     // all the required fields) are automatically included. For more information
     // on JSON Schemas, see https://json-schema.org/
     customOrderFilter?: JsonSchema;
+    // Determines whether Mesh executes the staticcalls encoded in StaticCall
+    // assetData during order validation. If enabled, orders involving a
+    // staticcall to one of staticCallAllowedTargets are accepted as long as the
+    // staticcall currently succeeds, and they are revalidated on every block.
+    // Defaults to false.
+    enableStaticCallExecution?: boolean;
+    // The maximum amount of gas a single staticcall may use when
+    // enableStaticCallExecution is true. Defaults to 100,000.
+    staticCallGasLimit?: number;
+    // The contract addresses that staticcalls may target when
+    // enableStaticCallExecution is true. The MaximumGasPrice contract is always
+    // allowed.
+    staticCallAllowedTargets?: string[];
+    // The maximum size of an order encoded as JSON. Larger orders are rejected
+    // with the MaxOrderSizeExceeded code. Defaults to 16,000.
+    maxOrderSizeInBytes?: number;
+    // Determines how Mesh treats peers that send orders exceeding
+    // maxOrderSizeInBytes. If "penalize" (the default), the peer's score is
+    // lowered. If "ignore", the order is dropped without affecting the peer's
+    // score.
+    oversizedOrderPolicy?: 'penalize' | 'ignore';
+    // Determines how Mesh treats orders whose assetData uses an asset proxy
+    // that Mesh doesn't recognize. If "reject" (the default), they are
+    // rejected. If "accept-without-balance-tracking", they are accepted but
+    // not revalidated when the maker's balances or allowances change. If
+    // "quarantine", they are accepted the same way but never shared with
+    // peers.
+    unknownAssetProxyPolicy?: 'reject' | 'accept-without-balance-tracking' | 'quarantine';
+    // The number of blocks for which Mesh keeps the full history of order
+    // state transitions. Older history is compacted so that only the state of
+    // orders which are still fillable is kept. If 0 (the default), no history
+    // is recorded.
+    orderStateHistoryRetentionBlocks?: number;
+    // A list of maker addresses whose orders bypass Mesh's anti-spam
+    // mechanisms. Their orders are not subject to the max expiration time and
+    // are always pinned, so they are never removed to make room for other
+    // orders.
+    trustedMakerAddresses?: string[];
+    // The number of shards that the order topic is split into. Orders are
+    // assigned to a shard based on a prefix of their order hash. If greater
+    // than 1, Mesh only receives orders for the shards in
+    // subscribeTopicShards, which allows nodes with limited bandwidth to
+    // participate in a partial view of a very large network. All nodes which
+    // want to exchange orders must use the same number of shards. Cannot be
+    // greater than 256. If 0 (the default) or 1, the order topic is not
+    // sharded.
+    topicShards?: number;
+    // The shards (from 0 to topicShards - 1) to subscribe to. If empty, all
+    // shards are subscribed to. Only used if topicShards is greater than 1.
+    subscribeTopicShards?: number[];
+    // The number of most recent order events that Mesh keeps so that they can
+    // be fetched by sequence number. Subscribers which detect a gap in the
+    // sequence numbers of the order events they receive can use them to
+    // repair the gap. Defaults to 1000.
+    orderEventLogSize?: number;
+    // The disk usage budget for the database in bytes. When it is exceeded,
+    // Mesh stops accepting new orders that are not pinned (they are rejected
+    // with the MaxDiskUsageExceeded code) and prunes orders until disk usage
+    // is back within budget. If 0 (the default), disk usage is not limited.
+    maxDiskUsageBytes?: number;
+    // While Mesh is catching up to the latest block (e.g. after being
+    // offline), new orders are validated against state that may be stale. If
+    // Mesh is at least this many blocks behind the latest block when an order
+    // is validated, the order is stored as provisional and re-validated once
+    // Mesh has caught up. If 0 (the default), orders are never stored as
+    // provisional.
+    provisionalOrderBlocksBehind?: number;
+    // The amount of time (in milliseconds) that addOrdersAsync waits for more
+    // orders before adding them to Mesh in a single batch. Batching prevents
+    // orders that are added one at a time from producing many small
+    // validation batches and GossipSub messages. Set to 0 to disable batching.
+    // Defaults to 50.
+    addOrdersBatchDelayMs?: number;
+    // The maximum number of orders in a single batch. Once a batch reaches
+    // this size it is added immediately. Defaults to 500.
+    maxAddOrdersBatchSize?: number;
     // Offers the ability to use your own web3 provider for all Ethereum RPC
     // requests instead of the default.
     web3Provider?: SupportedProvider;
@@ -192,7 +309,8 @@ export interface Config {
 
 export interface ContractAddresses {
     exchange: string;
-    devUtils: string;
+    devUtils?: string;
+    multicall?: string;
     erc20Proxy: string;
     erc721Proxy: string;
     erc1155Proxy: string;
@@ -245,14 +363,35 @@ export interface WrapperConfig {
     ethereumChainID: number;
     useBootstrapList?: boolean;
     bootstrapList?: string; // comma-separated string instead of an array of strings.
+    bootstrapEnvironment?: string;
+    bootstrapListURLs?: string; // comma-separated string instead of an array of strings.
+    bootstrapListPublicKey?: string;
     blockPollingIntervalSeconds?: number;
+    ethereumBlockTag?: string;
     ethereumRPCMaxContentLength?: number;
     ethereumRPCMaxRequestsPer24HrUTC?: number;
     ethereumRPCMaxRequestsPerSecond?: number;
+    ethereumRPCBlockWatchShare?: number;
+    ethereumRPCOrderValidationShare?: number;
+    ethereumRPCAPIShare?: number;
+    ethereumRPCSubsystemPriority?: string;
     enableEthereumRPCRateLimiting?: boolean;
     customContractAddresses?: string; // json-encoded string instead of Object.
     maxOrdersInStorage?: number;
     customOrderFilter?: string; // json-encoded string instead of Object
+    enableStaticCallExecution?: boolean;
+    staticCallGasLimit?: number;
+    staticCallAllowedTargets?: string; // comma-separated string instead of an array of strings.
+    maxOrderSizeInBytes?: number;
+    oversizedOrderPolicy?: string;
+    unknownAssetProxyPolicy?: string;
+    orderStateHistoryRetentionBlocks?: number;
+    trustedMakerAddresses?: string; // comma-separated string instead of an array of strings.
+    topicShards?: number;
+    subscribeTopicShards?: string; // comma-separated string instead of an array of numbers.
+    orderEventLogSize?: number;
+    maxDiskUsageBytes?: number;
+    provisionalOrderBlocksBehind?: number;
     web3Provider?: ZeroExProvider; // Standardized ZeroExProvider instead the more permissive SupportedProvider interface
 }
 
@@ -544,6 +683,8 @@ export enum OrderEventEndState {
     Unfunded = 'UNFUNDED',
     FillabilityIncreased = 'FILLABILITY_INCREASED',
     StoppedWatching = 'STOPPED_WATCHING',
+    StaticCallFailed = 'STATIC_CALL_FAILED',
+    Removed = 'REMOVED',
 }
 
 /** @ignore */
@@ -554,6 +695,10 @@ export interface WrapperOrderEvent {
     endState: OrderEventEndState;
     fillableTakerAssetAmount: string;
     contractEvents: WrapperContractEvent[];
+    orderFilterHash: string;
+    requestID?: string;
+    removalReason?: string;
+    sequenceNumber: number;
 }
 
 /**
@@ -567,6 +712,10 @@ export interface OrderEvent {
     endState: OrderEventEndState;
     fillableTakerAssetAmount: BigNumber;
     contractEvents: ContractEvent[];
+    orderFilterHash: string;
+    requestID?: string;
+    removalReason?: string;
+    sequenceNumber: number;
 }
 
 /** @ignore */
@@ -589,6 +738,7 @@ export interface WrapperRejectedOrderInfo {
     signedOrder: WrapperSignedOrder;
     kind: RejectedOrderKind;
     status: RejectedOrderStatus;
+    fieldErrors?: SchemaFieldError[];
 }
 
 /**
@@ -618,6 +768,23 @@ export interface RejectedOrderInfo {
     signedOrder: SignedOrder;
     kind: RejectedOrderKind;
     status: RejectedOrderStatus;
+    fieldErrors?: SchemaFieldError[];
+}
+
+/**
+ * Describes a single violation of the order schema in a machine-readable
+ * form, so that the offending field can be highlighted.
+ */
+export interface SchemaFieldError {
+    // The JSON pointer (RFC 6901) to the offending field, e.g. "/takerAddress".
+    pointer: string;
+    // The violated JSON schema keyword, e.g. "pattern" or "required".
+    constraint: string;
+    // The limit imposed by the constraint (e.g. the pattern), if any.
+    expected?: any;
+    // The value of the offending field, if it is present.
+    actual?: any;
+    message: string;
 }
 
 /**
@@ -643,10 +810,113 @@ export interface LatestBlock {
     hash: string;
 }
 
+export interface OrderSyncProviderStats {
+    peerID: string;
+    numSyncs: number;
+    numFailures: number;
+    ordersReceived: number;
+    ordersAccepted: number;
+    ordersRejected: { [code: string]: number };
+    averageDurationMs: number;
+    averageLatencyMs: number;
+    lastSyncTime: string;
+    lastSubprotocol: string;
+}
+
+export interface ProtocolBandwidthStats {
+    protocol: string;
+    totalIn: number;
+    totalOut: number;
+    rateIn: number;
+    rateOut: number;
+}
+
+export interface PeerBandwidthStats {
+    peerID: string;
+    totalIn: number;
+    totalOut: number;
+    rateIn: number;
+    rateOut: number;
+}
+
+export interface EthRPCHealth {
+    circuitState: 'open' | 'closed';
+    consecutiveFailures: number;
+    lastError: string;
+    lastErrorTime: string;
+    inconsistentHeaders: number;
+}
+
+export interface DiskUsage {
+    usageBytes: number;
+    maxBytes: number;
+    budgetExceeded: boolean;
+}
+
+export interface ResourceLimitStats {
+    numLimitHits: { [limit: string]: number };
+    numInboundStreams: number;
+}
+
+export interface OrderSyncCompressionStats {
+    compressedPagesSent: number;
+    compressedPagesReceived: number;
+    uncompressedBytes: number;
+    compressedBytes: number;
+    compressionRatio: number;
+}
+
+export interface StalledStage {
+    stage: 'BLOCK_PROCESSING' | 'VALIDATION' | 'EVENT_DELIVERY';
+    stalledForMs: number;
+    thresholdMs: number;
+}
+
+export interface PipelineHealth {
+    status: 'ok' | 'degraded';
+    stalledStages: StalledStage[];
+}
+
+export interface OrderFilterCheckStats {
+    numAccepted: number;
+    numRejected: { [reason: string]: number };
+    numErrors: number;
+}
+
+export interface OrderFilterStats {
+    messages: OrderFilterCheckStats;
+    orders: OrderFilterCheckStats;
+}
+
+export interface OrderFeedbackStats {
+    numRejectionsSent: { [code: string]: number };
+    numRejectionsReceived: { [code: string]: number };
+}
+
+export interface ResourceUsage {
+    sampledAt: string;
+    cpuPercent: number;
+    memoryBytes: number;
+    bandwidthInPerSecond: number;
+    bandwidthOutPerSecond: number;
+    peerCountLow: number;
+    peerCountHigh: number;
+    validationConcurrency: number;
+    throttled: boolean;
+}
+
+export interface DialStats {
+    numSuccesses: number;
+    numFailures: { [reason: string]: number };
+    numSkipped: number;
+    numPeersInBackoff: number;
+}
+
 /** @ignore */
 export interface WrapperStats {
     version: string;
     pubSubTopic: string;
+    orderFilterHash: string;
     rendezvous: string;
     secondaryRendezvous: string[];
     peerID: string;
@@ -656,15 +926,34 @@ export interface WrapperStats {
     numOrders: number;
     numOrdersIncludingRemoved: number;
     numPinnedOrders: number;
+    numProvisionalOrders: number;
     maxExpirationTime: string; // string instead of BigNumber
     startOfCurrentUTCDay: string; // string instead of Date
     ethRPCRequestsSentInCurrentUTCDay: number;
     ethRPCRateLimitExpiredRequests: number;
+    orderSyncProviders: OrderSyncProviderStats[];
+    ethRPCHealth: EthRPCHealth;
+    diskUsage: DiskUsage;
+    maxOrderSizeInBytes: number;
+    numOversizedOrdersRejected: number;
+    numOversizedMessagesDropped: number;
+    bandwidthByProtocol: ProtocolBandwidthStats[];
+    topPeersByBandwidth: PeerBandwidthStats[];
+    dialStats: DialStats;
+    resourceLimitStats: ResourceLimitStats;
+    subsystemCrashes: { [subsystem: string]: number };
+    numDuplicateOrders: { [source: string]: number };
+    orderSyncCompression: OrderSyncCompressionStats;
+    pipelineHealth: PipelineHealth;
+    orderFilter: OrderFilterStats;
+    resourceUsage: ResourceUsage;
+    orderFeedback: OrderFeedbackStats;
 }
 
 export interface Stats {
     version: string;
     pubSubTopic: string;
+    orderFilterHash: string;
     rendezvous: string;
     secondaryRendezvous: string[];
     peerID: string;
@@ -674,9 +963,27 @@ export interface Stats {
     numOrders: number;
     numOrdersIncludingRemoved: number;
     numPinnedOrders: number;
+    numProvisionalOrders: number;
     maxExpirationTime: BigNumber;
     startOfCurrentUTCDay: Date;
     ethRPCRequestsSentInCurrentUTCDay: number;
     ethRPCRateLimitExpiredRequests: number;
+    orderSyncProviders: OrderSyncProviderStats[];
+    ethRPCHealth: EthRPCHealth;
+    diskUsage: DiskUsage;
+    maxOrderSizeInBytes: number;
+    numOversizedOrdersRejected: number;
+    numOversizedMessagesDropped: number;
+    bandwidthByProtocol: ProtocolBandwidthStats[];
+    topPeersByBandwidth: PeerBandwidthStats[];
+    dialStats: DialStats;
+    resourceLimitStats: ResourceLimitStats;
+    subsystemCrashes: { [subsystem: string]: number };
+    numDuplicateOrders: { [source: string]: number };
+    orderSyncCompression: OrderSyncCompressionStats;
+    pipelineHealth: PipelineHealth;
+    orderFilter: OrderFilterStats;
+    resourceUsage: ResourceUsage;
+    orderFeedback: OrderFeedbackStats;
 }
 // tslint:disable-next-line:max-file-line-count
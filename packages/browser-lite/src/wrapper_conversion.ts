@@ -51,16 +51,27 @@ import {
 // tslint:disable:completed-docs
 export function configToWrapperConfig(config: Config): WrapperConfig {
     const bootstrapList = config.bootstrapList == null ? undefined : config.bootstrapList.join(',');
+    const bootstrapListURLs = config.bootstrapListURLs == null ? undefined : config.bootstrapListURLs.join(',');
     const customContractAddresses =
         config.customContractAddresses == null ? undefined : JSON.stringify(config.customContractAddresses);
     const customOrderFilter = config.customOrderFilter == null ? undefined : JSON.stringify(config.customOrderFilter);
+    const staticCallAllowedTargets =
+        config.staticCallAllowedTargets == null ? undefined : config.staticCallAllowedTargets.join(',');
+    const trustedMakerAddresses =
+        config.trustedMakerAddresses == null ? undefined : config.trustedMakerAddresses.join(',');
+    const subscribeTopicShards =
+        config.subscribeTopicShards == null ? undefined : config.subscribeTopicShards.join(',');
     const standardizedProvider =
         config.web3Provider == null ? undefined : providerUtils.standardizeOrThrow(config.web3Provider);
     return {
         ...config,
         bootstrapList,
+        bootstrapListURLs,
         customContractAddresses,
         customOrderFilter,
+        staticCallAllowedTargets,
+        trustedMakerAddresses,
+        subscribeTopicShards,
         web3Provider: standardizedProvider,
     };
 }
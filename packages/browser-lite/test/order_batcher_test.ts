@@ -0,0 +1,203 @@
+import { expect } from 'chai';
+import 'mocha';
+
+import { OrderBatcher, splitResults } from '../src/order_batcher';
+import { RejectedOrderKind, WrapperRejectedOrderInfo, WrapperSignedOrder, WrapperValidationResults } from '../src/types';
+
+const delayMs = 10;
+const maxBatchSize = 5;
+// Orders with this signature can't be parsed by the fake Mesh, which then
+// rejects them without the order like Mesh does for malformed orders.
+const malformedSignature = '0xmalformed';
+
+interface AddOrdersCall {
+    orders: WrapperSignedOrder[];
+    pinned: boolean;
+}
+
+function newOrder(salt: number, signature: string = '0x1b'): WrapperSignedOrder {
+    return {
+        chainId: 1337,
+        exchangeAddress: '0x48bacb9266a570d521063ef5dd96e61686dbe788',
+        makerAddress: '0x6ecbe1db9ef729cbe972c83fb886247691fb6beb',
+        makerAssetData: '0x',
+        makerAssetAmount: '1',
+        makerFee: '0',
+        makerFeeAssetData: '0x',
+        takerAddress: '0x0000000000000000000000000000000000000000',
+        takerAssetData: '0x',
+        takerAssetAmount: '1',
+        takerFee: '0',
+        takerFeeAssetData: '0x',
+        senderAddress: '0x0000000000000000000000000000000000000000',
+        feeRecipientAddress: '0x0000000000000000000000000000000000000000',
+        expirationTimeSeconds: '1000',
+        salt: salt.toString(),
+        signature,
+    };
+}
+
+function malformedRejection(): WrapperRejectedOrderInfo {
+    return {
+        orderHash: '',
+        // Mesh doesn't include orders it could not parse in the results.
+        signedOrder: null as any,
+        kind: RejectedOrderKind.MeshValidation,
+        status: { code: 'InvalidSchema', message: 'Malformed JSON or empty payload' },
+    };
+}
+
+// fakeAddOrdersAsync returns a function which validates orders the same way
+// Mesh orders the results: orders which can't be parsed are rejected first,
+// followed by the results for the other orders. Orders with an odd salt are
+// rejected and the others are accepted.
+function fakeAddOrdersAsync(
+    calls: AddOrdersCall[],
+): (orders: WrapperSignedOrder[], pinned: boolean) => Promise<WrapperValidationResults> {
+    return async (orders: WrapperSignedOrder[], pinned: boolean) => {
+        calls.push({ orders, pinned });
+        const results: WrapperValidationResults = { accepted: [], rejected: [] };
+        for (const order of orders) {
+            if (order.signature === malformedSignature) {
+                results.rejected.push(malformedRejection());
+            }
+        }
+        for (const order of orders) {
+            if (order.signature === malformedSignature) {
+                continue;
+            }
+            if (parseInt(order.salt, 10) % 2 === 1) {
+                results.rejected.push({
+                    orderHash: `0x${order.salt}`,
+                    signedOrder: order,
+                    kind: RejectedOrderKind.ZeroExValidation,
+                    status: { code: 'OrderExpired', message: 'order expired' },
+                });
+            } else {
+                results.accepted.push({
+                    orderHash: `0x${order.salt}`,
+                    signedOrder: order,
+                    fillableTakerAssetAmount: '1',
+                    isNew: true,
+                });
+            }
+        }
+        return results;
+    };
+}
+
+function salts(infos: Array<{ signedOrder: WrapperSignedOrder | null }>): Array<string | null> {
+    return infos.map(info => (info.signedOrder === null ? null : info.signedOrder.salt));
+}
+
+describe('OrderBatcher', () => {
+    it('combines orders added in quick succession into a single batch', async () => {
+        const calls: AddOrdersCall[] = [];
+        const batcher = new OrderBatcher(fakeAddOrdersAsync(calls), delayMs, maxBatchSize);
+        const [first, second] = await Promise.all([
+            batcher.addOrdersAsync([newOrder(0), newOrder(1)], true),
+            batcher.addOrdersAsync([newOrder(2)], true),
+        ]);
+        expect(calls.length).to.equal(1);
+        expect(calls[0].orders.map(order => order.salt)).to.deep.equal(['0', '1', '2']);
+        expect(salts(first.accepted)).to.deep.equal(['0']);
+        expect(salts(first.rejected)).to.deep.equal(['1']);
+        expect(salts(second.accepted)).to.deep.equal(['2']);
+        expect(second.rejected).to.deep.equal([]);
+    });
+
+    it('flushes a batch once it reaches the maximum size', async () => {
+        const calls: AddOrdersCall[] = [];
+        const batcher = new OrderBatcher(fakeAddOrdersAsync(calls), 60 * 1000, maxBatchSize);
+        // If the batch wasn't flushed, this would time out.
+        const results = await batcher.addOrdersAsync([0, 2, 4, 6, 8].map(salt => newOrder(salt)), false);
+        expect(calls.length).to.equal(1);
+        expect(results.accepted.length).to.equal(maxBatchSize);
+    });
+
+    it('flushes the current batch first if a request does not fit', async () => {
+        const calls: AddOrdersCall[] = [];
+        const batcher = new OrderBatcher(fakeAddOrdersAsync(calls), delayMs, maxBatchSize);
+        await Promise.all([
+            batcher.addOrdersAsync([newOrder(0), newOrder(2)], false),
+            batcher.addOrdersAsync([4, 6, 8, 10].map(salt => newOrder(salt)), false),
+        ]);
+        expect(calls.map(call => call.orders.length)).to.deep.equal([2, 4]);
+    });
+
+    it('batches pinned and unpinned orders separately', async () => {
+        const calls: AddOrdersCall[] = [];
+        const batcher = new OrderBatcher(fakeAddOrdersAsync(calls), delayMs, maxBatchSize);
+        await Promise.all([batcher.addOrdersAsync([newOrder(0)], true), batcher.addOrdersAsync([newOrder(2)], false)]);
+        expect(calls.length).to.equal(2);
+        for (const call of calls) {
+            expect(call.orders.length).to.equal(1);
+            expect(call.orders[0].salt).to.equal(call.pinned ? '0' : '2');
+        }
+    });
+
+    it('does not batch orders if the delay is 0', async () => {
+        const calls: AddOrdersCall[] = [];
+        const batcher = new OrderBatcher(fakeAddOrdersAsync(calls), 0, maxBatchSize);
+        await Promise.all([batcher.addOrdersAsync([newOrder(0)], true), batcher.addOrdersAsync([newOrder(2)], true)]);
+        expect(calls.length).to.equal(2);
+    });
+
+    it('rejects every request in a batch if adding the orders fails', async () => {
+        const batcher = new OrderBatcher(
+            async () => {
+                throw new Error('mesh is not running');
+            },
+            delayMs,
+            maxBatchSize,
+        );
+        const errors = await Promise.all(
+            [[newOrder(0)], [newOrder(2)]].map(async orders =>
+                batcher.addOrdersAsync(orders, true).then(() => undefined, (err: Error) => err.message),
+            ),
+        );
+        expect(errors).to.deep.equal(['mesh is not running', 'mesh is not running']);
+    });
+
+    it('returns rejections for malformed orders to the request which added them', async () => {
+        const calls: AddOrdersCall[] = [];
+        const batcher = new OrderBatcher(fakeAddOrdersAsync(calls), delayMs, maxBatchSize);
+        const [first, second, third] = await Promise.all([
+            batcher.addOrdersAsync([newOrder(0)], true),
+            batcher.addOrdersAsync([newOrder(1, malformedSignature), newOrder(2)], true),
+            batcher.addOrdersAsync([newOrder(3, malformedSignature)], true),
+        ]);
+        expect(calls.length).to.equal(1);
+        expect(salts(first.accepted)).to.deep.equal(['0']);
+        expect(first.rejected).to.deep.equal([]);
+        expect(salts(second.accepted)).to.deep.equal(['2']);
+        expect(salts(second.rejected)).to.deep.equal([null]);
+        expect(third.accepted).to.deep.equal([]);
+        expect(salts(third.rejected)).to.deep.equal([null]);
+    });
+});
+
+describe('splitResults', () => {
+    it('returns the results for duplicate orders to every request which contains them', () => {
+        const order = newOrder(0);
+        const results: WrapperValidationResults = {
+            accepted: [{ orderHash: '0x0', signedOrder: order, fillableTakerAssetAmount: '1', isNew: true }],
+            rejected: [],
+        };
+        const resultsByRequest = splitResults(results, [{ orders: [order] }, { orders: [newOrder(0)] }]);
+        expect(resultsByRequest).to.deep.equal([results, results]);
+    });
+
+    it('matches orders regardless of the casing of their addresses', () => {
+        const order = newOrder(0);
+        const checksummedOrder = { ...order, makerAddress: '0x6Ecbe1DB9EF729CBe972C83Fb886247691Fb6beb' };
+        const results: WrapperValidationResults = {
+            accepted: [
+                { orderHash: '0x0', signedOrder: checksummedOrder, fillableTakerAssetAmount: '1', isNew: true },
+            ],
+            rejected: [],
+        };
+        const resultsByRequest = splitResults(results, [{ orders: [order] }]);
+        expect(resultsByRequest).to.deep.equal([results]);
+    });
+});
@@ -34,9 +34,17 @@ func ConvertConfig(jsConfig js.Value) (core.Config, error) {
 		EthereumRPCMaxContentLength:      524288,
 		EthereumRPCMaxRequestsPer24HrUTC: 100000,
 		EthereumRPCMaxRequestsPerSecond:  30,
+		EthereumRPCBlockWatchShare:       0.2,
+		EthereumRPCOrderValidationShare:  0.6,
+		EthereumRPCAPIShare:              0.2,
+		EthereumRPCSubsystemPriority:     "blockwatch,api,orderValidation",
 		EnableEthereumRPCRateLimiting:    true,
 		MaxOrdersInStorage:               100000,
 		CustomOrderFilter:                orderfilter.DefaultCustomOrderSchema,
+		StaticCallGasLimit:               100000,
+		MaxOrderSizeInBytes:              16000,
+		OversizedOrderPolicy:             "penalize",
+		OrderEventLogSize:                1000,
 	}
 
 	// Required config options
@@ -56,9 +64,21 @@ func ConvertConfig(jsConfig js.Value) (core.Config, error) {
 	if bootstrapList := jsConfig.Get("bootstrapList"); !jsutil.IsNullOrUndefined(bootstrapList) {
 		config.BootstrapList = bootstrapList.String()
 	}
+	if bootstrapEnvironment := jsConfig.Get("bootstrapEnvironment"); !jsutil.IsNullOrUndefined(bootstrapEnvironment) {
+		config.BootstrapEnvironment = bootstrapEnvironment.String()
+	}
+	if bootstrapListURLs := jsConfig.Get("bootstrapListURLs"); !jsutil.IsNullOrUndefined(bootstrapListURLs) {
+		config.BootstrapListURLs = bootstrapListURLs.String()
+	}
+	if bootstrapListPublicKey := jsConfig.Get("bootstrapListPublicKey"); !jsutil.IsNullOrUndefined(bootstrapListPublicKey) {
+		config.BootstrapListPublicKey = bootstrapListPublicKey.String()
+	}
 	if blockPollingIntervalSeconds := jsConfig.Get("blockPollingIntervalSeconds"); !jsutil.IsNullOrUndefined(blockPollingIntervalSeconds) {
 		config.BlockPollingInterval = time.Duration(blockPollingIntervalSeconds.Int()) * time.Second
 	}
+	if ethereumBlockTag := jsConfig.Get("ethereumBlockTag"); !jsutil.IsNullOrUndefined(ethereumBlockTag) {
+		config.EthereumBlockTag = ethereumBlockTag.String()
+	}
 	if ethereumRPCMaxContentLength := jsConfig.Get("ethereumRPCMaxContentLength"); !jsutil.IsNullOrUndefined(ethereumRPCMaxContentLength) {
 		config.EthereumRPCMaxContentLength = ethereumRPCMaxContentLength.Int()
 	}
@@ -68,6 +88,18 @@ func ConvertConfig(jsConfig js.Value) (core.Config, error) {
 	if ethereumRPCMaxRequestsPerSecond := jsConfig.Get("ethereumRPCMaxRequestsPerSecond"); !jsutil.IsNullOrUndefined(ethereumRPCMaxRequestsPerSecond) {
 		config.EthereumRPCMaxRequestsPerSecond = ethereumRPCMaxRequestsPerSecond.Float()
 	}
+	if ethereumRPCBlockWatchShare := jsConfig.Get("ethereumRPCBlockWatchShare"); !jsutil.IsNullOrUndefined(ethereumRPCBlockWatchShare) {
+		config.EthereumRPCBlockWatchShare = ethereumRPCBlockWatchShare.Float()
+	}
+	if ethereumRPCOrderValidationShare := jsConfig.Get("ethereumRPCOrderValidationShare"); !jsutil.IsNullOrUndefined(ethereumRPCOrderValidationShare) {
+		config.EthereumRPCOrderValidationShare = ethereumRPCOrderValidationShare.Float()
+	}
+	if ethereumRPCAPIShare := jsConfig.Get("ethereumRPCAPIShare"); !jsutil.IsNullOrUndefined(ethereumRPCAPIShare) {
+		config.EthereumRPCAPIShare = ethereumRPCAPIShare.Float()
+	}
+	if ethereumRPCSubsystemPriority := jsConfig.Get("ethereumRPCSubsystemPriority"); !jsutil.IsNullOrUndefined(ethereumRPCSubsystemPriority) {
+		config.EthereumRPCSubsystemPriority = ethereumRPCSubsystemPriority.String()
+	}
 	if enableEthereumRPCRateLimiting := jsConfig.Get("enableEthereumRPCRateLimiting"); !jsutil.IsNullOrUndefined(enableEthereumRPCRateLimiting) {
 		config.EnableEthereumRPCRateLimiting = enableEthereumRPCRateLimiting.Bool()
 	}
@@ -80,6 +112,45 @@ func ConvertConfig(jsConfig js.Value) (core.Config, error) {
 	if customOrderFilter := jsConfig.Get("customOrderFilter"); !jsutil.IsNullOrUndefined(customOrderFilter) {
 		config.CustomOrderFilter = customOrderFilter.String()
 	}
+	if enableStaticCallExecution := jsConfig.Get("enableStaticCallExecution"); !jsutil.IsNullOrUndefined(enableStaticCallExecution) {
+		config.EnableStaticCallExecution = enableStaticCallExecution.Bool()
+	}
+	if staticCallGasLimit := jsConfig.Get("staticCallGasLimit"); !jsutil.IsNullOrUndefined(staticCallGasLimit) {
+		config.StaticCallGasLimit = uint64(staticCallGasLimit.Int())
+	}
+	if staticCallAllowedTargets := jsConfig.Get("staticCallAllowedTargets"); !jsutil.IsNullOrUndefined(staticCallAllowedTargets) {
+		config.StaticCallAllowedTargets = staticCallAllowedTargets.String()
+	}
+	if maxOrderSizeInBytes := jsConfig.Get("maxOrderSizeInBytes"); !jsutil.IsNullOrUndefined(maxOrderSizeInBytes) {
+		config.MaxOrderSizeInBytes = maxOrderSizeInBytes.Int()
+	}
+	if oversizedOrderPolicy := jsConfig.Get("oversizedOrderPolicy"); !jsutil.IsNullOrUndefined(oversizedOrderPolicy) {
+		config.OversizedOrderPolicy = oversizedOrderPolicy.String()
+	}
+	if unknownAssetProxyPolicy := jsConfig.Get("unknownAssetProxyPolicy"); !jsutil.IsNullOrUndefined(unknownAssetProxyPolicy) {
+		config.UnknownAssetProxyPolicy = unknownAssetProxyPolicy.String()
+	}
+	if orderStateHistoryRetentionBlocks := jsConfig.Get("orderStateHistoryRetentionBlocks"); !jsutil.IsNullOrUndefined(orderStateHistoryRetentionBlocks) {
+		config.OrderStateHistoryRetentionBlocks = orderStateHistoryRetentionBlocks.Int()
+	}
+	if trustedMakerAddresses := jsConfig.Get("trustedMakerAddresses"); !jsutil.IsNullOrUndefined(trustedMakerAddresses) {
+		config.TrustedMakerAddresses = trustedMakerAddresses.String()
+	}
+	if orderEventLogSize := jsConfig.Get("orderEventLogSize"); !jsutil.IsNullOrUndefined(orderEventLogSize) {
+		config.OrderEventLogSize = orderEventLogSize.Int()
+	}
+	if maxDiskUsageBytes := jsConfig.Get("maxDiskUsageBytes"); !jsutil.IsNullOrUndefined(maxDiskUsageBytes) {
+		config.MaxDiskUsageBytes = maxDiskUsageBytes.Int()
+	}
+	if provisionalOrderBlocksBehind := jsConfig.Get("provisionalOrderBlocksBehind"); !jsutil.IsNullOrUndefined(provisionalOrderBlocksBehind) {
+		config.ProvisionalOrderBlocksBehind = provisionalOrderBlocksBehind.Int()
+	}
+	if topicShards := jsConfig.Get("topicShards"); !jsutil.IsNullOrUndefined(topicShards) {
+		config.TopicShards = topicShards.Int()
+	}
+	if subscribeTopicShards := jsConfig.Get("subscribeTopicShards"); !jsutil.IsNullOrUndefined(subscribeTopicShards) {
+		config.SubscribeTopicShards = subscribeTopicShards.String()
+	}
 	if ethereumRPCURL := jsConfig.Get("ethereumRPCURL"); !jsutil.IsNullOrUndefined(ethereumRPCURL) && ethereumRPCURL.String() != "" {
 		config.EthereumRPCURL = ethereumRPCURL.String()
 	}
@@ -673,9 +673,17 @@ func setGlobals() {
 				EthereumRPCMaxContentLength:      524288,
 				EthereumRPCMaxRequestsPer24HrUTC: 100000,
 				EthereumRPCMaxRequestsPerSecond:  30,
+				EthereumRPCBlockWatchShare:       0.2,
+				EthereumRPCOrderValidationShare:  0.6,
+				EthereumRPCAPIShare:              0.2,
+				EthereumRPCSubsystemPriority:     "blockwatch,api,orderValidation",
 				EnableEthereumRPCRateLimiting:    true,
 				MaxOrdersInStorage:               100000,
 				CustomOrderFilter:                orderfilter.DefaultCustomOrderSchema,
+				StaticCallGasLimit:               100000,
+				MaxOrderSizeInBytes:              16000,
+				OversizedOrderPolicy:             "penalize",
+				OrderEventLogSize:                1000,
 				EthereumChainID:                  1337,
 			}, "", false)
 			testConvertConfig("FullConfig", args[4], core.Config{
@@ -689,9 +697,17 @@ func setGlobals() {
 				EthereumRPCMaxContentLength:      524100,
 				EthereumRPCMaxRequestsPer24HrUTC: 500000,
 				EthereumRPCMaxRequestsPerSecond:  12,
+				EthereumRPCBlockWatchShare:       0.2,
+				EthereumRPCOrderValidationShare:  0.6,
+				EthereumRPCAPIShare:              0.2,
+				EthereumRPCSubsystemPriority:     "blockwatch,api,orderValidation",
 				EnableEthereumRPCRateLimiting:    false,
 				MaxOrdersInStorage:               500000,
 				CustomOrderFilter:                `{"id":"/foobarbaz"}`,
+				StaticCallGasLimit:               100000,
+				MaxOrderSizeInBytes:              16000,
+				OversizedOrderPolicy:             "penalize",
+				OrderEventLogSize:                1000,
 				CustomContractAddresses:          "{\"exchange\":\"0x48bacb9266a570d521063ef5dd96e61686dbe788\",\"devUtils\":\"0x38ef19fdf8e8415f18c307ed71967e19aac28ba1\",\"erc20Proxy\":\"0x1dc4c1cefef38a777b15aa20260a54e584b16c48\",\"erc721Proxy\":\"0x1d7022f5b17d2f8b695918fb48fa1089c9f85401\",\"erc1155Proxy\":\"0x64517fa2b480ba3678a2a3c0cf08ef7fd4fad36f\"}",
 				EthereumChainID:                  1337,
 				EthereumRPCURL:                   "http://localhost:8545",
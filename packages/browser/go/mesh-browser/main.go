@@ -168,7 +168,7 @@ func (cw *MeshWrapper) GetStats() (js.Value, error) {
 // core.App.GetOrders, converts the result into basic JavaScript types (string,
 // int, etc.) and returns it.
 func (cw *MeshWrapper) GetOrders(page int, perPage int, snapshotID string) (js.Value, error) {
-	ordersResponse, err := cw.app.GetOrders(page, perPage, snapshotID)
+	ordersResponse, err := cw.app.GetOrders(cw.ctx, page, perPage, snapshotID)
 	if err != nil {
 		return js.Undefined(), err
 	}
@@ -6,13 +6,22 @@ export {
     OrderEventPayload,
     OrderEvent,
     OrderInfo,
+    OrderAnnotations,
+    OrdersSubscriptionOpts,
     AcceptedOrderInfo,
     RejectedKind,
     RejectedCode,
     RejectedStatus,
     RejectedOrderInfo,
+    SchemaFieldError,
     ValidationResults,
     GetOrdersResponse,
+    GetOrderEventsSinceResponse,
+    NotRemovedOrderInfo,
+    RemoveOrdersResponse,
+    NotRevalidatedOrderInfo,
+    RevalidateOrdersResponse,
+    OrdersStreamSummary,
     GetStatsResponse,
 } from './types';
 export { SignedOrder } from '@0x/types';
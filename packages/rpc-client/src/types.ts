@@ -290,6 +290,8 @@ export enum OrderEventEndState {
     Expired = 'EXPIRED',
     Unexpired = 'UNEXPIRED',
     StoppedWatching = 'STOPPED_WATCHING',
+    StaticCallFailed = 'STATIC_CALL_FAILED',
+    Removed = 'REMOVED',
     Unfunded = 'UNFUNDED',
     FillabilityIncreased = 'FILLABILITY_INCREASED',
 }
@@ -307,19 +309,28 @@ export interface HeartbeatEventPayload {
 export interface RawOrderEvent {
     timestamp: string;
     orderHash: string;
-    signedOrder: StringifiedSignedOrder;
+    signedOrder?: StringifiedSignedOrder;
     endState: OrderEventEndState;
     fillableTakerAssetAmount: string;
     contractEvents: StringifiedContractEvent[];
+    orderFilterHash: string;
+    requestID?: string;
+    removalReason?: string;
+    sequenceNumber: number;
 }
 
 export interface OrderEvent {
     timestampMs: number;
     orderHash: string;
-    signedOrder: SignedOrder;
+    // signedOrder is undefined for compact order events (see OrdersSubscriptionOpts).
+    signedOrder?: SignedOrder;
     endState: OrderEventEndState;
     fillableTakerAssetAmount: BigNumber;
     contractEvents: ContractEvent[];
+    orderFilterHash: string;
+    requestID?: string;
+    removalReason?: string;
+    sequenceNumber: number;
 }
 
 export interface RawAcceptedOrderInfo {
@@ -340,12 +351,36 @@ export interface RawOrderInfo {
     orderHash: string;
     signedOrder: StringifiedSignedOrder;
     fillableTakerAssetAmount: string;
+    annotations?: OrderAnnotations;
+    orderFilters?: string[];
 }
 
 export interface OrderInfo {
     orderHash: string;
     signedOrder: SignedOrder;
     fillableTakerAssetAmount: BigNumber;
+    annotations?: OrderAnnotations;
+    /**
+     * The names of the order filters which the order passes. Only set if the
+     * node uses additional order filters (see `ADDITIONAL_ORDER_FILTERS`).
+     */
+    orderFilters?: string[];
+}
+
+/**
+ * Key/value annotations attached to an order with `setOrderAnnotationsAsync`.
+ */
+export interface OrderAnnotations {
+    [key: string]: string;
+}
+
+/**
+ * Options for `subscribeToOrdersAsync`.
+ */
+export interface OrdersSubscriptionOpts {
+    // If true, the order events don't include the signed order, which can be
+    // fetched with `getOrderAsync` instead. Defaults to false.
+    compact?: boolean;
 }
 
 export enum RejectedKind {
@@ -357,8 +392,10 @@ export enum RejectedKind {
 export enum RejectedCode {
     InternalError = 'InternalError',
     MaxOrderSizeExceeded = 'MaxOrderSizeExceeded',
+    MaxDiskUsageExceeded = 'MaxDiskUsageExceeded',
     OrderAlreadyStored = 'OrderAlreadyStored',
     OrderForIncorrectChain = 'OrderForIncorrectChain',
+    IncorrectExchangeAddress = 'IncorrectExchangeAddress',
     NetworkRequestFailed = 'NetworkRequestFailed',
     OrderHasInvalidMakerAssetAmount = 'OrderHasInvalidMakerAssetAmount',
     OrderHasInvalidTakerAssetAmount = 'OrderHasInvalidTakerAssetAmount',
@@ -367,8 +404,14 @@ export enum RejectedCode {
     OrderCancelled = 'OrderCancelled',
     OrderUnfunded = 'OrderUnfunded',
     OrderHasInvalidMakerAssetData = 'OrderHasInvalidMakerAssetData',
+    OrderHasInvalidMakerFeeAssetData = 'OrderHasInvalidMakerFeeAssetData',
     OrderHasInvalidTakerAssetData = 'OrderHasInvalidTakerAssetData',
+    OrderHasInvalidTakerFeeAssetData = 'OrderHasInvalidTakerFeeAssetData',
     OrderHasInvalidSignature = 'OrderHasInvalidSignature',
+    OrderStaticCallFailed = 'OrderStaticCallFailed',
+    AssetDataUnsupported = 'AssetDataUnsupported',
+    InvalidSchema = 'InvalidSchema',
+    SchemaMismatch = 'SchemaMismatch',
 }
 
 export interface RejectedStatus {
@@ -376,11 +419,27 @@ export interface RejectedStatus {
     message: string;
 }
 
+/**
+ * Describes a single violation of the order schema in a machine-readable
+ * form. `pointer` is the JSON pointer (RFC 6901) to the offending field,
+ * `constraint` is the violated JSON schema keyword (e.g. "pattern"),
+ * `expected` is the limit imposed by the constraint and `actual` is the value
+ * of the offending field.
+ */
+export interface SchemaFieldError {
+    pointer: string;
+    constraint: string;
+    expected?: any;
+    actual?: any;
+    message: string;
+}
+
 export interface RawRejectedOrderInfo {
     orderHash: string;
     signedOrder: StringifiedSignedOrder;
     kind: RejectedKind;
     status: RejectedStatus;
+    fieldErrors?: SchemaFieldError[];
 }
 
 export interface RejectedOrderInfo {
@@ -388,16 +447,19 @@ export interface RejectedOrderInfo {
     signedOrder: SignedOrder;
     kind: RejectedKind;
     status: RejectedStatus;
+    fieldErrors?: SchemaFieldError[];
 }
 
 export interface RawValidationResults {
     accepted: RawAcceptedOrderInfo[];
     rejected: RawRejectedOrderInfo[];
+    requestID?: string;
 }
 
 export interface ValidationResults {
     accepted: AcceptedOrderInfo[];
     rejected: RejectedOrderInfo[];
+    requestID?: string;
 }
 
 export interface RawGetOrdersResponse {
@@ -415,6 +477,76 @@ export interface GetOrdersResponse {
     ordersInfos: OrderInfo[];
 }
 
+export interface RawGetOrderEventsSinceResponse {
+    orderEvents: RawOrderEvent[];
+    latestSequenceNumber: number;
+}
+
+// GetOrderEventsSinceResponse is the response returned when calling the
+// mesh_getOrderEventsSince method. If `latestSequenceNumber` is greater than
+// the sequence number of the last order event, there are more events to fetch.
+export interface GetOrderEventsSinceResponse {
+    orderEvents: OrderEvent[];
+    latestSequenceNumber: number;
+}
+
+// NotRemovedOrderInfo describes an order which could not be removed by
+// mesh_removeOrders. `reason` is either 'NOT_FOUND' or 'NOT_ADDED_LOCALLY'.
+export interface NotRemovedOrderInfo {
+    orderHash: string;
+    reason: string;
+}
+
+// RemoveOrdersResponse is the response returned when calling the
+// mesh_removeOrders method.
+export interface RemoveOrdersResponse {
+    removed: string[];
+    notRemoved: NotRemovedOrderInfo[];
+}
+
+// NotRevalidatedOrderInfo describes an order which could not be revalidated by
+// mesh_revalidateOrders. `reason` is either 'NOT_FOUND' or the code of the
+// rejected order status that prevented validation (e.g. 'EthRPCRequestFailed').
+export interface NotRevalidatedOrderInfo {
+    orderHash: string;
+    reason: string;
+}
+
+export interface RawRevalidateOrdersResponse {
+    ordersInfos: RawOrderInfo[];
+    notRevalidated: NotRevalidatedOrderInfo[];
+}
+
+// RevalidateOrdersResponse is the response returned when calling the
+// mesh_revalidateOrders method.
+export interface RevalidateOrdersResponse {
+    ordersInfos: OrderInfo[];
+    notRevalidated: NotRevalidatedOrderInfo[];
+}
+
+export interface RawOrdersStreamChunk {
+    snapshotTimestamp: string;
+    ordersInfos: RawOrderInfo[];
+    done: boolean;
+    numOrders?: number;
+    checksum?: string;
+    error?: string;
+}
+
+export interface OrdersStreamChunkPayload {
+    subscription: string;
+    result: RawOrdersStreamChunk;
+}
+
+// OrdersStreamSummary is returned once all orders have been received from the
+// `ordersSnapshot` subscription. The `checksum` is the hex encoded Keccak-256
+// hash of all order hashes concatenated in the order they were received.
+export interface OrdersStreamSummary {
+    snapshotTimestamp: number;
+    numOrders: number;
+    checksum: string;
+}
+
 export interface WSMessage {
     type: string;
     utf8Data: string;
@@ -425,9 +557,112 @@ export interface LatestBlock {
     hash: string;
 }
 
+export interface OrderSyncProviderStats {
+    peerID: string;
+    numSyncs: number;
+    numFailures: number;
+    ordersReceived: number;
+    ordersAccepted: number;
+    ordersRejected: { [code: string]: number };
+    averageDurationMs: number;
+    averageLatencyMs: number;
+    lastSyncTime: string;
+    lastSubprotocol: string;
+}
+
+export interface ProtocolBandwidthStats {
+    protocol: string;
+    totalIn: number;
+    totalOut: number;
+    rateIn: number;
+    rateOut: number;
+}
+
+export interface PeerBandwidthStats {
+    peerID: string;
+    totalIn: number;
+    totalOut: number;
+    rateIn: number;
+    rateOut: number;
+}
+
+export interface EthRPCHealth {
+    circuitState: 'open' | 'closed';
+    consecutiveFailures: number;
+    lastError: string;
+    lastErrorTime: string;
+    inconsistentHeaders: number;
+}
+
+export interface DiskUsage {
+    usageBytes: number;
+    maxBytes: number;
+    budgetExceeded: boolean;
+}
+
+export interface ResourceLimitStats {
+    numLimitHits: { [limit: string]: number };
+    numInboundStreams: number;
+}
+
+export interface OrderSyncCompressionStats {
+    compressedPagesSent: number;
+    compressedPagesReceived: number;
+    uncompressedBytes: number;
+    compressedBytes: number;
+    compressionRatio: number;
+}
+
+export interface StalledStage {
+    stage: 'BLOCK_PROCESSING' | 'VALIDATION' | 'EVENT_DELIVERY';
+    stalledForMs: number;
+    thresholdMs: number;
+}
+
+export interface PipelineHealth {
+    status: 'ok' | 'degraded';
+    stalledStages: StalledStage[];
+}
+
+export interface OrderFilterCheckStats {
+    numAccepted: number;
+    numRejected: { [reason: string]: number };
+    numErrors: number;
+}
+
+export interface OrderFilterStats {
+    messages: OrderFilterCheckStats;
+    orders: OrderFilterCheckStats;
+}
+
+export interface OrderFeedbackStats {
+    numRejectionsSent: { [code: string]: number };
+    numRejectionsReceived: { [code: string]: number };
+}
+
+export interface ResourceUsage {
+    sampledAt: string;
+    cpuPercent: number;
+    memoryBytes: number;
+    bandwidthInPerSecond: number;
+    bandwidthOutPerSecond: number;
+    peerCountLow: number;
+    peerCountHigh: number;
+    validationConcurrency: number;
+    throttled: boolean;
+}
+
+export interface DialStats {
+    numSuccesses: number;
+    numFailures: { [reason: string]: number };
+    numSkipped: number;
+    numPeersInBackoff: number;
+}
+
 export interface GetStatsResponse {
     version: string;
     pubSubTopic: string;
+    orderFilterHash: string;
     rendezvous: string;
     peerID: string;
     ethereumChainID: number;
@@ -436,8 +671,26 @@ export interface GetStatsResponse {
     numOrders: number;
     numOrdersIncludingRemoved: number;
     numPinnedOrders: number;
+    numProvisionalOrders: number;
     maxExpirationTime: string;
     startOfCurrentUTCDay: string;
     ethRPCRequestsSentInCurrentUTCDay: number;
     ethRPCRateLimitExpiredRequests: number;
+    orderSyncProviders: OrderSyncProviderStats[];
+    ethRPCHealth: EthRPCHealth;
+    diskUsage: DiskUsage;
+    maxOrderSizeInBytes: number;
+    numOversizedOrdersRejected: number;
+    numOversizedMessagesDropped: number;
+    bandwidthByProtocol: ProtocolBandwidthStats[];
+    topPeersByBandwidth: PeerBandwidthStats[];
+    dialStats: DialStats;
+    resourceLimitStats: ResourceLimitStats;
+    subsystemCrashes: { [subsystem: string]: number };
+    numDuplicateOrders: { [source: string]: number };
+    orderSyncCompression: OrderSyncCompressionStats;
+    pipelineHealth: PipelineHealth;
+    orderFilter: OrderFilterStats;
+    resourceUsage: ResourceUsage;
+    orderFeedback: OrderFeedbackStats;
 }
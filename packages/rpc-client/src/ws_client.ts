@@ -13,18 +13,28 @@ import {
     ERC1155ApprovalForAllEvent,
     ERC721ApprovalForAllEvent,
     ExchangeCancelEvent,
+    GetOrderEventsSinceResponse,
     GetOrdersResponse,
     GetStatsResponse,
     HeartbeatEventPayload,
+    OrderAnnotations,
     OrderEvent,
     OrderEventPayload,
     OrderInfo,
+    OrdersStreamChunkPayload,
+    OrdersStreamSummary,
+    OrdersSubscriptionOpts,
     RawAcceptedOrderInfo,
+    RawGetOrderEventsSinceResponse,
     RawGetOrdersResponse,
     RawOrderEvent,
     RawOrderInfo,
+    RawOrdersStreamChunk,
+    RawRevalidateOrdersResponse,
     RawValidationResults,
     RejectedOrderInfo,
+    RemoveOrdersResponse,
+    RevalidateOrdersResponse,
     StringifiedContractEvent,
     StringifiedERC1155TransferBatchEvent,
     StringifiedERC1155TransferSingleEvent,
@@ -84,6 +94,12 @@ export class WSClient {
                 signedOrder: WSClient._convertOrderStringFieldsToBigNumber(rawOrderInfo.signedOrder),
                 fillableTakerAssetAmount: new BigNumber(rawOrderInfo.fillableTakerAssetAmount),
             };
+            if (rawOrderInfo.annotations !== undefined) {
+                orderInfo.annotations = rawOrderInfo.annotations;
+            }
+            if (rawOrderInfo.orderFilters !== undefined) {
+                orderInfo.orderFilters = rawOrderInfo.orderFilters;
+            }
             orderInfos.push(orderInfo);
         });
         return orderInfos;
@@ -116,6 +132,23 @@ export class WSClient {
             ordersInfos: WSClient._convertRawOrderInfos(rawGetOrdersResponse.ordersInfos),
         };
     }
+    private static _convertRawOrderEvent(rawOrderEvent: RawOrderEvent): OrderEvent {
+        return {
+            timestampMs: new Date(rawOrderEvent.timestamp).getTime(),
+            orderHash: rawOrderEvent.orderHash,
+            signedOrder:
+                rawOrderEvent.signedOrder === undefined
+                    ? undefined
+                    : WSClient._convertOrderStringFieldsToBigNumber(rawOrderEvent.signedOrder),
+            endState: rawOrderEvent.endState,
+            fillableTakerAssetAmount: new BigNumber(rawOrderEvent.fillableTakerAssetAmount),
+            contractEvents: WSClient._convertStringifiedContractEvents(rawOrderEvent.contractEvents),
+            orderFilterHash: rawOrderEvent.orderFilterHash,
+            requestID: rawOrderEvent.requestID,
+            removalReason: rawOrderEvent.removalReason,
+            sequenceNumber: rawOrderEvent.sequenceNumber,
+        };
+    }
     private static _convertStringifiedContractEvents(rawContractEvents: StringifiedContractEvent[]): ContractEvent[] {
         const contractEvents: ContractEvent[] = [];
         if (rawContractEvents === null) {
@@ -276,17 +309,25 @@ export class WSClient {
      * orders will not be affected by any DDoS prevention or incentive
      * mechanisms and will always stay in storage until they are no longer
      * fillable.
+     * @param requestID    An optional ID for the request. It is included in the
+     * Mesh node's logs, the validation results and the resulting order events.
+     * If omitted, the Mesh node generates one.
      * @returns validation results
      */
-    public async addOrdersAsync(signedOrders: SignedOrder[], pinned: boolean = true): Promise<ValidationResults> {
+    public async addOrdersAsync(
+        signedOrders: SignedOrder[],
+        pinned: boolean = true,
+        requestID?: string,
+    ): Promise<ValidationResults> {
         assert.isArray('signedOrders', signedOrders);
         const rawValidationResults: RawValidationResults = await this._wsProvider.send('mesh_addOrders', [
             signedOrders,
-            { pinned },
+            { pinned, requestID },
         ]);
         const validationResults: ValidationResults = {
             accepted: WSClient._convertRawAcceptedOrderInfos(rawValidationResults.accepted),
             rejected: [],
+            requestID: rawValidationResults.requestID,
         };
         rawValidationResults.rejected.forEach(rawRejectedOrderInfo => {
             const rejectedOrderInfo: RejectedOrderInfo = {
@@ -295,6 +336,9 @@ export class WSClient {
                 kind: rawRejectedOrderInfo.kind,
                 status: rawRejectedOrderInfo.status,
             };
+            if (rawRejectedOrderInfo.fieldErrors !== undefined) {
+                rejectedOrderInfo.fieldErrors = rawRejectedOrderInfo.fieldErrors;
+            }
             validationResults.rejected.push(rejectedOrderInfo);
         });
         return validationResults;
@@ -354,33 +398,156 @@ export class WSClient {
         const getOrdersResponse = WSClient._convertRawGetOrdersResponse(rawGetOrdersResponse);
         return getOrdersResponse;
     }
+    /**
+     * Get a single order stored on the Mesh node. This can be used to fetch the
+     * orders of compact order events (see `subscribeToOrdersAsync`).
+     * @param orderHash The hash of the order
+     * @returns the order, its hash and fillableTakerAssetAmount. Throws if the node doesn't store the order
+     */
+    public async getOrderAsync(orderHash: string): Promise<OrderInfo> {
+        assert.isString('orderHash', orderHash);
+        const rawOrderInfo: RawOrderInfo = await this._wsProvider.send('mesh_getOrder', [orderHash]);
+        return WSClient._convertRawOrderInfos([rawOrderInfo])[0];
+    }
+    /**
+     * Get the order events with a sequence number greater than the given
+     * sequence number. Every order event has a strictly increasing
+     * `sequenceNumber`, so this can be used to fetch the order events that a
+     * subscription missed (e.g. while reconnecting).
+     * @param sequenceNumber The sequence number of the last order event that was processed
+     * @param limit The maximum number of order events to return (up to 1000). If 0, up to 1000 order events are returned
+     * @returns the order events and the sequence number of the latest order event emitted by Mesh
+     */
+    public async getOrderEventsSinceAsync(sequenceNumber: number, limit: number = 0): Promise<GetOrderEventsSinceResponse> {
+        const rawResponse: RawGetOrderEventsSinceResponse = await this._wsProvider.send('mesh_getOrderEventsSince', [
+            sequenceNumber,
+            limit,
+        ]);
+        return {
+            orderEvents: rawResponse.orderEvents.map(WSClient._convertRawOrderEvent),
+            latestSequenceNumber: rawResponse.latestSequenceNumber,
+        };
+    }
+    /**
+     * Remove orders that were previously added to this Mesh node with
+     * `addOrdersAsync`. Subscribers receive a REMOVED order event with the given
+     * reason for each removed order. Orders the node received from its peers
+     * cannot be removed.
+     * @param orderHashes The hashes of the orders to remove
+     * @param reason A human-readable reason which is included in the order events
+     * @returns the hashes of the removed orders and the orders that could not be removed
+     */
+    public async removeOrdersAsync(orderHashes: string[], reason: string = ''): Promise<RemoveOrdersResponse> {
+        const response: RemoveOrdersResponse = await this._wsProvider.send('mesh_removeOrders', [
+            orderHashes,
+            reason,
+        ]);
+        return response;
+    }
+    /**
+     * Immediately re-validate orders against the latest block instead of waiting
+     * for Mesh to process the block in which their state changed. This is useful
+     * when you know that the state of an order changed (e.g. because you filled
+     * it). Order events are emitted for any orders whose state changed.
+     * @param orderHashes The hashes of the orders to re-validate
+     * @returns the updated orders and the orders that could not be re-validated
+     */
+    public async revalidateOrdersAsync(orderHashes: string[]): Promise<RevalidateOrdersResponse> {
+        const rawResponse: RawRevalidateOrdersResponse = await this._wsProvider.send('mesh_revalidateOrders', [
+            orderHashes,
+        ]);
+        return {
+            ordersInfos: WSClient._convertRawOrderInfos(rawResponse.ordersInfos),
+            notRevalidated: rawResponse.notRevalidated,
+        };
+    }
+    /**
+     * Switch the Mesh node to another Ethereum RPC endpoint without restarting
+     * it (e.g. to rotate the API key of a provider). The endpoint must be for the
+     * same chain as the node. It is not persisted, so the node uses its
+     * configured endpoint again once it is restarted.
+     * @param url The URL of the new Ethereum RPC endpoint
+     */
+    public async setEthereumRPCURLAsync(url: string): Promise<void> {
+        await this._wsProvider.send('mesh_setEthereumRPCURL', [url]);
+    }
+    /**
+     * Attach key/value annotations (e.g. internal strategy IDs) to an order stored
+     * by Mesh. They are merged into the existing annotations of the order and
+     * annotations with an empty value are removed. Annotations are returned by
+     * `getOrdersAsync` but never shared with peers.
+     * @param orderHash The hash of the order to annotate
+     * @param annotations The annotations to set
+     * @returns all annotations of the order
+     */
+    public async setOrderAnnotationsAsync(orderHash: string, annotations: OrderAnnotations): Promise<OrderAnnotations> {
+        const orderAnnotations: OrderAnnotations = await this._wsProvider.send('mesh_setOrderAnnotations', [
+            orderHash,
+            annotations,
+        ]);
+        return orderAnnotations;
+    }
+    /**
+     * Stream all orders currently stored by Mesh in chunks. Unlike `getOrdersAsync`, this
+     * does not require paginating and Mesh only sends the next chunk once the previous one
+     * has been written to the connection, which makes it suitable for snapshotting very
+     * large order books.
+     * @param cb callback function that is called with each chunk of orders
+     * @param chunkSize The maximum number of orders in each chunk (up to 10000). If 0, chunks contain up to 1000 orders
+     * @returns the snapshotTimestamp, the number of orders streamed and a checksum of their hashes
+     */
+    public async streamOrdersAsync(
+        cb: (ordersInfos: OrderInfo[]) => void,
+        chunkSize: number = 0,
+    ): Promise<OrdersStreamSummary> {
+        assert.isFunction('cb', cb);
+        const subscriptionId = await this._wsProvider.subscribe('mesh_subscribe', 'ordersSnapshot', [chunkSize]);
+        return new Promise<OrdersStreamSummary>((resolve, reject) => {
+            const chunkCallback = (chunkPayload: OrdersStreamChunkPayload) => {
+                const rawChunk: RawOrdersStreamChunk = chunkPayload.result;
+                if (!rawChunk.done) {
+                    cb(WSClient._convertRawOrderInfos(rawChunk.ordersInfos));
+                    return;
+                }
+                this._wsProvider.removeAllListeners(subscriptionId);
+                // The stream has already ended so there is nothing to do if
+                // unsubscribing fails.
+                this._wsProvider.send('mesh_unsubscribe', [chunkPayload.subscription]).catch(() => undefined);
+                if (rawChunk.error !== undefined) {
+                    reject(new Error(rawChunk.error));
+                    return;
+                }
+                resolve({
+                    snapshotTimestamp: Math.round(new Date(rawChunk.snapshotTimestamp).getTime() / 1000),
+                    numOrders: rawChunk.numOrders || 0,
+                    checksum: rawChunk.checksum || '',
+                });
+            };
+            this._wsProvider.on(subscriptionId, chunkCallback as any);
+        });
+    }
     /**
      * Subscribe to the 'orders' topic and receive order events from Mesh. This method returns a
      * subscriptionId that can be used to `unsubscribe()` from this subscription.
      * @param   cb   callback function where you'd like to get notified about order events
+     * @param   opts options for the subscription. If `compact` is true, the order events don't include
+     *               the signed order, which can be fetched with `getOrderAsync`
      * @return subscriptionId
      */
-    public async subscribeToOrdersAsync(cb: (orderEvents: OrderEvent[]) => void): Promise<string> {
+    public async subscribeToOrdersAsync(
+        cb: (orderEvents: OrderEvent[]) => void,
+        opts?: OrdersSubscriptionOpts,
+    ): Promise<string> {
         assert.isFunction('cb', cb);
-        const orderEventsSubscriptionId = await this._wsProvider.subscribe('mesh_subscribe', 'orders', []);
+        const params = opts === undefined ? [] : [opts];
+        const orderEventsSubscriptionId = await this._wsProvider.subscribe('mesh_subscribe', 'orders', params);
         const id = uuid();
         this._subscriptionIdToMeshSpecificId[id] = orderEventsSubscriptionId;
 
         const orderEventsCallback = (eventPayload: OrderEventPayload) => {
             this._subscriptionIdToMeshSpecificId[id] = eventPayload.subscription;
             const rawOrderEvents: RawOrderEvent[] = eventPayload.result;
-            const orderEvents: OrderEvent[] = [];
-            rawOrderEvents.forEach(rawOrderEvent => {
-                const orderEvent = {
-                    timestampMs: new Date(rawOrderEvent.timestamp).getTime(),
-                    orderHash: rawOrderEvent.orderHash,
-                    signedOrder: WSClient._convertOrderStringFieldsToBigNumber(rawOrderEvent.signedOrder),
-                    endState: rawOrderEvent.endState,
-                    fillableTakerAssetAmount: new BigNumber(rawOrderEvent.fillableTakerAssetAmount),
-                    contractEvents: WSClient._convertStringifiedContractEvents(rawOrderEvent.contractEvents),
-                };
-                orderEvents.push(orderEvent);
-            });
+            const orderEvents = rawOrderEvents.map(WSClient._convertRawOrderEvent);
             cb(orderEvents);
         };
         this._wsProvider.on(orderEventsSubscriptionId, orderEventsCallback as any);
@@ -158,11 +158,53 @@ blockchainTests.resets('WSClient', env => {
                     numOrders: 0,
                     numOrdersIncludingRemoved: 0,
                     numPinnedOrders: 0,
+                    numProvisionalOrders: 0,
                     maxExpirationTime: constants.MAX_UINT256.toString(),
                     startOfCurrentUTCDay: expectedStartOfCurrentUTCDay,
                     ethRPCRequestsSentInCurrentUTCDay: 0,
                     ethRPCRateLimitExpiredRequests: 0,
+                    orderSyncProviders: [],
+                    ethRPCHealth: {
+                        circuitState: 'closed',
+                        consecutiveFailures: 0,
+                        lastError: '',
+                        lastErrorTime: '0001-01-01T00:00:00Z',
+                        inconsistentHeaders: 0,
+                    },
+                    diskUsage: {
+                        usageBytes: stats.diskUsage.usageBytes,
+                        maxBytes: 0,
+                        budgetExceeded: false,
+                    },
+                    maxOrderSizeInBytes: 16000,
+                    numOversizedOrdersRejected: 0,
+                    numOversizedMessagesDropped: 0,
+                    bandwidthByProtocol: [],
+                    topPeersByBandwidth: [],
+                    // Dials depend on which peers are discovered.
+                    dialStats: stats.dialStats,
+                    resourceLimitStats: {
+                        numLimitHits: {},
+                        numInboundStreams: 0,
+                    },
+                    subsystemCrashes: {},
+                    numDuplicateOrders: {},
+                    orderSyncCompression: stats.orderSyncCompression,
+                    pipelineHealth: {
+                        status: 'ok',
+                        stalledStages: [],
+                    },
+                    // Messages depend on which peers are discovered.
+                    orderFilter: stats.orderFilter,
+                    // Resource usage depends on the environment.
+                    resourceUsage: stats.resourceUsage,
+                    orderFeedback: {
+                        numRejectionsSent: {},
+                        numRejectionsReceived: {},
+                    },
                 };
+                // The exact disk usage depends on the environment.
+                expect(stats.diskUsage.usageBytes).to.be.greaterThan(0);
                 expect(stats).to.be.deep.eq(expectedStats);
             });
         });
@@ -282,7 +324,7 @@ blockchainTests.resets('WSClient', env => {
                             for (const orderEvent of orderEvents) {
                                 if (orderHash === orderEvent.orderHash) {
                                     hasSeenMatch = true;
-                                    expect(orderEvent.signedOrder).to.be.deep.eq(order);
+                                    expect(lowercaseAddresses(orderEvent.signedOrder)).to.be.deep.eq(order);
                                     expect(orderEvent.fillableTakerAssetAmount).to.be.bignumber.eq(
                                         order.takerAssetAmount,
                                     );
@@ -315,13 +357,13 @@ blockchainTests.resets('WSClient', env => {
                         const [orderEvent] = orderEvents;
                         expect(orderEvent.endState).to.be.eq(OrderEventEndState.Cancelled);
                         expect(orderEvent.fillableTakerAssetAmount).to.be.bignumber.eq(constants.ZERO_AMOUNT);
-                        expect(orderEvent.signedOrder).to.be.deep.eq(order);
+                        expect(lowercaseAddresses(orderEvent.signedOrder)).to.be.deep.eq(order);
                         assertRoughlyEquals(orderEvent.timestampMs, now, secondsToMs(2));
                         expect(orderEvent.contractEvents.length).to.be.eq(1);
 
                         // Ensure that the contract event is correct.
                         const [contractEvent] = orderEvent.contractEvents;
-                        expect(contractEvent.address).to.be.eq(exchangeAddress);
+                        expect(contractEvent.address.toLowerCase()).to.be.eq(exchangeAddress);
                         expect(contractEvent.kind).to.be.equal(ContractEventKind.ExchangeCancelEvent);
                         expect(contractEvent.logIndex).to.be.eq(0);
                         expect(contractEvent.isRemoved).to.be.false();
@@ -496,6 +538,22 @@ async function sleepAsync(ms: number): Promise<NodeJS.Timer> {
     return new Promise<NodeJS.Timer>(resolve => setTimeout(resolve, ms));
 }
 
+// Mesh returns checksummed addresses, while the orders created by the
+// OrderFactory use lowercase addresses.
+function lowercaseAddresses(order: SignedOrder | undefined): SignedOrder | undefined {
+    if (order === undefined) {
+        return undefined;
+    }
+    return {
+        ...order,
+        exchangeAddress: order.exchangeAddress.toLowerCase(),
+        makerAddress: order.makerAddress.toLowerCase(),
+        takerAddress: order.takerAddress.toLowerCase(),
+        senderAddress: order.senderAddress.toLowerCase(),
+        feeRecipientAddress: order.feeRecipientAddress.toLowerCase(),
+    };
+}
+
 // Verify that all of the orders that were added to the mesh node
 // were returned in the `getOrders` rpc response
 function expectContainsOrders(expectedOrders: SignedOrder[], ordersInfos: OrderInfo[]): void {
@@ -504,7 +562,7 @@ function expectContainsOrders(expectedOrders: SignedOrder[], ordersInfos: OrderI
         for (const responseOrder of ordersInfos) {
             if (orderHashUtils.getOrderHashHex(order) === responseOrder.orderHash) {
                 hasSeenMatch = true;
-                expect(order).to.be.deep.eq(responseOrder.signedOrder);
+                expect(order).to.be.deep.eq(lowercaseAddresses(responseOrder.signedOrder));
                 break;
             }
         }
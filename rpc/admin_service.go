@@ -0,0 +1,180 @@
+// +build !js
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/0xProject/0x-mesh/common/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	peer "github.com/libp2p/go-libp2p-core/peer"
+)
+
+// adminService is the RPC service of clients which use the admin token (see
+// AccessPolicy.AdminToken). In addition to the methods of rpcService, it
+// exposes the methods which change how the node operates, e.g. banning peers
+// and removing orders, or expose its internals and other clients.
+type adminService struct {
+	*rpcService
+}
+
+// BanPeer parses the given peer ID and calls rpcHandler.BanPeer. The peer is
+// banned for durationSeconds or indefinitely if durationSeconds is 0.
+func (s *adminService) BanPeer(peerID string, reason string, durationSeconds int) (err error) {
+	defer func() { err = toAPIError(err) }()
+	parsedPeerID, err := peer.IDB58Decode(peerID)
+	if err != nil {
+		return invalidParams(err)
+	}
+	if durationSeconds < 0 {
+		return invalidParams(errors.New("durationSeconds cannot be negative"))
+	}
+	return s.rpcHandler.BanPeer(parsedPeerID, reason, time.Duration(durationSeconds)*time.Second)
+}
+
+// UnbanPeer parses the given peer ID and calls rpcHandler.UnbanPeer.
+func (s *adminService) UnbanPeer(peerID string) (err error) {
+	defer func() { err = toAPIError(err) }()
+	parsedPeerID, err := peer.IDB58Decode(peerID)
+	if err != nil {
+		return invalidParams(err)
+	}
+	return s.rpcHandler.UnbanPeer(parsedPeerID)
+}
+
+// BanIPRange calls rpcHandler.BanIPRange. ipRange is either in CIDR notation
+// or a single IP address.
+func (s *adminService) BanIPRange(ipRange string, reason string) (err error) {
+	defer func() { err = toAPIError(err) }()
+	return s.rpcHandler.BanIPRange(ipRange, reason)
+}
+
+// UnbanIPRange calls rpcHandler.UnbanIPRange.
+func (s *adminService) UnbanIPRange(ipRange string) (err error) {
+	defer func() { err = toAPIError(err) }()
+	return s.rpcHandler.UnbanIPRange(ipRange)
+}
+
+// AddToAllowlist calls rpcHandler.AddToAllowlist. entry is either a peer ID or
+// an IP range.
+func (s *adminService) AddToAllowlist(entry string) (err error) {
+	defer func() { err = toAPIError(err) }()
+	return s.rpcHandler.AddToAllowlist(entry)
+}
+
+// RemoveFromAllowlist calls rpcHandler.RemoveFromAllowlist.
+func (s *adminService) RemoveFromAllowlist(entry string) (err error) {
+	defer func() { err = toAPIError(err) }()
+	return s.rpcHandler.RemoveFromAllowlist(entry)
+}
+
+// GetSubscriptions returns all active subscriptions of all connections.
+func (s *adminService) GetSubscriptions() (result []*types.SubscriptionInfo, err error) {
+	defer func() { err = toAPIError(err) }()
+	return s.subscriptions.GetSubscriptions(), nil
+}
+
+// TerminateSubscription terminates the subscription with the given ID, which
+// can belong to any connection.
+func (s *adminService) TerminateSubscription(id string) (err error) {
+	defer func() { err = toAPIError(err) }()
+	return s.subscriptions.TerminateSubscription(id)
+}
+
+// RemoveOrders parses the given order hashes and calls rpcHandler.RemoveOrders.
+// The reason is optional.
+func (s *adminService) RemoveOrders(orderHashes []string, reason *string) (result *types.RemoveOrdersResponse, err error) {
+	defer func() { err = toAPIError(err) }()
+	if len(orderHashes) == 0 {
+		return nil, invalidParams(errors.New("orderHashes cannot be empty"))
+	}
+	parsedOrderHashes := make([]common.Hash, len(orderHashes))
+	for i, orderHash := range orderHashes {
+		orderHashBytes, err := hexutil.Decode(orderHash)
+		if err != nil {
+			return nil, invalidParams(err)
+		}
+		if len(orderHashBytes) != common.HashLength {
+			return nil, invalidParams(fmt.Errorf("orderHash must be %d bytes long", common.HashLength))
+		}
+		parsedOrderHashes[i] = common.BytesToHash(orderHashBytes)
+	}
+	removalReason := ""
+	if reason != nil {
+		removalReason = *reason
+	}
+	return s.rpcHandler.RemoveOrders(parsedOrderHashes, removalReason)
+}
+
+// SetEthereumRPCURL calls rpcHandler.SetEthereumRPCURL. It doesn't time out,
+// since the node waits for in-flight Ethereum RPC requests to finish before it
+// switches to the new endpoint.
+func (s *adminService) SetEthereumRPCURL(ctx context.Context, rawurl string) (err error) {
+	defer func() { err = toAPIError(err) }()
+	if rawurl == "" {
+		return invalidParams(errors.New("url cannot be empty"))
+	}
+	return s.rpcHandler.SetEthereumRPCURL(ctx, rawurl)
+}
+
+// RevalidateOrders parses the given order hashes and calls
+// rpcHandler.RevalidateOrders.
+func (s *adminService) RevalidateOrders(ctx context.Context, orderHashes []string) (result *types.RevalidateOrdersResponse, err error) {
+	defer func() { err = toAPIError(err) }()
+	if len(orderHashes) == 0 {
+		return nil, invalidParams(errors.New("orderHashes cannot be empty"))
+	}
+	parsedOrderHashes := make([]common.Hash, len(orderHashes))
+	for i, orderHash := range orderHashes {
+		orderHashBytes, err := hexutil.Decode(orderHash)
+		if err != nil {
+			return nil, invalidParams(err)
+		}
+		if len(orderHashBytes) != common.HashLength {
+			return nil, invalidParams(fmt.Errorf("orderHash must be %d bytes long", common.HashLength))
+		}
+		parsedOrderHashes[i] = common.BytesToHash(orderHashBytes)
+	}
+	var response *types.RevalidateOrdersResponse
+	err = s.callWithTimeout(ctx, "mesh_revalidateOrders", func(ctx context.Context) error {
+		var err error
+		response, err = s.rpcHandler.RevalidateOrders(ctx, parsedOrderHashes)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// SetOrderAnnotations parses the given order hash and calls
+// rpcHandler.SetOrderAnnotations.
+func (s *adminService) SetOrderAnnotations(orderHash string, annotations map[string]string) (result map[string]string, err error) {
+	defer func() { err = toAPIError(err) }()
+	orderHashBytes, err := hexutil.Decode(orderHash)
+	if err != nil {
+		return nil, invalidParams(err)
+	}
+	if len(orderHashBytes) != common.HashLength {
+		return nil, invalidParams(fmt.Errorf("orderHash must be %d bytes long", common.HashLength))
+	}
+	return s.rpcHandler.SetOrderAnnotations(common.BytesToHash(orderHashBytes), annotations)
+}
+
+// GetGossipSubTopology calls rpcHandler.GetGossipSubTopology. If there is an
+// error, it returns it.
+func (s *adminService) GetGossipSubTopology() (result *types.GossipSubTopology, err error) {
+	defer func() { err = toAPIError(err) }()
+	return s.rpcHandler.GetGossipSubTopology()
+}
+
+// GetValidationQueue calls rpcHandler.GetValidationQueue. If there is an error,
+// it returns it.
+func (s *adminService) GetValidationQueue() (result *types.ValidationQueue, err error) {
+	defer func() { err = toAPIError(err) }()
+	return s.rpcHandler.GetValidationQueue()
+}
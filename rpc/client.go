@@ -4,24 +4,30 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"math/big"
+	"time"
 
 	"github.com/0xProject/0x-mesh/common/types"
 	"github.com/0xProject/0x-mesh/zeroex"
 	"github.com/0xProject/0x-mesh/zeroex/ordervalidator"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/rpc"
 	peer "github.com/libp2p/go-libp2p-core/peer"
 	peerstore "github.com/libp2p/go-libp2p-peerstore"
 )
 
-// Client is a JSON RPC 2.0 client implementation over WebSockets. It can be
-// used to communicate with a 0x Mesh node and add orders.
+// Client is a JSON RPC 2.0 client implementation over WebSockets or HTTP. It can
+// be used to communicate with a 0x Mesh node and add orders. Subscriptions are
+// only supported when the client is connected via WebSockets.
 type Client struct {
 	rpcClient *rpc.Client
 }
 
 // NewClient creates and returns a new client. addr is the address of the server
-// (i.e. a 0x Mesh node) to dial.
+// (i.e. a 0x Mesh node) to dial. The scheme of addr ("ws://" or "http://")
+// determines which transport is used.
 func NewClient(addr string) (*Client, error) {
 	rpcClient, err := rpc.Dial(addr)
 	if err != nil {
@@ -59,6 +65,16 @@ func (c *Client) GetOrders(page, perPage int, snapshotID string) (*types.GetOrde
 	return &getOrdersResponse, nil
 }
 
+// GetOrder gets the order with the given hash from the Mesh node. It returns
+// an error if the node doesn't store the order.
+func (c *Client) GetOrder(orderHash common.Hash) (*types.OrderInfo, error) {
+	var orderInfo types.OrderInfo
+	if err := c.rpcClient.Call(&orderInfo, "mesh_getOrder", orderHash.Hex()); err != nil {
+		return nil, err
+	}
+	return &orderInfo, nil
+}
+
 // AddPeer adds the peer to the node's list of peers. The node will attempt to
 // connect to this new peer and return an error if it cannot.
 func (c *Client) AddPeer(peerInfo peerstore.PeerInfo) error {
@@ -82,15 +98,253 @@ func (c *Client) GetStats() (*types.Stats, error) {
 	return getStatsResponse, nil
 }
 
+// GetStatsAndSnapshot atomically retrieves the stats of the Mesh node, a new
+// order snapshot which can be paged through with GetOrders and the sequence
+// number of the last order event reflected by the snapshot. Clients should
+// apply the order events after this sequence number (see GetOrderEventsSince)
+// to keep the orders up to date.
+func (c *Client) GetStatsAndSnapshot() (*types.StatsAndSnapshot, error) {
+	var response *types.StatsAndSnapshot
+	if err := c.rpcClient.Call(&response, "mesh_getStatsAndSnapshot"); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// BanPeer bans the peer with the given ID. The ban is persisted by the node
+// and lasts for the given duration (rounded down to the nearest second) or
+// indefinitely if duration is 0.
+func (c *Client) BanPeer(peerID peer.ID, reason string, duration time.Duration) error {
+	return c.rpcClient.Call(nil, "mesh_banPeer", peer.IDB58Encode(peerID), reason, int(duration/time.Second))
+}
+
+// UnbanPeer lifts the ban on the peer with the given ID.
+func (c *Client) UnbanPeer(peerID peer.ID) error {
+	return c.rpcClient.Call(nil, "mesh_unbanPeer", peer.IDB58Encode(peerID))
+}
+
+// GetPeerBans returns all active peer bans.
+func (c *Client) GetPeerBans() ([]*types.PeerBan, error) {
+	var peerBans []*types.PeerBan
+	if err := c.rpcClient.Call(&peerBans, "mesh_getPeerBans"); err != nil {
+		return nil, err
+	}
+	return peerBans, nil
+}
+
+// BanIPRange bans all IP addresses in the given range, which is either in CIDR
+// notation (e.g. "10.0.0.0/8") or a single IP address. The ban is persisted by
+// the node.
+func (c *Client) BanIPRange(ipRange string, reason string) error {
+	return c.rpcClient.Call(nil, "mesh_banIPRange", ipRange, reason)
+}
+
+// UnbanIPRange lifts the ban on the given IP range.
+func (c *Client) UnbanIPRange(ipRange string) error {
+	return c.rpcClient.Call(nil, "mesh_unbanIPRange", ipRange)
+}
+
+// GetIPRangeBans returns all IP range bans.
+func (c *Client) GetIPRangeBans() ([]*types.IPRangeBan, error) {
+	var ipRangeBans []*types.IPRangeBan
+	if err := c.rpcClient.Call(&ipRangeBans, "mesh_getIPRangeBans"); err != nil {
+		return nil, err
+	}
+	return ipRangeBans, nil
+}
+
+// AddToAllowlist adds a peer ID or an IP range to the allowlist. While the
+// allowlist is not empty, the node only connects to peers which are on it. The
+// allowlist is persisted by the node.
+func (c *Client) AddToAllowlist(entry string) error {
+	return c.rpcClient.Call(nil, "mesh_addToAllowlist", entry)
+}
+
+// RemoveFromAllowlist removes a peer ID or an IP range from the allowlist.
+func (c *Client) RemoveFromAllowlist(entry string) error {
+	return c.rpcClient.Call(nil, "mesh_removeFromAllowlist", entry)
+}
+
+// GetAllowlist returns all peer IDs and IP ranges on the allowlist.
+func (c *Client) GetAllowlist() ([]string, error) {
+	var allowlist []string
+	if err := c.rpcClient.Call(&allowlist, "mesh_getAllowlist"); err != nil {
+		return nil, err
+	}
+	return allowlist, nil
+}
+
+// GetSubscriptions returns all active subscriptions of all clients of the
+// node, including their backlog and the number of dropped notifications.
+func (c *Client) GetSubscriptions() ([]*types.SubscriptionInfo, error) {
+	var subscriptions []*types.SubscriptionInfo
+	if err := c.rpcClient.Call(&subscriptions, "mesh_getSubscriptions"); err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// TerminateSubscription terminates the subscription with the given ID, which
+// can belong to any client of the node. The client which created the
+// subscription is not notified and simply stops receiving notifications.
+func (c *Client) TerminateSubscription(id string) error {
+	return c.rpcClient.Call(nil, "mesh_terminateSubscription", id)
+}
+
+// GetPeers returns the peers the node is connected to along with the metadata
+// they sent.
+func (c *Client) GetPeers() ([]*types.PeerInfo, error) {
+	var peers []*types.PeerInfo
+	if err := c.rpcClient.Call(&peers, "mesh_getPeers"); err != nil {
+		return nil, err
+	}
+	return peers, nil
+}
+
+// GetGossipSubTopology returns the GossipSub mesh and fanout peers of the node
+// for every topic along with recent control message statistics.
+func (c *Client) GetGossipSubTopology() (*types.GossipSubTopology, error) {
+	var topology types.GossipSubTopology
+	if err := c.rpcClient.Call(&topology, "mesh_getGossipSubTopology"); err != nil {
+		return nil, err
+	}
+	return &topology, nil
+}
+
+// GetValidationQueue returns a summary of the batches of orders which are
+// currently being validated.
+func (c *Client) GetValidationQueue() (*types.ValidationQueue, error) {
+	var validationQueue *types.ValidationQueue
+	if err := c.rpcClient.Call(&validationQueue, "mesh_getValidationQueue"); err != nil {
+		return nil, err
+	}
+	return validationQueue, nil
+}
+
+// TestOrderAgainstFilter validates the given JSON encoded signed order against
+// the order filter of the node without adding it and returns all of the
+// violations of the order schema.
+func (c *Client) TestOrderAgainstFilter(signedOrderJSON json.RawMessage) (*types.FilterTestResult, error) {
+	var result *types.FilterTestResult
+	if err := c.rpcClient.Call(&result, "mesh_testOrderAgainstFilter", signedOrderJSON); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetOrderStateAtBlock returns the state of the order with the given hash at
+// the given block. The node must be configured to record order state history.
+func (c *Client) GetOrderStateAtBlock(orderHash common.Hash, blockNumber *big.Int) (*types.OrderState, error) {
+	var orderState *types.OrderState
+	if err := c.rpcClient.Call(&orderState, "mesh_getOrderStateAtBlock", orderHash.Hex(), blockNumber.Int64()); err != nil {
+		return nil, err
+	}
+	return orderState, nil
+}
+
+// GetOrdersFillableAtBlock returns the state of all orders which were fillable
+// at the given block. The node must be configured to record order state
+// history.
+func (c *Client) GetOrdersFillableAtBlock(blockNumber *big.Int) ([]*types.OrderState, error) {
+	var orderStates []*types.OrderState
+	if err := c.rpcClient.Call(&orderStates, "mesh_getOrdersFillableAtBlock", blockNumber.Int64()); err != nil {
+		return nil, err
+	}
+	return orderStates, nil
+}
+
+// GetOrderEventsSince returns up to limit order events with a sequence number
+// greater than the given sequence number. If limit is 0, the node's default is
+// used. It can be used to fetch order events that were missed by a
+// subscription.
+func (c *Client) GetOrderEventsSince(sequenceNumber uint64, limit int) (*types.GetOrderEventsSinceResponse, error) {
+	var response *types.GetOrderEventsSinceResponse
+	if err := c.rpcClient.Call(&response, "mesh_getOrderEventsSince", sequenceNumber, limit); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// RemoveOrders removes orders that were previously added to the node with
+// AddOrders. Subscribers receive a REMOVED order event with the given reason
+// for each removed order. Orders the node received from its peers cannot be
+// removed.
+func (c *Client) RemoveOrders(orderHashes []common.Hash, reason string) (*types.RemoveOrdersResponse, error) {
+	orderHashStrings := make([]string, len(orderHashes))
+	for i, orderHash := range orderHashes {
+		orderHashStrings[i] = orderHash.Hex()
+	}
+	var response *types.RemoveOrdersResponse
+	if err := c.rpcClient.Call(&response, "mesh_removeOrders", orderHashStrings, reason); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// SetEthereumRPCURL switches the node to the given Ethereum RPC endpoint without
+// restarting it (e.g. to rotate the API key of a provider). The endpoint must
+// be for the same chain as the node. It is not persisted, so the node uses its
+// configured endpoint again once it is restarted.
+func (c *Client) SetEthereumRPCURL(rawurl string) error {
+	return c.rpcClient.Call(nil, "mesh_setEthereumRPCURL", rawurl)
+}
+
+// RevalidateOrders immediately re-validates the given orders against the
+// latest block and returns their updated state.
+func (c *Client) RevalidateOrders(orderHashes []common.Hash) (*types.RevalidateOrdersResponse, error) {
+	orderHashStrings := make([]string, len(orderHashes))
+	for i, orderHash := range orderHashes {
+		orderHashStrings[i] = orderHash.Hex()
+	}
+	var response *types.RevalidateOrdersResponse
+	if err := c.rpcClient.Call(&response, "mesh_revalidateOrders", orderHashStrings); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// SetOrderAnnotations attaches the given key/value annotations to a stored
+// order and returns all of its annotations. Annotations with an empty value
+// are removed. Annotations are returned by GetOrders but never shared with
+// peers.
+func (c *Client) SetOrderAnnotations(orderHash common.Hash, annotations map[string]string) (map[string]string, error) {
+	var response map[string]string
+	if err := c.rpcClient.Call(&response, "mesh_setOrderAnnotations", orderHash.Hex(), annotations); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
 // SubscribeToOrders subscribes a stream of order events
 // Note copied from `go-ethereum` codebase: Slow subscribers will be dropped eventually. Client
 // buffers up to 8000 notifications before considering the subscriber dead. The subscription Err
 // channel will receive ErrSubscriptionQueueOverflow. Use a sufficiently large buffer on the channel
 // or ensure that the channel usually has at least one reader to prevent this issue.
-func (c *Client) SubscribeToOrders(ctx context.Context, ch chan<- []*zeroex.OrderEvent) (*rpc.ClientSubscription, error) {
+// Subscriptions with the Compact option receive order events without the signed
+// order, which can be fetched with GetOrder.
+func (c *Client) SubscribeToOrders(ctx context.Context, ch chan<- []*zeroex.OrderEvent, opts ...types.OrdersSubscriptionOpts) (*rpc.ClientSubscription, error) {
+	if len(opts) > 1 {
+		return nil, errors.New("invalid number of orders subscription opts")
+	}
+	if len(opts) == 1 {
+		return c.rpcClient.Subscribe(ctx, "mesh", ch, "orders", opts[0])
+	}
 	return c.rpcClient.Subscribe(ctx, "mesh", ch, "orders")
 }
 
+// SubscribeToOrdersSnapshot subscribes to a stream of all orders currently
+// stored by Mesh, sent in chunks of up to chunkSize orders. If chunkSize is 0,
+// the default chunk size of 1000 is used. The last chunk has Done set to true
+// and includes the total number of orders and a checksum, after which the
+// subscription should be unsubscribed.
+// Note copied from `go-ethereum` codebase: Slow subscribers will be dropped eventually. Client
+// buffers up to 8000 notifications before considering the subscriber dead. The subscription Err
+// channel will receive ErrSubscriptionQueueOverflow. Use a sufficiently large buffer on the channel
+// or ensure that the channel usually has at least one reader to prevent this issue.
+func (c *Client) SubscribeToOrdersSnapshot(ctx context.Context, chunkSize int, ch chan<- *types.OrdersStreamChunk) (*rpc.ClientSubscription, error) {
+	return c.rpcClient.Subscribe(ctx, "mesh", ch, "ordersSnapshot", chunkSize)
+}
+
 // SubscribeToHeartbeat subscribes a stream of heartbeats in order to have certainty that the WS
 // connection is still alive.
 // Note copied from `go-ethereum` codebase: Slow subscribers will be dropped eventually. Client
@@ -0,0 +1,217 @@
+// +build !js
+
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+)
+
+// The fork of go-ethereum we use only includes the code and the message of an
+// error in the JSON-RPC error object (it doesn't support rpc.DataError). In
+// order to include the APIError envelope as the data of the error object, the
+// RPC methods return an envelopeError, which appends the encoded envelope to
+// the message, and the connections of the JSON-RPC server move it to the data
+// before a response is written.
+
+// envelopeSeparator separates the message from the encoded envelope in the
+// message of an envelopeError.
+const envelopeSeparator = "\x00"
+
+// encodedEnvelopeSeparator is envelopeSeparator as it appears in a JSON
+// encoded message.
+var encodedEnvelopeSeparator = []byte(`\u0000`)
+
+// maxWebSocketMessageSize is the maximum size of a message received over a
+// WebSocket connection. It is the same as the limit of go-ethereum.
+const maxWebSocketMessageSize = 5 * 1024 * 1024
+
+// envelopeError is the error returned by the RPC methods. The envelope is
+// moved to the data of the JSON-RPC error object by moveEnvelopes.
+type envelopeError struct {
+	*APIError
+}
+
+func (e envelopeError) Error() string {
+	encoded, err := json.Marshal(e.APIError)
+	if err != nil {
+		return e.Message
+	}
+	return e.Message + envelopeSeparator + string(encoded)
+}
+
+// jsonRPCMessage mirrors the messages which go-ethereum writes to a
+// connection.
+type jsonRPCMessage struct {
+	Version string          `json:"jsonrpc,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Error   *jsonRPCError   `json:"error,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+}
+
+type jsonRPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// moveEnvelopes moves the envelopes of the errors in the given JSON encoded
+// message (or batch of messages) to the data of the error objects. Messages
+// without envelopes are returned as is.
+func moveEnvelopes(encoded []byte) []byte {
+	if !bytes.Contains(encoded, encodedEnvelopeSeparator) {
+		return encoded
+	}
+	trimmed := bytes.TrimSpace(encoded)
+	isBatch := len(trimmed) > 0 && trimmed[0] == '['
+	var msgs []*jsonRPCMessage
+	if isBatch {
+		if err := json.Unmarshal(trimmed, &msgs); err != nil {
+			return encoded
+		}
+	} else {
+		var msg jsonRPCMessage
+		if err := json.Unmarshal(trimmed, &msg); err != nil {
+			return encoded
+		}
+		msgs = []*jsonRPCMessage{&msg}
+	}
+	for _, msg := range msgs {
+		if msg.Error == nil {
+			continue
+		}
+		parts := strings.SplitN(msg.Error.Message, envelopeSeparator, 2)
+		if len(parts) != 2 {
+			continue
+		}
+		msg.Error.Message = parts[0]
+		msg.Error.Data = json.RawMessage(parts[1])
+	}
+	var result []byte
+	var err error
+	if isBatch {
+		result, err = json.Marshal(msgs)
+	} else {
+		result, err = json.Marshal(msgs[0])
+	}
+	if err != nil {
+		return encoded
+	}
+	// go-ethereum terminates each message with a newline.
+	return append(result, '\n')
+}
+
+// envelopeResponseWriter moves the envelopes of the errors in the responses
+// of the HTTP JSON-RPC server. go-ethereum writes each response with a single
+// call to Write.
+type envelopeResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w envelopeResponseWriter) Write(p []byte) (int, error) {
+	if _, err := w.ResponseWriter.Write(moveEnvelopes(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// newEnvelopeHTTPHandler returns a handler which serves JSON-RPC requests over
+// HTTP with the given server.
+func newEnvelopeHTTPHandler(rpcServer *rpc.Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rpcServer.ServeHTTP(envelopeResponseWriter{w}, r)
+	})
+}
+
+// newEnvelopeWebSocketHandler returns a handler which serves JSON-RPC requests
+// over WebSockets with the given server. It replaces
+// rpc.Server.WebsocketHandler, whose connections can't be wrapped. Browsers
+// are only allowed to connect from the given origins.
+func newEnvelopeWebSocketHandler(rpcServer *rpc.Server, allowedOrigins []string) http.Handler {
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				return true
+			}
+			_, allowed := matchOrigin(allowedOrigins, origin)
+			return allowed
+		},
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.WithError(err).Debug("WebSocket upgrade failed")
+			return
+		}
+		conn.SetReadLimit(maxWebSocketMessageSize)
+		codec := rpc.NewJSONCodec(&envelopeWebSocketConn{conn: conn, remoteAddr: r.RemoteAddr})
+		rpcServer.ServeCodec(codec, rpc.OptionMethodInvocation|rpc.OptionSubscriptions)
+	})
+}
+
+// envelopeWebSocketConn implements rpc.Conn for a WebSocket connection. Each
+// message written by go-ethereum is sent as one WebSocket message, after the
+// envelopes of its errors have been moved.
+type envelopeWebSocketConn struct {
+	conn       *websocket.Conn
+	remoteAddr string
+	reader     io.Reader
+}
+
+var _ rpc.Conn = &envelopeWebSocketConn{}
+
+// Read reads the messages received over the connection as a stream. It is
+// only called by a single goroutine.
+func (c *envelopeWebSocketConn) Read(p []byte) (int, error) {
+	for {
+		if c.reader == nil {
+			_, reader, err := c.conn.NextReader()
+			if err != nil {
+				return 0, err
+			}
+			c.reader = reader
+		}
+		n, err := c.reader.Read(p)
+		if err == io.EOF {
+			c.reader = nil
+			if n == 0 {
+				continue
+			}
+			return n, nil
+		}
+		return n, err
+	}
+}
+
+// Write sends p as one WebSocket message. go-ethereum serializes calls to
+// Write.
+func (c *envelopeWebSocketConn) Write(p []byte) (int, error) {
+	if err := c.conn.WriteMessage(websocket.TextMessage, moveEnvelopes(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *envelopeWebSocketConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *envelopeWebSocketConn) SetWriteDeadline(deadline time.Time) error {
+	return c.conn.SetWriteDeadline(deadline)
+}
+
+// RemoteAddr is used by go-ethereum in log messages.
+func (c *envelopeWebSocketConn) RemoteAddr() string {
+	return c.remoteAddr
+}
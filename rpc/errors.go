@@ -0,0 +1,201 @@
+// +build !js
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// warmUpRetryAfter is the RetryAfter of failed health checks while the node
+// is warming up.
+const warmUpRetryAfter = 5 * time.Second
+
+// ErrorCode is the machine-readable code of an APIError. Clients should use it
+// (rather than the message) to decide how to handle an error.
+type ErrorCode string
+
+const (
+	// ErrorCodeInvalidParams means that the request was invalid. Retrying the
+	// same request won't help.
+	ErrorCodeInvalidParams ErrorCode = "INVALID_PARAMS"
+	// ErrorCodeInvalidRequest means that the method can't be used this way,
+	// e.g. a subscription over HTTP.
+	ErrorCodeInvalidRequest ErrorCode = "INVALID_REQUEST"
+	// ErrorCodeNotFound means that the requested resource (e.g. an order or a
+	// snapshot) doesn't exist.
+	ErrorCodeNotFound ErrorCode = "NOT_FOUND"
+	// ErrorCodeUnauthorized means that the request didn't include a valid auth
+	// token.
+	ErrorCodeUnauthorized ErrorCode = "UNAUTHORIZED"
+	// ErrorCodeForbidden means that the request came from a browser origin
+	// which is not allowed (see AccessPolicy.AllowedOrigins).
+	ErrorCodeForbidden ErrorCode = "FORBIDDEN"
+	// ErrorCodeTimeout means that the method didn't return within its timeout
+	// (see Timeouts).
+	ErrorCodeTimeout ErrorCode = "TIMEOUT"
+	// ErrorCodeCanceled means that the request was canceled, e.g. because the
+	// client disconnected.
+	ErrorCodeCanceled ErrorCode = "CANCELED"
+	// ErrorCodeRateLimited means that the node has used up its Ethereum RPC
+	// requests. RetryAfter is set to when more requests are available.
+	ErrorCodeRateLimited ErrorCode = "RATE_LIMITED"
+	// ErrorCodeUnavailable means that the node can't handle the request right
+	// now (e.g. because it is warming up) or at all (e.g. because the
+	// subsystem is disabled). Clients should only retry if RetryAfter is set.
+	ErrorCodeUnavailable ErrorCode = "UNAVAILABLE"
+	// ErrorCodeInternal means that something went wrong on the node. Details
+	// are only logged.
+	ErrorCodeInternal ErrorCode = "INTERNAL"
+)
+
+// jsonRPCErrorCodes are the codes of the JSON-RPC error objects for each
+// ErrorCode.
+var jsonRPCErrorCodes = map[ErrorCode]int{
+	ErrorCodeInvalidParams:  -32602,
+	ErrorCodeInvalidRequest: -32600,
+	ErrorCodeNotFound:       -32001,
+	ErrorCodeUnauthorized:   -32002,
+	ErrorCodeForbidden:      -32007,
+	ErrorCodeTimeout:        -32003,
+	ErrorCodeCanceled:       -32004,
+	ErrorCodeRateLimited:    -32005,
+	ErrorCodeUnavailable:    -32006,
+	ErrorCodeInternal:       -32603,
+}
+
+// httpStatusCodes are the HTTP status codes of the errors that are returned
+// before a request reaches the JSON-RPC server.
+var httpStatusCodes = map[ErrorCode]int{
+	ErrorCodeInvalidParams:  http.StatusBadRequest,
+	ErrorCodeInvalidRequest: http.StatusMethodNotAllowed,
+	ErrorCodeUnauthorized:   http.StatusUnauthorized,
+	ErrorCodeForbidden:      http.StatusForbidden,
+	ErrorCodeUnavailable:    http.StatusServiceUnavailable,
+}
+
+// APIError is the envelope of all errors returned by the API. Its code
+// determines the code of the JSON-RPC error object, and the envelope itself
+// is included as the data of the error object (see envelopeError), so client
+// SDKs can implement uniform retry behavior. Errors returned before a request
+// reaches the JSON-RPC server (e.g. failed health checks) use the envelope as
+// the body of the HTTP response.
+type APIError struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	// Details holds additional machine-readable information about the error,
+	// e.g. the method which timed out.
+	Details map[string]interface{} `json:"details,omitempty"`
+	// RetryAfter is the number of seconds after which the request may
+	// succeed if it is retried. It is omitted if retrying won't help.
+	RetryAfter int `json:"retryAfter,omitempty"`
+}
+
+// NewAPIError returns an APIError with the given code and the message of the
+// given error.
+func NewAPIError(code ErrorCode, err error) *APIError {
+	return &APIError{
+		Code:    code,
+		Message: err.Error(),
+	}
+}
+
+// WithDetails sets the given details and returns the error.
+func (e *APIError) WithDetails(details map[string]interface{}) *APIError {
+	e.Details = details
+	return e
+}
+
+// WithRetryAfter sets RetryAfter to the given duration (rounded up to the
+// next second) and returns the error.
+func (e *APIError) WithRetryAfter(retryAfter time.Duration) *APIError {
+	e.RetryAfter = int(math.Ceil(retryAfter.Seconds()))
+	return e
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// ErrorCode implements rpc.Error of go-ethereum.
+func (e *APIError) ErrorCode() int {
+	if code, found := jsonRPCErrorCodes[e.Code]; found {
+		return code
+	}
+	return jsonRPCErrorCodes[ErrorCodeInternal]
+}
+
+// invalidParams returns an APIError with ErrorCodeInvalidParams for an error
+// caused by a parameter of the request.
+func invalidParams(err error) *APIError {
+	return NewAPIError(ErrorCodeInvalidParams, err)
+}
+
+// toAPIError converts the given error into the envelopeError returned to the
+// JSON-RPC server. Errors which are not already APIErrors and are not known
+// to the rpc package are reported as ErrorCodeInternal. Invalid parameters
+// must be reported with invalidParams.
+func toAPIError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return envelopeError{toEnvelope(err)}
+}
+
+// toEnvelope converts the given error into an APIError.
+func toEnvelope(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	var timeoutErr ErrMethodTimeout
+	var tooManySubscriptionsErr ErrTooManySubscriptions
+	var subscriptionNotFoundErr ErrSubscriptionNotFound
+	switch {
+	case errors.As(err, &timeoutErr):
+		return NewAPIError(ErrorCodeTimeout, err).WithDetails(map[string]interface{}{
+			"method":    timeoutErr.Method,
+			"timeoutMs": timeoutErr.Timeout.Milliseconds(),
+		})
+	case errors.Is(err, context.Canceled):
+		return NewAPIError(ErrorCodeCanceled, err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return NewAPIError(ErrorCodeTimeout, err)
+	case errors.Is(err, constants.ErrInternal):
+		return NewAPIError(ErrorCodeInternal, err)
+	case errors.Is(err, ErrSubscriptionsRequireWebSocket), errors.Is(err, rpc.ErrNotificationsUnsupported):
+		return NewAPIError(ErrorCodeInvalidRequest, err)
+	case errors.As(err, &tooManySubscriptionsErr):
+		return NewAPIError(ErrorCodeInvalidRequest, err).WithDetails(map[string]interface{}{
+			"limit": tooManySubscriptionsErr.Limit,
+		})
+	case errors.As(err, &subscriptionNotFoundErr):
+		return NewAPIError(ErrorCodeNotFound, err)
+	default:
+		return NewAPIError(ErrorCodeInternal, err)
+	}
+}
+
+// writeHTTPError writes the given error as the JSON encoded body of an HTTP
+// response. If RetryAfter is set, it is also sent in the Retry-After header.
+func writeHTTPError(w http.ResponseWriter, apiErr *APIError) {
+	status, found := httpStatusCodes[apiErr.Code]
+	if !found {
+		status = http.StatusInternalServerError
+	}
+	if apiErr.RetryAfter != 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(apiErr.RetryAfter))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(apiErr)
+}
@@ -0,0 +1,128 @@
+// +build !js
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/event"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// maxCachedResponses is the maximum number of responses held by a
+// ResponseCache. The least recently used responses are evicted first.
+const maxCachedResponses = 1024
+
+// ResponseCache caches the responses of expensive read methods (mesh_getOrders
+// and mesh_getStats) for a short time, keyed by the method and its
+// parameters. This protects public nodes from clients which repeatedly send
+// the same requests. Errors are not cached. A ResponseCache can be shared by
+// several Servers.
+//
+// Cached responses are purged whenever orders change (see
+// PurgeOnOrderEvents), so that clients are never served orders which are out
+// of date.
+type ResponseCache struct {
+	ttl time.Duration
+	// responses is nil if the cache is disabled. lru.Cache is safe for
+	// concurrent use.
+	responses *lru.Cache
+	// generationMu guards generation, which is incremented every time the
+	// cache is purged. Responses are only cached if the cache wasn't purged
+	// while they were computed.
+	generationMu sync.Mutex
+	generation   uint64
+}
+
+type cachedResponse struct {
+	response interface{}
+	expires  time.Time
+}
+
+// NewResponseCache returns a cache which keeps responses for the given TTL.
+// If ttl is 0, responses are not cached.
+func NewResponseCache(ttl time.Duration) *ResponseCache {
+	cache := &ResponseCache{ttl: ttl}
+	if ttl > 0 {
+		// lru.New only returns an error if size is <= 0, so we can safely
+		// ignore it.
+		cache.responses, _ = lru.New(maxCachedResponses)
+	}
+	return cache
+}
+
+// getOrCall returns the cached response of the given method for the given
+// params. If there is none (or it has expired), it calls call and caches the
+// response if there is no error. Concurrent requests which miss the cache
+// each call call.
+func (c *ResponseCache) getOrCall(method string, params []interface{}, call func() (interface{}, error)) (interface{}, error) {
+	if c == nil || c.responses == nil {
+		return call()
+	}
+	encodedParams, err := json.Marshal(params)
+	if err != nil {
+		return call()
+	}
+	key := method + string(encodedParams)
+
+	if cached, found := c.responses.Get(key); found {
+		if time.Now().Before(cached.(cachedResponse).expires) {
+			return cached.(cachedResponse).response, nil
+		}
+		c.responses.Remove(key)
+	}
+
+	c.generationMu.Lock()
+	generation := c.generation
+	c.generationMu.Unlock()
+	response, err := call()
+	if err != nil {
+		return nil, err
+	}
+	c.generationMu.Lock()
+	defer c.generationMu.Unlock()
+	if c.generation == generation {
+		c.responses.Add(key, cachedResponse{
+			response: response,
+			expires:  time.Now().Add(c.ttl),
+		})
+	}
+	return response, nil
+}
+
+// Purge removes all cached responses.
+func (c *ResponseCache) Purge() {
+	if c == nil || c.responses == nil {
+		return
+	}
+	c.generationMu.Lock()
+	defer c.generationMu.Unlock()
+	c.generation++
+	c.responses.Purge()
+}
+
+// PurgeOnOrderEvents purges the cache whenever there are new order events. It
+// subscribes to order events with the given function and blocks until the
+// context is canceled or the subscription fails.
+func (c *ResponseCache) PurgeOnOrderEvents(ctx context.Context, subscribe func(sink chan<- []*zeroex.OrderEvent) event.Subscription) error {
+	if c == nil || c.responses == nil {
+		return nil
+	}
+	orderEventsChan := make(chan []*zeroex.OrderEvent, 1)
+	sub := subscribe(orderEventsChan)
+	defer sub.Unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-sub.Err():
+			return err
+		case <-orderEventsChan:
+			c.Purge()
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// +build !js
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingCall returns a function which can be passed to getOrCall. It
+// returns the number of times it has been called so far.
+func countingCall() func() (interface{}, error) {
+	numCalls := 0
+	return func() (interface{}, error) {
+		numCalls++
+		return numCalls, nil
+	}
+}
+
+func TestResponseCacheTTL(t *testing.T) {
+	cache := NewResponseCache(50 * time.Millisecond)
+	call := countingCall()
+
+	response, err := cache.getOrCall("mesh_getStats", nil, call)
+	require.NoError(t, err)
+	assert.Equal(t, 1, response)
+	response, err = cache.getOrCall("mesh_getStats", nil, call)
+	require.NoError(t, err)
+	assert.Equal(t, 1, response, "response should be cached")
+
+	time.Sleep(100 * time.Millisecond)
+	response, err = cache.getOrCall("mesh_getStats", nil, call)
+	require.NoError(t, err)
+	assert.Equal(t, 2, response, "response should have expired")
+}
+
+func TestResponseCacheDisabled(t *testing.T) {
+	cache := NewResponseCache(0)
+	call := countingCall()
+	for i := 1; i <= 2; i++ {
+		response, err := cache.getOrCall("mesh_getStats", nil, call)
+		require.NoError(t, err)
+		assert.Equal(t, i, response)
+	}
+}
+
+func TestResponseCacheErrorsNotCached(t *testing.T) {
+	cache := NewResponseCache(time.Minute)
+	_, err := cache.getOrCall("mesh_getStats", nil, func() (interface{}, error) {
+		return nil, errors.New("request failed")
+	})
+	require.Error(t, err)
+	response, err := cache.getOrCall("mesh_getStats", nil, countingCall())
+	require.NoError(t, err)
+	assert.Equal(t, 1, response)
+}
+
+func TestResponseCacheEviction(t *testing.T) {
+	cache := NewResponseCache(time.Minute)
+	firstCall := countingCall()
+	_, err := cache.getOrCall("mesh_getOrders", []interface{}{0, 100, ""}, firstCall)
+	require.NoError(t, err)
+
+	// Fill the cache with other responses so that the first one is evicted.
+	for page := 1; page <= maxCachedResponses; page++ {
+		_, err := cache.getOrCall("mesh_getOrders", []interface{}{page, 100, ""}, countingCall())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, maxCachedResponses, cache.responses.Len())
+
+	response, err := cache.getOrCall("mesh_getOrders", []interface{}{0, 100, ""}, firstCall)
+	require.NoError(t, err)
+	assert.Equal(t, 2, response, "least recently used response should have been evicted")
+}
+
+func TestResponseCacheKeys(t *testing.T) {
+	cache := NewResponseCache(time.Minute)
+	testCases := []struct {
+		method string
+		params []interface{}
+	}{
+		{"mesh_getOrders", []interface{}{0, 100, ""}},
+		{"mesh_getOrders", []interface{}{1, 100, ""}},
+		{"mesh_getOrders", []interface{}{0, 100, "snapshot"}},
+		{"mesh_getStats", []interface{}{0, 100, ""}},
+		{"mesh_getStats", nil},
+	}
+	for i, testCase := range testCases {
+		response, err := cache.getOrCall(testCase.method, testCase.params, func() (interface{}, error) {
+			return i, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, i, response, fmt.Sprintf("%s %v", testCase.method, testCase.params))
+	}
+	for i, testCase := range testCases {
+		response, err := cache.getOrCall(testCase.method, testCase.params, countingCall())
+		require.NoError(t, err)
+		assert.Equal(t, i, response, fmt.Sprintf("%s %v", testCase.method, testCase.params))
+	}
+}
+
+func TestResponseCachePurgeOnOrderEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewResponseCache(time.Minute)
+	var orderEventsFeed event.Feed
+	subscribed := make(chan struct{})
+	go func() {
+		_ = cache.PurgeOnOrderEvents(ctx, func(sink chan<- []*zeroex.OrderEvent) event.Subscription {
+			defer close(subscribed)
+			return orderEventsFeed.Subscribe(sink)
+		})
+	}()
+	<-subscribed
+
+	call := countingCall()
+	params := []interface{}{0, 100, ""}
+	response, err := cache.getOrCall("mesh_getOrders", params, call)
+	require.NoError(t, err)
+	assert.Equal(t, 1, response)
+
+	orderEventsFeed.Send([]*zeroex.OrderEvent{{EndState: zeroex.ESOrderAdded}})
+	deadline := time.Now().Add(5 * time.Second)
+	for cache.responses.Len() > 0 {
+		require.True(t, time.Now().Before(deadline), "timed out waiting for the cache to be purged")
+		time.Sleep(10 * time.Millisecond)
+	}
+	response, err = cache.getOrCall("mesh_getOrders", params, call)
+	require.NoError(t, err)
+	assert.Equal(t, 2, response, "stale response should not be served after an order event")
+}
+
+func TestResponseCachePurgeDuringCall(t *testing.T) {
+	cache := NewResponseCache(time.Minute)
+	params := []interface{}{0, 100, ""}
+
+	// Responses which were computed while the cache was purged might already
+	// be out of date, so they must not be cached.
+	response, err := cache.getOrCall("mesh_getOrders", params, func() (interface{}, error) {
+		cache.Purge()
+		return 1, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, response)
+	assert.Equal(t, 0, cache.responses.Len())
+}
@@ -4,34 +4,92 @@ package rpc
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"strings"
 	"sync"
 
+	"github.com/0xProject/0x-mesh/common/types"
 	"github.com/ethereum/go-ethereum/rpc"
 	log "github.com/sirupsen/logrus"
 )
 
-// Server is a JSON RPC 2.0 server implementation over WebSockets. It accepts
-// requests from a client for adding orders to the 0x Mesh network.
+// Server is a JSON RPC 2.0 server implementation over WebSockets or HTTP. It
+// accepts requests from a client for adding orders to the 0x Mesh network.
+// Subscriptions are only supported over WebSockets.
 type Server struct {
-	mut          sync.Mutex
-	addr         string
-	listenerAddr net.Addr
-	rpcHandler   RPCHandler
-	listener     net.Listener
-	rpcServer    *rpc.Server
+	mut           sync.Mutex
+	addr          string
+	listenerAddr  net.Addr
+	rpcHandler    RPCHandler
+	listener      net.Listener
+	rpcServer     *rpc.Server
+	adminServer   *rpc.Server
+	accessPolicy  AccessPolicy
+	timeouts      Timeouts
+	subscriptions *SubscriptionRegistry
+	responseCache *ResponseCache
 }
 
+// AccessPolicy determines which clients are allowed to use a Server.
+type AccessPolicy struct {
+	// AllowedOrigins are the origins (e.g. "https://app.example.com") from
+	// which browsers are allowed to send requests. "*" allows all origins.
+	// Requests without an Origin header (i.e. from clients which are not
+	// browsers) are not restricted by it. For HTTP, the corresponding CORS
+	// headers are included in responses. If it is empty, only
+	// "http://localhost" is allowed.
+	AllowedOrigins []string
+	// AuthToken is the token that clients must include in every request,
+	// either as a bearer token in the Authorization header or in the "token"
+	// query parameter (browsers cannot set headers for WebSocket connections).
+	// Health checks (HTTP GET requests) don't require it. If empty, no token is
+	// required.
+	AuthToken string
+	// AdminToken is the token that clients must include, in the same way as
+	// AuthToken, to use the admin methods which change how the node operates
+	// or expose its internals (e.g. mesh_banPeer, mesh_removeOrders and
+	// mesh_getSubscriptions).
+	// Requests with it can use all other methods as well. If empty, the admin
+	// methods are not available.
+	AdminToken string
+}
+
+// ErrAdminTokenReused is returned by NewServer if the admin token is the same
+// as the auth token, which would give every client access to the admin
+// methods.
+var ErrAdminTokenReused = errors.New("admin token must be different from the auth token")
+
+// authTokenQueryParam is the query parameter which can be used to send the
+// auth token.
+const authTokenQueryParam = "token"
+
 // NewServer creates and returns a new server which will listen for new
 // connections on the given addr and use the rpcHandler to handle incoming
-// requests.
-func NewServer(addr string, rpcHandler RPCHandler) (*Server, error) {
+// requests. Only clients which satisfy the given access policy can use it.
+// Methods which don't return within the given timeouts fail with
+// ErrMethodTimeout. Subscriptions are tracked and limited by the given
+// registry, which can be shared with other Servers. If it is nil, the server
+// uses its own registry without a limit. Responses of expensive read methods
+// are cached by the given cache, which can be shared as well. If it is nil,
+// responses are not cached.
+func NewServer(addr string, rpcHandler RPCHandler, accessPolicy AccessPolicy, timeouts Timeouts, subscriptions *SubscriptionRegistry, responseCache *ResponseCache) (*Server, error) {
+	if accessPolicy.AdminToken != "" && accessPolicy.AdminToken == accessPolicy.AuthToken {
+		return nil, ErrAdminTokenReused
+	}
+	if subscriptions == nil {
+		subscriptions = NewSubscriptionRegistry(0)
+	}
 	return &Server{
-		addr:       addr,
-		rpcHandler: rpcHandler,
+		addr:          addr,
+		rpcHandler:    rpcHandler,
+		accessPolicy:  accessPolicy,
+		timeouts:      timeouts,
+		subscriptions: subscriptions,
+		responseCache: responseCache,
 	}, nil
 }
 
@@ -51,13 +109,28 @@ func (s *Server) Listen(ctx context.Context, handlerType HandlerType) error {
 	s.mut.Lock()
 
 	rpcService := &rpcService{
-		rpcHandler: s.rpcHandler,
+		rpcHandler:    s.rpcHandler,
+		timeouts:      s.timeouts,
+		subscriptions: s.subscriptions,
+		responseCache: s.responseCache,
 	}
 	s.rpcServer = rpc.NewServer()
 	if err := s.rpcServer.RegisterName("mesh", rpcService); err != nil {
+		s.mut.Unlock()
 		log.WithField("error", err.Error()).Error("could not register RPC service")
 		return err
 	}
+	// The admin methods are only registered if they are protected by an admin
+	// token. Requests with the admin token are served by a separate rpc.Server
+	// which exposes all methods.
+	if s.accessPolicy.AdminToken != "" {
+		s.adminServer = rpc.NewServer()
+		if err := s.adminServer.RegisterName("mesh", &adminService{rpcService: rpcService}); err != nil {
+			s.mut.Unlock()
+			log.WithField("error", err.Error()).Error("could not register admin RPC service")
+			return err
+		}
+	}
 	listener, err := net.Listen("tcp4", s.addr)
 	if err != nil {
 		s.mut.Unlock()
@@ -71,15 +144,23 @@ func (s *Server) Listen(ctx context.Context, handlerType HandlerType) error {
 	go func() {
 		<-ctx.Done()
 		s.rpcServer.Stop()
+		if s.adminServer != nil {
+			s.adminServer.Stop()
+		}
 		_ = s.listener.Close()
 	}()
 
 	var handler http.Handler
 	switch handlerType {
 	case HTTPHandler:
-		handler = s.rpcServer
+		handler = newHTTPHandler(s.rpcServer, s.adminServer, s.rpcHandler, s.accessPolicy)
 	case WSHandler:
-		handler = s.rpcServer.WebsocketHandler([]string{"*"})
+		// The WebSocket handler checks the Origin header itself.
+		var adminHandler http.Handler
+		if s.adminServer != nil {
+			adminHandler = newEnvelopeWebSocketHandler(s.adminServer, s.accessPolicy.AllowedOrigins)
+		}
+		handler = newAuthHandler(newEnvelopeWebSocketHandler(s.rpcServer, s.accessPolicy.AllowedOrigins), adminHandler, s.accessPolicy)
 	default:
 		return fmt.Errorf("Unrecognized HandlerType: %d", handlerType)
 	}
@@ -107,6 +188,111 @@ func (s *Server) Listen(ctx context.Context, handlerType HandlerType) error {
 	return nil
 }
 
+// newHTTPHandler wraps the given JSON-RPC handler so that it is easier to use
+// with simple HTTP tooling such as curl. Only POST requests (and GET requests,
+// which are used as health checks) are allowed. Health checks fail until
+// isReady returns true and while the order processing pipeline is degraded.
+// GET requests to OrderEventsSSEPath stream order events
+// as Server-Sent Events. Requests from browsers are only allowed from the
+// origins in the access policy, and CORS preflight requests are answered
+// accordingly. Requests with the admin token are served by adminServer, which
+// is nil if there is no admin token.
+func newHTTPHandler(rpcServer *rpc.Server, adminServer *rpc.Server, rpcHandler RPCHandler, accessPolicy AccessPolicy) http.Handler {
+	var adminHandler http.Handler
+	if adminServer != nil {
+		adminHandler = newEnvelopeHTTPHandler(adminServer)
+	}
+	authenticatedRPCServer := newAuthHandler(newEnvelopeHTTPHandler(rpcServer), adminHandler, accessPolicy)
+	// Order events are available with either token.
+	sseHandler := newOrderEventsSSEHandler(rpcHandler)
+	orderEventsSSEHandler := newAuthHandler(sseHandler, sseHandler, accessPolicy)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" {
+			w.Header().Add("Vary", "Origin")
+			allowedOrigin, allowed := matchOrigin(accessPolicy.AllowedOrigins, origin)
+			if !allowed {
+				writeHTTPError(w, NewAPIError(ErrorCodeForbidden, errors.New("origin not allowed")))
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
+		}
+		switch r.Method {
+		case http.MethodPost:
+			// Served by the authenticated JSON-RPC server below.
+		case http.MethodGet:
+			if r.URL.Path == OrderEventsSSEPath {
+				orderEventsSSEHandler.ServeHTTP(w, r)
+				return
+			}
+			if !rpcHandler.IsReady() {
+				writeHTTPError(w, NewAPIError(ErrorCodeUnavailable, errors.New("warming up")).WithRetryAfter(warmUpRetryAfter))
+				return
+			}
+			if health := rpcHandler.PipelineHealth(); health.Status == types.PipelineHealthDegraded {
+				writeHTTPError(w, NewAPIError(ErrorCodeUnavailable, errors.New("order processing pipeline is degraded")).WithDetails(map[string]interface{}{
+					"stalledStages": health.StalledStages,
+				}))
+				return
+			}
+			rpcServer.ServeHTTP(w, r)
+			return
+		case http.MethodOptions:
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Last-Event-ID")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		default:
+			w.Header().Set("Allow", "GET, POST, OPTIONS")
+			writeHTTPError(w, NewAPIError(ErrorCodeInvalidRequest, errors.New("method not allowed")))
+			return
+		}
+		authenticatedRPCServer.ServeHTTP(w, r)
+	})
+}
+
+// matchOrigin returns the value of the Access-Control-Allow-Origin header for
+// the given origin and whether it is allowed.
+func matchOrigin(allowedOrigins []string, origin string) (string, bool) {
+	if len(allowedOrigins) == 0 {
+		// This matches the WebSocket handler of go-ethereum.
+		allowedOrigins = []string{"http://localhost"}
+	}
+	for _, allowedOrigin := range allowedOrigins {
+		if allowedOrigin == "*" {
+			return "*", true
+		}
+		if strings.EqualFold(allowedOrigin, origin) {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+// newAuthHandler wraps the given handlers so that requests which include the
+// admin token are served by adminHandler and all other requests are rejected
+// unless they include the auth token (see AccessPolicy). adminHandler is only
+// used if the access policy has an admin token.
+func newAuthHandler(handler http.Handler, adminHandler http.Handler, accessPolicy AccessPolicy) http.Handler {
+	if accessPolicy.AuthToken == "" && accessPolicy.AdminToken == "" {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get(authTokenQueryParam)
+		if authorization := r.Header.Get("Authorization"); strings.HasPrefix(authorization, "Bearer ") {
+			token = strings.TrimPrefix(authorization, "Bearer ")
+		}
+		switch {
+		case accessPolicy.AdminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(accessPolicy.AdminToken)) == 1:
+			adminHandler.ServeHTTP(w, r)
+		case accessPolicy.AuthToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(accessPolicy.AuthToken)) == 1:
+			handler.ServeHTTP(w, r)
+		default:
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeHTTPError(w, NewAPIError(ErrorCodeUnauthorized, errors.New("invalid or missing auth token")))
+		}
+	})
+}
+
 func isClosedNetworkConnectionErr(err error) bool {
 	if opErr, ok := err.(*net.OpError); ok {
 		if strings.Contains(opErr.Error(), "use of closed network connection") {
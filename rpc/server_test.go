@@ -0,0 +1,112 @@
+// +build !js
+
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAuthToken  = "test-auth-token"
+	testAdminToken = "test-admin-token"
+	// methodNotFoundCode is the JSON-RPC error code for methods which don't
+	// exist.
+	methodNotFoundCode = -32601
+)
+
+// dummyRPCHandler is an RPCHandler that panics if any of its methods are
+// called. It can be used to test requests which fail before they reach the
+// handler.
+type dummyRPCHandler struct {
+	RPCHandler
+}
+
+type testRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type testRPCResponse struct {
+	Error *testRPCError `json:"error"`
+}
+
+func startTestServer(t *testing.T, ctx context.Context, accessPolicy AccessPolicy) string {
+	server, err := NewServer("127.0.0.1:0", &dummyRPCHandler{}, accessPolicy, Timeouts{}, nil, nil)
+	require.NoError(t, err)
+	go func() {
+		_ = server.Listen(ctx, HTTPHandler)
+	}()
+	for server.Addr() == nil {
+		select {
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for the server to listen")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	return fmt.Sprintf("http://%s", server.Addr().String())
+}
+
+func callTestServer(t *testing.T, url string, token string, method string, params ...interface{}) *testRPCResponse {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  method,
+		"params":  params,
+	})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	var response testRPCResponse
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+	return &response
+}
+
+func TestAdminMethodsRequireAdminToken(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	url := startTestServer(t, ctx, AccessPolicy{
+		AuthToken:  testAuthToken,
+		AdminToken: testAdminToken,
+	})
+
+	// The order hash is invalid so that the request fails before it reaches
+	// the handler.
+	params := []interface{}{"0x1234", map[string]string{"strategy": "test"}}
+	response := callTestServer(t, url, testAuthToken, "mesh_setOrderAnnotations", params...)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, methodNotFoundCode, response.Error.Code, response.Error.Message)
+
+	response = callTestServer(t, url, testAdminToken, "mesh_setOrderAnnotations", params...)
+	require.NotNil(t, response.Error)
+	assert.NotEqual(t, methodNotFoundCode, response.Error.Code, response.Error.Message)
+	assert.Contains(t, response.Error.Message, "orderHash must be 32 bytes long")
+
+	response = callTestServer(t, url, testAuthToken, "mesh_getSubscriptions")
+	require.NotNil(t, response.Error)
+	assert.Equal(t, methodNotFoundCode, response.Error.Code, response.Error.Message)
+
+	response = callTestServer(t, url, testAdminToken, "mesh_getSubscriptions")
+	assert.Nil(t, response.Error)
+
+	response = callTestServer(t, url, testAuthToken, "mesh_getValidationQueue")
+	require.NotNil(t, response.Error)
+	assert.Equal(t, methodNotFoundCode, response.Error.Code, response.Error.Message)
+
+	response = callTestServer(t, url, testAuthToken, "mesh_getGossipSubTopology")
+	require.NotNil(t, response.Error)
+	assert.Equal(t, methodNotFoundCode, response.Error.Code, response.Error.Message)
+}
@@ -5,13 +5,22 @@ package rpc
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
 	"net"
 	"strings"
 	"time"
 
+	"github.com/0xProject/0x-mesh/common/requestid"
+	"github.com/0xProject/0x-mesh/common/supervisor"
 	"github.com/0xProject/0x-mesh/common/types"
 	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/zeroex"
 	"github.com/0xProject/0x-mesh/zeroex/ordervalidator"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/rpc"
 	ethrpc "github.com/ethereum/go-ethereum/rpc"
 	peer "github.com/libp2p/go-libp2p-core/peer"
@@ -25,37 +34,141 @@ var minHeartbeatInterval = 5 * time.Second
 
 // rpcService is an /ethereum/go-ethereum/rpc compatible service.
 type rpcService struct {
-	rpcHandler RPCHandler
+	rpcHandler    RPCHandler
+	timeouts      Timeouts
+	subscriptions *SubscriptionRegistry
+	// responseCache is nil if responses are not cached.
+	responseCache *ResponseCache
 }
 
 // RPCHandler is used to respond to incoming requests from the client.
 type RPCHandler interface {
-	// AddOrders is called when the client sends an AddOrders request.
-	AddOrders(signedOrdersRaw []*json.RawMessage, opts types.AddOrdersOpts) (*ordervalidator.ValidationResults, error)
-	// GetOrders is called when the clients sends a GetOrders request
-	GetOrders(page, perPage int, snapshotID string) (*types.GetOrdersResponse, error)
+	// AddOrders is called when the client sends an AddOrders request. ctx is
+	// canceled if the request times out or the client disconnects.
+	AddOrders(ctx context.Context, signedOrdersRaw []*json.RawMessage, opts types.AddOrdersOpts) (*ordervalidator.ValidationResults, error)
+	// GetOrders is called when the clients sends a GetOrders request. ctx is
+	// canceled if the request times out or the client disconnects.
+	GetOrders(ctx context.Context, page, perPage int, snapshotID string) (*types.GetOrdersResponse, error)
+	// GetOrder is called when the client sends a GetOrder request.
+	GetOrder(orderHash common.Hash) (*types.OrderInfo, error)
 	// AddPeer is called when the client sends an AddPeer request.
 	AddPeer(peerInfo peerstore.PeerInfo) error
 	// GetStats is called when the client sends an GetStats request.
 	GetStats() (*types.Stats, error)
+	// GetStatsAndSnapshot is called when the client sends a
+	// GetStatsAndSnapshot request. ctx is canceled if the request times out or
+	// the client disconnects.
+	GetStatsAndSnapshot(ctx context.Context) (*types.StatsAndSnapshot, error)
 	// SubscribeToOrders is called when a client sends a Subscribe to `orders` request
-	SubscribeToOrders(ctx context.Context) (*rpc.Subscription, error)
+	SubscribeToOrders(ctx context.Context, opts types.OrdersSubscriptionOpts) (*rpc.Subscription, error)
+	// StreamOrders is called when a client sends a Subscribe to
+	// `ordersSnapshot` request
+	StreamOrders(ctx context.Context, chunkSize int) (*rpc.Subscription, error)
+	// BanPeer is called when the client sends a BanPeer request.
+	BanPeer(peerID peer.ID, reason string, duration time.Duration) error
+	// UnbanPeer is called when the client sends an UnbanPeer request.
+	UnbanPeer(peerID peer.ID) error
+	// GetPeerBans is called when the client sends a GetPeerBans request.
+	GetPeerBans() ([]*types.PeerBan, error)
+	// BanIPRange is called when the client sends a BanIPRange request.
+	BanIPRange(ipRange string, reason string) error
+	// UnbanIPRange is called when the client sends an UnbanIPRange request.
+	UnbanIPRange(ipRange string) error
+	// GetIPRangeBans is called when the client sends a GetIPRangeBans request.
+	GetIPRangeBans() ([]*types.IPRangeBan, error)
+	// AddToAllowlist is called when the client sends an AddToAllowlist request.
+	AddToAllowlist(entry string) error
+	// RemoveFromAllowlist is called when the client sends a
+	// RemoveFromAllowlist request.
+	RemoveFromAllowlist(entry string) error
+	// GetAllowlist is called when the client sends a GetAllowlist request.
+	GetAllowlist() ([]string, error)
+	// GetPeers is called when the client sends a GetPeers request.
+	GetPeers() ([]*types.PeerInfo, error)
+	// GetGossipSubTopology is called when the client sends a
+	// GetGossipSubTopology request.
+	GetGossipSubTopology() (*types.GossipSubTopology, error)
+	// GetOrderStateAtBlock is called when the client sends a
+	// GetOrderStateAtBlock request.
+	GetOrderStateAtBlock(orderHash common.Hash, blockNumber *big.Int) (*types.OrderState, error)
+	// GetOrdersFillableAtBlock is called when the client sends a
+	// GetOrdersFillableAtBlock request.
+	GetOrdersFillableAtBlock(blockNumber *big.Int) ([]*types.OrderState, error)
+	// GetOrderEventsSince is called when the client sends a
+	// GetOrderEventsSince request.
+	GetOrderEventsSince(sequenceNumber uint64, limit int) (*types.GetOrderEventsSinceResponse, error)
+	// RemoveOrders is called when the client sends a RemoveOrders request.
+	RemoveOrders(orderHashes []common.Hash, reason string) (*types.RemoveOrdersResponse, error)
+	// RevalidateOrders is called when the client sends a RevalidateOrders
+	// request. ctx is canceled if the request times out or the client
+	// disconnects.
+	RevalidateOrders(ctx context.Context, orderHashes []common.Hash) (*types.RevalidateOrdersResponse, error)
+	// SetOrderAnnotations is called when the client sends a
+	// SetOrderAnnotations request.
+	SetOrderAnnotations(orderHash common.Hash, annotations map[string]string) (map[string]string, error)
+	// SetEthereumRPCURL is called when the client sends a SetEthereumRPCURL
+	// request. ctx is canceled if the client disconnects.
+	SetEthereumRPCURL(ctx context.Context, rawurl string) error
+	// GetValidationQueue is called when the client sends a GetValidationQueue
+	// request.
+	GetValidationQueue() (*types.ValidationQueue, error)
+	// TestOrderAgainstFilter is called when the client sends a
+	// TestOrderAgainstFilter request.
+	TestOrderAgainstFilter(signedOrderRaw json.RawMessage) (*types.FilterTestResult, error)
+	// IsReady is called when a health check is received over HTTP. Until it
+	// returns true, health checks fail with 503 Service Unavailable.
+	IsReady() bool
+	// PipelineHealth is called when a health check is received over HTTP
+	// after IsReady returned true. While the order processing pipeline is
+	// degraded, health checks fail with 503 Service Unavailable.
+	PipelineHealth() *types.PipelineHealth
+	// SubscribeToOrderEvents is called when a client connects to the order
+	// events SSE endpoint. Order events are sent to the given sink until the
+	// subscription is unsubscribed.
+	SubscribeToOrderEvents(sink chan<- []*zeroex.OrderEvent) event.Subscription
 }
 
+// ErrSubscriptionsRequireWebSocket is returned when a client attempts to create
+// a subscription over a transport that does not support notifications (e.g.
+// plain HTTP).
+var ErrSubscriptionsRequireWebSocket = errors.New("subscriptions are only supported over WebSocket connections")
+
 // Orders calls rpcHandler.SubscribeToOrders and returns the rpc subscription.
-func (s *rpcService) Orders(ctx context.Context) (*rpc.Subscription, error) {
-	return s.rpcHandler.SubscribeToOrders(ctx)
+// If opts is omitted, the subscription receives full order events.
+func (s *rpcService) Orders(ctx context.Context, opts *types.OrdersSubscriptionOpts) (result *rpc.Subscription, err error) {
+	defer func() { err = toAPIError(err) }()
+	if opts == nil {
+		opts = &types.OrdersSubscriptionOpts{}
+	}
+	return s.subscriptions.subscribe(ctx, SubscriptionTypeOrders, opts, func(ctx context.Context) (*rpc.Subscription, error) {
+		return s.rpcHandler.SubscribeToOrders(ctx, *opts)
+	})
+}
+
+// OrdersSnapshot calls rpcHandler.StreamOrders and returns the rpc
+// subscription. If chunkSize is omitted, the default chunk size is used.
+func (s *rpcService) OrdersSnapshot(ctx context.Context, chunkSize *int) (result *rpc.Subscription, err error) {
+	defer func() { err = toAPIError(err) }()
+	return s.subscriptions.subscribe(ctx, SubscriptionTypeOrdersSnapshot, nil, func(ctx context.Context) (*rpc.Subscription, error) {
+		if chunkSize == nil {
+			return s.rpcHandler.StreamOrders(ctx, 0)
+		}
+		return s.rpcHandler.StreamOrders(ctx, *chunkSize)
+	})
 }
 
 // Heartbeat calls rpcHandler.SubscribeToHeartbeat and returns the rpc subscription.
-func (s *rpcService) Heartbeat(ctx context.Context) (*rpc.Subscription, error) {
+func (s *rpcService) Heartbeat(ctx context.Context) (result *rpc.Subscription, err error) {
+	defer func() { err = toAPIError(err) }()
 	log.Debug("received heartbeat subscription request via RPC")
-	subscription, err := SetupHeartbeat(ctx)
-	if err != nil {
-		log.WithField("error", err.Error()).Error("internal error in `mesh_subscribe` to `heartbeat` RPC call")
-		return nil, constants.ErrInternal
-	}
-	return subscription, nil
+	return s.subscriptions.subscribe(ctx, SubscriptionTypeHeartbeat, nil, func(ctx context.Context) (*rpc.Subscription, error) {
+		subscription, err := SetupHeartbeat(ctx)
+		if err != nil {
+			log.WithField("error", err.Error()).Error("internal error in `mesh_subscribe` to `heartbeat` RPC call")
+			return nil, constants.ErrInternal
+		}
+		return subscription, nil
+	})
 }
 
 // SetupHeartbeat sets up the heartbeat for a subscription
@@ -66,10 +179,14 @@ func SetupHeartbeat(ctx context.Context) (*ethrpc.Subscription, error) {
 	}
 
 	rpcSub := notifier.CreateSubscription()
+	tracked := TrackedSubscriptionFromContext(ctx)
 
 	go func() {
+		defer supervisor.Recover("rpc.heartbeatSubscription")
 		for {
 			select {
+			case <-tracked.Terminated():
+				return
 			case err := <-rpcSub.Err():
 				if err != nil {
 					log.WithField("err", err).Error("rpcSub returned an error")
@@ -87,6 +204,7 @@ func SetupHeartbeat(ctx context.Context) (*ethrpc.Subscription, error) {
 
 			err := notifier.Notify(rpcSub.ID, "tick")
 			if err != nil {
+				tracked.RecordDropped()
 				// TODO(fabio): The current implementation of `notifier.Notify` returns a
 				// `write: broken pipe` error when it is called _after_ the client has
 				// disconnected but before the corresponding error is received on the
@@ -128,25 +246,71 @@ var defaultAddOrdersOpts = types.AddOrdersOpts{
 }
 
 // AddOrders calls rpcHandler.AddOrders and returns the validation results.
-func (s *rpcService) AddOrders(signedOrdersRaw []*json.RawMessage, opts *types.AddOrdersOpts) (*ordervalidator.ValidationResults, error) {
-	if opts == nil {
-		opts = &defaultAddOrdersOpts
+// If the client did not supply a request ID, a random one is generated.
+func (s *rpcService) AddOrders(ctx context.Context, signedOrdersRaw []*json.RawMessage, opts *types.AddOrdersOpts) (result *ordervalidator.ValidationResults, err error) {
+	defer func() { err = toAPIError(err) }()
+	addOrdersOpts := defaultAddOrdersOpts
+	if opts != nil {
+		addOrdersOpts = *opts
+	}
+	if len(addOrdersOpts.RequestID) > requestid.MaxLength {
+		return nil, invalidParams(fmt.Errorf("requestID cannot be longer than %d characters", requestid.MaxLength))
+	}
+	if addOrdersOpts.RequestID == "" {
+		addOrdersOpts.RequestID = requestid.New()
 	}
-	return s.rpcHandler.AddOrders(signedOrdersRaw, *opts)
+	var results *ordervalidator.ValidationResults
+	err = s.callWithTimeout(ctx, "mesh_addOrders", func(ctx context.Context) error {
+		var err error
+		results, err = s.rpcHandler.AddOrders(ctx, signedOrdersRaw, addOrdersOpts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
 }
 
 // GetOrders calls rpcHandler.GetOrders and returns the validation results.
-func (s *rpcService) GetOrders(page, perPage int, snapshotID string) (*types.GetOrdersResponse, error) {
-	return s.rpcHandler.GetOrders(page, perPage, snapshotID)
+// Responses are cached by the response cache.
+func (s *rpcService) GetOrders(ctx context.Context, page, perPage int, snapshotID string) (result *types.GetOrdersResponse, err error) {
+	defer func() { err = toAPIError(err) }()
+	response, err := s.responseCache.getOrCall("mesh_getOrders", []interface{}{page, perPage, snapshotID}, func() (interface{}, error) {
+		var response *types.GetOrdersResponse
+		err := s.callWithTimeout(ctx, "mesh_getOrders", func(ctx context.Context) error {
+			var err error
+			response, err = s.rpcHandler.GetOrders(ctx, page, perPage, snapshotID)
+			return err
+		})
+		return response, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.(*types.GetOrdersResponse), nil
+}
+
+// GetOrder parses the given order hash and calls rpcHandler.GetOrder.
+func (s *rpcService) GetOrder(orderHash string) (result *types.OrderInfo, err error) {
+	defer func() { err = toAPIError(err) }()
+	orderHashBytes, err := hexutil.Decode(orderHash)
+	if err != nil {
+		return nil, invalidParams(err)
+	}
+	if len(orderHashBytes) != common.HashLength {
+		return nil, invalidParams(fmt.Errorf("orderHash must be %d bytes long", common.HashLength))
+	}
+	return s.rpcHandler.GetOrder(common.BytesToHash(orderHashBytes))
 }
 
 // AddPeer builds PeerInfo out of the given peer ID and multiaddresses and
 // calls rpcHandler.AddPeer. If there is an error, it returns it.
-func (s *rpcService) AddPeer(peerID string, multiaddrs []string) error {
+func (s *rpcService) AddPeer(peerID string, multiaddrs []string) (err error) {
+	defer func() { err = toAPIError(err) }()
 	// Parse peer ID.
 	parsedPeerID, err := peer.IDB58Decode(peerID)
 	if err != nil {
-		return err
+		return invalidParams(err)
 	}
 	peerInfo := peerstore.PeerInfo{
 		ID: parsedPeerID,
@@ -157,7 +321,7 @@ func (s *rpcService) AddPeer(peerID string, multiaddrs []string) error {
 	for i, addr := range multiaddrs {
 		parsed, err := ma.NewMultiaddr(addr)
 		if err != nil {
-			return err
+			return invalidParams(err)
 		}
 		parsedMultiaddrs[i] = parsed
 	}
@@ -167,6 +331,98 @@ func (s *rpcService) AddPeer(peerID string, multiaddrs []string) error {
 }
 
 // GetStats calls rpcHandler.GetStats. If there is an error, it returns it.
-func (s *rpcService) GetStats() (*types.Stats, error) {
-	return s.rpcHandler.GetStats()
+// Responses are cached by the response cache.
+func (s *rpcService) GetStats() (result *types.Stats, err error) {
+	defer func() { err = toAPIError(err) }()
+	stats, err := s.responseCache.getOrCall("mesh_getStats", nil, func() (interface{}, error) {
+		stats, err := s.rpcHandler.GetStats()
+		return stats, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats.(*types.Stats), nil
+}
+
+// GetStatsAndSnapshot calls rpcHandler.GetStatsAndSnapshot.
+func (s *rpcService) GetStatsAndSnapshot(ctx context.Context) (result *types.StatsAndSnapshot, err error) {
+	defer func() { err = toAPIError(err) }()
+	var response *types.StatsAndSnapshot
+	err = s.callWithTimeout(ctx, "mesh_getStatsAndSnapshot", func(ctx context.Context) error {
+		var err error
+		response, err = s.rpcHandler.GetStatsAndSnapshot(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// GetPeerBans calls rpcHandler.GetPeerBans. If there is an error, it returns it.
+func (s *rpcService) GetPeerBans() (result []*types.PeerBan, err error) {
+	defer func() { err = toAPIError(err) }()
+	return s.rpcHandler.GetPeerBans()
+}
+
+// GetIPRangeBans calls rpcHandler.GetIPRangeBans. If there is an error, it
+// returns it.
+func (s *rpcService) GetIPRangeBans() (result []*types.IPRangeBan, err error) {
+	defer func() { err = toAPIError(err) }()
+	return s.rpcHandler.GetIPRangeBans()
+}
+
+// GetAllowlist calls rpcHandler.GetAllowlist. If there is an error, it returns
+// it.
+func (s *rpcService) GetAllowlist() (result []string, err error) {
+	defer func() { err = toAPIError(err) }()
+	return s.rpcHandler.GetAllowlist()
+}
+
+// GetPeers calls rpcHandler.GetPeers. If there is an error, it returns it.
+func (s *rpcService) GetPeers() (result []*types.PeerInfo, err error) {
+	defer func() { err = toAPIError(err) }()
+	return s.rpcHandler.GetPeers()
+}
+
+// TestOrderAgainstFilter calls rpcHandler.TestOrderAgainstFilter. If there is
+// an error, it returns it.
+func (s *rpcService) TestOrderAgainstFilter(signedOrderRaw json.RawMessage) (result *types.FilterTestResult, err error) {
+	defer func() { err = toAPIError(err) }()
+	if len(signedOrderRaw) == 0 {
+		return nil, invalidParams(errors.New("signedOrder cannot be empty"))
+	}
+	return s.rpcHandler.TestOrderAgainstFilter(signedOrderRaw)
+}
+
+// GetOrderStateAtBlock parses the given order hash and calls
+// rpcHandler.GetOrderStateAtBlock.
+func (s *rpcService) GetOrderStateAtBlock(orderHash string, blockNumber int64) (result *types.OrderState, err error) {
+	defer func() { err = toAPIError(err) }()
+	orderHashBytes, err := hexutil.Decode(orderHash)
+	if err != nil {
+		return nil, invalidParams(err)
+	}
+	if len(orderHashBytes) != common.HashLength {
+		return nil, invalidParams(fmt.Errorf("orderHash must be %d bytes long", common.HashLength))
+	}
+	if blockNumber < 0 {
+		return nil, invalidParams(errors.New("blockNumber cannot be negative"))
+	}
+	return s.rpcHandler.GetOrderStateAtBlock(common.BytesToHash(orderHashBytes), big.NewInt(blockNumber))
+}
+
+// GetOrdersFillableAtBlock calls rpcHandler.GetOrdersFillableAtBlock.
+func (s *rpcService) GetOrdersFillableAtBlock(blockNumber int64) (result []*types.OrderState, err error) {
+	defer func() { err = toAPIError(err) }()
+	if blockNumber < 0 {
+		return nil, invalidParams(errors.New("blockNumber cannot be negative"))
+	}
+	return s.rpcHandler.GetOrdersFillableAtBlock(big.NewInt(blockNumber))
+}
+
+// GetOrderEventsSince calls rpcHandler.GetOrderEventsSince.
+func (s *rpcService) GetOrderEventsSince(sequenceNumber uint64, limit int) (result *types.GetOrderEventsSinceResponse, err error) {
+	defer func() { err = toAPIError(err) }()
+	return s.rpcHandler.GetOrderEventsSince(sequenceNumber, limit)
 }
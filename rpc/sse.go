@@ -0,0 +1,177 @@
+// +build !js
+
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/0xProject/0x-mesh/zeroex"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// OrderEventsSSEPath is the path of the Server-Sent Events endpoint for
+	// order events on the HTTP server.
+	OrderEventsSSEPath = "/events/orders"
+	// lastEventIDQueryParam is the query parameter which can be used instead of
+	// the Last-Event-ID header to resume from a sequence number when first
+	// connecting (browsers only send the header when they reconnect).
+	lastEventIDQueryParam = "lastEventId"
+	// sseKeepAliveInterval is the interval at which comments are sent to keep
+	// idle connections from being closed by proxies.
+	sseKeepAliveInterval = 15 * time.Second
+	// sseOrderEventsBufferSize is the buffer size for the order events of each
+	// SSE connection.
+	sseOrderEventsBufferSize = 8000
+	// sseReplayPageSize is the number of missed order events which are fetched
+	// at once when a client resumes.
+	sseReplayPageSize = 1000
+)
+
+// orderEventsSSEStream writes order events to an SSE connection. The ID of
+// each SSE event is the sequence number of the order event.
+type orderEventsSSEStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	// lastSequenceNumber is the sequence number of the last order event which
+	// was written. Order events with a sequence number that is not greater are
+	// skipped so that events are not sent twice while resuming.
+	lastSequenceNumber uint64
+}
+
+// newOrderEventsSSEHandler returns a handler which streams order events as
+// Server-Sent Events. If the client sends a Last-Event-ID header (or the
+// lastEventId query parameter), the events it missed are sent first. If they
+// are no longer available, a "resync" event is sent instead and the client
+// needs to re-sync all orders with GetOrders.
+func newOrderEventsSSEHandler(rpcHandler RPCHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeHTTPError(w, NewAPIError(ErrorCodeInternal, errors.New("streaming is not supported")))
+			return
+		}
+		lastEventID := r.Header.Get("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = r.URL.Query().Get(lastEventIDQueryParam)
+		}
+		var lastSequenceNumber uint64
+		if lastEventID != "" {
+			var err error
+			lastSequenceNumber, err = strconv.ParseUint(lastEventID, 10, 64)
+			if err != nil {
+				writeHTTPError(w, NewAPIError(ErrorCodeInvalidParams, errors.New("last event ID must be an order event sequence number")))
+				return
+			}
+		}
+
+		// Subscribe before replaying the missed events so that no events are
+		// lost in between.
+		orderEventsChan := make(chan []*zeroex.OrderEvent, sseOrderEventsBufferSize)
+		subscription := rpcHandler.SubscribeToOrderEvents(orderEventsChan)
+		defer subscription.Unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		// Disables response buffering in nginx.
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		stream := &orderEventsSSEStream{
+			w:       w,
+			flusher: flusher,
+		}
+		if lastEventID != "" {
+			if err := stream.replay(rpcHandler, lastSequenceNumber); err != nil {
+				log.WithError(err).Trace("could not write to order events SSE connection")
+				return
+			}
+		} else {
+			flusher.Flush()
+		}
+
+		ticker := time.NewTicker(sseKeepAliveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case err := <-subscription.Err():
+				if err != nil {
+					log.WithError(err).Error("order events subscription for SSE connection returned an error")
+				}
+				return
+			case orderEvents := <-orderEventsChan:
+				if err := stream.writeOrderEvents(orderEvents); err != nil {
+					log.WithError(err).Trace("could not write to order events SSE connection")
+					return
+				}
+			case <-ticker.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	})
+}
+
+// replay writes the order events with a sequence number greater than the given
+// one.
+func (s *orderEventsSSEStream) replay(rpcHandler RPCHandler, sequenceNumber uint64) error {
+	s.lastSequenceNumber = sequenceNumber
+	for {
+		res, err := rpcHandler.GetOrderEventsSince(s.lastSequenceNumber, sseReplayPageSize)
+		if err != nil {
+			// The missed events cannot be replayed. Events which were emitted
+			// since the client connected are still sent, but the client first
+			// needs to re-sync all orders.
+			s.lastSequenceNumber = 0
+			return s.writeResync(err)
+		}
+		if err := s.writeOrderEvents(res.OrderEvents); err != nil {
+			return err
+		}
+		if len(res.OrderEvents) == 0 || s.lastSequenceNumber >= res.LatestSequenceNumber {
+			return nil
+		}
+	}
+}
+
+// writeOrderEvents writes an SSE event for each of the given order events which
+// was not written yet.
+func (s *orderEventsSSEStream) writeOrderEvents(orderEvents []*zeroex.OrderEvent) error {
+	for _, orderEvent := range orderEvents {
+		if orderEvent.SequenceNumber <= s.lastSequenceNumber {
+			continue
+		}
+		encoded, err := json.Marshal(orderEvent)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(s.w, "id: %d\ndata: %s\n\n", orderEvent.SequenceNumber, encoded); err != nil {
+			return err
+		}
+		s.lastSequenceNumber = orderEvent.SequenceNumber
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// writeResync writes a "resync" event which tells the client that it missed
+// order events which cannot be replayed.
+func (s *orderEventsSSEStream) writeResync(reason error) error {
+	encoded, err := json.Marshal(map[string]string{"error": reason.Error()})
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "event: resync\ndata: %s\n\n", encoded); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
@@ -0,0 +1,259 @@
+// +build !js
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/0xProject/0x-mesh/common/types"
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+)
+
+// Subscription types as reported by GetSubscriptions. They match the topics
+// of mesh_subscribe.
+const (
+	SubscriptionTypeOrders         = "orders"
+	SubscriptionTypeOrdersSnapshot = "ordersSnapshot"
+	SubscriptionTypeHeartbeat      = "heartbeat"
+)
+
+// ErrTooManySubscriptions is returned when a client attempts to create more
+// subscriptions on a single connection than the SubscriptionRegistry allows.
+type ErrTooManySubscriptions struct {
+	Limit int
+}
+
+func (e ErrTooManySubscriptions) Error() string {
+	return fmt.Sprintf("too many subscriptions on this connection (the limit is %d)", e.Limit)
+}
+
+// ErrSubscriptionNotFound is returned by TerminateSubscription if there is no
+// active subscription with the given ID.
+type ErrSubscriptionNotFound struct {
+	ID string
+}
+
+func (e ErrSubscriptionNotFound) Error() string {
+	return fmt.Sprintf("subscription not found: %s", e.ID)
+}
+
+// SubscriptionRegistry keeps track of the active subscriptions of one or more
+// Servers and limits the number of subscriptions per connection, so that
+// operators of shared nodes can find and terminate the subscriptions of noisy
+// clients. The same registry should be used by all Servers of a node so that
+// all subscriptions can be managed via any of them.
+type SubscriptionRegistry struct {
+	mut              sync.Mutex
+	maxPerConnection int
+	nextConnectionID int
+	// connections is keyed by the channel which is closed when the connection
+	// is closed, since go-ethereum doesn't expose the connection itself.
+	connections   map[interface{}]*subscribedConnection
+	subscriptions map[ethrpc.ID]*TrackedSubscription
+}
+
+type subscribedConnection struct {
+	id               int
+	numSubscriptions int
+}
+
+// NewSubscriptionRegistry creates a SubscriptionRegistry which allows up to
+// maxPerConnection subscriptions per connection. If maxPerConnection is 0,
+// the number of subscriptions is not limited.
+func NewSubscriptionRegistry(maxPerConnection int) *SubscriptionRegistry {
+	return &SubscriptionRegistry{
+		maxPerConnection: maxPerConnection,
+		connections:      map[interface{}]*subscribedConnection{},
+		subscriptions:    map[ethrpc.ID]*TrackedSubscription{},
+	}
+}
+
+// TrackedSubscription holds the statistics of a subscription which is tracked
+// by a SubscriptionRegistry. RPCHandlers can get it with
+// TrackedSubscriptionFromContext in order to report the backlog and dropped
+// notifications of the subscription and to find out when it is terminated.
+type TrackedSubscription struct {
+	// backlog and dropped are accessed atomically.
+	backlog          int64
+	dropped          int64
+	id               ethrpc.ID
+	connectionID     int
+	subscriptionType string
+	filter           *types.OrdersSubscriptionOpts
+	createdAt        time.Time
+	terminateOnce    sync.Once
+	terminated       chan struct{}
+}
+
+func newTrackedSubscription(subscriptionType string, filter *types.OrdersSubscriptionOpts) *TrackedSubscription {
+	return &TrackedSubscription{
+		subscriptionType: subscriptionType,
+		filter:           filter,
+		createdAt:        time.Now().UTC(),
+		terminated:       make(chan struct{}),
+	}
+}
+
+// SetBacklog sets the number of notifications which are waiting to be sent.
+func (s *TrackedSubscription) SetBacklog(backlog int) {
+	atomic.StoreInt64(&s.backlog, int64(backlog))
+}
+
+// RecordDropped records a notification which could not be sent.
+func (s *TrackedSubscription) RecordDropped() {
+	atomic.AddInt64(&s.dropped, 1)
+}
+
+// Terminated returns a channel which is closed when the subscription is
+// terminated by the node operator. The subscription should stop sending
+// notifications at that point.
+func (s *TrackedSubscription) Terminated() <-chan struct{} {
+	return s.terminated
+}
+
+func (s *TrackedSubscription) terminate() {
+	s.terminateOnce.Do(func() {
+		close(s.terminated)
+	})
+}
+
+func (s *TrackedSubscription) info() *types.SubscriptionInfo {
+	return &types.SubscriptionInfo{
+		ID:           string(s.id),
+		ConnectionID: s.connectionID,
+		Type:         s.subscriptionType,
+		Filter:       s.filter,
+		Backlog:      int(atomic.LoadInt64(&s.backlog)),
+		Dropped:      int(atomic.LoadInt64(&s.dropped)),
+		CreatedAt:    s.createdAt,
+	}
+}
+
+type trackedSubscriptionContextKey struct{}
+
+// TrackedSubscriptionFromContext returns the TrackedSubscription for the
+// subscription which is being created with ctx. If ctx doesn't carry one
+// (e.g. because the subscription was not created by a Server), it returns a
+// TrackedSubscription which is not tracked by any registry, so callers don't
+// need to check for nil.
+func TrackedSubscriptionFromContext(ctx context.Context) *TrackedSubscription {
+	if tracked, ok := ctx.Value(trackedSubscriptionContextKey{}).(*TrackedSubscription); ok {
+		return tracked
+	}
+	return newTrackedSubscription("", nil)
+}
+
+// subscribe creates a subscription with the given create function unless the
+// connection of ctx already has the maximum number of subscriptions. The
+// subscription is tracked until the client unsubscribes, the connection is
+// closed or it is terminated with TerminateSubscription.
+func (r *SubscriptionRegistry) subscribe(ctx context.Context, subscriptionType string, filter *types.OrdersSubscriptionOpts, create func(ctx context.Context) (*ethrpc.Subscription, error)) (*ethrpc.Subscription, error) {
+	notifier, supported := ethrpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, ErrSubscriptionsRequireWebSocket
+	}
+	connection := notifier.Closed()
+
+	// Reserve a slot for the subscription before creating it so that
+	// concurrent requests cannot exceed the limit.
+	r.mut.Lock()
+	conn, found := r.connections[connection]
+	if !found {
+		r.nextConnectionID++
+		conn = &subscribedConnection{id: r.nextConnectionID}
+		r.connections[connection] = conn
+	}
+	if r.maxPerConnection > 0 && conn.numSubscriptions >= r.maxPerConnection {
+		r.mut.Unlock()
+		log.WithFields(log.Fields{
+			"connectionID":     conn.id,
+			"subscriptionType": subscriptionType,
+			"limit":            r.maxPerConnection,
+		}).Debug("rejected subscription because the connection has too many subscriptions")
+		return nil, ErrTooManySubscriptions{Limit: r.maxPerConnection}
+	}
+	conn.numSubscriptions++
+	r.mut.Unlock()
+
+	tracked := newTrackedSubscription(subscriptionType, filter)
+	tracked.connectionID = conn.id
+	rpcSub, err := create(context.WithValue(ctx, trackedSubscriptionContextKey{}, tracked))
+	if err != nil {
+		r.release(connection)
+		return nil, err
+	}
+	tracked.id = rpcSub.ID
+	r.mut.Lock()
+	r.subscriptions[rpcSub.ID] = tracked
+	r.mut.Unlock()
+
+	go func() {
+		select {
+		case <-rpcSub.Err():
+		case <-notifier.Closed():
+		case <-tracked.Terminated():
+		}
+		r.mut.Lock()
+		delete(r.subscriptions, rpcSub.ID)
+		r.mut.Unlock()
+		r.release(connection)
+	}()
+	return rpcSub, nil
+}
+
+// release frees the slot of a subscription on the given connection.
+func (r *SubscriptionRegistry) release(connection interface{}) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	conn, found := r.connections[connection]
+	if !found {
+		return
+	}
+	conn.numSubscriptions--
+	if conn.numSubscriptions <= 0 {
+		delete(r.connections, connection)
+	}
+}
+
+// GetSubscriptions returns all active subscriptions sorted by connection and
+// creation time.
+func (r *SubscriptionRegistry) GetSubscriptions() []*types.SubscriptionInfo {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	subscriptions := make([]*types.SubscriptionInfo, 0, len(r.subscriptions))
+	for _, tracked := range r.subscriptions {
+		subscriptions = append(subscriptions, tracked.info())
+	}
+	sort.Slice(subscriptions, func(i, j int) bool {
+		if subscriptions[i].ConnectionID != subscriptions[j].ConnectionID {
+			return subscriptions[i].ConnectionID < subscriptions[j].ConnectionID
+		}
+		return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt)
+	})
+	return subscriptions
+}
+
+// TerminateSubscription stops sending notifications for the subscription with
+// the given ID. go-ethereum doesn't allow servers to end subscriptions, so the
+// client is not notified; it simply stops receiving notifications.
+func (r *SubscriptionRegistry) TerminateSubscription(id string) error {
+	r.mut.Lock()
+	tracked, found := r.subscriptions[ethrpc.ID(id)]
+	r.mut.Unlock()
+	if !found {
+		return ErrSubscriptionNotFound{ID: id}
+	}
+	log.WithFields(log.Fields{
+		"subscriptionID":   id,
+		"connectionID":     tracked.connectionID,
+		"subscriptionType": tracked.subscriptionType,
+	}).Info("terminating subscription")
+	tracked.terminate()
+	return nil
+}
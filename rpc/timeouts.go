@@ -0,0 +1,93 @@
+// +build !js
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Timeouts determine how long the Server waits for a method to return before
+// it gives up and returns ErrMethodTimeout. The context passed to the
+// RPCHandler is canceled at the same time, so that any database queries or
+// Ethereum RPC requests made on behalf of the method are stopped as well.
+// Timeouts only apply to methods which accept a context (i.e. AddOrders,
+// GetOrders, GetStatsAndSnapshot and RevalidateOrders). Subscriptions never time out.
+type Timeouts struct {
+	// Default is the timeout for methods without a timeout in Methods. If it
+	// is 0, these methods don't time out.
+	Default time.Duration
+	// Methods are the timeouts for specific methods, keyed by the name of the
+	// method (e.g. "mesh_getOrders"). A timeout of 0 means the method doesn't
+	// time out.
+	Methods map[string]time.Duration
+}
+
+// ErrMethodTimeout is returned if a method didn't return within its timeout.
+type ErrMethodTimeout struct {
+	Method  string
+	Timeout time.Duration
+}
+
+func (e ErrMethodTimeout) Error() string {
+	return fmt.Sprintf("%s timed out after %s", e.Method, e.Timeout)
+}
+
+// ParseMethodTimeouts parses a comma-separated list of method timeouts (e.g.
+// "mesh_getOrders=30s,mesh_addOrders=2m") into a map which can be used for
+// Timeouts.Methods. The durations use the format of time.ParseDuration.
+func ParseMethodTimeouts(methodTimeouts string) (map[string]time.Duration, error) {
+	timeouts := map[string]time.Duration{}
+	for _, methodTimeout := range strings.Split(methodTimeouts, ",") {
+		methodTimeout = strings.TrimSpace(methodTimeout)
+		if methodTimeout == "" {
+			continue
+		}
+		parts := strings.SplitN(methodTimeout, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid method timeout %q: expected <method>=<duration>", methodTimeout)
+		}
+		method := strings.TrimSpace(parts[0])
+		if !strings.HasPrefix(method, "mesh_") {
+			return nil, fmt.Errorf("invalid method timeout %q: method must start with \"mesh_\"", methodTimeout)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid method timeout %q: %s", methodTimeout, err)
+		}
+		if timeout < 0 {
+			return nil, fmt.Errorf("invalid method timeout %q: duration cannot be negative", methodTimeout)
+		}
+		timeouts[method] = timeout
+	}
+	return timeouts, nil
+}
+
+// forMethod returns the timeout for the given method or 0 if it doesn't time
+// out.
+func (t Timeouts) forMethod(method string) time.Duration {
+	if timeout, found := t.Methods[method]; found {
+		return timeout
+	}
+	return t.Default
+}
+
+// callWithTimeout calls the given function with a context that is canceled
+// once the timeout for the given method expires or ctx is done (e.g. because
+// the client disconnected). If the timeout expired, it returns
+// ErrMethodTimeout instead of whatever the function returned.
+func (s *rpcService) callWithTimeout(ctx context.Context, method string, call func(ctx context.Context) error) error {
+	timeout := s.timeouts.forMethod(method)
+	if timeout == 0 {
+		return call(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := call(ctx)
+	if ctx.Err() == context.DeadlineExceeded {
+		return ErrMethodTimeout{Method: method, Timeout: timeout}
+	}
+	return err
+}
@@ -85,6 +85,20 @@ func MakerFee(amount *big.Int) Option {
 	}
 }
 
+func TakerFeeAssetData(assetData []byte) Option {
+	return func(cfg *Config) error {
+		cfg.Order.TakerFeeAssetData = assetData
+		return nil
+	}
+}
+
+func TakerFee(amount *big.Int) Option {
+	return func(cfg *Config) error {
+		cfg.Order.TakerFee = amount
+		return nil
+	}
+}
+
 func SenderAddress(address common.Address) Option {
 	return func(cfg *Config) error {
 		cfg.Order.SenderAddress = address
@@ -0,0 +1,121 @@
+package zeroex
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Mesh uses two representations for addresses. Internally (e.g. in the JSON
+// encoding of orders, which is what order filters are matched against and what
+// is sent to peers), addresses are always lowercase. Everywhere else they are
+// meant to be read by humans, addresses are checksummed as described in
+// EIP-55, which is what common.Address.Hex returns. The helpers below should be
+// used instead of formatting or parsing addresses by hand so that the same
+// address never ends up with two different representations.
+
+// addressFields are the fields of a JSON encoded order that hold addresses.
+var addressFields = []string{
+	"exchangeAddress",
+	"makerAddress",
+	"takerAddress",
+	"senderAddress",
+	"feeRecipientAddress",
+}
+
+// hexFields are the fields of a JSON encoded order that hold arbitrary hex
+// encoded bytes.
+var hexFields = []string{
+	"makerAssetData",
+	"takerAssetData",
+	"makerFeeAssetData",
+	"takerFeeAssetData",
+	"signature",
+}
+
+// InvalidAddressError is returned by ParseAddress if the given string is not a
+// valid address.
+type InvalidAddressError struct {
+	Address string
+	Reason  string
+}
+
+func (e InvalidAddressError) Error() string {
+	return fmt.Sprintf("invalid address %q: %s", e.Address, e.Reason)
+}
+
+// ParseAddress parses a hex encoded address regardless of its casing. Like
+// most wallets, it treats mixed-case addresses as checksummed and returns an
+// error if the checksum doesn't match, since that usually indicates a typo.
+func ParseAddress(rawAddress string) (common.Address, error) {
+	if !common.IsHexAddress(rawAddress) {
+		return common.Address{}, InvalidAddressError{Address: rawAddress, Reason: "not a 20 byte hex string"}
+	}
+	address := common.HexToAddress(rawAddress)
+	hexPart := strings.TrimPrefix(strings.TrimPrefix(rawAddress, "0x"), "0X")
+	isMixedCase := hexPart != strings.ToLower(hexPart) && hexPart != strings.ToUpper(hexPart)
+	if isMixedCase && "0x"+hexPart != address.Hex() {
+		return common.Address{}, InvalidAddressError{Address: rawAddress, Reason: "checksum does not match"}
+	}
+	return address, nil
+}
+
+// NormalizedAddressHex returns the canonical internal representation of the
+// address, i.e. lowercase hex with a 0x prefix.
+func NormalizedAddressHex(address common.Address) string {
+	return strings.ToLower(address.Hex())
+}
+
+// ChecksummedSignedOrder wraps a SignedOrder so that the addresses in its JSON
+// encoding are checksummed. It is used for the orders in API responses. The
+// encoding of orders that are sent to peers or matched against order filters
+// (i.e. SignedOrder.MarshalJSON) stays lowercase.
+type ChecksummedSignedOrder struct {
+	*SignedOrder
+}
+
+// MarshalJSON implements a custom JSON marshaller for the
+// ChecksummedSignedOrder type.
+func (s ChecksummedSignedOrder) MarshalJSON() ([]byte, error) {
+	if s.SignedOrder == nil {
+		return []byte("null"), nil
+	}
+	signedOrderJSON := s.toSignedOrderJSON()
+	signedOrderJSON.ExchangeAddress = s.ExchangeAddress.Hex()
+	signedOrderJSON.MakerAddress = s.MakerAddress.Hex()
+	signedOrderJSON.TakerAddress = s.TakerAddress.Hex()
+	signedOrderJSON.SenderAddress = s.SenderAddress.Hex()
+	signedOrderJSON.FeeRecipientAddress = s.FeeRecipientAddress.Hex()
+	return json.Marshal(signedOrderJSON)
+}
+
+// NormalizeSignedOrderJSON returns a copy of the given JSON encoded signed
+// order in which all addresses and hex encoded bytes are lowercase. Orders sent
+// by clients may use any casing, but order filters and everything else that
+// looks at the JSON encoding of an order expect lowercase. Fields which are
+// missing or have the wrong type are left as is so that schema validation can
+// still report them. It returns an error if the input is not a JSON object.
+func NormalizeSignedOrderJSON(data []byte) ([]byte, error) {
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// Use json.Number so that large numbers (e.g. the salt) don't lose
+	// precision.
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+	for _, field := range addressFields {
+		if value, ok := fields[field].(string); ok {
+			fields[field] = strings.ToLower(value)
+		}
+	}
+	for _, field := range hexFields {
+		if value, ok := fields[field].(string); ok {
+			fields[field] = strings.ToLower(value)
+		}
+	}
+	return json.Marshal(fields)
+}
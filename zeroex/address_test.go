@@ -0,0 +1,141 @@
+package zeroex
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAddress(t *testing.T) {
+	expected := common.HexToAddress("0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2")
+	validAddresses := []string{
+		"0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2",
+		"0xC02AAA39B223FE8D0A0E5C4F27EAD9083C756CC2",
+		"0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2",
+		"c02aaa39b223fe8d0a0e5c4f27ead9083c756cc2",
+	}
+	for _, rawAddress := range validAddresses {
+		address, err := ParseAddress(rawAddress)
+		require.NoError(t, err, rawAddress)
+		assert.Equal(t, expected, address, rawAddress)
+	}
+
+	invalidAddresses := []string{
+		"",
+		"0x1234",
+		"0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cz2",
+		// Mixed case with an invalid checksum.
+		"0xc02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2",
+	}
+	for _, rawAddress := range invalidAddresses {
+		_, err := ParseAddress(rawAddress)
+		assert.IsType(t, InvalidAddressError{}, err, rawAddress)
+	}
+}
+
+func TestNormalizedAddressHex(t *testing.T) {
+	address := common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	assert.Equal(t, "0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2", NormalizedAddressHex(address))
+}
+
+func TestNormalizeSignedOrderJSON(t *testing.T) {
+	signedOrder := &SignedOrder{
+		Order: Order{
+			ChainID:               big.NewInt(constants.TestChainID),
+			MakerAddress:          common.HexToAddress("0x6ecbe1db9ef729cbe972c83fb886247691fb6beb"),
+			MakerAssetData:        common.FromHex("0xf47261b0000000000000000000000000c02aaa39b223fe8d0a0e5c4f27ead9083c756cc2"),
+			MakerFeeAssetData:     constants.NullBytes,
+			TakerAssetData:        common.FromHex("0xf47261b0000000000000000000000000e41d2489571d322189246dafa5ebde1f4699f498"),
+			TakerFeeAssetData:     constants.NullBytes,
+			Salt:                  big.NewInt(1548619145450),
+			MakerFee:              big.NewInt(0),
+			TakerFee:              big.NewInt(0),
+			MakerAssetAmount:      big.NewInt(1000),
+			TakerAssetAmount:      big.NewInt(2000),
+			ExpirationTimeSeconds: big.NewInt(1548619325),
+			ExchangeAddress:       contractAddresses.Exchange,
+		},
+		Signature: common.FromHex("0x1cABCDEF03"),
+	}
+	expectedJSON, err := signedOrder.MarshalJSON()
+	require.NoError(t, err)
+
+	mixedCaseJSON := []byte(`{
+		"chainId": 1337,
+		"exchangeAddress": "` + contractAddresses.Exchange.Hex() + `",
+		"makerAddress": "0x6ecBe1dB9EF729CBe972C83Fb886247691Fb6beb",
+		"makerAssetData": "0xF47261B0000000000000000000000000C02AAA39B223FE8D0A0E5C4F27EAD9083C756CC2",
+		"makerFeeAssetData": "0x",
+		"makerAssetAmount": "1000",
+		"makerFee": "0",
+		"takerAddress": "0x0000000000000000000000000000000000000000",
+		"takerAssetData": "0xf47261b0000000000000000000000000E41D2489571D322189246DAFA5EBDE1F4699F498",
+		"takerFeeAssetData": "0x",
+		"takerAssetAmount": "2000",
+		"takerFee": "0",
+		"senderAddress": "0x0000000000000000000000000000000000000000",
+		"feeRecipientAddress": "0x0000000000000000000000000000000000000000",
+		"expirationTimeSeconds": "1548619325",
+		"salt": "1548619145450",
+		"signature": "0x1cABCDEF03"
+	}`)
+	normalizedJSON, err := NormalizeSignedOrderJSON(mixedCaseJSON)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expectedJSON), string(normalizedJSON))
+
+	// Large numbers must not lose precision and fields with the wrong type are
+	// left as is.
+	normalizedJSON, err = NormalizeSignedOrderJSON([]byte(`{"salt":115792089237316195423570985008687907853269984665640564039457584007913129639935,"makerAddress":42}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"salt":115792089237316195423570985008687907853269984665640564039457584007913129639935,"makerAddress":42}`, string(normalizedJSON))
+
+	_, err = NormalizeSignedOrderJSON([]byte(`[]`))
+	assert.Error(t, err)
+}
+
+func TestChecksummedSignedOrder(t *testing.T) {
+	signedOrder := &SignedOrder{
+		Order: Order{
+			ChainID:               big.NewInt(constants.TestChainID),
+			MakerAddress:          common.HexToAddress("0x6ecbe1db9ef729cbe972c83fb886247691fb6beb"),
+			MakerAssetData:        common.FromHex("0xf47261b0000000000000000000000000c02aaa39b223fe8d0a0e5c4f27ead9083c756cc2"),
+			MakerFeeAssetData:     constants.NullBytes,
+			TakerAssetData:        common.FromHex("0xf47261b0000000000000000000000000e41d2489571d322189246dafa5ebde1f4699f498"),
+			TakerFeeAssetData:     constants.NullBytes,
+			Salt:                  big.NewInt(1548619145450),
+			MakerFee:              big.NewInt(0),
+			TakerFee:              big.NewInt(0),
+			MakerAssetAmount:      big.NewInt(1000),
+			TakerAssetAmount:      big.NewInt(2000),
+			ExpirationTimeSeconds: big.NewInt(1548619325),
+			ExchangeAddress:       contractAddresses.Exchange,
+		},
+		Signature: common.FromHex("0x1cabcdef03"),
+	}
+	checksummedJSON, err := json.Marshal(ChecksummedSignedOrder{signedOrder})
+	require.NoError(t, err)
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(checksummedJSON, &fields))
+	assert.Equal(t, "0x6Ecbe1DB9EF729CBe972C83Fb886247691Fb6beb", fields["makerAddress"])
+	assert.Equal(t, contractAddresses.Exchange.Hex(), fields["exchangeAddress"])
+	// Only addresses are checksummed.
+	assert.Equal(t, "0xf47261b0000000000000000000000000c02aaa39b223fe8d0a0e5c4f27ead9083c756cc2", fields["makerAssetData"])
+
+	// The checksummed encoding decodes to the same order.
+	var decodedSignedOrder SignedOrder
+	require.NoError(t, json.Unmarshal(checksummedJSON, &decodedSignedOrder))
+	expectedHash, err := signedOrder.ComputeOrderHash()
+	require.NoError(t, err)
+	actualHash, err := decodedSignedOrder.ComputeOrderHash()
+	require.NoError(t, err)
+	assert.Equal(t, expectedHash, actualHash)
+
+	nullJSON, err := json.Marshal(ChecksummedSignedOrder{})
+	require.NoError(t, err)
+	assert.Equal(t, "null", string(nullJSON))
+}
@@ -2,7 +2,6 @@ package zeroex
 
 import (
 	"errors"
-	"fmt"
 	"math/big"
 	"strings"
 
@@ -171,7 +170,8 @@ func NewAssetDataDecoder() *AssetDataDecoder {
 	return decoder
 }
 
-// GetName returns the name of the assetData type
+// GetName returns the name of the assetData type. This includes the asset
+// proxies registered with RegisterAssetProxy.
 func (a *AssetDataDecoder) GetName(assetData []byte) (string, error) {
 	if len(assetData) < 4 {
 		return "", errors.New("assetData must be at least 4 bytes long")
@@ -180,7 +180,10 @@ func (a *AssetDataDecoder) GetName(assetData []byte) (string, error) {
 	idHex := common.Bytes2Hex(id)
 	info, ok := a.idToAssetDataInfo[idHex]
 	if !ok {
-		return "", errors.New(fmt.Sprintf("Unrecognized assetData with prefix: %s", idHex))
+		if customDecoder, found := customAssetProxyForID(idHex); found {
+			return customDecoder.Name(), nil
+		}
+		return "", UnrecognizedAssetDataError{AssetProxyID: idHex}
 	}
 	return info.name, nil
 }
@@ -194,7 +197,7 @@ func (a *AssetDataDecoder) Decode(assetData []byte, decodedAssetData interface{}
 	idHex := common.Bytes2Hex(id)
 	info, ok := a.idToAssetDataInfo[idHex]
 	if !ok {
-		return errors.New(fmt.Sprintf("Unrecognized assetData with prefix: %s", idHex))
+		return UnrecognizedAssetDataError{AssetProxyID: idHex}
 	}
 
 	// This is necessary to prevent a nil pointer exception for ABIs with no inputs
@@ -0,0 +1,136 @@
+package zeroex
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// AssetProxyDecoder decodes the assetData of an asset proxy which Mesh does not
+// support out of the box. Embedders can register one with RegisterAssetProxy
+// so that orders involving a new asset proxy are supported immediately.
+type AssetProxyDecoder interface {
+	// Name returns the name of the assetData type (e.g. "MyWrappedToken"). It is
+	// returned by AssetDataDecoder.GetName.
+	Name() string
+	// NestedAssetData returns the assetData of the standard assets (e.g. ERC20
+	// tokens) that are transferred by the asset proxy. Mesh watches these assets
+	// for balance and allowance changes just like the assets nested in
+	// MultiAsset assetData. If it returns no assetData, orders involving the
+	// asset proxy are still accepted but are only revalidated when they are
+	// filled, cancelled or expire.
+	NestedAssetData(assetData []byte) ([][]byte, error)
+}
+
+// UnrecognizedAssetDataError is returned when decoding assetData whose asset
+// proxy ID is neither supported by Mesh nor registered with RegisterAssetProxy.
+type UnrecognizedAssetDataError struct {
+	AssetProxyID string
+}
+
+func (e UnrecognizedAssetDataError) Error() string {
+	return fmt.Sprintf("Unrecognized assetData with prefix: %s", e.AssetProxyID)
+}
+
+var (
+	customAssetProxiesMu sync.RWMutex
+	customAssetProxies   = map[string]AssetProxyDecoder{}
+)
+
+// RegisterAssetProxy registers a decoder for the assetData of the asset proxy
+// with the given ID (the 4 byte hex-encoded assetData prefix, e.g.
+// "0xdc1600f3"). It must be called before Mesh is started. The ID and name of
+// the asset proxy may not clash with the asset proxies supported by Mesh or
+// with asset proxies that were registered before.
+func RegisterAssetProxy(assetProxyID string, decoder AssetProxyDecoder) error {
+	id := strings.ToLower(strings.TrimPrefix(assetProxyID, "0x"))
+	if decoded, err := hex.DecodeString(id); err != nil || len(decoded) != 4 {
+		return fmt.Errorf("invalid asset proxy ID %q: must be 4 hex-encoded bytes", assetProxyID)
+	}
+	builtInDecoder := NewAssetDataDecoder()
+	if info, found := builtInDecoder.idToAssetDataInfo[id]; found {
+		return fmt.Errorf("asset proxy ID %q is already used by %s assetData", assetProxyID, info.name)
+	}
+	for _, info := range builtInDecoder.idToAssetDataInfo {
+		if info.name == decoder.Name() {
+			return fmt.Errorf("asset proxy name %q is already used by a built-in asset proxy", decoder.Name())
+		}
+	}
+
+	customAssetProxiesMu.Lock()
+	defer customAssetProxiesMu.Unlock()
+	if existing, found := customAssetProxies[id]; found {
+		return fmt.Errorf("asset proxy ID %q is already registered for %s assetData", assetProxyID, existing.Name())
+	}
+	for _, existing := range customAssetProxies {
+		if existing.Name() == decoder.Name() {
+			return fmt.Errorf("asset proxy name %q is already registered", decoder.Name())
+		}
+	}
+	customAssetProxies[id] = decoder
+	return nil
+}
+
+// customAssetProxyForID returns the registered decoder for the given hex-encoded
+// asset proxy ID (without the 0x prefix), if any.
+func customAssetProxyForID(idHex string) (AssetProxyDecoder, bool) {
+	customAssetProxiesMu.RLock()
+	defer customAssetProxiesMu.RUnlock()
+	decoder, found := customAssetProxies[idHex]
+	return decoder, found
+}
+
+// IsCustomAssetProxy returns true if the asset proxy of the given assetData was
+// registered with RegisterAssetProxy.
+func (a *AssetDataDecoder) IsCustomAssetProxy(assetData []byte) bool {
+	if len(assetData) < 4 {
+		return false
+	}
+	_, found := customAssetProxyForID(common.Bytes2Hex(assetData[:4]))
+	return found
+}
+
+// DecodeNestedAssetData returns the standard assetData nested in the assetData
+// of an asset proxy that was registered with RegisterAssetProxy.
+func (a *AssetDataDecoder) DecodeNestedAssetData(assetData []byte) ([][]byte, error) {
+	if len(assetData) < 4 {
+		return nil, errors.New("assetData must be at least 4 bytes long")
+	}
+	idHex := common.Bytes2Hex(assetData[:4])
+	decoder, found := customAssetProxyForID(idHex)
+	if !found {
+		return nil, UnrecognizedAssetDataError{AssetProxyID: idHex}
+	}
+	return decoder.NestedAssetData(assetData)
+}
+
+// HasUnrecognizedAssetProxy returns true if the given assetData (or any
+// assetData nested within it) belongs to an asset proxy which is neither
+// supported by Mesh nor registered with RegisterAssetProxy.
+func (a *AssetDataDecoder) HasUnrecognizedAssetProxy(assetData []byte) bool {
+	if len(assetData) == 0 {
+		return false
+	}
+	assetDataName, err := a.GetName(assetData)
+	if err != nil {
+		_, isUnrecognized := err.(UnrecognizedAssetDataError)
+		return isUnrecognized
+	}
+	if assetDataName != "MultiAsset" {
+		return false
+	}
+	var decodedAssetData MultiAssetData
+	if err := a.Decode(assetData, &decodedAssetData); err != nil {
+		return false
+	}
+	for _, nestedAssetData := range decodedAssetData.NestedAssetData {
+		if a.HasUnrecognizedAssetProxy(nestedAssetData) {
+			return true
+		}
+	}
+	return false
+}
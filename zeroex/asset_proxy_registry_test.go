@@ -0,0 +1,76 @@
+package zeroex
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wrappedTokenDecoder decodes assetData which consists of the asset proxy ID
+// followed by the address of a single ERC20 token.
+type wrappedTokenDecoder struct {
+	name string
+}
+
+func (d wrappedTokenDecoder) Name() string {
+	return d.name
+}
+
+func (d wrappedTokenDecoder) NestedAssetData(assetData []byte) ([][]byte, error) {
+	return [][]byte{append(common.Hex2Bytes(ERC20AssetDataID), assetData[4:]...)}, nil
+}
+
+func TestRegisterAssetProxy(t *testing.T) {
+	require.NoError(t, RegisterAssetProxy("0x11112222", wrappedTokenDecoder{name: "WrappedToken"}))
+
+	assetData := common.Hex2Bytes("1111222200000000000000000000000038ae374ecf4db50b0ff37125b591a04997106a32")
+	d := NewAssetDataDecoder()
+	name, err := d.GetName(assetData)
+	require.NoError(t, err)
+	assert.Equal(t, "WrappedToken", name)
+	assert.True(t, d.IsCustomAssetProxy(assetData))
+	assert.False(t, d.HasUnrecognizedAssetProxy(assetData))
+
+	nestedAssetDatas, err := d.DecodeNestedAssetData(assetData)
+	require.NoError(t, err)
+	require.Len(t, nestedAssetDatas, 1)
+	var decodedAssetData ERC20AssetData
+	require.NoError(t, d.Decode(nestedAssetDatas[0], &decodedAssetData))
+	assert.Equal(t, common.HexToAddress("0x38ae374ecf4db50b0ff37125b591a04997106a32"), decodedAssetData.Address)
+}
+
+func TestRegisterAssetProxyErrors(t *testing.T) {
+	require.NoError(t, RegisterAssetProxy("33334444", wrappedTokenDecoder{name: "OtherWrappedToken"}))
+
+	testCases := []struct {
+		assetProxyID string
+		name         string
+	}{
+		{assetProxyID: "0x1234", name: "TooShort"},
+		{assetProxyID: "0xzzzzzzzz", name: "NotHex"},
+		{assetProxyID: "0x" + ERC20AssetDataID, name: "BuiltInID"},
+		{assetProxyID: "0x55556666", name: "ERC20Token"},
+		{assetProxyID: "0x33334444", name: "SameID"},
+		{assetProxyID: "0x77778888", name: "OtherWrappedToken"},
+	}
+	for _, testCase := range testCases {
+		err := RegisterAssetProxy(testCase.assetProxyID, wrappedTokenDecoder{name: testCase.name})
+		assert.Error(t, err, testCase.name)
+	}
+}
+
+func TestUnrecognizedAssetProxy(t *testing.T) {
+	assetData := common.Hex2Bytes("9999999900000000000000000000000038ae374ecf4db50b0ff37125b591a04997106a32")
+	d := NewAssetDataDecoder()
+
+	_, err := d.GetName(assetData)
+	assert.Equal(t, UnrecognizedAssetDataError{AssetProxyID: "99999999"}, err)
+	assert.False(t, d.IsCustomAssetProxy(assetData))
+	assert.True(t, d.HasUnrecognizedAssetProxy(assetData))
+
+	erc20AssetData := common.Hex2Bytes("f47261b000000000000000000000000038ae374ecf4db50b0ff37125b591a04997106a32")
+	assert.False(t, d.HasUnrecognizedAssetProxy(erc20AssetData))
+	assert.False(t, d.HasUnrecognizedAssetProxy([]byte{}))
+}
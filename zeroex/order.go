@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
-	"strings"
 	"time"
 
 	"github.com/0xProject/0x-mesh/ethereum/signer"
@@ -111,7 +110,7 @@ func (c ContractEvent) MarshalJSON() ([]byte, error) {
 		"txIndex":    c.TxIndex,
 		"logIndex":   c.LogIndex,
 		"isRemoved":  c.IsRemoved,
-		"address":    c.Address,
+		"address":    c.Address.Hex(),
 		"kind":       c.Kind,
 		"parameters": c.Parameters,
 	}
@@ -129,7 +128,8 @@ type OrderEvent struct {
 	Timestamp time.Time `json:"timestamp"`
 	// OrderHash is the EIP712 hash of the 0x order
 	OrderHash common.Hash `json:"orderHash"`
-	// SignedOrder is the signed 0x order struct
+	// SignedOrder is the signed 0x order struct. It is nil in compact order
+	// events (see Compact).
 	SignedOrder *SignedOrder `json:"signedOrder"`
 	// EndState is the end state of this order at the time this event was generated
 	EndState OrderEventEndState `json:"endState"`
@@ -139,6 +139,22 @@ type OrderEvent struct {
 	// They did not all necessarily cause the orders state change itself, only it's re-evaluation.
 	// Since it's state _did_ change, at least one of them did cause the actual state change.
 	ContractEvents []*ContractEvent `json:"contractEvents"`
+	// OrderFilterHash is the hash of the order filter that was in use when the
+	// order was added. It can be used to distinguish orders that were admitted
+	// under different filters.
+	OrderFilterHash string `json:"orderFilterHash"`
+	// RequestID is the ID of the AddOrders request that caused this event, if
+	// any. It is empty for events that were not caused by an AddOrders request.
+	RequestID string `json:"requestID,omitempty"`
+	// RemovalReason is the reason given by the client that removed the order,
+	// ORDER_FILTER_CHANGED if the order no longer matched the order filter
+	// after it was changed, or TOKEN_LIST_CHANGED if one of its tokens was
+	// removed from the dynamic token list. It is only set for REMOVED events.
+	RemovalReason string `json:"removalReason,omitempty"`
+	// SequenceNumber is a strictly increasing number which is assigned to every
+	// order event emitted by Mesh (starting at 1). Subscribers can use it to
+	// detect missed events and fetch them with GetOrderEventsSince.
+	SequenceNumber uint64 `json:"sequenceNumber"`
 }
 
 type orderEventJSON struct {
@@ -148,18 +164,44 @@ type orderEventJSON struct {
 	EndState                 string               `json:"endState"`
 	FillableTakerAssetAmount string               `json:"fillableTakerAssetAmount"`
 	ContractEvents           []*contractEventJSON `json:"contractEvents"`
+	OrderFilterHash          string               `json:"orderFilterHash"`
+	RequestID                string               `json:"requestID,omitempty"`
+	RemovalReason            string               `json:"removalReason,omitempty"`
+	SequenceNumber           uint64               `json:"sequenceNumber"`
 }
 
 // MarshalJSON implements a custom JSON marshaller for the OrderEvent type
 func (o OrderEvent) MarshalJSON() ([]byte, error) {
-	return json.Marshal(map[string]interface{}{
+	orderEvent := map[string]interface{}{
 		"timestamp":                o.Timestamp,
 		"orderHash":                o.OrderHash.Hex(),
-		"signedOrder":              o.SignedOrder,
 		"endState":                 o.EndState,
 		"fillableTakerAssetAmount": o.FillableTakerAssetAmount.String(),
 		"contractEvents":           o.ContractEvents,
-	})
+		"orderFilterHash":          o.OrderFilterHash,
+		"sequenceNumber":           o.SequenceNumber,
+	}
+	if o.SignedOrder != nil {
+		orderEvent["signedOrder"] = ChecksummedSignedOrder{o.SignedOrder}
+	}
+	if o.RequestID != "" {
+		orderEvent["requestID"] = o.RequestID
+	}
+	if o.RemovalReason != "" {
+		orderEvent["removalReason"] = o.RemovalReason
+	}
+	return json.Marshal(orderEvent)
+}
+
+// Compact returns a copy of the order event without the signed order. Orders
+// never change, so subscribers which already know an order only need the
+// fields that describe what happened to it. This considerably reduces the size
+// of order events for subscribers with a lot of churn. The full order can be
+// fetched by its hash.
+func (o *OrderEvent) Compact() *OrderEvent {
+	compact := *o
+	compact.SignedOrder = nil
+	return &compact
 }
 
 // UnmarshalJSON implements a custom JSON unmarshaller for the OrderEvent type
@@ -177,6 +219,10 @@ func (o *OrderEvent) fromOrderEventJSON(orderEventJSON orderEventJSON) error {
 	o.OrderHash = common.HexToHash(orderEventJSON.OrderHash)
 	o.SignedOrder = orderEventJSON.SignedOrder
 	o.EndState = OrderEventEndState(orderEventJSON.EndState)
+	o.OrderFilterHash = orderEventJSON.OrderFilterHash
+	o.RequestID = orderEventJSON.RequestID
+	o.RemovalReason = orderEventJSON.RemovalReason
+	o.SequenceNumber = orderEventJSON.SequenceNumber
 	var ok bool
 	o.FillableTakerAssetAmount, ok = math.ParseBig256(orderEventJSON.FillableTakerAssetAmount)
 	if !ok {
@@ -336,6 +382,13 @@ const (
 	// and no further events for this order will be emitted. In some cases, the order may be re-added in the
 	// future.
 	ESStoppedWatching = OrderEventEndState("STOPPED_WATCHING")
+	// ESOrderStaticCallFailed means a staticcall encoded in the order's assetData no longer succeeds. This event
+	// is only emitted if staticcall execution is enabled.
+	ESOrderStaticCallFailed = OrderEventEndState("STATIC_CALL_FAILED")
+	// ESOrderRemoved means an order that was added via AddOrders was removed by a client or an order no longer
+	// matched the order filter after it was changed (see RemovalReason). The order will no longer be watched and
+	// no further events for this order will be emitted.
+	ESOrderRemoved = OrderEventEndState("REMOVED")
 )
 
 var eip712OrderTypes = gethsigner.Types{
@@ -427,15 +480,37 @@ func (o *Order) ComputeOrderHash() (common.Hash, error) {
 	if o.hash != nil {
 		return *o.hash, nil
 	}
+	domainSeparator, err := eip712DomainSeparator(o.ChainID, o.ExchangeAddress)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return o.computeOrderHashWithDomainSeparator(domainSeparator)
+}
 
-	chainID := math.NewHexOrDecimal256(o.ChainID.Int64())
-	var domain = gethsigner.TypedDataDomain{
+// eip712DomainSeparator computes the EIP712 domain separator for orders on the
+// given chain and exchange. It is the same for all orders with the same chain
+// ID and exchange address, which means it can be shared when hashing a batch
+// of orders.
+func eip712DomainSeparator(chainID *big.Int, exchangeAddress common.Address) ([]byte, error) {
+	var typedData = gethsigner.TypedData{
+		Types:  eip712OrderTypes,
+		Domain: eip712Domain(chainID, exchangeAddress),
+	}
+	return typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
+}
+
+func eip712Domain(chainID *big.Int, exchangeAddress common.Address) gethsigner.TypedDataDomain {
+	return gethsigner.TypedDataDomain{
 		Name:              "0x Protocol",
 		Version:           "3.0.0",
-		ChainId:           chainID,
-		VerifyingContract: o.ExchangeAddress.Hex(),
+		ChainId:           math.NewHexOrDecimal256(chainID.Int64()),
+		VerifyingContract: exchangeAddress.Hex(),
 	}
+}
 
+// computeOrderHashWithDomainSeparator computes the order hash given the
+// domain separator returned by eip712DomainSeparator and caches it.
+func (o *Order) computeOrderHashWithDomainSeparator(domainSeparator []byte) (common.Hash, error) {
 	var message = map[string]interface{}{
 		"makerAddress":          o.MakerAddress.Hex(),
 		"takerAddress":          o.TakerAddress.Hex(),
@@ -453,17 +528,15 @@ func (o *Order) ComputeOrderHash() (common.Hash, error) {
 		"expirationTimeSeconds": o.ExpirationTimeSeconds.String(),
 	}
 
+	// go-ethereum refuses to hash a struct without a valid domain, even though
+	// the domain is not part of the struct hash.
 	var typedData = gethsigner.TypedData{
 		Types:       eip712OrderTypes,
 		PrimaryType: "Order",
-		Domain:      domain,
+		Domain:      eip712Domain(o.ChainID, o.ExchangeAddress),
 		Message:     message,
 	}
 
-	domainSeparator, err := typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
-	if err != nil {
-		return common.Hash{}, err
-	}
 	typedDataHash, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
 	if err != nil {
 		return common.Hash{}, err
@@ -557,6 +630,12 @@ type SignedOrderJSON struct {
 
 // MarshalJSON implements a custom JSON marshaller for the SignedOrder type
 func (s SignedOrder) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.toSignedOrderJSON())
+}
+
+// toSignedOrderJSON returns the JSON representation of the order, in which
+// addresses are lowercase (see NormalizedAddressHex).
+func (s SignedOrder) toSignedOrderJSON() SignedOrderJSON {
 	makerAssetData := "0x"
 	if len(s.MakerAssetData) != 0 {
 		makerAssetData = fmt.Sprintf("0x%s", common.Bytes2Hex(s.MakerAssetData))
@@ -581,26 +660,25 @@ func (s SignedOrder) MarshalJSON() ([]byte, error) {
 		signature = fmt.Sprintf("0x%s", common.Bytes2Hex(s.Signature))
 	}
 
-	signedOrderBytes, err := json.Marshal(SignedOrderJSON{
+	return SignedOrderJSON{
 		ChainID:               s.ChainID.Int64(),
-		ExchangeAddress:       strings.ToLower(s.ExchangeAddress.Hex()),
-		MakerAddress:          strings.ToLower(s.MakerAddress.Hex()),
+		ExchangeAddress:       NormalizedAddressHex(s.ExchangeAddress),
+		MakerAddress:          NormalizedAddressHex(s.MakerAddress),
 		MakerAssetData:        makerAssetData,
 		MakerFeeAssetData:     makerFeeAssetData,
 		MakerAssetAmount:      s.MakerAssetAmount.String(),
 		MakerFee:              s.MakerFee.String(),
-		TakerAddress:          strings.ToLower(s.TakerAddress.Hex()),
+		TakerAddress:          NormalizedAddressHex(s.TakerAddress),
 		TakerAssetData:        takerAssetData,
 		TakerFeeAssetData:     takerFeeAssetData,
 		TakerAssetAmount:      s.TakerAssetAmount.String(),
 		TakerFee:              s.TakerFee.String(),
-		SenderAddress:         strings.ToLower(s.SenderAddress.Hex()),
-		FeeRecipientAddress:   strings.ToLower(s.FeeRecipientAddress.Hex()),
+		SenderAddress:         NormalizedAddressHex(s.SenderAddress),
+		FeeRecipientAddress:   NormalizedAddressHex(s.FeeRecipientAddress),
 		ExpirationTimeSeconds: s.ExpirationTimeSeconds.String(),
 		Salt:                  s.Salt.String(),
 		Signature:             signature,
-	})
-	return signedOrderBytes, err
+	}
 }
 
 const addressHexLength = 42
@@ -4,7 +4,6 @@ package zeroex
 
 import (
 	"fmt"
-	"strings"
 	"syscall/js"
 	"time"
 
@@ -23,6 +22,10 @@ func (o OrderEvent) JSValue() js.Value {
 		"endState":                 string(o.EndState),
 		"fillableTakerAssetAmount": o.FillableTakerAssetAmount.String(),
 		"contractEvents":           contractEventsJS,
+		"orderFilterHash":          o.OrderFilterHash,
+		"requestID":                o.RequestID,
+		"removalReason":            o.RemovalReason,
+		"sequenceNumber":           o.SequenceNumber,
 	})
 }
 
@@ -53,19 +56,19 @@ func (s SignedOrder) JSValue() js.Value {
 
 	return js.ValueOf(map[string]interface{}{
 		"chainId":               s.ChainID.Int64(),
-		"exchangeAddress":       strings.ToLower(s.ExchangeAddress.Hex()),
-		"makerAddress":          strings.ToLower(s.MakerAddress.Hex()),
+		"exchangeAddress":       s.ExchangeAddress.Hex(),
+		"makerAddress":          s.MakerAddress.Hex(),
 		"makerAssetData":        makerAssetData,
 		"makerFeeAssetData":     makerFeeAssetData,
 		"makerAssetAmount":      s.MakerAssetAmount.String(),
 		"makerFee":              s.MakerFee.String(),
-		"takerAddress":          strings.ToLower(s.TakerAddress.Hex()),
+		"takerAddress":          s.TakerAddress.Hex(),
 		"takerAssetData":        takerAssetData,
 		"takerFeeAssetData":     takerFeeAssetData,
 		"takerAssetAmount":      s.TakerAssetAmount.String(),
 		"takerFee":              s.TakerFee.String(),
-		"senderAddress":         strings.ToLower(s.SenderAddress.Hex()),
-		"feeRecipientAddress":   strings.ToLower(s.FeeRecipientAddress.Hex()),
+		"senderAddress":         s.SenderAddress.Hex(),
+		"feeRecipientAddress":   s.FeeRecipientAddress.Hex(),
 		"expirationTimeSeconds": s.ExpirationTimeSeconds.String(),
 		"salt":                  s.Salt.String(),
 		"signature":             signature,
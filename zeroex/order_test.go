@@ -113,3 +113,32 @@ func TestMarshalUnmarshalOrderEvent(t *testing.T) {
 	require.NoError(t, json.NewDecoder(buf).Decode(&decoded))
 	assert.Equal(t, orderEvent, decoded)
 }
+
+func TestMarshalUnmarshalCompactOrderEvent(t *testing.T) {
+	signedOrder, err := SignTestOrder(testOrder)
+	require.NoError(t, err)
+	orderHash, err := signedOrder.ComputeOrderHash()
+	require.NoError(t, err)
+	orderEvent := &OrderEvent{
+		Timestamp:                time.Now().UTC(),
+		OrderHash:                orderHash,
+		SignedOrder:              signedOrder,
+		EndState:                 ESOrderFilled,
+		FillableTakerAssetAmount: big.NewInt(1000),
+		ContractEvents:           []*ContractEvent{},
+		SequenceNumber:           42,
+	}
+	compact := orderEvent.Compact()
+	assert.Nil(t, compact.SignedOrder)
+	assert.NotNil(t, orderEvent.SignedOrder, "Compact should not modify the original order event")
+
+	encoded, err := json.Marshal(compact)
+	require.NoError(t, err)
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(encoded, &fields))
+	assert.NotContains(t, fields, "signedOrder")
+
+	var decoded OrderEvent
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, compact, &decoded)
+}
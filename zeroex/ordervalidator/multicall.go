@@ -0,0 +1,491 @@
+package ordervalidator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/ethereum/wrappers"
+	"github.com/0xProject/0x-mesh/zeroex"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	log "github.com/sirupsen/logrus"
+)
+
+// multicallABIJSON is the ABI for the `tryAggregate` method of the Multicall2
+// contract. Unlike `aggregate`, `tryAggregate` does not revert if one of the
+// calls fails which means a single misbehaving token cannot cause the
+// validation of an entire batch of orders to fail.
+const multicallABIJSON = `[{"constant":false,"inputs":[{"internalType":"bool","name":"requireSuccess","type":"bool"},{"components":[{"internalType":"address","name":"target","type":"address"},{"internalType":"bytes","name":"callData","type":"bytes"}],"internalType":"struct Multicall2.Call[]","name":"calls","type":"tuple[]"}],"name":"tryAggregate","outputs":[{"components":[{"internalType":"bool","name":"success","type":"bool"},{"internalType":"bytes","name":"returnData","type":"bytes"}],"internalType":"struct Multicall2.Result[]","name":"returnData","type":"tuple[]"}],"payable":false,"stateMutability":"nonpayable","type":"function"}]`
+
+// errMulticallNotConfigured is returned if DevUtils is unavailable and there is
+// no Multicall contract to fall back to.
+var errMulticallNotConfigured = errors.New("cannot validate orders without DevUtils: no Multicall contract address configured")
+
+// orderRelevantStates holds the same information as the result of the
+// `getOrderRelevantStates` method of the DevUtils contract.
+type orderRelevantStates struct {
+	OrdersInfo                []wrappers.OrderInfo
+	FillableTakerAssetAmounts []*big.Int
+	IsValidSignature          []bool
+}
+
+type multicallCall struct {
+	Target   common.Address
+	CallData []byte
+}
+
+type multicallResult struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// multicallABIs holds the parsed ABIs needed to validate orders without
+// DevUtils.
+type multicallABIs struct {
+	multicall abi.ABI
+	exchange  abi.ABI
+	// We don't have generic ERC20 and ERC721 wrappers, but the ABIs of the ZRX
+	// token and the dummy ERC721 token include all of the standard methods we
+	// need.
+	erc20  abi.ABI
+	erc721 abi.ABI
+}
+
+func newMulticallABIs() (*multicallABIs, error) {
+	multicallABI, err := abi.JSON(strings.NewReader(multicallABIJSON))
+	if err != nil {
+		return nil, err
+	}
+	exchangeABI, err := abi.JSON(strings.NewReader(wrappers.ExchangeABI))
+	if err != nil {
+		return nil, err
+	}
+	erc20ABI, err := abi.JSON(strings.NewReader(wrappers.ZRXTokenABI))
+	if err != nil {
+		return nil, err
+	}
+	erc721ABI, err := abi.JSON(strings.NewReader(wrappers.DummyERC721TokenABI))
+	if err != nil {
+		return nil, err
+	}
+	return &multicallABIs{
+		multicall: multicallABI,
+		exchange:  exchangeABI,
+		erc20:     erc20ABI,
+		erc721:    erc721ABI,
+	}, nil
+}
+
+// isDevUtilsAvailable returns true if orders are validated via the DevUtils
+// contract. If false, orders are validated by calling the Exchange and token
+// contracts directly through the Multicall contract.
+func (o *OrderValidator) isDevUtilsAvailable() bool {
+	return o.contractAddresses.DevUtils != constants.NullAddress
+}
+
+// getOrderRelevantStates returns the order info, fillable taker asset amount
+// and signature validity for each of the given orders.
+func (o *OrderValidator) getOrderRelevantStates(opts *bind.CallOpts, signedOrders []*zeroex.SignedOrder) (orderRelevantStates, error) {
+	if !o.isDevUtilsAvailable() {
+		return o.getOrderRelevantStatesViaMulticall(opts, signedOrders)
+	}
+	trimmedOrders := []wrappers.TrimmedOrder{}
+	signatures := [][]byte{}
+	for _, signedOrder := range signedOrders {
+		trimmedOrders = append(trimmedOrders, signedOrder.Trim())
+		signatures = append(signatures, signedOrder.Signature)
+	}
+	results, err := o.devUtils.GetOrderRelevantStates(opts, trimmedOrders, signatures)
+	if err != nil {
+		return orderRelevantStates{}, err
+	}
+	return orderRelevantStates(results), nil
+}
+
+// transferableQuery describes how to compute the amount of some assetData that
+// can currently be transferred from the maker by the asset proxies. callIndexes
+// refer to the results of a single Multicall request.
+type transferableQuery struct {
+	assetDataName string
+	callIndexes   []int
+	// ERC721 only.
+	proxy common.Address
+	// MultiAsset only.
+	amounts []*big.Int
+	nested  []*transferableQuery
+}
+
+// orderStateQuery holds the indexes of all the calls needed to compute the
+// relevant state of a single order.
+type orderStateQuery struct {
+	orderInfoIndex      int
+	isValidSigIndex     int
+	makerAsset          *transferableQuery
+	makerFeeAsset       *transferableQuery
+	hasSeparateMakerFee bool
+}
+
+// multicallBuilder accumulates the calls for a single Multicall request.
+type multicallBuilder struct {
+	abis  *multicallABIs
+	calls []multicallCall
+}
+
+func (b *multicallBuilder) add(target common.Address, contractABI abi.ABI, method string, args ...interface{}) (int, error) {
+	callData, err := contractABI.Pack(method, args...)
+	if err != nil {
+		return 0, err
+	}
+	b.calls = append(b.calls, multicallCall{
+		Target:   target,
+		CallData: callData,
+	})
+	return len(b.calls) - 1, nil
+}
+
+// addOrderToMulticall adds all the calls needed to compute the relevant state
+// of the given order.
+func (o *OrderValidator) addOrderToMulticall(b *multicallBuilder, signedOrder *zeroex.SignedOrder) (*orderStateQuery, error) {
+	trimmedOrder := signedOrder.Trim()
+	orderInfoIndex, err := b.add(o.contractAddresses.Exchange, b.abis.exchange, "getOrderInfo", trimmedOrder)
+	if err != nil {
+		return nil, err
+	}
+	isValidSigIndex, err := b.add(o.contractAddresses.Exchange, b.abis.exchange, "isValidOrderSignature", trimmedOrder, signedOrder.Signature)
+	if err != nil {
+		return nil, err
+	}
+	makerAsset, err := o.addTransferableQuery(b, signedOrder.MakerAddress, signedOrder.MakerAssetData)
+	if err != nil {
+		return nil, err
+	}
+	query := &orderStateQuery{
+		orderInfoIndex:  orderInfoIndex,
+		isValidSigIndex: isValidSigIndex,
+		makerAsset:      makerAsset,
+	}
+	if signedOrder.MakerFee.Sign() > 0 && len(signedOrder.MakerFeeAssetData) > 0 && !bytes.Equal(signedOrder.MakerFeeAssetData, signedOrder.MakerAssetData) {
+		makerFeeAsset, err := o.addTransferableQuery(b, signedOrder.MakerAddress, signedOrder.MakerFeeAssetData)
+		if err != nil {
+			return nil, err
+		}
+		query.makerFeeAsset = makerFeeAsset
+		query.hasSeparateMakerFee = true
+	}
+	return query, nil
+}
+
+func (o *OrderValidator) addTransferableQuery(b *multicallBuilder, makerAddress common.Address, assetData []byte) (*transferableQuery, error) {
+	assetDataName, err := o.assetDataDecoder.GetName(assetData)
+	if err != nil {
+		return nil, err
+	}
+	query := &transferableQuery{assetDataName: assetDataName}
+	switch assetDataName {
+	case "ERC20Token":
+		var decodedAssetData zeroex.ERC20AssetData
+		if err := o.assetDataDecoder.Decode(assetData, &decodedAssetData); err != nil {
+			return nil, err
+		}
+		balanceIndex, err := b.add(decodedAssetData.Address, b.abis.erc20, "balanceOf", makerAddress)
+		if err != nil {
+			return nil, err
+		}
+		allowanceIndex, err := b.add(decodedAssetData.Address, b.abis.erc20, "allowance", makerAddress, o.contractAddresses.ERC20Proxy)
+		if err != nil {
+			return nil, err
+		}
+		query.callIndexes = []int{balanceIndex, allowanceIndex}
+	case "ERC721Token":
+		var decodedAssetData zeroex.ERC721AssetData
+		if err := o.assetDataDecoder.Decode(assetData, &decodedAssetData); err != nil {
+			return nil, err
+		}
+		ownerIndex, err := b.add(decodedAssetData.Address, b.abis.erc721, "ownerOf", decodedAssetData.TokenId)
+		if err != nil {
+			return nil, err
+		}
+		approvedForAllIndex, err := b.add(decodedAssetData.Address, b.abis.erc721, "isApprovedForAll", makerAddress, o.contractAddresses.ERC721Proxy)
+		if err != nil {
+			return nil, err
+		}
+		approvedIndex, err := b.add(decodedAssetData.Address, b.abis.erc721, "getApproved", decodedAssetData.TokenId)
+		if err != nil {
+			return nil, err
+		}
+		query.callIndexes = []int{ownerIndex, approvedForAllIndex, approvedIndex}
+		query.proxy = o.contractAddresses.ERC721Proxy
+	case "StaticCall":
+		// StaticCall assetData does not transfer anything. Whether or not the
+		// staticcall succeeds is checked separately.
+	case "MultiAsset":
+		var decodedAssetData zeroex.MultiAssetData
+		if err := o.assetDataDecoder.Decode(assetData, &decodedAssetData); err != nil {
+			return nil, err
+		}
+		// evaluate relies on there being an amount for each nested assetData.
+		// Such assetData is rejected by isSupportedAssetData.
+		if len(decodedAssetData.Amounts) != len(decodedAssetData.NestedAssetData) {
+			return nil, fmt.Errorf("MultiAsset assetData has %d amounts but %d nested assetData", len(decodedAssetData.Amounts), len(decodedAssetData.NestedAssetData))
+		}
+		for _, nestedAssetData := range decodedAssetData.NestedAssetData {
+			nestedQuery, err := o.addTransferableQuery(b, makerAddress, nestedAssetData)
+			if err != nil {
+				return nil, err
+			}
+			query.nested = append(query.nested, nestedQuery)
+		}
+		query.amounts = decodedAssetData.Amounts
+	default:
+		return nil, fmt.Errorf("cannot validate %s assetData without DevUtils", assetDataName)
+	}
+	return query, nil
+}
+
+// isSupportedWithoutDevUtils returns true if the transferable amount of the
+// given assetData can be computed without the DevUtils contract.
+func (o *OrderValidator) isSupportedWithoutDevUtils(assetData []byte) bool {
+	if len(assetData) == 0 {
+		return true
+	}
+	assetDataName, err := o.assetDataDecoder.GetName(assetData)
+	if err != nil {
+		return false
+	}
+	switch assetDataName {
+	case "ERC20Token", "ERC721Token", "StaticCall":
+		return true
+	case "MultiAsset":
+		var decodedAssetData zeroex.MultiAssetData
+		if err := o.assetDataDecoder.Decode(assetData, &decodedAssetData); err != nil {
+			return false
+		}
+		for _, nestedAssetData := range decodedAssetData.NestedAssetData {
+			if !o.isSupportedWithoutDevUtils(nestedAssetData) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// batchValidateSupportedWithoutDevUtils rejects any orders involving assetData
+// for which the transferable amount cannot be computed without DevUtils (e.g.
+// ERC1155 or ERC20Bridge assetData). It is a no-op if DevUtils is available.
+func (o *OrderValidator) batchValidateSupportedWithoutDevUtils(signedOrders []*zeroex.SignedOrder) ([]*zeroex.SignedOrder, []*RejectedOrderInfo) {
+	if o.isDevUtilsAvailable() {
+		return signedOrders, nil
+	}
+	rejectedOrderInfos := []*RejectedOrderInfo{}
+	validSignedOrders := []*zeroex.SignedOrder{}
+	for _, signedOrder := range signedOrders {
+		if o.isSupportedWithoutDevUtils(signedOrder.MakerAssetData) && o.isSupportedWithoutDevUtils(signedOrder.MakerFeeAssetData) {
+			validSignedOrders = append(validSignedOrders, signedOrder)
+			continue
+		}
+		orderHash, err := signedOrder.ComputeOrderHash()
+		if err != nil {
+			log.WithError(err).WithField("signedOrder", signedOrder).Error("Computing the orderHash failed unexpectedly")
+		}
+		rejectedOrderInfos = append(rejectedOrderInfos, &RejectedOrderInfo{
+			OrderHash:   orderHash,
+			SignedOrder: signedOrder,
+			Kind:        MeshValidation,
+			Status:      ROAssetDataUnsupported,
+		})
+	}
+	return validSignedOrders, rejectedOrderInfos
+}
+
+// evaluate computes the amount of the assetData that can be transferred from
+// the maker given the results of the Multicall request. Calls that failed are
+// treated as if nothing can be transferred.
+func (q *transferableQuery) evaluate(abis *multicallABIs, makerAddress common.Address, results []multicallResult) *big.Int {
+	switch q.assetDataName {
+	case "ERC20Token":
+		balance := unpackBigInt(abis.erc20, "balanceOf", results[q.callIndexes[0]])
+		allowance := unpackBigInt(abis.erc20, "allowance", results[q.callIndexes[1]])
+		return math.BigMin(balance, allowance)
+	case "ERC721Token":
+		ownerResult := results[q.callIndexes[0]]
+		if !ownerResult.Success {
+			return big.NewInt(0)
+		}
+		var owner common.Address
+		if err := abis.erc721.Unpack(&owner, "ownerOf", ownerResult.ReturnData); err != nil || owner != makerAddress {
+			return big.NewInt(0)
+		}
+		var isApprovedForAll bool
+		if result := results[q.callIndexes[1]]; result.Success {
+			_ = abis.erc721.Unpack(&isApprovedForAll, "isApprovedForAll", result.ReturnData)
+		}
+		var approved common.Address
+		if result := results[q.callIndexes[2]]; result.Success {
+			_ = abis.erc721.Unpack(&approved, "getApproved", result.ReturnData)
+		}
+		if !isApprovedForAll && approved != q.proxy {
+			return big.NewInt(0)
+		}
+		return big.NewInt(1)
+	case "StaticCall":
+		return new(big.Int).Set(math.MaxBig256)
+	case "MultiAsset":
+		transferable := new(big.Int).Set(math.MaxBig256)
+		for i, nested := range q.nested {
+			if q.amounts[i].Sign() == 0 {
+				continue
+			}
+			nestedTransferable := nested.evaluate(abis, makerAddress, results)
+			transferable = math.BigMin(transferable, new(big.Int).Div(nestedTransferable, q.amounts[i]))
+		}
+		return transferable
+	default:
+		return big.NewInt(0)
+	}
+}
+
+func unpackBigInt(contractABI abi.ABI, method string, result multicallResult) *big.Int {
+	if !result.Success {
+		return big.NewInt(0)
+	}
+	value := new(*big.Int)
+	if err := contractABI.Unpack(value, method, result.ReturnData); err != nil || *value == nil {
+		return big.NewInt(0)
+	}
+	return *value
+}
+
+// computeFillableTakerAssetAmount mirrors the logic of the DevUtils contract.
+// The fillable amount is the remaining taker asset amount limited by how much
+// of the maker asset (and maker fee asset) can currently be transferred from
+// the maker.
+func computeFillableTakerAssetAmount(signedOrder *zeroex.SignedOrder, orderInfo wrappers.OrderInfo, transferableMakerAssetAmount *big.Int, transferableMakerFeeAssetAmount *big.Int, hasSeparateMakerFee bool) *big.Int {
+	if zeroex.OrderStatus(orderInfo.OrderStatus) != zeroex.OSFillable {
+		return big.NewInt(0)
+	}
+	remainingTakerAssetAmount := new(big.Int).Sub(signedOrder.TakerAssetAmount, orderInfo.OrderTakerAssetFilledAmount)
+	if signedOrder.MakerAssetAmount.Sign() == 0 {
+		return big.NewInt(0)
+	}
+	makerFeeIsMakerAsset := signedOrder.MakerFee.Sign() > 0 && !hasSeparateMakerFee && len(signedOrder.MakerFeeAssetData) > 0
+	var fillable *big.Int
+	if makerFeeIsMakerAsset {
+		// The maker fee is paid in the maker asset so both must be transferred
+		// from the same balance.
+		total := new(big.Int).Add(signedOrder.MakerAssetAmount, signedOrder.MakerFee)
+		fillable = getPartialAmountFloor(transferableMakerAssetAmount, total, signedOrder.TakerAssetAmount)
+	} else {
+		fillable = getPartialAmountFloor(transferableMakerAssetAmount, signedOrder.MakerAssetAmount, signedOrder.TakerAssetAmount)
+		if hasSeparateMakerFee {
+			fillable = math.BigMin(fillable, getPartialAmountFloor(transferableMakerFeeAssetAmount, signedOrder.MakerFee, signedOrder.TakerAssetAmount))
+		}
+	}
+	return math.BigMin(fillable, remainingTakerAssetAmount)
+}
+
+// getPartialAmountFloor returns floor(numerator * target / denominator).
+func getPartialAmountFloor(numerator, denominator, target *big.Int) *big.Int {
+	result := new(big.Int).Mul(numerator, target)
+	return result.Div(result, denominator)
+}
+
+// getOrderRelevantStatesViaMulticall computes the same information as the
+// `getOrderRelevantStates` method of the DevUtils contract by calling
+// Exchange.getOrderInfo, Exchange.isValidOrderSignature and the relevant token
+// contracts directly, all within a single Multicall request.
+func (o *OrderValidator) getOrderRelevantStatesViaMulticall(opts *bind.CallOpts, signedOrders []*zeroex.SignedOrder) (orderRelevantStates, error) {
+	if o.contractAddresses.Multicall == constants.NullAddress {
+		return orderRelevantStates{}, errMulticallNotConfigured
+	}
+	builder := &multicallBuilder{abis: o.multicallABIs}
+	queries := make([]*orderStateQuery, len(signedOrders))
+	for i, signedOrder := range signedOrders {
+		query, err := o.addOrderToMulticall(builder, signedOrder)
+		if err != nil {
+			return orderRelevantStates{}, err
+		}
+		queries[i] = query
+	}
+	callData, err := o.multicallABIs.multicall.Pack("tryAggregate", false, builder.calls)
+	if err != nil {
+		return orderRelevantStates{}, err
+	}
+	multicallAddress := o.contractAddresses.Multicall
+	msg := ethereum.CallMsg{
+		From: opts.From,
+		To:   &multicallAddress,
+		Data: callData,
+	}
+	returnData, err := o.contractCaller.CallContract(opts.Context, msg, opts.BlockNumber)
+	if err != nil {
+		return orderRelevantStates{}, err
+	}
+	var results []multicallResult
+	if err := o.multicallABIs.multicall.Unpack(&results, "tryAggregate", returnData); err != nil {
+		return orderRelevantStates{}, err
+	}
+	if len(results) != len(builder.calls) {
+		return orderRelevantStates{}, fmt.Errorf("expected %d results from Multicall but got %d", len(builder.calls), len(results))
+	}
+
+	states := orderRelevantStates{
+		OrdersInfo:                make([]wrappers.OrderInfo, len(signedOrders)),
+		FillableTakerAssetAmounts: make([]*big.Int, len(signedOrders)),
+		IsValidSignature:          make([]bool, len(signedOrders)),
+	}
+	for i, signedOrder := range signedOrders {
+		query := queries[i]
+		orderInfoResult := results[query.orderInfoIndex]
+		if !orderInfoResult.Success {
+			return orderRelevantStates{}, fmt.Errorf("Exchange.getOrderInfo failed for order %d", i)
+		}
+		var orderInfo wrappers.OrderInfo
+		if err := o.multicallABIs.exchange.Unpack(&orderInfo, "getOrderInfo", orderInfoResult.ReturnData); err != nil {
+			return orderRelevantStates{}, err
+		}
+		var isValidSignature bool
+		if result := results[query.isValidSigIndex]; result.Success {
+			// Exchange.isValidOrderSignature reverts for some invalid signature
+			// types. In that case the signature is considered invalid.
+			_ = o.multicallABIs.exchange.Unpack(&isValidSignature, "isValidOrderSignature", result.ReturnData)
+		}
+		transferableMakerAssetAmount := query.makerAsset.evaluate(o.multicallABIs, signedOrder.MakerAddress, results)
+		transferableMakerFeeAssetAmount := big.NewInt(0)
+		if query.hasSeparateMakerFee {
+			transferableMakerFeeAssetAmount = query.makerFeeAsset.evaluate(o.multicallABIs, signedOrder.MakerAddress, results)
+		}
+		states.OrdersInfo[i] = orderInfo
+		states.IsValidSignature[i] = isValidSignature
+		states.FillableTakerAssetAmounts[i] = computeFillableTakerAssetAmount(signedOrder, orderInfo, transferableMakerAssetAmount, transferableMakerFeeAssetAmount, query.hasSeparateMakerFee)
+	}
+	return states, nil
+}
+
+// computeMulticallEncodedSignedOrderByteLength returns the number of bytes
+// that the given order adds to the JSON-encoded payload of a Multicall
+// request.
+func (o *OrderValidator) computeMulticallEncodedSignedOrderByteLength(signedOrder *zeroex.SignedOrder) (int, error) {
+	builder := &multicallBuilder{abis: o.multicallABIs}
+	if _, err := o.addOrderToMulticall(builder, signedOrder); err != nil {
+		return 0, err
+	}
+	emptyCallData, err := o.multicallABIs.multicall.Pack("tryAggregate", false, []multicallCall{})
+	if err != nil {
+		return 0, err
+	}
+	callData, err := o.multicallABIs.multicall.Pack("tryAggregate", false, builder.calls)
+	if err != nil {
+		return 0, err
+	}
+	// Each byte is hex-encoded in the JSON-RPC payload.
+	return 2 * (len(callData) - len(emptyCallData)), nil
+}
@@ -12,11 +12,15 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/0xProject/0x-mesh/common/requestid"
+	"github.com/0xProject/0x-mesh/common/supervisor"
 	"github.com/0xProject/0x-mesh/constants"
 	"github.com/0xProject/0x-mesh/ethereum"
 	"github.com/0xProject/0x-mesh/ethereum/wrappers"
+	"github.com/0xProject/0x-mesh/orderfilter"
 	"github.com/0xProject/0x-mesh/zeroex"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -27,9 +31,10 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-// Specifies the max number of eth_call requests we want to make concurrently.
-// Additional requests will block until an ongoing request has completed.
-const concurrencyLimit = 5
+// DefaultConcurrencyLimit is the default max number of eth_call requests we
+// want to make concurrently. Additional requests will block until an ongoing
+// request has completed. It can be changed with SetConcurrencyLimit.
+const DefaultConcurrencyLimit = 5
 
 // RejectedOrderInfo encapsulates all the needed information to understand _why_ a 0x order
 // was rejected (i.e. did not pass) order validation. Since there are many potential reasons, some
@@ -41,6 +46,27 @@ type RejectedOrderInfo struct {
 	SignedOrder *zeroex.SignedOrder `json:"signedOrder"`
 	Kind        RejectedOrderKind   `json:"kind"`
 	Status      RejectedOrderStatus `json:"status"`
+	// FieldErrors describes which fields of the order violate the order schema
+	// and how. It is only set if the order was rejected because it didn't pass
+	// the order schema (see SchemaRejectedOrderStatus).
+	FieldErrors []*orderfilter.FieldError `json:"fieldErrors,omitempty"`
+}
+
+// MarshalJSON is a custom Marshaler for RejectedOrderInfo
+func (r RejectedOrderInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		OrderHash   common.Hash                   `json:"orderHash"`
+		SignedOrder zeroex.ChecksummedSignedOrder `json:"signedOrder"`
+		Kind        RejectedOrderKind             `json:"kind"`
+		Status      RejectedOrderStatus           `json:"status"`
+		FieldErrors []*orderfilter.FieldError     `json:"fieldErrors,omitempty"`
+	}{
+		OrderHash:   r.OrderHash,
+		SignedOrder: zeroex.ChecksummedSignedOrder{SignedOrder: r.SignedOrder},
+		Kind:        r.Kind,
+		Status:      r.Status,
+		FieldErrors: r.FieldErrors,
+	})
 }
 
 // AcceptedOrderInfo represents an fillable order and how much it could be filled for
@@ -62,7 +88,7 @@ type acceptedOrderInfoJSON struct {
 func (a AcceptedOrderInfo) MarshalJSON() ([]byte, error) {
 	return json.Marshal(map[string]interface{}{
 		"orderHash":                a.OrderHash.Hex(),
-		"signedOrder":              a.SignedOrder,
+		"signedOrder":              zeroex.ChecksummedSignedOrder{SignedOrder: a.SignedOrder},
 		"fillableTakerAssetAmount": a.FillableTakerAssetAmount.String(),
 		"isNew":                    a.IsNew,
 	})
@@ -165,7 +191,7 @@ var (
 	}
 	ROMaxOrderSizeExceeded = RejectedOrderStatus{
 		Code:    "MaxOrderSizeExceeded",
-		Message: fmt.Sprintf("order exceeds the maximum encoded size of %d bytes", constants.MaxOrderSizeInBytes),
+		Message: "order exceeds the maximum encoded size configured for this node",
 	}
 	ROOrderAlreadyStoredAndUnfillable = RejectedOrderStatus{
 		Code:    "OrderAlreadyStoredAndUnfillable",
@@ -187,11 +213,99 @@ var (
 		Code:    "DatabaseFullOfOrders",
 		Message: "database is full of pinned orders and no orders can be deleted to make space (consider increasing MAX_ORDERS_IN_STORAGE)",
 	}
+	ROMaxDiskUsageExceeded = RejectedOrderStatus{
+		Code:    "MaxDiskUsageExceeded",
+		Message: "database exceeds the disk usage budget and only pinned orders are accepted until it has been pruned (consider increasing MAX_DISK_USAGE_BYTES)",
+	}
+	ROAssetDataUnsupported = RejectedOrderStatus{
+		Code:    "AssetDataUnsupported",
+		Message: "order includes assetData that cannot be validated on this chain because DevUtils is not deployed",
+	}
+	ROStaticCallFailed = RejectedOrderStatus{
+		Code:    "OrderStaticCallFailed",
+		Message: "a staticcall encoded in the order's assetData reverted or did not return the expected result",
+	}
+	ROSchemaMismatch = RejectedOrderStatus{
+		Code:    "SchemaMismatch",
+		Message: "order is well-formed but does not match the custom order filter of this node",
+	}
 )
 
 // ROInvalidSchemaCode is the RejectedOrderStatus emitted if an order doesn't conform to the order schema
 const ROInvalidSchemaCode = "InvalidSchema"
 
+// SchemaRejectedOrderStatus translates the field errors of an order which did
+// not pass the order schema (see orderfilter.FieldErrors) into the most
+// specific RejectedOrderStatus, so that clients don't need to parse the error
+// messages of the schema validator:
+//
+//   - ROIncorrectChain and ROIncorrectExchangeAddress if the order is for a
+//     different chain or exchange
+//   - ROInvalidMakerAssetData (and friends) and ROInvalidSignature if the
+//     asset data or signature is malformed
+//   - a status with the ROInvalidSchemaCode code if the order is malformed in
+//     any other way. Its message includes the given description of the errors.
+//   - ROSchemaMismatch if the order is well-formed but doesn't match the custom
+//     order schema
+//   - ROMaxExpirationExceeded if the order only expires later than the max
+//     expiration duration of the filter allows
+func SchemaRejectedOrderStatus(fieldErrors []*orderfilter.FieldError, description string) RejectedOrderStatus {
+	invalidSchema := RejectedOrderStatus{
+		Code:    ROInvalidSchemaCode,
+		Message: fmt.Sprintf("order did not pass JSON-schema validation: %s", description),
+	}
+	if len(fieldErrors) == 0 {
+		return invalidSchema
+	}
+	builtInViolations := map[string]bool{}
+	onlyExceedsMaxExpiration := true
+	for _, fieldError := range fieldErrors {
+		if fieldError.ViolatesBuiltInSchema() {
+			builtInViolations[fieldError.Pointer] = true
+		}
+		if fieldError.Constraint != orderfilter.MaxExpirationConstraint {
+			onlyExceedsMaxExpiration = false
+		}
+	}
+	if onlyExceedsMaxExpiration {
+		return ROMaxExpirationExceeded
+	}
+	if len(builtInViolations) == 0 {
+		return ROSchemaMismatch
+	}
+	// The fields are checked in order of precedence. An order for a different
+	// chain or exchange would be rejected even if it was otherwise valid.
+	for _, field := range []struct {
+		pointer string
+		status  RejectedOrderStatus
+	}{
+		{"/chainId", ROIncorrectChain},
+		{"/exchangeAddress", ROIncorrectExchangeAddress},
+	} {
+		if builtInViolations[field.pointer] {
+			return field.status
+		}
+	}
+	if len(builtInViolations) > 1 {
+		return invalidSchema
+	}
+	for pointer := range builtInViolations {
+		switch pointer {
+		case "/makerAssetData":
+			return ROInvalidMakerAssetData
+		case "/makerFeeAssetData":
+			return ROInvalidMakerFeeAssetData
+		case "/takerAssetData":
+			return ROInvalidTakerAssetData
+		case "/takerFeeAssetData":
+			return ROInvalidTakerFeeAssetData
+		case "/signature":
+			return ROInvalidSignature
+		}
+	}
+	return invalidSchema
+}
+
 // ConvertRejectOrderCodeToOrderEventEndState converts an RejectOrderCode to an OrderEventEndState type
 func ConvertRejectOrderCodeToOrderEventEndState(rejectedOrderStatus RejectedOrderStatus) (zeroex.OrderEventEndState, bool) {
 	switch rejectedOrderStatus {
@@ -203,6 +317,8 @@ func ConvertRejectOrderCodeToOrderEventEndState(rejectedOrderStatus RejectedOrde
 		return zeroex.ESOrderCancelled, true
 	case ROUnfunded:
 		return zeroex.ESOrderBecameUnfunded, true
+	case ROStaticCallFailed:
+		return zeroex.ESOrderStaticCallFailed, true
 	default:
 		// Catch-all returns Invalid OrderEventEndState
 		return zeroex.ESInvalid, false
@@ -228,6 +344,8 @@ const (
 type ValidationResults struct {
 	Accepted []*AcceptedOrderInfo `json:"accepted"`
 	Rejected []*RejectedOrderInfo `json:"rejected"`
+	// RequestID identifies the request which produced these results (if any).
+	RequestID string `json:"requestID,omitempty"`
 }
 
 // OrderValidator validates 0x orders
@@ -240,6 +358,17 @@ type OrderValidator struct {
 	chainID                      int
 	cachedFeeRecipientToEndpoint map[common.Address]string
 	contractAddresses            ethereum.ContractAddresses
+	contractCaller               bind.ContractCaller
+	staticCallExecution          StaticCallExecutionConfig
+	staticCallAllowedTargets     map[common.Address]struct{}
+	multicallABIs                *multicallABIs
+	// acceptUnrecognizedAssetProxies determines whether orders involving asset
+	// proxies that are neither supported nor registered with
+	// zeroex.RegisterAssetProxy pass offchain validation.
+	acceptUnrecognizedAssetProxies bool
+	// concurrencyLimit is the max number of eth_call requests made
+	// concurrently by BatchValidate. It is accessed atomically.
+	concurrencyLimit int32
 }
 
 // New instantiates a new order validator
@@ -257,6 +386,10 @@ func New(contractCaller bind.ContractCaller, chainID int, maxRequestContentLengt
 		return nil, err
 	}
 	assetDataDecoder := zeroex.NewAssetDataDecoder()
+	multicallABIs, err := newMulticallABIs()
+	if err != nil {
+		return nil, err
+	}
 
 	return &OrderValidator{
 		maxRequestContentLength:      maxRequestContentLength,
@@ -267,11 +400,15 @@ func New(contractCaller bind.ContractCaller, chainID int, maxRequestContentLengt
 		chainID:                      chainID,
 		cachedFeeRecipientToEndpoint: map[common.Address]string{},
 		contractAddresses:            contractAddresses,
+		contractCaller:               contractCaller,
+		staticCallAllowedTargets:     map[common.Address]struct{}{},
+		multicallABIs:                multicallABIs,
+		concurrencyLimit:             DefaultConcurrencyLimit,
 	}, nil
 }
 
 // BatchValidate retrieves all the information needed to validate the supplied orders.
-// It splits the orders into chunks of `chunkSize`, and makes no more then `concurrencyLimit`
+// It splits the orders into chunks of `chunkSize`, and makes no more then `ConcurrencyLimit()`
 // requests concurrently. If a request fails, re-attempt it up to four times before giving up.
 // If some requests fail, this method still returns whatever order information it was able to
 // retrieve up until the failure.
@@ -293,6 +430,14 @@ func (o *OrderValidator) BatchValidate(ctx context.Context, rawSignedOrders []*z
 		validationResults.Rejected = append(validationResults.Rejected, rejectedOrderInfo)
 	}
 
+	// Execute any staticcalls encoded in the assetData (if enabled)
+	signedOrders, staticCallRejectedOrderInfos := o.batchValidateStaticCalls(ctx, signedOrders, blockNumber)
+	validationResults.Rejected = append(validationResults.Rejected, staticCallRejectedOrderInfos...)
+
+	// Reject any orders that cannot be validated without DevUtils (if needed)
+	signedOrders, unsupportedRejectedOrderInfos := o.batchValidateSupportedWithoutDevUtils(signedOrders)
+	validationResults.Rejected = append(validationResults.Rejected, unsupportedRejectedOrderInfos...)
+
 	signedOrderChunks := [][]*zeroex.SignedOrder{}
 	chunkSizes := o.computeOptimalChunkSizes(signedOrders)
 	for _, chunkSize := range chunkSizes {
@@ -300,7 +445,7 @@ func (o *OrderValidator) BatchValidate(ctx context.Context, rawSignedOrders []*z
 		signedOrders = signedOrders[chunkSize:]
 	}
 
-	semaphoreChan := make(chan struct{}, concurrencyLimit)
+	semaphoreChan := make(chan struct{}, o.ConcurrencyLimit())
 	defer close(semaphoreChan)
 
 	wg := &sync.WaitGroup{}
@@ -311,16 +456,37 @@ func (o *OrderValidator) BatchValidate(ctx context.Context, rawSignedOrders []*z
 			for _, signedOrder := range signedOrders {
 				trimmedOrders = append(trimmedOrders, signedOrder.Trim())
 			}
-			signatures := [][]byte{}
-			for _, signedOrder := range signedOrders {
-				signatures = append(signatures, signedOrder.Signature)
-			}
 
 			defer wg.Done()
 
+			// If validating the chunk panics, reject its orders instead of crashing
+			// the node.
+			holdsSemaphore := false
+			defer func() {
+				if r := recover(); r != nil {
+					supervisor.ReportCrash("ordervalidator", r)
+					if holdsSemaphore {
+						<-semaphoreChan
+					}
+					for _, signedOrder := range signedOrders {
+						orderHash, err := signedOrder.ComputeOrderHash()
+						if err != nil {
+							continue
+						}
+						validationResults.Rejected = append(validationResults.Rejected, &RejectedOrderInfo{
+							OrderHash:   orderHash,
+							SignedOrder: signedOrder,
+							Kind:        MeshError,
+							Status:      ROInternalError,
+						})
+					}
+				}
+			}()
+
 			// Add one to the semaphore chan. If it already has concurrencyLimit values,
 			// the request blocks here until one frees up.
 			semaphoreChan <- struct{}{}
+			holdsSemaphore = true
 
 			// Attempt to make the eth_call request 4 times with an exponential back-off.
 			maxDuration := 4 * time.Second
@@ -341,16 +507,18 @@ func (o *OrderValidator) BatchValidate(ctx context.Context, rawSignedOrders []*z
 				}
 				opts.BlockNumber = blockNumber
 
-				results, err := o.devUtils.GetOrderRelevantStates(opts, trimmedOrders, signatures)
+				results, err := o.getOrderRelevantStates(opts, signedOrders)
 				if err != nil {
 					log.WithFields(log.Fields{
 						"error":     err.Error(),
 						"attempt":   b.Attempt(),
 						"numOrders": len(trimmedOrders),
+						"requestID": requestid.FromContext(ctx),
 					}).Info("GetOrderRelevantStates request failed")
 					d := b.Duration()
 					if d == maxDuration {
 						<-semaphoreChan
+						holdsSemaphore = false
 						var fields log.Fields
 						match, regexpErr := regexp.MatchString("abi: improperly formatted output", err.Error())
 						if regexpErr != nil {
@@ -361,11 +529,13 @@ func (o *OrderValidator) BatchValidate(ctx context.Context, rawSignedOrders []*z
 								"error":     err.Error(),
 								"numOrders": len(trimmedOrders),
 								"orders":    trimmedOrders,
+								"requestID": requestid.FromContext(ctx),
 							}
 						} else {
 							fields = log.Fields{
 								"error":     err.Error(),
 								"numOrders": len(trimmedOrders),
+								"requestID": requestid.FromContext(ctx),
 							}
 						}
 						log.WithFields(fields).Warning("Gave up on GetOrderRelevantStates request after backoff limit reached")
@@ -441,6 +611,7 @@ func (o *OrderValidator) BatchValidate(ctx context.Context, rawSignedOrders []*z
 				}
 
 				<-semaphoreChan
+				holdsSemaphore = false
 				return
 			}
 		}(signedOrders, i)
@@ -630,12 +801,19 @@ func (o *OrderValidator) batchValidateSoftCancelled(ctx context.Context, signedO
 // - `MakerAssetAmount` and `TakerAssetAmount` cannot be 0
 // - `AssetData` fields contain properly encoded, and currently supported assetData (ERC20 & ERC721 for now)
 // - `Signature` contains a properly encoded 0x signature
+// - EIP712 and EthSign signatures were produced by the maker
 // - Validate that order isn't expired
 // Returns the signedOrders that are off-chain valid along with an array of orderInfo for the rejected orders
 func (o *OrderValidator) BatchOffchainValidation(signedOrders []*zeroex.SignedOrder) ([]*zeroex.SignedOrder, []*RejectedOrderInfo) {
 	rejectedOrderInfos := []*RejectedOrderInfo{}
 	offchainValidSignedOrders := []*zeroex.SignedOrder{}
-	for _, signedOrder := range signedOrders {
+	// Verifying the signatures of all orders at once is much faster than
+	// verifying them one by one. It also computes and caches the order hashes.
+	signatureVerifications, err := zeroex.BatchVerifyOrderSignatures(signedOrders, zeroex.BatchVerifyOptions{})
+	if err != nil {
+		log.WithError(err).Error("Verifying order signatures failed unexpectedly")
+	}
+	for i, signedOrder := range signedOrders {
 		orderHash, err := signedOrder.ComputeOrderHash()
 		if err != nil {
 			log.WithError(err).WithField("signedOrder", signedOrder).Error("Computing the orderHash failed unexpectedly")
@@ -692,7 +870,10 @@ func (o *OrderValidator) BatchOffchainValidation(signedOrders []*zeroex.SignedOr
 			continue
 		}
 
-		if len(signedOrder.MakerFeeAssetData) != 0 {
+		// Fees may be paid in any supported asset, not just ZRX. Empty fee
+		// assetData is only allowed if there is no fee, since the Exchange
+		// contract cannot transfer a fee without knowing the asset.
+		if len(signedOrder.MakerFeeAssetData) != 0 || signedOrder.MakerFee.Sign() > 0 {
 			isMakerFeeAssetDataSupported := o.isSupportedAssetData(signedOrder.MakerFeeAssetData)
 			if !isMakerFeeAssetDataSupported {
 				rejectedOrderInfos = append(rejectedOrderInfos, &RejectedOrderInfo{
@@ -704,7 +885,7 @@ func (o *OrderValidator) BatchOffchainValidation(signedOrders []*zeroex.SignedOr
 				continue
 			}
 		}
-		if len(signedOrder.TakerFeeAssetData) != 0 {
+		if len(signedOrder.TakerFeeAssetData) != 0 || signedOrder.TakerFee.Sign() > 0 {
 			isTakerFeeAssetDataSupported := o.isSupportedAssetData(signedOrder.TakerFeeAssetData)
 			if !isTakerFeeAssetDataSupported {
 				rejectedOrderInfos = append(rejectedOrderInfos, &RejectedOrderInfo{
@@ -718,7 +899,7 @@ func (o *OrderValidator) BatchOffchainValidation(signedOrders []*zeroex.SignedOr
 		}
 
 		isSupportedSignature := isSupportedSignature(signedOrder.Signature, orderHash)
-		if !isSupportedSignature {
+		if !isSupportedSignature || (signatureVerifications != nil && signatureVerifications[i].Result == zeroex.SVInvalid) {
 			rejectedOrderInfos = append(rejectedOrderInfos, &RejectedOrderInfo{
 				OrderHash:   orderHash,
 				SignedOrder: signedOrder,
@@ -734,9 +915,38 @@ func (o *OrderValidator) BatchOffchainValidation(signedOrders []*zeroex.SignedOr
 	return offchainValidSignedOrders, rejectedOrderInfos
 }
 
+// SetAcceptUnrecognizedAssetProxies determines whether orders involving asset
+// proxies that are neither supported by Mesh nor registered with
+// zeroex.RegisterAssetProxy are accepted. If they are, their fillability is
+// still checked on-chain, but it is up to the caller to decide how to watch
+// them since their balances can't be tracked.
+func (o *OrderValidator) SetAcceptUnrecognizedAssetProxies(accept bool) {
+	o.acceptUnrecognizedAssetProxies = accept
+}
+
+// ConcurrencyLimit returns the max number of eth_call requests made
+// concurrently by BatchValidate.
+func (o *OrderValidator) ConcurrencyLimit() int {
+	return int(atomic.LoadInt32(&o.concurrencyLimit))
+}
+
+// SetConcurrencyLimit changes the max number of eth_call requests made
+// concurrently by BatchValidate. It can be called while orders are being
+// validated, in which case it only applies to the following calls to
+// BatchValidate. Limits below 1 are treated as 1.
+func (o *OrderValidator) SetConcurrencyLimit(limit int) {
+	if limit < 1 {
+		limit = 1
+	}
+	atomic.StoreInt32(&o.concurrencyLimit, int32(limit))
+}
+
 func (o *OrderValidator) isSupportedAssetData(assetData []byte) bool {
 	assetDataName, err := o.assetDataDecoder.GetName(assetData)
 	if err != nil {
+		if _, ok := err.(zeroex.UnrecognizedAssetDataError); ok {
+			return o.acceptUnrecognizedAssetProxies
+		}
 		return false
 	}
 	switch assetDataName {
@@ -771,6 +981,16 @@ func (o *OrderValidator) isSupportedAssetData(assetData []byte) bool {
 		if err != nil {
 			return false
 		}
+		// The MultiAssetProxy reverts unless there is exactly one amount for
+		// each nested assetData.
+		if len(decodedAssetData.Amounts) != len(decodedAssetData.NestedAssetData) {
+			return false
+		}
+		for _, nestedAssetData := range decodedAssetData.NestedAssetData {
+			if !o.isSupportedAssetData(nestedAssetData) {
+				return false
+			}
+		}
 	case "ERC20Bridge":
 		var decodedAssetData zeroex.ERC20BridgeAssetData
 		err := o.assetDataDecoder.Decode(assetData, &decodedAssetData)
@@ -784,12 +1004,28 @@ func (o *OrderValidator) isSupportedAssetData(assetData []byte) bool {
 			return false
 		}
 	default:
-		return false
+		if !o.assetDataDecoder.IsCustomAssetProxy(assetData) {
+			return false
+		}
+		nestedAssetDatas, err := o.assetDataDecoder.DecodeNestedAssetData(assetData)
+		if err != nil {
+			return false
+		}
+		for _, nestedAssetData := range nestedAssetDatas {
+			if !o.isSupportedAssetData(nestedAssetData) {
+				return false
+			}
+		}
 	}
 	return true
 }
 
 func (o *OrderValidator) isSupportedStaticCallData(staticCallAssetData zeroex.StaticCallAssetData) bool {
+	// If staticcall execution is enabled, any staticcall to an allowed target
+	// is supported since we check the result of the call during validation.
+	if o.isAllowedStaticCallTarget(staticCallAssetData.StaticCallTargetAddress) {
+		return true
+	}
 	staticCallDataName, err := o.assetDataDecoder.GetName(staticCallAssetData.StaticCallData)
 	if err != nil {
 		return false
@@ -838,6 +1074,9 @@ const emptyGetOrderRelevantStatesCallDataByteLength = 268
 const jsonRPCPayloadByteLength = 444
 
 func (o *OrderValidator) computeABIEncodedSignedOrderByteLength(signedOrder *zeroex.SignedOrder) (int, error) {
+	if !o.isDevUtilsAvailable() {
+		return o.computeMulticallEncodedSignedOrderByteLength(signedOrder)
+	}
 	trimmedOrder := signedOrder.Trim()
 	data, err := o.devUtilsABI.Pack(
 		"getOrderRelevantStates",
@@ -895,17 +1134,16 @@ func isSupportedSignature(signature []byte, orderHash common.Hash) bool {
 		return false
 
 	case zeroex.EIP712Signature:
+		// The signer is verified by zeroex.BatchVerifyOrderSignatures.
 		if len(signature) != 66 {
 			return false
 		}
-		// TODO(fabio): Do further validation by splitting into r,s,v and do ECRecover
 
 	case zeroex.EthSignSignature:
+		// The signer is verified by zeroex.BatchVerifyOrderSignatures.
 		if len(signature) != 66 {
 			return false
 		}
-		// TODO(fabio): Do further validation by splitting into r,s,v, add prefix to hash
-		// and do ECRecover
 
 	case zeroex.ValidatorSignature:
 		if len(signature) < 21 {
@@ -2,7 +2,11 @@
 
 package ordervalidator
 
-import "syscall/js"
+import (
+	"syscall/js"
+
+	"github.com/0xProject/0x-mesh/packages/browser/go/jsutil"
+)
 
 func (v ValidationResults) JSValue() js.Value {
 	accepted := make([]interface{}, len(v.Accepted))
@@ -14,8 +18,9 @@ func (v ValidationResults) JSValue() js.Value {
 		rejected[i] = info
 	}
 	return js.ValueOf(map[string]interface{}{
-		"accepted": accepted,
-		"rejected": rejected,
+		"accepted":  accepted,
+		"rejected":  rejected,
+		"requestID": v.RequestID,
 	})
 }
 
@@ -29,12 +34,20 @@ func (a AcceptedOrderInfo) JSValue() js.Value {
 }
 
 func (r RejectedOrderInfo) JSValue() js.Value {
-	return js.ValueOf(map[string]interface{}{
+	value := map[string]interface{}{
 		"orderHash":   r.OrderHash.String(),
 		"signedOrder": r.SignedOrder.JSValue(),
 		"kind":        string(r.Kind),
 		"status":      r.Status.JSValue(),
-	})
+	}
+	if len(r.FieldErrors) > 0 {
+		// Expected and Actual can be any JSON value, so the field errors are
+		// converted via JSON.
+		if fieldErrors, err := jsutil.InefficientlyConvertToJS(r.FieldErrors); err == nil {
+			value["fieldErrors"] = fieldErrors
+		}
+	}
+	return js.ValueOf(value)
 }
 
 func (s RejectedOrderStatus) JSValue() js.Value {
@@ -6,6 +6,7 @@ package ordervalidator
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"math/big"
@@ -20,9 +21,11 @@ import (
 	"github.com/0xProject/0x-mesh/ethereum/ratelimit"
 	"github.com/0xProject/0x-mesh/ethereum/signer"
 	"github.com/0xProject/0x-mesh/ethereum/wrappers"
+	"github.com/0xProject/0x-mesh/orderfilter"
 	"github.com/0xProject/0x-mesh/scenario"
 	"github.com/0xProject/0x-mesh/scenario/orderopts"
 	"github.com/0xProject/0x-mesh/zeroex"
+	goethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -47,6 +50,9 @@ var (
 	malformedAssetData   = []byte("9HJhsAAAAAAAAAAAAAAAAInSSmtMyxtvqiYl")
 	malformedSignature   = []byte("9HJhsAAAAAAAAAAAAAAAAInSSmtMyxtvqiYl")
 	multiAssetAssetData  = common.Hex2Bytes("94cfcdd7000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000000000000000000000000000000000c000000000000000000000000000000000000000000000000000000000000000030000000000000000000000000000000000000000000000000000000000000046000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000000000120000000000000000000000000000000000000000000000000000000000000003000000000000000000000000000000000000000000000000000000000000006000000000000000000000000000000000000000000000000000000000000000c000000000000000000000000000000000000000000000000000000000000001400000000000000000000000000000000000000000000000000000000000000024f47261b00000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c48000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000044025717920000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c480000000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000204a7cb5fb70000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c480000000000000000000000000000000000000000000000000000000000000080000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000001800000000000000000000000000000000000000000000000000000000000000003000000000000000000000000000000000000000000000000000000000000006400000000000000000000000000000000000000000000000000000000000003e90000000000000000000000000000000000000000000000000000000000002711000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000000000000000000000000000000000000c800000000000000000000000000000000000000000000000000000000000007d10000000000000000000000000000000000000000000000000000000000004e210000000000000000000000000000000000000000000000000000000000000044025717920000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c4800000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000")
+	// mismatchedMultiAssetAssetData has one amount for two nested assetData (ZRX
+	// and WETH).
+	mismatchedMultiAssetAssetData = common.Hex2Bytes("94cfcdd700000000000000000000000000000000000000000000000000000000000000400000000000000000000000000000000000000000000000000000000000000080000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000024f47261b0000000000000000000000000871dd7c2b4b25e1aa18728e9d5f2af4c4e431f5c000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000024f47261b00000000000000000000000000b1ba0af832d7c05fd64161e0db78e85978e808200000000000000000000000000000000000000000000000000000000")
 )
 
 // Since these tests must be run sequentially, we don't want them to run as part of
@@ -112,6 +118,16 @@ func TestBatchValidateOffChainCases(t *testing.T) {
 			SignedOrder: scenario.NewSignedTestOrder(t, orderopts.MakerAssetData(multiAssetAssetData)),
 			IsValid:     true,
 		},
+		testCase{
+			SignedOrder:                 scenario.NewSignedTestOrder(t, orderopts.MakerAssetData(mismatchedMultiAssetAssetData)),
+			IsValid:                     false,
+			ExpectedRejectedOrderStatus: ROInvalidMakerAssetData,
+		},
+		testCase{
+			SignedOrder:                 scenario.NewSignedTestOrder(t, orderopts.TakerAssetData(mismatchedMultiAssetAssetData)),
+			IsValid:                     false,
+			ExpectedRejectedOrderStatus: ROInvalidTakerAssetData,
+		},
 		testCase{
 			SignedOrder:                 scenario.NewSignedTestOrder(t, orderopts.MakerAssetData(malformedAssetData)),
 			IsValid:                     false,
@@ -132,6 +148,29 @@ func TestBatchValidateOffChainCases(t *testing.T) {
 			IsValid:                     false,
 			ExpectedRejectedOrderStatus: ROInvalidTakerAssetData,
 		},
+		testCase{
+			SignedOrder: scenario.NewSignedTestOrder(t, orderopts.MakerFeeAssetData(scenario.WETHAssetData), orderopts.MakerFee(big.NewInt(1))),
+			IsValid:     true,
+		},
+		testCase{
+			SignedOrder: scenario.NewSignedTestOrder(t, orderopts.TakerFeeAssetData(scenario.WETHAssetData), orderopts.TakerFee(big.NewInt(1))),
+			IsValid:     true,
+		},
+		testCase{
+			SignedOrder:                 scenario.NewSignedTestOrder(t, orderopts.MakerFeeAssetData(constants.NullBytes), orderopts.MakerFee(big.NewInt(1))),
+			IsValid:                     false,
+			ExpectedRejectedOrderStatus: ROInvalidMakerFeeAssetData,
+		},
+		testCase{
+			SignedOrder:                 scenario.NewSignedTestOrder(t, orderopts.TakerFeeAssetData(constants.NullBytes), orderopts.TakerFee(big.NewInt(1))),
+			IsValid:                     false,
+			ExpectedRejectedOrderStatus: ROInvalidTakerFeeAssetData,
+		},
+		testCase{
+			SignedOrder:                 scenario.NewSignedTestOrder(t, orderopts.TakerFeeAssetData(malformedAssetData)),
+			IsValid:                     false,
+			ExpectedRejectedOrderStatus: ROInvalidTakerFeeAssetData,
+		},
 		testCase{
 			SignedOrder:                 signedOrderWithCustomSignature(t, malformedSignature),
 			IsValid:                     false,
@@ -290,6 +329,115 @@ func TestBatchValidateMaxGasPriceOrder(t *testing.T) {
 	}
 }
 
+func TestBatchValidateStaticCallExecution(t *testing.T) {
+	if !serialTestsEnabled {
+		t.Skip("Serial tests (tests which cannot run in parallel) are disabled. You can enable them with the --serial flag")
+	}
+
+	orderValidator, err := New(ethRPCClient, constants.TestChainID, constants.TestMaxContentLength, ganacheAddresses)
+	require.NoError(t, err)
+	orderValidator.SetStaticCallExecution(StaticCallExecutionConfig{
+		Enabled:        true,
+		AllowedTargets: []common.Address{ganacheAddresses.MaximumGasPrice},
+	})
+
+	// Replace the expected return hash of the staticcall so that the result of
+	// executing it will not match.
+	mismatchedReturnHashStaticCallData := make([]byte, len(checkGasPriceDefaultStaticCallData))
+	copy(mismatchedReturnHashStaticCallData, checkGasPriceDefaultStaticCallData)
+	copy(mismatchedReturnHashStaticCallData[68:100], common.HexToHash("0x1").Bytes())
+
+	testCases := []struct {
+		staticCallAssetData []byte
+		isValid             bool
+	}{
+		{
+			staticCallAssetData: checkGasPriceDefaultStaticCallData,
+			isValid:             true,
+		},
+		{
+			staticCallAssetData: mismatchedReturnHashStaticCallData,
+			isValid:             false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		teardownSubTest := setupSubTest(t)
+
+		signedOrder := scenario.NewSignedTestOrder(t, orderopts.SetupMakerState(true), orderopts.MakerFeeAssetData(testCase.staticCallAssetData))
+		signedOrders := []*zeroex.SignedOrder{
+			signedOrder,
+		}
+
+		ctx := context.Background()
+		latestBlock, err := ethRPCClient.HeaderByNumber(ctx, nil)
+		require.NoError(t, err)
+		validationResults := orderValidator.BatchValidate(ctx, signedOrders, areNewOrders, latestBlock.Number)
+		if testCase.isValid {
+			assert.Len(t, validationResults.Accepted, 1)
+			require.Len(t, validationResults.Rejected, 0)
+		} else {
+			assert.Len(t, validationResults.Accepted, 0)
+			require.Len(t, validationResults.Rejected, 1)
+			assert.Equal(t, ROStaticCallFailed, validationResults.Rejected[0].Status)
+		}
+
+		teardownSubTest(t)
+	}
+}
+
+// errContractCaller is a bind.ContractCaller whose calls all fail with err.
+type errContractCaller struct {
+	err error
+}
+
+func (c *errContractCaller) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return nil, c.err
+}
+
+func (c *errContractCaller) CallContract(ctx context.Context, call goethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return nil, c.err
+}
+
+func TestBatchValidateStaticCallErrors(t *testing.T) {
+	testCases := []struct {
+		err            error
+		expectedKind   RejectedOrderKind
+		expectedStatus RejectedOrderStatus
+	}{
+		{
+			err:            errors.New("dial tcp 127.0.0.1:8545: connect: connection refused"),
+			expectedKind:   MeshError,
+			expectedStatus: ROEthRPCRequestFailed,
+		},
+		{
+			err:            errors.New("VM Exception while processing transaction: revert"),
+			expectedKind:   ZeroExValidation,
+			expectedStatus: ROStaticCallFailed,
+		},
+		{
+			err:            errors.New("execution reverted"),
+			expectedKind:   ZeroExValidation,
+			expectedStatus: ROStaticCallFailed,
+		},
+	}
+
+	for _, testCase := range testCases {
+		orderValidator, err := New(&errContractCaller{err: testCase.err}, constants.TestChainID, constants.TestMaxContentLength, ganacheAddresses)
+		require.NoError(t, err)
+		orderValidator.SetStaticCallExecution(StaticCallExecutionConfig{
+			Enabled: true,
+		})
+
+		signedOrder := scenario.NewSignedTestOrder(t, orderopts.MakerFeeAssetData(checkGasPriceDefaultStaticCallData))
+		validSignedOrders, rejectedOrderInfos := orderValidator.batchValidateStaticCalls(context.Background(), []*zeroex.SignedOrder{signedOrder}, big.NewInt(1))
+		assert.Empty(t, validSignedOrders, testCase.err.Error())
+		require.Len(t, rejectedOrderInfos, 1, testCase.err.Error())
+		assert.Equal(t, testCase.expectedKind, rejectedOrderInfos[0].Kind, testCase.err.Error())
+		assert.Equal(t, testCase.expectedStatus, rejectedOrderInfos[0].Status, testCase.err.Error())
+	}
+}
+
 func TestBatchValidateSignatureInvalid(t *testing.T) {
 	signedOrder := signedOrderWithCustomSignature(t, malformedSignature)
 	signedOrders := []*zeroex.SignedOrder{
@@ -435,6 +583,65 @@ func TestComputeOptimalChunkSizesMultiAssetOrder(t *testing.T) {
 	assert.Equal(t, expectedChunkSizes, chunkSizes)
 }
 
+func TestBatchValidateAssetDataUnsupportedWithoutDevUtils(t *testing.T) {
+	contractAddresses := ganacheAddresses
+	contractAddresses.DevUtils = constants.NullAddress
+	contractAddresses.Multicall = common.HexToAddress("0x5ba1e12693dc8f9c48aad8770482f4739beed696")
+	orderValidator, err := New(ethRPCClient, constants.TestChainID, constants.TestMaxContentLength, contractAddresses)
+	require.NoError(t, err)
+
+	signedOrder := scenario.NewSignedTestOrder(t)
+	// multiAssetAssetData includes ERC1155 assets which are not supported without
+	// DevUtils.
+	signedMultiAssetOrder := scenario.NewSignedTestOrder(t, orderopts.MakerAssetData(multiAssetAssetData))
+	multiAssetOrderHash, err := signedMultiAssetOrder.ComputeOrderHash()
+	require.NoError(t, err)
+
+	validSignedOrders, rejectedOrderInfos := orderValidator.batchValidateSupportedWithoutDevUtils([]*zeroex.SignedOrder{signedOrder, signedMultiAssetOrder})
+	assert.Equal(t, []*zeroex.SignedOrder{signedOrder}, validSignedOrders)
+	require.Len(t, rejectedOrderInfos, 1)
+	assert.Equal(t, multiAssetOrderHash, rejectedOrderInfos[0].OrderHash)
+	assert.Equal(t, ROAssetDataUnsupported, rejectedOrderInfos[0].Status)
+}
+
+func TestAddTransferableQueryMismatchedMultiAsset(t *testing.T) {
+	contractAddresses := ganacheAddresses
+	contractAddresses.DevUtils = constants.NullAddress
+	orderValidator, err := New(ethRPCClient, constants.TestChainID, constants.TestMaxContentLength, contractAddresses)
+	require.NoError(t, err)
+
+	// evaluate would index out of range if the query was built for
+	// MultiAsset assetData with fewer amounts than nested assetData.
+	builder := &multicallBuilder{abis: orderValidator.multicallABIs}
+	_, err = orderValidator.addTransferableQuery(builder, constants.GanacheAccount0, mismatchedMultiAssetAssetData)
+	assert.Error(t, err)
+}
+
+func TestComputeFillableTakerAssetAmount(t *testing.T) {
+	signedOrder := scenario.NewSignedTestOrder(t,
+		orderopts.MakerAssetAmount(big.NewInt(100)),
+		orderopts.TakerAssetAmount(big.NewInt(50)),
+	)
+	orderInfo := wrappers.OrderInfo{
+		OrderStatus:                 uint8(zeroex.OSFillable),
+		OrderTakerAssetFilledAmount: big.NewInt(10),
+	}
+
+	// Enough balance to fill the remaining amount.
+	fillable := computeFillableTakerAssetAmount(signedOrder, orderInfo, big.NewInt(1000), big.NewInt(0), false)
+	assert.Equal(t, big.NewInt(40), fillable)
+
+	// Balance only covers part of the order.
+	fillable = computeFillableTakerAssetAmount(signedOrder, orderInfo, big.NewInt(30), big.NewInt(0), false)
+	assert.Equal(t, big.NewInt(15), fillable)
+
+	// Orders which are not fillable have a fillable amount of zero.
+	expiredOrderInfo := orderInfo
+	expiredOrderInfo.OrderStatus = uint8(zeroex.OSExpired)
+	fillable = computeFillableTakerAssetAmount(signedOrder, expiredOrderInfo, big.NewInt(1000), big.NewInt(0), false)
+	assert.Equal(t, big.NewInt(0), fillable)
+}
+
 func setupSubTest(t *testing.T) func(t *testing.T) {
 	blockchainLifecycle.Start(t)
 	return func(t *testing.T) {
@@ -451,3 +658,46 @@ func signedOrderWithCustomSignature(t *testing.T, signature []byte) *zeroex.Sign
 func copyOrder(order zeroex.Order) zeroex.Order {
 	return order
 }
+
+func TestSchemaRejectedOrderStatus(t *testing.T) {
+	t.Parallel()
+
+	wrongChain := &orderfilter.FieldError{Pointer: "/chainId", Constraint: "const", Expected: 1337.0, Actual: 1.0}
+	wrongExchange := &orderfilter.FieldError{Pointer: "/exchangeAddress", Constraint: "enum"}
+	invalidMakerAssetData := &orderfilter.FieldError{Pointer: "/makerAssetData", Constraint: "pattern", Expected: "^0x(([0-9a-fA-F][0-9a-fA-F])+)?$", Actual: "0x1"}
+	invalidSignature := &orderfilter.FieldError{Pointer: "/signature", Constraint: "pattern", Expected: "^0x(([0-9a-fA-F][0-9a-fA-F])+)?$", Actual: "hi"}
+	missingSalt := &orderfilter.FieldError{Pointer: "/salt", Constraint: "required"}
+	invalidTakerFee := &orderfilter.FieldError{Pointer: "/takerFee", Constraint: "anyOf", Actual: "-1"}
+	customConst := &orderfilter.FieldError{Pointer: "/senderAddress", Constraint: "const", Expected: "0x00000000000000000000000000000000ba5eba11"}
+	customPattern := &orderfilter.FieldError{Pointer: "/makerAssetData", Constraint: "pattern", Expected: "^0xf47261b0"}
+	expiresTooLate := &orderfilter.FieldError{Pointer: "/expirationTimeSeconds", Constraint: orderfilter.MaxExpirationConstraint}
+
+	testCases := []struct {
+		note           string
+		fieldErrors    []*orderfilter.FieldError
+		expectedStatus RejectedOrderStatus
+	}{
+		{"wrong chain", []*orderfilter.FieldError{wrongChain, invalidSignature}, ROIncorrectChain},
+		{"wrong exchange", []*orderfilter.FieldError{wrongExchange, customConst}, ROIncorrectExchangeAddress},
+		{"invalid maker asset data", []*orderfilter.FieldError{invalidMakerAssetData, customConst}, ROInvalidMakerAssetData},
+		{"invalid signature", []*orderfilter.FieldError{invalidSignature}, ROInvalidSignature},
+		{"only custom violations", []*orderfilter.FieldError{customConst, customPattern}, ROSchemaMismatch},
+		{"only expires too late", []*orderfilter.FieldError{expiresTooLate}, ROMaxExpirationExceeded},
+		{"custom violation and expires too late", []*orderfilter.FieldError{customConst, expiresTooLate}, ROSchemaMismatch},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expectedStatus, SchemaRejectedOrderStatus(tc.fieldErrors, "description"), tc.note)
+	}
+
+	// Malformed orders are rejected with the generic InvalidSchema code.
+	for _, fieldErrors := range [][]*orderfilter.FieldError{
+		nil,
+		{missingSalt},
+		{invalidTakerFee, customConst},
+		{invalidMakerAssetData, invalidSignature},
+	} {
+		status := SchemaRejectedOrderStatus(fieldErrors, "description")
+		assert.Equal(t, ROInvalidSchemaCode, status.Code)
+		assert.Equal(t, "order did not pass JSON-schema validation: description", status.Message)
+	}
+}
@@ -0,0 +1,197 @@
+package ordervalidator
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"regexp"
+
+	"github.com/0xProject/0x-mesh/zeroex"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultStaticCallGasLimit is the gas limit used when executing staticcalls
+// if StaticCallExecutionConfig.GasLimit is not set.
+const defaultStaticCallGasLimit = 100000
+
+// staticCallExecutionErrorRegex matches the errors that Ethereum RPC endpoints
+// return when a call fails during execution (e.g. because it reverts or runs
+// out of gas), as opposed to errors which mean that the request itself failed.
+var staticCallExecutionErrorRegex = regexp.MustCompile(`(?i)revert|VM execution error|VM Exception|out of gas|invalid opcode|invalid jump`)
+
+// StaticCallExecutionConfig configures the optional execution of StaticCall
+// asset data during order validation.
+type StaticCallExecutionConfig struct {
+	// Enabled determines whether staticcalls are executed. If false, only the
+	// known `checkGasPrice` staticcall is supported and it is never executed.
+	Enabled bool
+	// GasLimit is the maximum amount of gas a single staticcall may use.
+	// Defaults to 100,000.
+	GasLimit uint64
+	// AllowedTargets is the set of contract addresses that staticcalls may
+	// target. Orders with StaticCall asset data targeting any other address
+	// are rejected. The MaximumGasPrice contract is always allowed.
+	AllowedTargets []common.Address
+}
+
+// SetStaticCallExecution enables or disables the execution of StaticCall
+// asset data during validation. It should be called before the OrderValidator
+// is used.
+func (o *OrderValidator) SetStaticCallExecution(config StaticCallExecutionConfig) {
+	if config.GasLimit == 0 {
+		config.GasLimit = defaultStaticCallGasLimit
+	}
+	allowedTargets := map[common.Address]struct{}{}
+	for _, target := range config.AllowedTargets {
+		allowedTargets[target] = struct{}{}
+	}
+	o.staticCallExecution = config
+	o.staticCallAllowedTargets = allowedTargets
+}
+
+// StaticCallExecutionEnabled returns true if StaticCall asset data is executed
+// during validation. If so, orders involving StaticCall asset data should be
+// revalidated on every block since the result of a staticcall can change
+// without any events being emitted.
+func (o *OrderValidator) StaticCallExecutionEnabled() bool {
+	return o.staticCallExecution.Enabled
+}
+
+// HasStaticCallAssetData returns true if any of the asset data fields of the
+// given order (including nested MultiAsset data) encodes a staticcall.
+func (o *OrderValidator) HasStaticCallAssetData(signedOrder *zeroex.SignedOrder) bool {
+	return len(o.findStaticCalls(signedOrder)) > 0
+}
+
+func (o *OrderValidator) isAllowedStaticCallTarget(target common.Address) bool {
+	if !o.staticCallExecution.Enabled {
+		return false
+	}
+	_, found := o.staticCallAllowedTargets[target]
+	return found
+}
+
+// findStaticCalls returns all the StaticCall asset data contained in the
+// given order.
+func (o *OrderValidator) findStaticCalls(signedOrder *zeroex.SignedOrder) []zeroex.StaticCallAssetData {
+	staticCalls := []zeroex.StaticCallAssetData{}
+	for _, assetData := range [][]byte{
+		signedOrder.MakerAssetData,
+		signedOrder.TakerAssetData,
+		signedOrder.MakerFeeAssetData,
+		signedOrder.TakerFeeAssetData,
+	} {
+		staticCalls = append(staticCalls, o.findStaticCallsInAssetData(assetData)...)
+	}
+	return staticCalls
+}
+
+func (o *OrderValidator) findStaticCallsInAssetData(assetData []byte) []zeroex.StaticCallAssetData {
+	if len(assetData) == 0 {
+		return nil
+	}
+	assetDataName, err := o.assetDataDecoder.GetName(assetData)
+	if err != nil {
+		return nil
+	}
+	switch assetDataName {
+	case "StaticCall":
+		var decodedAssetData zeroex.StaticCallAssetData
+		if err := o.assetDataDecoder.Decode(assetData, &decodedAssetData); err != nil {
+			return nil
+		}
+		return []zeroex.StaticCallAssetData{decodedAssetData}
+	case "MultiAsset":
+		var decodedAssetData zeroex.MultiAssetData
+		if err := o.assetDataDecoder.Decode(assetData, &decodedAssetData); err != nil {
+			return nil
+		}
+		staticCalls := []zeroex.StaticCallAssetData{}
+		for _, nestedAssetData := range decodedAssetData.NestedAssetData {
+			staticCalls = append(staticCalls, o.findStaticCallsInAssetData(nestedAssetData)...)
+		}
+		return staticCalls
+	default:
+		return nil
+	}
+}
+
+// batchValidateStaticCalls executes the staticcalls encoded in the asset data
+// of each order at the given block number. Orders for which any staticcall
+// reverts or returns data that does not match the expected hash are rejected.
+// Orders for which a staticcall could not be executed because the Ethereum RPC
+// request failed are rejected with ROEthRPCRequestFailed so that their state
+// is left as it is. It is a no-op if staticcall execution is disabled.
+func (o *OrderValidator) batchValidateStaticCalls(ctx context.Context, signedOrders []*zeroex.SignedOrder, blockNumber *big.Int) ([]*zeroex.SignedOrder, []*RejectedOrderInfo) {
+	if !o.staticCallExecution.Enabled {
+		return signedOrders, nil
+	}
+	rejectedOrderInfos := []*RejectedOrderInfo{}
+	validSignedOrders := []*zeroex.SignedOrder{}
+	for _, signedOrder := range signedOrders {
+		orderHash, err := signedOrder.ComputeOrderHash()
+		if err != nil {
+			log.WithError(err).WithField("signedOrder", signedOrder).Error("Computing the orderHash failed unexpectedly")
+		}
+		isValid := true
+		var requestErr error
+		for _, staticCall := range o.findStaticCalls(signedOrder) {
+			isValid, requestErr = o.executeStaticCall(ctx, staticCall, blockNumber)
+			if requestErr != nil || !isValid {
+				break
+			}
+		}
+		if requestErr != nil {
+			rejectedOrderInfos = append(rejectedOrderInfos, &RejectedOrderInfo{
+				OrderHash:   orderHash,
+				SignedOrder: signedOrder,
+				Kind:        MeshError,
+				Status:      ROEthRPCRequestFailed,
+			})
+			continue
+		}
+		if !isValid {
+			rejectedOrderInfos = append(rejectedOrderInfos, &RejectedOrderInfo{
+				OrderHash:   orderHash,
+				SignedOrder: signedOrder,
+				Kind:        ZeroExValidation,
+				Status:      ROStaticCallFailed,
+			})
+			continue
+		}
+		validSignedOrders = append(validSignedOrders, signedOrder)
+	}
+	return validSignedOrders, rejectedOrderInfos
+}
+
+// executeStaticCall returns true if the staticcall succeeds and the hash of the
+// data it returns matches the expected hash. This mirrors the check performed
+// by the StaticCallProxy contract. It returns an error if the staticcall could
+// not be executed because the Ethereum RPC request failed.
+func (o *OrderValidator) executeStaticCall(ctx context.Context, staticCall zeroex.StaticCallAssetData, blockNumber *big.Int) (bool, error) {
+	target := staticCall.StaticCallTargetAddress
+	msg := ethereum.CallMsg{
+		To:   &target,
+		Gas:  o.staticCallExecution.GasLimit,
+		Data: staticCall.StaticCallData,
+	}
+	result, err := o.contractCaller.CallContract(ctx, msg, blockNumber)
+	if err != nil {
+		if !staticCallExecutionErrorRegex.MatchString(err.Error()) {
+			log.WithFields(log.Fields{
+				"error":  err.Error(),
+				"target": target.Hex(),
+			}).Info("staticcall request failed during order validation")
+			return false, err
+		}
+		log.WithFields(log.Fields{
+			"error":  err.Error(),
+			"target": target.Hex(),
+		}).Debug("staticcall failed during order validation")
+		return false, nil
+	}
+	return bytes.Equal(crypto.Keccak256(result), staticCall.ExpectedReturnHashData[:]), nil
+}
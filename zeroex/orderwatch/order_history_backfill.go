@@ -0,0 +1,253 @@
+package orderwatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/0xProject/0x-mesh/db"
+	"github.com/0xProject/0x-mesh/ethereum/blockwatch"
+	"github.com/0xProject/0x-mesh/meshdb"
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/0xProject/0x-mesh/zeroex/orderwatch/decoder"
+	"github.com/ethereum/go-ethereum/common"
+	logger "github.com/sirupsen/logrus"
+)
+
+// OrderHistoryBackfillResults describes the outcome of
+// BackfillOrderStateHistory.
+type OrderHistoryBackfillResults struct {
+	// FromBlock and ToBlock are the first and last block which were replayed.
+	// FromBlock can be later than the requested block if the requested block is
+	// older than the retention period of the order state history.
+	FromBlock int
+	ToBlock   int
+	// NumOrders is the number of orders in the database whose history was
+	// reconstructed.
+	NumOrders int
+	// NumContractEvents is the number of Fill, Cancel and CancelUpTo events
+	// which affected these orders.
+	NumContractEvents int
+	// NumRecordsInserted is the number of records which were added to the
+	// order state history. Records which were already stored (e.g. by a
+	// previous backfill or because the blocks were watched live) are kept.
+	NumRecordsInserted int
+}
+
+// orderHistoryBackfillState tracks the orders whose history is reconstructed
+// and their remaining amount while the historical blocks are replayed.
+type orderHistoryBackfillState struct {
+	orders         map[common.Hash]*meshdb.Order
+	ordersByMaker  map[common.Address][]*meshdb.Order
+	remainingTaker map[common.Hash]*big.Int
+}
+
+// BackfillOrderStateHistory reconstructs the fill and cancel history of all
+// orders that are currently in the database by replaying the Exchange events of
+// the historical blocks from fromBlock up to the latest block processed by the
+// Watcher. The reconstructed state transitions are added to the order state
+// history.
+//
+// The backfill doesn't affect the live state: orders are not updated, no order
+// events are emitted and records that are already in the order state history
+// are not replaced. Since only Exchange events are replayed, the fillable
+// amount of the reconstructed records only accounts for fills and
+// cancellations, not for the balances and allowances of makers. Fills before
+// fromBlock are only accounted for if the order state history already has a
+// record for the order before fromBlock.
+func (w *Watcher) BackfillOrderStateHistory(ctx context.Context, fromBlock int) (*OrderHistoryBackfillResults, error) {
+	if w.orderStateHistoryRetentionBlocks == 0 {
+		return nil, errors.New("cannot backfill the order state history because it is disabled")
+	}
+	latestBlock, err := w.meshDB.FindLatestMiniHeader()
+	if err != nil {
+		return nil, err
+	}
+	toBlock := int(latestBlock.Number.Int64())
+	// Records older than the retention period would be compacted right away.
+	if cutoff := toBlock - w.orderStateHistoryRetentionBlocks; fromBlock < cutoff {
+		logger.WithFields(logger.Fields{
+			"requestedFromBlock": fromBlock,
+			"fromBlock":          cutoff,
+		}).Warn("order state history backfill starts before the retention period; starting at the beginning of the retention period instead")
+		fromBlock = cutoff
+	}
+	if fromBlock < 0 {
+		fromBlock = 0
+	}
+	if fromBlock > toBlock {
+		return nil, fmt.Errorf("cannot backfill the order state history from block %d because the latest block is %d", fromBlock, toBlock)
+	}
+
+	var orders []*meshdb.Order
+	if err := w.meshDB.Orders.FindAll(&orders); err != nil {
+		return nil, err
+	}
+	state, err := w.newOrderHistoryBackfillState(orders, fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	results := &OrderHistoryBackfillResults{
+		FromBlock: fromBlock,
+		ToBlock:   toBlock,
+		NumOrders: len(orders),
+	}
+	if len(orders) == 0 {
+		return results, nil
+	}
+
+	// A separate decoder is used so that the decoder of the live Watcher is
+	// not affected.
+	eventDecoder, err := decoder.New()
+	if err != nil {
+		return nil, err
+	}
+	eventDecoder.AddKnownExchange(w.contractAddresses.Exchange)
+
+	logger.WithFields(logger.Fields{
+		"fromBlock": fromBlock,
+		"toBlock":   toBlock,
+		"numOrders": len(orders),
+	}).Info("starting order state history backfill")
+	err = w.blockWatcher.ArchivalBackfill(ctx, fromBlock, toBlock, GetExchangeTopics(), func(events []*blockwatch.Event) error {
+		records := []*meshdb.OrderStateRecord{}
+		for _, event := range events {
+			blockRecords, numContractEvents, err := state.replayBlock(eventDecoder, event)
+			if err != nil {
+				return err
+			}
+			records = append(records, blockRecords...)
+			results.NumContractEvents += numContractEvents
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		numInserted, err := w.meshDB.InsertMissingOrderStateRecords(records)
+		if err != nil {
+			return err
+		}
+		results.NumRecordsInserted += numInserted
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.WithFields(logger.Fields{
+		"fromBlock":          results.FromBlock,
+		"toBlock":            results.ToBlock,
+		"numOrders":          results.NumOrders,
+		"numContractEvents":  results.NumContractEvents,
+		"numRecordsInserted": results.NumRecordsInserted,
+	}).Info("finished order state history backfill")
+	return results, nil
+}
+
+// newOrderHistoryBackfillState returns the state for replaying the blocks
+// starting at fromBlock. The remaining amount of each order starts out as its
+// fillable amount in the most recent record before fromBlock or, if there is
+// none, as its full taker asset amount.
+func (w *Watcher) newOrderHistoryBackfillState(orders []*meshdb.Order, fromBlock int) (*orderHistoryBackfillState, error) {
+	state := &orderHistoryBackfillState{
+		orders:         map[common.Hash]*meshdb.Order{},
+		ordersByMaker:  map[common.Address][]*meshdb.Order{},
+		remainingTaker: map[common.Hash]*big.Int{},
+	}
+	for _, order := range orders {
+		state.orders[order.Hash] = order
+		state.ordersByMaker[order.SignedOrder.MakerAddress] = append(state.ordersByMaker[order.SignedOrder.MakerAddress], order)
+		remaining := new(big.Int).Set(order.SignedOrder.TakerAssetAmount)
+		if fromBlock > 0 {
+			record, err := w.meshDB.FindOrderStateAtBlock(order.Hash, big.NewInt(int64(fromBlock-1)))
+			if err == nil {
+				if record.FillableTakerAssetAmount != nil {
+					remaining.Set(record.FillableTakerAssetAmount)
+				}
+			} else if _, ok := err.(db.NotFoundError); !ok {
+				return nil, err
+			}
+		}
+		state.remainingTaker[order.Hash] = remaining
+	}
+	return state, nil
+}
+
+// replayBlock applies the Exchange events in the given block to the state and
+// returns the resulting order state records and the number of events which
+// affected the orders.
+func (s *orderHistoryBackfillState) replayBlock(eventDecoder *decoder.Decoder, event *blockwatch.Event) ([]*meshdb.OrderStateRecord, int, error) {
+	records := []*meshdb.OrderStateRecord{}
+	numContractEvents := 0
+	record := func(orderHash common.Hash, endState zeroex.OrderEventEndState) {
+		numContractEvents++
+		records = append(records, &meshdb.OrderStateRecord{
+			OrderHash:                orderHash,
+			BlockNumber:              event.BlockHeader.Number,
+			EndState:                 string(endState),
+			FillableTakerAssetAmount: new(big.Int).Set(s.remainingTaker[orderHash]),
+			Timestamp:                event.BlockHeader.Timestamp,
+		})
+	}
+	for _, log := range event.BlockHeader.Logs {
+		eventType, err := eventDecoder.FindEventType(log)
+		if err != nil {
+			switch err.(type) {
+			case decoder.UntrackedTokenError, decoder.UnsupportedEventError:
+				continue
+			default:
+				return nil, 0, err
+			}
+		}
+		switch eventType {
+		case "ExchangeFillEvent":
+			var fillEvent decoder.ExchangeFillEvent
+			if err := eventDecoder.Decode(log, &fillEvent); err != nil {
+				return nil, 0, err
+			}
+			remaining, found := s.remainingTaker[fillEvent.OrderHash]
+			if !found {
+				continue
+			}
+			remaining.Sub(remaining, fillEvent.TakerAssetFilledAmount)
+			if remaining.Sign() <= 0 {
+				remaining.SetInt64(0)
+				record(fillEvent.OrderHash, zeroex.ESOrderFullyFilled)
+			} else {
+				record(fillEvent.OrderHash, zeroex.ESOrderFilled)
+			}
+
+		case "ExchangeCancelEvent":
+			var cancelEvent decoder.ExchangeCancelEvent
+			if err := eventDecoder.Decode(log, &cancelEvent); err != nil {
+				return nil, 0, err
+			}
+			remaining, found := s.remainingTaker[cancelEvent.OrderHash]
+			if !found {
+				continue
+			}
+			remaining.SetInt64(0)
+			record(cancelEvent.OrderHash, zeroex.ESOrderCancelled)
+
+		case "ExchangeCancelUpToEvent":
+			var cancelUpToEvent decoder.ExchangeCancelUpToEvent
+			if err := eventDecoder.Decode(log, &cancelUpToEvent); err != nil {
+				return nil, 0, err
+			}
+			for _, order := range s.ordersByMaker[cancelUpToEvent.MakerAddress] {
+				if order.SignedOrder.SenderAddress != cancelUpToEvent.OrderSenderAddress {
+					continue
+				}
+				if order.SignedOrder.Salt.Cmp(cancelUpToEvent.OrderEpoch) >= 0 {
+					continue
+				}
+				remaining := s.remainingTaker[order.Hash]
+				if remaining.Sign() == 0 {
+					continue
+				}
+				remaining.SetInt64(0)
+				record(order.Hash, zeroex.ESOrderCancelled)
+			}
+		}
+	}
+	return records, numContractEvents, nil
+}
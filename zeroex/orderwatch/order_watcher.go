@@ -7,8 +7,11 @@ import (
 	"fmt"
 	"math/big"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/0xProject/0x-mesh/common/requestid"
+	"github.com/0xProject/0x-mesh/common/supervisor"
 	"github.com/0xProject/0x-mesh/constants"
 	"github.com/0xProject/0x-mesh/db"
 	"github.com/0xProject/0x-mesh/ethereum"
@@ -74,6 +77,25 @@ const (
 	slowCounterOffset   = 5 // seconds
 	slowCounterRate     = 2.0
 	slowCounterInterval = 5 * time.Minute
+
+	// diskUsageCheckInterval is how often to check whether the database
+	// exceeds the disk usage budget.
+	diskUsageCheckInterval = 1 * time.Minute
+
+	// diskUsageTrimRatio affects how many orders are trimmed whenever the
+	// database exceeds the disk usage budget. Watcher will remove non-pinned
+	// orders until only diskUsageTrimRatio of them remain.
+	diskUsageTrimRatio = 0.8
+
+	// diskUsageResumeRatio determines when new non-pinned orders are accepted
+	// again after the disk usage budget was exceeded. Watcher keeps rejecting
+	// them until disk usage drops below diskUsageResumeRatio * maxDiskUsageBytes
+	// so that it doesn't keep flapping around the budget.
+	diskUsageResumeRatio = 0.9
+
+	// removeOrdersNotMatchingChunkSize is the number of orders read from the
+	// database at a time by RemoveOrdersNotMatching.
+	removeOrdersNotMatchingChunkSize = 1000
 )
 
 // Watcher watches all order-relevant state and handles the state transitions
@@ -101,6 +123,55 @@ type Watcher struct {
 	atLeastOneBlockProcessed   chan struct{}
 	atLeastOneBlockProcessedMu sync.Mutex
 	didProcessABlock           bool
+	orderFilterHashMu          sync.RWMutex
+	orderFilterHash            string
+	// staticCallOrderHashes is the set of orders whose assetData contains a
+	// staticcall. If staticcall execution is enabled in the OrderValidator,
+	// these orders are revalidated on every block.
+	staticCallOrderHashesMu sync.Mutex
+	staticCallOrderHashes   map[common.Hash]struct{}
+	maxOrderSizeInBytes     int
+	// numOversizedOrdersRejected is the number of orders that were rejected
+	// because they exceeded maxOrderSizeInBytes. It must be accessed atomically.
+	numOversizedOrdersRejected       int64
+	orderStateHistoryRetentionBlocks int
+	// trustedMakers is the set of maker addresses whose orders are exempt from
+	// the max expiration time and are always pinned.
+	trustedMakers map[common.Address]struct{}
+	// orderFeedMu ensures that order events are sent to subscribers in the
+	// order of their sequence numbers. orderEventsMu protects the sequence
+	// number and the order event log.
+	orderFeedMu                  sync.Mutex
+	orderEventsMu                sync.Mutex
+	lastOrderEventSequenceNumber uint64
+	orderEventLogSize            int
+	maxDiskUsageBytes            int64
+	// diskBudgetExceeded is 1 if the database exceeds the disk usage budget
+	// and 0 otherwise. It must be accessed atomically.
+	diskBudgetExceeded int32
+	// numDuplicateOrders is the number of times an order that was already
+	// stored was delivered again, by source.
+	numDuplicateOrdersMu sync.Mutex
+	numDuplicateOrders   map[meshdb.OrderSource]int64
+	// provisionalOrderBlocksBehind is the number of blocks the BlockWatcher
+	// must be behind the chain head for new orders to be stored as
+	// provisional. If 0, orders are never stored as provisional.
+	provisionalOrderBlocksBehind int
+	// validationQueue keeps track of the batches of orders which are
+	// currently being validated by ValidateAndStoreValidOrders.
+	validationQueue *validationQueue
+	// maxExpirationDuration is the maximum time until new orders expire or 0
+	// if it is not limited. It must be accessed atomically.
+	maxExpirationDuration int64
+	// watchdogConfig configures the watchdog which detects stalled stages of
+	// the pipeline. blockProcessing and eventDelivery keep track of the work
+	// those stages are busy with. Validation batches are tracked by
+	// validationQueue.
+	watchdogConfig  WatchdogConfig
+	blockProcessing *stageProgress
+	eventDelivery   *stageProgress
+	stalledStagesMu sync.Mutex
+	stalledStages   []StalledStage
 }
 
 type Config struct {
@@ -111,6 +182,50 @@ type Config struct {
 	ContractAddresses ethereum.ContractAddresses
 	MaxOrders         int
 	MaxExpirationTime *big.Int
+	// OrderFilterHash is the hash of the order filter currently in use (see
+	// orderfilter.Filter.Hash). It is stored alongside each new order and
+	// included in order events.
+	OrderFilterHash string
+	// MaxOrderSizeInBytes is the maximum size of an order encoded as JSON. Larger
+	// orders are rejected with ROMaxOrderSizeExceeded. Defaults to
+	// constants.MaxOrderSizeInBytes.
+	MaxOrderSizeInBytes int
+	// OrderStateHistoryRetentionBlocks is the number of blocks for which the
+	// full history of order state transitions is kept. Older history is
+	// compacted so that only the state of orders that are still fillable is
+	// kept. If 0, no history is recorded.
+	OrderStateHistoryRetentionBlocks int
+	// TrustedMakerAddresses is a list of maker addresses whose orders bypass
+	// Mesh's anti-spam mechanisms. Their orders are not subject to the max
+	// expiration time and are always pinned, so they are never removed to make
+	// room for other orders.
+	TrustedMakerAddresses []common.Address
+	// OrderEventLogSize is the number of most recent order events to keep in
+	// the order event log so that they can be fetched by sequence number. The
+	// most recent order event is always kept so that sequence numbers keep
+	// increasing across restarts.
+	OrderEventLogSize int
+	// MaxDiskUsageBytes is the disk usage budget for the database. When it is
+	// exceeded, new orders that are not pinned are rejected with
+	// ROMaxDiskUsageExceeded and orders are pruned until disk usage drops back
+	// below the budget. If 0, disk usage is not limited.
+	MaxDiskUsageBytes int64
+	// ProvisionalOrderBlocksBehind enables provisional orders. Orders which are
+	// validated while the BlockWatcher is at least this many blocks behind the
+	// chain head (e.g. after downtime) are validated against state that may be
+	// stale. They are stored as provisional (see meshdb.Order.IsProvisional) and
+	// re-validated once the BlockWatcher has caught up. If 0, orders are never
+	// stored as provisional.
+	ProvisionalOrderBlocksBehind int
+	// MaxExpirationDuration is used for the expiresWithin constraint of order
+	// filters. If it is not 0, new orders which expire more than this long
+	// after they are validated are rejected with ROMaxExpirationExceeded, even
+	// if they are from a trusted maker.
+	MaxExpirationDuration time.Duration
+	// Watchdog configures the watchdog which detects stalled stages of the
+	// order processing pipeline (see StalledStages). If all of its thresholds
+	// are 0, the watchdog is disabled.
+	Watchdog WatchdogConfig
 }
 
 // New instantiates a new order watcher
@@ -131,6 +246,14 @@ func New(config Config) (*Watcher, error) {
 		// MaxExpirationTime should never be in the past.
 		config.MaxExpirationTime = big.NewInt(time.Now().Unix())
 	}
+	if config.MaxOrderSizeInBytes == 0 {
+		config.MaxOrderSizeInBytes = constants.MaxOrderSizeInBytes
+	}
+
+	trustedMakers := make(map[common.Address]struct{}, len(config.TrustedMakerAddresses))
+	for _, makerAddress := range config.TrustedMakerAddresses {
+		trustedMakers[makerAddress] = struct{}{}
+	}
 
 	// Configure a SlowCounter to be used for increasing max expiration time.
 	slowCounterConfig := slowcounter.Config{
@@ -144,21 +267,42 @@ func New(config Config) (*Watcher, error) {
 		return nil, err
 	}
 
+	// Continue numbering order events where we left off.
+	_, lastOrderEventSequenceNumber, err := config.MeshDB.GetOrderEventLogBounds()
+	if err != nil {
+		return nil, err
+	}
+
 	w := &Watcher{
-		meshDB:                     config.MeshDB,
-		blockWatcher:               config.BlockWatcher,
-		expirationWatcher:          expirationwatch.New(),
-		contractAddressToSeenCount: map[common.Address]uint{},
-		orderValidator:             config.OrderValidator,
-		eventDecoder:               decoder,
-		assetDataDecoder:           assetDataDecoder,
-		contractAddresses:          config.ContractAddresses,
-		maxExpirationTime:          big.NewInt(0).Set(config.MaxExpirationTime),
-		maxExpirationCounter:       maxExpirationCounter,
-		maxOrders:                  config.MaxOrders,
-		blockEventsChan:            make(chan []*blockwatch.Event, 100),
-		atLeastOneBlockProcessed:   make(chan struct{}),
-		didProcessABlock:           false,
+		meshDB:                           config.MeshDB,
+		blockWatcher:                     config.BlockWatcher,
+		expirationWatcher:                expirationwatch.New(),
+		contractAddressToSeenCount:       map[common.Address]uint{},
+		orderValidator:                   config.OrderValidator,
+		eventDecoder:                     decoder,
+		assetDataDecoder:                 assetDataDecoder,
+		contractAddresses:                config.ContractAddresses,
+		maxExpirationTime:                big.NewInt(0).Set(config.MaxExpirationTime),
+		maxExpirationCounter:             maxExpirationCounter,
+		maxOrders:                        config.MaxOrders,
+		blockEventsChan:                  make(chan []*blockwatch.Event, 100),
+		atLeastOneBlockProcessed:         make(chan struct{}),
+		didProcessABlock:                 false,
+		orderFilterHash:                  config.OrderFilterHash,
+		staticCallOrderHashes:            map[common.Hash]struct{}{},
+		maxOrderSizeInBytes:              config.MaxOrderSizeInBytes,
+		orderStateHistoryRetentionBlocks: config.OrderStateHistoryRetentionBlocks,
+		trustedMakers:                    trustedMakers,
+		lastOrderEventSequenceNumber:     lastOrderEventSequenceNumber,
+		orderEventLogSize:                config.OrderEventLogSize,
+		maxDiskUsageBytes:                config.MaxDiskUsageBytes,
+		numDuplicateOrders:               map[meshdb.OrderSource]int64{},
+		provisionalOrderBlocksBehind:     config.ProvisionalOrderBlocksBehind,
+		validationQueue:                  newValidationQueue(),
+		maxExpirationDuration:            int64(config.MaxExpirationDuration),
+		watchdogConfig:                   config.Watchdog,
+		blockProcessing:                  &stageProgress{},
+		eventDelivery:                    &stageProgress{},
 	}
 
 	// Check if any orders need to be removed right away due to high expiration
@@ -167,7 +311,7 @@ func New(config Config) (*Watcher, error) {
 	if err != nil {
 		return nil, err
 	}
-	w.orderFeed.Send(orderEvents)
+	w.emitOrderEvents(orderEvents)
 
 	// Pre-populate the OrderWatcher with all orders already stored in the DB
 	orders := []*meshdb.Order{}
@@ -211,26 +355,44 @@ func (w *Watcher) Watch(ctx context.Context) error {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		mainLoopErrChan <- w.mainLoop(innerCtx)
+		mainLoopErrChan <- supervisor.Run(innerCtx, supervisor.Config{Name: "orderwatch.mainLoop"}, w.mainLoop)
 	}()
 	cleanupLoopErrChan := make(chan error, 1)
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		cleanupLoopErrChan <- w.cleanupLoop(innerCtx)
+		cleanupLoopErrChan <- supervisor.Run(innerCtx, supervisor.Config{Name: "orderwatch.cleanupLoop"}, w.cleanupLoop)
 	}()
 	maxExpirationTimeLoopErrChan := make(chan error, 1)
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		maxExpirationTimeLoopErrChan <- w.maxExpirationTimeLoop(innerCtx)
+		maxExpirationTimeLoopErrChan <- supervisor.Run(innerCtx, supervisor.Config{Name: "orderwatch.maxExpirationTimeLoop"}, w.maxExpirationTimeLoop)
 	}()
 	removedCheckerLoopErrChan := make(chan error, 1)
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		removedCheckerLoopErrChan <- w.removedCheckerLoop(innerCtx)
+		removedCheckerLoopErrChan <- supervisor.Run(innerCtx, supervisor.Config{Name: "orderwatch.removedCheckerLoop"}, w.removedCheckerLoop)
 	}()
+	// The disk usage loop is only started if there is a disk usage budget.
+	diskUsageLoopErrChan := make(chan error, 1)
+	if w.maxDiskUsageBytes > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			diskUsageLoopErrChan <- supervisor.Run(innerCtx, supervisor.Config{Name: "orderwatch.diskUsageLoop"}, w.diskUsageLoop)
+		}()
+	}
+	// The watchdog loop is only started if the watchdog is enabled.
+	watchdogLoopErrChan := make(chan error, 1)
+	if w.watchdogConfig.enabled() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			watchdogLoopErrChan <- supervisor.Run(innerCtx, supervisor.Config{Name: "orderwatch.watchdogLoop"}, w.watchdogLoop)
+		}()
+	}
 
 	// If any error channel returns a non-nil error, we cancel the inner context
 	// and return the error. Note that this means we only return the first error
@@ -256,6 +418,16 @@ func (w *Watcher) Watch(ctx context.Context) error {
 			cancel()
 			return err
 		}
+	case err := <-diskUsageLoopErrChan:
+		if err != nil {
+			cancel()
+			return err
+		}
+	case err := <-watchdogLoopErrChan:
+		if err != nil {
+			cancel()
+			return err
+		}
 	}
 
 	// Wait for all goroutines to exit. If we reached here it means we are done
@@ -265,8 +437,11 @@ func (w *Watcher) Watch(ctx context.Context) error {
 }
 
 func (w *Watcher) mainLoop(ctx context.Context) error {
-	// Set up the channel used for subscribing to block events.
-	w.blockSubscription = w.blockWatcher.Subscribe(w.blockEventsChan)
+	// Set up the channel used for subscribing to block events. The
+	// subscription is kept if the main loop is restarted after a panic.
+	if w.blockSubscription == nil {
+		w.blockSubscription = w.blockWatcher.Subscribe(w.blockEventsChan)
+	}
 
 	for {
 		select {
@@ -283,14 +458,135 @@ func (w *Watcher) mainLoop(ctx context.Context) error {
 			// we might as well process _all_ events in the channel.
 			drainedEvents := drainBlockEventsChan(w.blockEventsChan, maxBlockEventsToHandle)
 			events = append(events, drainedEvents...)
-			w.handleBlockEventsMu.Lock()
-			if err := w.handleBlockEvents(ctx, events); err != nil {
-				w.handleBlockEventsMu.Unlock()
+			if err := w.handleBlockEventsUntilDone(ctx, events); err != nil {
 				return err
 			}
-			w.handleBlockEventsMu.Unlock()
+			w.revalidateProvisionalOrdersIfCaughtUp(ctx)
+		}
+	}
+}
+
+// handleBlockEventsUntilDone calls lockAndHandleBlockEvents and handles the
+// same events again if the watchdog restarted block processing while they
+// were being handled (see WatchdogConfig.RestartStalledStages). Since the
+// changes to the database are only committed once all events were handled,
+// handling them again is safe.
+func (w *Watcher) handleBlockEventsUntilDone(ctx context.Context, events []*blockwatch.Event) error {
+	for {
+		restarted, err := w.handleBlockEventsOnce(ctx, events)
+		if !restarted || err == nil || ctx.Err() != nil {
+			return err
+		}
+		logger.WithFields(logger.Fields{
+			"error":     err.Error(),
+			"numEvents": len(events),
+		}).Warn("block processing was restarted; handling block events again")
+	}
+}
+
+// handleBlockEventsOnce calls lockAndHandleBlockEvents with a context which is
+// canceled if the watchdog restarts block processing. It returns true if that
+// happened.
+func (w *Watcher) handleBlockEventsOnce(ctx context.Context, events []*blockwatch.Event) (restarted bool, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w.blockProcessing.start(time.Now(), cancel)
+	defer func() {
+		restarted = w.blockProcessing.finish()
+	}()
+	return false, w.lockAndHandleBlockEvents(ctx, events)
+}
+
+// lockAndHandleBlockEvents calls handleBlockEvents while holding the
+// `handleBlockEventsMu` mutex. The mutex is also released if handleBlockEvents
+// panics so that the main loop can be restarted.
+func (w *Watcher) lockAndHandleBlockEvents(ctx context.Context, events []*blockwatch.Event) error {
+	w.handleBlockEventsMu.Lock()
+	defer w.handleBlockEventsMu.Unlock()
+	return w.handleBlockEvents(ctx, events)
+}
+
+// revalidateProvisionalOrdersIfCaughtUp re-validates all provisional orders at
+// the latest block once the BlockWatcher has caught up to the chain head and
+// clears their provisional flag. Errors are logged and the orders stay
+// provisional so that they are retried after the next block.
+func (w *Watcher) revalidateProvisionalOrdersIfCaughtUp(ctx context.Context) {
+	isBehind, err := w.isBehindChainHead()
+	if err != nil {
+		logger.WithError(err).Error("could not determine whether BlockWatcher is caught up")
+		return
+	}
+	if isBehind {
+		return
+	}
+	provisionalOrders, err := w.meshDB.FindProvisionalOrders()
+	if err != nil {
+		logger.WithError(err).Error("could not find provisional orders")
+		return
+	}
+	if len(provisionalOrders) == 0 {
+		return
+	}
+	orderHashes := make([]common.Hash, len(provisionalOrders))
+	for i, order := range provisionalOrders {
+		orderHashes[i] = order.Hash
+	}
+	results, err := w.RevalidateOrders(ctx, orderHashes)
+	if err != nil {
+		logger.WithError(err).Error("could not re-validate provisional orders")
+		return
+	}
+
+	w.handleBlockEventsMu.Lock()
+	defer w.handleBlockEventsMu.Unlock()
+	txn := w.meshDB.Orders.OpenTransaction()
+	defer func() {
+		_ = txn.Discard()
+	}()
+	for _, revalidatedOrder := range results.Orders {
+		var order meshdb.Order
+		if err := w.meshDB.Orders.FindByID(revalidatedOrder.Hash.Bytes(), &order); err != nil {
+			if _, ok := err.(db.NotFoundError); ok {
+				continue
+			}
+			logger.WithError(err).Error("could not find re-validated provisional order")
+			return
+		}
+		order.IsProvisional = false
+		if err := txn.Update(&order); err != nil {
+			logger.WithError(err).Error("could not clear provisional flag of order")
+			return
 		}
 	}
+	if err := txn.Commit(); err != nil {
+		logger.WithError(err).Error("could not clear provisional flag of orders")
+		return
+	}
+	logger.WithFields(logger.Fields{
+		"numRevalidated":    len(results.Orders),
+		"numNotRevalidated": len(results.NotRevalidated),
+	}).Info("re-validated provisional orders after catching up to the latest block")
+}
+
+// isBehindChainHead returns true if provisional orders are enabled and the
+// BlockWatcher is at least provisionalOrderBlocksBehind blocks behind the
+// chain head.
+func (w *Watcher) isBehindChainHead() (bool, error) {
+	if w.provisionalOrderBlocksBehind <= 0 {
+		return false, nil
+	}
+	blocksBehind, err := w.blockWatcher.BlocksBehind()
+	if err != nil {
+		return false, err
+	}
+	return blocksBehind >= w.provisionalOrderBlocksBehind, nil
+}
+
+// NumProvisionalOrders returns the number of orders that were validated while
+// the BlockWatcher was catching up to the chain head and have not been
+// re-validated yet.
+func (w *Watcher) NumProvisionalOrders() (int, error) {
+	return w.meshDB.CountProvisionalOrders()
 }
 
 func drainBlockEventsChan(blockEventsChan chan []*blockwatch.Event, max int) []*blockwatch.Event {
@@ -364,6 +660,103 @@ func (w *Watcher) removedCheckerLoop(ctx context.Context) error {
 	}
 }
 
+func (w *Watcher) diskUsageLoop(ctx context.Context) error {
+	for {
+		start := time.Now()
+		if err := w.checkDiskUsage(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(diskUsageCheckInterval - time.Since(start)):
+			continue
+		}
+	}
+}
+
+// DiskBudgetExceeded returns true if the database exceeds the disk usage
+// budget. While it does, new orders that are not pinned are rejected.
+func (w *Watcher) DiskBudgetExceeded() bool {
+	return atomic.LoadInt32(&w.diskBudgetExceeded) == 1
+}
+
+// checkDiskUsage compares the disk usage of the database to the disk usage
+// budget. If the budget is exceeded, it stops accepting new orders that are
+// not pinned and prunes orders to free up space.
+func (w *Watcher) checkDiskUsage() error {
+	diskUsage, err := w.meshDB.DiskUsage()
+	if err != nil {
+		return err
+	}
+	if diskUsage <= w.maxDiskUsageBytes {
+		if w.DiskBudgetExceeded() && float64(diskUsage) < diskUsageResumeRatio*float64(w.maxDiskUsageBytes) {
+			atomic.StoreInt32(&w.diskBudgetExceeded, 0)
+			logger.WithFields(logger.Fields{
+				"diskUsageBytes":    diskUsage,
+				"maxDiskUsageBytes": w.maxDiskUsageBytes,
+			}).Info("disk usage is back within budget; accepting new orders again")
+		}
+		return nil
+	}
+
+	if !w.DiskBudgetExceeded() {
+		atomic.StoreInt32(&w.diskBudgetExceeded, 1)
+		logger.WithFields(logger.Fields{
+			"diskUsageBytes":    diskUsage,
+			"maxDiskUsageBytes": w.maxDiskUsageBytes,
+		}).Warn("disk usage budget exceeded; rejecting new orders that are not pinned and pruning orders")
+	}
+	orderEvents, err := w.pruneOrdersToReduceDiskUsage()
+	if err != nil {
+		return err
+	}
+	w.emitOrderEvents(orderEvents)
+	return nil
+}
+
+// pruneOrdersToReduceDiskUsage permanently deletes all orders flagged for
+// removal (regardless of how recently they were removed), trims the non-pinned
+// orders with the highest expiration times and then compacts the database.
+func (w *Watcher) pruneOrdersToReduceDiskUsage() ([]*zeroex.OrderEvent, error) {
+	w.handleBlockEventsMu.Lock()
+	defer w.handleBlockEventsMu.Unlock()
+
+	removedOrders, err := w.meshDB.FindRemovedOrders()
+	if err != nil {
+		return nil, err
+	}
+	for _, order := range removedOrders {
+		if err := w.permanentlyDeleteOrder(w.meshDB.Orders, order); err != nil {
+			return nil, err
+		}
+	}
+
+	orderEvents := []*zeroex.OrderEvent{}
+	numOrders, err := w.meshDB.Orders.Count()
+	if err != nil {
+		return nil, err
+	}
+	numPinnedOrders, err := w.meshDB.CountPinnedOrders()
+	if err != nil {
+		return nil, err
+	}
+	numOrdersToRemove := int((1 - diskUsageTrimRatio) * float64(numOrders-numPinnedOrders))
+	if numOrdersToRemove > 0 {
+		orderEvents, err = w.trimOrdersAndGenerateEvents(numOrders - numOrdersToRemove)
+		if err != nil {
+			return orderEvents, err
+		}
+	}
+	logger.WithFields(logger.Fields{
+		"numRemovedOrdersDeleted": len(removedOrders),
+		"numOrdersTrimmed":        len(orderEvents),
+	}).Debug("pruned orders to reduce disk usage")
+
+	return orderEvents, w.meshDB.Compact()
+}
+
 // handleOrderExpirations takes care of generating expired and unexpired order events for orders that do not require re-validation.
 // Since expiry is now done according to block timestamp, we can figure out which orders have expired/unexpired statically. We do not
 // process blocks that require re-validation, since the validation process will already emit the necessary events and we cannot make
@@ -401,6 +794,7 @@ func (w *Watcher) handleOrderExpirations(ordersColTxn *db.Transaction, latestBlo
 				SignedOrder:              order.SignedOrder,
 				FillableTakerAssetAmount: big.NewInt(0),
 				EndState:                 zeroex.ESOrderExpired,
+				OrderFilterHash:          order.OrderFilterHash,
 			}
 			orderEvents = append(orderEvents, orderEvent)
 		}
@@ -431,6 +825,7 @@ func (w *Watcher) handleOrderExpirations(ordersColTxn *db.Transaction, latestBlo
 					SignedOrder:              order.SignedOrder,
 					FillableTakerAssetAmount: order.FillableTakerAssetAmount,
 					EndState:                 zeroex.ESOrderUnexpired,
+					OrderFilterHash:          order.OrderFilterHash,
 				}
 				orderEvents = append(orderEvents, orderEvent)
 			}
@@ -773,6 +1168,21 @@ func (w *Watcher) handleBlockEvents(
 		}
 	}
 
+	// The result of a staticcall can change without any events being emitted,
+	// so if staticcalls are executed during validation we need to revalidate
+	// all orders involving a staticcall on every block.
+	if w.orderValidator.StaticCallExecutionEnabled() {
+		for _, orderHash := range w.getStaticCallOrderHashes() {
+			if _, found := orderHashToDBOrder[orderHash]; found {
+				continue
+			}
+			order := w.findOrder(orderHash)
+			if order != nil {
+				orderHashToDBOrder[orderHash] = order
+			}
+		}
+	}
+
 	expirationOrderEvents, err := w.handleOrderExpirations(ordersColTxn, latestBlockTimestamp, previousLatestBlockTimestamp, orderHashToDBOrder)
 	if err != nil {
 		return err
@@ -801,7 +1211,8 @@ func (w *Watcher) handleBlockEvents(
 
 	orderEvents := append(expirationOrderEvents, postValidationOrderEvents...)
 	if len(orderEvents) > 0 {
-		w.orderFeed.Send(orderEvents)
+		w.recordOrderStateHistory(orderEvents, latestBlockNumber)
+		w.emitOrderEvents(orderEvents)
 	}
 
 	w.atLeastOneBlockProcessedMu.Lock()
@@ -872,12 +1283,421 @@ func (w *Watcher) Cleanup(ctx context.Context, lastUpdatedBuffer time.Duration)
 	}
 
 	if len(orderEvents) > 0 {
-		w.orderFeed.Send(orderEvents)
+		w.recordOrderStateHistory(orderEvents, latestBlock.Number)
+		w.emitOrderEvents(orderEvents)
 	}
+	w.compactOrderStateHistory(latestBlock.Number)
 
 	return nil
 }
 
+// Reasons why an order could not be removed, as returned by RemoveOrders.
+const (
+	RemoveOrderNotFound        = "NOT_FOUND"
+	RemoveOrderNotAddedLocally = "NOT_ADDED_LOCALLY"
+)
+
+// RemoveOrdersResults are the results of RemoveOrders.
+type RemoveOrdersResults struct {
+	// Removed are the hashes of the orders that were removed.
+	Removed []common.Hash
+	// NotRemoved maps the hashes of the orders that could not be removed to
+	// the reason why (one of the RemoveOrder constants).
+	NotRemoved map[common.Hash]string
+}
+
+// RemoveOrders permanently deletes the orders with the given hashes and emits
+// a REMOVED order event with the given reason for each of them. Only orders
+// that were added locally (i.e. not received from peers) can be removed.
+// Peers which already received the orders may continue to share them.
+func (w *Watcher) RemoveOrders(orderHashes []common.Hash, reason string) (*RemoveOrdersResults, error) {
+	w.handleBlockEventsMu.Lock()
+	defer w.handleBlockEventsMu.Unlock()
+
+	results := &RemoveOrdersResults{
+		Removed:    []common.Hash{},
+		NotRemoved: map[common.Hash]string{},
+	}
+	orderEvents := []*zeroex.OrderEvent{}
+	seen := map[common.Hash]struct{}{}
+	now := time.Now().UTC()
+	for _, orderHash := range orderHashes {
+		if _, found := seen[orderHash]; found {
+			continue
+		}
+		seen[orderHash] = struct{}{}
+
+		var order meshdb.Order
+		if err := w.meshDB.Orders.FindByID(orderHash.Bytes(), &order); err != nil {
+			if _, ok := err.(db.NotFoundError); ok {
+				results.NotRemoved[orderHash] = RemoveOrderNotFound
+				continue
+			}
+			return nil, err
+		}
+		if order.IsRemoved {
+			results.NotRemoved[orderHash] = RemoveOrderNotFound
+			continue
+		}
+		if !order.AddedLocally {
+			results.NotRemoved[orderHash] = RemoveOrderNotAddedLocally
+			continue
+		}
+		orderEvent, err := w.removeOrder(&order, reason, now)
+		if err != nil {
+			return nil, err
+		}
+		results.Removed = append(results.Removed, orderHash)
+		orderEvents = append(orderEvents, orderEvent)
+	}
+
+	if len(orderEvents) > 0 {
+		logger.WithFields(logger.Fields{
+			"numOrdersRemoved": len(orderEvents),
+			"reason":           reason,
+		}).Info("removed locally added orders")
+		w.emitRemovedOrderEvents(orderEvents)
+	}
+	return results, nil
+}
+
+// RemoveOrdersNotMatching permanently deletes all stored orders for which
+// match returns false, regardless of where they came from, and emits a REMOVED
+// order event with the given reason for each of them. It returns the hashes of
+// the removed orders. It is used to remove the orders which don't match a new
+// order filter. The orders are read and removed in chunks, so that they don't
+// all need to be held in memory at once.
+func (w *Watcher) RemoveOrdersNotMatching(match func(order *zeroex.SignedOrder) (bool, error), reason string) ([]common.Hash, error) {
+	w.handleBlockEventsMu.Lock()
+	defer w.handleBlockEventsMu.Unlock()
+
+	removed := []common.Hash{}
+	now := time.Now().UTC()
+	notRemovedFilter := w.meshDB.Orders.IsRemovedIndex.ValueFilter([]byte{0})
+	var orders []*meshdb.Order
+	err := w.meshDB.Orders.NewQuery(notRemovedFilter).RunInChunks(&orders, removeOrdersNotMatchingChunkSize, func() error {
+		orderEvents := []*zeroex.OrderEvent{}
+		for _, order := range orders {
+			matches, err := match(order.SignedOrder)
+			if err != nil {
+				return err
+			}
+			if matches {
+				continue
+			}
+			orderEvent, err := w.removeOrder(order, reason, now)
+			if err != nil {
+				return err
+			}
+			removed = append(removed, order.Hash)
+			orderEvents = append(orderEvents, orderEvent)
+		}
+		if len(orderEvents) > 0 {
+			w.emitRemovedOrderEvents(orderEvents)
+		}
+		return nil
+	})
+	if len(removed) > 0 {
+		logger.WithFields(logger.Fields{
+			"numOrdersRemoved": len(removed),
+			"reason":           reason,
+		}).Info("removed orders which no longer match")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// removeOrder permanently deletes the given order and returns a REMOVED order
+// event with the given reason. The caller must hold handleBlockEventsMu.
+func (w *Watcher) removeOrder(order *meshdb.Order, reason string, now time.Time) (*zeroex.OrderEvent, error) {
+	if err := w.permanentlyDeleteOrder(w.meshDB.Orders, order); err != nil {
+		return nil, err
+	}
+	expirationTimestamp := time.Unix(order.SignedOrder.ExpirationTimeSeconds.Int64(), 0)
+	w.expirationWatcher.Remove(expirationTimestamp, order.Hash.Hex())
+	return &zeroex.OrderEvent{
+		Timestamp:                now,
+		OrderHash:                order.Hash,
+		SignedOrder:              order.SignedOrder,
+		FillableTakerAssetAmount: big.NewInt(0),
+		EndState:                 zeroex.ESOrderRemoved,
+		RemovalReason:            reason,
+		OrderFilterHash:          order.OrderFilterHash,
+	}, nil
+}
+
+// emitRemovedOrderEvents records the given REMOVED order events in the order
+// state history and emits them.
+func (w *Watcher) emitRemovedOrderEvents(orderEvents []*zeroex.OrderEvent) {
+	if latestBlock, err := w.meshDB.FindLatestMiniHeader(); err == nil {
+		w.recordOrderStateHistory(orderEvents, latestBlock.Number)
+	}
+	w.emitOrderEvents(orderEvents)
+}
+
+// RevalidateOrderNotFound is the reason returned by RevalidateOrders for
+// orders which are not stored by the Watcher.
+const RevalidateOrderNotFound = "NOT_FOUND"
+
+// RevalidateOrdersResults are the results of RevalidateOrders.
+type RevalidateOrdersResults struct {
+	// Orders are the revalidated orders with their updated state, in the
+	// order in which they were requested.
+	Orders []*meshdb.Order
+	// NotRevalidated maps the hashes of the orders that could not be
+	// revalidated to the reason why. It is either RevalidateOrderNotFound or
+	// the code of the RejectedOrderStatus returned by the order validator (e.g.
+	// "EthRPCRequestFailed").
+	NotRevalidated map[common.Hash]string
+	// NumOrderEvents is the number of order events which were emitted because
+	// the state of a revalidated order changed.
+	NumOrderEvents int
+}
+
+// RevalidateOrders immediately re-validates the orders with the given hashes
+// at the latest block instead of waiting for a relevant block event or the
+// cleanup job. Any order events resulting from changes in their state are
+// emitted as usual.
+func (w *Watcher) RevalidateOrders(ctx context.Context, orderHashes []common.Hash) (*RevalidateOrdersResults, error) {
+	w.handleBlockEventsMu.Lock()
+	defer w.handleBlockEventsMu.Unlock()
+
+	return w.revalidateOrders(ctx, orderHashes, nil)
+}
+
+// RevalidateOrdersAtBlock is like RevalidateOrders but re-validates the orders
+// at the given block, which is newer than the latest block processed by the
+// Watcher. It is used to act on hints from peers that the state of some orders
+// changed in a block which the Watcher hasn't processed yet. If the block was
+// already processed, block processing took care of any changes so the orders
+// are not re-validated and the results are empty. The state of the orders is
+// checked on-chain, so a peer cannot use a false hint to remove an order.
+func (w *Watcher) RevalidateOrdersAtBlock(ctx context.Context, orderHashes []common.Hash, blockNumber *big.Int) (*RevalidateOrdersResults, error) {
+	w.handleBlockEventsMu.Lock()
+	defer w.handleBlockEventsMu.Unlock()
+
+	latestBlock, err := w.meshDB.FindLatestMiniHeader()
+	if err != nil {
+		return nil, err
+	}
+	if blockNumber.Cmp(latestBlock.Number) <= 0 {
+		return &RevalidateOrdersResults{
+			Orders:         []*meshdb.Order{},
+			NotRevalidated: map[common.Hash]string{},
+		}, nil
+	}
+	return w.revalidateOrders(ctx, orderHashes, blockNumber)
+}
+
+// revalidateOrders re-validates the orders with the given hashes at the given
+// block or, if blockNumber is nil, at the latest block processed by the
+// Watcher. handleBlockEventsMu must be held while it is called.
+func (w *Watcher) revalidateOrders(ctx context.Context, orderHashes []common.Hash, blockNumber *big.Int) (*RevalidateOrdersResults, error) {
+	results := &RevalidateOrdersResults{
+		Orders:         []*meshdb.Order{},
+		NotRevalidated: map[common.Hash]string{},
+	}
+	orderHashToDBOrder := map[common.Hash]*meshdb.Order{}
+	orderHashToEvents := map[common.Hash][]*zeroex.ContractEvent{}
+	signedOrders := []*zeroex.SignedOrder{}
+	for _, orderHash := range orderHashes {
+		if _, found := orderHashToDBOrder[orderHash]; found {
+			continue
+		}
+		var order meshdb.Order
+		if err := w.meshDB.Orders.FindByID(orderHash.Bytes(), &order); err != nil {
+			if _, ok := err.(db.NotFoundError); ok {
+				results.NotRevalidated[orderHash] = RevalidateOrderNotFound
+				continue
+			}
+			return nil, err
+		}
+		orderHashToDBOrder[orderHash] = &order
+		orderHashToEvents[orderHash] = []*zeroex.ContractEvent{}
+		signedOrders = append(signedOrders, order.SignedOrder)
+	}
+	if len(signedOrders) == 0 {
+		return results, nil
+	}
+
+	latestBlock, err := w.meshDB.FindLatestMiniHeader()
+	if err != nil {
+		return nil, err
+	}
+	if blockNumber == nil {
+		blockNumber = latestBlock.Number
+	}
+	ordersColTxn := w.meshDB.Orders.OpenTransaction()
+	defer func() {
+		_ = ordersColTxn.Discard()
+	}()
+	areNewOrders := false
+	validationResults := w.orderValidator.BatchValidate(ctx, signedOrders, areNewOrders, blockNumber)
+	// We only know the timestamp of the blocks we processed, so the latest of
+	// them is used even if the orders were validated at a newer block.
+	orderEvents, err := w.convertValidationResultsIntoOrderEvents(ordersColTxn, validationResults, orderHashToDBOrder, orderHashToEvents, latestBlock.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if err := ordersColTxn.Commit(); err != nil {
+		return nil, err
+	}
+	if len(orderEvents) > 0 {
+		w.recordOrderStateHistory(orderEvents, blockNumber)
+		w.emitOrderEvents(orderEvents)
+	}
+	results.NumOrderEvents = len(orderEvents)
+
+	// Orders which could not be validated (e.g. because the Ethereum RPC
+	// request failed) keep their previous state.
+	for _, rejectedOrderInfo := range validationResults.Rejected {
+		if rejectedOrderInfo.Kind == ordervalidator.MeshError {
+			results.NotRevalidated[rejectedOrderInfo.OrderHash] = rejectedOrderInfo.Status.Code
+		}
+	}
+	for _, orderHash := range orderHashes {
+		order, found := orderHashToDBOrder[orderHash]
+		if !found {
+			continue
+		}
+		if _, notRevalidated := results.NotRevalidated[orderHash]; notRevalidated {
+			continue
+		}
+		results.Orders = append(results.Orders, order)
+		// Only include each order once, even if it was requested more than once.
+		delete(orderHashToDBOrder, orderHash)
+	}
+	return results, nil
+}
+
+// emitOrderEvents assigns the next sequence numbers to the given order events,
+// appends them to the order event log and sends them to all subscribers. Order
+// events are always sent in the order of their sequence numbers.
+func (w *Watcher) emitOrderEvents(orderEvents []*zeroex.OrderEvent) {
+	w.orderFeedMu.Lock()
+	defer w.orderFeedMu.Unlock()
+
+	w.appendToOrderEventLog(orderEvents)
+	// Send blocks until all subscribers received the events, so a slow
+	// subscriber stalls event delivery.
+	w.eventDelivery.start(time.Now(), nil)
+	defer w.eventDelivery.finish()
+	w.orderFeed.Send(orderEvents)
+}
+
+// appendToOrderEventLog assigns the next sequence numbers to the given order
+// events and appends them to the order event log.
+func (w *Watcher) appendToOrderEventLog(orderEvents []*zeroex.OrderEvent) {
+	w.orderEventsMu.Lock()
+	defer w.orderEventsMu.Unlock()
+
+	for _, orderEvent := range orderEvents {
+		w.lastOrderEventSequenceNumber++
+		orderEvent.SequenceNumber = w.lastOrderEventSequenceNumber
+	}
+	if len(orderEvents) > 0 {
+		if err := w.meshDB.AppendOrderEvents(orderEvents); err != nil {
+			logger.WithFields(logger.Fields{
+				"error": err.Error(),
+			}).Error("could not append order events to the order event log")
+		}
+		logSize := uint64(w.orderEventLogSize)
+		if logSize < 1 {
+			logSize = 1
+		}
+		if w.lastOrderEventSequenceNumber > logSize {
+			if err := w.meshDB.DeleteOrderEventsBefore(w.lastOrderEventSequenceNumber - logSize + 1); err != nil {
+				logger.WithFields(logger.Fields{
+					"error": err.Error(),
+				}).Error("could not trim the order event log")
+			}
+		}
+	}
+}
+
+// GetOrderEventsSince returns up to max order events with a sequence number
+// greater than the given sequence number from the order event log. It also
+// returns the sequence numbers of the oldest and latest events in the log.
+func (w *Watcher) GetOrderEventsSince(sequenceNumber uint64, max int) (orderEvents []*zeroex.OrderEvent, oldest uint64, latest uint64, err error) {
+	// Hold the lock so that no events are added to the log between reading the
+	// bounds and reading the events. Note that this does not block on slow
+	// subscribers.
+	w.orderEventsMu.Lock()
+	defer w.orderEventsMu.Unlock()
+
+	oldest, latest, err = w.meshDB.GetOrderEventLogBounds()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	orderEvents, err = w.meshDB.FindOrderEventsSince(sequenceNumber, max)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return orderEvents, oldest, latest, nil
+}
+
+// WithOrderEventsPaused calls fn with the sequence number of the last emitted
+// order event while no further order events are emitted. Since order events
+// are only emitted after the stored orders were updated, a snapshot of the
+// database taken by fn reflects every order event up to that sequence number.
+// It may also reflect some of the following events, but applying those to the
+// snapshot again is harmless. fn should return quickly, since it blocks the
+// Watcher.
+func (w *Watcher) WithOrderEventsPaused(fn func(lastSequenceNumber uint64) error) error {
+	w.orderFeedMu.Lock()
+	defer w.orderFeedMu.Unlock()
+
+	w.orderEventsMu.Lock()
+	lastSequenceNumber := w.lastOrderEventSequenceNumber
+	w.orderEventsMu.Unlock()
+	return fn(lastSequenceNumber)
+}
+
+// recordOrderStateHistory stores the state transitions described by the given
+// order events, which were generated at the given block, in the order state
+// history.
+func (w *Watcher) recordOrderStateHistory(orderEvents []*zeroex.OrderEvent, blockNumber *big.Int) {
+	if w.orderStateHistoryRetentionBlocks == 0 || blockNumber == nil {
+		return
+	}
+	records := make([]*meshdb.OrderStateRecord, len(orderEvents))
+	for i, orderEvent := range orderEvents {
+		records[i] = &meshdb.OrderStateRecord{
+			OrderHash:                orderEvent.OrderHash,
+			BlockNumber:              blockNumber,
+			EndState:                 string(orderEvent.EndState),
+			FillableTakerAssetAmount: orderEvent.FillableTakerAssetAmount,
+			Timestamp:                orderEvent.Timestamp,
+		}
+	}
+	if err := w.meshDB.SaveOrderStateRecords(records); err != nil {
+		logger.WithFields(logger.Fields{
+			"error":       err.Error(),
+			"blockNumber": blockNumber,
+		}).Error("could not save order state history")
+	}
+}
+
+// compactOrderStateHistory compacts the order state history that is older than
+// orderStateHistoryRetentionBlocks relative to the given block.
+func (w *Watcher) compactOrderStateHistory(latestBlockNumber *big.Int) {
+	if w.orderStateHistoryRetentionBlocks == 0 {
+		return
+	}
+	cutoff := big.NewInt(0).Sub(latestBlockNumber, big.NewInt(int64(w.orderStateHistoryRetentionBlocks)))
+	if cutoff.Sign() <= 0 {
+		return
+	}
+	if err := w.meshDB.CompactOrderStateRecordsBefore(cutoff); err != nil {
+		logger.WithFields(logger.Fields{
+			"error":  err.Error(),
+			"cutoff": cutoff,
+		}).Error("could not compact order state history")
+	}
+}
+
 func (w *Watcher) permanentlyDeleteStaleRemovedOrders(ctx context.Context) error {
 	removedOrders, err := w.meshDB.FindRemovedOrders()
 	if err != nil {
@@ -893,15 +1713,32 @@ func (w *Watcher) permanentlyDeleteStaleRemovedOrders(ctx context.Context) error
 		}
 	}
 
+	// Orders can be permanently deleted in several ways (e.g. when they are
+	// trimmed or removed via RemoveOrders), so the annotations of deleted
+	// orders are cleaned up here instead.
+	numAnnotationsDeleted, err := w.meshDB.DeleteOrphanedOrderAnnotations()
+	if err != nil {
+		return err
+	}
+	if numAnnotationsDeleted > 0 {
+		logger.WithField("numOrders", numAnnotationsDeleted).Debug("deleted annotations of permanently deleted orders")
+	}
+
 	return nil
 }
 
-// add adds a 0x order to the DB and watches it for changes in fillability. It
-// will no-op (and return nil) if the order has already been added. If pinned is
-// true, the orders will be marked as pinned. Pinned orders will not be affected
-// by any DDoS prevention or incentive mechanisms and will always stay in
-// storage until they are no longer fillable.
-func (w *Watcher) add(orderInfos []*ordervalidator.AcceptedOrderInfo, validationBlockNumber *big.Int, pinned bool) ([]*zeroex.OrderEvent, error) {
+// add adds a 0x order to the DB and watches it for changes in fillability.
+// Orders which have already been added (e.g. because the same order was
+// delivered by another source in the meantime) are not added again; source is
+// recorded as one of their sources instead. If pinned is true, the orders will
+// be marked as pinned. Pinned orders will not be affected by any DDoS
+// prevention or incentive mechanisms and will always stay in storage until
+// they are no longer fillable. Orders from trusted makers are always pinned.
+// Orders from OrderSourceRPC are marked as added locally so that they can
+// later be removed with RemoveOrders. If provisional is true, the orders are
+// marked as provisional so that they are re-validated once the BlockWatcher
+// has caught up to the chain head.
+func (w *Watcher) add(orderInfos []*ordervalidator.AcceptedOrderInfo, validationBlockNumber *big.Int, pinned bool, provisional bool, source meshdb.OrderSource) ([]*zeroex.OrderEvent, error) {
 	orderEvents, err := w.decreaseMaxExpirationTimeIfNeeded()
 	if err != nil {
 		return orderEvents, err
@@ -917,19 +1754,26 @@ func (w *Watcher) add(orderInfos []*ordervalidator.AcceptedOrderInfo, validation
 	}()
 
 	now := time.Now().UTC()
+	orderFilterHash := w.OrderFilterHash()
 
+	insertedOrderInfos := []*ordervalidator.AcceptedOrderInfo{}
 	for _, orderInfo := range orderInfos {
+		isPinned := pinned || w.IsTrustedMaker(orderInfo.SignedOrder.MakerAddress)
 		order := &meshdb.Order{
 			Hash:                     orderInfo.OrderHash,
 			SignedOrder:              orderInfo.SignedOrder,
 			LastUpdated:              now,
 			FillableTakerAssetAmount: orderInfo.FillableTakerAssetAmount,
 			IsRemoved:                false,
-			IsPinned:                 pinned,
+			IsPinned:                 isPinned,
+			AddedLocally:             source == meshdb.OrderSourceRPC,
+			Sources:                  []meshdb.OrderSource{source},
+			OrderFilterHash:          orderFilterHash,
+			IsProvisional:            provisional,
 		}
 		// Final expiration time check before inserting the order. We might have just
 		// changed max expiration time above.
-		if !pinned && orderInfo.SignedOrder.ExpirationTimeSeconds.Cmp(w.maxExpirationTime) == 1 {
+		if !isPinned && orderInfo.SignedOrder.ExpirationTimeSeconds.Cmp(w.maxExpirationTime) == 1 {
 			// HACK(albrow): This is technically not the ideal way to respond to this
 			// situation, but it is a lot easier to implement for the time being. In the
 			// future, we should return an error and then react to that error
@@ -949,6 +1793,7 @@ func (w *Watcher) add(orderInfos []*ordervalidator.AcceptedOrderInfo, validation
 				SignedOrder:              orderInfo.SignedOrder,
 				FillableTakerAssetAmount: orderInfo.FillableTakerAssetAmount,
 				EndState:                 zeroex.ESOrderAdded,
+				OrderFilterHash:          orderFilterHash,
 			}
 			orderEvents = append(orderEvents, addedEvent)
 			stoppedWatchingEvent := &zeroex.OrderEvent{
@@ -957,25 +1802,32 @@ func (w *Watcher) add(orderInfos []*ordervalidator.AcceptedOrderInfo, validation
 				SignedOrder:              orderInfo.SignedOrder,
 				FillableTakerAssetAmount: orderInfo.FillableTakerAssetAmount,
 				EndState:                 zeroex.ESStoppedWatching,
+				OrderFilterHash:          orderFilterHash,
 			}
 			orderEvents = append(orderEvents, stoppedWatchingEvent)
 		} else {
 			err = txn.Insert(order)
 			if err != nil {
-				if _, ok := err.(db.AlreadyExistsError); ok {
-					// If we're already watching the order, that's fine in this case. Don't
-					// return an error.
-					return orderEvents, nil
-				}
-				if _, ok := err.(db.ConflictingOperationsError); ok {
-					logger.WithFields(logger.Fields{
-						"error": err.Error(),
-						"order": order,
-					}).Error("Failed to insert order into DB")
-					return orderEvents, nil
+				switch err.(type) {
+				case db.AlreadyExistsError:
+					// The order was stored after it was validated, e.g. because another
+					// source delivered it concurrently. This is not an error; we just
+					// record the additional source.
+					if err := w.addOrderSource(txn, orderInfo.OrderHash, source); err != nil {
+						if _, ok := err.(db.ConflictingOperationsError); !ok {
+							return orderEvents, err
+						}
+					}
+					continue
+				case db.ConflictingOperationsError:
+					// The same order was included more than once in orderInfos.
+					w.countDuplicateOrder(source)
+					continue
+				default:
+					return orderEvents, err
 				}
-				return orderEvents, err
 			}
+			insertedOrderInfos = append(insertedOrderInfos, orderInfo)
 		}
 	}
 
@@ -983,7 +1835,7 @@ func (w *Watcher) add(orderInfos []*ordervalidator.AcceptedOrderInfo, validation
 		return orderEvents, err
 	}
 
-	for _, orderInfo := range orderInfos {
+	for _, orderInfo := range insertedOrderInfos {
 		err = w.setupInMemoryOrderState(orderInfo.SignedOrder)
 		if err != nil {
 			return orderEvents, err
@@ -995,6 +1847,7 @@ func (w *Watcher) add(orderInfos []*ordervalidator.AcceptedOrderInfo, validation
 			SignedOrder:              orderInfo.SignedOrder,
 			FillableTakerAssetAmount: orderInfo.FillableTakerAssetAmount,
 			EndState:                 zeroex.ESOrderAdded,
+			OrderFilterHash:          orderFilterHash,
 		}
 		orderEvents = append(orderEvents, addedOrderEvent)
 	}
@@ -1002,10 +1855,9 @@ func (w *Watcher) add(orderInfos []*ordervalidator.AcceptedOrderInfo, validation
 	return orderEvents, nil
 }
 
-func (w *Watcher) trimOrdersAndGenerateEvents() ([]*zeroex.OrderEvent, error) {
+func (w *Watcher) trimOrdersAndGenerateEvents(targetMaxOrders int) ([]*zeroex.OrderEvent, error) {
 	orderEvents := []*zeroex.OrderEvent{}
 
-	targetMaxOrders := int(maxOrdersTrimRatio * float64(w.maxOrders))
 	newMaxExpirationTime, removedOrders, err := w.meshDB.TrimOrdersByExpirationTime(targetMaxOrders)
 	if err != nil {
 		return orderEvents, err
@@ -1025,12 +1877,14 @@ func (w *Watcher) trimOrdersAndGenerateEvents() ([]*zeroex.OrderEvent, error) {
 			SignedOrder:              removedOrder.SignedOrder,
 			FillableTakerAssetAmount: removedOrder.FillableTakerAssetAmount,
 			EndState:                 zeroex.ESStoppedWatching,
+			OrderFilterHash:          removedOrder.OrderFilterHash,
 		}
 		orderEvents = append(orderEvents, orderEvent)
 
 		// Remove in-memory state
 		expirationTimestamp := time.Unix(removedOrder.SignedOrder.ExpirationTimeSeconds.Int64(), 0)
 		w.expirationWatcher.Remove(expirationTimestamp, removedOrder.Hash.Hex())
+		w.untrackStaticCallOrder(removedOrder.Hash)
 		err = w.removeAssetDataAddressFromEventDecoder(removedOrder.SignedOrder.MakerAssetData)
 		if err != nil {
 			// This should never happen since the same error would have happened when adding
@@ -1113,6 +1967,35 @@ func updateBlockHeadersStoredInDB(miniHeadersColTxn *db.Transaction, events []*b
 	return nil
 }
 
+// OrderFilterHash returns the hash of the order filter that will be attached
+// to newly added orders.
+func (w *Watcher) OrderFilterHash() string {
+	w.orderFilterHashMu.RLock()
+	defer w.orderFilterHashMu.RUnlock()
+	return w.orderFilterHash
+}
+
+// SetOrderFilterHash changes the hash of the order filter that will be
+// attached to newly added orders. Orders that were already added keep the
+// hash they were added with.
+func (w *Watcher) SetOrderFilterHash(orderFilterHash string) {
+	w.orderFilterHashMu.Lock()
+	defer w.orderFilterHashMu.Unlock()
+	w.orderFilterHash = orderFilterHash
+}
+
+// MaxExpirationDuration returns the maximum time until new orders expire or 0
+// if it is not limited (see Config.MaxExpirationDuration).
+func (w *Watcher) MaxExpirationDuration() time.Duration {
+	return time.Duration(atomic.LoadInt64(&w.maxExpirationDuration))
+}
+
+// SetMaxExpirationDuration changes the maximum time until new orders expire.
+// Orders that were already added are not affected.
+func (w *Watcher) SetMaxExpirationDuration(maxExpirationDuration time.Duration) {
+	atomic.StoreInt64(&w.maxExpirationDuration, int64(maxExpirationDuration))
+}
+
 // MaxExpirationTime returns the current maximum expiration time for incoming
 // orders.
 func (w *Watcher) MaxExpirationTime() *big.Int {
@@ -1141,9 +2024,31 @@ func (w *Watcher) setupInMemoryOrderState(signedOrder *zeroex.SignedOrder) error
 	expirationTimestamp := time.Unix(signedOrder.ExpirationTimeSeconds.Int64(), 0)
 	w.expirationWatcher.Add(expirationTimestamp, orderHash.Hex())
 
+	if w.orderValidator.HasStaticCallAssetData(signedOrder) {
+		w.staticCallOrderHashesMu.Lock()
+		w.staticCallOrderHashes[orderHash] = struct{}{}
+		w.staticCallOrderHashesMu.Unlock()
+	}
+
 	return nil
 }
 
+func (w *Watcher) untrackStaticCallOrder(orderHash common.Hash) {
+	w.staticCallOrderHashesMu.Lock()
+	defer w.staticCallOrderHashesMu.Unlock()
+	delete(w.staticCallOrderHashes, orderHash)
+}
+
+func (w *Watcher) getStaticCallOrderHashes() []common.Hash {
+	w.staticCallOrderHashesMu.Lock()
+	defer w.staticCallOrderHashesMu.Unlock()
+	orderHashes := make([]common.Hash, 0, len(w.staticCallOrderHashes))
+	for orderHash := range w.staticCallOrderHashes {
+		orderHashes = append(orderHashes, orderHash)
+	}
+	return orderHashes
+}
+
 // Subscribe allows one to subscribe to the order events emitted by the OrderWatcher.
 // To unsubscribe, simply call `Unsubscribe` on the returned subscription.
 // The sink channel should have ample buffer space to avoid blocking other subscribers.
@@ -1225,6 +2130,7 @@ func (w *Watcher) convertValidationResultsIntoOrderEvents(
 				FillableTakerAssetAmount: acceptedOrderInfo.FillableTakerAssetAmount,
 				EndState:                 zeroex.ESOrderAdded,
 				ContractEvents:           orderHashToEvents[order.Hash],
+				OrderFilterHash:          order.OrderFilterHash,
 			}
 			orderEvents = append(orderEvents, orderEvent)
 		} else {
@@ -1240,6 +2146,7 @@ func (w *Watcher) convertValidationResultsIntoOrderEvents(
 						SignedOrder:              order.SignedOrder,
 						FillableTakerAssetAmount: order.FillableTakerAssetAmount,
 						EndState:                 zeroex.ESOrderUnexpired,
+						OrderFilterHash:          order.OrderFilterHash,
 					}
 					orderEvents = append(orderEvents, orderEvent)
 				}
@@ -1256,6 +2163,7 @@ func (w *Watcher) convertValidationResultsIntoOrderEvents(
 						SignedOrder:              order.SignedOrder,
 						FillableTakerAssetAmount: order.FillableTakerAssetAmount,
 						EndState:                 zeroex.ESOrderUnexpired,
+						OrderFilterHash:          order.OrderFilterHash,
 					}
 					orderEvents = append(orderEvents, orderEvent)
 				} else {
@@ -1270,6 +2178,7 @@ func (w *Watcher) convertValidationResultsIntoOrderEvents(
 					EndState:                 zeroex.ESOrderFilled,
 					FillableTakerAssetAmount: acceptedOrderInfo.FillableTakerAssetAmount,
 					ContractEvents:           orderHashToEvents[order.Hash],
+					OrderFilterHash:          order.OrderFilterHash,
 				}
 				orderEvents = append(orderEvents, orderEvent)
 			} else if oldFillableAmount.Cmp(big.NewInt(0)) == 1 && !oldAmountIsMoreThenNewAmount {
@@ -1283,6 +2192,7 @@ func (w *Watcher) convertValidationResultsIntoOrderEvents(
 						SignedOrder:              order.SignedOrder,
 						FillableTakerAssetAmount: order.FillableTakerAssetAmount,
 						EndState:                 zeroex.ESOrderUnexpired,
+						OrderFilterHash:          order.OrderFilterHash,
 					}
 					orderEvents = append(orderEvents, orderEvent)
 				} else {
@@ -1296,6 +2206,7 @@ func (w *Watcher) convertValidationResultsIntoOrderEvents(
 					EndState:                 zeroex.ESOrderFillabilityIncreased,
 					FillableTakerAssetAmount: acceptedOrderInfo.FillableTakerAssetAmount,
 					ContractEvents:           orderHashToEvents[order.Hash],
+					OrderFilterHash:          order.OrderFilterHash,
 				}
 				orderEvents = append(orderEvents, orderEvent)
 			}
@@ -1334,6 +2245,7 @@ func (w *Watcher) convertValidationResultsIntoOrderEvents(
 					FillableTakerAssetAmount: big.NewInt(0),
 					EndState:                 endState,
 					ContractEvents:           orderHashToEvents[order.Hash],
+					OrderFilterHash:          order.OrderFilterHash,
 				}
 				orderEvents = append(orderEvents, orderEvent)
 			}
@@ -1377,17 +2289,36 @@ func (w *Watcher) generateOrderEventsIfChanged(
 }
 
 // ValidateAndStoreValidOrders applies general 0x validation and Mesh-specific validation to
-// the given orders and if they are valid, adds them to the OrderWatcher
-func (w *Watcher) ValidateAndStoreValidOrders(ctx context.Context, orders []*zeroex.SignedOrder, pinned bool, chainID int) (*ordervalidator.ValidationResults, error) {
-	results, validMeshOrders, err := w.meshSpecificOrderValidation(orders, chainID)
+// the given orders and if they are valid, adds them to the OrderWatcher. source is the way in
+// which the orders were received. Orders that are already stored are accepted (with IsNew set
+// to false) and source is recorded as one of their sources.
+func (w *Watcher) ValidateAndStoreValidOrders(ctx context.Context, orders []*zeroex.SignedOrder, pinned bool, source meshdb.OrderSource, chainID int) (*ordervalidator.ValidationResults, error) {
+	// The watchdog cancels the context if the batch stalls (see
+	// WatchdogConfig.RestartStalledStages).
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	batchID := w.validationQueue.add(source, len(orders), time.Now(), cancel)
+	defer func() {
+		w.validationQueue.remove(batchID, time.Now())
+	}()
+
+	results, validMeshOrders, err := w.meshSpecificOrderValidation(orders, pinned, chainID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Lock down the processing of additional block events until we've validated and added these new orders
+	w.validationQueue.update(batchID, WaitReasonBlockProcessing, len(validMeshOrders))
 	w.handleBlockEventsMu.RLock()
 	defer w.handleBlockEventsMu.RUnlock()
 
+	// Orders validated while the BlockWatcher is catching up to the chain head
+	// are validated against state that may be stale.
+	provisional, err := w.isBehindChainHead()
+	if err != nil {
+		return nil, err
+	}
+	w.validationQueue.update(batchID, WaitReasonEthRPC, len(validMeshOrders))
 	validationBlock, zeroexResults, err := w.onchainOrderValidation(ctx, validMeshOrders)
 	if err != nil {
 		return nil, err
@@ -1395,31 +2326,51 @@ func (w *Watcher) ValidateAndStoreValidOrders(ctx context.Context, orders []*zer
 	results.Accepted = append(results.Accepted, zeroexResults.Accepted...)
 	results.Rejected = append(results.Rejected, zeroexResults.Rejected...)
 
+	requestID := requestid.FromContext(ctx)
+	for _, rejectedOrderInfo := range results.Rejected {
+		logger.WithFields(logger.Fields{
+			"orderHash": rejectedOrderInfo.OrderHash.Hex(),
+			"code":      rejectedOrderInfo.Status.Code,
+			"requestID": requestID,
+		}).Debug("rejected order during validation")
+	}
+
 	// Filter out only the new orders.
 	newOrderInfos := []*ordervalidator.AcceptedOrderInfo{}
+	duplicateOrderHashes := []common.Hash{}
 	for _, acceptedOrderInfo := range results.Accepted {
 		// If the order isn't new, we don't add to OrderWatcher.
 		if acceptedOrderInfo.IsNew {
 			newOrderInfos = append(newOrderInfos, acceptedOrderInfo)
+		} else {
+			duplicateOrderHashes = append(duplicateOrderHashes, acceptedOrderInfo.OrderHash)
 		}
 	}
+	if err := w.recordDuplicateOrders(duplicateOrderHashes, source); err != nil {
+		return nil, err
+	}
 
 	// Add the order to the OrderWatcher. This also saves the order in the
 	// database.
+	w.validationQueue.update(batchID, WaitReasonStoring, len(newOrderInfos))
 	allOrderEvents := []*zeroex.OrderEvent{}
-	orderEvents, err := w.add(newOrderInfos, validationBlock.Number, pinned)
+	orderEvents, err := w.add(newOrderInfos, validationBlock.Number, pinned, provisional, source)
 	if err != nil {
 		return nil, err
 	}
+	for _, orderEvent := range orderEvents {
+		orderEvent.RequestID = requestID
+	}
 	allOrderEvents = append(allOrderEvents, orderEvents...)
 
 	if len(allOrderEvents) > 0 {
+		w.recordOrderStateHistory(allOrderEvents, validationBlock.Number)
 		// NOTE(albrow): Send can block if the subscriber(s) are slow. Blocking here can cause problems when Mesh is
 		// shutting down, so to prevent that, we call Send in a goroutine and return immediately if the context
 		// is done.
 		done := make(chan interface{})
 		go func() {
-			w.orderFeed.Send(allOrderEvents)
+			w.emitOrderEvents(allOrderEvents)
 			done <- struct{}{}
 		}()
 		select {
@@ -1433,6 +2384,12 @@ func (w *Watcher) ValidateAndStoreValidOrders(ctx context.Context, orders []*zer
 	return results, nil
 }
 
+// ValidationQueue returns a summary of the batches of orders which are
+// currently being validated by ValidateAndStoreValidOrders.
+func (w *Watcher) ValidationQueue() *ValidationQueue {
+	return w.validationQueue.summary(time.Now())
+}
+
 func (w *Watcher) onchainOrderValidation(ctx context.Context, orders []*zeroex.SignedOrder) (*miniheader.MiniHeader, *ordervalidator.ValidationResults, error) {
 	// HACK(fabio): While we wait for EIP-1898 support in Parity, we have no choice but to do the `eth_call`
 	// at the latest known block _number_. As outlined in the `Rationale` section of EIP-1898, this approach cannot account
@@ -1452,7 +2409,7 @@ func (w *Watcher) onchainOrderValidation(ctx context.Context, orders []*zeroex.S
 	return validationBlock, zeroexResults, nil
 }
 
-func (w *Watcher) meshSpecificOrderValidation(orders []*zeroex.SignedOrder, chainID int) (*ordervalidator.ValidationResults, []*zeroex.SignedOrder, error) {
+func (w *Watcher) meshSpecificOrderValidation(orders []*zeroex.SignedOrder, pinned bool, chainID int) (*ordervalidator.ValidationResults, []*zeroex.SignedOrder, error) {
 	results := &ordervalidator.ValidationResults{}
 	validMeshOrders := []*zeroex.SignedOrder{}
 	for _, order := range orders {
@@ -1467,7 +2424,16 @@ func (w *Watcher) meshSpecificOrderValidation(orders []*zeroex.SignedOrder, chai
 			})
 			continue
 		}
-		if order.ExpirationTimeSeconds.Cmp(w.MaxExpirationTime()) == 1 {
+		if !w.IsTrustedMaker(order.MakerAddress) && order.ExpirationTimeSeconds.Cmp(w.MaxExpirationTime()) == 1 {
+			results.Rejected = append(results.Rejected, &ordervalidator.RejectedOrderInfo{
+				OrderHash:   orderHash,
+				SignedOrder: order,
+				Kind:        ordervalidator.MeshValidation,
+				Status:      ordervalidator.ROMaxExpirationExceeded,
+			})
+			continue
+		}
+		if maxExpirationDuration := w.MaxExpirationDuration(); maxExpirationDuration != 0 && order.ExpirationTimeSeconds.Cmp(big.NewInt(time.Now().Add(maxExpirationDuration).Unix())) == 1 {
 			results.Rejected = append(results.Rejected, &ordervalidator.RejectedOrderInfo{
 				OrderHash:   orderHash,
 				SignedOrder: order,
@@ -1516,8 +2482,9 @@ func (w *Watcher) meshSpecificOrderValidation(orders []*zeroex.SignedOrder, chai
 			}
 		}
 
-		if err := validateOrderSize(order); err != nil {
+		if err := w.validateOrderSize(order); err != nil {
 			if err == constants.ErrMaxOrderSize {
+				atomic.AddInt64(&w.numOversizedOrdersRejected, 1)
 				results.Rejected = append(results.Rejected, &ordervalidator.RejectedOrderInfo{
 					OrderHash:   orderHash,
 					SignedOrder: order,
@@ -1569,23 +2536,116 @@ func (w *Watcher) meshSpecificOrderValidation(orders []*zeroex.SignedOrder, chai
 			}
 		}
 
+		// New orders are only stored while the database is within its disk
+		// usage budget, unless they are pinned.
+		if !pinned && w.DiskBudgetExceeded() && !w.IsTrustedMaker(order.MakerAddress) {
+			results.Rejected = append(results.Rejected, &ordervalidator.RejectedOrderInfo{
+				OrderHash:   orderHash,
+				SignedOrder: order,
+				Kind:        ordervalidator.MeshValidation,
+				Status:      ordervalidator.ROMaxDiskUsageExceeded,
+			})
+			continue
+		}
+
 		validMeshOrders = append(validMeshOrders, order)
 	}
 
 	return results, validMeshOrders, nil
 }
 
-func validateOrderSize(order *zeroex.SignedOrder) error {
+func (w *Watcher) validateOrderSize(order *zeroex.SignedOrder) error {
 	encoded, err := json.Marshal(order)
 	if err != nil {
 		return err
 	}
-	if len(encoded) > constants.MaxOrderSizeInBytes {
+	if len(encoded) > w.maxOrderSizeInBytes {
 		return constants.ErrMaxOrderSize
 	}
 	return nil
 }
 
+// IsTrustedMaker returns true if orders from the given maker address bypass
+// Mesh's anti-spam mechanisms (see Config.TrustedMakerAddresses).
+func (w *Watcher) IsTrustedMaker(makerAddress common.Address) bool {
+	_, found := w.trustedMakers[makerAddress]
+	return found
+}
+
+// MaxOrderSizeInBytes returns the maximum size of an order encoded as JSON.
+func (w *Watcher) MaxOrderSizeInBytes() int {
+	return w.maxOrderSizeInBytes
+}
+
+// recordDuplicateOrders records source as one of the sources of the given
+// orders, which were already stored when they were delivered again, and
+// counts them as duplicates.
+func (w *Watcher) recordDuplicateOrders(orderHashes []common.Hash, source meshdb.OrderSource) error {
+	if len(orderHashes) == 0 {
+		return nil
+	}
+	txn := w.meshDB.Orders.OpenTransaction()
+	defer func() {
+		_ = txn.Discard()
+	}()
+	for _, orderHash := range orderHashes {
+		if err := w.addOrderSource(txn, orderHash, source); err != nil {
+			switch err.(type) {
+			case db.NotFoundError, db.ConflictingOperationsError:
+				// The order was deleted in the meantime or was included more than
+				// once in orderHashes.
+				continue
+			default:
+				return err
+			}
+		}
+	}
+	return txn.Commit()
+}
+
+// addOrderSource counts a duplicate delivery of the stored order with the
+// given hash and adds source to its sources if it isn't already one of them.
+// The order is read within txn so that no other changes to it can be made in
+// between, and only its sources are changed. In particular, orders received
+// from peers are not marked as added locally when they are delivered again
+// through the RPC API.
+func (w *Watcher) addOrderSource(txn *db.Transaction, orderHash common.Hash, source meshdb.OrderSource) error {
+	w.countDuplicateOrder(source)
+	var order meshdb.Order
+	if err := txn.FindByID(orderHash.Bytes(), &order); err != nil {
+		return err
+	}
+	if order.HasSource(source) {
+		return nil
+	}
+	order.Sources = append(order.Sources, source)
+	return txn.Update(&order)
+}
+
+func (w *Watcher) countDuplicateOrder(source meshdb.OrderSource) {
+	w.numDuplicateOrdersMu.Lock()
+	defer w.numDuplicateOrdersMu.Unlock()
+	w.numDuplicateOrders[source]++
+}
+
+// NumDuplicateOrders returns the number of times an order that was already
+// stored was delivered again, by source.
+func (w *Watcher) NumDuplicateOrders() map[string]int64 {
+	w.numDuplicateOrdersMu.Lock()
+	defer w.numDuplicateOrdersMu.Unlock()
+	numDuplicateOrders := make(map[string]int64, len(w.numDuplicateOrders))
+	for source, count := range w.numDuplicateOrders {
+		numDuplicateOrders[string(source)] = count
+	}
+	return numDuplicateOrders
+}
+
+// NumOversizedOrdersRejected returns the number of orders that have been
+// rejected because they exceeded the maximum order size.
+func (w *Watcher) NumOversizedOrdersRejected() int64 {
+	return atomic.LoadInt64(&w.numOversizedOrdersRejected)
+}
+
 type orderUpdater interface {
 	Update(model db.Model) error
 }
@@ -1654,6 +2714,8 @@ func (w *Watcher) permanentlyDeleteOrder(deleter orderDeleter, order *meshdb.Ord
 		return err
 	}
 
+	w.untrackStaticCallOrder(order.Hash)
+
 	// After permanently deleting an order, we also remove it's assetData from the Decoder
 	err = w.removeAssetDataAddressFromEventDecoder(order.SignedOrder.MakerAssetData)
 	if err != nil {
@@ -1665,6 +2727,18 @@ func (w *Watcher) permanentlyDeleteOrder(deleter orderDeleter, order *meshdb.Ord
 		}).Error("Unexpected error when trying to remove an assetData from decoder")
 		return err
 	}
+	// The MakerFeeAssetData was only added to the Decoder if there is a maker
+	// fee (see setupInMemoryOrderState).
+	if order.SignedOrder.MakerFee.Cmp(big.NewInt(0)) == 1 {
+		err = w.removeAssetDataAddressFromEventDecoder(order.SignedOrder.MakerFeeAssetData)
+		if err != nil {
+			logger.WithFields(logger.Fields{
+				"error":       err.Error(),
+				"signedOrder": order.SignedOrder,
+			}).Error("Unexpected error when trying to remove an assetData from decoder")
+			return err
+		}
+	}
 
 	return nil
 }
@@ -1695,6 +2769,12 @@ func (w *Watcher) checkDecodeErr(err error, eventType string) bool {
 func (w *Watcher) addAssetDataAddressToEventDecoder(assetData []byte) error {
 	assetDataName, err := w.assetDataDecoder.GetName(assetData)
 	if err != nil {
+		if _, ok := err.(zeroex.UnrecognizedAssetDataError); ok {
+			// Orders involving unrecognized asset proxies are only accepted
+			// if balance tracking was disabled for them (see
+			// OrderValidator.SetAcceptUnrecognizedAssetProxies).
+			return nil
+		}
 		return err
 	}
 	switch assetDataName {
@@ -1744,7 +2824,15 @@ func (w *Watcher) addAssetDataAddressToEventDecoder(assetData []byte) error {
 			}
 		}
 	default:
-		return fmt.Errorf("unrecognized assetData type name found: %s", assetDataName)
+		nestedAssetDatas, err := w.assetDataDecoder.DecodeNestedAssetData(assetData)
+		if err != nil {
+			return fmt.Errorf("unrecognized assetData type name found: %s", assetDataName)
+		}
+		for _, assetData := range nestedAssetDatas {
+			if err := w.addAssetDataAddressToEventDecoder(assetData); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
@@ -1756,6 +2844,12 @@ func (w *Watcher) addAssetDataAddressToEventDecoder(assetData []byte) error {
 func (w *Watcher) removeAssetDataAddressFromEventDecoder(assetData []byte) error {
 	assetDataName, err := w.assetDataDecoder.GetName(assetData)
 	if err != nil {
+		if _, ok := err.(zeroex.UnrecognizedAssetDataError); ok {
+			// Orders involving unrecognized asset proxies are only accepted
+			// if balance tracking was disabled for them (see
+			// OrderValidator.SetAcceptUnrecognizedAssetProxies).
+			return nil
+		}
 		return err
 	}
 	switch assetDataName {
@@ -1810,7 +2904,15 @@ func (w *Watcher) removeAssetDataAddressFromEventDecoder(assetData []byte) error
 			}
 		}
 	default:
-		return fmt.Errorf("unrecognized assetData type name found: %s", assetDataName)
+		nestedAssetDatas, err := w.assetDataDecoder.DecodeNestedAssetData(assetData)
+		if err != nil {
+			return fmt.Errorf("unrecognized assetData type name found: %s", assetDataName)
+		}
+		for _, assetData := range nestedAssetDatas {
+			if err := w.removeAssetDataAddressFromEventDecoder(assetData); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
@@ -1820,7 +2922,7 @@ func (w *Watcher) decreaseMaxExpirationTimeIfNeeded() ([]*zeroex.OrderEvent, err
 	if orderCount, err := w.meshDB.Orders.Count(); err != nil {
 		return orderEvents, err
 	} else if orderCount+1 > w.maxOrders {
-		return w.trimOrdersAndGenerateEvents()
+		return w.trimOrdersAndGenerateEvents(int(maxOrdersTrimRatio * float64(w.maxOrders)))
 	}
 	return orderEvents, nil
 }
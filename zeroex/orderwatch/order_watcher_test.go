@@ -636,6 +636,81 @@ func TestOrderWatcherWETHWithdrawAndDeposit(t *testing.T) {
 	assert.Equal(t, big.NewInt(0), orders[0].FillableTakerAssetAmount)
 }
 
+func TestOrderWatcherWETHWithdrawAndDepositForMakerFee(t *testing.T) {
+	if !serialTestsEnabled {
+		t.Skip("Serial tests (tests which cannot run in parallel) are disabled. You can enable them with the --serial flag")
+	}
+
+	teardownSubTest := setupSubTest(t)
+	defer teardownSubTest(t)
+
+	meshDB, err := meshdb.New("/tmp/leveldb_testing/"+uuid.New().String(), ganacheAddresses)
+	require.NoError(t, err)
+
+	makerAssetData := scenario.GetDummyERC721AssetData(big.NewInt(1))
+	wethFeeAmount := new(big.Int).Mul(big.NewInt(5), eighteenDecimalsInBaseUnits)
+	signedOrder := scenario.NewSignedTestOrder(t,
+		orderopts.SetupMakerState(true),
+		orderopts.MakerAssetData(makerAssetData),
+		orderopts.MakerAssetAmount(big.NewInt(1)),
+		orderopts.MakerFeeAssetData(scenario.WETHAssetData),
+		orderopts.MakerFee(wethFeeAmount),
+	)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	blockWatcher, orderEventsChan := setupOrderWatcherScenario(ctx, t, ethClient, meshDB, signedOrder)
+
+	// Withdraw the WETH the maker needs to pay the maker fee. As in
+	// TestOrderWatcherWETHWithdrawAndDeposit, the txn fails with an "out of gas"
+	// error with the estimated gas amount.
+	gasLimit := uint64(50000)
+	opts := &bind.TransactOpts{
+		From:     signedOrder.MakerAddress,
+		Signer:   scenario.GetTestSignerFn(signedOrder.MakerAddress),
+		GasLimit: gasLimit,
+	}
+	txn, err := weth.Withdraw(opts, wethFeeAmount)
+	require.NoError(t, err)
+	waitTxnSuccessfullyMined(t, ethClient, txn)
+
+	err = blockWatcher.SyncToLatestBlock()
+	require.NoError(t, err)
+
+	orderEvents := waitForOrderEvents(t, orderEventsChan, 1, 4*time.Second)
+	require.Len(t, orderEvents, 1)
+	orderEvent := orderEvents[0]
+	assert.Equal(t, zeroex.ESOrderBecameUnfunded, orderEvent.EndState)
+	require.Len(t, orderEvent.ContractEvents, 1)
+	assert.Equal(t, "WethWithdrawalEvent", orderEvent.ContractEvents[0].Kind)
+
+	// Deposit the maker's ETH again
+	opts = &bind.TransactOpts{
+		From:   signedOrder.MakerAddress,
+		Signer: scenario.GetTestSignerFn(signedOrder.MakerAddress),
+		Value:  wethFeeAmount,
+	}
+	txn, err = weth.Deposit(opts)
+	require.NoError(t, err)
+	waitTxnSuccessfullyMined(t, ethClient, txn)
+
+	err = blockWatcher.SyncToLatestBlock()
+	require.NoError(t, err)
+
+	orderEvents = waitForOrderEvents(t, orderEventsChan, 1, 4*time.Second)
+	require.Len(t, orderEvents, 1)
+	orderEvent = orderEvents[0]
+	assert.Equal(t, zeroex.ESOrderAdded, orderEvent.EndState)
+	require.Len(t, orderEvent.ContractEvents, 1)
+	assert.Equal(t, "WethDepositEvent", orderEvent.ContractEvents[0].Kind)
+
+	var orders []*meshdb.Order
+	err = meshDB.Orders.FindAll(&orders)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, orderEvent.OrderHash, orders[0].Hash)
+	assert.Equal(t, false, orders[0].IsRemoved)
+}
+
 func TestOrderWatcherCanceled(t *testing.T) {
 	if !serialTestsEnabled {
 		t.Skip("Serial tests (tests which cannot run in parallel) are disabled. You can enable them with the --serial flag")
@@ -1038,7 +1113,7 @@ func TestOrderWatcherBatchEmitsAddedEvents(t *testing.T) {
 	err = blockWatcher.SyncToLatestBlock()
 	require.NoError(t, err)
 
-	validationResults, err := orderWatcher.ValidateAndStoreValidOrders(ctx, signedOrders, false, constants.TestChainID)
+	validationResults, err := orderWatcher.ValidateAndStoreValidOrders(ctx, signedOrders, false, meshdb.OrderSourceGossip, constants.TestChainID)
 	require.Len(t, validationResults.Rejected, 0)
 	require.NoError(t, err)
 
@@ -1054,6 +1129,280 @@ func TestOrderWatcherBatchEmitsAddedEvents(t *testing.T) {
 	require.Len(t, orders, numOrders)
 }
 
+func TestOrderWatcherRemoveOrders(t *testing.T) {
+	if !serialTestsEnabled {
+		t.Skip("Serial tests (tests which cannot run in parallel) are disabled. You can enable them with the --serial flag")
+	}
+
+	teardownSubTest := setupSubTest(t)
+	defer teardownSubTest(t)
+
+	meshDB, err := meshdb.New("/tmp/leveldb_testing/"+uuid.New().String(), ganacheAddresses)
+	require.NoError(t, err)
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+
+	blockWatcher, orderWatcher := setupOrderWatcher(ctx, t, ethRPCClient, meshDB)
+
+	orderEventsChan := make(chan []*zeroex.OrderEvent, 10)
+	orderWatcher.Subscribe(orderEventsChan)
+
+	// Create one order that is added locally and one that is received from a
+	// peer.
+	orderOptions := scenario.OptionsForAll(orderopts.SetupMakerState(true))
+	signedOrders := scenario.NewSignedTestOrdersBatch(t, 2, orderOptions)
+	time.Sleep(500 * time.Millisecond)
+	err = blockWatcher.SyncToLatestBlock()
+	require.NoError(t, err)
+
+	validationResults, err := orderWatcher.ValidateAndStoreValidOrders(ctx, signedOrders[:1], false, meshdb.OrderSourceRPC, constants.TestChainID)
+	require.NoError(t, err)
+	require.Len(t, validationResults.Accepted, 1)
+	<-orderEventsChan
+	localOrderHash := validationResults.Accepted[0].OrderHash
+
+	validationResults, err = orderWatcher.ValidateAndStoreValidOrders(ctx, signedOrders[1:], false, meshdb.OrderSourceGossip, constants.TestChainID)
+	require.NoError(t, err)
+	require.Len(t, validationResults.Accepted, 1)
+	<-orderEventsChan
+	peerOrderHash := validationResults.Accepted[0].OrderHash
+
+	unknownOrderHash := common.HexToHash("0x1")
+	results, err := orderWatcher.RemoveOrders([]common.Hash{localOrderHash, peerOrderHash, unknownOrderHash}, "no longer wanted")
+	require.NoError(t, err)
+	assert.Equal(t, []common.Hash{localOrderHash}, results.Removed)
+	assert.Equal(t, map[common.Hash]string{
+		peerOrderHash:    RemoveOrderNotAddedLocally,
+		unknownOrderHash: RemoveOrderNotFound,
+	}, results.NotRemoved)
+
+	orderEvents := <-orderEventsChan
+	require.Len(t, orderEvents, 1)
+	assert.Equal(t, localOrderHash, orderEvents[0].OrderHash)
+	assert.Equal(t, zeroex.ESOrderRemoved, orderEvents[0].EndState)
+	assert.Equal(t, "no longer wanted", orderEvents[0].RemovalReason)
+
+	var orders []*meshdb.Order
+	err = meshDB.Orders.FindAll(&orders)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, peerOrderHash, orders[0].Hash)
+}
+
+func TestOrderWatcherRemoveOrdersNotMatching(t *testing.T) {
+	if !serialTestsEnabled {
+		t.Skip("Serial tests (tests which cannot run in parallel) are disabled. You can enable them with the --serial flag")
+	}
+
+	teardownSubTest := setupSubTest(t)
+	defer teardownSubTest(t)
+
+	meshDB, err := meshdb.New("/tmp/leveldb_testing/"+uuid.New().String(), ganacheAddresses)
+	require.NoError(t, err)
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+
+	blockWatcher, orderWatcher := setupOrderWatcher(ctx, t, ethRPCClient, meshDB)
+
+	orderEventsChan := make(chan []*zeroex.OrderEvent, 10)
+	orderWatcher.Subscribe(orderEventsChan)
+
+	orderOptions := scenario.OptionsForAll(orderopts.SetupMakerState(true))
+	signedOrders := scenario.NewSignedTestOrdersBatch(t, 2, orderOptions)
+	time.Sleep(500 * time.Millisecond)
+	err = blockWatcher.SyncToLatestBlock()
+	require.NoError(t, err)
+
+	validationResults, err := orderWatcher.ValidateAndStoreValidOrders(ctx, signedOrders, false, meshdb.OrderSourceGossip, constants.TestChainID)
+	require.NoError(t, err)
+	require.Len(t, validationResults.Accepted, 2)
+	<-orderEventsChan
+	keptOrderHash, err := signedOrders[0].ComputeOrderHash()
+	require.NoError(t, err)
+	removedOrderHash, err := signedOrders[1].ComputeOrderHash()
+	require.NoError(t, err)
+
+	// Orders received from peers are removed too.
+	removed, err := orderWatcher.RemoveOrdersNotMatching(func(order *zeroex.SignedOrder) (bool, error) {
+		orderHash, err := order.ComputeOrderHash()
+		return orderHash == keptOrderHash, err
+	}, "ORDER_FILTER_CHANGED")
+	require.NoError(t, err)
+	assert.Equal(t, []common.Hash{removedOrderHash}, removed)
+
+	orderEvents := <-orderEventsChan
+	require.Len(t, orderEvents, 1)
+	assert.Equal(t, removedOrderHash, orderEvents[0].OrderHash)
+	assert.Equal(t, zeroex.ESOrderRemoved, orderEvents[0].EndState)
+	assert.Equal(t, "ORDER_FILTER_CHANGED", orderEvents[0].RemovalReason)
+
+	var orders []*meshdb.Order
+	err = meshDB.Orders.FindAll(&orders)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, keptOrderHash, orders[0].Hash)
+}
+
+func TestOrderWatcherDuplicateOrdersFromMultipleSources(t *testing.T) {
+	if !serialTestsEnabled {
+		t.Skip("Serial tests (tests which cannot run in parallel) are disabled. You can enable them with the --serial flag")
+	}
+
+	teardownSubTest := setupSubTest(t)
+	defer teardownSubTest(t)
+
+	meshDB, err := meshdb.New("/tmp/leveldb_testing/"+uuid.New().String(), ganacheAddresses)
+	require.NoError(t, err)
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+
+	blockWatcher, orderWatcher := setupOrderWatcher(ctx, t, ethRPCClient, meshDB)
+
+	orderEventsChan := make(chan []*zeroex.OrderEvent, 10)
+	orderWatcher.Subscribe(orderEventsChan)
+
+	signedOrder := scenario.NewSignedTestOrder(t, orderopts.SetupMakerState(true))
+	time.Sleep(500 * time.Millisecond)
+	err = blockWatcher.SyncToLatestBlock()
+	require.NoError(t, err)
+
+	validationResults, err := orderWatcher.ValidateAndStoreValidOrders(ctx, []*zeroex.SignedOrder{signedOrder}, false, meshdb.OrderSourceGossip, constants.TestChainID)
+	require.NoError(t, err)
+	require.Len(t, validationResults.Rejected, 0)
+	orderEvents := <-orderEventsChan
+	require.Len(t, orderEvents, 1)
+	assert.Equal(t, zeroex.ESOrderAdded, orderEvents[0].EndState)
+	orderHash := orderEvents[0].OrderHash
+
+	for _, source := range []meshdb.OrderSource{meshdb.OrderSourceOrderSync, meshdb.OrderSourceRPC, meshdb.OrderSourceOrderSync} {
+		validationResults, err = orderWatcher.ValidateAndStoreValidOrders(ctx, []*zeroex.SignedOrder{signedOrder}, false, source, constants.TestChainID)
+		require.NoError(t, err)
+		require.Len(t, validationResults.Rejected, 0)
+		require.Len(t, validationResults.Accepted, 1)
+		assert.False(t, validationResults.Accepted[0].IsNew)
+	}
+
+	select {
+	case <-orderEventsChan:
+		t.Error("Expected no order events for duplicate orders")
+	case <-time.After(100 * time.Millisecond):
+		// Noop
+	}
+
+	var dbOrder meshdb.Order
+	err = meshDB.Orders.FindByID(orderHash.Bytes(), &dbOrder)
+	require.NoError(t, err)
+	assert.Equal(t, []meshdb.OrderSource{meshdb.OrderSourceGossip, meshdb.OrderSourceOrderSync, meshdb.OrderSourceRPC}, dbOrder.Sources)
+	// The order was first received from a peer, so it must not become
+	// removable through the RPC API when it is delivered again.
+	assert.False(t, dbOrder.AddedLocally)
+	assert.Equal(t, map[string]int64{"ordersync": 2, "rpc": 1}, orderWatcher.NumDuplicateOrders())
+}
+
+func TestOrderWatcherRevalidateOrders(t *testing.T) {
+	if !serialTestsEnabled {
+		t.Skip("Serial tests (tests which cannot run in parallel) are disabled. You can enable them with the --serial flag")
+	}
+
+	teardownSubTest := setupSubTest(t)
+	defer teardownSubTest(t)
+
+	meshDB, err := meshdb.New("/tmp/leveldb_testing/"+uuid.New().String(), ganacheAddresses)
+	require.NoError(t, err)
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+
+	blockWatcher, orderWatcher := setupOrderWatcher(ctx, t, ethRPCClient, meshDB)
+
+	signedOrder := scenario.NewSignedTestOrder(t, orderopts.SetupMakerState(true))
+	watchOrder(ctx, t, orderWatcher, blockWatcher, ethClient, signedOrder)
+	orderHash, err := signedOrder.ComputeOrderHash()
+	require.NoError(t, err)
+
+	// Make the stored state of the order out of date.
+	dbOrder := &meshdb.Order{}
+	err = meshDB.Orders.FindByID(orderHash.Bytes(), dbOrder)
+	require.NoError(t, err)
+	dbOrder.FillableTakerAssetAmount = big.NewInt(1)
+	err = meshDB.Orders.Update(dbOrder)
+	require.NoError(t, err)
+
+	orderEventsChan := make(chan []*zeroex.OrderEvent, 10)
+	orderWatcher.Subscribe(orderEventsChan)
+
+	unknownOrderHash := common.HexToHash("0x1")
+	results, err := orderWatcher.RevalidateOrders(ctx, []common.Hash{orderHash, unknownOrderHash})
+	require.NoError(t, err)
+	require.Len(t, results.Orders, 1)
+	assert.Equal(t, orderHash, results.Orders[0].Hash)
+	assert.Equal(t, signedOrder.TakerAssetAmount, results.Orders[0].FillableTakerAssetAmount)
+	assert.Equal(t, map[common.Hash]string{unknownOrderHash: RevalidateOrderNotFound}, results.NotRevalidated)
+
+	orderEvents := <-orderEventsChan
+	require.Len(t, orderEvents, 1)
+	assert.Equal(t, orderHash, orderEvents[0].OrderHash)
+	assert.Equal(t, zeroex.ESOrderFillabilityIncreased, orderEvents[0].EndState)
+
+	err = meshDB.Orders.FindByID(orderHash.Bytes(), dbOrder)
+	require.NoError(t, err)
+	assert.Equal(t, signedOrder.TakerAssetAmount, dbOrder.FillableTakerAssetAmount)
+}
+
+func TestOrderWatcherRevalidateProvisionalOrders(t *testing.T) {
+	if !serialTestsEnabled {
+		t.Skip("Serial tests (tests which cannot run in parallel) are disabled. You can enable them with the --serial flag")
+	}
+
+	teardownSubTest := setupSubTest(t)
+	defer teardownSubTest(t)
+
+	meshDB, err := meshdb.New("/tmp/leveldb_testing/"+uuid.New().String(), ganacheAddresses)
+	require.NoError(t, err)
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+
+	blockWatcher, orderWatcher := setupOrderWatcher(ctx, t, ethRPCClient, meshDB)
+	orderWatcher.provisionalOrderBlocksBehind = 1
+
+	signedOrder := scenario.NewSignedTestOrder(t, orderopts.SetupMakerState(true))
+	watchOrder(ctx, t, orderWatcher, blockWatcher, ethClient, signedOrder)
+	orderHash, err := signedOrder.ComputeOrderHash()
+	require.NoError(t, err)
+
+	// The BlockWatcher is caught up, so the order is not provisional.
+	numProvisionalOrders, err := orderWatcher.NumProvisionalOrders()
+	require.NoError(t, err)
+	assert.Equal(t, 0, numProvisionalOrders)
+
+	// Pretend that the order was validated while catching up and that its
+	// stored state is out of date.
+	dbOrder := &meshdb.Order{}
+	err = meshDB.Orders.FindByID(orderHash.Bytes(), dbOrder)
+	require.NoError(t, err)
+	dbOrder.IsProvisional = true
+	dbOrder.FillableTakerAssetAmount = big.NewInt(1)
+	err = meshDB.Orders.Update(dbOrder)
+	require.NoError(t, err)
+	numProvisionalOrders, err = orderWatcher.NumProvisionalOrders()
+	require.NoError(t, err)
+	assert.Equal(t, 1, numProvisionalOrders)
+
+	orderWatcher.revalidateProvisionalOrdersIfCaughtUp(ctx)
+
+	numProvisionalOrders, err = orderWatcher.NumProvisionalOrders()
+	require.NoError(t, err)
+	assert.Equal(t, 0, numProvisionalOrders)
+	err = meshDB.Orders.FindByID(orderHash.Bytes(), dbOrder)
+	require.NoError(t, err)
+	assert.False(t, dbOrder.IsProvisional)
+	assert.Equal(t, signedOrder.TakerAssetAmount, dbOrder.FillableTakerAssetAmount)
+}
+
 func TestOrderWatcherCleanup(t *testing.T) {
 	if !serialTestsEnabled {
 		t.Skip("Serial tests (tests which cannot run in parallel) are disabled. You can enable them with the --serial flag")
@@ -1665,7 +2014,7 @@ func watchOrder(ctx context.Context, t *testing.T, orderWatcher *Watcher, blockW
 	err := blockWatcher.SyncToLatestBlock()
 	require.NoError(t, err)
 
-	validationResults, err := orderWatcher.ValidateAndStoreValidOrders(ctx, []*zeroex.SignedOrder{signedOrder}, false, constants.TestChainID)
+	validationResults, err := orderWatcher.ValidateAndStoreValidOrders(ctx, []*zeroex.SignedOrder{signedOrder}, false, meshdb.OrderSourceGossip, constants.TestChainID)
 	require.NoError(t, err)
 	if len(validationResults.Rejected) != 0 {
 		spew.Dump(validationResults.Rejected)
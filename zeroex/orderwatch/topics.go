@@ -1,6 +1,8 @@
 package orderwatch
 
 import (
+	"strings"
+
 	"github.com/0xProject/0x-mesh/zeroex/orderwatch/decoder"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -17,3 +19,23 @@ func GetRelevantTopics() []common.Hash {
 
 	return topics
 }
+
+// exchangeEventSignaturePrefixes are the prefixes of the signatures in
+// decoder.EVENT_SIGNATURES of the Exchange events which change the state of
+// orders.
+var exchangeEventSignaturePrefixes = []string{"Fill(", "Cancel(", "CancelUpTo("}
+
+// GetExchangeTopics returns the topics of the Exchange events which change the
+// state of orders (Fill, Cancel and CancelUpTo).
+func GetExchangeTopics() []common.Hash {
+	topics := []common.Hash{}
+	for _, signature := range decoder.EVENT_SIGNATURES {
+		for _, prefix := range exchangeEventSignaturePrefixes {
+			if strings.HasPrefix(signature, prefix) {
+				topics = append(topics, common.BytesToHash(crypto.Keccak256([]byte(signature))))
+				break
+			}
+		}
+	}
+	return topics
+}
@@ -0,0 +1,206 @@
+package orderwatch
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/0xProject/0x-mesh/meshdb"
+)
+
+// ValidationWaitReason describes what a batch of orders which is being
+// validated by ValidateAndStoreValidOrders is currently waiting for.
+type ValidationWaitReason string
+
+// ValidationWaitReason values
+const (
+	// WaitReasonFiltering means that the orders are being checked against the
+	// Mesh-specific criteria (max expiration time, chain, size, duplicates,
+	// disk usage budget) which don't require any Ethereum RPC requests.
+	WaitReasonFiltering ValidationWaitReason = "FILTERING"
+	// WaitReasonBlockProcessing means that the batch is waiting for Watcher to
+	// finish processing block events. A large number of batches with this
+	// reason indicates a backlog of blocks.
+	WaitReasonBlockProcessing ValidationWaitReason = "BLOCK_PROCESSING"
+	// WaitReasonEthRPC means that the orders are being validated on-chain.
+	// This includes the time spent waiting for the Ethereum RPC rate limiter,
+	// so a large number of batches with this reason indicates that the node is
+	// limited by its Ethereum RPC provider.
+	WaitReasonEthRPC ValidationWaitReason = "ETH_RPC"
+	// WaitReasonStoring means that the valid orders are being stored in the
+	// database.
+	WaitReasonStoring ValidationWaitReason = "STORING"
+)
+
+// validationDurationSmoothing is the weight of the most recent batch in the
+// moving average of the time it takes to validate a single order.
+const validationDurationSmoothing = 0.2
+
+// ValidationBatch is a batch of orders which is currently being validated.
+type ValidationBatch struct {
+	Source     meshdb.OrderSource
+	NumOrders  int
+	WaitReason ValidationWaitReason
+	// Age is how long ago the batch was received.
+	Age time.Duration
+	// ETA is a rough estimate of how long it will take to finish validating
+	// the batch, based on the average time it took to validate recent orders.
+	// It is -1 if no orders were validated on-chain yet.
+	ETA time.Duration
+}
+
+// ValidationQueue summarizes the batches of orders which are currently being
+// validated. It can be used to tell why new orders are slow to be added.
+type ValidationQueue struct {
+	NumBatches            int
+	NumOrders             int
+	NumOrdersByWaitReason map[ValidationWaitReason]int
+	// OldestBatchAge is the age of the oldest batch in the queue or 0 if it is
+	// empty.
+	OldestBatchAge time.Duration
+	// Batches are sorted from oldest to newest.
+	Batches []*ValidationBatch
+}
+
+type queuedValidationBatch struct {
+	source     meshdb.OrderSource
+	numOrders  int
+	waitReason ValidationWaitReason
+	receivedAt time.Time
+	// numOrdersValidatedOnChain is the number of orders in the batch which
+	// reached on-chain validation.
+	numOrdersValidatedOnChain int
+	// cancel cancels the validation of the batch. It is set to nil once
+	// called.
+	cancel context.CancelFunc
+}
+
+// validationQueue keeps track of the batches of orders which are currently
+// being validated.
+type validationQueue struct {
+	mu      sync.Mutex
+	nextID  uint64
+	batches map[uint64]*queuedValidationBatch
+	// durationPerOrder is the moving average of the time it took to validate a
+	// single order in recent batches. It is 0 until the first batch which
+	// required on-chain validation was finished.
+	durationPerOrder time.Duration
+}
+
+func newValidationQueue() *validationQueue {
+	return &validationQueue{
+		batches: map[uint64]*queuedValidationBatch{},
+	}
+}
+
+// add adds a batch with the given number of orders to the queue and returns
+// its ID. cancel (which may be nil) cancels the validation of the batch if it
+// stalls (see cancelReceivedBefore).
+func (q *validationQueue) add(source meshdb.OrderSource, numOrders int, receivedAt time.Time, cancel context.CancelFunc) uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	id := q.nextID
+	q.nextID++
+	q.batches[id] = &queuedValidationBatch{
+		source:     source,
+		numOrders:  numOrders,
+		waitReason: WaitReasonFiltering,
+		receivedAt: receivedAt,
+		cancel:     cancel,
+	}
+	return id
+}
+
+// update sets the wait reason and the number of orders which remain to be
+// validated in the batch with the given ID.
+func (q *validationQueue) update(id uint64, waitReason ValidationWaitReason, numOrders int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	batch, found := q.batches[id]
+	if !found {
+		return
+	}
+	batch.waitReason = waitReason
+	batch.numOrders = numOrders
+	if waitReason == WaitReasonEthRPC {
+		batch.numOrdersValidatedOnChain = numOrders
+	}
+}
+
+// remove removes the batch with the given ID from the queue. If the batch was
+// validated on-chain, its duration is used to estimate the ETA of other
+// batches.
+func (q *validationQueue) remove(id uint64, finishedAt time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	batch, found := q.batches[id]
+	if !found {
+		return
+	}
+	delete(q.batches, id)
+	if batch.numOrdersValidatedOnChain == 0 {
+		return
+	}
+	durationPerOrder := finishedAt.Sub(batch.receivedAt) / time.Duration(batch.numOrdersValidatedOnChain)
+	if q.durationPerOrder == 0 {
+		q.durationPerOrder = durationPerOrder
+		return
+	}
+	q.durationPerOrder = time.Duration(validationDurationSmoothing*float64(durationPerOrder) + (1-validationDurationSmoothing)*float64(q.durationPerOrder))
+}
+
+// cancelReceivedBefore cancels the validation of all batches which were
+// received before the given time and returns the number of batches which were
+// canceled. Batches are only canceled once.
+func (q *validationQueue) cancelReceivedBefore(cutoff time.Time) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	numCanceled := 0
+	for _, batch := range q.batches {
+		if batch.cancel == nil || !batch.receivedAt.Before(cutoff) {
+			continue
+		}
+		batch.cancel()
+		batch.cancel = nil
+		numCanceled++
+	}
+	return numCanceled
+}
+
+// summary returns a summary of the batches in the queue at the given time.
+func (q *validationQueue) summary(now time.Time) *ValidationQueue {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	summary := &ValidationQueue{
+		NumOrdersByWaitReason: map[ValidationWaitReason]int{},
+		Batches:               make([]*ValidationBatch, 0, len(q.batches)),
+	}
+	for _, batch := range q.batches {
+		age := now.Sub(batch.receivedAt)
+		eta := time.Duration(-1)
+		if q.durationPerOrder != 0 {
+			eta = q.durationPerOrder*time.Duration(batch.numOrders) - age
+			if eta < 0 {
+				eta = 0
+			}
+		}
+		summary.NumBatches++
+		summary.NumOrders += batch.numOrders
+		summary.NumOrdersByWaitReason[batch.waitReason] += batch.numOrders
+		if age > summary.OldestBatchAge {
+			summary.OldestBatchAge = age
+		}
+		summary.Batches = append(summary.Batches, &ValidationBatch{
+			Source:     batch.source,
+			NumOrders:  batch.numOrders,
+			WaitReason: batch.waitReason,
+			Age:        age,
+			ETA:        eta,
+		})
+	}
+	sort.Slice(summary.Batches, func(i, j int) bool {
+		return summary.Batches[i].Age > summary.Batches[j].Age
+	})
+	return summary
+}
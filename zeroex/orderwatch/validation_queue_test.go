@@ -0,0 +1,58 @@
+// +build !js
+
+package orderwatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/0xProject/0x-mesh/meshdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidationQueue(t *testing.T) {
+	q := newValidationQueue()
+	start := time.Now()
+
+	empty := q.summary(start)
+	assert.Equal(t, 0, empty.NumBatches)
+	assert.Equal(t, time.Duration(0), empty.OldestBatchAge)
+	assert.Empty(t, empty.Batches)
+
+	rpcBatch := q.add(meshdb.OrderSourceRPC, 10, start, nil)
+	gossipBatch := q.add(meshdb.OrderSourceGossip, 5, start.Add(1*time.Second), nil)
+	q.update(rpcBatch, WaitReasonEthRPC, 8)
+
+	summary := q.summary(start.Add(2 * time.Second))
+	assert.Equal(t, 2, summary.NumBatches)
+	assert.Equal(t, 13, summary.NumOrders)
+	assert.Equal(t, map[ValidationWaitReason]int{
+		WaitReasonEthRPC:    8,
+		WaitReasonFiltering: 5,
+	}, summary.NumOrdersByWaitReason)
+	assert.Equal(t, 2*time.Second, summary.OldestBatchAge)
+	require.Len(t, summary.Batches, 2)
+	assert.Equal(t, meshdb.OrderSourceRPC, summary.Batches[0].Source)
+	assert.Equal(t, meshdb.OrderSourceGossip, summary.Batches[1].Source)
+	// The ETA is unknown until a batch was validated on-chain.
+	assert.Equal(t, time.Duration(-1), summary.Batches[0].ETA)
+
+	// Validating 8 orders on-chain took 4 seconds.
+	q.remove(rpcBatch, start.Add(4*time.Second))
+	q.update(gossipBatch, WaitReasonEthRPC, 5)
+	summary = q.summary(start.Add(2 * time.Second))
+	assert.Equal(t, 1, summary.NumBatches)
+	require.Len(t, summary.Batches, 1)
+	assert.Equal(t, WaitReasonEthRPC, summary.Batches[0].WaitReason)
+	assert.Equal(t, 1500*time.Millisecond, summary.Batches[0].ETA)
+
+	// Batches which were not validated on-chain don't affect the ETA.
+	duplicateBatch := q.add(meshdb.OrderSourceOrderSync, 20, start, nil)
+	q.remove(duplicateBatch, start.Add(1*time.Hour))
+	summary = q.summary(start.Add(2 * time.Second))
+	assert.Equal(t, 1500*time.Millisecond, summary.Batches[0].ETA)
+
+	q.remove(gossipBatch, start.Add(3*time.Second))
+	assert.Equal(t, 0, q.summary(start).NumBatches)
+}
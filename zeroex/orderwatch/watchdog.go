@@ -0,0 +1,197 @@
+package orderwatch
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	logger "github.com/sirupsen/logrus"
+)
+
+// watchdogCheckInterval is how often the watchdog checks whether any stage of
+// the order processing pipeline has stalled.
+const watchdogCheckInterval = 5 * time.Second
+
+// Stage is a stage of the order processing pipeline which is monitored by the
+// watchdog (see WatchdogConfig).
+type Stage string
+
+// Stage values
+const (
+	// StageBlockProcessing is the handling of block events by the main loop.
+	StageBlockProcessing Stage = "BLOCK_PROCESSING"
+	// StageValidation is the validation of new orders by
+	// ValidateAndStoreValidOrders.
+	StageValidation Stage = "VALIDATION"
+	// StageEventDelivery is the delivery of order events to subscribers.
+	StageEventDelivery Stage = "EVENT_DELIVERY"
+)
+
+// WatchdogConfig configures the watchdog which detects stalled stages of the
+// order processing pipeline. A stage is stalled if it has been working on the
+// same block events, validation batch or order events for longer than its
+// threshold. A threshold of 0 disables the check for that stage.
+type WatchdogConfig struct {
+	BlockProcessingStallThreshold time.Duration
+	ValidationStallThreshold      time.Duration
+	EventDeliveryStallThreshold   time.Duration
+	// RestartStalledStages causes the watchdog to restart stalled stages where
+	// this is safe. Block processing is restarted by canceling any Ethereum
+	// RPC requests made for the current block events and handling them again.
+	// Stalled validation batches are canceled, so ValidateAndStoreValidOrders
+	// returns an error for them. Event delivery can't be restarted without
+	// dropping subscribers, so it is only reported.
+	RestartStalledStages bool
+}
+
+func (c WatchdogConfig) enabled() bool {
+	return c.BlockProcessingStallThreshold > 0 || c.ValidationStallThreshold > 0 || c.EventDeliveryStallThreshold > 0
+}
+
+// StalledStage describes a stage of the order processing pipeline which has
+// stalled.
+type StalledStage struct {
+	Stage Stage
+	// StalledFor is how long the stage has been working on the same unit of
+	// work.
+	StalledFor time.Duration
+	Threshold  time.Duration
+}
+
+// stageProgress keeps track of the unit of work which a stage is currently
+// working on.
+type stageProgress struct {
+	mu sync.Mutex
+	// busySince is the zero time while the stage is idle.
+	busySince time.Time
+	// cancel cancels the current unit of work. It is nil if the work can't be
+	// canceled.
+	cancel context.CancelFunc
+	// restarted is true if the watchdog canceled the current unit of work.
+	restarted bool
+}
+
+// start marks the stage as busy with a new unit of work, which is canceled by
+// the given function if the stage is restarted.
+func (p *stageProgress) start(now time.Time, cancel context.CancelFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.busySince = now
+	p.cancel = cancel
+	p.restarted = false
+}
+
+// finish marks the stage as idle. It returns true if the unit of work was
+// canceled by restart.
+func (p *stageProgress) finish() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	restarted := p.restarted
+	p.busySince = time.Time{}
+	p.cancel = nil
+	p.restarted = false
+	return restarted
+}
+
+// busyFor returns how long the stage has been working on the current unit of
+// work at the given time, or 0 if it is idle.
+func (p *stageProgress) busyFor(now time.Time) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.busySince.IsZero() {
+		return 0
+	}
+	return now.Sub(p.busySince)
+}
+
+// restart cancels the current unit of work. It returns false if the stage is
+// idle, the work can't be canceled or it was already canceled.
+func (p *stageProgress) restart() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.busySince.IsZero() || p.cancel == nil || p.restarted {
+		return false
+	}
+	p.restarted = true
+	p.cancel()
+	return true
+}
+
+// StalledStages returns the stages of the order processing pipeline which the
+// watchdog found to be stalled the last time it checked. The node should be
+// considered degraded while it is not empty.
+func (w *Watcher) StalledStages() []StalledStage {
+	w.stalledStagesMu.Lock()
+	defer w.stalledStagesMu.Unlock()
+	return append([]StalledStage{}, w.stalledStages...)
+}
+
+// watchdogLoop periodically checks whether any stage of the order processing
+// pipeline has stalled.
+func (w *Watcher) watchdogLoop(ctx context.Context) error {
+	ticker := time.NewTicker(watchdogCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			w.checkStages(now)
+		}
+	}
+}
+
+// checkStages updates the stalled stages at the given time. It logs an alert
+// when a stage stalls and when it recovers, and restarts stalled stages if
+// WatchdogConfig.RestartStalledStages is set.
+func (w *Watcher) checkStages(now time.Time) {
+	stalledStages := []StalledStage{}
+	checkStage := func(stage Stage, threshold time.Duration, busyFor time.Duration) {
+		if threshold > 0 && busyFor > threshold {
+			stalledStages = append(stalledStages, StalledStage{Stage: stage, StalledFor: busyFor, Threshold: threshold})
+		}
+	}
+	checkStage(StageBlockProcessing, w.watchdogConfig.BlockProcessingStallThreshold, w.blockProcessing.busyFor(now))
+	checkStage(StageValidation, w.watchdogConfig.ValidationStallThreshold, w.validationQueue.summary(now).OldestBatchAge)
+	checkStage(StageEventDelivery, w.watchdogConfig.EventDeliveryStallThreshold, w.eventDelivery.busyFor(now))
+
+	w.stalledStagesMu.Lock()
+	previouslyStalled := map[Stage]struct{}{}
+	for _, stalledStage := range w.stalledStages {
+		previouslyStalled[stalledStage.Stage] = struct{}{}
+	}
+	w.stalledStages = stalledStages
+	w.stalledStagesMu.Unlock()
+
+	for _, stalledStage := range stalledStages {
+		if _, found := previouslyStalled[stalledStage.Stage]; !found {
+			logger.WithFields(logger.Fields{
+				"alert":      "orderPipelineStalled",
+				"stage":      stalledStage.Stage,
+				"stalledFor": stalledStage.StalledFor,
+				"threshold":  stalledStage.Threshold,
+			}).Error("order processing stage stalled; node is degraded")
+		}
+		delete(previouslyStalled, stalledStage.Stage)
+		if w.watchdogConfig.RestartStalledStages {
+			w.restartStage(stalledStage, now)
+		}
+	}
+	for stage := range previouslyStalled {
+		logger.WithField("stage", stage).Info("order processing stage recovered")
+	}
+}
+
+// restartStage restarts the given stalled stage if possible.
+func (w *Watcher) restartStage(stalledStage StalledStage, now time.Time) {
+	switch stalledStage.Stage {
+	case StageBlockProcessing:
+		if w.blockProcessing.restart() {
+			logger.WithField("stalledFor", stalledStage.StalledFor).Warn("restarting stalled block processing")
+		}
+	case StageValidation:
+		if numCanceled := w.validationQueue.cancelReceivedBefore(now.Add(-stalledStage.Threshold)); numCanceled > 0 {
+			logger.WithField("numBatches", numCanceled).Warn("canceled stalled validation batches")
+		}
+	}
+}
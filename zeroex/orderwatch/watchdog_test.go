@@ -0,0 +1,98 @@
+// +build !js
+
+package orderwatch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/0xProject/0x-mesh/meshdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWatcherForWatchdogTest(config WatchdogConfig) *Watcher {
+	return &Watcher{
+		watchdogConfig:  config,
+		blockProcessing: &stageProgress{},
+		eventDelivery:   &stageProgress{},
+		validationQueue: newValidationQueue(),
+	}
+}
+
+func TestWatchdogDetectsStalledStages(t *testing.T) {
+	w := newWatcherForWatchdogTest(WatchdogConfig{
+		BlockProcessingStallThreshold: 1 * time.Minute,
+		ValidationStallThreshold:      2 * time.Minute,
+		EventDeliveryStallThreshold:   30 * time.Second,
+	})
+	start := time.Now()
+
+	w.checkStages(start)
+	assert.Empty(t, w.StalledStages())
+
+	blockCtx, cancelBlock := context.WithCancel(context.Background())
+	defer cancelBlock()
+	w.blockProcessing.start(start, cancelBlock)
+	validationCtx, cancelValidation := context.WithCancel(context.Background())
+	defer cancelValidation()
+	w.validationQueue.add(meshdb.OrderSourceRPC, 10, start, cancelValidation)
+	w.eventDelivery.start(start, nil)
+
+	// Only event delivery exceeded its threshold.
+	w.checkStages(start.Add(45 * time.Second))
+	assert.Equal(t, []StalledStage{
+		{Stage: StageEventDelivery, StalledFor: 45 * time.Second, Threshold: 30 * time.Second},
+	}, w.StalledStages())
+
+	w.eventDelivery.finish()
+	w.checkStages(start.Add(3 * time.Minute))
+	assert.Equal(t, []StalledStage{
+		{Stage: StageBlockProcessing, StalledFor: 3 * time.Minute, Threshold: 1 * time.Minute},
+		{Stage: StageValidation, StalledFor: 3 * time.Minute, Threshold: 2 * time.Minute},
+	}, w.StalledStages())
+
+	// Stalled stages are not restarted unless RestartStalledStages is set.
+	assert.NoError(t, blockCtx.Err())
+	assert.NoError(t, validationCtx.Err())
+	assert.False(t, w.blockProcessing.finish())
+
+	w.checkStages(start.Add(4 * time.Minute))
+	assert.Equal(t, []StalledStage{
+		{Stage: StageValidation, StalledFor: 4 * time.Minute, Threshold: 2 * time.Minute},
+	}, w.StalledStages())
+}
+
+func TestWatchdogRestartsStalledStages(t *testing.T) {
+	w := newWatcherForWatchdogTest(WatchdogConfig{
+		BlockProcessingStallThreshold: 1 * time.Minute,
+		ValidationStallThreshold:      1 * time.Minute,
+		EventDeliveryStallThreshold:   1 * time.Minute,
+		RestartStalledStages:          true,
+	})
+	start := time.Now()
+
+	blockCtx, cancelBlock := context.WithCancel(context.Background())
+	defer cancelBlock()
+	w.blockProcessing.start(start, cancelBlock)
+	stalledCtx, cancelStalled := context.WithCancel(context.Background())
+	defer cancelStalled()
+	w.validationQueue.add(meshdb.OrderSourceGossip, 5, start, cancelStalled)
+	recentCtx, cancelRecent := context.WithCancel(context.Background())
+	defer cancelRecent()
+	w.validationQueue.add(meshdb.OrderSourceGossip, 5, start.Add(90*time.Second), cancelRecent)
+	w.eventDelivery.start(start, nil)
+
+	w.checkStages(start.Add(2 * time.Minute))
+	require.Len(t, w.StalledStages(), 3)
+
+	// Block processing is canceled so that the same events are handled again.
+	assert.Equal(t, context.Canceled, blockCtx.Err())
+	assert.True(t, w.blockProcessing.finish())
+	// Only the batch which exceeded the threshold is canceled.
+	assert.Equal(t, context.Canceled, stalledCtx.Err())
+	assert.NoError(t, recentCtx.Err())
+	// Event delivery can't be restarted.
+	assert.False(t, w.eventDelivery.restart())
+}
@@ -0,0 +1,215 @@
+package zeroex
+
+import (
+	"errors"
+	"math/big"
+	"runtime"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// SignatureVerificationResult is the outcome of verifying an order signature
+// off-chain.
+type SignatureVerificationResult uint8
+
+// SignatureVerificationResult values
+const (
+	// SVRequiresOnChainVerification means that the signature type (e.g.
+	// WalletSignature or ValidatorSignature) can only be verified by the
+	// Exchange contract.
+	SVRequiresOnChainVerification SignatureVerificationResult = iota
+	// SVValid means that the signature was produced by the maker.
+	SVValid
+	// SVInvalid means that the signature is malformed or was not produced by
+	// the maker. The Exchange contract would reject it as well.
+	SVInvalid
+)
+
+// ethSignPrefix is prepended to the order hash before it is signed with
+// eth_sign.
+var ethSignPrefix = []byte("\x19Ethereum Signed Message:\n32")
+
+// VerifyOrderSignature uses ecrecover to verify EIP712 and EthSign order
+// signatures the same way as the Exchange contract. All other signature types
+// require on-chain verification.
+func VerifyOrderSignature(signedOrder *SignedOrder, orderHash common.Hash) SignatureVerificationResult {
+	signature := signedOrder.Signature
+	if len(signature) == 0 {
+		return SVInvalid
+	}
+	var hash []byte
+	switch SignatureType(signature[len(signature)-1]) {
+	case EIP712Signature:
+		hash = orderHash.Bytes()
+	case EthSignSignature:
+		hash = keccak256(ethSignPrefix, orderHash.Bytes())
+	case WalletSignature, ValidatorSignature, PreSignedSignature, EIP1271WalletSignature:
+		return SVRequiresOnChainVerification
+	default:
+		return SVInvalid
+	}
+	if len(signature) != 66 {
+		return SVInvalid
+	}
+
+	// 0x signatures are in the [V || R || S || type] format whereas ecrecover
+	// expects [R || S || V] where V is 0 or 1.
+	v := signature[0] - 27
+	r := new(big.Int).SetBytes(signature[1:33])
+	s := new(big.Int).SetBytes(signature[33:65])
+	if signature[0] < 27 || !crypto.ValidateSignatureValues(v, r, s, false) {
+		return SVInvalid
+	}
+	ecSignature := make([]byte, 65)
+	copy(ecSignature[0:64], signature[1:65])
+	ecSignature[64] = v
+	publicKey, err := crypto.SigToPub(hash, ecSignature)
+	if err != nil {
+		return SVInvalid
+	}
+	if crypto.PubkeyToAddress(*publicKey) != signedOrder.MakerAddress {
+		return SVInvalid
+	}
+	return SVValid
+}
+
+// SignatureVerification is the result of verifying the signature of a single
+// order in BatchVerifyOrderSignatures.
+type SignatureVerification struct {
+	OrderHash common.Hash
+	Result    SignatureVerificationResult
+	// Err is set if the order hash could not be computed. Result is SVInvalid
+	// in that case.
+	Err error
+}
+
+// BatchVerifyOptions are the options for BatchVerifyOrderSignatures.
+type BatchVerifyOptions struct {
+	// OrderHashes are the precomputed hashes of the orders, in the same order.
+	// If it is nil, the order hashes are computed (and cached on the orders).
+	OrderHashes []common.Hash
+	// NumWorkers is the number of goroutines which verify signatures in
+	// parallel. It defaults to the number of CPUs.
+	NumWorkers int
+}
+
+// ErrOrderHashesLengthMismatch is returned by BatchVerifyOrderSignatures if
+// precomputed order hashes were given for some but not all of the orders.
+var ErrOrderHashesLengthMismatch = errors.New("the number of order hashes must match the number of orders")
+
+type domainSeparatorKey struct {
+	chainID         string
+	exchangeAddress common.Address
+}
+
+// BatchVerifyOrderSignatures verifies the signatures of the given orders (see
+// VerifyOrderSignature) and returns the results in the same order. It is
+// much faster than verifying the orders one by one: the EIP712 domain
+// separator is only computed once for all orders with the same chain and
+// exchange and orders are hashed and verified by a pool of workers.
+func BatchVerifyOrderSignatures(signedOrders []*SignedOrder, opts BatchVerifyOptions) ([]*SignatureVerification, error) {
+	if opts.OrderHashes != nil && len(opts.OrderHashes) != len(signedOrders) {
+		return nil, ErrOrderHashesLengthMismatch
+	}
+	numWorkers := opts.NumWorkers
+	if numWorkers <= 0 {
+		numWorkers = runtime.NumCPU()
+	}
+
+	// Computing an order hash caches it on the order, so each order must only
+	// be handled by one worker even if it is included more than once.
+	verifications := make([]*SignatureVerification, len(signedOrders))
+	firstIndex := make(map[*SignedOrder]int, len(signedOrders))
+	jobs := make([]int, 0, len(signedOrders))
+	domainSeparators := map[domainSeparatorKey][]byte{}
+	for i, signedOrder := range signedOrders {
+		verifications[i] = &SignatureVerification{}
+		if _, found := firstIndex[signedOrder]; found {
+			continue
+		}
+		firstIndex[signedOrder] = i
+		jobs = append(jobs, i)
+		if signedOrder == nil || opts.OrderHashes != nil || signedOrder.hash != nil || signedOrder.ChainID == nil {
+			continue
+		}
+		key := domainSeparatorKey{
+			chainID:         signedOrder.ChainID.String(),
+			exchangeAddress: signedOrder.ExchangeAddress,
+		}
+		if _, found := domainSeparators[key]; found {
+			continue
+		}
+		domainSeparator, err := eip712DomainSeparator(signedOrder.ChainID, signedOrder.ExchangeAddress)
+		if err != nil {
+			// The order hash will fail to be computed below as well.
+			continue
+		}
+		domainSeparators[key] = domainSeparator
+	}
+
+	verify := func(i int) {
+		signedOrder := signedOrders[i]
+		verification := verifications[i]
+		if signedOrder == nil {
+			verification.Result = SVInvalid
+			verification.Err = errors.New("cannot verify the signature of a nil order")
+			return
+		}
+		switch {
+		case opts.OrderHashes != nil:
+			verification.OrderHash = opts.OrderHashes[i]
+		case signedOrder.hash != nil:
+			verification.OrderHash = *signedOrder.hash
+		default:
+			var domainSeparator []byte
+			if signedOrder.ChainID != nil {
+				domainSeparator = domainSeparators[domainSeparatorKey{
+					chainID:         signedOrder.ChainID.String(),
+					exchangeAddress: signedOrder.ExchangeAddress,
+				}]
+			}
+			if domainSeparator == nil {
+				verification.Result = SVInvalid
+				verification.Err = errors.New("could not compute the EIP712 domain separator of the order")
+				return
+			}
+			orderHash, err := signedOrder.computeOrderHashWithDomainSeparator(domainSeparator)
+			if err != nil {
+				verification.Result = SVInvalid
+				verification.Err = err
+				return
+			}
+			verification.OrderHash = orderHash
+		}
+		verification.Result = VerifyOrderSignature(signedOrder, verification.OrderHash)
+	}
+
+	if numWorkers > len(jobs) {
+		numWorkers = len(jobs)
+	}
+	jobChan := make(chan int, len(jobs))
+	for _, i := range jobs {
+		jobChan <- i
+	}
+	close(jobChan)
+	wg := &sync.WaitGroup{}
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobChan {
+				verify(i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, signedOrder := range signedOrders {
+		if first := firstIndex[signedOrder]; first != i {
+			*verifications[i] = *verifications[first]
+		}
+	}
+	return verifications, nil
+}
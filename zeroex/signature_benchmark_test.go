@@ -0,0 +1,74 @@
+package zeroex
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// numBenchmarkOrders is the number of orders in a typical ordersync
+// ingestion.
+const numBenchmarkOrders = 10000
+
+func newBenchmarkOrders(b *testing.B) []*SignedOrder {
+	signedOrders := make([]*SignedOrder, numBenchmarkOrders)
+	for i := range signedOrders {
+		signedOrder, err := SignTestOrder(newTestOrderWithSalt(int64(i)))
+		if err != nil {
+			b.Fatal(err)
+		}
+		signedOrders[i] = signedOrder
+	}
+	return signedOrders
+}
+
+// BenchmarkVerifyOrderSignatures10000 hashes and verifies orders one by one.
+func BenchmarkVerifyOrderSignatures10000(b *testing.B) {
+	signedOrders := newBenchmarkOrders(b)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, signedOrder := range signedOrders {
+			signedOrder.ResetHash()
+			orderHash, err := signedOrder.ComputeOrderHash()
+			if err != nil {
+				b.Fatal(err)
+			}
+			if VerifyOrderSignature(signedOrder, orderHash) != SVValid {
+				b.Fatal("expected signature to be valid")
+			}
+		}
+	}
+}
+
+func BenchmarkBatchVerifyOrderSignatures10000(b *testing.B) {
+	signedOrders := newBenchmarkOrders(b)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		for _, signedOrder := range signedOrders {
+			signedOrder.ResetHash()
+		}
+		b.StartTimer()
+		if _, err := BatchVerifyOrderSignatures(signedOrders, BatchVerifyOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBatchVerifyOrderSignaturesWithPrecomputedHashes10000(b *testing.B) {
+	signedOrders := newBenchmarkOrders(b)
+	orderHashes := make([]common.Hash, len(signedOrders))
+	for i, signedOrder := range signedOrders {
+		orderHash, err := signedOrder.ComputeOrderHash()
+		if err != nil {
+			b.Fatal(err)
+		}
+		orderHashes[i] = orderHash
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := BatchVerifyOrderSignatures(signedOrders, BatchVerifyOptions{OrderHashes: orderHashes}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
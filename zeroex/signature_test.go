@@ -0,0 +1,131 @@
+package zeroex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestOrderWithSalt returns a copy of testOrder with the given salt.
+func newTestOrderWithSalt(salt int64) *Order {
+	order := *testOrder
+	order.Salt = big.NewInt(salt)
+	order.ResetHash()
+	return &order
+}
+
+// signTestOrderEIP712 signs the order with an EIP712 signature of the maker.
+func signTestOrderEIP712(t *testing.T, order *Order) *SignedOrder {
+	orderHash, err := order.ComputeOrderHash()
+	require.NoError(t, err)
+	privateKey, err := crypto.ToECDSA(constants.GanacheAccountToPrivateKey[order.MakerAddress])
+	require.NoError(t, err)
+	ecSignature, err := crypto.Sign(orderHash.Bytes(), privateKey)
+	require.NoError(t, err)
+	signature := make([]byte, 66)
+	signature[0] = ecSignature[64] + 27
+	copy(signature[1:65], ecSignature[0:64])
+	signature[65] = byte(EIP712Signature)
+	return &SignedOrder{Order: *order, Signature: signature}
+}
+
+func TestVerifyOrderSignature(t *testing.T) {
+	ethSignOrder, err := SignTestOrder(newTestOrderWithSalt(1))
+	require.NoError(t, err)
+	eip712Order := signTestOrderEIP712(t, newTestOrderWithSalt(2))
+
+	for _, signedOrder := range []*SignedOrder{ethSignOrder, eip712Order} {
+		orderHash, err := signedOrder.ComputeOrderHash()
+		require.NoError(t, err)
+		assert.Equal(t, SVValid, VerifyOrderSignature(signedOrder, orderHash))
+
+		// The signature doesn't match other orders.
+		otherOrderHash, err := newTestOrderWithSalt(3).ComputeOrderHash()
+		require.NoError(t, err)
+		assert.Equal(t, SVInvalid, VerifyOrderSignature(signedOrder, otherOrderHash))
+
+		// The signature must have been produced by the maker.
+		otherMaker := *signedOrder
+		otherMaker.MakerAddress = constants.GanacheAccount1
+		assert.Equal(t, SVInvalid, VerifyOrderSignature(&otherMaker, orderHash))
+	}
+
+	orderHash, err := ethSignOrder.ComputeOrderHash()
+	require.NoError(t, err)
+	testCases := []struct {
+		signature []byte
+		expected  SignatureVerificationResult
+	}{
+		{signature: []byte{}, expected: SVInvalid},
+		{signature: []byte{byte(IllegalSignature)}, expected: SVInvalid},
+		{signature: append(common.Hex2Bytes("1b"), byte(EthSignSignature)), expected: SVInvalid},
+		{signature: append(make([]byte, 65), byte(EIP712Signature)), expected: SVInvalid},
+		{signature: append(constants.GanacheAccount1.Bytes(), byte(WalletSignature)), expected: SVRequiresOnChainVerification},
+		{signature: []byte{byte(PreSignedSignature)}, expected: SVRequiresOnChainVerification},
+	}
+	for i, testCase := range testCases {
+		signedOrder := &SignedOrder{Order: ethSignOrder.Order, Signature: testCase.signature}
+		assert.Equal(t, testCase.expected, VerifyOrderSignature(signedOrder, orderHash), "test case %d", i)
+	}
+}
+
+func TestBatchVerifyOrderSignatures(t *testing.T) {
+	validOrder, err := SignTestOrder(newTestOrderWithSalt(1))
+	require.NoError(t, err)
+	invalidOrder, err := SignTestOrder(newTestOrderWithSalt(2))
+	require.NoError(t, err)
+	invalidOrder.Signature = validOrder.Signature
+	eip712Order := signTestOrderEIP712(t, newTestOrderWithSalt(3))
+	otherChainOrder := signTestOrderEIP712(t, &Order{
+		ChainID:               big.NewInt(1),
+		MakerAddress:          testOrder.MakerAddress,
+		TakerAddress:          testOrder.TakerAddress,
+		SenderAddress:         testOrder.SenderAddress,
+		FeeRecipientAddress:   testOrder.FeeRecipientAddress,
+		MakerAssetData:        testOrder.MakerAssetData,
+		MakerFeeAssetData:     testOrder.MakerFeeAssetData,
+		TakerAssetData:        testOrder.TakerAssetData,
+		TakerFeeAssetData:     testOrder.TakerFeeAssetData,
+		Salt:                  testOrder.Salt,
+		MakerFee:              testOrder.MakerFee,
+		TakerFee:              testOrder.TakerFee,
+		MakerAssetAmount:      testOrder.MakerAssetAmount,
+		TakerAssetAmount:      testOrder.TakerAssetAmount,
+		ExpirationTimeSeconds: testOrder.ExpirationTimeSeconds,
+		ExchangeAddress:       constants.GanacheAccount2,
+	})
+
+	signedOrders := []*SignedOrder{validOrder, invalidOrder, eip712Order, otherChainOrder, validOrder}
+	expectedHashes := make([]common.Hash, len(signedOrders))
+	for i, signedOrder := range signedOrders {
+		orderHash, err := signedOrder.ComputeOrderHash()
+		require.NoError(t, err)
+		expectedHashes[i] = orderHash
+		signedOrder.ResetHash()
+	}
+	expectedResults := []SignatureVerificationResult{SVValid, SVInvalid, SVValid, SVValid, SVValid}
+
+	verifications, err := BatchVerifyOrderSignatures(signedOrders, BatchVerifyOptions{NumWorkers: 2})
+	require.NoError(t, err)
+	require.Len(t, verifications, len(signedOrders))
+	for i, verification := range verifications {
+		require.NoError(t, verification.Err)
+		assert.Equal(t, expectedHashes[i], verification.OrderHash, "order %d", i)
+		assert.Equal(t, expectedResults[i], verification.Result, "order %d", i)
+	}
+
+	// The order hashes can be precomputed.
+	verifications, err = BatchVerifyOrderSignatures(signedOrders, BatchVerifyOptions{OrderHashes: expectedHashes})
+	require.NoError(t, err)
+	for i, verification := range verifications {
+		assert.Equal(t, expectedResults[i], verification.Result, "order %d", i)
+	}
+
+	_, err = BatchVerifyOrderSignatures(signedOrders, BatchVerifyOptions{OrderHashes: expectedHashes[:1]})
+	assert.Equal(t, ErrOrderHashesLengthMismatch, err)
+}
@@ -0,0 +1,264 @@
+package zeroex
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// The wire format of a signed order is its JSON encoding as sent to peers and
+// matched against order filters. Go and Javascript implementations used to
+// parse it leniently (e.g. numbers with leading zeros or in hex, addresses of
+// the wrong length), so the same message could be decoded into different
+// orders with different hashes. MarshalSignedOrderWire and
+// UnmarshalSignedOrderWire implement a strict version of it:
+//
+//   - The fields are always encoded in the order of SignedOrderJSON.
+//   - Unknown and missing fields are rejected.
+//   - Addresses and hex encoded bytes must be lowercase and 0x-prefixed.
+//   - Numbers which are encoded as strings (the wholeNumber values, e.g.
+//     makerAssetAmount and salt) must be base 10 integers between 0 and
+//     2^256-1 without leading zeros, so that each number has exactly one
+//     encoding.
+
+// signedOrderWireFields are the fields of the wire format in canonical order.
+var signedOrderWireFields = []string{
+	"chainId",
+	"exchangeAddress",
+	"makerAddress",
+	"makerAssetData",
+	"makerFeeAssetData",
+	"makerAssetAmount",
+	"makerFee",
+	"takerAddress",
+	"takerAssetData",
+	"takerFeeAssetData",
+	"takerAssetAmount",
+	"takerFee",
+	"senderAddress",
+	"feeRecipientAddress",
+	"expirationTimeSeconds",
+	"salt",
+	"signature",
+}
+
+var (
+	wireAddressRegex     = regexp.MustCompile(`^0x[0-9a-f]{40}$`)
+	wireHexRegex         = regexp.MustCompile(`^0x([0-9a-f]{2})*$`)
+	wireWholeNumberRegex = regexp.MustCompile(`^(0|[1-9][0-9]{0,77})$`)
+	wireChainIDRegex     = regexp.MustCompile(`^[1-9][0-9]{0,17}$`)
+	maxUint256           = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+)
+
+// UnknownOrderFieldError is returned by UnmarshalSignedOrderWire if the order
+// has a field which is not part of the wire format.
+type UnknownOrderFieldError struct {
+	Field string
+}
+
+func (e UnknownOrderFieldError) Error() string {
+	return fmt.Sprintf("unknown order field %q", e.Field)
+}
+
+// InvalidOrderFieldError is returned by MarshalSignedOrderWire and
+// UnmarshalSignedOrderWire if a field of the order is missing or can't be
+// encoded in the wire format.
+type InvalidOrderFieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e InvalidOrderFieldError) Error() string {
+	return fmt.Sprintf("invalid order field %q: %s", e.Field, e.Reason)
+}
+
+// MarshalSignedOrderWire encodes the given order in the wire format. Unlike
+// MarshalJSON, it returns an error instead of encoding orders which peers would
+// reject, e.g. orders with missing or negative numbers.
+func MarshalSignedOrderWire(signedOrder *SignedOrder) ([]byte, error) {
+	if signedOrder == nil {
+		return nil, fmt.Errorf("signed order must not be nil")
+	}
+	if signedOrder.ChainID == nil || !signedOrder.ChainID.IsInt64() || signedOrder.ChainID.Sign() <= 0 {
+		return nil, InvalidOrderFieldError{Field: "chainId", Reason: "must be a positive 64 bit integer"}
+	}
+	numbers := []struct {
+		field string
+		value *big.Int
+	}{
+		{"makerAssetAmount", signedOrder.MakerAssetAmount},
+		{"makerFee", signedOrder.MakerFee},
+		{"takerAssetAmount", signedOrder.TakerAssetAmount},
+		{"takerFee", signedOrder.TakerFee},
+		{"expirationTimeSeconds", signedOrder.ExpirationTimeSeconds},
+		{"salt", signedOrder.Salt},
+	}
+	for _, number := range numbers {
+		if number.value == nil || number.value.Sign() < 0 || number.value.Cmp(maxUint256) > 0 {
+			return nil, InvalidOrderFieldError{Field: number.field, Reason: "must be an integer between 0 and 2^256-1"}
+		}
+	}
+	// MarshalJSON encodes the fields of SignedOrderJSON in declaration order,
+	// addresses and bytes as lowercase hex and numbers with big.Int.String,
+	// which is the canonical encoding.
+	return signedOrder.MarshalJSON()
+}
+
+// UnmarshalSignedOrderWire decodes an order in the wire format. It returns an
+// UnknownOrderFieldError or InvalidOrderFieldError if the order is not
+// encoded strictly according to the wire format.
+func UnmarshalSignedOrderWire(data []byte) (*SignedOrder, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	var fields map[string]json.RawMessage
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after signed order")
+	}
+	if fields == nil {
+		return nil, fmt.Errorf("signed order must be a JSON object")
+	}
+	values := make(map[string]string, len(signedOrderWireFields))
+	for _, field := range signedOrderWireFields {
+		rawValue, found := fields[field]
+		if !found {
+			return nil, InvalidOrderFieldError{Field: field, Reason: "missing"}
+		}
+		delete(fields, field)
+		if field == "chainId" {
+			// The chain ID is the only field which is encoded as a JSON number.
+			values[field] = string(rawValue)
+			continue
+		}
+		var value string
+		if err := json.Unmarshal(rawValue, &value); err != nil {
+			return nil, InvalidOrderFieldError{Field: field, Reason: "must be a string"}
+		}
+		values[field] = value
+	}
+	if len(fields) != 0 {
+		unknownFields := make([]string, 0, len(fields))
+		for field := range fields {
+			unknownFields = append(unknownFields, field)
+		}
+		sort.Strings(unknownFields)
+		return nil, UnknownOrderFieldError{Field: unknownFields[0]}
+	}
+
+	if !wireChainIDRegex.MatchString(values["chainId"]) {
+		return nil, InvalidOrderFieldError{Field: "chainId", Reason: "must be a positive integer without leading zeros, exponent or fraction"}
+	}
+	chainID, ok := new(big.Int).SetString(values["chainId"], 10)
+	if !ok || !chainID.IsInt64() {
+		return nil, InvalidOrderFieldError{Field: "chainId", Reason: "must be a positive 64 bit integer"}
+	}
+	signedOrder := &SignedOrder{}
+	signedOrder.ChainID = chainID
+	addresses := []struct {
+		field  string
+		target *common.Address
+	}{
+		{"exchangeAddress", &signedOrder.ExchangeAddress},
+		{"makerAddress", &signedOrder.MakerAddress},
+		{"takerAddress", &signedOrder.TakerAddress},
+		{"senderAddress", &signedOrder.SenderAddress},
+		{"feeRecipientAddress", &signedOrder.FeeRecipientAddress},
+	}
+	for _, address := range addresses {
+		value := values[address.field]
+		if !wireAddressRegex.MatchString(value) {
+			return nil, InvalidOrderFieldError{Field: address.field, Reason: "must be a lowercase 0x-prefixed 20 byte hex string"}
+		}
+		*address.target = common.HexToAddress(value)
+	}
+	hexValues := []struct {
+		field  string
+		target *[]byte
+	}{
+		{"makerAssetData", &signedOrder.MakerAssetData},
+		{"makerFeeAssetData", &signedOrder.MakerFeeAssetData},
+		{"takerAssetData", &signedOrder.TakerAssetData},
+		{"takerFeeAssetData", &signedOrder.TakerFeeAssetData},
+		{"signature", &signedOrder.Signature},
+	}
+	for _, hexValue := range hexValues {
+		value := values[hexValue.field]
+		if !wireHexRegex.MatchString(value) {
+			return nil, InvalidOrderFieldError{Field: hexValue.field, Reason: "must be lowercase 0x-prefixed hex encoded bytes"}
+		}
+		*hexValue.target = common.FromHex(value)
+	}
+	numbers := []struct {
+		field  string
+		target **big.Int
+	}{
+		{"makerAssetAmount", &signedOrder.MakerAssetAmount},
+		{"makerFee", &signedOrder.MakerFee},
+		{"takerAssetAmount", &signedOrder.TakerAssetAmount},
+		{"takerFee", &signedOrder.TakerFee},
+		{"expirationTimeSeconds", &signedOrder.ExpirationTimeSeconds},
+		{"salt", &signedOrder.Salt},
+	}
+	for _, number := range numbers {
+		value := values[number.field]
+		if !wireWholeNumberRegex.MatchString(value) {
+			return nil, InvalidOrderFieldError{Field: number.field, Reason: "must be a base 10 integer without leading zeros"}
+		}
+		parsed, ok := new(big.Int).SetString(value, 10)
+		if !ok || parsed.Cmp(maxUint256) > 0 {
+			return nil, InvalidOrderFieldError{Field: number.field, Reason: "must be an integer between 0 and 2^256-1"}
+		}
+		*number.target = parsed
+	}
+	return signedOrder, nil
+}
+
+// WireSignedOrders is a list of signed orders which is encoded as a JSON array
+// of orders in the wire format (see MarshalSignedOrderWire). It can be used
+// instead of []*SignedOrder in messages sent to peers.
+type WireSignedOrders []*SignedOrder
+
+// MarshalJSON implements json.Marshaler.
+func (orders WireSignedOrders) MarshalJSON() ([]byte, error) {
+	if orders == nil {
+		return []byte("null"), nil
+	}
+	encodedOrders := make([]json.RawMessage, len(orders))
+	for i, order := range orders {
+		encoded, err := MarshalSignedOrderWire(order)
+		if err != nil {
+			return nil, err
+		}
+		encodedOrders[i] = encoded
+	}
+	return json.Marshal(encodedOrders)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (orders *WireSignedOrders) UnmarshalJSON(data []byte) error {
+	var encodedOrders []json.RawMessage
+	if err := json.Unmarshal(data, &encodedOrders); err != nil {
+		return err
+	}
+	if encodedOrders == nil {
+		*orders = nil
+		return nil
+	}
+	decodedOrders := make(WireSignedOrders, len(encodedOrders))
+	for i, encoded := range encodedOrders {
+		order, err := UnmarshalSignedOrderWire(encoded)
+		if err != nil {
+			return err
+		}
+		decodedOrders[i] = order
+	}
+	*orders = decodedOrders
+	return nil
+}
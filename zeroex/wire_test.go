@@ -0,0 +1,112 @@
+package zeroex
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignedOrderWireRoundTrip(t *testing.T) {
+	signedOrder, err := SignTestOrder(testOrder)
+	require.NoError(t, err)
+	expectedHash, err := signedOrder.ComputeOrderHash()
+	require.NoError(t, err)
+
+	encoded, err := MarshalSignedOrderWire(signedOrder)
+	require.NoError(t, err)
+	// The fields are encoded in canonical order.
+	var fieldOrder []string
+	decoder := json.NewDecoder(strings.NewReader(string(encoded)))
+	_, err = decoder.Token()
+	require.NoError(t, err)
+	for decoder.More() {
+		token, err := decoder.Token()
+		require.NoError(t, err)
+		fieldOrder = append(fieldOrder, token.(string))
+		var value json.RawMessage
+		require.NoError(t, decoder.Decode(&value))
+	}
+	assert.Equal(t, signedOrderWireFields, fieldOrder)
+
+	decoded, err := UnmarshalSignedOrderWire(encoded)
+	require.NoError(t, err)
+	actualHash, err := decoded.ComputeOrderHash()
+	require.NoError(t, err)
+	assert.Equal(t, expectedHash, actualHash)
+	reencoded, err := MarshalSignedOrderWire(decoded)
+	require.NoError(t, err)
+	assert.Equal(t, string(encoded), string(reencoded))
+}
+
+func TestUnmarshalSignedOrderWireErrors(t *testing.T) {
+	signedOrder, err := SignTestOrder(testOrder)
+	require.NoError(t, err)
+	encoded, err := MarshalSignedOrderWire(signedOrder)
+	require.NoError(t, err)
+	withField := func(field string, value string) []byte {
+		var fields map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(encoded, &fields))
+		if value == "" {
+			delete(fields, field)
+		} else {
+			fields[field] = json.RawMessage(value)
+		}
+		modified, err := json.Marshal(fields)
+		require.NoError(t, err)
+		return modified
+	}
+
+	testCases := []struct {
+		note        string
+		data        []byte
+		expectedErr error
+	}{
+		{"unknown field", withField("orderHash", `"0x00"`), UnknownOrderFieldError{Field: "orderHash"}},
+		{"missing field", withField("salt", ""), InvalidOrderFieldError{Field: "salt", Reason: "missing"}},
+		{"leading zeros", withField("makerAssetAmount", `"0203"`), InvalidOrderFieldError{Field: "makerAssetAmount", Reason: "must be a base 10 integer without leading zeros"}},
+		{"hex number", withField("takerFee", `"0xca"`), InvalidOrderFieldError{Field: "takerFee", Reason: "must be a base 10 integer without leading zeros"}},
+		{"number instead of string", withField("makerFee", `201`), InvalidOrderFieldError{Field: "makerFee", Reason: "must be a string"}},
+		{"too large", withField("salt", `"`+new(big.Int).Lsh(big.NewInt(1), 256).String()+`"`), InvalidOrderFieldError{Field: "salt", Reason: "must be an integer between 0 and 2^256-1"}},
+		{"chain ID with fraction", withField("chainId", `1337.0`), InvalidOrderFieldError{Field: "chainId", Reason: "must be a positive integer without leading zeros, exponent or fraction"}},
+		{"short address", withField("makerAddress", `"0x1234"`), InvalidOrderFieldError{Field: "makerAddress", Reason: "must be a lowercase 0x-prefixed 20 byte hex string"}},
+		{"uppercase address", withField("makerAddress", `"0x5409ED021D9299BF6814279A6A1411A7E866A631"`), InvalidOrderFieldError{Field: "makerAddress", Reason: "must be a lowercase 0x-prefixed 20 byte hex string"}},
+		{"odd length hex", withField("signature", `"0x123"`), InvalidOrderFieldError{Field: "signature", Reason: "must be lowercase 0x-prefixed hex encoded bytes"}},
+	}
+	for _, tc := range testCases {
+		_, err := UnmarshalSignedOrderWire(tc.data)
+		assert.Equal(t, tc.expectedErr, err, tc.note)
+	}
+
+	_, err = UnmarshalSignedOrderWire(append(append([]byte{}, encoded...), []byte(`{}`)...))
+	assert.Error(t, err, "trailing data")
+}
+
+func TestMarshalSignedOrderWireErrors(t *testing.T) {
+	signedOrder, err := SignTestOrder(testOrder)
+	require.NoError(t, err)
+	signedOrder.Salt = nil
+	_, err = MarshalSignedOrderWire(signedOrder)
+	assert.Equal(t, InvalidOrderFieldError{Field: "salt", Reason: "must be an integer between 0 and 2^256-1"}, err)
+	signedOrder.Salt = big.NewInt(-1)
+	_, err = MarshalSignedOrderWire(signedOrder)
+	assert.Equal(t, InvalidOrderFieldError{Field: "salt", Reason: "must be an integer between 0 and 2^256-1"}, err)
+}
+
+func TestWireSignedOrders(t *testing.T) {
+	signedOrder, err := SignTestOrder(testOrder)
+	require.NoError(t, err)
+	encoded, err := json.Marshal(WireSignedOrders{signedOrder})
+	require.NoError(t, err)
+	var decoded WireSignedOrders
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	require.Len(t, decoded, 1)
+	assert.Equal(t, signedOrder.Signature, decoded[0].Signature)
+
+	// A single invalid order causes the whole list to be rejected.
+	invalid := strings.Replace(string(encoded), `"salt":"200"`, `"salt":"0200"`, 1)
+	assert.Error(t, json.Unmarshal([]byte(invalid), &decoded))
+}